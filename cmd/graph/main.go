@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/pflag"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/graph"
+)
+
+var (
+	cfg       = pflag.StringP("config", "c", "", "snake config file path.")
+	format    = pflag.StringP("format", "f", graph.FormatGraphML, "export format, graphml or edgelist.")
+	output    = pflag.StringP("output", "o", "", "output file, default stdout.")
+	batchSize = pflag.Int("batch-size", 1000, "follows read from db per batch.")
+)
+
+// 导出关注关系图，边从关注者指向被关注者，用于在 igraph、Gephi 中做社区发现等分析
+// e.g. graph -c config.yaml -f edgelist -o follows.txt
+func main() {
+	pflag.Parse()
+
+	if err := conf.Init(*cfg); err != nil {
+		panic(err)
+	}
+	conf.InitLog()
+	model.Init()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := graph.NewWriter(*format, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// 收到退出信号时中断导出
+	ctx, cancel := context.WithCancel(context.Background())
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	total, err := user.Svc.ExportFollowGraph(ctx, w, *batchSize)
+	if err == nil {
+		err = w.Close()
+	}
+	fmt.Fprintf(os.Stderr, "exported %d follows\n", total)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
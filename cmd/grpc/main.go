@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/server/interceptor"
+	"github.com/1024casts/snake/internal/server/userserver0"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/deadline"
+	"github.com/1024casts/snake/pkg/idgen"
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/storage"
+	"github.com/1024casts/snake/pkg/tracing"
+
+	userpb "github.com/1024casts/snake/internal/rpc/user/v0"
+)
+
+var (
+	cfg  = pflag.StringP("config", "c", "", "snake config file path.")
+	addr = pflag.String("addr", "", "listen address, default grpc.addr in config.")
+)
+
+// gRPC 服务，供内部服务调用，和 HTTP 服务共用 service 层
+func main() {
+	pflag.Parse()
+
+	if err := conf.Init(*cfg); err != nil {
+		panic(err)
+	}
+	conf.InitLog()
+	model.Init()
+	model.InitRegions()
+	redis.Init()
+	redis.InitRegions()
+	storage.Init()
+	queue.Init()
+	deadline.Init()
+	idgen.Init()
+	tracing.Init()
+
+	listenAddr := *addr
+	if listenAddr == "" {
+		listenAddr = viper.GetString("grpc.addr")
+	}
+
+	callers := viper.GetStringMapString("grpc.callers")
+	if len(callers) == 0 {
+		log.Warn("[grpc] grpc.callers is empty, all calls will be rejected")
+	}
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptor.RequestID(),
+		interceptor.Tracing(),
+		interceptor.Auth(callers),
+		interceptor.Logging(),
+		interceptor.Recovery(),
+		interceptor.Errors(),
+	))
+	userpb.RegisterUserServer(srv, &userserver0.UserServer{})
+
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	// 退出时先停止 grpc 服务，再导出剩余的 span、关闭连接池
+	lc := lifecycle.New(lifecycle.Config{Timeout: shutdownTimeout()})
+	lc.Add(model.Hook())
+	lc.Add(redis.Hook())
+	lc.Add(tracing.Hook())
+	lc.Add(lifecycle.Hook{
+		Name: "grpc",
+		OnStart: func(ctx context.Context) error {
+			lis, err := net.Listen("tcp", listenAddr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				log.Infof("[grpc] listening on %s", lis.Addr())
+				if err := srv.Serve(lis); err != nil {
+					log.Errorf("[grpc] serve err: %v", err)
+					os.Exit(1)
+				}
+			}()
+			return nil
+		},
+		// 先标记为不可用，负载均衡摘除后再停止
+		OnStop: func(ctx context.Context) error {
+			healthSrv.Shutdown()
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				log.Warn("[grpc] graceful stop timed out, force stop")
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+
+	if err := lc.Run(); err != nil {
+		log.Fatalf("[grpc] %v", err)
+	}
+}
+
+// shutdownTimeout 等待正在处理的调用完成的时间
+func shutdownTimeout() time.Duration {
+	if d := viper.GetDuration("grpc.shutdown_timeout"); d > 0 {
+		return d
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// jobView 管理接口中任务的信息
+type jobView struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`
+	Paused  bool       `json:"paused"`
+	Running bool       `json:"running"`
+	Prev    *time.Time `json:"prev,omitempty"`
+	Next    *time.Time `json:"next,omitempty"`
+	Last    *jobStatus `json:"last,omitempty"`
+}
+
+// serveHTTP 配置了 job.http_addr 时暴露 /metrics，配置了 job.admin_token 时同时开启任务管理接口
+// 返回的 server 由 lifecycle 启动和停止，未配置时返回 nil
+//
+//	GET  /jobs              任务列表
+//	GET  /jobs/:name        任务详情
+//	POST /jobs/:name/pause  暂停，所有实例都不再按计划执行
+//	POST /jobs/:name/resume 恢复
+//	POST /jobs/:name/run    在当前实例立即执行一次，不受暂停影响，其他实例正在执行时会被任务锁跳过
+func serveHTTP(s *scheduler) *http.Server {
+	addr := viper.GetString("job.http_addr")
+	if addr == "" {
+		return nil
+	}
+
+	g := gin.New()
+	g.Use(gin.Recovery())
+	g.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	if token := viper.GetString("job.admin_token"); token != "" {
+		jobs := g.Group("/jobs", adminAuth(token))
+		jobs.GET("", s.listJobs)
+		jobs.GET("/:name", s.getJob)
+		jobs.POST("/:name/pause", s.pauseJob)
+		jobs.POST("/:name/resume", s.resumeJob)
+		jobs.POST("/:name/run", s.runJob)
+	} else {
+		log.Warn("[job] job.admin_token is empty, job admin api is disabled")
+	}
+
+	log.Infof("[job] http on %s", addr)
+	return &http.Server{Addr: addr, Handler: g}
+}
+
+// adminAuth 校验 Authorization: Bearer <job.admin_token>
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			handler.SendStatusResponse(c, http.StatusUnauthorized, errno.ErrTokenInvalid, nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// view 组装任务信息，paused 为 nil 时从 redis 中读取
+func (s *scheduler) view(sj *scheduledJob, paused map[string]bool) (*jobView, error) {
+	if paused == nil {
+		ok, err := redis.RedisClient.SIsMember(keyPausedJobs, sj.Name).Result()
+		if err != nil {
+			return nil, err
+		}
+		paused = map[string]bool{sj.Name: ok}
+	}
+	last, err := sj.lastStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &jobView{
+		Name:    sj.Name,
+		Spec:    sj.Spec,
+		Paused:  paused[sj.Name],
+		Running: atomic.LoadInt32(&sj.running) == 1,
+		Last:    last,
+	}
+	entry := s.cron.Entry(sj.id)
+	if !entry.Prev.IsZero() {
+		v.Prev = &entry.Prev
+	}
+	if !entry.Next.IsZero() && !v.Paused {
+		v.Next = &entry.Next
+	}
+	return v, nil
+}
+
+// listJobs 任务列表
+func (s *scheduler) listJobs(c *gin.Context) {
+	names, err := redis.RedisClient.SMembers(keyPausedJobs).Result()
+	if err != nil {
+		handler.SendError(c, err)
+		return
+	}
+	paused := make(map[string]bool, len(names))
+	for _, name := range names {
+		paused[name] = true
+	}
+
+	views := make([]*jobView, 0, len(s.jobs))
+	for _, sj := range s.jobs {
+		v, err := s.view(sj, paused)
+		if err != nil {
+			handler.SendError(c, err)
+			return
+		}
+		views = append(views, v)
+	}
+	handler.SendResponse(c, nil, views)
+}
+
+// getJob 任务详情
+func (s *scheduler) getJob(c *gin.Context) {
+	sj, ok := s.find(c.Param("name"))
+	if !ok {
+		handler.SendResponse(c, errno.ErrJobNotFound, nil)
+		return
+	}
+	v, err := s.view(sj, nil)
+	if err != nil {
+		handler.SendError(c, err)
+		return
+	}
+	handler.SendResponse(c, nil, v)
+}
+
+// pauseJob 暂停任务，正在执行的不受影响
+func (s *scheduler) pauseJob(c *gin.Context) {
+	s.setPaused(c, true)
+}
+
+// resumeJob 恢复任务
+func (s *scheduler) resumeJob(c *gin.Context) {
+	s.setPaused(c, false)
+}
+
+func (s *scheduler) setPaused(c *gin.Context, paused bool) {
+	sj, ok := s.find(c.Param("name"))
+	if !ok {
+		handler.SendResponse(c, errno.ErrJobNotFound, nil)
+		return
+	}
+
+	var err error
+	if paused {
+		err = redis.RedisClient.SAdd(keyPausedJobs, sj.Name).Err()
+	} else {
+		err = redis.RedisClient.SRem(keyPausedJobs, sj.Name).Err()
+	}
+	if err != nil {
+		handler.SendError(c, err)
+		return
+	}
+	log.Infof("[job] %s paused: %v, by %s", sj.Name, paused, c.ClientIP())
+
+	v, err := s.view(sj, nil)
+	if err != nil {
+		handler.SendError(c, err)
+		return
+	}
+	handler.SendResponse(c, nil, v)
+}
+
+// runJob 在当前实例立即执行一次，异步执行，通过任务详情查看结果
+func (s *scheduler) runJob(c *gin.Context) {
+	sj, ok := s.find(c.Param("name"))
+	if !ok {
+		handler.SendResponse(c, errno.ErrJobNotFound, nil)
+		return
+	}
+	if atomic.LoadInt32(&sj.running) == 1 {
+		handler.SendResponse(c, errno.ErrJobRunning, nil)
+		return
+	}
+
+	sj.runNow()
+	handler.SendStatusResponse(c, http.StatusAccepted, nil, nil)
+}
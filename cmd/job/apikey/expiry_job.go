@@ -0,0 +1,24 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/apikey"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ExpiryJob 定时给即将过期的 api key 发送提醒
+type ExpiryJob struct {
+	// Limit 每次最多提醒的 key 数量
+	Limit int
+}
+
+// Run 发送提醒
+func (j ExpiryJob) Run() {
+	count, err := apikey.Svc.SendExpiryReminders(context.Background(), j.Limit)
+	if err != nil {
+		log.Warnf("[job] send api key expiry reminders err: %v", err)
+		return
+	}
+	log.Infof("[job] send api key expiry reminders done, count: %d", count)
+}
@@ -0,0 +1,28 @@
+package campaign
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/campaign"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// CampaignJob 定时发送召回活动
+type CampaignJob struct{}
+
+// Run 发送召回活动
+func (j CampaignJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 发送召回活动，ctx 取消时中断
+func (j CampaignJob) RunContext(ctx context.Context) error {
+	results, err := campaign.Svc.Run(ctx)
+	if err != nil {
+		log.Warnf("[job] run campaigns err: %v", err)
+	}
+	for _, ret := range results {
+		log.Infof("[job] campaign %s done, outcomes: %v", ret.Campaign, ret.Outcomes)
+	}
+	return err
+}
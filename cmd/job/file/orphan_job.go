@@ -0,0 +1,40 @@
+package file
+
+import (
+	"context"
+	"time"
+
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// defaultGrace 上传后未被引用的文件保留一天，给客户端留出使用的时间
+const defaultGrace = 24 * time.Hour
+
+// CleanOrphanJob 定时清理没有被引用的文件
+type CleanOrphanJob struct {
+	// Grace 未被引用的文件的保留时间
+	Grace time.Duration
+	// Limit 每次清理的文件数
+	Limit int
+}
+
+// Run 执行清理
+func (j CleanOrphanJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 执行清理，ctx 取消时中断
+func (j CleanOrphanJob) RunContext(ctx context.Context) error {
+	grace := j.Grace
+	if grace <= 0 {
+		grace = defaultGrace
+	}
+	count, err := file.Svc.CleanOrphans(ctx, grace, j.Limit)
+	if err != nil {
+		log.Warnf("[job] clean orphan files err: %v", err)
+		return err
+	}
+	log.Infof("[job] clean orphan files done, count: %d", count)
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/1024casts/snake/cmd/job/apikey"
+	"github.com/1024casts/snake/cmd/job/campaign"
+	"github.com/1024casts/snake/cmd/job/demo"
+	"github.com/1024casts/snake/cmd/job/file"
+	"github.com/1024casts/snake/cmd/job/notification"
+	"github.com/1024casts/snake/cmd/job/saga"
+	"github.com/1024casts/snake/cmd/job/segment"
+	"github.com/1024casts/snake/cmd/job/user"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Entry 注册的计划任务
+type Entry struct {
+	// Name 任务名称，命令行中通过名称指定任务
+	Name string
+	// Spec cron 表达式，支持 @every、@daily 等描述符
+	Spec string
+	Job  cron.Job
+	// Wrappers 执行时的包装，如跳过上一次还没执行完的任务
+	Wrappers []cron.JobWrapper
+}
+
+// 计划任务
+// see: https://mp.weixin.qq.com/s/Ak7RBv1NuS-VBeDNo8_fww
+//
+// cron 内置3个用得比较多的JobWrapper：
+//
+// Recover：捕获内部Job产生的 panic；
+// DelayIfStillRunning：触发时，如果上一次任务还未执行完成（耗时太长），则等待上一次任务完成之后再执行；
+// SkipIfStillRunning：触发时，如果上一次任务还未完成，则跳过此次执行。
+func entries() []Entry {
+	skip := []cron.JobWrapper{cron.SkipIfStillRunning(cron.DefaultLogger)}
+	return []Entry{
+		// demo
+		{Name: "demo", Spec: "*/5 * * * *", Job: cron.FuncJob(func() {
+			log.Infof("test cron, time: %d ", time.Now().Unix())
+		})},
+		// test recover
+		{Name: "demo_panic", Spec: "@every 1s", Job: &demo.PanicJob{},
+			Wrappers: []cron.JobWrapper{cron.Recover(cron.DefaultLogger)}},
+		// test DelayIfStillRunning
+		{Name: "demo_delay", Spec: "@every 1s", Job: &demo.DelayJob{},
+			Wrappers: []cron.JobWrapper{cron.DelayIfStillRunning(cron.DefaultLogger)}},
+		// test SkipIfStillRunning
+		{Name: "demo_skip", Spec: "@every 1s", Job: &demo.SkipJob{}, Wrappers: skip},
+		// 执行具体的任务
+		{Name: "demo_greeting", Spec: "@every 3s", Job: demo.GreetingJob{Name: "dj"}},
+
+		// 预热热点用户cache, 间隔需小于用户cache的过期时间
+		{Name: "warm_user_cache", Spec: "@every 1h", Job: user.WarmCacheJob{Limit: 100}, Wrappers: skip},
+		// 按关注关系修正关注数和粉丝数，计数缓冲丢失增量或重复累加时产生的偏差在这里恢复，凌晨低峰期执行
+		{Name: "user_stat_reconcile", Spec: "30 3 * * *", Job: user.ReconcileStatJob{BatchSize: 500}, Wrappers: skip},
+		// 发送摘要通知，比如一天内的新粉丝合并成一条
+		{Name: "notification_digest", Spec: "@daily", Job: notification.DigestJob{}, Wrappers: skip},
+		// 分批发送系统公告，发送进度记录在公告表中，中断后会继续发送
+		{Name: "announcement", Spec: "@every 1m", Job: notification.AnnouncementJob{BatchSize: 500}, Wrappers: skip},
+		// 重新计算用户分群，活跃度、粉丝数等规则依赖的数据会变化
+		{Name: "segment_materialize", Spec: "@every 1h", Job: segment.MaterializeJob{}, Wrappers: skip},
+		// 清理上传后没有被引用的文件，如上传了头像但没有使用
+		{Name: "file_clean_orphan", Spec: "@every 1h", Job: file.CleanOrphanJob{Limit: 500}, Wrappers: skip},
+		// 召回一段时间没有登录的用户，每天上午发送，避开休息时间
+		{Name: "campaign", Spec: "0 10 * * *", Job: campaign.CampaignJob{}, Wrappers: skip},
+		// 回收注销超过宽限期的帐号占用的用户名、邮箱，之后可以被重新注册
+		{Name: "user_reclaim", Spec: "@every 1h", Job: user.ReclaimJob{Limit: 500}, Wrappers: skip},
+		// 提醒即将过期的 api key，每个 key 只提醒一次
+		{Name: "api_key_expiry", Spec: "@every 1h", Job: apikey.ExpiryJob{Limit: 500}, Wrappers: skip},
+		// 补偿中断的 saga，如开通会员时进程崩溃，已完成的步骤会被回滚
+		{Name: "saga_recover", Spec: "@every 5m", Job: saga.RecoverJob{Limit: 100}, Wrappers: skip},
+	}
+}
@@ -1,44 +1,150 @@
-package job
+package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 
-	"github.com/1024casts/snake/cmd/job/demo"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/cronspec"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/lifecycle"
 	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/storage"
+	"github.com/1024casts/snake/pkg/ws"
 )
 
-// 计划任务
-// see: https://mp.weixin.qq.com/s/Ak7RBv1NuS-VBeDNo8_fww
-//
-// cron 内置3个用得比较多的JobWrapper：
-//
-// Recover：捕获内部Job产生的 panic；
-// DelayIfStillRunning：触发时，如果上一次任务还未执行完成（耗时太长），则等待上一次任务完成之后再执行；
-// SkipIfStillRunning：触发时，如果上一次任务还未完成，则跳过此次执行。
+var (
+	cfg     = pflag.StringP("config", "c", "", "snake config file path.")
+	timeout = pflag.Duration("timeout", 10*time.Minute, "timeout of job run.")
+)
+
+const usage = `usage: job -c config.yaml [command]
+
+commands:
+  (none)      start the scheduler
+  validate    parse all job specs and print next run times, exit non-zero on invalid specs
+  run <name>  run one job immediately with debug logging, e.g. job -c config.yaml run campaign --timeout 5m`
+
+// 计划任务，按 entries 中注册的 cron 表达式执行
 func main() {
-	c := cron.New()
-	// demo
-	_, err := c.AddFunc("* */5 * * *", func() {
-		log.Infof("test cron, time: %d ", time.Now().Unix())
-	})
-	if err != nil {
-		log.Warnf("cron AddFunc err, %+v", err)
-		return
+	pflag.Parse()
+
+	if err := conf.Init(*cfg); err != nil {
+		panic(err)
 	}
 
-	// test recover
-	c.AddJob("@every 1s", cron.NewChain(cron.Recover(cron.DefaultLogger)).Then(&demo.PanicJob{}))
+	args := pflag.Args()
+	if len(args) > 0 {
+		var err error
+		switch args[0] {
+		case "validate":
+			err = validate(os.Stdout)
+		case "run":
+			if len(args) != 2 {
+				err = errors.New(usage)
+				break
+			}
+			e, ok := findEntry(args[1])
+			if !ok {
+				err = fmt.Errorf("unknown job: %s, run `job validate` to list registered jobs", args[1])
+				break
+			}
+			forceDebugLog()
+			initDeps()
+			var r *resources
+			if r, err = newResources(e.Name); err != nil {
+				break
+			}
+			err = runOnce(e, r, *timeout)
+			r.Close()
+		default:
+			err = errors.New(usage)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// test DelayIfStillRunning
-	c.AddJob("@every 1s", cron.NewChain(cron.DelayIfStillRunning(cron.DefaultLogger)).Then(&demo.DelayJob{}))
+	initDeps()
 
-	// test SkipIfStillRunning
-	c.AddJob("@every 1s", cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(&demo.SkipJob{}))
+	loc, err := cronspec.Location(viper.GetString("job.timezone"))
+	if err != nil {
+		panic(err)
+	}
+	c := cron.New(cron.WithLocation(loc))
+	s := newScheduler(c)
+	var scoped []*resources
+	for _, e := range entries() {
+		schedule, err := cronspec.Parse(e.Spec)
+		if err != nil {
+			panic(fmt.Sprintf("job %s: %v", e.Name, err))
+		}
+		r, err := newResources(e.Name)
+		if err != nil {
+			panic(fmt.Sprintf("job %s: %v", e.Name, err))
+		}
+		if r != nil {
+			scoped = append(scoped, r)
+		}
+		s.add(e, schedule, r)
+	}
+	// 退出时先停止管理接口和调度，等待执行中的任务完成，再关闭任务的资源和连接池
+	lc := lifecycle.Init()
+	lc.Add(model.Hook())
+	lc.Add(redis.Hook())
+	lc.Append("resources", func(ctx context.Context) error {
+		for _, r := range scoped {
+			r.Close()
+		}
+		return nil
+	})
+	lc.Add(lifecycle.Hook{
+		Name: "cron",
+		OnStart: func(ctx context.Context) error {
+			c.Start()
+			log.Infof("[job] scheduler started, jobs: %d, timezone: %s", len(c.Entries()), loc)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Info("[job] waiting for running jobs...")
+			select {
+			case <-c.Stop().Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	if srv := serveHTTP(s); srv != nil {
+		lc.Add(lifecycle.HTTP(srv))
+	}
 
-	// 执行具体的任务
-	c.AddJob("@every 3s", demo.GreetingJob{"dj"})
+	if err := lc.Run(); err != nil {
+		log.Fatalf("[job] %v", err)
+	}
+}
 
-	c.Start()
+// initDeps 初始化任务依赖的组件
+func initDeps() {
+	conf.InitLog()
+	model.Init()
+	redis.Init()
+	storage.Init()
+	queue.Init()
+	experiment.Init()
+	// 通知通过 websocket 推送给在线的用户
+	ws.InitPublisher()
+	limitProcess()
 }
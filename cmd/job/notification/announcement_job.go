@@ -0,0 +1,30 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/announcement"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// AnnouncementJob 定时发送公告
+type AnnouncementJob struct {
+	// BatchSize 每批发送的用户数
+	BatchSize int
+}
+
+// Run 发送公告
+func (j AnnouncementJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 发送公告
+func (j AnnouncementJob) RunContext(ctx context.Context) error {
+	count, err := announcement.Svc.SendAnnouncements(ctx, j.BatchSize)
+	if err != nil {
+		log.Warnf("[job] send announcements err: %v", err)
+		return err
+	}
+	log.Infof("[job] send announcements done, count: %d", count)
+	return nil
+}
@@ -0,0 +1,27 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// DigestJob 定时发送摘要通知，执行间隔即摘要的窗口
+type DigestJob struct{}
+
+// Run 发送摘要
+func (j DigestJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 发送摘要
+func (j DigestJob) RunContext(ctx context.Context) error {
+	count, err := notification.Svc.SendDigest(ctx)
+	if err != nil {
+		log.Warnf("[job] send notification digest err: %v", err)
+		return err
+	}
+	log.Infof("[job] send notification digest done, count: %d", count)
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// limitProcess 限制整个计划任务进程的数据库连接数和 redis 命令速率，
+// 计划任务与 API 服务共用数据库和 redis，回填等重任务不能占满连接
+func limitProcess() {
+	if n := viper.GetInt("job.max_db_conns"); n > 0 {
+		if sqlDB, err := model.DB.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(n)
+		}
+	}
+	redis.LimitRate(redis.RedisClient, redis.NewRateLimiter(viper.GetInt("job.redis_rate")))
+}
+
+// resources 任务独立的资源，在进程内复用
+type resources struct {
+	db      *gorm.DB
+	limiter *redis.RateLimiter
+}
+
+// newResources 按 job.policies 中的配置创建任务独立的连接池和限流器，没有配置时返回 nil
+func newResources(name string) (*resources, error) {
+	var p conf.JobPolicyConfig
+	if err := viper.UnmarshalKey("job.policies."+name, &p); err != nil {
+		return nil, err
+	}
+	if p.MaxDBConns <= 0 && p.RedisRate <= 0 {
+		return nil, nil
+	}
+
+	r := &resources{limiter: redis.NewRateLimiter(p.RedisRate)}
+	if p.MaxDBConns > 0 {
+		db, err := model.NewScopedDB(p.MaxDBConns)
+		if err != nil {
+			return nil, err
+		}
+		r.db = db
+	}
+	return r, nil
+}
+
+// bind 把资源绑定到 ctx，任务中通过 model.WithContext、redis.WithContext 使用
+func (r *resources) bind(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	if r.db != nil {
+		ctx = model.WithScopedDB(ctx, r.db)
+	}
+	return redis.WithRateLimiter(ctx, r.limiter)
+}
+
+// Close 关闭任务独立的连接池
+func (r *resources) Close() {
+	if r == nil || r.db == nil {
+		return
+	}
+	if sqlDB, err := r.db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+// enforce 按任务的资源上限执行，支持 ctx 的任务使用独立的资源，其他任务只受进程整体的上限限制
+func enforce(e Entry, r *resources) cron.Job {
+	if r == nil {
+		return e.Job
+	}
+	j, ok := e.Job.(ContextJob)
+	if !ok {
+		log.Warnf("[job] %s does not support ctx, job.policies.%s is ignored", e.Name, e.Name)
+		return e.Job
+	}
+	return scopedJob{job: j, r: r}
+}
+
+// scopedJob 使用任务独立资源执行的任务，保留 RunContext 以便记录执行结果
+type scopedJob struct {
+	job ContextJob
+	r   *resources
+}
+
+func (s scopedJob) Run() {
+	_ = s.RunContext(context.Background())
+}
+
+func (s scopedJob) RunContext(ctx context.Context) error {
+	return s.job.RunContext(s.r.bind(ctx))
+}
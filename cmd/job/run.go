@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ContextJob 支持 ctx 的任务，job run 执行时 ctx 带有超时时间，按计划执行时使用 context.Background()
+type ContextJob interface {
+	RunContext(ctx context.Context) error
+}
+
+// findEntry 按名称查找任务
+func findEntry(name string) (Entry, bool) {
+	for _, e := range entries() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// forceDebugLog 单独执行任务时输出完整的日志到标准输出
+func forceDebugLog() {
+	viper.Set("log.logger_level", "DEBUG")
+	if writers := viper.GetString("log.writers"); !strings.Contains(writers, "stdout") {
+		viper.Set("log.writers", strings.TrimPrefix(writers+",stdout", ","))
+	}
+}
+
+// runOnce 立即执行一次任务，不经过 Wrappers，资源上限与按计划执行时相同
+// 超时后不再等待，不支持 ctx 的任务会在进程退出时被中断
+func runOnce(e Entry, r *resources, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(r.bind(context.Background()), timeout)
+	defer cancel()
+
+	log.Infof("[job] run %s once, spec: %s, timeout: %s", e.Name, e.Spec, timeout)
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		if j, ok := e.Job.(ContextJob); ok {
+			done <- j.RunContext(ctx)
+			return
+		}
+		e.Job.Run()
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Errorf("[job] run %s failed after %s, err: %v", e.Name, time.Since(start), err)
+			return err
+		}
+		log.Infof("[job] run %s done in %s", e.Name, time.Since(start))
+		return nil
+	case <-ctx.Done():
+		log.Errorf("[job] run %s timed out after %s", e.Name, timeout)
+		return fmt.Errorf("job %s timed out after %s", e.Name, timeout)
+	}
+}
@@ -0,0 +1,40 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/saga"
+)
+
+// defaultTimeout 超过该时间没有进展的 saga 认为已中断
+const defaultTimeout = 10 * time.Minute
+
+// RecoverJob 补偿进程崩溃导致中断或补偿失败的 saga
+type RecoverJob struct {
+	// Timeout 没有进展的时间
+	Timeout time.Duration
+	// Limit 每次处理的个数
+	Limit int
+}
+
+// Run 执行补偿
+func (j RecoverJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 执行补偿，ctx 取消时中断
+func (j RecoverJob) RunContext(ctx context.Context) error {
+	timeout := j.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	count, err := saga.Recover(ctx, time.Now().Add(-timeout), j.Limit)
+	if err != nil {
+		log.Warnf("[job] recover saga err: %v", err)
+		return err
+	}
+	log.Infof("[job] recover saga done, count: %d", count)
+	return nil
+}
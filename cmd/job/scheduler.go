@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/robfig/cron/v3"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// keyPausedJobs 暂停的任务，多个实例共享
+	keyPausedJobs = "snake:job:paused"
+	// prefixJobStatus 任务最近一次执行的状态，完整的 key 为 snake:job:status:<name>
+	prefixJobStatus = "snake:job:status:"
+
+	statusRunning = "running"
+	statusOK      = "ok"
+	statusFailed  = "failed"
+
+	resultPaused = "paused"
+)
+
+// instance 当前实例的标识，记录在执行状态中
+var instance = func() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}()
+
+// jobStatus 任务最近一次执行的状态，任务可能在任意一个实例上执行，所以保存在 redis 中
+type jobStatus struct {
+	Instance   string     `json:"instance"`
+	Result     string     `json:"result"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Duration 执行耗时，单位毫秒
+	Duration int64 `json:"duration"`
+}
+
+// scheduledJob 已注册到 cron 的任务
+type scheduledJob struct {
+	Entry
+	id cron.EntryID
+	// job 带有 Wrappers 和任务锁，不检查暂停状态，手动执行时使用
+	job cron.Job
+	// running 当前实例是否正在执行
+	running int32
+}
+
+// scheduler 计划任务调度，支持暂停、恢复和手动执行
+type scheduler struct {
+	cron *cron.Cron
+	jobs []*scheduledJob
+}
+
+func newScheduler(c *cron.Cron) *scheduler {
+	return &scheduler{cron: c}
+}
+
+// add 注册任务，执行顺序为: 暂停检查 -> Wrappers -> 任务锁 -> 记录状态 -> 任务
+// 任务锁在 Wrappers 的内层，SkipIfStillRunning 等包装跳过的调度不会占用锁
+func (s *scheduler) add(e Entry, schedule cron.Schedule, r *resources) {
+	sj := &scheduledJob{Entry: e}
+	wrappers := append(append([]cron.JobWrapper{}, e.Wrappers...), lockWrappers(e, schedule)...)
+	wrappers = append(wrappers, sj.track)
+	sj.job = cron.NewChain(wrappers...).Then(enforce(e, r))
+	sj.id = s.cron.Schedule(schedule, sj.skipIfPaused(sj.job))
+	s.jobs = append(s.jobs, sj)
+}
+
+// find 按名称查找任务
+func (s *scheduler) find(name string) (*scheduledJob, bool) {
+	for _, sj := range s.jobs {
+		if sj.Name == name {
+			return sj, true
+		}
+	}
+	return nil, false
+}
+
+// skipIfPaused 暂停的任务跳过本次调度，redis 不可用时照常执行
+func (sj *scheduledJob) skipIfPaused(j cron.Job) cron.Job {
+	return cron.FuncJob(func() {
+		paused, err := redis.RedisClient.SIsMember(keyPausedJobs, sj.Name).Result()
+		if err != nil {
+			log.Warnf("[job] %s get paused err: %v", sj.Name, err)
+		}
+		if paused {
+			jobRuns.WithLabelValues(sj.Name, resultPaused).Inc()
+			return
+		}
+		j.Run()
+	})
+}
+
+// track 记录执行状态和耗时，panic 记录后继续抛出，由 Recover 等包装处理
+func (sj *scheduledJob) track(j cron.Job) cron.Job {
+	return cron.FuncJob(func() {
+		atomic.StoreInt32(&sj.running, 1)
+		defer atomic.StoreInt32(&sj.running, 0)
+
+		status := &jobStatus{Instance: instance, Result: statusRunning, StartedAt: time.Now()}
+		sj.saveStatus(status)
+
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				sj.finish(status, err)
+				panic(r)
+			}
+			sj.finish(status, err)
+		}()
+		if cj, ok := j.(ContextJob); ok {
+			err = cj.RunContext(context.Background())
+			return
+		}
+		j.Run()
+	})
+}
+
+// finish 记录执行结果
+func (sj *scheduledJob) finish(status *jobStatus, err error) {
+	now := time.Now()
+	status.FinishedAt = &now
+	status.Duration = now.Sub(status.StartedAt).Milliseconds()
+	status.Result = statusOK
+	if err != nil {
+		status.Result = statusFailed
+		status.Error = err.Error()
+	}
+	sj.saveStatus(status)
+}
+
+func (sj *scheduledJob) saveStatus(status *jobStatus) {
+	data, err := json.Marshal(status)
+	if err == nil {
+		err = redis.RedisClient.Set(prefixJobStatus+sj.Name, data, 0).Err()
+	}
+	if err != nil {
+		log.Warnf("[job] %s save status err: %v", sj.Name, err)
+	}
+}
+
+// lastStatus 最近一次执行的状态，没有执行过时返回 nil
+func (sj *scheduledJob) lastStatus() (*jobStatus, error) {
+	data, err := redis.RedisClient.Get(prefixJobStatus + sj.Name).Bytes()
+	if err == goredis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status jobStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// runNow 在当前实例立即执行一次，和按计划执行一样经过 Wrappers 和任务锁
+func (sj *scheduledJob) runNow() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("[job] run %s manually panic: %v", sj.Name, r)
+			}
+		}()
+		log.Infof("[job] run %s manually", sj.Name)
+		sj.job.Run()
+	}()
+}
@@ -0,0 +1,27 @@
+package segment
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/segment"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// MaterializeJob 定时按规则重新计算所有用户分群
+type MaterializeJob struct{}
+
+// Run 计算分群
+func (j MaterializeJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 计算分群
+func (j MaterializeJob) RunContext(ctx context.Context) error {
+	count, err := segment.Svc.MaterializeAll(ctx)
+	if err != nil {
+		log.Warnf("[job] materialize segments err: %v", err)
+		return err
+	}
+	log.Infof("[job] materialize segments done, count: %d", count)
+	return nil
+}
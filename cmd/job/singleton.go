@@ -0,0 +1,80 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/lock"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// defaultLockTTL 任务锁的默认过期时间，执行期间自动续期
+const defaultLockTTL = 30 * time.Second
+
+const (
+	resultRun       = "run"
+	resultSkipped   = "skipped"
+	resultLockError = "lock_error"
+)
+
+var jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "snake",
+	Subsystem: "job",
+	Name:      "runs_total",
+	Help:      "Total number of scheduled job triggers by result, skipped means another instance holds the lock, paused means the job is paused.",
+}, []string{"job", "result"})
+
+// singleton 多副本部署时同一次调度只有一个实例执行
+// 执行期间锁自动续期，执行完后锁保留到 hold 之后才过期，避免触发时间有偏差的实例再执行一次
+// redis 不可用时跳过本次执行，宁可少执行一次也不重复执行
+func singleton(name string, hold, ttl time.Duration) cron.JobWrapper {
+	return func(j cron.Job) cron.Job {
+		return cron.FuncJob(func() {
+			l := lock.New(redis.RedisClient, "job:"+name, lock.WithTTL(ttl), lock.WithWatchdog())
+			ok, err := l.TryLock()
+			if err != nil {
+				jobRuns.WithLabelValues(name, resultLockError).Inc()
+				log.Warnf("[job] %s lock err, skipped: %v", name, err)
+				return
+			}
+			if !ok {
+				jobRuns.WithLabelValues(name, resultSkipped).Inc()
+				log.Infof("[job] %s is running or has just run on another instance, skipped", name)
+				return
+			}
+
+			start := time.Now()
+			defer func() {
+				if err := l.ReleaseAfter(hold - time.Since(start)); err != nil {
+					log.Warnf("[job] %s release lock err: %v", name, err)
+				}
+			}()
+			jobRuns.WithLabelValues(name, resultRun).Inc()
+			j.Run()
+		})
+	}
+}
+
+// lockHold 执行完后锁的保留时间，为两次调度间隔的 90%
+// @every 的任务在各实例按启动时间计时，触发时间可能相差接近一个间隔
+func lockHold(schedule cron.Schedule, now time.Time) time.Duration {
+	next := schedule.Next(now)
+	return schedule.Next(next).Sub(next) * 9 / 10
+}
+
+// lockWrappers 按 job.lock 的配置返回任务锁，关闭时返回 nil
+func lockWrappers(e Entry, schedule cron.Schedule) []cron.JobWrapper {
+	if viper.GetBool("job.lock.disable") {
+		return nil
+	}
+	ttl := viper.GetDuration("job.lock.ttl")
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	return []cron.JobWrapper{singleton(e.Name, lockHold(schedule, time.Now()), ttl)}
+}
@@ -0,0 +1,40 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// defaultGrace 注销后保留标识的时间，期间可以恢复帐号
+const defaultGrace = 30 * 24 * time.Hour
+
+// ReclaimJob 定时回收已注销帐号占用的用户名、邮箱、手机号
+type ReclaimJob struct {
+	// Limit 每次回收的记录数
+	Limit int
+}
+
+// Run 执行回收
+func (j ReclaimJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 执行回收，宽限期读取 account.deleted_grace_period
+func (j ReclaimJob) RunContext(ctx context.Context) error {
+	grace := viper.GetDuration("account.deleted_grace_period")
+	if grace <= 0 {
+		grace = defaultGrace
+	}
+	count, err := user.Svc.ReclaimDeletedIdentifiers(ctx, grace, j.Limit)
+	if err != nil {
+		log.Warnf("[job] reclaim deleted identifiers err: %v", err)
+		return err
+	}
+	log.Infof("[job] reclaim deleted identifiers done, count: %d", count)
+	return nil
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ReconcileStatJob 定时按关注关系修正用户的关注数和粉丝数
+type ReconcileStatJob struct {
+	// BatchSize 每批统计的用户数
+	BatchSize int
+}
+
+// Run 执行修正
+func (j ReconcileStatJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 执行修正
+func (j ReconcileStatJob) RunContext(ctx context.Context) error {
+	count, err := user.Svc.ReconcileFollowCounts(ctx, j.BatchSize)
+	if err != nil {
+		log.Warnf("[job] reconcile follow counts err: %v, fixed: %d", err, count)
+		return err
+	}
+	log.Infof("[job] reconcile follow counts done, fixed: %d", count)
+	return nil
+}
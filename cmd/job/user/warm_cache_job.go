@@ -0,0 +1,30 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// WarmCacheJob 定时预热热点用户cache
+type WarmCacheJob struct {
+	// Limit 每次预热的用户数
+	Limit int
+}
+
+// Run 执行预热
+func (j WarmCacheJob) Run() {
+	_ = j.RunContext(context.Background())
+}
+
+// RunContext 执行预热
+func (j WarmCacheJob) RunContext(ctx context.Context) error {
+	count, err := user.Svc.WarmHotUserCache(ctx, j.Limit)
+	if err != nil {
+		log.Warnf("[job] warm hot user cache err: %v", err)
+		return err
+	}
+	log.Infof("[job] warm hot user cache done, count: %d", count)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/cronspec"
+)
+
+// validateNextRuns 每个任务打印接下来执行的次数
+const validateNextRuns = 3
+
+// validate 解析所有任务的 cron 表达式，打印在配置的时区下接下来的执行时间，有错误时返回错误
+func validate(w io.Writer) error {
+	loc, err := cronspec.Location(viper.GetString("job.timezone"))
+	if err != nil {
+		return fmt.Errorf("invalid job.timezone: %v", err)
+	}
+	now := time.Now().In(loc)
+	fmt.Fprintf(w, "timezone: %s, now: %s\n\n", loc, now.Format(time.RFC3339))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSPEC\tNEXT RUNS")
+	invalid := 0
+	for _, e := range entries() {
+		schedule, err := cronspec.Parse(e.Spec)
+		if err != nil {
+			invalid++
+			fmt.Fprintf(tw, "%s\t%s\tINVALID: %v\n", e.Name, e.Spec, err)
+			continue
+		}
+		runs := make([]string, 0, validateNextRuns)
+		for _, t := range cronspec.Next(schedule, now, validateNextRuns) {
+			runs = append(runs, t.Format("2006-01-02 15:04:05"))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Name, e.Spec, strings.Join(runs, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid job specs", invalid)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/1024casts/snake/pkg/recorder"
+)
+
+var (
+	file    = pflag.StringP("file", "f", "", "recorded requests file, one json per line.")
+	target  = pflag.StringP("target", "t", "http://localhost:8080", "staging instance to replay against.")
+	headers = pflag.StringArrayP("header", "H", nil, "extra header, e.g. \"Authorization: Bearer xxx\".")
+	ignore  = pflag.StringSlice("ignore", []string{"data.created_at", "data.updated_at"}, "response fields to ignore when comparing.")
+	mask    = pflag.StringSlice("mask-fields", recorder.DefaultMaskFields, "fields masked when recording, masked in replayed responses too.")
+	only    = pflag.String("route", "", "only replay requests of this route, e.g. /v1/users/:id.")
+	verbose = pflag.BoolP("verbose", "v", false, "print matched requests too.")
+)
+
+// 回放录制的请求，对比状态码和响应，有不一致时以非0状态退出
+// e.g. replay -f records.log -t http://staging:8080 -H "Authorization: Bearer xxx"
+func main() {
+	pflag.Parse()
+	if *file == "" {
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	header := make(map[string]string, len(*headers))
+	for _, h := range *headers {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			fmt.Printf("invalid header: %s\n", h)
+			os.Exit(2)
+		}
+		header[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	r := recorder.NewReplayer(*target, header, *ignore, recorder.NewMasker(*mask))
+	var total, matched, skipped, failed int
+	err = recorder.ReadRecords(f, func(rec *recorder.Record) error {
+		if rec.Truncated || (*only != "" && rec.Route != *only) {
+			skipped++
+			return nil
+		}
+
+		total++
+		res := r.Replay(rec)
+		switch {
+		case res.Err != nil:
+			failed++
+			fmt.Printf("ERROR\t%s %s\t%v\n", rec.Method, rec.URL, res.Err)
+		case !res.Match():
+			failed++
+			fmt.Printf("DIFF\t%s %s\t%s\n", rec.Method, rec.URL, strings.Join(res.Diffs, ", "))
+		default:
+			matched++
+			if *verbose {
+				fmt.Printf("OK\t%s %s\n", rec.Method, rec.URL)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("total: %d, matched: %d, mismatched: %d, skipped: %d\n", total, matched, failed, skipped)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/1024casts/snake/cmd/snake/mock"
 	"github.com/1024casts/snake/cmd/snake/new"
 )
 
@@ -17,6 +18,7 @@ func main() {
 	app.Version = Version
 	app.Commands = []cli.Command{
 		new.Cmd,
+		mock.Cmd,
 	}
 	err := app.Run(os.Args)
 	if err != nil {
@@ -0,0 +1,115 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDepth 嵌套结构的最大深度，避免循环引用
+const maxDepth = 5
+
+// baseTime 生成时间的起点，生成的时间在这之后的一年内
+var baseTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	names = []string{"张三", "李四", "王五", "赵六", "孙七", "周八", "吴九", "郑十", "alice", "bob", "carol", "dave"}
+	words = []string{"snake", "golang", "gin", "redis", "mysql", "cache", "queue", "follow", "hello", "world", "你好", "世界"}
+)
+
+// faker 按 schema 生成假数据，rand 的种子相同时生成的数据相同
+type faker struct {
+	spec  *Spec
+	r     *rand.Rand
+	users int
+}
+
+func newFaker(spec *Spec, seed int64, users int) *faker {
+	return &faker{spec: spec, r: rand.New(rand.NewSource(seed)), users: users}
+}
+
+// value 生成符合 schema 的数据，name 为字段名，用于生成更像真实数据的值
+func (f *faker) value(name string, schema *Schema, depth int) interface{} {
+	schema = f.spec.resolve(schema)
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[f.r.Intn(len(schema.Enum))]
+	}
+
+	switch schema.Type {
+	case "array":
+		items := make([]interface{}, 1+f.r.Intn(3))
+		for i := range items {
+			items[i] = f.value(name, schema.Items, depth+1)
+		}
+		return items
+	case "integer":
+		return f.integer(name)
+	case "number":
+		return float64(f.r.Intn(10000)) / 100
+	case "boolean":
+		return f.r.Intn(2) == 1
+	case "string":
+		return f.string(name, schema.Format)
+	default:
+		obj := make(map[string]interface{}, len(schema.Properties))
+		// map 的遍历顺序是随机的，按字段名排序保证同一个种子生成的数据相同
+		keys := make([]string, 0, len(schema.Properties))
+		for k := range schema.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			obj[k] = f.value(k, schema.Properties[k], depth+1)
+		}
+		return obj
+	}
+}
+
+func (f *faker) integer(name string) int {
+	switch {
+	case name == "user_id" || name == "owner_id" || name == "created_by" || strings.HasSuffix(name, "_uid"):
+		// 关联到预置的用户
+		return 1 + f.r.Intn(f.users)
+	case name == "id" || strings.HasSuffix(name, "_id"):
+		return 1 + f.r.Intn(100000)
+	case name == "status" || name == "sex" || strings.HasPrefix(name, "is_"):
+		return f.r.Intn(2)
+	case name == "progress":
+		return f.r.Intn(101)
+	default:
+		return f.r.Intn(1000)
+	}
+}
+
+func (f *faker) string(name, format string) string {
+	switch {
+	case format == "date-time" || strings.HasSuffix(name, "_at") || strings.HasSuffix(name, "time"):
+		return f.time().Format(time.RFC3339)
+	case name == "avatar" || name == "url" || strings.HasSuffix(name, "_url"):
+		return fmt.Sprintf("https://example.com/static/%d.png", 1+f.r.Intn(1000))
+	case name == "email":
+		return fmt.Sprintf("user%d@example.com", 1+f.r.Intn(f.users))
+	case name == "phone":
+		return fmt.Sprintf("138%08d", f.r.Intn(100000000))
+	case name == "username" || strings.HasSuffix(name, "name"):
+		return fmt.Sprintf("%s%d", names[f.r.Intn(len(names))], f.r.Intn(100))
+	case name == "token" || strings.HasSuffix(name, "_token"):
+		return fmt.Sprintf("mock-token-%d", f.r.Int63())
+	default:
+		n := 1 + f.r.Intn(4)
+		s := make([]string, n)
+		for i := range s {
+			s[i] = words[f.r.Intn(len(words))]
+		}
+		return strings.Join(s, " ")
+	}
+}
+
+func (f *faker) time() time.Time {
+	return baseTime.Add(time.Duration(f.r.Int63n(int64(365 * 24 * time.Hour))))
+}
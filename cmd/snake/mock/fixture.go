@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// viewerID 当前登录的用户，mock 不校验 token
+	viewerID uint64 = 1
+	// pageSize 列表每页的数量，和 handler 保持一致
+	pageSize = 10
+	// maxFollowing 每个用户最多关注的人数
+	maxFollowing = 30
+)
+
+// userFollow 同 model.UserFollow
+type userFollow struct {
+	FollowNum int `json:"follow_num"`
+	FansNum   int `json:"fans_num"`
+	IsFollow  int `json:"is_follow"`
+	IsFans    int `json:"is_fans"`
+}
+
+// userInfo 同 model.UserInfo
+type userInfo struct {
+	ID         uint64      `json:"id"`
+	Username   string      `json:"username"`
+	Avatar     string      `json:"avatar"`
+	Sex        int         `json:"sex"`
+	UserFollow *userFollow `json:"user_follow"`
+}
+
+// listResponse 同 handler/v1/user.ListResponse
+type listResponse struct {
+	TotalCount uint64      `json:"total_count"`
+	HasMore    int         `json:"has_more"`
+	PageKey    string      `json:"page_key"`
+	PageValue  int         `json:"page_value"`
+	Items      interface{} `json:"items"`
+}
+
+// follow 关注表或粉丝表的一条记录
+type follow struct {
+	ID uint64
+	// UID 关注或被关注的用户
+	UID uint64
+}
+
+// fixture 预置的用户和关注关系，种子相同时数据相同，翻页、刷新时数据保持一致
+type fixture struct {
+	users     []*userInfo
+	following map[uint64][]*follow
+	followers map[uint64][]*follow
+}
+
+func newFixture(seed int64, n int) *fixture {
+	r := rand.New(rand.NewSource(seed))
+	fx := &fixture{
+		users:     make([]*userInfo, n),
+		following: make(map[uint64][]*follow, n),
+		followers: make(map[uint64][]*follow, n),
+	}
+	for i := range fx.users {
+		fx.users[i] = &userInfo{
+			ID:       uint64(i + 1),
+			Username: fmt.Sprintf("%s%d", names[r.Intn(len(names))], i+1),
+			Avatar:   fmt.Sprintf("https://example.com/static/avatar/%d.png", i+1),
+			Sex:      r.Intn(3),
+		}
+	}
+
+	// 关注关系打乱后再分配记录id，和线上一样不同用户的记录id交错
+	var edges [][2]uint64
+	for i := range fx.users {
+		for _, j := range r.Perm(n)[:r.Intn(min(n, maxFollowing+1))] {
+			if i != j {
+				edges = append(edges, [2]uint64{uint64(i + 1), uint64(j + 1)})
+			}
+		}
+	}
+	r.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+	for i, e := range edges {
+		id := uint64(i + 1)
+		fx.following[e[0]] = append(fx.following[e[0]], &follow{ID: id, UID: e[1]})
+		fx.followers[e[1]] = append(fx.followers[e[1]], &follow{ID: id, UID: e[0]})
+	}
+	for _, lists := range []map[uint64][]*follow{fx.following, fx.followers} {
+		for _, list := range lists {
+			sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+		}
+	}
+	return fx
+}
+
+// user 从 viewer 的角度获取用户信息，不存在时返回 nil
+func (fx *fixture) user(viewer, id uint64) *userInfo {
+	if id == 0 || id > uint64(len(fx.users)) {
+		return nil
+	}
+	u := *fx.users[id-1]
+	u.UserFollow = &userFollow{
+		FollowNum: len(fx.following[id]),
+		FansNum:   len(fx.followers[id]),
+		IsFollow:  fx.isFollowing(viewer, id),
+		IsFans:    fx.isFollowing(id, viewer),
+	}
+	return &u
+}
+
+func (fx *fixture) isFollowing(uid, followedUID uint64) int {
+	for _, f := range fx.following[uid] {
+		if f.UID == followedUID {
+			return 1
+		}
+	}
+	return 0
+}
+
+// list 和关注、粉丝列表接口的分页方式相同：按记录id倒序，返回 id 小于 lastID 的记录
+func (fx *fixture) list(viewer uint64, records []*follow, lastID uint64) *listResponse {
+	items := make([]*userInfo, 0, pageSize)
+	resp := &listResponse{PageKey: "last_id", PageValue: int(lastID)}
+	for _, f := range records {
+		if lastID > 0 && f.ID >= lastID {
+			continue
+		}
+		if len(items) == pageSize {
+			resp.HasMore = 1
+			break
+		}
+		items = append(items, fx.user(viewer, f.UID))
+		resp.PageValue = int(f.ID)
+	}
+	resp.Items = items
+	return resp
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+package mock
+
+import "github.com/urfave/cli"
+
+var Cmd = cli.Command{
+	Name:            "mock",
+	Aliases:         []string{"m"},
+	Usage:           "Serve mock API from the swagger spec",
+	Action:          Serve,
+	SkipFlagParsing: false,
+	UsageText:       MockHelpTemplate,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "f",
+			Value:       "docs/swagger.json",
+			Usage:       "Specify the swagger spec file, generated by swag init",
+			Destination: &option.Spec,
+		},
+		&cli.StringFlag{
+			Name:        "a",
+			Value:       ":8080",
+			Usage:       "Specify the address to listen on",
+			Destination: &option.Addr,
+		},
+		&cli.Int64Flag{
+			Name:        "seed",
+			Value:       1,
+			Usage:       "Specify the seed of fake data, the same seed always generates the same data",
+			Destination: &option.Seed,
+		},
+		&cli.IntFlag{
+			Name:        "users",
+			Value:       50,
+			Usage:       "Specify the number of seeded users",
+			Destination: &option.Users,
+		},
+	},
+}
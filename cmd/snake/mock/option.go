@@ -0,0 +1,15 @@
+package mock
+
+// Option ...
+type Option struct {
+	// swagger spec file
+	Spec string
+	// listen address
+	Addr string
+	// fake data seed
+	Seed int64
+	// seeded users
+	Users int
+}
+
+var option Option
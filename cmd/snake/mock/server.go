@@ -0,0 +1,227 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/1024casts/snake/pkg/util/color"
+)
+
+// 和 pkg/errno 中的错误码保持一致
+const (
+	codeOK           = 0
+	codeUserNotFound = 20102
+)
+
+// envelope 同 handler.Response
+type envelope struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// route 文档中的一个接口，segments 中 {xxx} 形式的为路径参数
+type route struct {
+	method   string
+	path     string
+	segments []string
+	op       *Operation
+	// handle 预置数据的接口，为 nil 时按文档生成数据
+	handle func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+// server 按 swagger 文档返回假数据的 mock 服务
+type server struct {
+	spec    *Spec
+	seed    int64
+	users   int
+	fixture *fixture
+	routes  []*route
+}
+
+// Serve 启动 mock 服务
+func Serve(c *cli.Context) error {
+	if option.Users <= 0 {
+		return errors.New("users must be greater than 0")
+	}
+	spec, err := LoadSpec(option.Spec)
+	if err != nil {
+		fmt.Println(color.Red("Load swagger spec error, please run swag init first"))
+		return err
+	}
+
+	s := newServer(spec, option.Seed, option.Users)
+	fmt.Println(color.Greenf("Mock routes:", len(s.routes)))
+	fmt.Println(color.Greenf("Mock server listening on", option.Addr+spec.BasePath))
+	return http.ListenAndServe(option.Addr, s)
+}
+
+func newServer(spec *Spec, seed int64, users int) *server {
+	s := &server{spec: spec, seed: seed, users: users, fixture: newFixture(seed, users)}
+	fixed := map[string]func(w http.ResponseWriter, r *http.Request, params map[string]string){
+		"GET /users/{id}":           s.getUser,
+		"GET /users/{id}/following": s.followList,
+		"GET /users/{id}/followers": s.followerList,
+	}
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			rt := &route{
+				method:   strings.ToUpper(method),
+				path:     path,
+				segments: strings.Split(strings.Trim(path, "/"), "/"),
+				op:       op,
+			}
+			rt.handle = fixed[rt.method+" "+path]
+			s.routes = append(s.routes, rt)
+		}
+	}
+	// 固定的路径优先于路径参数，如 /admin/queues/dead 优先于 /admin/queues/{topic}
+	sort.Slice(s.routes, func(i, j int) bool {
+		a, b := s.routes[i], s.routes[j]
+		if a.path != b.path {
+			return literals(a.segments) > literals(b.segments) ||
+				literals(a.segments) == literals(b.segments) && a.path < b.path
+		}
+		return a.method < b.method
+	})
+	return s
+}
+
+func literals(segments []string) int {
+	n := 0
+	for _, seg := range segments {
+		if !strings.HasPrefix(seg, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+// match 匹配路径，返回路径参数
+func (rt *route) match(method string, segments []string) (map[string]string, bool) {
+	if rt.method != method || len(rt.segments) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params[seg[1:len(seg)-1]] = segments[i]
+			continue
+		}
+		if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 前端开发时页面和 mock 服务通常不同源
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	log.Printf("[mock] %s %s", r.Method, r.URL.RequestURI())
+
+	if !strings.HasPrefix(r.URL.Path, s.spec.BasePath+"/") {
+		http.Error(w, "the route not found", http.StatusNotFound)
+		return
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, s.spec.BasePath), "/"), "/")
+	for _, rt := range s.routes {
+		params, ok := rt.match(r.Method, segments)
+		if !ok {
+			continue
+		}
+		if rt.handle != nil {
+			rt.handle(w, r, params)
+			return
+		}
+		s.generate(w, r, rt.op)
+		return
+	}
+	http.Error(w, "the route not found", http.StatusNotFound)
+}
+
+// generate 按文档中第一个 2xx 响应的 schema 生成数据，同一个请求每次返回的数据相同
+func (s *server) generate(w http.ResponseWriter, r *http.Request, op *Operation) {
+	status, resp := http.StatusOK, (*Response)(nil)
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if n, err := strconv.Atoi(code); err == nil && n >= 200 && n < 400 {
+			status, resp = n, op.Responses[code]
+			break
+		}
+	}
+
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d %s %s", s.seed, r.Method, r.URL.RequestURI())
+	f := newFaker(s.spec, int64(h.Sum64()), s.users)
+	if resp == nil || resp.Schema == nil {
+		if status >= 300 {
+			w.Header().Set("Location", f.string("url", ""))
+		}
+		w.WriteHeader(status)
+		return
+	}
+	if resp.Schema.Type == "string" && resp.Schema.Ref == "" {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, f.value("", resp.Schema, 0))
+		return
+	}
+	var data interface{}
+	if !isEnvelope(resp.Schema) {
+		data = f.value("", resp.Schema, 0)
+	}
+	writeJSON(w, status, envelope{Code: codeOK, Message: "OK", Data: data})
+}
+
+func (s *server) getUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	id, _ := strconv.ParseUint(params["id"], 10, 64)
+	u := s.fixture.user(viewerID, id)
+	if u == nil {
+		writeJSON(w, http.StatusOK, envelope{Code: codeUserNotFound, Message: "The user was not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, envelope{Code: codeOK, Message: "OK", Data: u})
+}
+
+func (s *server) followList(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s.list(w, r, params, s.fixture.following)
+}
+
+func (s *server) followerList(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s.list(w, r, params, s.fixture.followers)
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request, params map[string]string, lists map[uint64][]*follow) {
+	id, _ := strconv.ParseUint(params["id"], 10, 64)
+	if s.fixture.user(viewerID, id) == nil {
+		writeJSON(w, http.StatusOK, envelope{Code: codeUserNotFound, Message: "The user was not found."})
+		return
+	}
+	lastID, _ := strconv.ParseUint(r.URL.Query().Get("last_id"), 10, 64)
+	writeJSON(w, http.StatusOK, envelope{Code: codeOK, Message: "OK", Data: s.fixture.list(viewerID, lists[id], lastID)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
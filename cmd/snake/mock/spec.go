@@ -0,0 +1,65 @@
+package mock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+const definitionPrefix = "#/definitions/"
+
+// Spec swagger 2.0 文档中 mock 用到的部分
+type Spec struct {
+	BasePath    string                           `json:"basePath"`
+	Paths       map[string]map[string]*Operation `json:"paths"`
+	Definitions map[string]*Schema               `json:"definitions"`
+}
+
+// Operation 接口
+type Operation struct {
+	Summary   string               `json:"summary"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Response 接口的一种响应
+type Response struct {
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Schema 数据结构
+type Schema struct {
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Ref        string             `json:"$ref"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Enum       []interface{}      `json:"enum"`
+}
+
+// LoadSpec 读取 swag init 生成的 swagger.json
+func LoadSpec(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	spec.BasePath = strings.TrimSuffix(spec.BasePath, "/")
+	return &spec, nil
+}
+
+// resolve 解析 $ref，找不到定义时返回 nil
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for i := 0; schema != nil && schema.Ref != "" && i < 10; i++ {
+		schema = s.Definitions[strings.TrimPrefix(schema.Ref, definitionPrefix)]
+	}
+	return schema
+}
+
+// isEnvelope 是否为 handler.Response，即接口本身只声明了外层结构
+func isEnvelope(schema *Schema) bool {
+	return schema != nil && schema.Ref == definitionPrefix+"handler.Response"
+}
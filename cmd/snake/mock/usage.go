@@ -0,0 +1,15 @@
+package mock
+
+const MockHelpTemplate = `
+snake mock [flags]
+The flags are:
+  -f      Swagger spec file, default docs/swagger.json
+  -a      Address to listen on, default :8080
+  -seed   Seed of fake data, the same seed always generates the same data
+  -users  Number of seeded users, default 50
+Examples:
+  # Serve mock API of the current project
+  swag init && snake mock
+  # Serve on another port with other fake data
+  snake mock -a :8081 -seed 2
+`
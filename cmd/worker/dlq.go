@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+const dlqUsage = `usage: worker -c config.yaml dlq <command> [args]
+
+commands:
+  topics                      list topics with dead messages
+  list <topic> [offset]       list dead messages of topic, newest first
+  show <topic> <id>           show message payload and error history
+  replay <topic> <id>...|all  requeue messages to topic
+  discard <topic> <id>...     discard messages`
+
+// runDLQ 死信队列的命令行工具，查看、重放或丢弃处理失败的消息
+func runDLQ(args []string) error {
+	if len(args) == 0 {
+		return errors.New(dlqUsage)
+	}
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "topics":
+		topics, err := dl.DeadTopics(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range topics {
+			fmt.Printf("%s\t%d\n", t.Topic, t.Count)
+		}
+	case args[0] == "list" && len(args) >= 2:
+		offset := 0
+		if len(args) > 2 {
+			offset, _ = strconv.Atoi(args[2])
+		}
+		msgs, err := dl.ListDead(ctx, args[1], offset, 50)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			lastErr := ""
+			if len(msg.Errors) > 0 {
+				lastErr = msg.Errors[len(msg.Errors)-1].Error
+			}
+			fmt.Printf("%s\t%s\t%d\t%s\n", msg.ID, time.Unix(msg.DeadAt, 0).Format(time.RFC3339), msg.Attempts, lastErr)
+		}
+	case args[0] == "show" && len(args) == 3:
+		msg, err := dl.GetDead(ctx, args[1], args[2])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(msg)
+	case args[0] == "replay" && len(args) >= 3:
+		ids := args[2:]
+		if len(ids) == 1 && ids[0] == "all" {
+			ids = nil
+		}
+		n, err := dl.ReplayDead(ctx, args[1], ids...)
+		fmt.Printf("replayed %d messages\n", n)
+		return err
+	case args[0] == "discard" && len(args) >= 3:
+		n, err := dl.DiscardDead(ctx, args[1], args[2:]...)
+		fmt.Printf("discarded %d messages\n", n)
+		return err
+	default:
+		return errors.New(dlqUsage)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package file
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// ScanHandler 文件上传后扫描病毒
+func ScanHandler(ctx context.Context, msg *queue.Message) error {
+	var event file.UploadedEvent
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	return file.Svc.Scan(ctx, event.FileID)
+}
@@ -0,0 +1,26 @@
+package image
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/image"
+	"github.com/1024casts/snake/internal/service/upload"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// ThumbnailHandler 图片上传后生成缩略图
+func ThumbnailHandler(ctx context.Context, msg *queue.Message) error {
+	var event upload.ImageUploadedEvent
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	if err := image.Svc.ProcessImage(ctx, event.Key); err != nil {
+		return err
+	}
+	log.Infof("[worker] image processed, key: %s", event.Key)
+	return nil
+}
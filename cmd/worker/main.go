@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/cmd/worker/file"
+	"github.com/1024casts/snake/cmd/worker/image"
+	"github.com/1024casts/snake/cmd/worker/notification"
+	"github.com/1024casts/snake/cmd/worker/record"
+	"github.com/1024casts/snake/cmd/worker/task"
+	"github.com/1024casts/snake/cmd/worker/user"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/ban"
+	filesvc "github.com/1024casts/snake/internal/service/file"
+	notificationsvc "github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/internal/service/projection"
+	tasksvc "github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/internal/service/upload"
+	usersvc "github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/antivirus"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/nonce"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/recorder"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/search"
+	"github.com/1024casts/snake/pkg/storage"
+	"github.com/1024casts/snake/pkg/ws"
+)
+
+var (
+	cfg = pflag.StringP("config", "c", "", "snake config file path.")
+	// 投影出错修复后，从某个事件偏移量开始重放，重建缓存、搜索索引等，完成后退出
+	replayFrom  = pflag.Int64("replay-from", -1, "replay user events after this offset to rebuild projections, then exit.")
+	projections = pflag.StringSlice("projections", nil, "projections to replay, e.g. cache,search,feed. default all.")
+)
+
+// handlers 消息主题和对应的处理函数
+var handlers = map[string]queue.Handler{
+	// 图片上传后生成缩略图
+	upload.TopicImageUploaded: image.ThumbnailHandler,
+	// 文件上传后扫描病毒
+	filesvc.TopicFileUploaded: file.ScanHandler,
+	// 用户事件更新缓存、搜索索引和动态
+	usersvc.TopicUserEvent: user.ProjectionHandler,
+	// 用户动作归档到数据库并写入审计日志
+	usersvc.TopicUserActivity: user.ActivityArchiveHandler,
+	// 录制的请求写入文件，用于回放
+	recorder.TopicRequestRecorded: record.ArchiveHandler,
+	// 临时封禁到期自动解封
+	ban.TopicBanExpired: user.BanExpiredHandler,
+	// 执行异步任务
+	tasksvc.TopicTaskCreated: task.RunHandler,
+	// 按在线状态把通知分发到 websocket、推送、邮件
+	notificationsvc.TopicNotificationFanout: notification.FanoutHandler,
+}
+
+// 异步任务，消费队列中的消息
+// 每个主题一个 goroutine，收到退出信号后处理完当前消息再退出
+func main() {
+	pflag.Parse()
+
+	if err := conf.Init(*cfg); err != nil {
+		panic(err)
+	}
+	conf.InitLog()
+	model.Init()
+	model.InitRegions()
+	redis.Init()
+	redis.InitRegions()
+	storage.Init()
+	queue.Init()
+	antivirus.Init()
+	search.Init()
+	nonce.Init()
+	// 通知通过 websocket 推送给在线的用户，并按在线状态路由
+	ws.InitPublisher()
+
+	if args := pflag.Args(); len(args) > 0 && args[0] == "dlq" {
+		if err := runDLQ(args[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if *replayFrom >= 0 {
+		replay(ctx, cancel)
+		return
+	}
+
+	// 退出时先停止消费，在 app.shutdown_timeout 内等待处理中的消息完成，再关闭连接池
+	// 不经过负载均衡，不需要等待摘除
+	var wg sync.WaitGroup
+	lc := lifecycle.New(lifecycle.Config{Timeout: viper.GetDuration("app.shutdown_timeout")})
+	lc.Add(model.Hook())
+	lc.Add(redis.Hook())
+	lc.Add(lifecycle.Worker("consumers", func() {
+		for topic, handler := range handlers {
+			wg.Add(1)
+			go func(topic string, handler queue.Handler) {
+				defer wg.Done()
+				log.Infof("[worker] start consuming topic: %s", topic)
+				handler = queue.Chain(handler, queue.Idempotent(nonce.Client, idempotencyTTL()))
+				if err := queue.Default.Consume(ctx, topic, handler); err != nil {
+					log.Errorf("[worker] consume topic %s err: %v", topic, err)
+				}
+			}(topic, handler)
+		}
+	}, func() {
+		cancel()
+		wg.Wait()
+	}))
+
+	if err := lc.Run(); err != nil {
+		log.Fatalf("[worker] %v", err)
+	}
+}
+
+// idempotencyTTL 消息消费记录的保留时间，需要大于消息可能被重复投递的时间
+func idempotencyTTL() time.Duration {
+	ttl := viper.GetDuration("queue.idempotency_ttl")
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return ttl
+}
+
+// replay 重放期间收到退出信号时中断，日志中的偏移量可以用于继续重放
+func replay(ctx context.Context, cancel context.CancelFunc) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	lastID, err := projection.Svc.Replay(ctx, uint64(*replayFrom), *projections)
+	if err != nil {
+		log.Errorf("[worker] replay stopped at offset %d, err: %v", lastID, err)
+		os.Exit(1)
+	}
+	log.Infof("[worker] replay done, last offset: %d", lastID)
+}
@@ -0,0 +1,24 @@
+package notification
+
+import (
+	"context"
+	"errors"
+
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// FanoutHandler 按用户的通知偏好和在线状态把通知分发到各个渠道
+func FanoutHandler(ctx context.Context, msg *queue.Message) error {
+	var event notification.FanoutEvent
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+	if event.Message == nil {
+		return errno.WithKind(errno.KindInvalid, errors.New("empty message"))
+	}
+
+	return notification.Svc.Deliver(ctx, event.UserID, event.Message)
+}
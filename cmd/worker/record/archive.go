@@ -0,0 +1,35 @@
+package record
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/recorder"
+)
+
+var (
+	sink     recorder.Sink
+	sinkErr  error
+	sinkOnce sync.Once
+)
+
+// ArchiveHandler 把各个实例录制的请求统一写入文件，用于回放
+func ArchiveHandler(ctx context.Context, msg *queue.Message) error {
+	rec := &recorder.Record{}
+	if err := msg.Decode(rec); err != nil {
+		log.Warnf("[worker] decode request record err: %v, id: %s", err, msg.ID)
+		return nil
+	}
+
+	sinkOnce.Do(func() {
+		sink, sinkErr = recorder.NewFileSink(viper.GetString("record.file"))
+	})
+	if sinkErr != nil {
+		return sinkErr
+	}
+	return sink.Write(rec)
+}
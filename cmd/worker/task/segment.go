@@ -0,0 +1,28 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/segment"
+	"github.com/1024casts/snake/internal/service/task"
+)
+
+func init() {
+	task.Register(segment.TaskTypeMaterialize, MaterializeSegment)
+}
+
+// MaterializeSegment 计算分群，结果为分群的用户数
+func MaterializeSegment(ctx context.Context, t *model.TaskModel, r task.Reporter) (interface{}, error) {
+	var payload segment.MaterializeTaskPayload
+	if err := json.Unmarshal([]byte(t.Payload), &payload); err != nil {
+		return nil, err
+	}
+
+	count, err := segment.Svc.MaterializeSegment(ctx, payload.SegmentID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"user_count": count}, nil
+}
@@ -0,0 +1,20 @@
+package task
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// RunHandler 执行异步任务，任务的状态和进度由 task 服务记录
+func RunHandler(ctx context.Context, msg *queue.Message) error {
+	var event task.CreatedEvent
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	return task.Svc.Run(ctx, event.TaskID)
+}
@@ -0,0 +1,21 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/model"
+	usersvc "github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// ActivityArchiveHandler 用户动作归档到数据库，stream 裁剪后仍然可以查询
+func ActivityArchiveHandler(ctx context.Context, msg *queue.Message) error {
+	var a model.UserActivityModel
+	if err := msg.Decode(&a); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	return usersvc.Svc.ArchiveActivity(ctx, &a)
+}
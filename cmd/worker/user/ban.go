@@ -0,0 +1,20 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// BanExpiredHandler 临时封禁到期后自动解封
+func BanExpiredHandler(ctx context.Context, msg *queue.Message) error {
+	var event ban.ExpiredEvent
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	return ban.Svc.LiftExpired(ctx, event.BanID)
+}
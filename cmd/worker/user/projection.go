@@ -0,0 +1,21 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/projection"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// ProjectionHandler 用户事件更新缓存、搜索索引和动态
+func ProjectionHandler(ctx context.Context, msg *queue.Message) error {
+	var event model.UserEventModel
+	if err := msg.Decode(&event); err != nil {
+		// 消息格式有误，重试也无法处理，直接进入死信队列
+		return errno.WithKind(errno.KindInvalid, err)
+	}
+
+	return projection.Svc.Apply(ctx, &event)
+}
@@ -25,7 +25,8 @@ CREATE TABLE `user_fans` (
      `updated_at` datetime DEFAULT NULL,
      PRIMARY KEY (`id`),
      UNIQUE KEY `idx_uid_fid` (`user_id`,`follower_uid`),
-     KEY `idx_status_uid` (`status`,`user_id`)
+     KEY `idx_status_uid` (`status`,`user_id`),
+     KEY `idx_uid_status_id` (`user_id`,`status`,`id`) COMMENT '粉丝列表按关注时间分页'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户粉丝表';
 
 LOCK TABLES `user_fans` WRITE;
@@ -73,6 +74,24 @@ VALUES
 UNLOCK TABLES;
 
 
+# Dump of table user_block
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_block`;
+
+CREATE TABLE `user_block` (
+   `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+   `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发起拉黑的人',
+   `blocked_uid` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '被拉黑用户的uid',
+   `status` tinyint(1) unsigned NOT NULL DEFAULT '0' COMMENT '拉黑状态 1:已拉黑 0:取消拉黑',
+   `created_at` datetime DEFAULT NULL,
+   `updated_at` datetime DEFAULT NULL,
+   PRIMARY KEY (`id`),
+   UNIQUE KEY `uniq_uid_buid` (`user_id`,`blocked_uid`),
+   KEY `idx_buid_uid` (`blocked_uid`,`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户拉黑表';
+
+
 # Dump of table user_stat
 # ------------------------------------------------------------
 
@@ -83,11 +102,13 @@ CREATE TABLE `user_stat` (
  `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
  `follow_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '关注数',
  `follower_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '粉丝数',
+ `view_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '主页浏览数',
  `status` tinyint(4) unsigned NOT NULL DEFAULT '1' COMMENT '状态  1:正常',
  `created_at` timestamp NULL DEFAULT NULL,
  `updated_at` timestamp NULL DEFAULT NULL,
  PRIMARY KEY (`id`),
  UNIQUE KEY `uniq_uid` (`user_id`),
+ KEY `idx_uid_follower_count` (`user_id`,`follower_count`) COMMENT '粉丝列表按粉丝数排序时覆盖查询',
  KEY `idx_status` (`status`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户统计表';
 
@@ -106,6 +127,536 @@ VALUES
 UNLOCK TABLES;
 
 
+# Dump of table user_device
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_device`;
+
+CREATE TABLE `user_device` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `device_key` char(32) NOT NULL DEFAULT '' COMMENT '设备标识，设备类型+设备+系统+浏览器的md5',
+    `device_type` varchar(16) NOT NULL DEFAULT '' COMMENT '设备类型 desktop,mobile,tablet,bot,unknown',
+    `device` varchar(64) NOT NULL DEFAULT '' COMMENT '设备型号',
+    `os` varchar(32) NOT NULL DEFAULT '' COMMENT '操作系统',
+    `os_version` varchar(32) NOT NULL DEFAULT '' COMMENT '操作系统版本',
+    `browser` varchar(32) NOT NULL DEFAULT '' COMMENT '浏览器',
+    `user_agent` varchar(512) NOT NULL DEFAULT '' COMMENT '最近一次登录的原始UA',
+    `last_ip` varchar(64) NOT NULL DEFAULT '' COMMENT '最近一次登录的ip',
+    `last_login_at` timestamp NULL DEFAULT NULL COMMENT '最近一次登录时间',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_uid_key` (`user_id`,`device_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户登录设备表';
+
+
+# Dump of table user_email_change
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_email_change`;
+
+CREATE TABLE `user_email_change` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `old_email` varchar(255) NOT NULL DEFAULT '' COMMENT '旧邮箱',
+    `new_email` varchar(255) NOT NULL DEFAULT '' COMMENT '新邮箱',
+    `old_token` char(64) NOT NULL DEFAULT '' COMMENT '旧邮箱确认token的sha256',
+    `new_token` char(64) NOT NULL DEFAULT '' COMMENT '新邮箱确认token的sha256',
+    `old_confirmed_at` timestamp NULL DEFAULT NULL COMMENT '旧邮箱确认时间',
+    `new_confirmed_at` timestamp NULL DEFAULT NULL COMMENT '新邮箱确认时间',
+    `status` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '状态 0:等待确认 1:已完成 2:已取消 3:已过期',
+    `expired_at` timestamp NULL DEFAULT NULL COMMENT '过期时间',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_uid_status` (`user_id`,`status`),
+    KEY `idx_old_token` (`old_token`),
+    KEY `idx_new_token` (`new_token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='修改邮箱申请表';
+
+
+# Dump of table user_oauth
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_oauth`;
+
+CREATE TABLE `user_oauth` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `provider` varchar(32) NOT NULL DEFAULT '' COMMENT '第三方平台 wechat,github,google',
+    `open_id` varchar(128) NOT NULL DEFAULT '' COMMENT '第三方帐号在应用下的唯一标识',
+    `union_id` varchar(128) NOT NULL DEFAULT '' COMMENT '微信开放平台下多个应用共用的标识',
+    `nickname` varchar(255) NOT NULL DEFAULT '' COMMENT '第三方昵称',
+    `avatar` varchar(512) NOT NULL DEFAULT '' COMMENT '第三方头像',
+    `access_token` varchar(512) NOT NULL DEFAULT '',
+    `refresh_token` varchar(512) NOT NULL DEFAULT '',
+    `expired_at` timestamp NULL DEFAULT NULL COMMENT 'access_token 过期时间',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_provider_open_id` (`provider`,`open_id`),
+    KEY `idx_provider_union_id` (`provider`,`union_id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户第三方帐号表';
+
+
+# Dump of table user_activity
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_activity`;
+
+CREATE TABLE `user_activity` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `activity_id` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '由 redis stream 消息 id 换算，毫秒时间戳*1000+序号',
+    `action` varchar(32) NOT NULL DEFAULT '' COMMENT '动作 login,follow,unfollow,profile_update',
+    `detail` varchar(1024) NOT NULL DEFAULT '' COMMENT '详情 json',
+    `ip` varchar(64) NOT NULL DEFAULT '',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_uid_activity_id` (`user_id`,`activity_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户动作归档表';
+
+
+# Dump of table user_api_key
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_api_key`;
+
+CREATE TABLE `user_api_key` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `name` varchar(64) NOT NULL DEFAULT '' COMMENT '名称',
+    `prefix` varchar(16) NOT NULL DEFAULT '' COMMENT 'key 的前缀，用于展示',
+    `key_hash` char(64) NOT NULL DEFAULT '' COMMENT 'key 的 sha256',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0:正常 1:已吊销',
+    `last_used_at` timestamp NULL DEFAULT NULL COMMENT '最近使用时间，每分钟最多更新一次',
+    `expired_at` timestamp NULL DEFAULT NULL COMMENT '过期时间',
+    `reminded_at` timestamp NULL DEFAULT NULL COMMENT '发送过期提醒的时间',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_key_hash` (`key_hash`),
+    KEY `idx_user_id` (`user_id`),
+    KEY `idx_status_expired_at` (`status`,`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户 api key 表';
+
+
+# Dump of table user_username_history
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_username_history`;
+
+CREATE TABLE `user_username_history` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `username` varchar(255) NOT NULL DEFAULT '' COMMENT '修改前的用户名',
+    `created_at` timestamp NULL DEFAULT NULL COMMENT '修改时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_username` (`username`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户名修改记录表';
+
+
+# Dump of table audit_log
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `audit_log`;
+
+CREATE TABLE `audit_log` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '被操作的用户id',
+    `operator_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '执行操作的用户id',
+    `action` varchar(64) NOT NULL DEFAULT '' COMMENT '操作',
+    `detail` text COMMENT '操作详情, json格式',
+    `ip` varchar(64) NOT NULL DEFAULT '' COMMENT '操作ip',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_uid` (`user_id`),
+    KEY `idx_action` (`action`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='审计日志表';
+
+
+# Dump of table notification
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `notification`;
+
+CREATE TABLE `notification` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '接收通知的用户id',
+    `event_type` varchar(64) NOT NULL DEFAULT '' COMMENT '事件类型, 如 new_follower',
+    `ref_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '关联对象的id, 如公告id',
+    `title` varchar(255) NOT NULL DEFAULT '' COMMENT '标题',
+    `content` text COMMENT '内容',
+    `is_read` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已读',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_uid_event` (`user_id`,`event_type`,`is_read`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='站内通知表';
+
+
+# Dump of table notification_delivery
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `notification_delivery`;
+
+CREATE TABLE `notification_delivery` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '接收通知的用户id',
+    `notification_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '站内信id, 没有站内信时为0',
+    `event_type` varchar(64) NOT NULL DEFAULT '' COMMENT '事件类型, 如 new_follower',
+    `ref_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '关联对象的id, 如公告id',
+    `channel` varchar(32) NOT NULL DEFAULT '' COMMENT '渠道 in_app, websocket, push, email',
+    `status` varchar(16) NOT NULL DEFAULT '' COMMENT '状态 sent, failed, skipped, queued',
+    `reason` varchar(255) NOT NULL DEFAULT '' COMMENT '失败或跳过的原因',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_uid` (`user_id`),
+    KEY `idx_notification_id` (`notification_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='通知投递记录表';
+
+
+# Dump of table announcement
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `announcement`;
+
+CREATE TABLE `announcement` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `title` varchar(255) NOT NULL DEFAULT '' COMMENT '标题',
+    `content` text COMMENT '内容',
+    `segment` varchar(16) NOT NULL DEFAULT 'all' COMMENT '目标用户 all:所有用户 vip:会员 region:指定地区 segment:自定义分群',
+    `region` varchar(16) NOT NULL DEFAULT '' COMMENT '目标地区, segment 为 region 时有效',
+    `segment_name` varchar(64) NOT NULL DEFAULT '' COMMENT '用户分群名称, segment 为 segment 时有效',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '状态 0:待发送 1:发送中 2:已完成',
+    `total` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '目标用户数',
+    `sent` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '已发送用户数',
+    `last_user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '已发送的最后一个用户id, 用于中断后继续发送',
+    `created_by` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '创建公告的管理员id',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    `finished_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='系统公告表';
+
+
+# Dump of table user_notify_preference
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_notify_preference`;
+
+CREATE TABLE `user_notify_preference` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `event_type` varchar(64) NOT NULL DEFAULT '' COMMENT '事件类型, 如 new_follower',
+    `channel` varchar(32) NOT NULL DEFAULT '' COMMENT '通知渠道, in_app:站内信 push:推送 email:邮件',
+    `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否开启',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_uid_event_channel` (`user_id`,`event_type`,`channel`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户通知偏好表，只保存修改过的设置';
+
+
+# Dump of table moderation_queue
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `moderation_queue`;
+
+CREATE TABLE `moderation_queue` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `field` varchar(32) NOT NULL DEFAULT '' COMMENT '资料字段 username:用户名 bio:简介 avatar:头像',
+    `old_value` varchar(255) NOT NULL DEFAULT '' COMMENT '修改前的内容',
+    `new_value` varchar(255) NOT NULL DEFAULT '' COMMENT '修改后的内容',
+    `source` varchar(32) NOT NULL DEFAULT '' COMMENT '进入审核的来源 sensitive_word:敏感词 image:图片审核',
+    `reason` varchar(255) NOT NULL DEFAULT '' COMMENT '进入审核的原因, 如命中的敏感词',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '状态 0:待审核 1:通过 2:拒绝',
+    `quarantined` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已隔离 1:修改未生效，审核通过后生效',
+    `reviewer_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '审核的管理员id',
+    `review_reason` varchar(255) NOT NULL DEFAULT '' COMMENT '拒绝原因',
+    `reviewed_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_status` (`status`),
+    KEY `idx_uid` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='资料审核队列';
+
+
+# Dump of table segment
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `segment`;
+
+CREATE TABLE `segment` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `name` varchar(64) NOT NULL DEFAULT '' COMMENT '分群名称, 供公告、功能开关等引用',
+    `description` varchar(255) NOT NULL DEFAULT '' COMMENT '描述',
+    `rules` text COMMENT '分群规则, json格式',
+    `user_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '最近一次计算的用户数',
+    `materialized_at` timestamp NULL DEFAULT NULL COMMENT '最近一次计算时间',
+    `created_by` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '创建分群的管理员id',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户分群表';
+
+
+# Dump of table user_tag
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_tag`;
+
+CREATE TABLE `user_tag` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `tag` varchar(64) NOT NULL DEFAULT '' COMMENT '标签',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_uid_tag` (`user_id`,`tag`),
+    KEY `idx_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户标签表';
+
+
+# Dump of table image_variant
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `image_variant`;
+
+CREATE TABLE `image_variant` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `source_key` varchar(255) NOT NULL DEFAULT '' COMMENT '原图的存储路径',
+    `size` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '尺寸, 如 64、128、256',
+    `format` varchar(16) NOT NULL DEFAULT '' COMMENT '格式 jpeg、png、webp',
+    `storage_key` varchar(255) NOT NULL DEFAULT '' COMMENT '该版本的存储路径',
+    `width` int(10) unsigned NOT NULL DEFAULT '0',
+    `height` int(10) unsigned NOT NULL DEFAULT '0',
+    `file_size` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文件大小, 单位字节',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_source_size_format` (`source_key`,`size`,`format`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='图片版本表';
+
+
+# Dump of table file
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `file`;
+
+CREATE TABLE `file` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '上传者id',
+    `category` varchar(32) NOT NULL DEFAULT '' COMMENT '分类 avatar:头像 media:音视频等大文件',
+    `storage_key` varchar(255) NOT NULL DEFAULT '' COMMENT '存储路径',
+    `hash` char(64) NOT NULL DEFAULT '' COMMENT '内容的 sha256, 用于去重',
+    `size` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '文件大小, 单位字节',
+    `content_type` varchar(128) NOT NULL DEFAULT '',
+    `ref_count` int(10) NOT NULL DEFAULT '0' COMMENT '引用次数, 为0时会被定时清理',
+    `scan_status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '病毒扫描状态 0:待扫描 1:正常 2:已感染',
+    `scan_result` varchar(255) NOT NULL DEFAULT '' COMMENT '命中的病毒特征名',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_storage_key` (`storage_key`),
+    KEY `idx_hash` (`hash`),
+    KEY `idx_ref_updated` (`ref_count`,`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='文件表';
+
+
+# Dump of table user_event
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_event`;
+
+CREATE TABLE `user_event` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '事件偏移量',
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `event_type` varchar(32) NOT NULL DEFAULT '' COMMENT '事件类型 user_created user_updated user_followed user_unfollowed',
+    `ref_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '关联的用户id',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户事件表';
+
+
+# Dump of table nonce
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `nonce`;
+
+CREATE TABLE `nonce` (
+    `key` varchar(255) NOT NULL DEFAULT '' COMMENT '防重放、幂等、消息消费记录的key',
+    `value` text NOT NULL,
+    `expired_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`key`),
+    KEY `idx_expired_at` (`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='nonce表, nonce.driver 为 mysql 时使用';
+
+
+# Dump of table user_membership
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_membership`;
+
+CREATE TABLE `user_membership` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `plan` varchar(32) NOT NULL DEFAULT '' COMMENT '套餐',
+    `order_no` varchar(64) NOT NULL DEFAULT '' COMMENT '支付订单号',
+    `started_at` timestamp NULL DEFAULT NULL,
+    `expired_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_order_no` (`order_no`),
+    KEY `idx_user_expired` (`user_id`,`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户会员表';
+
+
+# Dump of table saga
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `saga`;
+
+CREATE TABLE `saga` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `name` varchar(64) NOT NULL DEFAULT '' COMMENT 'saga 名称, 如 activate_membership',
+    `status` varchar(16) NOT NULL DEFAULT '' COMMENT '状态 running done compensating compensated failed',
+    `step` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '已完成并且没有被补偿的步骤数',
+    `data` text NOT NULL COMMENT '步骤之间传递的数据, json',
+    `error` varchar(1024) NOT NULL DEFAULT '' COMMENT '失败的步骤和原因',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_status_updated` (`status`,`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='saga 执行记录表';
+
+
+# Dump of table user_role
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_role`;
+
+CREATE TABLE `user_role` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `role` varchar(64) NOT NULL DEFAULT '' COMMENT '角色，权限在配置 authz.roles 中定义',
+    `operator_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '授予角色的管理员',
+    `created_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_user_role` (`user_id`,`role`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户角色表';
+
+
+# Dump of table user_ban
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_ban`;
+
+CREATE TABLE `user_ban` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `reason` varchar(255) NOT NULL DEFAULT '' COMMENT '封禁原因',
+    `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '1:封禁中 2:已解封',
+    `operator_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '执行封禁的管理员',
+    `expired_at` timestamp NULL DEFAULT NULL COMMENT '到期时间，为空表示永久封禁',
+    `lifted_by` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '解封的管理员，0 表示到期自动解封',
+    `lifted_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_user_status` (`user_id`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户封禁表';
+
+
+# Dump of table user_appeal
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_appeal`;
+
+CREATE TABLE `user_appeal` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `ban_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `content` varchar(1024) NOT NULL DEFAULT '' COMMENT '申诉内容',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0:待处理 1:通过 2:驳回',
+    `reviewer_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `review_note` varchar(255) NOT NULL DEFAULT '',
+    `reviewed_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_ban_id` (`ban_id`),
+    KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='封禁申诉表';
+
+
+# Dump of table policy
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `policy`;
+
+CREATE TABLE `policy` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `type` varchar(32) NOT NULL DEFAULT '' COMMENT 'tos:用户服务协议 privacy:隐私政策',
+    `version` varchar(32) NOT NULL DEFAULT '' COMMENT '版本号',
+    `title` varchar(255) NOT NULL DEFAULT '',
+    `content` text,
+    `created_by` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布的管理员',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_type_version` (`type`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='协议版本表';
+
+
+# Dump of table user_policy
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_policy`;
+
+CREATE TABLE `user_policy` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `policy_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `type` varchar(32) NOT NULL DEFAULT '',
+    `version` varchar(32) NOT NULL DEFAULT '',
+    `ip` varchar(64) NOT NULL DEFAULT '' COMMENT '同意时的ip',
+    `accepted_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_user_policy` (`user_id`,`policy_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户同意协议记录表';
+
+
+# Dump of table task
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `task`;
+
+CREATE TABLE `task` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `type` varchar(64) NOT NULL DEFAULT '' COMMENT '任务类型',
+    `owner_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '提交任务的用户',
+    `payload` text COMMENT '任务参数',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '0:等待执行 1:执行中 2:成功 3:失败',
+    `progress` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '进度百分比',
+    `result` text COMMENT '执行结果',
+    `error` varchar(1024) NOT NULL DEFAULT '' COMMENT '失败原因',
+    `started_at` timestamp NULL DEFAULT NULL,
+    `finished_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_owner` (`owner_id`),
+    KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='异步任务表';
+
+
 # Dump of table users
 # ------------------------------------------------------------
 
@@ -116,15 +667,22 @@ CREATE TABLE `user_base` (
      `username` varchar(255) NOT NULL DEFAULT '',
      `password` varchar(60) NOT NULL DEFAULT '',
      `avatar` varchar(255) NOT NULL DEFAULT '' COMMENT '头像',
+     `bio` varchar(255) NOT NULL DEFAULT '' COMMENT '个人简介',
      `phone` bigint(20) unsigned NOT NULL DEFAULT '0' COMMENT '手机号',
      `email` varchar(255) NOT NULL DEFAULT '' COMMENT '邮箱',
+     `email_verified` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '邮箱是否已验证 0:否 1:是',
      `sex` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '性别 0:未知 1:男 2:女',
-     `deleted_at` timestamp NULL DEFAULT NULL,
+     `plan` varchar(16) NOT NULL DEFAULT 'free' COMMENT '套餐 free:免费 vip:会员',
+     `region` varchar(16) NOT NULL DEFAULT '' COMMENT '所在地区, 如 cn、us',
+     `birthday` date DEFAULT NULL COMMENT '生日，设置后不能修改',
+     `version` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '资料版本号，每次修改加1，用于乐观锁',
+     `status` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '状态 0:正常 1:已停用',
+     `deleted_at` timestamp NULL DEFAULT NULL COMMENT '停用帐号时软删除，宽限期内可以恢复',
      `created_at` timestamp NULL DEFAULT NULL,
      `updated_at` timestamp NULL DEFAULT NULL,
      PRIMARY KEY (`id`),
      UNIQUE KEY `uniq_username` (`username`),
-     UNIQUE KEY `uniq_phone` (`phone`)
+     KEY `idx_phone` (`phone`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户表';
 
 LOCK TABLES `users` WRITE;
@@ -143,6 +701,39 @@ UNLOCK TABLES;
 
 
 
+# Dump of table user_identity
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `user_identity`;
+
+CREATE TABLE `user_identity` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
+    `provider` varchar(32) NOT NULL DEFAULT '' COMMENT '登录方式 email,phone 或第三方平台',
+    `identifier` varchar(255) NOT NULL DEFAULT '' COMMENT '标识，邮箱、手机号或第三方平台的用户id',
+    `verified` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '是否已验证 0:否 1:是',
+    `created_at` timestamp NULL DEFAULT NULL,
+    `updated_at` timestamp NULL DEFAULT NULL,
+    `deleted_at` timestamp NULL DEFAULT NULL COMMENT '注销帐号时软删除，宽限期后 identifier 改为 deleted:<id>:<原标识> 释放唯一索引',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_provider_identifier` (`provider`,`identifier`),
+    UNIQUE KEY `uniq_uid_provider` (`user_id`,`provider`),
+    KEY `idx_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='用户登录身份表';
+
+# 从用户表迁移已有的邮箱和手机号
+INSERT INTO `user_identity` (`user_id`, `provider`, `identifier`, `verified`, `created_at`, `updated_at`)
+SELECT `id`, 'email', `email`, 1, NOW(), NOW() FROM `user_base` WHERE `email` != '';
+
+INSERT INTO `user_identity` (`user_id`, `provider`, `identifier`, `verified`, `created_at`, `updated_at`)
+SELECT `id`, 'phone', `phone`, 1, NOW(), NOW() FROM `user_base` WHERE `phone` > 0;
+
+# 增加邮箱验证前注册的用户视为已验证
+# ALTER TABLE `user_base` ADD COLUMN `email_verified` tinyint(4) unsigned NOT NULL DEFAULT '0' COMMENT '邮箱是否已验证 0:否 1:是' AFTER `email`;
+UPDATE `user_base` SET `email_verified` = 1 WHERE `email` != '';
+
+
+
 /*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
 /*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
 /*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;
@@ -5,8 +5,14 @@ import (
 	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/pkg/constvar"
 	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/startup"
 )
 
 // Response api的返回结构体
@@ -14,18 +20,61 @@ type Response struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	// Kind 错误分类，客户端按分类决定是否重试，成功时为空
+	Kind string `json:"kind,omitempty"`
 }
 
+// ContextKeyCode 返回的错误码，供中间件统计使用
+const ContextKeyCode = "errno_code"
+
 // SendResponse 返回json
 func SendResponse(c *gin.Context, err error, data interface{}) {
+	// always return http.StatusOK
+	SendStatusResponse(c, http.StatusOK, err, data)
+}
+
+// SendStatusResponse 返回json并指定http状态码，用于客户端需要按状态码处理的错误，如 412
+func SendStatusResponse(c *gin.Context, status int, err error, data interface{}) {
 	code, message := errno.DecodeErr(err)
+	c.Set(ContextKeyCode, code)
 
-	// always return http.StatusOK
-	c.JSON(http.StatusOK, Response{
+	resp := Response{
 		Code:    code,
 		Message: message,
 		Data:    data,
-	})
+	}
+	if code != errno.OK.Code {
+		resp.Kind = errno.KindOf(err).String()
+	}
+	c.JSON(status, resp)
+}
+
+// unavailableRetryAfter 依赖不可用时建议客户端重试的间隔，单位秒
+const unavailableRetryAfter = "1"
+
+// SendError 按错误分类返回，业务错误直接返回给客户端
+// 依赖不可用时返回 503，未分类的错误记录日志后统一返回内部错误
+func SendError(c *gin.Context, err error) {
+	kind := errno.KindOf(err)
+	if e, ok := errors.Cause(err).(*errno.Errno); ok {
+		if kind == errno.KindUnavailable {
+			c.Header(constvar.XRetryAfter, unavailableRetryAfter)
+			SendStatusResponse(c, http.StatusServiceUnavailable, e, nil)
+			return
+		}
+		SendResponse(c, e, nil)
+		return
+	}
+
+	switch kind {
+	case errno.KindUnavailable:
+		log.Warnf("[handler] dependency unavailable: %+v", err)
+		c.Header(constvar.XRetryAfter, unavailableRetryAfter)
+		SendStatusResponse(c, http.StatusServiceUnavailable, errno.ErrDependencyUnavailable, nil)
+	default:
+		log.Warnf("[handler] service err: %+v", err)
+		SendResponse(c, errno.InternalServerError, nil)
+	}
 }
 
 // GetUserID 返回用户id
@@ -46,6 +95,55 @@ func GetUserID(c *gin.Context) uint64 {
 	return 0
 }
 
+// GetImpersonatorID 返回模拟登录的管理员id，不是模拟登录时返回0
+func GetImpersonatorID(c *gin.Context) uint64 {
+	if c == nil {
+		return 0
+	}
+
+	// impersonator_id 必须和 middleware/auth 中的命名一致
+	if v, exists := c.Get("impersonator_id"); exists {
+		if id, ok := v.(uint64); ok {
+			return id
+		}
+	}
+	return 0
+}
+
+// GetAPIKeyID 返回请求使用的 api key id，通过 token 访问时返回0
+func GetAPIKeyID(c *gin.Context) uint64 {
+	if c == nil {
+		return 0
+	}
+
+	// api_key_id 必须和 middleware/auth 中的命名一致
+	if v, exists := c.Get("api_key_id"); exists {
+		if id, ok := v.(uint64); ok {
+			return id
+		}
+	}
+	return 0
+}
+
+// GetRegion 返回 token 中用户所在的地区，旧的 token 没有地区时返回空
+func GetRegion(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+
+	// region 必须和 middleware/auth 中的命名一致
+	return c.GetString("region")
+}
+
+// GetFormat 查看者的输出格式，格式和时区可以通过请求头或参数指定，未指定时区时使用登录用户所在地区的时区
+func GetFormat(c *gin.Context) idl.Format {
+	mode := c.GetHeader(constvar.XTimeFormat)
+	if mode == "" {
+		mode = c.Query("time_format")
+	}
+	return idl.NewFormat(mode, c.GetHeader(constvar.XTimezone), c.GetHeader("Accept-Language"), GetRegion(c))
+}
+
 // RouteNotFound 未找到相关路由
 func RouteNotFound(c *gin.Context) {
 	c.String(http.StatusNotFound, "the route not found")
@@ -62,11 +160,32 @@ func getHostname() string {
 
 // healthCheckResponse 健康检查响应结构体
 type healthCheckResponse struct {
-	Status   string `json:"status"`
-	Hostname string `json:"hostname"`
+	Status       string           `json:"status"`
+	Hostname     string           `json:"hostname"`
+	Dependencies []startup.Status `json:"dependencies,omitempty"`
 }
 
 // HealthCheck will return OK if the underlying BoltDB is healthy. At least healthy enough for demoing purposes.
 func HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, healthCheckResponse{Status: "UP", Hostname: getHostname()})
 }
+
+// ReadyCheck 就绪检查，启动时依赖还未就绪返回 503 和各依赖的状态
+// 开始退出后返回 503，负载均衡摘除实例后不再有新请求进来
+func ReadyCheck(c *gin.Context) {
+	resp := healthCheckResponse{Status: "UP", Hostname: getHostname()}
+	if startup.Client != nil {
+		resp.Dependencies = startup.Client.Status()
+	}
+	if lifecycle.Stopping() {
+		resp.Status = "STOPPING"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	if !startup.Ready() {
+		resp.Status = "STARTING"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/authz"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/quota"
+	"github.com/1024casts/snake/pkg/ratelimit"
+)
+
+// ImpersonateRequest 模拟登录请求
+type ImpersonateRequest struct {
+	Reason string `json:"reason" form:"reason" binding:"required"`
+}
+
+// ImpersonateResponse 模拟登录响应
+type ImpersonateResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// CreateAnnouncementRequest 创建公告请求
+type CreateAnnouncementRequest struct {
+	Title   string `json:"title" form:"title" binding:"required" example:"系统维护通知"`
+	Content string `json:"content" form:"content" binding:"required"`
+	// Segment 目标用户 all:所有用户 vip:会员 region:指定地区 segment:自定义分群
+	Segment     string `json:"segment" form:"segment" binding:"required" example:"all"`
+	Region      string `json:"region" form:"region" example:"cn"`
+	SegmentName string `json:"segment_name" form:"segment_name" example:"active_vip"`
+}
+
+// ListResponse 通用列表结构
+type ListResponse struct {
+	HasMore   int         `json:"has_more"`
+	PageKey   string      `json:"page_key"`
+	PageValue int         `json:"page_value"`
+	Items     interface{} `json:"items"`
+}
+
+// SegmentRequest 创建、修改用户分群请求
+type SegmentRequest struct {
+	// Name 分群名称，只能包含小写字母、数字和下划线，创建后不能修改
+	Name        string              `json:"name" form:"name" example:"active_vip"`
+	Description string              `json:"description" form:"description"`
+	Rules       *model.SegmentRules `json:"rules" form:"rules" binding:"required"`
+}
+
+// SegmentResponse 用户分群详情
+type SegmentResponse struct {
+	*model.SegmentModel
+	Rules *model.SegmentRules `json:"rules"`
+}
+
+// StartExperimentRequest 开始实验请求
+type StartExperimentRequest struct {
+	// Variants 实验分组，第一个为对照组，按权重分配流量
+	Variants []experiment.Variant `json:"variants" binding:"required"`
+}
+
+// RejectModerationRequest 审核拒绝请求
+type RejectModerationRequest struct {
+	Reason string `json:"reason" form:"reason" example:"用户名包含违规内容"`
+}
+
+// DeadMessageRequest 重放、丢弃死信消息请求
+type DeadMessageRequest struct {
+	IDs []string `json:"ids" form:"ids"`
+	// All 重放主题下的所有消息，只对重放有效
+	All bool `json:"all" form:"all"`
+}
+
+// DeadMessageResponse 重放、丢弃的消息条数
+type DeadMessageResponse struct {
+	Count int `json:"count"`
+}
+
+// ActivateMembershipRequest 开通会员请求
+type ActivateMembershipRequest struct {
+	Plan    string `json:"plan" form:"plan" binding:"required" example:"vip"`
+	OrderNo string `json:"order_no" form:"order_no" binding:"required" example:"202010150001"`
+	Days    int    `json:"days" form:"days" binding:"required" example:"30"`
+}
+
+// RateLimitSubjectRequest 查询、清空限流计数的主体，ip 和 user_id 至少需要一个
+type RateLimitSubjectRequest struct {
+	IP     string `json:"ip" form:"ip" example:"127.0.0.1"`
+	UserID uint64 `json:"user_id" form:"user_id" example:"1"`
+}
+
+// RateLimitCountersResponse 限流计数和配额使用情况
+type RateLimitCountersResponse struct {
+	// Counters ip 的限流计数
+	Counters []*ratelimit.Counter `json:"counters"`
+	// Quota 用户的配额使用情况
+	Quota *quota.Usage `json:"quota,omitempty"`
+}
+
+// DeleteRateLimitRuleRequest 删除限流规则请求
+type DeleteRateLimitRuleRequest struct {
+	Method string `json:"method" form:"method" example:"POST"`
+	Route  string `json:"route" form:"route" binding:"required" example:"/v1/users/follow"`
+}
+
+// BanUserRequest 封禁用户请求
+type BanUserRequest struct {
+	Reason string `json:"reason" form:"reason" binding:"required" example:"发布违规内容"`
+	// Duration 封禁时长(秒)，0为永久封禁
+	Duration int64 `json:"duration" form:"duration" example:"86400"`
+}
+
+// ReviewAppealRequest 处理申诉请求
+type ReviewAppealRequest struct {
+	Note string `json:"note" form:"note" example:"核实后解除封禁"`
+}
+
+// PublishPolicyRequest 发布协议请求
+type PublishPolicyRequest struct {
+	Type    string `json:"type" form:"type" binding:"required" example:"tos"`
+	Version string `json:"version" form:"version" binding:"required" example:"2020-10-01"`
+	Title   string `json:"title" form:"title" binding:"required" example:"用户服务协议"`
+	Content string `json:"content" form:"content"`
+}
+
+// GrantRoleRequest 授予角色请求
+type GrantRoleRequest struct {
+	Role string `json:"role" form:"role" binding:"required" example:"operator"`
+}
+
+// RoleInfo 角色及其权限
+type RoleInfo struct {
+	Name        string             `json:"name"`
+	Permissions []authz.Permission `json:"permissions"`
+}
+
+// LogLevelRequest 修改日志级别请求
+type LogLevelRequest struct {
+	// Module 模块名，为空时修改全局的日志级别
+	Module string `json:"module" form:"module" example:"user_service"`
+	// Level debug、info、warn、error，修改模块的级别时为空表示取消单独设置
+	Level string `json:"level" form:"level" example:"debug"`
+}
+
+// LogLevelResponse 当前的日志级别
+type LogLevelResponse struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/announcement"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// CreateAnnouncement 创建公告
+// @Summary 创建系统公告
+// @Description 按目标用户(所有用户、会员、地区)发送站内通知和推送，创建后由定时任务分批发送
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body CreateAnnouncementRequest true "公告内容"
+// @Success 200 {object} model.AnnouncementModel "公告"
+// @Router /admin/announcements [post]
+func CreateAnnouncement(c *gin.Context) {
+	var req CreateAnnouncementRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("create announcement bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	a, err := announcement.Svc.CreateAnnouncement(c.Request.Context(), handler.GetUserID(c), req.Title, req.Content, req.Segment, req.Region, req.SegmentName)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, a)
+}
+
+// GetAnnouncement 公告详情
+// @Summary 获取公告及发送进度
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "公告id"
+// @Success 200 {object} model.AnnouncementModel "公告"
+// @Router /admin/announcements/{id} [get]
+func GetAnnouncement(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	a, err := announcement.Svc.GetAnnouncement(c.Request.Context(), uint64(id))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, a)
+}
+
+// AnnouncementList 公告列表
+// @Summary 获取公告列表
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param last_id query uint64 false "上一页最后一条公告的id"
+// @Success 200 {object} model.AnnouncementModel "公告"
+// @Router /admin/announcements [get]
+func AnnouncementList(c *gin.Context) {
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	announcements, err := announcement.Svc.GetAnnouncementList(c.Request.Context(), uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get announcement list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(announcements) > limit {
+		hasMore = 1
+		announcements = announcements[0:limit]
+	}
+	pageValue := lastID
+	if len(announcements) > 0 {
+		pageValue = int(announcements[len(announcements)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     announcements,
+	})
+}
+
+// sendBizErr 按错误分类返回，见 handler.SendError
+func sendBizErr(c *gin.Context, err error) {
+	handler.SendError(c, err)
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// AuditLogList 用户的审计日志
+// @Summary 获取用户的审计日志
+// @Description 包括帐号安全相关的操作、管理员的操作，以及登录、关注、修改资料等用户动作
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.AuditLogModel "审计日志"
+// @Router /admin/users/{id}/audit_logs [get]
+func AuditLogList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	logs, err := audit.Svc.GetAuditLogList(c.Request.Context(), uint64(userID), uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get audit log list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(logs) > limit {
+		hasMore = 1
+		logs = logs[0:limit]
+	}
+	pageValue := lastID
+	if len(logs) > 0 {
+		pageValue = int(logs[len(logs)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     logs,
+	})
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// BanUser 封禁用户
+// @Summary 封禁用户
+// @Description 临时封禁到期后自动解封，已在封禁中时覆盖原来的封禁
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body BanUserRequest true "封禁原因和时长"
+// @Success 200 {object} model.UserBanModel "封禁记录"
+// @Router /admin/users/{id}/ban [post]
+func BanUser(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	var req BanUserRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("ban user bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	b, err := ban.Svc.Ban(c.Request.Context(), uint64(userID), handler.GetUserID(c), req.Reason,
+		time.Duration(req.Duration)*time.Second, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, b)
+}
+
+// UnbanUser 解封用户
+// @Summary 解封用户
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/unban [post]
+func UnbanUser(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	err := ban.Svc.Unban(c.Request.Context(), uint64(userID), handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// AppealList 封禁申诉列表
+// @Summary 获取封禁申诉列表
+// @Description 默认返回待处理的申诉
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param status query int false "状态 0:待处理 1:通过 2:驳回"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.UserAppealModel "申诉"
+// @Router /admin/appeals [get]
+func AppealList(c *gin.Context) {
+	status, _ := strconv.Atoi(c.DefaultQuery("status", strconv.Itoa(model.AppealStatusPending)))
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	appeals, err := ban.Svc.GetAppealList(c.Request.Context(), status, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get appeal list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(appeals) > limit {
+		hasMore = 1
+		appeals = appeals[0:limit]
+	}
+	pageValue := lastID
+	if len(appeals) > 0 {
+		pageValue = int(appeals[len(appeals)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     appeals,
+	})
+}
+
+// ApproveAppeal 申诉通过
+// @Summary 封禁申诉通过
+// @Description 通过后解除对应的封禁
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "申诉id"
+// @Param req body ReviewAppealRequest false "处理说明"
+// @Success 200 {object} model.UserAppealModel "申诉"
+// @Router /admin/appeals/{id}/approve [post]
+func ApproveAppeal(c *gin.Context) {
+	reviewAppeal(c, true)
+}
+
+// RejectAppeal 申诉驳回
+// @Summary 封禁申诉驳回
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "申诉id"
+// @Param req body ReviewAppealRequest false "处理说明"
+// @Success 200 {object} model.UserAppealModel "申诉"
+// @Router /admin/appeals/{id}/reject [post]
+func RejectAppeal(c *gin.Context) {
+	reviewAppeal(c, false)
+}
+
+func reviewAppeal(c *gin.Context, approve bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req ReviewAppealRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("review appeal bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	appeal, err := ban.Svc.ReviewAppeal(c.Request.Context(), uint64(id), handler.GetUserID(c), approve, req.Note, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, appeal)
+}
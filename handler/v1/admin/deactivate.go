@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// DeactivateUser 停用帐号
+// @Summary 停用帐号
+// @Description 用户和登录身份一起软删除，查询、关注列表都不再返回该用户，宽限期内可以恢复
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/deactivate [post]
+func DeactivateUser(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	if userID <= 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	err := user.Svc.DeactivateUser(c.Request.Context(), uint64(userID), handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// ReactivateUser 恢复帐号
+// @Summary 恢复已停用的帐号
+// @Description 超过 account.deleted_grace_period 后用户名、邮箱等可能已被回收，不能恢复
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/reactivate [post]
+func ReactivateUser(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	if userID <= 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	err := user.Svc.ReactivateUser(c.Request.Context(), uint64(userID), handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
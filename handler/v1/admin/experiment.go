@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ExperimentList 实验列表
+// @Summary 获取所有 A/B 实验
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} experiment.Experiment "实验"
+// @Router /admin/experiments [get]
+func ExperimentList(c *gin.Context) {
+	experiments, err := experiment.Client.List()
+	if err != nil {
+		log.Warnf("get experiment list err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, nil, ListResponse{Items: experiments})
+}
+
+// StartExperiment 开始实验
+// @Summary 开始 A/B 实验
+// @Description 已存在的实验会按新的分组重新开始，用户按id稳定地分配到分组
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param name path string true "实验名称"
+// @Param req body StartExperimentRequest true "实验分组"
+// @Success 200 {object} experiment.Experiment "实验"
+// @Router /admin/experiments/{name}/start [post]
+func StartExperiment(c *gin.Context) {
+	var req StartExperimentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("start experiment bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	e, err := experiment.Client.Start(c.Param("name"), req.Variants)
+	if err != nil {
+		sendExperimentErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d start experiment %s", handler.GetUserID(c), e.Name)
+	handler.SendResponse(c, nil, e)
+}
+
+// StopExperiment 停止实验
+// @Summary 停止 A/B 实验
+// @Description 停止后所有用户都使用对照组，不再记录曝光
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param name path string true "实验名称"
+// @Success 200 {object} experiment.Experiment "实验"
+// @Router /admin/experiments/{name}/stop [post]
+func StopExperiment(c *gin.Context) {
+	e, err := experiment.Client.Stop(c.Param("name"))
+	if err != nil {
+		sendExperimentErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d stop experiment %s", handler.GetUserID(c), e.Name)
+	handler.SendResponse(c, nil, e)
+}
+
+// sendExperimentErr 实验的错误转换为错误码
+func sendExperimentErr(c *gin.Context, err error) {
+	switch err {
+	case experiment.ErrNotFound:
+		handler.SendResponse(c, errno.ErrExperimentNotFound, nil)
+	case experiment.ErrInvalid:
+		handler.SendResponse(c, errno.ErrParam, nil)
+	default:
+		log.Warnf("[admin] experiment err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+	}
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/graph"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// exportFollowBatchSize 导出关注关系时每批读取的数量
+const exportFollowBatchSize = 1000
+
+// ExportFollowGraph 导出关注关系图
+// @Summary 导出所有有效的关注关系，用于在 igraph、Gephi 中做社区发现等分析
+// @Description 边从关注者指向被关注者，流式返回，数据量大时耗时较长
+// @Tags 管理后台
+// @Produce  plain
+// @Param format query string false "导出格式 graphml(默认)、edgelist"
+// @Success 200 {string} string "关注关系图"
+// @Router /admin/follows/export [get]
+func ExportFollowGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", graph.FormatGraphML)
+	w, err := graph.NewWriter(format, c.Writer)
+	if err != nil {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	c.Header("Content-Type", graph.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=follows.%s", format))
+
+	// 导出耗时较长，不受请求的超时时间限制，客户端断开连接后写入失败时中断
+	total, err := user.Svc.ExportFollowGraph(context.Background(), w, exportFollowBatchSize)
+	if err != nil {
+		log.Warnf("[admin] export follow graph err: %+v, exported: %d", err, total)
+		// 还没有写出内容时可以返回错误，否则只能中断响应
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			handler.SendError(c, err)
+		}
+		return
+	}
+	if err := w.Close(); err != nil {
+		log.Warnf("[admin] close follow graph writer err: %v", err)
+		return
+	}
+	log.Infof("[admin] exported follow graph, admin: %d, format: %s, edges: %d", handler.GetUserID(c), format, total)
+}
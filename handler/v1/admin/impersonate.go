@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Impersonate 模拟用户登录
+// @Summary 管理员模拟用户登录
+// @Description 签发一个短期有效的只读 token，用于排查用户问题，操作会记录到审计日志
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body ImpersonateRequest true "模拟登录原因"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":{"token":"xxx","expires_at":1600000000}}"
+// @Router /admin/users/{id}/impersonate [post]
+func Impersonate(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	if userID <= 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("impersonate bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	adminID := handler.GetUserID(c)
+	tokenStr, expiresAt, err := user.Svc.Impersonate(c.Request.Context(), adminID, uint64(userID), req.Reason, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ImpersonateResponse{
+		Token:     tokenStr,
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
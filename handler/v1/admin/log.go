@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// GetLogLevel 日志级别
+// @Summary 获取当前实例的日志级别
+// @Description modules 为单独设置了级别的模块
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} admin.LogLevelResponse "日志级别"
+// @Router /admin/log/level [get]
+func GetLogLevel(c *gin.Context) {
+	handler.SendResponse(c, nil, LogLevelResponse{Level: log.GetLevel(), Modules: log.GetModuleLevels()})
+}
+
+// SetLogLevel 修改日志级别
+// @Summary 修改当前实例的日志级别，无需重启
+// @Description 只对处理该请求的实例生效，配置文件热加载时会被 log.logger_level 和 log.module_levels 覆盖
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body LogLevelRequest true "模块和级别"
+// @Success 200 {object} admin.LogLevelResponse "日志级别"
+// @Router /admin/log/level [put]
+func SetLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("set log level bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	var err error
+	if req.Module == "" {
+		if req.Level == "" {
+			handler.SendResponse(c, errno.ErrParam, nil)
+			return
+		}
+		err = log.SetLevel(req.Level)
+	} else {
+		err = log.SetModuleLevel(req.Module, req.Level)
+	}
+	if err != nil {
+		log.Warnf("set log level err: %v", err)
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	log.Infof("[admin] admin %d set log level, module: %s, level: %s", handler.GetUserID(c), req.Module, req.Level)
+
+	handler.SendResponse(c, nil, LogLevelResponse{Level: log.GetLevel(), Modules: log.GetModuleLevels()})
+}
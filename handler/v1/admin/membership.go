@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ActivateMembership 开通会员
+// @Summary 支付成功后为用户开通会员
+// @Description 记录会员并修改用户套餐，任一步失败时回滚，同一个订单号重复调用只开通一次
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body ActivateMembershipRequest true "套餐和订单"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/membership [post]
+func ActivateMembership(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	if userID <= 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	var req ActivateMembershipRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("activate membership bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := user.Svc.ActivateMembership(c.Request.Context(), uint64(userID), req.Plan, req.OrderNo, req.Days, handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
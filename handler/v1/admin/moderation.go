@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/moderation"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ModerationList 资料审核队列
+// @Summary 获取资料审核队列
+// @Description 用户名、简介、头像命中敏感词或图片审核的修改，默认返回待审核的记录
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param status query int false "状态 0:待审核 1:通过 2:拒绝"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.ModerationModel "审核记录"
+// @Router /admin/moderations [get]
+func ModerationList(c *gin.Context) {
+	status, _ := strconv.Atoi(c.DefaultQuery("status", strconv.Itoa(model.ModerationStatusPending)))
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	items, err := moderation.Svc.GetModerationList(c.Request.Context(), status, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get moderation list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(items) > limit {
+		hasMore = 1
+		items = items[0:limit]
+	}
+	pageValue := lastID
+	if len(items) > 0 {
+		pageValue = int(items[len(items)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     items,
+	})
+}
+
+// ApproveModeration 审核通过
+// @Summary 资料审核通过
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "审核记录id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/moderations/{id}/approve [post]
+func ApproveModeration(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	err := moderation.Svc.Approve(c.Request.Context(), uint64(id), handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// RejectModeration 审核拒绝
+// @Summary 资料审核拒绝
+// @Description 恢复为修改前的内容，并通知用户
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "审核记录id"
+// @Param req body RejectModerationRequest true "拒绝原因"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/moderations/{id}/reject [post]
+func RejectModeration(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req RejectModerationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("reject moderation bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := moderation.Svc.Reject(c.Request.Context(), uint64(id), handler.GetUserID(c), req.Reason, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// NotificationDeliveryList 用户的通知投递记录
+// @Summary 获取用户的通知投递记录
+// @Description 每条通知在站内信、websocket、推送、邮件各个渠道的投递状态，用于排查用户没有收到通知的问题
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.NotificationDeliveryModel "投递记录"
+// @Router /admin/users/{id}/notification_deliveries [get]
+func NotificationDeliveryList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	deliveries, err := notification.Svc.GetDeliveryList(c.Request.Context(), uint64(userID), uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get notification delivery list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(deliveries) > limit {
+		hasMore = 1
+		deliveries = deliveries[0:limit]
+	}
+	pageValue := lastID
+	if len(deliveries) > 0 {
+		pageValue = int(deliveries[len(deliveries)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     deliveries,
+	})
+}
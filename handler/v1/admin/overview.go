@@ -0,0 +1,26 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/overview"
+)
+
+// Overview 运营概览
+// @Summary 获取运营面板的概览数据
+// @Description 用户数、日活、今日关注数、当前实例的错误率、队列积压、失败的计划任务，结果缓存 admin.overview_cache_ttl
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} overview.Overview "概览数据"
+// @Router /admin/overview [get]
+func Overview(c *gin.Context) {
+	ret, err := overview.Svc.Get(c.Request.Context())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ret)
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/policy"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// PublishPolicy 发布协议
+// @Summary 发布新版本的协议
+// @Description 发布后用户需要重新同意才能继续使用需要登录的接口
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body PublishPolicyRequest true "协议"
+// @Success 200 {object} model.PolicyModel "协议"
+// @Router /admin/policies [post]
+func PublishPolicy(c *gin.Context) {
+	var req PublishPolicyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("publish policy bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	p, err := policy.Svc.Publish(c.Request.Context(), handler.GetUserID(c), req.Type, req.Version, req.Title, req.Content, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, p)
+}
+
+// PolicyList 协议历史版本
+// @Summary 获取协议的历史版本
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param type query string true "协议类型"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.PolicyModel "协议"
+// @Router /admin/policies [get]
+func PolicyList(c *gin.Context) {
+	typ := c.Query("type")
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	policies, err := policy.Svc.GetPolicyList(c.Request.Context(), typ, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get policy list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(policies) > limit {
+		hasMore = 1
+		policies = policies[0:limit]
+	}
+	pageValue := lastID
+	if len(policies) > 0 {
+		pageValue = int(policies[len(policies)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     policies,
+	})
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// DeadTopicList 死信队列的主题
+// @Summary 获取有死信消息的主题
+// @Description 超过最大重试次数的消息进入死信队列，按主题统计条数
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} queue.DeadTopic "主题"
+// @Router /admin/queues/dead [get]
+func DeadTopicList(c *gin.Context) {
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+	topics, err := dl.DeadTopics(c)
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ListResponse{Items: topics})
+}
+
+// DeadMessageList 死信消息列表
+// @Summary 获取主题下的死信消息
+// @Description 按进入死信队列的时间倒序，包含消息内容和每次处理失败的错误
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param topic path string true "主题"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} queue.Message "消息"
+// @Router /admin/queues/dead/{topic} [get]
+func DeadMessageList(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit := 20
+
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+	msgs, err := dl.ListDead(c, c.Param("topic"), offset, limit+1)
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+
+	hasMore := 0
+	if len(msgs) > limit {
+		hasMore = 1
+		msgs = msgs[0:limit]
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "offset",
+		PageValue: offset + len(msgs),
+		Items:     msgs,
+	})
+}
+
+// GetDeadMessage 死信消息详情
+// @Summary 获取死信消息
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param topic path string true "主题"
+// @Param id path string true "消息id"
+// @Success 200 {object} queue.Message "消息"
+// @Router /admin/queues/dead/{topic}/{id} [get]
+func GetDeadMessage(c *gin.Context) {
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+	msg, err := dl.GetDead(c, c.Param("topic"), c.Param("id"))
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, msg)
+}
+
+// ReplayDeadMessages 重放死信消息
+// @Summary 重放死信消息
+// @Description 重新投递到原主题，重试次数清零，保留错误记录，all 为 true 时重放主题下的所有消息
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param topic path string true "主题"
+// @Param req body DeadMessageRequest true "消息id"
+// @Success 200 {object} DeadMessageResponse "重放的条数"
+// @Router /admin/queues/dead/{topic}/replay [post]
+func ReplayDeadMessages(c *gin.Context) {
+	var req DeadMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("replay dead messages bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if len(req.IDs) == 0 && !req.All {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	if req.All {
+		req.IDs = nil
+	}
+
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+	topic := c.Param("topic")
+	n, err := dl.ReplayDead(c, topic, req.IDs...)
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d replay %d dead messages, topic: %s, ids: %v", handler.GetUserID(c), n, topic, req.IDs)
+	handler.SendResponse(c, nil, DeadMessageResponse{Count: n})
+}
+
+// DiscardDeadMessages 丢弃死信消息
+// @Summary 丢弃死信消息
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param topic path string true "主题"
+// @Param req body DeadMessageRequest true "消息id"
+// @Success 200 {object} DeadMessageResponse "丢弃的条数"
+// @Router /admin/queues/dead/{topic}/discard [post]
+func DiscardDeadMessages(c *gin.Context) {
+	var req DeadMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("discard dead messages bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if len(req.IDs) == 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+	topic := c.Param("topic")
+	n, err := dl.DiscardDead(c, topic, req.IDs...)
+	if err != nil {
+		sendQueueErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d discard %d dead messages, topic: %s, ids: %v", handler.GetUserID(c), n, topic, req.IDs)
+	handler.SendResponse(c, nil, DeadMessageResponse{Count: n})
+}
+
+func sendQueueErr(c *gin.Context, err error) {
+	switch err {
+	case queue.ErrMessageNotFound:
+		handler.SendResponse(c, errno.ErrDeadMessageNotFound, nil)
+	default:
+		log.Warnf("[admin] dead letter queue err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+	}
+}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/quota"
+	"github.com/1024casts/snake/pkg/ratelimit"
+)
+
+// RateLimitCounters 限流计数
+// @Summary 查看 ip 的限流计数和用户的配额使用情况
+// @Description 按路由单独计数的限流也会返回，subject 中带上路由
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param ip query string false "ip"
+// @Param user_id query uint64 false "用户id"
+// @Success 200 {object} RateLimitCountersResponse "限流计数和配额"
+// @Router /admin/ratelimit/counters [get]
+func RateLimitCounters(c *gin.Context) {
+	var req RateLimitSubjectRequest
+	if err := c.ShouldBindQuery(&req); err != nil || (req.IP == "" && req.UserID == 0) {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	resp := RateLimitCountersResponse{Counters: make([]*ratelimit.Counter, 0)}
+	if req.IP != "" {
+		if ratelimit.Client == nil {
+			handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+			return
+		}
+		counters, err := ratelimit.Client.Counters("ip:" + req.IP)
+		if err != nil {
+			sendRateLimitErr(c, err)
+			return
+		}
+		resp.Counters = counters
+	}
+	if req.UserID > 0 {
+		if quota.Client == nil {
+			handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+			return
+		}
+		u, err := user.Svc.GetUserByID(c.Request.Context(), req.UserID)
+		if err != nil {
+			sendBizErr(c, err)
+			return
+		}
+		if u.ID == 0 {
+			handler.SendResponse(c, errno.ErrUserNotFound, nil)
+			return
+		}
+		usage, err := quota.Client.Get(quota.UserSubject(req.UserID), u.Plan)
+		if err != nil {
+			sendRateLimitErr(c, err)
+			return
+		}
+		resp.Quota = usage
+	}
+
+	handler.SendResponse(c, nil, resp)
+}
+
+// ResetRateLimit 清空限流计数
+// @Summary 清空 ip 的限流计数或用户本日、本月的配额
+// @Description 用于故障期间误伤的调用方恢复访问，软限制的宽限记录也会被清空
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body RateLimitSubjectRequest true "ip 或用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/ratelimit/reset [post]
+func ResetRateLimit(c *gin.Context) {
+	var req RateLimitSubjectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("reset ratelimit bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if req.IP == "" && req.UserID == 0 {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	if (req.IP != "" && ratelimit.Client == nil) || (req.UserID > 0 && quota.Client == nil) {
+		handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+		return
+	}
+
+	if req.IP != "" {
+		n, err := ratelimit.Client.Reset("ip:" + req.IP)
+		if err != nil {
+			sendRateLimitErr(c, err)
+			return
+		}
+		log.Infof("[admin] admin %d reset ratelimit of ip %s, keys: %d", handler.GetUserID(c), req.IP, n)
+	}
+	if req.UserID > 0 {
+		if err := quota.Client.Reset(quota.UserSubject(req.UserID)); err != nil {
+			sendRateLimitErr(c, err)
+			return
+		}
+		log.Infof("[admin] admin %d reset quota of user %d", handler.GetUserID(c), req.UserID)
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// RateLimitRuleList 限流规则列表
+// @Summary 获取按路由的限流规则
+// @Description 没有规则的路由使用配置中的默认限制
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} ratelimit.Rule "限流规则"
+// @Router /admin/ratelimit/rules [get]
+func RateLimitRuleList(c *gin.Context) {
+	if ratelimit.Client == nil {
+		handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+		return
+	}
+	rules, err := ratelimit.Client.Rules()
+	if err != nil {
+		sendRateLimitErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ListResponse{Items: rules})
+}
+
+// SetRateLimitRule 新增或修改限流规则
+// @Summary 新增或修改路由的限流规则
+// @Description 保存在 redis 中，无需重新部署，其他实例最多延迟10秒生效；有规则的路由单独计数
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body ratelimit.Rule true "限流规则"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/ratelimit/rules [put]
+func SetRateLimitRule(c *gin.Context) {
+	var rule ratelimit.Rule
+	if err := c.ShouldBind(&rule); err != nil {
+		log.Warnf("set ratelimit rule bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if ratelimit.Client == nil {
+		handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+		return
+	}
+
+	if err := ratelimit.Client.SetRule(rule); err != nil {
+		sendRateLimitErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d set ratelimit rule %s %s, limit: %d, window: %ds",
+		handler.GetUserID(c), rule.Method, rule.Route, rule.Limit, rule.Window)
+	handler.SendResponse(c, nil, nil)
+}
+
+// DeleteRateLimitRule 删除限流规则
+// @Summary 删除路由的限流规则
+// @Description 删除后路由恢复使用默认的限制
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body DeleteRateLimitRuleRequest true "路由"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/ratelimit/rules [delete]
+func DeleteRateLimitRule(c *gin.Context) {
+	var req DeleteRateLimitRuleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("delete ratelimit rule bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if ratelimit.Client == nil {
+		handler.SendResponse(c, errno.ErrRateLimitDisabled, nil)
+		return
+	}
+
+	if err := ratelimit.Client.DeleteRule(req.Method, req.Route); err != nil {
+		sendRateLimitErr(c, err)
+		return
+	}
+
+	log.Infof("[admin] admin %d delete ratelimit rule %s %s", handler.GetUserID(c), req.Method, req.Route)
+	handler.SendResponse(c, nil, nil)
+}
+
+// sendRateLimitErr 限流的错误转换为错误码
+func sendRateLimitErr(c *gin.Context, err error) {
+	switch err {
+	case ratelimit.ErrInvalidRule:
+		handler.SendResponse(c, errno.ErrParam, nil)
+	default:
+		log.Warnf("[admin] ratelimit err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+	}
+}
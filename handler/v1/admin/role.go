@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/permission"
+	"github.com/1024casts/snake/pkg/authz"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// RoleList 角色列表
+// @Summary 获取所有角色及其权限
+// @Description 角色在配置 authz.roles 中定义，admin 为内置角色，拥有所有权限
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} RoleInfo "角色"
+// @Router /admin/roles [get]
+func RoleList(c *gin.Context) {
+	policy := authz.Default()
+	roles := make([]*RoleInfo, 0)
+	for _, name := range policy.Roles() {
+		roles = append(roles, &RoleInfo{Name: name, Permissions: policy.Permissions(name)})
+	}
+
+	handler.SendResponse(c, nil, roles)
+}
+
+// UserRoles 用户的角色
+// @Summary 获取用户的角色
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":["operator"]}"
+// @Router /admin/users/{id}/roles [get]
+func UserRoles(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	roles, err := permission.Svc.GetRoles(c.Request.Context(), uint64(userID))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, roles)
+}
+
+// GrantRole 授予角色
+// @Summary 授予用户角色
+// @Description 已拥有该角色时不做处理
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body GrantRoleRequest true "角色"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/roles [post]
+func GrantRole(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	var req GrantRoleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("grant role bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := permission.Svc.GrantRole(c.Request.Context(), uint64(userID), req.Role, handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// RevokeRole 收回角色
+// @Summary 收回用户的角色
+// @Description 配置在 admin.uids 中的管理员的 admin 角色不能收回
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param role path string true "角色"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/users/{id}/roles/{role} [delete]
+func RevokeRole(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	err := permission.Svc.RevokeRole(c.Request.Context(), uint64(userID), c.Param("role"), handler.GetUserID(c), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/handler/v1/task"
+	"github.com/1024casts/snake/internal/service/segment"
+	tasksvc "github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// CreateSegment 创建用户分群
+// @Summary 创建用户分群
+// @Description 按注册时间、活跃度、粉丝数、标签等规则圈选用户，供公告、功能开关使用
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param req body SegmentRequest true "分群规则"
+// @Success 200 {object} model.SegmentModel "用户分群"
+// @Router /admin/segments [post]
+func CreateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("create segment bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	s, err := segment.Svc.CreateSegment(c.Request.Context(), handler.GetUserID(c), req.Name, req.Description, req.Rules)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, s)
+}
+
+// UpdateSegment 修改用户分群
+// @Summary 修改用户分群的规则
+// @Description 修改后会立即重新计算分群的用户
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "分群id"
+// @Param req body SegmentRequest true "分群规则"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /admin/segments/{id} [put]
+func UpdateSegment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req SegmentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("update segment bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := segment.Svc.UpdateSegment(c.Request.Context(), uint64(id), req.Description, req.Rules)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// GetSegment 用户分群详情
+// @Summary 获取用户分群及规则
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "分群id"
+// @Success 200 {object} SegmentResponse "用户分群"
+// @Router /admin/segments/{id} [get]
+func GetSegment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	s, err := segment.Svc.GetSegment(c.Request.Context(), uint64(id))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+	rules, err := s.GetRules()
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, SegmentResponse{SegmentModel: s, Rules: rules})
+}
+
+// SegmentList 用户分群列表
+// @Summary 获取所有用户分群
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.SegmentModel "用户分群"
+// @Router /admin/segments [get]
+func SegmentList(c *gin.Context) {
+	segments, err := segment.Svc.GetSegmentList(c.Request.Context())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ListResponse{Items: segments})
+}
+
+// MaterializeSegment 重新计算用户分群
+// @Summary 立即重新计算用户分群
+// @Description 定时任务会定期计算，需要立即生效时可以手动触发，返回异步任务，通过 /tasks/{id} 查询结果
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "分群id"
+// @Success 200 {object} model.TaskModel "异步任务，结果为 {"user_count":100}"
+// @Router /admin/segments/{id}/materialize [post]
+func MaterializeSegment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if _, err := segment.Svc.GetSegment(c.Request.Context(), uint64(id)); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	t, err := tasksvc.Svc.Submit(c.Request.Context(), segment.TaskTypeMaterialize, handler.GetUserID(c),
+		&segment.MaterializeTaskPayload{SegmentID: uint64(id)})
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	task.Accepted(c, t)
+}
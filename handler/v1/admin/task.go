@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/handler/v1/task"
+	tasksvc "github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// TaskList 异步任务列表
+// @Summary 获取所有用户提交的异步任务
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param type query string false "任务类型"
+// @Param status query int false "状态 0:等待执行 1:执行中 2:成功 3:失败"
+// @Param last_id query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.TaskModel "异步任务"
+// @Router /admin/tasks [get]
+func TaskList(c *gin.Context) {
+	where := make(map[string]interface{})
+	if typ := c.Query("type"); typ != "" {
+		where["type"] = typ
+	}
+	if status := c.Query("status"); status != "" {
+		where["status"], _ = strconv.Atoi(status)
+	}
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 20
+
+	tasks, err := tasksvc.Svc.GetTaskList(c.Request.Context(), where, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get task list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(tasks) > limit {
+		hasMore = 1
+		tasks = tasks[0:limit]
+	}
+	pageValue := lastID
+	if len(tasks) > 0 {
+		pageValue = int(tasks[len(tasks)-1].ID)
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     tasks,
+	})
+}
+
+// GetTask 异步任务详情
+// @Summary 获取任意用户提交的异步任务
+// @Tags 管理后台
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "任务id"
+// @Success 200 {object} model.TaskModel "异步任务"
+// @Router /admin/tasks/{id} [get]
+func GetTask(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	t, err := tasksvc.Svc.GetTask(c.Request.Context(), uint64(id))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	task.Send(c, t)
+}
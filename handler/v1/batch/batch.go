@@ -0,0 +1,166 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const (
+	// Path 批量请求的路由，子请求不能嵌套调用
+	Path = "/v1/batch"
+
+	defaultMaxRequests    = 20
+	defaultMaxConcurrency = 5
+)
+
+// subRequestKey 标记子请求，批量请求的 handler 拒绝带有标记的请求，防止编码后的路径绕过嵌套检查
+type subRequestKey struct{}
+
+// clientIPHeaders 客户端ip相关的请求头，子请求只使用批量请求的值，不能通过 headers 伪造
+var clientIPHeaders = map[string]bool{"X-Forwarded-For": true, "X-Real-Ip": true}
+
+// Request 批量请求
+type Request struct {
+	Requests []*Item `json:"requests" binding:"required"`
+}
+
+// Item 子请求，没有指定 Authorization 时使用批量请求的 Authorization
+type Item struct {
+	Method  string            `json:"method" example:"GET"`
+	Path    string            `json:"path" example:"/v1/users/1"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+// Result 子请求的响应，body 为 json 时原样返回，否则为字符串
+type Result struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+// Handle 批量请求
+// @Summary 批量请求
+// @Description 一次请求执行多个 api，如个人主页需要的资料、统计和关注列表，减少移动端的请求次数
+// @Description 每个子请求都经过完整的中间件(认证、限流等)，按顺序返回每个子请求的响应
+// @Tags 批量请求
+// @Accept  json
+// @Produce  json
+// @Param req body Request true "子请求"
+// @Success 200 {object} handler.Response "data 为按顺序返回的子请求响应 Result"
+// @Router /batch [post]
+func Handle(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Context().Value(subRequestKey{}) != nil {
+			handler.SendResponse(c, errno.ErrParam, nil)
+			return
+		}
+		var req Request
+		if err := c.ShouldBind(&req); err != nil {
+			log.Warnf("batch bind param err: %v", err)
+			handler.SendResponse(c, errno.ErrBind, nil)
+			return
+		}
+		maxRequests := viper.GetInt("batch.max_requests")
+		if maxRequests <= 0 {
+			maxRequests = defaultMaxRequests
+		}
+		if len(req.Requests) == 0 || len(req.Requests) > maxRequests {
+			handler.SendResponse(c, errno.ErrBatchTooMany, nil)
+			return
+		}
+
+		concurrency := viper.GetInt("batch.max_concurrency")
+		if concurrency <= 0 {
+			concurrency = defaultMaxConcurrency
+		}
+		results := make([]*Result, len(req.Requests))
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for i, item := range req.Requests {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, item *Item) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				results[i] = serve(engine, c.Request, item)
+			}(i, item)
+		}
+		wg.Wait()
+
+		handler.SendResponse(c, nil, results)
+	}
+}
+
+// serve 在同一个 engine 中执行子请求
+// 路径检查使用解码并清理后的路径，和路由匹配时使用的一致
+func serve(engine *gin.Engine, parent *http.Request, item *Item) *Result {
+	if item == nil {
+		return errResult(http.StatusBadRequest, errno.ErrParam)
+	}
+	method := strings.ToUpper(item.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	ctx := context.WithValue(parent.Context(), subRequestKey{}, true)
+	r, err := http.NewRequestWithContext(ctx, method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		return errResult(http.StatusBadRequest, errno.ErrParam)
+	}
+	p := path.Clean(r.URL.Path)
+	if !strings.HasPrefix(p, "/v1/") || p == Path || strings.HasPrefix(p, Path+"/") {
+		return errResult(http.StatusBadRequest, errno.ErrParam)
+	}
+	r.RemoteAddr = parent.RemoteAddr
+	for _, h := range []string{"Authorization", "User-Agent", "Accept-Language", "X-Forwarded-For", "X-Real-Ip"} {
+		if v := parent.Header.Get(h); v != "" {
+			r.Header.Set(h, v)
+		}
+	}
+	if len(item.Body) > 0 {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range item.Headers {
+		if clientIPHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		r.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+
+	res := &Result{Status: w.Code, Headers: make(map[string]string, len(w.Header()))}
+	for k := range w.Header() {
+		res.Headers[k] = w.Header().Get(k)
+	}
+	body := w.Body.Bytes()
+	if json.Valid(body) {
+		res.Body = body
+	} else if len(body) > 0 {
+		res.Body, _ = json.Marshal(string(body))
+	}
+	return res
+}
+
+// errResult 不合法的子请求，不会执行
+func errResult(status int, err *errno.Errno) *Result {
+	b, _ := json.Marshal(handler.Response{Code: err.Code, Message: err.Message, Kind: err.Kind.String()})
+	return &Result{Status: status, Body: b}
+}
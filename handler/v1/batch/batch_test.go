@@ -0,0 +1,111 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestHandle(t *testing.T) {
+	asserts := assert.New(t)
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.GET("/v1/users/:id", func(c *gin.Context) {
+		handler.SendResponse(c, nil, gin.H{"id": c.Param("id"), "auth": c.GetHeader("Authorization"), "ip": c.ClientIP()})
+	})
+	g.POST("/v1/echo", func(c *gin.Context) {
+		var body map[string]interface{}
+		_ = c.BindJSON(&body)
+		handler.SendResponse(c, nil, body)
+	})
+	g.POST(Path, Handle(g))
+
+	reqBody, _ := json.Marshal(Request{Requests: []*Item{
+		{Method: "GET", Path: "/v1/users/1"},
+		{Method: "POST", Path: "/v1/echo", Body: json.RawMessage(`{"a":1}`)},
+		{Method: "GET", Path: "/v1/users/2", Headers: map[string]string{"Authorization": "Bearer other", "x-forwarded-for": "1.1.1.1"}},
+		{Method: "POST", Path: Path},
+		{Method: "GET", Path: "/v1/not_found"},
+		{Method: "POST", Path: "/v1/%62atch"},
+		{Method: "POST", Path: "/v1/users/../batch"},
+	}})
+	r := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(reqBody))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer token")
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	var resp struct {
+		Code int       `json:"code"`
+		Data []*Result `json:"data"`
+	}
+	asserts.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	asserts.Equal(0, resp.Code)
+	asserts.Len(resp.Data, 7)
+
+	asserts.Equal(http.StatusOK, resp.Data[0].Status)
+	asserts.JSONEq(`{"code":0,"message":"OK","data":{"id":"1","auth":"Bearer token","ip":"10.0.0.1"}}`, string(resp.Data[0].Body))
+	asserts.JSONEq(`{"code":0,"message":"OK","data":{"a":1}}`, string(resp.Data[1].Body))
+	// 子请求不能伪造客户端ip
+	asserts.JSONEq(`{"code":0,"message":"OK","data":{"id":"2","auth":"Bearer other","ip":"10.0.0.1"}}`, string(resp.Data[2].Body))
+	// 不能嵌套批量请求，编码或包含 .. 的路径也不行
+	asserts.Equal(http.StatusBadRequest, resp.Data[3].Status)
+	asserts.Equal(http.StatusNotFound, resp.Data[4].Status)
+	asserts.Equal(http.StatusBadRequest, resp.Data[5].Status)
+	asserts.Equal(http.StatusBadRequest, resp.Data[6].Status)
+}
+
+// 绕过路径检查的子请求到达批量请求的 handler 时也会被拒绝
+func TestHandle_RejectSubRequest(t *testing.T) {
+	asserts := assert.New(t)
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.POST(Path, Handle(g))
+
+	reqBody, _ := json.Marshal(Request{Requests: []*Item{{Path: "/v1/users/1"}}})
+	r := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(reqBody))
+	r = r.WithContext(context.WithValue(r.Context(), subRequestKey{}, true))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	var resp handler.Response
+	asserts.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	asserts.Equal(errno.ErrParam.Code, resp.Code)
+}
+
+func TestHandle_TooMany(t *testing.T) {
+	asserts := assert.New(t)
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.POST(Path, Handle(g))
+
+	items := make([]*Item, defaultMaxRequests+1)
+	for i := range items {
+		items[i] = &Item{Path: "/v1/users/1"}
+	}
+	reqBody, _ := json.Marshal(Request{Requests: items})
+	r := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(reqBody))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	var resp handler.Response
+	asserts.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	asserts.Equal(errno.ErrBatchTooMany.Code, resp.Code)
+}
@@ -0,0 +1,68 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// defaultPollInterval 默认的轮询间隔
+const defaultPollInterval = 2 * time.Second
+
+// Get 任务状态
+// @Summary 获取异步任务的状态
+// @Description 任务未完成时通过 Retry-After 响应头返回建议的轮询间隔(秒)，status 为2或3时停止轮询
+// @Tags 任务
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "任务id"
+// @Success 200 {object} model.TaskModel "任务 status 0:等待执行 1:执行中 2:成功 3:失败"
+// @Router /tasks/{id} [get]
+func Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	t, err := task.Svc.GetTask(c.Request.Context(), uint64(id))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+	// 只能查看自己提交的任务
+	if t.OwnerID != handler.GetUserID(c) {
+		handler.SendResponse(c, errno.ErrTaskNotFound, nil)
+		return
+	}
+
+	Send(c, t)
+}
+
+// Accepted 返回刚提交的任务，Location 响应头为状态查询地址
+func Accepted(c *gin.Context, t *model.TaskModel) {
+	c.Header(constvar.XLocation, fmt.Sprintf("/v1/tasks/%d", t.ID))
+	Send(c, t)
+}
+
+// Send 返回任务，未完成时带上建议的轮询间隔
+func Send(c *gin.Context, t *model.TaskModel) {
+	if !t.IsFinished() {
+		interval := viper.GetDuration("task.poll_interval")
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+		c.Header(constvar.XRetryAfter, strconv.Itoa(int((interval+time.Second-1)/time.Second)))
+	}
+	handler.SendResponse(c, nil, t)
+}
+
+// sendBizErr 按错误分类返回，见 handler.SendError
+func sendBizErr(c *gin.Context, err error) {
+	handler.SendError(c, err)
+}
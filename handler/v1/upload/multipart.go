@@ -0,0 +1,120 @@
+package upload
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/upload"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// InitMultipart 初始化分片上传
+// @Summary 初始化分片上传
+// @Description 返回 upload_id 和分片大小，客户端按分片大小切分文件后逐片上传
+// @Tags 上传
+// @Accept  json
+// @Produce  json
+// @Param req body InitMultipartRequest true "文件信息"
+// @Success 200 {object} MultipartResponse "上传信息"
+// @Router /uploads/multipart [post]
+func InitMultipart(c *gin.Context) {
+	var req InitMultipartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("bind request param err: %+v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	multipart, err := upload.Svc.InitMultipart(c, handler.GetUserID(c), req.Filename, req.ContentType, req.Size)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, MultipartResponse{MultipartUpload: multipart, Parts: []storage.Part{}})
+}
+
+// UploadPart 上传分片
+// @Summary 上传分片
+// @Description 请求体为分片的二进制内容，分片可以乱序、重复上传
+// @Tags 上传
+// @Accept  application/octet-stream
+// @Produce  json
+// @Param upload_id path string true "上传id"
+// @Param part_number path int true "分片序号, 从1开始"
+// @Success 200 {object} storage.Part "已上传的分片"
+// @Router /uploads/multipart/{upload_id}/parts/{part_number} [put]
+func UploadPart(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("part_number"))
+	if err != nil {
+		handler.SendResponse(c, errno.ErrUploadPart, nil)
+		return
+	}
+
+	part, err := upload.Svc.UploadPart(c, handler.GetUserID(c), c.Param("upload_id"), number,
+		c.Request.Body, c.Request.ContentLength)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, part)
+}
+
+// GetMultipart 查询上传进度
+// @Summary 查询上传进度
+// @Description 断线后查询已上传的分片，只需要继续上传缺少的分片
+// @Tags 上传
+// @Produce  json
+// @Param upload_id path string true "上传id"
+// @Success 200 {object} MultipartResponse "上传信息和已上传的分片"
+// @Router /uploads/multipart/{upload_id} [get]
+func GetMultipart(c *gin.Context) {
+	multipart, parts, err := upload.Svc.GetMultipart(handler.GetUserID(c), c.Param("upload_id"))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, MultipartResponse{MultipartUpload: multipart, Parts: parts})
+}
+
+// CompleteMultipart 完成分片上传
+// @Summary 完成分片上传
+// @Description 所有分片上传后合并为一个文件
+// @Tags 上传
+// @Produce  json
+// @Param upload_id path string true "上传id"
+// @Success 200 {object} upload.File "上传后的文件"
+// @Router /uploads/multipart/{upload_id}/complete [post]
+func CompleteMultipart(c *gin.Context) {
+	file, err := upload.Svc.CompleteMultipart(c, handler.GetUserID(c), c.Param("upload_id"))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, file)
+}
+
+// AbortMultipart 取消分片上传
+// @Summary 取消分片上传
+// @Description 删除已上传的分片
+// @Tags 上传
+// @Produce  json
+// @Param upload_id path string true "上传id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /uploads/multipart/{upload_id} [delete]
+func AbortMultipart(c *gin.Context) {
+	err := upload.Svc.AbortMultipart(c, handler.GetUserID(c), c.Param("upload_id"))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
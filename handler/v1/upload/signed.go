@@ -0,0 +1,144 @@
+package upload
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/upload"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// Presign 获取直传地址
+// @Summary 获取直传地址
+// @Description 客户端使用返回的地址和请求头直接上传到对象存储
+// @Tags 上传
+// @Accept  json
+// @Produce  json
+// @Param req body PresignRequest true "文件信息"
+// @Success 200 {object} upload.SignedURL "签名地址"
+// @Router /uploads/presign [post]
+func Presign(c *gin.Context) {
+	var req PresignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("bind request param err: %+v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	signed, err := upload.Svc.PresignUpload(c, handler.GetUserID(c), req.Filename, req.ContentType, req.Size)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, signed)
+}
+
+// CompletePresign 直传完成
+// @Summary 直传完成
+// @Description 直传完成后通知服务端记录文件，内容重复时返回已有的文件
+// @Tags 上传
+// @Accept  json
+// @Produce  json
+// @Param req body CompletePresignRequest true "直传的文件"
+// @Success 200 {object} upload.File "上传后的文件"
+// @Router /uploads/presign/complete [post]
+func CompletePresign(c *gin.Context) {
+	var req CompletePresignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("bind request param err: %+v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	file, err := upload.Svc.CompletePresignedUpload(c, handler.GetUserID(c), req.Key, req.ContentType)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, file)
+}
+
+// SignedURL 获取私有文件的下载地址
+// @Summary 获取私有文件的下载地址
+// @Description 只能获取自己上传的文件，地址在有效期后失效
+// @Tags 上传
+// @Produce  json
+// @Param key query string true "文件的存储路径"
+// @Success 200 {object} upload.SignedURL "签名地址"
+// @Router /uploads/signed-url [get]
+func SignedURL(c *gin.Context) {
+	signed, err := upload.Svc.SignDownloadURL(handler.GetUserID(c), c.Query("key"))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, signed)
+}
+
+// GetObject 使用签名地址下载文件，只用于本地磁盘存储
+func GetObject(c *gin.Context) {
+	key, ok := verifySignedRequest(c)
+	if !ok {
+		return
+	}
+
+	r, err := storage.Default.Get(c, key)
+	if err == storage.ErrNotFound {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Warnf("[upload] get object err: %v, key: %s", err, key)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	defer r.Close()
+
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, r); err != nil {
+		log.Warnf("[upload] write object err: %v, key: %s", err, key)
+	}
+}
+
+// PutObject 使用签名地址上传文件，只用于本地磁盘存储
+func PutObject(c *gin.Context) {
+	key, ok := verifySignedRequest(c)
+	if !ok {
+		return
+	}
+
+	err := storage.Default.Put(c, key, c.Request.Body, c.Request.ContentLength, c.ContentType())
+	if err != nil {
+		log.Warnf("[upload] put object err: %v, key: %s", err, key)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// verifySignedRequest 校验签名，失败时和对象存储一样返回 403
+func verifySignedRequest(c *gin.Context) (string, bool) {
+	v, ok := storage.Default.(storage.Verifier)
+	if !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return "", false
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if !v.Verify(c.Request.Method, key, expires, c.Query("signature")) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return "", false
+	}
+	return key, true
+}
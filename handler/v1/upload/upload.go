@@ -0,0 +1,40 @@
+package upload
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	cacheupload "github.com/1024casts/snake/internal/cache/upload"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// InitMultipartRequest 初始化分片上传请求
+type InitMultipartRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size" binding:"required"`
+}
+
+// MultipartResponse 分片上传信息和已上传的分片
+type MultipartResponse struct {
+	*cacheupload.MultipartUpload
+	Parts []storage.Part `json:"parts"`
+}
+
+// sendBizErr 按错误分类返回，见 handler.SendError
+func sendBizErr(c *gin.Context, err error) {
+	handler.SendError(c, err)
+}
+
+// PresignRequest 获取直传地址请求
+type PresignRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size" binding:"required"`
+}
+
+// CompletePresignRequest 直传完成请求
+type CompletePresignRequest struct {
+	Key         string `json:"key" binding:"required"`
+	ContentType string `json:"content_type"`
+}
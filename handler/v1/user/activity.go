@@ -0,0 +1,64 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ActivityList 自己的动作记录
+// @Summary 获取自己的动作记录
+// @Description 登录、关注、取消关注、修改资料等记录，按时间倒序
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param before query uint64 false "上一页最后一条记录的id"
+// @Success 200 {object} model.UserActivityModel "动作记录"
+// @Router /users/{id}/activity [get]
+func ActivityList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的动作记录
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	before, err := strconv.ParseUint(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	limit := 20
+
+	activities, err := user.Svc.GetMyActivity(c.Request.Context(), curUserID, before, limit+1)
+	if err != nil {
+		log.Warnf("get activity list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(activities) > limit {
+		hasMore = 1
+		activities = activities[0:limit]
+	}
+	pageValue := int(before)
+	if len(activities) > 0 {
+		pageValue = int(activities[len(activities)-1].ActivityID)
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "before",
+		PageValue: pageValue,
+		Items:     activities,
+	})
+}
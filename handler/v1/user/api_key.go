@@ -0,0 +1,164 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/apikey"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// checkAPIKeyOwner 只能管理自己的 api key，且只能通过 token 管理，泄露的 key 不能用来创建新的 key
+func checkAPIKeyOwner(c *gin.Context) (uint64, bool) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return 0, false
+	}
+	if handler.GetAPIKeyID(c) > 0 {
+		handler.SendResponse(c, errno.ErrAPIKeyForbidden, nil)
+		return 0, false
+	}
+	return curUserID, true
+}
+
+// APIKeyList api key 列表
+// @Summary 获取自己的 api key
+// @Description 包括已吊销、已过期的 key，只返回 key 的前缀
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} model.UserAPIKeyModel "api key"
+// @Router /users/{id}/api_keys [get]
+func APIKeyList(c *gin.Context) {
+	userID, ok := checkAPIKeyOwner(c)
+	if !ok {
+		return
+	}
+
+	keys, err := apikey.Svc.GetKeyList(c.Request.Context(), userID)
+	if err != nil {
+		log.Warnf("get api key list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, nil, ListResponse{
+		TotalCount: uint64(len(keys)),
+		Items:      keys,
+	})
+}
+
+// CreateAPIKey 创建 api key
+// @Summary 创建 api key
+// @Description 请求时通过 X-Api-Key 请求头携带，key 的明文只在创建时返回一次
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body CreateAPIKeyRequest true "名称"
+// @Success 200 {object} user.APIKeySecretResponse "api key"
+// @Router /users/{id}/api_keys [post]
+func CreateAPIKey(c *gin.Context) {
+	userID, ok := checkAPIKeyOwner(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("create api key bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	key, secret, err := apikey.Svc.CreateKey(c.Request.Context(), userID, req.Name, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, APIKeySecretResponse{APIKey: key, Secret: secret})
+}
+
+// RotateAPIKey 轮换 api key
+// @Summary 轮换 api key
+// @Description 生成新的 key，旧的 key 立即失效，有效期重新计算
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param key_id path uint64 true "api key id"
+// @Success 200 {object} user.APIKeySecretResponse "api key"
+// @Router /users/{id}/api_keys/{key_id}/rotate [post]
+func RotateAPIKey(c *gin.Context) {
+	userID, ok := checkAPIKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, _ := strconv.ParseUint(c.Param("key_id"), 10, 64)
+
+	key, secret, err := apikey.Svc.RotateKey(c.Request.Context(), userID, keyID, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, APIKeySecretResponse{APIKey: key, Secret: secret})
+}
+
+// RevokeAPIKey 吊销 api key
+// @Summary 吊销 api key
+// @Description 吊销后不能恢复
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param key_id path uint64 true "api key id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/api_keys/{key_id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	userID, ok := checkAPIKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, _ := strconv.ParseUint(c.Param("key_id"), 10, 64)
+
+	if err := apikey.Svc.RevokeKey(c.Request.Context(), userID, keyID, c.ClientIP()); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// APIKeyUsage api key 用量
+// @Summary 获取 api key 的用量
+// @Description 本日、本月通过该 key 的请求次数，配额按用户的套餐计算
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param key_id path uint64 true "api key id"
+// @Success 200 {object} quota.Usage "用量"
+// @Router /users/{id}/api_keys/{key_id}/usage [get]
+func APIKeyUsage(c *gin.Context) {
+	userID, ok := checkAPIKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, _ := strconv.ParseUint(c.Param("key_id"), 10, 64)
+
+	usage, err := apikey.Svc.GetUsage(c.Request.Context(), userID, keyID)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, usage)
+}
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/image"
+	"github.com/1024casts/snake/internal/service/upload"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 上传后立即生效，缩略图和图片审核异步进行
+// @Tags 用户
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param file formData file true "头像图片, 支持 jpg、png、gif"
+// @Success 200 {object} upload.File "上传后的文件"
+// @Router /users/{id}/avatar [post]
+func UploadAvatar(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能修改自己的头像
+	if uint64(userID) != handler.GetUserID(c) {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		log.Warnf("get form file err: %+v", err)
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		log.Warnf("open form file err: %+v", err)
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	defer f.Close()
+
+	file, err := upload.Svc.UploadImage(c, uint64(userID), upload.CategoryAvatar, f, fh.Size)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	err = user.Svc.UpdateProfile(c.Request.Context(), uint64(userID), map[string]interface{}{"avatar": file.URL})
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, file)
+}
+
+// Avatar 获取头像
+// @Summary 获取某个尺寸的头像
+// @Description 重定向到对应尺寸的头像地址，缩略图还没有生成时重定向到原图
+// @Tags 用户
+// @Param id path uint64 true "用户id"
+// @Param size query int false "尺寸, 如 64、128、256"
+// @Param format query string false "格式 jpeg、webp, 默认 jpeg"
+// @Success 302
+// @Router /users/{id}/avatar [get]
+func Avatar(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	u, err := user.Svc.GetUserByID(c.Request.Context(), uint64(userID))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+	if u.ID == 0 || u.Avatar == "" {
+		handler.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+
+	url := u.Avatar
+	if size, _ := strconv.Atoi(c.Query("size")); size > 0 {
+		url, err = image.Svc.GetVariantURL(c.Request.Context(), u.Avatar, size, c.Query("format"))
+		if err != nil {
+			sendBizErr(c, err)
+			return
+		}
+	}
+
+	c.Redirect(http.StatusFound, url)
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// BanStatus 封禁状态
+// @Summary 获取自己的封禁状态
+// @Description 未被封禁时 data 为 null
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} model.UserBanModel "生效中的封禁"
+// @Router /users/{id}/ban [get]
+func BanStatus(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的封禁状态
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	b, err := ban.Svc.GetActiveBan(c.Request.Context(), curUserID)
+	if err != nil {
+		log.Warnf("get active ban err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+	if b.ID == 0 {
+		handler.SendResponse(c, errno.OK, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, b)
+}
+
+// SubmitAppeal 提交封禁申诉
+// @Summary 对生效中的封禁提交申诉
+// @Description 同一封禁同时只能有一个待处理的申诉
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body SubmitAppealRequest true "申诉内容"
+// @Success 200 {object} model.UserAppealModel "申诉"
+// @Router /users/{id}/appeals [post]
+func SubmitAppeal(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	var req SubmitAppealRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("submit appeal bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	appeal, err := ban.Svc.SubmitAppeal(c.Request.Context(), curUserID, req.Content)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, appeal)
+}
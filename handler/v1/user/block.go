@@ -0,0 +1,123 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Block 拉黑
+// @Summary 拉黑用户
+// @Description 同时取消双方之间的关注，之后双方都不能再关注对方
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param req body BlockRequest true "被拉黑的用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/block [post]
+func Block(c *gin.Context) {
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("block bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	if err := user.Svc.BlockUser(c.Request.Context(), handler.GetUserID(c), req.UserID); err != nil {
+		handler.SendError(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// Unblock 取消拉黑
+// @Summary 取消拉黑用户
+// @Description 拉黑时取消的关注不会恢复
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param req body BlockRequest true "被拉黑的用户id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/unblock [post]
+func Unblock(c *gin.Context) {
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("unblock bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	if err := user.Svc.UnblockUser(c.Request.Context(), handler.GetUserID(c), req.UserID); err != nil {
+		handler.SendError(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// BlockedList 拉黑列表
+// @Summary 获取自己拉黑的用户列表
+// @Description 按拉黑时间倒序，通过 last_id 翻页
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param last_id query int false "上一页最后一条记录的id"
+// @Success 200 {object} model.UserInfo "用户信息"
+// @Router /users/{id}/blocked [get]
+func BlockedList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的拉黑列表
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 10
+
+	blockedList, err := user.Svc.GetBlockedList(c.Request.Context(), curUserID, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get blocked list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	pageValue := lastID
+	if len(blockedList) > limit {
+		hasMore = 1
+		blockedList = blockedList[0:limit]
+		pageValue = int(blockedList[limit-1].ID)
+	}
+
+	userIDs := make([]uint64, 0, len(blockedList))
+	for _, v := range blockedList {
+		userIDs = append(userIDs, v.BlockedUID)
+	}
+
+	userOutList, err := user.Svc.BatchGetUsers(c.Request.Context(), curUserID, userIDs)
+	if err != nil {
+		log.Warnf("batch get users err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+	idl.LocalizeUsers(userOutList, handler.GetFormat(c))
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: 0,
+		HasMore:    hasMore,
+		PageKey:    "last_id",
+		PageValue:  pageValue,
+		Items:      userOutList,
+	})
+}
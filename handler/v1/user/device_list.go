@@ -0,0 +1,47 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// DeviceList 登录设备列表
+// @Summary 获取自己登录过的设备列表
+// @Description Get login devices of current user
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param time_format query string false "时间格式 rfc3339、epoch、local，也可以通过请求头 X-Time-Format 指定"
+// @Success 200 {object} idl.DeviceInfo "设备信息"
+// @Router /users/{id}/devices [get]
+func DeviceList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的设备
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	devices, err := user.Svc.GetUserDeviceList(c.Request.Context(), curUserID, handler.GetRegion(c))
+	if err != nil {
+		log.Warnf("get user device list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: uint64(len(devices)),
+		HasMore:    0,
+		Items:      idl.TransferDevices(devices, handler.GetFormat(c)),
+	})
+}
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ChangeEmail 申请修改邮箱
+// @Summary 申请修改邮箱
+// @Description 确认链接会同时发送到新旧邮箱，两个都确认后才会生效
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body ChangeEmailRequest true "新邮箱和当前密码"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/email [post]
+func ChangeEmail(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能修改自己的邮箱
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("change email bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	err := user.Svc.RequestEmailChange(c.Request.Context(), curUserID, req.Email, req.Password, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// ConfirmEmail 确认修改邮箱
+// @Summary 确认修改邮箱
+// @Description 邮件中的确认链接，新旧邮箱都确认后修改生效
+// @Tags 用户
+// @Produce  json
+// @Param token query string true "确认token"
+// @Success 200 {object} handler.Response "data 为 ConfirmEmailResponse，done 表示是否已修改完成"
+// @Router /email/confirm [get]
+func ConfirmEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	done, err := user.Svc.ConfirmEmailChange(c.Request.Context(), token, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, ConfirmEmailResponse{Done: done})
+}
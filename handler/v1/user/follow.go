@@ -27,7 +27,7 @@ func Follow(c *gin.Context) {
 	}
 
 	// Get the user by the `user_id` from the database.
-	_, err := user.Svc.GetUserByID(req.UserID)
+	_, err := user.Svc.GetUserByID(c.Request.Context(), req.UserID)
 	if err != nil {
 		handler.SendResponse(c, errno.ErrUserNotFound, nil)
 		return
@@ -41,7 +41,7 @@ func Follow(c *gin.Context) {
 	}
 
 	// 检查是否已经关注过
-	isFollowed := user.Svc.IsFollowedUser(userID, req.UserID)
+	isFollowed := user.Svc.IsFollowedUser(c.Request.Context(), userID, req.UserID)
 	if isFollowed {
 		handler.SendResponse(c, errno.OK, nil)
 		return
@@ -49,18 +49,17 @@ func Follow(c *gin.Context) {
 
 	if isFollowed {
 		// 取消关注
-		err = user.Svc.CancelUserFollow(userID, req.UserID)
+		err = user.Svc.CancelUserFollow(c.Request.Context(), userID, req.UserID)
 		if err != nil {
 			log.Warnf("[follow] cancel user follow err: %v", err)
 			handler.SendResponse(c, errno.InternalServerError, nil)
 			return
 		}
 	} else {
-		// 添加关注
-		err = user.Svc.AddUserFollow(userID, req.UserID)
+		// 添加关注，双方有拉黑关系时返回 ErrUserBlocked
+		err = user.Svc.AddUserFollow(c.Request.Context(), userID, req.UserID)
 		if err != nil {
-			log.Warnf("[follow] add user follow err: %v", err)
-			handler.SendResponse(c, errno.InternalServerError, nil)
+			handler.SendError(c, err)
 			return
 		}
 	}
@@ -0,0 +1,68 @@
+package user
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// maxFollowCountsIDs 每次最多查询的用户数
+const maxFollowCountsIDs = 100
+
+// FollowCounts 批量获取关注数和粉丝数
+// @Summary 批量获取关注数和粉丝数
+// @Description 用于主页头部等只需要显示计数的地方，不需要请求粉丝列表
+// @Tags 用户
+// @Produce  json
+// @Param user_ids query string true "用户id，多个用逗号分隔，最多 100 个"
+// @Param time_format query string false "为 local 时返回按 Accept-Language 格式化的关注数、粉丝数"
+// @Success 200 {object} model.FollowCounts "关注数和粉丝数"
+// @Router /users/follow_counts [get]
+func FollowCounts(c *gin.Context) {
+	var userIDs []uint64
+	seen := make(map[uint64]bool)
+	for _, s := range strings.Split(c.Query("user_ids"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+		if err != nil || id == 0 {
+			handler.SendResponse(c, errno.ErrParam, nil)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) > maxFollowCountsIDs {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	counts, err := user.Svc.GetFollowCounts(c.Request.Context(), userIDs)
+	if err != nil {
+		log.Warnf("get follow counts err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	f := handler.GetFormat(c)
+	items := make([]*model.FollowCounts, 0, len(userIDs))
+	for _, id := range userIDs {
+		v := counts[id]
+		v.FollowCountText = f.Count(int64(v.FollowCount))
+		v.FollowerCountText = f.Count(int64(v.FollowerCount))
+		items = append(items, v)
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: uint64(len(items)),
+		HasMore:    0,
+		Items:      items,
+	})
+}
@@ -6,9 +6,12 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/internal/service/user"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/pagination"
 )
 
 // FollowList 关注列表
@@ -18,6 +21,10 @@ import (
 // @Accept  json
 // @Produce  json
 // @Param user_id body string true "用户id"
+// @Param cursor query string false "上一页返回的 next_cursor，第一页不传"
+// @Param page_size query int false "每页条数，默认10，最大50"
+// @Param last_id query int false "上一页最后一条记录的id，已废弃，使用 cursor"
+// @Param time_format query string false "为 local 时返回按 Accept-Language 格式化的关注数、粉丝数"
 // @Success 200 {object} model.UserInfo "用户信息"
 // @Router /users/{id}/following [get]
 func FollowList(c *gin.Context) {
@@ -27,29 +34,36 @@ func FollowList(c *gin.Context) {
 	curUserID := handler.GetUserID(c)
 	log.Infof("cur uid: %d", curUserID)
 
-	_, err := user.Svc.GetUserByID(uint64(userID))
+	u, err := user.Svc.GetUserByID(c.Request.Context(), uint64(userID))
 	if err != nil {
 		handler.SendResponse(c, errno.ErrUserNotFound, nil)
 		return
 	}
+	// 未成年人受限模式下只有自己可以查看
+	if u.ID != curUserID && user.Svc.HideFollowList(u) {
+		handler.SendResponse(c, errno.ErrFollowListHidden, nil)
+		return
+	}
 
-	lastIDStr := c.DefaultQuery("last_id", "0")
-	lastID, _ := strconv.Atoi(lastIDStr)
-	limit := 10
+	page, err := pagination.Parse(c.Request.URL.Query(), followListPage)
+	if err != nil {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
 
-	userFollowList, err := user.Svc.GetFollowingUserList(uint64(userID), uint64(lastID), limit+1)
+	userFollowList, err := user.Svc.GetFollowingUserList(c.Request.Context(), uint64(userID), page.LastID(), page.Limit+1)
 	if err != nil {
 		log.Warnf("get following user list err: %+v", err)
 		handler.SendResponse(c, errno.InternalServerError, nil)
 		return
 	}
 
-	hasMore := 0
-	pageValue := lastID
-	if len(userFollowList) > limit {
-		hasMore = 1
-		userFollowList = userFollowList[0 : len(userFollowList)-1]
-		pageValue = lastID + 1
+	userFollowList, nextCursor := pagination.Page(userFollowList, page.Limit, func(v *model.UserFollowModel) pagination.Cursor {
+		return pagination.Cursor{ID: v.ID, Time: v.CreatedAt}
+	})
+	hasMore, pageValue := 0, int(page.LastID())
+	if nextCursor != "" {
+		hasMore, pageValue = 1, int(userFollowList[len(userFollowList)-1].ID)
 	}
 
 	var userIDs []uint64
@@ -57,18 +71,20 @@ func FollowList(c *gin.Context) {
 		userIDs = append(userIDs, v.FollowedUID)
 	}
 
-	userOutList, err := user.Svc.BatchGetUsers(curUserID, userIDs)
+	userOutList, err := user.Svc.BatchGetUsers(c.Request.Context(), curUserID, userIDs)
 	if err != nil {
 		log.Warnf("batch get users err: %v", err)
 		handler.SendResponse(c, errno.InternalServerError, nil)
 		return
 	}
+	idl.LocalizeUsers(userOutList, handler.GetFormat(c))
 
 	handler.SendResponse(c, errno.OK, ListResponse{
 		TotalCount: 0,
 		HasMore:    hasMore,
 		PageKey:    "last_id",
 		PageValue:  pageValue,
+		NextCursor: nextCursor,
 		Items:      userOutList,
 	})
 }
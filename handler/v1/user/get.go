@@ -7,6 +7,7 @@ import (
 
 	"github.com/1024casts/snake/handler"
 	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/constvar"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
 )
@@ -19,7 +20,8 @@ import (
 // @Produce  json
 // @Param id path string true "用户id"
 // @Success 200 {object} model.UserInfo "用户信息"
-// @Router /users/:id [get]
+// @Header 200 {string} ETag "资料的实体标签"
+// @Router /users/{id} [get]
 func Get(c *gin.Context) {
 	log.Info("Get function called.")
 
@@ -31,12 +33,31 @@ func Get(c *gin.Context) {
 	userID, _ := strconv.Atoi(userIDStr)
 
 	// Get the user by the `user_id` from the database.
-	u, err := user.Svc.GetUserByID(uint64(userID))
+	u, err := user.Svc.GetUserByID(c.Request.Context(), uint64(userID))
 	if err != nil {
 		log.Warnf("get user info err: %v", err)
 		handler.SendResponse(c, errno.ErrUserNotFound, nil)
 		return
 	}
 
+	// 修改资料时通过 If-Match 带回
+	if u.ID > 0 {
+		c.Header(constvar.XETag, u.ETag())
+	}
+
+	// 记录主页浏览数
+	if u.ID > 0 {
+		if err := user.Svc.IncrUserViewCount(u.ID); err != nil {
+			log.Warnf("incr user view count err: %v", err)
+		}
+	}
+
+	// 生日只有自己可以查看
+	if u.Birthday != nil {
+		info := *u
+		info.Birthday = nil
+		u = &info
+	}
+
 	handler.SendResponse(c, nil, u)
 }
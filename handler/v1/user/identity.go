@@ -0,0 +1,75 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// IdentityList 登录方式列表
+// @Summary 获取自己绑定的登录方式
+// @Description Get login identities of current user
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} model.UserIdentityModel "登录方式"
+// @Router /users/{id}/identities [get]
+func IdentityList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的登录方式
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	identities, err := user.Svc.GetUserIdentities(c.Request.Context(), curUserID)
+	if err != nil {
+		log.Warnf("get user identities err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: uint64(len(identities)),
+		HasMore:    0,
+		Items:      identities,
+	})
+}
+
+// UnlinkIdentity 解绑登录方式
+// @Summary 解绑登录方式
+// @Description 至少需要保留一种登录方式
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param identity_id path uint64 true "登录方式id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/identities/{identity_id} [delete]
+func UnlinkIdentity(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	identityID, _ := strconv.Atoi(c.Param("identity_id"))
+
+	// 只能解绑自己的登录方式
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	err := user.Svc.UnlinkIdentity(c.Request.Context(), curUserID, uint64(identityID), c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
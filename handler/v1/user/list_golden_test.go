@@ -0,0 +1,44 @@
+package user
+
+import (
+	"testing"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/testutil"
+)
+
+// 关注、粉丝等用户列表的响应快照，列表结构或 UserInfo 字段变化需要更新 testdata
+// go test ./handler/v1/user/ -run Golden -update
+func TestUserListResponse_Golden(t *testing.T) {
+	items := []*model.UserInfo{
+		idl.TransferUser(&idl.TransferUserInput{
+			User:     &model.UserBaseModel{ID: 1, Username: "snake", Avatar: "/uploads/avatar/1.png", Sex: 1},
+			UserStat: &model.UserStatModel{UserID: 1, FollowCount: 10, FollowerCount: 20},
+			IsFollow: 1,
+			IsFans:   1,
+		}),
+		idl.TransferUser(&idl.TransferUserInput{
+			User: &model.UserBaseModel{ID: 2, Username: "eagle"},
+		}),
+	}
+
+	tests := []struct {
+		name string
+		data ListResponse
+	}{
+		{"user_list", ListResponse{HasMore: 1, PageKey: "last_id", PageValue: 1, NextCursor: "MTow", Items: items}},
+		{"user_list_empty", ListResponse{PageKey: "last_id", Items: make([]*model.UserInfo, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertGolden(t, tt.name, handler.Response{
+				Code:    errno.OK.Code,
+				Message: errno.OK.Message,
+				Data:    tt.data,
+			})
+		})
+	}
+}
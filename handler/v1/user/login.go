@@ -6,7 +6,6 @@ import (
 	"github.com/1024casts/snake/handler"
 	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/internal/service/user"
-	"github.com/1024casts/snake/internal/service/vcode"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
 )
@@ -16,13 +15,13 @@ import (
 // @Description 仅限邮箱登录
 // @Tags 用户
 // @Produce  json
-// @Param req body LoginCredentials true ""
-// @Success 200 {string} json "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
+// @Param req body LoginCredentials true "邮箱和密码"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
 // @Router /login [post]
 func Login(c *gin.Context) {
 	// Binding the data with the u struct.
 	var req LoginCredentials
-	if err := c.Bind(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		log.Warnf("email login bind param err: %v", err)
 		handler.SendResponse(c, errno.ErrBind, nil)
 		return
@@ -36,9 +35,13 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	t, err := user.Svc.EmailLogin(c, req.Email, req.Password)
+	t, err := user.Svc.EmailLogin(c.Request.Context(), req.Email, req.Password, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		log.Warnf("email login err: %v", err)
+		if err == errno.ErrUserBanned {
+			handler.SendResponse(c, err, nil)
+			return
+		}
 		handler.SendResponse(c, errno.ErrEmailOrPassword, nil)
 		return
 	}
@@ -54,14 +57,14 @@ func Login(c *gin.Context) {
 // @Tags 用户
 // @Produce  json
 // @Param req body PhoneLoginCredentials true "phone"
-// @Success 200 {string} json "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
-// @Router /users/login [post]
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
+// @Router /login/phone [post]
 func PhoneLogin(c *gin.Context) {
 	log.Info("Phone Login function called.")
 
 	// Binding the data with the u struct.
 	var req PhoneLoginCredentials
-	if err := c.Bind(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		log.Warnf("phone login bind param err: %v", err)
 		handler.SendResponse(c, errno.ErrBind, nil)
 		return
@@ -75,16 +78,10 @@ func PhoneLogin(c *gin.Context) {
 		return
 	}
 
-	// 验证校验码
-	if !vcode.VCodeService.CheckLoginVCode(req.Phone, req.VerifyCode) {
-		handler.SendResponse(c, errno.ErrVerifyCode, nil)
-		return
-	}
-
-	// 登录
-	t, err := user.Svc.PhoneLogin(c, req.Phone, req.VerifyCode)
+	// 校验验证码并登录，验证码错误、封禁中等返回对应的错误码
+	t, err := user.Svc.PhoneLogin(c.Request.Context(), req.Phone, req.VerifyCode, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		handler.SendResponse(c, errno.ErrVerifyCode, nil)
+		handler.SendError(c, err)
 		return
 	}
 
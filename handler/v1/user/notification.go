@@ -0,0 +1,189 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// NotificationList 站内通知列表
+// @Summary 获取自己的站内通知
+// @Description Get in-app notifications of current user
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param last_id query uint64 false "上一页最后一条通知的id"
+// @Param time_format query string false "时间格式 rfc3339、epoch、local，也可以通过请求头 X-Time-Format 指定"
+// @Success 200 {object} idl.NotificationInfo "站内通知"
+// @Router /users/{id}/notifications [get]
+func NotificationList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的通知
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	lastID, _ := strconv.Atoi(c.DefaultQuery("last_id", "0"))
+	limit := 10
+
+	notifications, err := notification.Svc.GetNotificationList(c.Request.Context(), curUserID, uint64(lastID), limit+1)
+	if err != nil {
+		log.Warnf("get notification list err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	hasMore := 0
+	if len(notifications) > limit {
+		hasMore = 1
+		notifications = notifications[0:limit]
+	}
+	pageValue := lastID
+	if len(notifications) > 0 {
+		pageValue = int(notifications[len(notifications)-1].ID)
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		HasMore:   hasMore,
+		PageKey:   "last_id",
+		PageValue: pageValue,
+		Items:     idl.TransferNotifications(notifications, handler.GetFormat(c)),
+	})
+}
+
+// NotificationPreferences 通知偏好
+// @Summary 获取自己的通知偏好
+// @Description 每种事件在站内信、推送、邮件渠道是否开启
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} notification.Preference "通知偏好"
+// @Router /users/{id}/notification/preferences [get]
+func NotificationPreferences(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	prefs, err := notification.Svc.GetPreferences(c.Request.Context(), curUserID)
+	if err != nil {
+		log.Warnf("get notification preferences err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: uint64(len(prefs)),
+		HasMore:    0,
+		Items:      prefs,
+	})
+}
+
+// UpdateNotificationPreferences 修改通知偏好
+// @Summary 修改自己的通知偏好
+// @Description 可以只传需要修改的事件和渠道，比如关闭新粉丝的邮件通知
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body UpdateNotificationPreferencesRequest true "通知偏好"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/notification/preferences [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("update notification preferences bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := notification.Svc.UpdatePreferences(c.Request.Context(), curUserID, req.Preferences)
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// AnnouncementList 未读公告
+// @Summary 获取自己未读的系统公告
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} model.NotificationModel "公告通知，ref_id 为公告id"
+// @Router /users/{id}/announcements [get]
+func AnnouncementList(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	announcements, err := notification.Svc.GetUnreadNotifications(c.Request.Context(), curUserID, model.NotifyEventAnnouncement, 20)
+	if err != nil {
+		log.Warnf("get unread announcements err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, ListResponse{
+		TotalCount: uint64(len(announcements)),
+		HasMore:    0,
+		Items:      announcements,
+	})
+}
+
+// ReadNotification 标记通知为已读
+// @Summary 标记通知为已读
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param notification_id path uint64 true "通知id"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/notifications/{notification_id}/read [post]
+func ReadNotification(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+	notificationID, _ := strconv.Atoi(c.Param("notification_id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	err := notification.Svc.MarkRead(c.Request.Context(), curUserID, uint64(notificationID))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/oauth"
+)
+
+// OAuthURL 第三方授权页地址
+// @Summary 获取第三方授权页地址
+// @Description 客户端跳转到授权页，授权后第三方回调 redirect_url 并带回 code 和 state，state 10 分钟内有效
+// @Tags 用户
+// @Produce  json
+// @Param provider path string true "wechat、github 或 google"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":{"url":"https://github.com/login/oauth/authorize?client_id=","state":"5f0c"}}"
+// @Router /oauth/{provider} [get]
+func OAuthURL(c *gin.Context) {
+	p, err := oauth.Get(c.Param("provider"))
+	if err != nil {
+		handler.SendResponse(c, errno.ErrOAuthProvider, nil)
+		return
+	}
+
+	state, err := oauth.NewState(p.Name())
+	if err != nil {
+		log.Warnf("new oauth state err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, nil, OAuthURLResponse{URL: p.AuthCodeURL(state), State: state})
+}
+
+// OAuthLogin 第三方帐号登录
+// @Summary 第三方帐号登录接口
+// @Description 用授权回调的 code 登录，首次登录时关联邮箱相同的用户或创建新用户
+// @Tags 用户
+// @Produce  json
+// @Param provider path string true "wechat、github 或 google"
+// @Param req body OAuthLoginCredentials true "code 和 state"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
+// @Router /login/oauth/{provider} [post]
+func OAuthLogin(c *gin.Context) {
+	var req OAuthLoginCredentials
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("oauth login bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	p, err := oauth.Get(c.Param("provider"))
+	if err != nil {
+		handler.SendResponse(c, errno.ErrOAuthProvider, nil)
+		return
+	}
+	if err := oauth.CheckState(p.Name(), req.State); err != nil {
+		log.Warnf("check oauth state err: %v", err)
+		handler.SendResponse(c, errno.ErrOAuthState, nil)
+		return
+	}
+
+	info, err := p.Exchange(c.Request.Context(), req.Code)
+	if err != nil {
+		log.Warnf("oauth exchange err: %v", err)
+		handler.SendResponse(c, errno.ErrOAuthLogin, nil)
+		return
+	}
+
+	t, err := user.Svc.OAuthLogin(c.Request.Context(), info, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		log.Warnf("oauth login err: %v", err)
+		if err == errno.ErrUserBanned {
+			handler.SendResponse(c, err, nil)
+			return
+		}
+		handler.SendResponse(c, errno.ErrOAuthLogin, nil)
+		return
+	}
+
+	handler.SendResponse(c, nil, model.Token{
+		Token: t,
+	})
+}
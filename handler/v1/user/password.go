@@ -0,0 +1,72 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ForgotPassword 发送重置密码邮件
+// @Summary 忘记密码
+// @Description 向邮箱发送重置密码链接，邮箱未注册时也返回成功
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param req body ForgotPasswordRequest true "注册邮箱"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("forgot password bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if req.Email == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	if err := user.Svc.ForgotPassword(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用邮件中的 token 设置新密码，token 只能使用一次，重置后所有登录状态失效
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param req body ResetPasswordRequest true "token 和新密码"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("reset password bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		handler.SendResponse(c, errno.ErrTwicePasswordNotMatch, nil)
+		return
+	}
+
+	if err := user.Svc.ResetPassword(c.Request.Context(), req.Token, req.Password, c.ClientIP()); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
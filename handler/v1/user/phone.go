@@ -0,0 +1,48 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// ChangePhone 修改手机号
+// @Summary 修改绑定的手机号
+// @Description 需要当前手机号和新手机号的验证码，修改后通过手机号登录的会话会失效
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body ChangePhoneRequest true "新手机号和验证码"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/{id}/phone [post]
+func ChangePhone(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能修改自己的手机号
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	var req ChangePhoneRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("change phone bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	err := user.Svc.ChangePhone(c.Request.Context(), curUserID, req.OldVerifyCode, req.Phone, req.VerifyCode, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/policy"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// GetPolicy 获取协议的当前版本
+// @Summary 获取协议的当前版本
+// @Description 用户服务协议、隐私政策等
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param type path string true "协议类型 tos:用户服务协议 privacy:隐私政策"
+// @Success 200 {object} model.PolicyModel "协议"
+// @Router /policies/{type} [get]
+func GetPolicy(c *gin.Context) {
+	p, err := policy.Svc.GetLatest(c.Request.Context(), c.Param("type"))
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, p)
+}
+
+// AcceptPolicy 同意协议
+// @Summary 同意协议的当前版本
+// @Description 记录同意的时间和ip，协议更新后需要重新同意
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Param req body AcceptPolicyRequest true "协议类型和版本"
+// @Success 200 {object} model.UserPolicyModel "同意记录"
+// @Router /users/{id}/policies/accept [post]
+func AcceptPolicy(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	var req AcceptPolicyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("accept policy bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+
+	up, err := policy.Svc.Accept(c.Request.Context(), curUserID, req.Type, req.Version, c.ClientIP())
+	if err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, up)
+}
@@ -0,0 +1,54 @@
+package user
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/quota"
+)
+
+// Quota 配额使用情况
+// @Summary 获取自己的套餐配额使用情况
+// @Description Get api quota usage of current user
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param id path uint64 true "用户id"
+// @Success 200 {object} quota.Usage "配额使用情况"
+// @Router /users/{id}/quota [get]
+func Quota(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// 只能查看自己的配额
+	curUserID := handler.GetUserID(c)
+	if uint64(userID) != curUserID {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	if quota.Client == nil {
+		handler.SendResponse(c, errno.OK, nil)
+		return
+	}
+
+	u, err := user.Svc.GetUserByID(c.Request.Context(), curUserID)
+	if err != nil {
+		log.Warnf("get user err: %+v", err)
+		handler.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+
+	usage, err := quota.Client.Get(quota.UserSubject(curUserID), u.Plan)
+	if err != nil {
+		log.Warnf("get user quota err: %+v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	handler.SendResponse(c, errno.OK, usage)
+}
@@ -14,9 +14,9 @@ import (
 // @Description 用户注册
 // @Tags 用户
 // @Produce  json
-// @Param req body RegisterRequest true ""
-// @Success 200 {string} json "{"code":0,"message":"OK","data":{"token":"eyJhbGciOiJIUzI1NiIsInR5cCI6Ik"}}"
-// @Router /Register [post]
+// @Param req body RegisterRequest true "注册信息"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /register [post]
 func Register(c *gin.Context) {
 	// Binding the data with the u struct.
 	var req RegisterRequest
@@ -41,9 +41,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	err := user.Svc.Register(c, req.Username, req.Email, req.Password)
+	err := user.Svc.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		log.Warnf("register err: %v", err)
+		switch err {
+		case errno.ErrEmailExist, errno.ErrUsernameExist, errno.ErrUsernameReserved, errno.ErrParam:
+			handler.SendResponse(c, err, nil)
+			return
+		}
 		handler.SendResponse(c, errno.ErrRegisterFailed, nil)
 		return
 	}
@@ -1,14 +1,19 @@
 package user
 
 import (
+	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/1024casts/snake/handler"
 	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/constvar"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 // Update 更新用户信息
@@ -18,16 +23,32 @@ import (
 // @Accept  json
 // @Produce  json
 // @Param id path uint64 true "The user's database id index num"
-// @Param user body model.UserModel true "The user info"
+// @Param If-Match header string true "获取用户信息时返回的 ETag, * 表示不校验"
+// @Param user body UpdateRequest true "The user info"
 // @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Failure 412 {object} handler.Response "资料已被修改"
+// @Failure 428 {object} handler.Response "缺少 If-Match"
 // @Router /users/{id} [put]
 func Update(c *gin.Context) {
 	// Get the user id from the url parameter.
 	userID, _ := strconv.Atoi(c.Param("id"))
 
+	// 只能修改自己的资料
+	if uint64(userID) != handler.GetUserID(c) {
+		handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+
+	// 多端同时修改时，以 If-Match 校验资料版本，防止互相覆盖
+	version, err := ifMatchVersion(c.GetHeader(constvar.XIfMatch), uint64(userID))
+	if err != nil {
+		sendUpdateErr(c, err)
+		return
+	}
+
 	// Binding the user data.
 	var req UpdateRequest
-	if err := c.Bind(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		log.Warnf("bind request param err: %+v", err)
 		handler.SendResponse(c, errno.ErrBind, nil)
 		return
@@ -37,12 +58,61 @@ func Update(c *gin.Context) {
 	userMap := make(map[string]interface{})
 	userMap["avatar"] = req.Avatar
 	userMap["sex"] = req.Sex
-	err := user.Svc.UpdateUser(uint64(userID), userMap)
+	if req.Username != nil {
+		if *req.Username == "" {
+			handler.SendResponse(c, errno.ErrParam, nil)
+			return
+		}
+		userMap["username"] = *req.Username
+	}
+	if req.Bio != nil {
+		userMap["bio"] = *req.Bio
+	}
+	// 生日和其他字段一起更新，校验在 service 中
+	if req.Birthday != nil {
+		userMap["birthday"] = *req.Birthday
+	}
+	err = user.Svc.UpdateProfileIfMatch(c.Request.Context(), uint64(userID), version, userMap)
 	if err != nil {
-		log.Warnf("[user] update user err, %v", err)
-		handler.SendResponse(c, errno.InternalServerError, nil)
+		sendUpdateErr(c, err)
 		return
 	}
 
+	// 返回修改后的 ETag，客户端可以继续修改而不用重新获取
+	if u, err := user.Svc.GetUserByID(c.Request.Context(), uint64(userID)); err == nil && u.ID > 0 {
+		c.Header(constvar.XETag, u.ETag())
+	}
+
 	handler.SendResponse(c, nil, userID)
 }
+
+// ifMatchVersion 从 If-Match 中解析资料版本号，* 时返回 -1 表示不校验
+func ifMatchVersion(ifMatch string, userID uint64) (int, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		return 0, errno.ErrPreconditionRequired
+	}
+	if ifMatch == "*" {
+		return -1, nil
+	}
+
+	var id uint64
+	var version int
+	tag := strings.TrimPrefix(ifMatch, "W/")
+	if _, err := fmt.Sscanf(tag, `"%d-%d"`, &id, &version); err != nil || id != userID || version < 0 {
+		return 0, errno.ErrVersionConflict
+	}
+	return version, nil
+}
+
+// sendUpdateErr 版本不一致返回 412，缺少 If-Match 返回 428，其他错误同 sendBizErr
+func sendUpdateErr(c *gin.Context, err error) {
+	switch errors.Cause(err) {
+	case errno.ErrVersionConflict:
+		handler.SendStatusResponse(c, http.StatusPreconditionFailed, errno.ErrVersionConflict, nil)
+	case errno.ErrPreconditionRequired:
+		handler.SendStatusResponse(c, http.StatusPreconditionRequired, errno.ErrPreconditionRequired, nil)
+	default:
+		sendBizErr(c, err)
+	}
+}
@@ -1,7 +1,12 @@
 package user
 
 import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
 	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/pagination"
 )
 
 // CreateRequest 创建用户请求
@@ -35,10 +40,78 @@ type PhoneLoginCredentials struct {
 	VerifyCode int `json:"verify_code" form:"verify_code" binding:"required" example:"120110"`
 }
 
+// OAuthLoginCredentials 第三方帐号登录，code 和 state 为授权回调时带回的参数
+type OAuthLoginCredentials struct {
+	Code  string `json:"code" form:"code" binding:"required"`
+	State string `json:"state" form:"state" binding:"required"`
+}
+
+// OAuthURLResponse 第三方授权页地址
+type OAuthURLResponse struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+// CreateAPIKeyRequest 创建 api key 请求
+type CreateAPIKeyRequest struct {
+	// Name 用于区分不同用途的 key
+	Name string `json:"name" form:"name" binding:"required,max=64" example:"ci"`
+}
+
+// APIKeySecretResponse 创建、轮换 api key 的返回，key 的明文只返回这一次
+type APIKeySecretResponse struct {
+	APIKey *model.UserAPIKeyModel `json:"api_key"`
+	Secret string                 `json:"secret" example:"sk_3f9a..."`
+}
+
 // UpdateRequest 更新请求
 type UpdateRequest struct {
 	Avatar string `json:"avatar"`
 	Sex    int    `json:"sex"`
+	// Username、Bio 不传时不修改
+	Username *string `json:"username"`
+	Bio      *string `json:"bio"`
+	// Birthday 生日，格式 2006-01-02，设置后不能修改
+	Birthday *string `json:"birthday" example:"2000-01-01"`
+}
+
+// ChangeEmailRequest 修改邮箱请求
+type ChangeEmailRequest struct {
+	Email    string `json:"email" form:"email"`
+	Password string `json:"password" form:"password"`
+}
+
+// ConfirmEmailResponse 确认修改邮箱响应
+type ConfirmEmailResponse struct {
+	// Done 新旧邮箱是否都已确认，修改已生效
+	Done bool `json:"done"`
+}
+
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email" example:"user@example.com"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	// Token 重置密码邮件链接中的 token
+	Token           string `json:"token" form:"token"`
+	Password        string `json:"password" form:"password"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password"`
+}
+
+// VerifyEmailRequest 验证邮箱请求
+type VerifyEmailRequest struct {
+	// Token 验证邮件链接中的 token
+	Token string `json:"token" form:"token"`
+}
+
+// ChangePhoneRequest 修改手机号请求
+type ChangePhoneRequest struct {
+	// OldVerifyCode 当前手机号的验证码，没有绑定过手机号时不需要
+	OldVerifyCode int `json:"old_verify_code" form:"old_verify_code" example:"120110"`
+	Phone         int `json:"phone" form:"phone" binding:"required" example:"13010002000"`
+	VerifyCode    int `json:"verify_code" form:"verify_code" binding:"required" example:"120110"`
 }
 
 // FollowRequest 关注请求
@@ -46,17 +119,48 @@ type FollowRequest struct {
 	UserID uint64 `json:"user_id"`
 }
 
+// BlockRequest 拉黑、取消拉黑请求
+type BlockRequest struct {
+	UserID uint64 `json:"user_id" binding:"required"`
+}
+
+// UpdateNotificationPreferencesRequest 修改通知偏好请求
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []*notification.Preference `json:"preferences" binding:"required,dive"`
+}
+
+// SubmitAppealRequest 封禁申诉请求
+type SubmitAppealRequest struct {
+	Content string `json:"content" form:"content" binding:"required" example:"误封，请核实"`
+}
+
+// AcceptPolicyRequest 同意协议请求，version 需要是当前版本
+type AcceptPolicyRequest struct {
+	Type    string `json:"type" form:"type" binding:"required" example:"tos"`
+	Version string `json:"version" form:"version" binding:"required" example:"2020-10-01"`
+}
+
 // ListResponse 通用列表resp
 type ListResponse struct {
-	TotalCount uint64      `json:"total_count"`
-	HasMore    int         `json:"has_more"`
-	PageKey    string      `json:"page_key"`
-	PageValue  int         `json:"page_value"`
+	TotalCount uint64 `json:"total_count"`
+	HasMore    int    `json:"has_more"`
+	PageKey    string `json:"page_key"`
+	PageValue  int    `json:"page_value"`
+	// NextCursor 下一页的游标，请求下一页时作为 cursor 参数原样传回，没有下一页时为空
+	NextCursor string      `json:"next_cursor"`
 	Items      interface{} `json:"items"`
 }
 
+// followListPage 关注、粉丝列表每页的条数
+var followListPage = pagination.Options{DefaultSize: 10, MaxSize: 50}
+
 // SwaggerListResponse 文档
 type SwaggerListResponse struct {
 	TotalCount uint64           `json:"totalCount"`
 	UserList   []model.UserInfo `json:"userList"`
 }
+
+// sendBizErr 按错误分类返回，见 handler.SendError
+func sendBizErr(c *gin.Context, err error) {
+	handler.SendError(c, err)
+}
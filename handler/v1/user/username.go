@@ -0,0 +1,70 @@
+package user
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/idl"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// profilePath 用户主页的路径，用户名修改后旧链接重定向到这里
+const profilePath = "/v1/u/"
+
+// GetByUsername 通过用户名获取用户主页
+// @Summary 通过用户名获取用户主页
+// @Description 主页链接使用用户名，用户名修改后旧用户名的链接 301 重定向到新用户名
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param username path string true "用户名"
+// @Param time_format query string false "为 local 时返回按 Accept-Language 格式化的关注数、粉丝数"
+// @Success 200 {object} model.UserInfo "用户信息"
+// @Success 301 "用户名已修改，Location 为新用户名的主页"
+// @Router /u/{username} [get]
+func GetByUsername(c *gin.Context) {
+	username := c.Param("username")
+	if username == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	u, moved, err := user.Svc.ResolveUsername(c.Request.Context(), username)
+	if err != nil {
+		log.Warnf("resolve username err: %v", err)
+		sendBizErr(c, err)
+		return
+	}
+	if u.ID == 0 {
+		handler.SendResponse(c, errno.ErrUserNotFound, nil)
+		return
+	}
+	if moved {
+		location := profilePath + url.PathEscape(u.Username)
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+
+	infos, err := user.Svc.BatchGetUsers(c.Request.Context(), handler.GetUserID(c), []uint64{u.ID})
+	if err != nil || len(infos) == 0 {
+		log.Warnf("batch get users err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		return
+	}
+
+	// 记录主页浏览数
+	if err := user.Svc.IncrUserViewCount(u.ID); err != nil {
+		log.Warnf("incr user view count err: %v", err)
+	}
+
+	idl.LocalizeUsers(infos, handler.GetFormat(c))
+	handler.SendResponse(c, nil, infos[0])
+}
@@ -1,26 +1,35 @@
 package user
 
 import (
+	"time"
+
 	"github.com/1024casts/snake/handler"
-	"github.com/1024casts/snake/internal/service/sms"
-	"github.com/1024casts/snake/internal/service/vcode"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/otp"
+	smspkg "github.com/1024casts/snake/pkg/sms"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
 
+// vcodePurposes 可以通过短信获取验证码的用途
+var vcodePurposes = map[string]bool{
+	otp.PurposeLogin:  true,
+	otp.PurposeRebind: true,
+}
+
 // VCode 获取验证码
 // @Summary 根据手机号获取校验码
-// @Description Get an user by username
+// @Description 登录和换绑手机号的验证码互不通用，同一手机号 1 分钟内只能获取一次
 // @Tags 用户
 // @Accept  json
 // @Produce  json
 // @Param area_code query string true "区域码，比如86"
 // @Param phone query string true "手机号"
+// @Param purpose query string false "用途 login:登录(默认) rebind:换绑手机号"
 // @Success 200 {object} handler.Response
-// @Router /users/vcode [get]
+// @Router /vcode [get]
 func VCode(c *gin.Context) {
 	// 验证区号和手机号是否为空
 	if c.Query("area_code") == "" {
@@ -36,18 +45,29 @@ func VCode(c *gin.Context) {
 		return
 	}
 
-	// TODO: 频率控制，以防攻击
-
-	// 生成短信验证码
-	verifyCode, err := vcode.VCodeService.GenLoginVCode(phone)
-	if err != nil {
-		log.Warnf("gen login verify code err, %v", errors.WithStack(err))
-		handler.SendResponse(c, errno.ErrGenVCode, nil)
+	purpose := c.DefaultQuery("purpose", otp.PurposeLogin)
+	if !vcodePurposes[purpose] {
+		handler.SendResponse(c, errno.ErrParam, nil)
 		return
 	}
 
-	// 发送短信
-	err = sms.ServiceSms.Send(phone, verifyCode)
+	// 生成并发送短信验证码
+	err := otp.Default().Send(c.Request.Context(), otp.ChannelSMS, purpose, phone)
+	if err == otp.ErrResendTooSoon {
+		handler.SendResponse(c, errno.ErrSendSMSTooFrequent, nil)
+		return
+	}
+	// 同一手机号的发送频率见 sms.limits
+	var limitErr *smspkg.LimitError
+	if errors.As(err, &limitErr) {
+		log.Warnf("send phone sms too often, phone: %s, %v", phone, err)
+		if limitErr.Window >= 24*time.Hour {
+			handler.SendResponse(c, errno.ErrSendSMSTooMany, nil)
+			return
+		}
+		handler.SendResponse(c, errno.ErrSendSMSTooFrequent, nil)
+		return
+	}
 	if err != nil {
 		log.Warnf("send phone sms err, %v", errors.WithStack(err))
 		handler.SendResponse(c, errno.ErrSendSMS, nil)
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用注册后验证邮件中的 token 验证邮箱，token 只能使用一次
+// @Tags 用户
+// @Accept  json
+// @Produce  json
+// @Param req body VerifyEmailRequest true "验证邮件中的 token"
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		log.Warnf("verify email bind param err: %v", err)
+		handler.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	if req.Token == "" {
+		handler.SendResponse(c, errno.ErrParam, nil)
+		return
+	}
+
+	if err := user.Svc.VerifyEmail(c.Request.Context(), req.Token, c.ClientIP()); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
+
+// ResendVerification 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 向当前用户的邮箱重新发送验证邮件，之前发送的链接失效
+// @Tags 用户
+// @Produce  json
+// @Success 200 {object} handler.Response "{"code":0,"message":"OK","data":null}"
+// @Router /users/verify/resend [post]
+func ResendVerification(c *gin.Context) {
+	if err := user.Svc.SendVerificationEmail(c.Request.Context(), handler.GetUserID(c)); err != nil {
+		sendBizErr(c, err)
+		return
+	}
+
+	handler.SendResponse(c, nil, nil)
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/ws"
+)
+
+// WebSocket 实时消息
+// @Summary 建立 websocket 连接，接收通知等实时消息
+// @Description 通过 Sec-WebSocket-Protocol 请求协议版本，如 snake.v1，连接后服务端先发送 hello 消息，包括协商的版本和支持的版本；
+// @Description 请求的版本都不支持时返回 error 消息后断开。ack 为 true 的消息需要回复 {"type":"ack","id":"消息id"}，未确认的消息在重连后重新投递
+// @Tags 用户
+// @Param access_token query string false "浏览器不能设置请求头时通过参数传递 token"
+// @Success 101 {object} ws.Envelope "消息"
+// @Router /ws [get]
+func WebSocket(c *gin.Context) {
+	if ws.Client == nil {
+		handler.SendResponse(c, errno.ErrWebSocketDisabled, nil)
+		return
+	}
+
+	// 升级失败时已经返回了 http 错误
+	if err := ws.Client.Serve(c.Writer, c.Request, handler.GetUserID(c)); err != nil {
+		log.Warnf("[ws] upgrade err: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package ban
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixBanCacheKey 用户当前的封禁，没有封禁时保存空对象，避免每次请求都查库
+	PrefixBanCacheKey = "user:ban:%d"
+	// DefaultExpireTime 默认过期时间
+	DefaultExpireTime = 5 * time.Minute
+)
+
+// Cache 用户封禁状态的缓存
+type Cache struct{}
+
+// NewBanCache new一个封禁cache
+func NewBanCache() *Cache {
+	return &Cache{}
+}
+
+// GetBanCacheKey 获取封禁的cache key
+func (c *Cache) GetBanCacheKey(userID uint64) string {
+	return cache.PrefixCacheKey + ":" + fmt.Sprintf(PrefixBanCacheKey, userID)
+}
+
+// GetBan 获取缓存的封禁，ok 为 false 表示未缓存，没有封禁时返回空结构体
+func (c *Cache) GetBan(userID uint64) (ban *model.UserBanModel, ok bool, err error) {
+	val, err := redis.RedisClient.Get(c.GetBanCacheKey(userID)).Bytes()
+	if err == goredis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	ban = &model.UserBanModel{}
+	if err := json.Unmarshal(val, ban); err != nil {
+		return nil, false, err
+	}
+	return ban, true, nil
+}
+
+// SetBan 缓存封禁，封禁到期时间早于默认过期时间时按到期时间过期
+func (c *Cache) SetBan(userID uint64, ban *model.UserBanModel) error {
+	b, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	ttl := DefaultExpireTime
+	if ban.ExpiredAt != nil {
+		if d := time.Until(*ban.ExpiredAt); d > 0 && d < ttl {
+			ttl = d
+		}
+	}
+	return redis.RedisClient.Set(c.GetBanCacheKey(userID), b, ttl).Err()
+}
+
+// DelBan 删除缓存，封禁状态变化后调用
+func (c *Cache) DelBan(userID uint64) error {
+	return redis.RedisClient.Del(c.GetBanCacheKey(userID)).Err()
+}
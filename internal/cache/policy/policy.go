@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixAcceptedCacheKey 用户是否同意了某个版本的协议，1:已同意 0:未同意
+	PrefixAcceptedCacheKey = "user:policy:%d:%d"
+	// DefaultExpireTime 默认过期时间
+	DefaultExpireTime = 10 * time.Minute
+)
+
+// Cache 用户同意协议状态的缓存
+type Cache struct{}
+
+// NewPolicyCache new一个协议cache
+func NewPolicyCache() *Cache {
+	return &Cache{}
+}
+
+// GetAcceptedCacheKey 获取同意状态的cache key
+func (c *Cache) GetAcceptedCacheKey(userID, policyID uint64) string {
+	return cache.PrefixCacheKey + ":" + fmt.Sprintf(PrefixAcceptedCacheKey, userID, policyID)
+}
+
+// GetAccepted 获取缓存的同意状态，ok 为 false 表示未缓存
+func (c *Cache) GetAccepted(userID, policyID uint64) (accepted bool, ok bool, err error) {
+	val, err := redis.RedisClient.Get(c.GetAcceptedCacheKey(userID, policyID)).Result()
+	if err == goredis.Nil {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return val == "1", true, nil
+}
+
+// SetAccepted 缓存同意状态
+func (c *Cache) SetAccepted(userID, policyID uint64, accepted bool) error {
+	val := "0"
+	if accepted {
+		val = "1"
+	}
+	return redis.RedisClient.Set(c.GetAcceptedCacheKey(userID, policyID), val, DefaultExpireTime).Err()
+}
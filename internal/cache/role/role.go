@@ -0,0 +1,66 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixRoleCacheKey 用户拥有的角色，没有角色时保存空数组，避免每次请求都查库
+	PrefixRoleCacheKey = "user:role:%d"
+	// DefaultExpireTime 默认过期时间
+	DefaultExpireTime = 5 * time.Minute
+)
+
+// Cache 用户角色的缓存
+type Cache struct{}
+
+// NewRoleCache new一个角色cache
+func NewRoleCache() *Cache {
+	return &Cache{}
+}
+
+// GetRoleCacheKey 获取角色的cache key
+func (c *Cache) GetRoleCacheKey(userID uint64) string {
+	return cache.PrefixCacheKey + ":" + fmt.Sprintf(PrefixRoleCacheKey, userID)
+}
+
+// GetRoles 获取缓存的角色，ok 为 false 表示未缓存
+func (c *Cache) GetRoles(ctx context.Context, userID uint64) (roles []string, ok bool, err error) {
+	val, err := redis.WithContext(ctx).Get(c.GetRoleCacheKey(userID)).Bytes()
+	if err == goredis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := json.Unmarshal(val, &roles); err != nil {
+		return nil, false, err
+	}
+	return roles, true, nil
+}
+
+// SetRoles 缓存角色
+func (c *Cache) SetRoles(ctx context.Context, userID uint64, roles []string) error {
+	if roles == nil {
+		roles = []string{}
+	}
+	b, err := json.Marshal(roles)
+	if err != nil {
+		return err
+	}
+	return redis.WithContext(ctx).Set(c.GetRoleCacheKey(userID), b, DefaultExpireTime).Err()
+}
+
+// DelRoles 删除缓存，角色变化后调用
+func (c *Cache) DelRoles(userID uint64) error {
+	return redis.RedisClient.Del(c.GetRoleCacheKey(userID)).Err()
+}
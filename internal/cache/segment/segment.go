@@ -0,0 +1,111 @@
+package segment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	redis2 "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixSegmentCacheKey 分群用户的有序集合，member 和 score 都为用户id，按id分页时使用 ZRANGEBYSCORE
+	PrefixSegmentCacheKey = "segment_users:%s"
+
+	// buildingTTL 临时集合的过期时间，计算中断时不会一直占用内存
+	buildingTTL = time.Hour
+)
+
+// Cache 用户分群的 redis 有序集合
+// 计算时先写入临时集合，完成后再替换，避免读到计算了一半的数据
+type Cache struct{}
+
+// NewSegmentCache new一个分群cache
+func NewSegmentCache() *Cache {
+	return &Cache{}
+}
+
+// GetSegmentCacheKey 获取分群的cache key
+func (c *Cache) GetSegmentCacheKey(name string) string {
+	return cache.PrefixCacheKey + ":" + fmt.Sprintf(PrefixSegmentCacheKey, name)
+}
+
+// GetBuildingCacheKey 获取计算中的临时 key
+func (c *Cache) GetBuildingCacheKey(name string) string {
+	return fmt.Sprintf("%s:building:%d", c.GetSegmentCacheKey(name), time.Now().UnixNano())
+}
+
+// AddMembers 添加用户到临时集合，每次添加时延长临时集合的过期时间
+func (c *Cache) AddMembers(key string, userIDs []uint64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	members := make([]redis2.Z, 0, len(userIDs))
+	for _, userID := range userIDs {
+		members = append(members, redis2.Z{Score: float64(userID), Member: userID})
+	}
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.ZAdd(key, members...)
+	pipe.Expire(key, buildingTTL)
+	_, err := pipe.Exec()
+	return err
+}
+
+// DelBuilding 删除计算失败的临时集合
+func (c *Cache) DelBuilding(key string) error {
+	return redis.RedisClient.Del(key).Err()
+}
+
+// Replace 用计算好的临时集合替换分群集合，临时集合不存在表示分群没有用户
+// rename 会带上临时集合的过期时间，替换后需要去掉
+func (c *Cache) Replace(name, buildingKey string) error {
+	key := c.GetSegmentCacheKey(name)
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.Rename(buildingKey, key)
+	pipe.Persist(key)
+	_, err := pipe.Exec()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		return redis.RedisClient.Del(key).Err()
+	}
+	return err
+}
+
+// IsMember 用户是否在分群中
+func (c *Cache) IsMember(name string, userID uint64) (bool, error) {
+	err := redis.RedisClient.ZScore(c.GetSegmentCacheKey(name), strconv.FormatUint(userID, 10)).Err()
+	if err == redis2.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Count 分群的用户数
+func (c *Cache) Count(name string) (int64, error) {
+	return redis.RedisClient.ZCard(c.GetSegmentCacheKey(name)).Result()
+}
+
+// Range 按id正序获取 id 大于 lastID 的 limit 个用户
+func (c *Cache) Range(name string, lastID uint64, limit int) ([]uint64, error) {
+	members, err := redis.RedisClient.ZRangeByScore(c.GetSegmentCacheKey(name), redis2.ZRangeBy{
+		Min:   "(" + strconv.FormatUint(lastID, 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint64, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,118 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+const (
+	// PrefixMultipartCacheKey 分片上传的进度，hash 中 meta 为上传信息，part:N 为已上传的分片
+	PrefixMultipartCacheKey = "upload:multipart:%s"
+
+	fieldMeta       = "meta"
+	fieldPartPrefix = "part:"
+)
+
+// MultipartUpload 分片上传信息
+type MultipartUpload struct {
+	UploadID    string    `json:"upload_id"`
+	UserID      uint64    `json:"user_id"`
+	Category    string    `json:"category"`
+	Key         string    `json:"key"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	PartSize    int64     `json:"part_size"`
+	TotalParts  int       `json:"total_parts"`
+	ExpiredAt   time.Time `json:"expired_at"`
+}
+
+// MultipartCache 分片上传进度，客户端断线后可以查询已上传的分片继续上传
+type MultipartCache struct{}
+
+// NewMultipartCache new一个分片上传cache
+func NewMultipartCache() *MultipartCache {
+	return &MultipartCache{}
+}
+
+// GetMultipartCacheKey 获取分片上传的cache key
+func (c *MultipartCache) GetMultipartCacheKey(uploadID string) string {
+	return cache.PrefixCacheKey + ":" + fmt.Sprintf(PrefixMultipartCacheKey, uploadID)
+}
+
+// SetUpload 保存上传信息，过期后未完成的上传会被丢弃
+func (c *MultipartCache) SetUpload(upload *MultipartUpload, ttl time.Duration) error {
+	b, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	key := c.GetMultipartCacheKey(upload.UploadID)
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.HSet(key, fieldMeta, b)
+	pipe.Expire(key, ttl)
+	_, err = pipe.Exec()
+	return err
+}
+
+// GetUpload 获取上传信息，不存在时返回nil
+func (c *MultipartCache) GetUpload(uploadID string) (*MultipartUpload, error) {
+	b, err := redis.RedisClient.HGet(c.GetMultipartCacheKey(uploadID), fieldMeta).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &MultipartUpload{}
+	if err := json.Unmarshal(b, upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// SetPart 记录已上传的分片，重复上传时覆盖
+func (c *MultipartCache) SetPart(uploadID string, part *storage.Part) error {
+	b, err := json.Marshal(part)
+	if err != nil {
+		return err
+	}
+	return redis.RedisClient.HSet(c.GetMultipartCacheKey(uploadID), fieldPartPrefix+strconv.Itoa(part.Number), b).Err()
+}
+
+// GetParts 获取已上传的分片，按序号排序
+func (c *MultipartCache) GetParts(uploadID string) ([]storage.Part, error) {
+	fields, err := redis.RedisClient.HGetAll(c.GetMultipartCacheKey(uploadID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]storage.Part, 0, len(fields))
+	for field, value := range fields {
+		if !strings.HasPrefix(field, fieldPartPrefix) {
+			continue
+		}
+		var part storage.Part
+		if err := json.Unmarshal([]byte(value), &part); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+	return parts, nil
+}
+
+// Delete 删除上传进度
+func (c *MultipartCache) Delete(uploadID string) error {
+	return redis.RedisClient.Del(c.GetMultipartCacheKey(uploadID)).Err()
+}
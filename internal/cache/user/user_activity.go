@@ -0,0 +1,81 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixUserActivityCacheKey 用户最近的动作，stream 消息 id 的毫秒时间戳即动作时间
+	PrefixUserActivityCacheKey = "user:activity:%d"
+	// MaxUserActivitySize 每个用户保留的动作条数，超出的近似裁剪，更早的从数据库归档中查询
+	MaxUserActivitySize = 1000
+	// UserActivityExpireTime 一段时间没有新动作时整个 stream 过期
+	UserActivityExpireTime = 30 * 24 * time.Hour
+)
+
+// GetUserActivityCacheKey 获取用户动作的cache key
+func (u *Cache) GetUserActivityCacheKey(userID uint64) string {
+	return fmt.Sprintf(cache.PrefixCacheKey+":"+PrefixUserActivityCacheKey, userID)
+}
+
+// AddUserActivity 追加一条动作，返回 stream 的消息 id
+func (u *Cache) AddUserActivity(a *model.UserActivityModel) (string, error) {
+	key := u.GetUserActivityCacheKey(a.UserID)
+	pipe := redis.RedisClient.TxPipeline()
+	add := pipe.XAdd(&goredis.XAddArgs{
+		Stream:       key,
+		MaxLenApprox: MaxUserActivitySize,
+		Values: map[string]interface{}{
+			"action": a.Action,
+			"detail": a.Detail,
+			"ip":     a.IP,
+		},
+	})
+	pipe.Expire(key, UserActivityExpireTime)
+	if _, err := pipe.Exec(); err != nil {
+		return "", err
+	}
+	return add.Val(), nil
+}
+
+// GetUserActivity 按时间倒序获取 activity_id 小于 beforeID 的动作，beforeID 为0时从最新的开始
+func (u *Cache) GetUserActivity(userID uint64, beforeID uint64, limit int) ([]*model.UserActivityModel, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	start := "+"
+	if beforeID > 0 {
+		start = model.ActivityStreamID(beforeID - 1)
+	}
+	msgs, err := redis.RedisClient.XRevRangeN(u.GetUserActivityCacheKey(userID), start, "-", int64(limit)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]*model.UserActivityModel, 0, len(msgs))
+	for _, msg := range msgs {
+		id, err := model.ParseActivityID(msg.ID)
+		if err != nil {
+			continue
+		}
+		action, _ := msg.Values["action"].(string)
+		detail, _ := msg.Values["detail"].(string)
+		ip, _ := msg.Values["ip"].(string)
+		activities = append(activities, &model.UserActivityModel{
+			UserID:     userID,
+			ActivityID: id,
+			Action:     action,
+			Detail:     detail,
+			IP:         ip,
+			CreatedAt:  model.ActivityTime(id),
+		})
+	}
+	return activities, nil
+}
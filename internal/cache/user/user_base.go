@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/cache/typed"
 	"github.com/1024casts/snake/pkg/redis"
 )
 
@@ -16,74 +19,78 @@ const (
 	DefaultExpireTime = time.Hour * 24
 )
 
-// Cache cache
-type Cache struct {
-	cache cache.Driver
-}
+// Cache cache，每次使用时读取 redis.RedisClient，实例化时配置可能还没有加载
+type Cache struct{}
 
 // NewUserCache new一个用户cache
 func NewUserCache() *Cache {
-	encoding := cache.JSONEncoding{}
-	cachePrefix := cache.PrefixCacheKey
-	redis.Init()
-	return &Cache{
-		cache: cache.NewRedisCache(redis.RedisClient, cachePrefix, encoding, func() interface{} {
-			return &model.UserBaseModel{}
-		}),
-	}
+	redis.Connect()
+	return &Cache{}
 }
 
-// GetUserBaseCacheKey 获取cache key
-func (u *Cache) GetUserBaseCacheKey(userID uint64) string {
-	return fmt.Sprintf(cache.PrefixCacheKey+":"+PrefixUserBaseCacheKey, userID)
-}
-
-// SetUserBaseCache 写入用户cache
+// SetUserBaseCache 写入用户cache, 用户不存在时写入空对象
 func (u *Cache) SetUserBaseCache(userID uint64, user *model.UserBaseModel) error {
-	if user == nil || user.ID == 0 {
-		return nil
-	}
 	cacheKey := fmt.Sprintf(PrefixUserBaseCacheKey, userID)
-	err := u.cache.Set(cacheKey, user, DefaultExpireTime)
-	if err != nil {
-		return err
+	if user == nil || user.ID == 0 {
+		return typed.SetNil(redis.RedisClient, cacheKey, typed.WithName("user_base"))
 	}
-	return nil
+	return typed.Set(redis.RedisClient, cacheKey, user, DefaultExpireTime, typed.WithName("user_base"))
 }
 
 // GetUserBaseCache 获取用户cache
-func (u *Cache) GetUserBaseCache(userID uint64) (userModel *model.UserBaseModel, err error) {
+// 未命中时返回 typed.ErrCacheMiss, 命中空对象时返回 typed.ErrNotFound
+func (u *Cache) GetUserBaseCache(userID uint64) (*model.UserBaseModel, error) {
 	cacheKey := fmt.Sprintf(PrefixUserBaseCacheKey, userID)
-	err = u.cache.Get(cacheKey, &userModel)
-	if err != nil {
-		return userModel, err
-	}
-	return userModel, nil
+	return typed.Get[*model.UserBaseModel](redis.RedisClient, cacheKey, typed.WithName("user_base"))
 }
 
 // MultiGetUserBaseCache 批量获取用户cache
-func (u *Cache) MultiGetUserBaseCache(userIDs []uint64) (map[string]*model.UserBaseModel, error) {
-	var keys []string
+// 返回命中的用户和命中空对象的用户id，其余的为未命中
+func (u *Cache) MultiGetUserBaseCache(userIDs []uint64) (map[uint64]*model.UserBaseModel, map[uint64]bool, error) {
+	keys := make([]string, 0, len(userIDs))
+	ids := make(map[string]uint64, len(userIDs))
 	for _, v := range userIDs {
 		cacheKey := fmt.Sprintf(PrefixUserBaseCacheKey, v)
 		keys = append(keys, cacheKey)
+		ids[cacheKey] = v
 	}
 
-	// 需要在这里make实例化，如果在返回参数里直接定义会报 nil map
-	userMap := make(map[string]*model.UserBaseModel)
-	err := u.cache.MultiGet(keys, userMap)
+	vals, nilKeys, err := typed.MultiGet[*model.UserBaseModel](redis.RedisClient, keys, typed.WithName("user_base"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	userMap := make(map[uint64]*model.UserBaseModel, len(vals))
+	for key, user := range vals {
+		userMap[ids[key]] = user
+	}
+	notFound := make(map[uint64]bool, len(nilKeys))
+	for key := range nilKeys {
+		notFound[ids[key]] = true
+	}
+	return userMap, notFound, nil
+}
+
+// MultiSetUserBaseCache 批量写入用户cache，不存在的用户写入空对象
+func (u *Cache) MultiSetUserBaseCache(users []*model.UserBaseModel, notFoundIDs []uint64) error {
+	vals := make(map[string]*model.UserBaseModel, len(users))
+	for _, user := range users {
+		vals[fmt.Sprintf(PrefixUserBaseCacheKey, user.ID)] = user
+	}
+	nilKeys := make([]string, 0, len(notFoundIDs))
+	for _, id := range notFoundIDs {
+		nilKeys = append(nilKeys, fmt.Sprintf(PrefixUserBaseCacheKey, id))
 	}
-	return userMap, nil
+	return typed.MultiSet(redis.RedisClient, vals, nilKeys, DefaultExpireTime, typed.WithName("user_base"))
 }
 
 // DelUserBaseCache 删除用户cache
 func (u *Cache) DelUserBaseCache(userID uint64) error {
-	cacheKey := fmt.Sprintf(PrefixUserBaseCacheKey, userID)
-	err := u.cache.Del(cacheKey)
+	cacheKey, err := cache.BuildCacheKey(cache.PrefixCacheKey, fmt.Sprintf(PrefixUserBaseCacheKey, userID))
 	if err != nil {
 		return err
 	}
+	if err := redis.RedisClient.Del(cacheKey).Err(); err != nil {
+		return errors.Wrapf(err, "redis delete error, key is %s", cacheKey)
+	}
 	return nil
 }
@@ -0,0 +1,59 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixUserFeedCacheKey 用户的动态，有序集合 member 为事件json, score 为事件偏移量
+	PrefixUserFeedCacheKey = "user:feed:%d"
+	// MaxUserFeedSize 每个用户保留的动态条数
+	MaxUserFeedSize = 200
+)
+
+// GetUserFeedCacheKey 获取用户动态的cache key
+func (u *Cache) GetUserFeedCacheKey(userID uint64) string {
+	return fmt.Sprintf(cache.PrefixCacheKey+":"+PrefixUserFeedCacheKey, userID)
+}
+
+// AddUserFeed 添加一条动态，同一事件重复添加不会产生多条
+func (u *Cache) AddUserFeed(event *model.UserEventModel) error {
+	member, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key := u.GetUserFeedCacheKey(event.UserID)
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.ZAdd(key, goredis.Z{Score: float64(event.ID), Member: string(member)})
+	pipe.ZRemRangeByRank(key, 0, -MaxUserFeedSize-1)
+	_, err = pipe.Exec()
+	return err
+}
+
+// GetUserFeed 获取最新的动态
+func (u *Cache) GetUserFeed(userID uint64, limit int) ([]*model.UserEventModel, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	members, err := redis.RedisClient.ZRevRange(u.GetUserFeedCacheKey(userID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*model.UserEventModel, 0, len(members))
+	for _, member := range members {
+		event := &model.UserEventModel{}
+		if err := json.Unmarshal([]byte(member), event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"strconv"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixUserHotCacheKey 热点用户访问计数，有序集合 member 为用户id, score 为访问次数
+	PrefixUserHotCacheKey = "user:hot"
+	// hotDecayWeight 每轮预热后访问计数的衰减系数，让过气的热点逐渐淘汰
+	hotDecayWeight = 0.5
+)
+
+// GetUserHotCacheKey 获取热点用户的cache key
+func (u *Cache) GetUserHotCacheKey() string {
+	return cache.PrefixCacheKey + ":" + PrefixUserHotCacheKey
+}
+
+// IncrUserAccess 用户访问计数加1
+func (u *Cache) IncrUserAccess(userID uint64) error {
+	return redis.RedisClient.ZIncrBy(u.GetUserHotCacheKey(), 1, strconv.FormatUint(userID, 10)).Err()
+}
+
+// GetHotUserIDs 获取访问次数最多的用户id
+func (u *Cache) GetHotUserIDs(limit int) ([]uint64, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	members, err := redis.RedisClient.ZRevRange(u.GetUserHotCacheKey(), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uint64, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// DecayUserAccess 衰减访问计数，并只保留前 limit 个用户
+func (u *Cache) DecayUserAccess(limit int) error {
+	key := u.GetUserHotCacheKey()
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.ZUnionStore(key, goredis.ZStore{Weights: []float64{hotDecayWeight}}, key)
+	pipe.ZRemRangeByRank(key, 0, int64(-limit-1))
+	_, err := pipe.Exec()
+	return err
+}
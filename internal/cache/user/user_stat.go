@@ -0,0 +1,56 @@
+package user
+
+import (
+	"fmt"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/cache/typed"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// PrefixUserStatCacheKey 数据库中的关注数和粉丝数，不包含计数缓冲中的增量
+const PrefixUserStatCacheKey = "user:stat:%d"
+
+// MultiGetFollowCountsCache 批量获取关注数和粉丝数的cache，未命中的不在返回值中
+func (u *Cache) MultiGetFollowCountsCache(userIDs []uint64) (map[uint64]*model.FollowCounts, error) {
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, fmt.Sprintf(PrefixUserStatCacheKey, id))
+	}
+
+	vals, _, err := typed.MultiGet[*model.FollowCounts](redis.RedisClient, keys, typed.WithName("user_stat"))
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint64]*model.FollowCounts, len(vals))
+	for _, v := range vals {
+		counts[v.UserID] = v
+	}
+	return counts, nil
+}
+
+// MultiSetFollowCountsCache 批量写入关注数和粉丝数的cache，没有统计数据的用户写入0
+func (u *Cache) MultiSetFollowCountsCache(counts []*model.FollowCounts) error {
+	vals := make(map[string]*model.FollowCounts, len(counts))
+	for _, v := range counts {
+		vals[fmt.Sprintf(PrefixUserStatCacheKey, v.UserID)] = v
+	}
+	return typed.MultiSet(redis.RedisClient, vals, nil, DefaultExpireTime, typed.WithName("user_stat"))
+}
+
+// MultiDelFollowCountsCache 批量删除关注数和粉丝数的cache，计数写入数据库后调用
+func (u *Cache) MultiDelFollowCountsCache(userIDs []uint64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		key, err := cache.BuildCacheKey(cache.PrefixCacheKey, fmt.Sprintf(PrefixUserStatCacheKey, id))
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	return redis.RedisClient.Del(keys...).Err()
+}
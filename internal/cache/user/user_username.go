@@ -0,0 +1,41 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/cache/typed"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// PrefixUsernameCacheKey 用户名到用户id的映射，用户名不区分大小写
+const PrefixUsernameCacheKey = "user:username:%s"
+
+// SetUsernameCache 写入用户名对应的用户id，用户不存在时写入空对象
+func (u *Cache) SetUsernameCache(username string, userID uint64) error {
+	cacheKey := usernameCacheKey(username)
+	if userID == 0 {
+		return typed.SetNil(redis.RedisClient, cacheKey, typed.WithName("user_username"))
+	}
+	return typed.Set(redis.RedisClient, cacheKey, userID, DefaultExpireTime, typed.WithName("user_username"))
+}
+
+// GetUsernameCache 获取用户名对应的用户id
+// 未命中时返回 typed.ErrCacheMiss, 命中空对象时返回 typed.ErrNotFound
+func (u *Cache) GetUsernameCache(username string) (uint64, error) {
+	return typed.Get[uint64](redis.RedisClient, usernameCacheKey(username), typed.WithName("user_username"))
+}
+
+// DelUsernameCache 删除用户名的映射
+func (u *Cache) DelUsernameCache(username string) error {
+	cacheKey, err := cache.BuildCacheKey(cache.PrefixCacheKey, usernameCacheKey(username))
+	if err != nil {
+		return err
+	}
+	return redis.RedisClient.Del(cacheKey).Err()
+}
+
+func usernameCacheKey(username string) string {
+	return fmt.Sprintf(PrefixUsernameCacheKey, strings.ToLower(username))
+}
@@ -0,0 +1,39 @@
+package idl
+
+import (
+	"github.com/1024casts/snake/internal/model"
+)
+
+// DeviceInfo 对外输出的登录设备
+type DeviceInfo struct {
+	ID          uint64 `json:"id"`
+	UserID      uint64 `json:"user_id"`
+	DeviceType  string `json:"device_type"`
+	Device      string `json:"device"`
+	OS          string `json:"os"`
+	OSVersion   string `json:"os_version"`
+	Browser     string `json:"browser"`
+	LastIP      string `json:"last_ip"`
+	LastLoginAt Time   `json:"last_login_at" swaggertype:"primitive,string"`
+	CreatedAt   Time   `json:"created_at" swaggertype:"primitive,string"`
+}
+
+// TransferDevices 转换登录设备列表，时间按查看者的格式输出
+func TransferDevices(devices []*model.UserDeviceModel, f Format) []*DeviceInfo {
+	infos := make([]*DeviceInfo, 0, len(devices))
+	for _, d := range devices {
+		infos = append(infos, &DeviceInfo{
+			ID:          d.ID,
+			UserID:      d.UserID,
+			DeviceType:  d.DeviceType,
+			Device:      d.Device,
+			OS:          d.OS,
+			OSVersion:   d.OSVersion,
+			Browser:     d.Browser,
+			LastIP:      d.LastIP,
+			LastLoginAt: f.Time(d.LastLoginAt),
+			CreatedAt:   f.Time(d.CreatedAt),
+		})
+	}
+	return infos
+}
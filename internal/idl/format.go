@@ -0,0 +1,157 @@
+package idl
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 输出格式，客户端通过请求头 X-Time-Format 或参数 time_format 指定
+const (
+	// FormatRFC3339 默认格式，带时区偏移的 RFC3339 时间，按查看者的时区输出
+	FormatRFC3339 = "rfc3339"
+	// FormatEpoch 时间输出为 {"ts": 秒级时间戳, "tz": "Asia/Shanghai"}，客户端自行格式化
+	FormatEpoch = "epoch"
+	// FormatLocal 按查看者的语言输出格式化好的时间和数字，如 2024年1月2日 15:04、1.2万
+	FormatLocal = "local"
+)
+
+// 支持的语言
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
+
+// 按语言格式化时间的布局
+var localTimeLayouts = map[string]string{
+	LocaleZH: "2006年1月2日 15:04",
+	LocaleEN: "Jan 2, 2006 3:04 PM",
+}
+
+// Format 查看者的输出格式，零值保持时间原来的时区和 RFC3339 格式
+type Format struct {
+	Mode     string
+	Location *time.Location
+	Locale   string
+}
+
+// NewFormat 按请求解析查看者的输出格式
+// 时区优先使用请求头 X-Timezone，其次是用户资料中地区对应的时区(i18n.timezones)，最后是 i18n.default_timezone
+// 语言从 Accept-Language 中按顺序取第一个支持的语言，没有时使用 i18n.default_locale
+func NewFormat(mode, timezone, acceptLanguage, region string) Format {
+	f := Format{Mode: FormatRFC3339, Locale: parseLocale(acceptLanguage)}
+	switch mode = strings.ToLower(mode); mode {
+	case FormatEpoch, FormatLocal:
+		f.Mode = mode
+	}
+
+	for _, name := range []string{timezone, viper.GetStringMapString("i18n.timezones")[strings.ToLower(region)], viper.GetString("i18n.default_timezone")} {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			f.Location = loc
+			break
+		}
+	}
+	return f
+}
+
+// parseLocale 解析 Accept-Language，如 en-US,en;q=0.9,zh-CN;q=0.8
+func parseLocale(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.TrimSpace(part)
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if strings.TrimSpace(tag[i+1:]) == "q=0" {
+				continue
+			}
+			tag = tag[:i]
+		}
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		if _, ok := localTimeLayouts[strings.ToLower(tag)]; ok {
+			return strings.ToLower(tag)
+		}
+	}
+	if locale := viper.GetString("i18n.default_locale"); locale != "" {
+		if _, ok := localTimeLayouts[locale]; ok {
+			return locale
+		}
+	}
+	return LocaleZH
+}
+
+// Time 按查看者的格式输出的时间
+type Time struct {
+	t time.Time
+	f Format
+}
+
+// Time 转换时间
+func (f Format) Time(t time.Time) Time {
+	return Time{t: t, f: f}
+}
+
+// TimePtr 转换可能为空的时间，为空时输出 null
+func (f Format) TimePtr(t *time.Time) *Time {
+	if t == nil {
+		return nil
+	}
+	v := f.Time(*t)
+	return &v
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (t Time) MarshalJSON() ([]byte, error) {
+	v := t.t
+	if t.f.Location != nil {
+		v = v.In(t.f.Location)
+	}
+
+	switch t.f.Mode {
+	case FormatEpoch:
+		return json.Marshal(struct {
+			TS int64  `json:"ts"`
+			TZ string `json:"tz"`
+		}{v.Unix(), v.Location().String()})
+	case FormatLocal:
+		layout, ok := localTimeLayouts[t.f.Locale]
+		if !ok {
+			layout = localTimeLayouts[LocaleZH]
+		}
+		return json.Marshal(v.Format(layout))
+	}
+	return v.MarshalJSON()
+}
+
+// 按语言缩写数字的单位，从大到小
+var countUnits = map[string][]struct {
+	value  int64
+	suffix string
+}{
+	LocaleZH: {{100000000, "亿"}, {10000, "万"}},
+	LocaleEN: {{1000000000, "B"}, {1000000, "M"}, {1000, "K"}},
+}
+
+// Count 按查看者的语言格式化计数，如粉丝数 12345 输出 1.2万 或 12.3K，只在 FormatLocal 时返回
+func (f Format) Count(n int64) string {
+	if f.Mode != FormatLocal {
+		return ""
+	}
+	units, ok := countUnits[f.Locale]
+	if !ok {
+		units = countUnits[LocaleZH]
+	}
+	for _, u := range units {
+		if n >= u.value || n <= -u.value {
+			// 保留一位小数并去掉末尾的 .0，截断而不是四舍五入，避免 99999 显示为 10万
+			v := float64(n/(u.value/10)) / 10
+			return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0") + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,88 @@
+package idl
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestNewFormat(t *testing.T) {
+	viper.Set("i18n.timezones", map[string]string{"us": "America/New_York"})
+	viper.Set("i18n.default_timezone", "Asia/Shanghai")
+	t.Cleanup(func() {
+		viper.Set("i18n.timezones", nil)
+		viper.Set("i18n.default_timezone", "")
+	})
+
+	cases := []struct {
+		mode, tz, lang, region string
+		wantMode, wantTZ       string
+		wantLocale             string
+	}{
+		{"", "", "", "", FormatRFC3339, "Asia/Shanghai", LocaleZH},
+		{"EPOCH", "Europe/Berlin", "en-US,en;q=0.9", "us", FormatEpoch, "Europe/Berlin", LocaleEN},
+		// 时区无效时使用地区的时区
+		{"local", "Mars/Base", "fr-FR,zh-CN;q=0.8", "us", FormatLocal, "America/New_York", LocaleZH},
+		{"unknown", "", "ja;q=0, en", "cn", FormatRFC3339, "Asia/Shanghai", LocaleEN},
+	}
+	for _, c := range cases {
+		f := NewFormat(c.mode, c.tz, c.lang, c.region)
+		if f.Mode != c.wantMode || f.Location.String() != c.wantTZ || f.Locale != c.wantLocale {
+			t.Fatalf("NewFormat(%q, %q, %q, %q) = %s %s %s, want %s %s %s", c.mode, c.tz, c.lang, c.region,
+				f.Mode, f.Location, f.Locale, c.wantMode, c.wantTZ, c.wantLocale)
+		}
+	}
+}
+
+func TestTime_MarshalJSON(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	ts := time.Date(2024, 1, 2, 7, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		f    Format
+		want string
+	}{
+		// 零值和原来的输出一致
+		{Format{}, `"2024-01-02T07:04:05Z"`},
+		{Format{Mode: FormatRFC3339, Location: shanghai}, `"2024-01-02T15:04:05+08:00"`},
+		{Format{Mode: FormatEpoch, Location: shanghai}, `{"ts":1704179045,"tz":"Asia/Shanghai"}`},
+		{Format{Mode: FormatLocal, Location: shanghai, Locale: LocaleZH}, `"2024年1月2日 15:04"`},
+		{Format{Mode: FormatLocal, Location: shanghai, Locale: LocaleEN}, `"Jan 2, 2024 3:04 PM"`},
+	}
+	for _, c := range cases {
+		b, err := json.Marshal(c.f.Time(ts))
+		if err != nil || string(b) != c.want {
+			t.Fatalf("Marshal(%+v) = %s, %v, want %s", c.f, b, err, c.want)
+		}
+	}
+
+	if b, _ := json.Marshal(Format{}.TimePtr(nil)); string(b) != "null" {
+		t.Fatalf("Marshal(nil) = %s, want null", b)
+	}
+}
+
+func TestFormat_Count(t *testing.T) {
+	zh := Format{Mode: FormatLocal, Locale: LocaleZH}
+	en := Format{Mode: FormatLocal, Locale: LocaleEN}
+	cases := []struct {
+		f    Format
+		n    int64
+		want string
+	}{
+		{zh, 9999, "9999"},
+		{zh, 12345, "1.2万"},
+		{zh, 99999, "9.9万"},
+		{zh, 150000000, "1.5亿"},
+		{en, 999, "999"},
+		{en, 12345, "12.3K"},
+		{en, 2000000, "2M"},
+		{Format{}, 12345, ""},
+	}
+	for _, c := range cases {
+		if got := c.f.Count(c.n); got != c.want {
+			t.Fatalf("Count(%d) %s = %q, want %q", c.n, c.f.Locale, got, c.want)
+		}
+	}
+}
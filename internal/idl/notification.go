@@ -0,0 +1,35 @@
+package idl
+
+import (
+	"github.com/1024casts/snake/internal/model"
+)
+
+// NotificationInfo 对外输出的站内通知
+type NotificationInfo struct {
+	ID        uint64 `json:"id"`
+	UserID    uint64 `json:"user_id"`
+	EventType string `json:"event_type"`
+	RefID     uint64 `json:"ref_id"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	IsRead    int    `json:"is_read"`
+	CreatedAt Time   `json:"created_at" swaggertype:"primitive,string"`
+}
+
+// TransferNotifications 转换站内通知列表，时间按查看者的格式输出
+func TransferNotifications(notifications []*model.NotificationModel, f Format) []*NotificationInfo {
+	infos := make([]*NotificationInfo, 0, len(notifications))
+	for _, n := range notifications {
+		infos = append(infos, &NotificationInfo{
+			ID:        n.ID,
+			UserID:    n.UserID,
+			EventType: n.EventType,
+			RefID:     n.RefID,
+			Title:     n.Title,
+			Content:   n.Content,
+			IsRead:    n.IsRead,
+			CreatedAt: f.Time(n.CreatedAt),
+		})
+	}
+	return infos
+}
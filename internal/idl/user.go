@@ -47,3 +47,17 @@ func transferUserFollow(input *TransferUserInput) *model.UserFollow {
 		IsFans:    input.IsFans,
 	}
 }
+
+// LocalizeUsers 按查看者的语言补充格式化后的关注数、粉丝数
+func LocalizeUsers(users []*model.UserInfo, f Format) {
+	if f.Mode != FormatLocal {
+		return
+	}
+	for _, u := range users {
+		if u == nil || u.UserFollow == nil {
+			continue
+		}
+		u.UserFollow.FollowNumText = f.Count(int64(u.UserFollow.FollowNum))
+		u.UserFollow.FansNumText = f.Count(int64(u.UserFollow.FansNum))
+	}
+}
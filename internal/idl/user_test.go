@@ -0,0 +1,49 @@
+package idl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/testutil"
+)
+
+// 对外输出的用户结构做快照，字段变化需要更新 testdata 并在 review 时确认客户端兼容
+// go test ./internal/idl/ -update
+func TestTransferUser_Golden(t *testing.T) {
+	birthday := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := &model.UserBaseModel{
+		ID:       1,
+		Username: "snake",
+		Password: "$2a$10$hash",
+		Phone:    13010102020,
+		Email:    "snake@example.com",
+		Avatar:   "/uploads/avatar/1.png",
+		Bio:      "hello",
+		Sex:      1,
+		Plan:     "pro",
+		Region:   "CN",
+		Birthday: &birthday,
+		Version:  3,
+	}
+
+	tests := []struct {
+		name  string
+		input *TransferUserInput
+	}{
+		{"transfer_user_nil", &TransferUserInput{}},
+		{"transfer_user_no_stat", &TransferUserInput{User: user}},
+		{"transfer_user", &TransferUserInput{
+			CurUser:  &model.UserBaseModel{ID: 2},
+			User:     user,
+			UserStat: &model.UserStatModel{UserID: 1, FollowCount: 10, FollowerCount: 20},
+			IsFollow: 1,
+			IsFans:   0,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertGolden(t, tt.name, TransferUser(tt.input))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// 公告的目标用户
+const (
+	// AnnouncementSegmentAll 所有用户
+	AnnouncementSegmentAll = "all"
+	// AnnouncementSegmentVIP 会员
+	AnnouncementSegmentVIP = "vip"
+	// AnnouncementSegmentRegion 指定地区的用户
+	AnnouncementSegmentRegion = "region"
+	// AnnouncementSegmentCustom 自定义的用户分群
+	AnnouncementSegmentCustom = "segment"
+)
+
+// 公告的发送状态
+const (
+	// AnnouncementStatusPending 待发送
+	AnnouncementStatusPending = 0
+	// AnnouncementStatusSending 发送中
+	AnnouncementStatusSending = 1
+	// AnnouncementStatusDone 已完成
+	AnnouncementStatusDone = 2
+)
+
+// AnnouncementModel 系统公告表
+// 由定时任务分批发送给目标用户，sent 和 last_user_id 记录发送进度
+type AnnouncementModel struct {
+	ID      uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Title   string `gorm:"column:title;not null" json:"title"`
+	Content string `gorm:"column:content" json:"content"`
+	Segment string `gorm:"column:segment" json:"segment"`
+	Region  string `gorm:"column:region" json:"region"`
+	// SegmentName 用户分群名称，Segment 为 segment 时有效
+	SegmentName string     `gorm:"column:segment_name" json:"segment_name"`
+	Status      int        `gorm:"column:status" json:"status"`
+	Total       int        `gorm:"column:total" json:"total"`
+	Sent        int        `gorm:"column:sent" json:"sent"`
+	LastUserID  uint64     `gorm:"column:last_user_id" json:"-"`
+	CreatedBy   uint64     `gorm:"column:created_by" json:"created_by"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"-"`
+	FinishedAt  *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+// TableName 表名
+func (a *AnnouncementModel) TableName() string {
+	return "announcement"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AuditLogModel 审计日志表，记录帐号安全相关的敏感操作
+type AuditLogModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	OperatorID uint64    `gorm:"column:operator_id;not null" json:"operator_id"`
+	Action     string    `gorm:"column:action;not null" json:"action"`
+	Detail     string    `gorm:"column:detail" json:"detail"`
+	IP         string    `gorm:"column:ip" json:"ip"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (a *AuditLogModel) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// 封禁状态
+const (
+	// BanStatusActive 封禁中
+	BanStatusActive = 1
+	// BanStatusLifted 已解封，到期自动解封、管理员解封或申诉通过
+	BanStatusLifted = 2
+)
+
+// 申诉状态
+const (
+	// AppealStatusPending 待处理
+	AppealStatusPending = 0
+	// AppealStatusApproved 申诉通过，已解封
+	AppealStatusApproved = 1
+	// AppealStatusRejected 申诉驳回
+	AppealStatusRejected = 2
+)
+
+// UserBanModel 用户封禁记录，ExpiredAt 为空表示永久封禁
+type UserBanModel struct {
+	ID         uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	Reason     string     `gorm:"column:reason" json:"reason"`
+	Status     int        `gorm:"column:status" json:"status"`
+	OperatorID uint64     `gorm:"column:operator_id" json:"operator_id"`
+	ExpiredAt  *time.Time `gorm:"column:expired_at" json:"expired_at"`
+	LiftedBy   uint64     `gorm:"column:lifted_by" json:"lifted_by"`
+	LiftedAt   *time.Time `gorm:"column:lifted_at" json:"lifted_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (b *UserBanModel) TableName() string {
+	return "user_ban"
+}
+
+// IsPermanent 是否永久封禁
+func (b *UserBanModel) IsPermanent() bool {
+	return b.ExpiredAt == nil
+}
+
+// IsActive 是否在封禁中，到期但还未被自动解封的也不再生效
+func (b *UserBanModel) IsActive(now time.Time) bool {
+	if b.ID == 0 || b.Status != BanStatusActive {
+		return false
+	}
+	return b.ExpiredAt == nil || b.ExpiredAt.After(now)
+}
+
+// UserAppealModel 封禁申诉，每次封禁同时只能有一个待处理的申诉
+type UserAppealModel struct {
+	ID         uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	BanID      uint64     `gorm:"column:ban_id;not null" json:"ban_id"`
+	UserID     uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	Content    string     `gorm:"column:content" json:"content"`
+	Status     int        `gorm:"column:status" json:"status"`
+	ReviewerID uint64     `gorm:"column:reviewer_id" json:"reviewer_id"`
+	ReviewNote string     `gorm:"column:review_note" json:"review_note"`
+	ReviewedAt *time.Time `gorm:"column:reviewed_at" json:"reviewed_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (a *UserAppealModel) TableName() string {
+	return "user_appeal"
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/pkg/deadline"
+)
+
+// WithContext 返回绑定了请求 ctx 的默认数据库，使用 deadline.Downstream 的预算，
+// 超时或客户端断开连接后查询被中断并释放连接；
+// ctx 通过 WithScopedDB 绑定了独立的连接池时使用该连接池；ctx 中有 span 时每条语句创建子 span
+func WithContext(ctx context.Context) *gorm.DB {
+	db := dbFromContext(ctx)
+	if db == nil {
+		return nil
+	}
+	return withTracing(ctx, db.WithContext(deadline.Downstream(ctx)))
+}
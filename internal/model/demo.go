@@ -0,0 +1,45 @@
+package model
+
+import (
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/pkg/demo"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// openDemoDB 演示模式使用 sqlite，按模型自动建表，不需要先导入 db.sql
+func openDemoDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(demo.DSN()), &gorm.Config{
+		Logger: newLogger(),
+	})
+	if err != nil {
+		log.Errorf("Demo database open failed. dsn: %s, err: %+v", demo.DSN(), err)
+		panic(err)
+	}
+	if err := db.Use(Tracing); err != nil {
+		panic(err)
+	}
+	if err := db.Use(QueryCounter); err != nil {
+		panic(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	// 内存数据库在最后一个连接关闭后被删除，保留空闲连接
+	sqlDB.SetMaxIdleConns(4)
+	sqlDB.SetConnMaxLifetime(0)
+
+	tables := models()
+	dst := make([]interface{}, 0, len(tables))
+	for _, m := range tables {
+		dst = append(dst, m)
+	}
+	if err := db.AutoMigrate(dst...); err != nil {
+		log.Errorf("Demo database migrate failed. err: %+v", err)
+		panic(err)
+	}
+	return db
+}
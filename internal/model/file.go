@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 文件的病毒扫描状态
+const (
+	// FileScanPending 待扫描
+	FileScanPending = 0
+	// FileScanClean 未发现病毒
+	FileScanClean = 1
+	// FileScanInfected 已感染，文件已移到隔离区
+	FileScanInfected = 2
+)
+
+// FileModel 上传的文件
+// 内容相同的文件只保存一份，ref_count 为引用次数，为0且超过保留时间的文件会被定时清理
+type FileModel struct {
+	ID          uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID      uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	Category    string    `gorm:"column:category" json:"category"`
+	StorageKey  string    `gorm:"column:storage_key;not null" json:"storage_key"`
+	Hash        string    `gorm:"column:hash" json:"hash"`
+	Size        int64     `gorm:"column:size" json:"size"`
+	ContentType string    `gorm:"column:content_type" json:"content_type"`
+	RefCount    int       `gorm:"column:ref_count" json:"ref_count"`
+	ScanStatus  int       `gorm:"column:scan_status" json:"scan_status"`
+	ScanResult  string    `gorm:"column:scan_result" json:"scan_result"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (f *FileModel) TableName() string {
+	return "file"
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// ImageVariantModel 图片处理后生成的不同尺寸、格式的版本
+type ImageVariantModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
+	SourceKey  string    `gorm:"column:source_key;not null" json:"source_key"`
+	Size       int       `gorm:"column:size" json:"size"`
+	Format     string    `gorm:"column:format" json:"format"`
+	StorageKey string    `gorm:"column:storage_key" json:"storage_key"`
+	Width      int       `gorm:"column:width" json:"width"`
+	Height     int       `gorm:"column:height" json:"height"`
+	FileSize   int64     `gorm:"column:file_size" json:"file_size"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (i *ImageVariantModel) TableName() string {
+	return "image_variant"
+}
@@ -1,32 +1,38 @@
 package model
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
-	// MySQL driver.
-	"github.com/jinzhu/gorm"
-	// GORM MySQL
-	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 
+	"github.com/1024casts/snake/pkg/demo"
 	"github.com/1024casts/snake/pkg/log"
 )
 
 // DB 数据库全局变量
 var DB *gorm.DB
 
-// Init 初始化数据库
+// Init 初始化数据库，演示模式下使用 sqlite
 func Init() *gorm.DB {
-	return openDB(viper.GetString("mysql.username"),
+	if demo.Enabled() {
+		DB = openDemoDB()
+		return DB
+	}
+	DB = withReplicas(openDB(viper.GetString("mysql.username"),
 		viper.GetString("mysql.password"),
 		viper.GetString("mysql.addr"),
-		viper.GetString("mysql.name"))
+		viper.GetString("mysql.name")))
+	return DB
 }
 
-// openDB 链接数据库，生成数据库实例
-func openDB(username, password, addr, name string) *gorm.DB {
-	config := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=%t&loc=%s",
+// dsn 生成 mysql 的连接串
+func dsn(username, password, addr, name string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=%t&loc=%s",
 		username,
 		password,
 		addr,
@@ -34,33 +40,83 @@ func openDB(username, password, addr, name string) *gorm.DB {
 		true,
 		//"Asia/Shanghai"),
 		"Local")
+}
 
-	db, err := gorm.Open("mysql", config)
+// openDB 链接数据库，生成数据库实例
+func openDB(username, password, addr, name string) *gorm.DB {
+	// 先创建连接池再交给 gorm，数据库暂时不可用时连接池不会被关闭，由启动编排重试探测
+	sqlDB, err := sql.Open("mysql", dsn(username, password, addr, name))
+	if err != nil {
+		log.Errorf("Database open failed. Database name: %s, err: %+v", name, err)
+		panic(err)
+	}
+	db, err := newDB(sqlDB)
 	if err != nil {
 		log.Errorf("Database connection failed. Database name: %s, err: %+v", name, err)
+		panic(err)
 	}
 
-	db.Set("gorm:table_options", "CHARSET=utf8mb4")
-
 	// set for db connection
-	setupDB(db)
-
-	DB = db
+	setupDB(sqlDB)
 
 	return db
 }
 
+// newDB 使用已经创建的连接池初始化 gorm，不会立即连接数据库
+// 开启预编译语句缓存，同一个连接上相同的语句只预编译一次
+func newDB(sqlDB *sql.DB) (*gorm.DB, error) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn: sqlDB,
+		// 不查询数据库版本，数据库暂时不可用时也可以初始化
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger:      newLogger(),
+		PrepareStmt: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(Tracing); err != nil {
+		return nil, err
+	}
+	if err := db.Use(QueryCounter); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// newLogger 开启 mysql.show_log 时打印所有的 SQL，否则只打印慢查询和错误
+func newLogger() logger.Interface {
+	if viper.GetBool("mysql.show_log") {
+		return logger.Default.LogMode(logger.Info)
+	}
+	return logger.Default.LogMode(logger.Warn)
+}
+
 // setupDB 配置数据库
-func setupDB(db *gorm.DB) {
-	db.LogMode(viper.GetBool("mysql.show_log"))
+func setupDB(sqlDB *sql.DB) {
 	// 用于设置最大打开的连接数，默认值为0表示不限制.设置最大的连接数，可以避免并发太高导致连接mysql出现too many connections的错误。
-	db.DB().SetMaxOpenConns(viper.GetInt("mysql.max_open_conn"))
+	sqlDB.SetMaxOpenConns(viper.GetInt("mysql.max_open_conn"))
 	// 用于设置闲置的连接数.设置闲置的连接数则当开启的一个连接使用完成后可以放在池里等候下一次使用。
-	db.DB().SetMaxIdleConns(viper.GetInt("mysql.max_idle_conn"))
-	db.DB().SetConnMaxLifetime(time.Minute * viper.GetDuration("mysql.conn_max_life_time"))
+	sqlDB.SetMaxIdleConns(viper.GetInt("mysql.max_idle_conn"))
+	sqlDB.SetConnMaxLifetime(time.Minute * viper.GetDuration("mysql.conn_max_life_time"))
 }
 
 // GetDB 返回默认的数据库
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Ping 探测默认数据库和各地区的数据库是否可用
+func Ping() error {
+	for _, db := range GetAllDBs() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		if err := sqlDB.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
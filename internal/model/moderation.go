@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// 需要审核的资料字段
+const (
+	// ModerationFieldUsername 用户名
+	ModerationFieldUsername = "username"
+	// ModerationFieldBio 个人简介
+	ModerationFieldBio = "bio"
+	// ModerationFieldAvatar 头像
+	ModerationFieldAvatar = "avatar"
+)
+
+// 进入审核队列的原因
+const (
+	// ModerationSourceSensitiveWord 命中敏感词
+	ModerationSourceSensitiveWord = "sensitive_word"
+	// ModerationSourceImage 图片审核服务
+	ModerationSourceImage = "image"
+)
+
+// 审核状态
+const (
+	// ModerationStatusPending 待审核
+	ModerationStatusPending = 0
+	// ModerationStatusApproved 审核通过
+	ModerationStatusApproved = 1
+	// ModerationStatusRejected 审核拒绝，已恢复为修改前的内容
+	ModerationStatusRejected = 2
+)
+
+// ModerationModel 资料审核队列
+// 资料修改先生效，被标记的修改进入队列人工审核，拒绝时恢复为修改前的内容
+// 被图片审核服务标记的头像会先隔离并恢复为修改前的内容，审核通过后才生效
+type ModerationModel struct {
+	ID           uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID       uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	Field        string     `gorm:"column:field;not null" json:"field"`
+	OldValue     string     `gorm:"column:old_value" json:"old_value"`
+	NewValue     string     `gorm:"column:new_value" json:"new_value"`
+	Source       string     `gorm:"column:source" json:"source"`
+	Reason       string     `gorm:"column:reason" json:"reason"`
+	Status       int        `gorm:"column:status" json:"status"`
+	Quarantined  int        `gorm:"column:quarantined" json:"quarantined"`
+	ReviewerID   uint64     `gorm:"column:reviewer_id" json:"reviewer_id"`
+	ReviewReason string     `gorm:"column:review_reason" json:"review_reason"`
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at" json:"reviewed_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (m *ModerationModel) TableName() string {
+	return "moderation_queue"
+}
@@ -0,0 +1,98 @@
+package model
+
+import "time"
+
+// 通知事件类型
+const (
+	// NotifyEventNewFollower 新粉丝
+	NotifyEventNewFollower = "new_follower"
+	// NotifyEventAnnouncement 系统公告
+	NotifyEventAnnouncement = "announcement"
+	// NotifyEventModerationRejected 资料审核未通过
+	NotifyEventModerationRejected = "moderation_rejected"
+	// NotifyEventFileQuarantined 上传的文件发现病毒被隔离
+	NotifyEventFileQuarantined = "file_quarantined"
+	// NotifyEventReengagement 召回活动，用户可以在偏好设置中关闭
+	NotifyEventReengagement = "reengagement"
+	// NotifyEventSLOAlert 接口的错误预算消耗过快，只发送给管理员
+	NotifyEventSLOAlert = "slo_alert"
+	// NotifyEventAPIKeyExpiring api key 即将过期
+	NotifyEventAPIKeyExpiring = "api_key_expiring"
+)
+
+// 通知渠道
+const (
+	// NotifyChannelInApp 站内信
+	NotifyChannelInApp = "in_app"
+	// NotifyChannelPush 推送
+	NotifyChannelPush = "push"
+	// NotifyChannelEmail 邮件
+	NotifyChannelEmail = "email"
+	// NotifyChannelWebSocket websocket 实时推送，跟随站内信，用户不能单独设置
+	NotifyChannelWebSocket = "websocket"
+)
+
+// 通知在各个渠道的投递状态
+const (
+	// DeliveryStatusSent 已发送
+	DeliveryStatusSent = "sent"
+	// DeliveryStatusFailed 发送失败
+	DeliveryStatusFailed = "failed"
+	// DeliveryStatusSkipped 按在线状态路由后不需要发送，如用户在线时不发推送
+	DeliveryStatusSkipped = "skipped"
+	// DeliveryStatusQueued 等待稍后发送，如离线用户的邮件摘要、websocket 待确认消息
+	DeliveryStatusQueued = "queued"
+)
+
+// NotificationModel 站内通知表
+type NotificationModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID    uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	EventType string    `gorm:"column:event_type;not null" json:"event_type"`
+	RefID     uint64    `gorm:"column:ref_id" json:"ref_id"`
+	Title     string    `gorm:"column:title" json:"title"`
+	Content   string    `gorm:"column:content" json:"content"`
+	IsRead    int       `gorm:"column:is_read" json:"is_read"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (n *NotificationModel) TableName() string {
+	return "notification"
+}
+
+// NotifyPreferenceModel 用户通知偏好表
+// 只保存用户修改过的设置，没有记录时使用默认设置
+type NotifyPreferenceModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID    uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	EventType string    `gorm:"column:event_type;not null" json:"event_type"`
+	Channel   string    `gorm:"column:channel;not null" json:"channel"`
+	Enabled   int       `gorm:"column:enabled" json:"enabled"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"-"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (p *NotifyPreferenceModel) TableName() string {
+	return "user_notify_preference"
+}
+
+// NotificationDeliveryModel 通知投递记录表，每个渠道一条
+type NotificationDeliveryModel struct {
+	ID     uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID uint64 `gorm:"column:user_id;not null" json:"user_id"`
+	// NotificationID 站内信的id，没有开启站内信时为0
+	NotificationID uint64    `gorm:"column:notification_id" json:"notification_id"`
+	EventType      string    `gorm:"column:event_type;not null" json:"event_type"`
+	RefID          uint64    `gorm:"column:ref_id" json:"ref_id"`
+	Channel        string    `gorm:"column:channel;not null" json:"channel"`
+	Status         string    `gorm:"column:status;not null" json:"status"`
+	Reason         string    `gorm:"column:reason" json:"reason"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (d *NotificationDeliveryModel) TableName() string {
+	return "notification_delivery"
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// 协议类型
+const (
+	// PolicyTypeTerms 用户服务协议
+	PolicyTypeTerms = "tos"
+	// PolicyTypePrivacy 隐私政策
+	PolicyTypePrivacy = "privacy"
+)
+
+// PolicyTypes 支持的协议类型
+var PolicyTypes = []string{PolicyTypeTerms, PolicyTypePrivacy}
+
+// PolicyModel 协议版本，同一类型 id 最大的为当前版本
+type PolicyModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Type      string    `gorm:"column:type;not null" json:"type"`
+	Version   string    `gorm:"column:version;not null" json:"version"`
+	Title     string    `gorm:"column:title" json:"title"`
+	Content   string    `gorm:"column:content" json:"content"`
+	CreatedBy uint64    `gorm:"column:created_by" json:"-"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (p *PolicyModel) TableName() string {
+	return "policy"
+}
+
+// UserPolicyModel 用户同意协议的记录
+type UserPolicyModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	PolicyID   uint64    `gorm:"column:policy_id;not null" json:"policy_id"`
+	Type       string    `gorm:"column:type" json:"type"`
+	Version    string    `gorm:"column:version" json:"version"`
+	IP         string    `gorm:"column:ip" json:"ip"`
+	AcceptedAt time.Time `gorm:"column:accepted_at" json:"accepted_at"`
+}
+
+// TableName 表名
+func (p *UserPolicyModel) TableName() string {
+	return "user_policy"
+}
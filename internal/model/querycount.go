@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// maxStackFrames 超过阈值时输出的调用栈最多的层数
+const maxStackFrames = 20
+
+// QueryCounter 统计请求中执行的语句条数的插件，ctx 通过 WithQueryStats 绑定统计时才统计
+// 默认数据库已经注册，单独打开的数据库通过 db.Use(model.QueryCounter) 注册
+var QueryCounter gorm.Plugin = queryCounterPlugin{}
+
+type queryCounterPlugin struct{}
+
+func (queryCounterPlugin) Name() string {
+	return "snake:query_counter"
+}
+
+func (queryCounterPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("query_counter:create", countQuery),
+		cb.Query().After("gorm:query").Register("query_counter:query", countQuery),
+		cb.Update().After("gorm:update").Register("query_counter:update", countQuery),
+		cb.Delete().After("gorm:delete").Register("query_counter:delete", countQuery),
+		cb.Row().After("gorm:row").Register("query_counter:row", countQuery),
+		cb.Raw().After("gorm:raw").Register("query_counter:raw", countQuery),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type queryStatsKey struct{}
+
+// QueryStats 一个请求中执行的语句条数，超过阈值时输出一次警告日志和调用栈，用于发现 N+1 查询
+type QueryStats struct {
+	count     int64
+	threshold int64
+	warned    int32
+}
+
+// WithQueryStats 在 ctx 中绑定语句统计，之后通过 WithContext、ReadContext 执行的语句都会计数
+// threshold 小于等于0时只计数，不输出警告
+func WithQueryStats(ctx context.Context, threshold int) (context.Context, *QueryStats) {
+	s := &QueryStats{threshold: int64(threshold)}
+	return context.WithValue(ctx, queryStatsKey{}, s), s
+}
+
+// Count 已执行的语句条数
+func (s *QueryStats) Count() int {
+	return int(atomic.LoadInt64(&s.count))
+}
+
+// countQuery 语句执行后计数，未执行(如出错前被中断)的语句不计数
+func countQuery(db *gorm.DB) {
+	if db.Statement.Context == nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	s, ok := db.Statement.Context.Value(queryStatsKey{}).(*QueryStats)
+	if !ok {
+		return
+	}
+	n := atomic.AddInt64(&s.count, 1)
+	if s.threshold <= 0 || n <= s.threshold || !atomic.CompareAndSwapInt32(&s.warned, 0, 1) {
+		return
+	}
+	log.Warnf("[model] too many queries in one request, count: %d, threshold: %d, sql: %s\n%s",
+		n, s.threshold, db.Statement.SQL.String(), callerStack())
+}
+
+// callerStack 返回业务代码的调用栈，跳过 gorm 和标准库，超过阈值的语句通常在循环中执行
+func callerStack() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	lines := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && strings.Contains(frame.Function, ".") &&
+			!strings.HasPrefix(frame.Function, "runtime.") && lines < maxStackFrames {
+			fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			lines++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestQueryCounter(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(QueryCounter); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&UserBaseModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stats := WithQueryStats(context.Background(), 3)
+	tx := db.WithContext(ctx)
+	if err := tx.Create(&UserBaseModel{ID: 1, Username: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 模拟 N+1 查询
+	for i := 0; i < 3; i++ {
+		if err := tx.Where("id = ?", 1).First(&UserBaseModel{}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var n int64
+	if err := tx.Model(&UserBaseModel{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Model(&UserBaseModel{}).Where("id = ?", 1).Update("username", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := stats.Count(); got != 6 {
+		t.Fatalf("Count() = %d, want 6", got)
+	}
+	if stats.warned != 1 {
+		t.Fatal("want warned after exceeding threshold")
+	}
+
+	// 没有绑定统计的 ctx 不计数
+	if err := db.WithContext(context.Background()).First(&UserBaseModel{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := stats.Count(); got != 6 {
+		t.Fatalf("Count() = %d, want 6", got)
+	}
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// regionDBs 按地区单独部署的数据库，key 和用户的 region 一致
+var regionDBs = map[string]*gorm.DB{}
+
+// InitRegions 初始化配置 residency.regions 中各地区的数据库
+// 数据需要存放在所在地区的用户，相关数据读写到对应地区的数据库，未配置的地区使用默认数据库
+func InitRegions() map[string]*gorm.DB {
+	if !viper.GetBool("residency.enable") {
+		return regionDBs
+	}
+
+	for region := range viper.GetStringMap("residency.regions") {
+		key := fmt.Sprintf("residency.regions.%s.mysql", region)
+		if !viper.IsSet(key) {
+			continue
+		}
+		regionDBs[region] = openDB(viper.GetString(key+".username"),
+			viper.GetString(key+".password"),
+			viper.GetString(key+".addr"),
+			viper.GetString(key+".name"))
+		log.Infof("[model] region db initialized, region: %s", region)
+	}
+	return regionDBs
+}
+
+// GetRegionDB 返回地区的数据库，未单独部署的地区返回默认数据库
+func GetRegionDB(region string) *gorm.DB {
+	if db, ok := regionDBs[region]; ok {
+		return db
+	}
+	return DB
+}
+
+// GetAllDBs 返回默认数据库和各地区的数据库，用于汇总统计
+func GetAllDBs() []*gorm.DB {
+	dbs := make([]*gorm.DB, 0, len(regionDBs)+1)
+	dbs = append(dbs, DB)
+	for _, db := range regionDBs {
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+// Close 关闭默认数据库、从库和各地区数据库的连接池，返回第一个错误
+func Close() error {
+	err := closeReplicas()
+	for _, db := range GetAllDBs() {
+		if db == nil {
+			continue
+		}
+		sqlDB, e := db.DB()
+		if e == nil {
+			e = sqlDB.Close()
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Hook 连接池的退出回调，需要最先注册，在依赖数据库的模块都停止后关闭
+func Hook() lifecycle.Hook {
+	return lifecycle.Hook{Name: "mysql", OnStop: func(ctx context.Context) error { return Close() }}
+}
+
+// ReadRegion 在地区的数据库中读取，出错时按配置 residency.fallback 回退到默认数据库读取，
+// 用于地区数据库故障或数据还未迁移完成的情况，只能用于读操作
+func ReadRegion(region string, fn func(db *gorm.DB) error) error {
+	db := GetRegionDB(region)
+	err := fn(db)
+	if err == nil || db == DB || !viper.GetBool("residency.fallback") {
+		return err
+	}
+
+	log.Warnf("[model] read region db err: %v, region: %s, fallback to default db", err, region)
+	return fn(DB)
+}
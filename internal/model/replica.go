@@ -0,0 +1,142 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/spf13/viper"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/1024casts/snake/pkg/deadline"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const (
+	// ReplicaPolicyRandom 随机选择从库
+	ReplicaPolicyRandom = "random"
+	// ReplicaPolicyRoundRobin 依次轮流选择从库
+	ReplicaPolicyRoundRobin = "round_robin"
+)
+
+// replicaConfig 从库配置，用户名、密码和库名为空时和主库相同
+type replicaConfig struct {
+	Addr     string
+	Username string
+	Password string
+	Name     string
+}
+
+// replicaDBs 默认数据库的从库连接池，退出时关闭
+var replicaDBs []*sql.DB
+
+type primaryKey struct{}
+
+// withReplicas 按配置 mysql.replicas 为默认数据库注册从库，未配置时读写都使用主库
+// 返回的 db 默认使用主库，写操作和事务始终使用主库，只有通过 ReadContext 发起的读操作会路由到从库
+func withReplicas(db *gorm.DB) *gorm.DB {
+	if err := initReplicas(db); err != nil {
+		log.Errorf("Database replicas init failed. err: %+v", err)
+		panic(err)
+	}
+	return Primary(db)
+}
+
+// initReplicas 注册从库
+func initReplicas(db *gorm.DB) error {
+	var configs []replicaConfig
+	if err := viper.UnmarshalKey("mysql.replicas", &configs); err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	replicas := make([]gorm.Dialector, 0, len(configs))
+	for _, c := range configs {
+		if c.Username == "" {
+			c.Username = viper.GetString("mysql.username")
+			c.Password = viper.GetString("mysql.password")
+		}
+		if c.Name == "" {
+			c.Name = viper.GetString("mysql.name")
+		}
+		sqlDB, err := sql.Open("mysql", dsn(c.Username, c.Password, c.Addr, c.Name))
+		if err != nil {
+			return err
+		}
+		setupDB(sqlDB)
+		replicaDBs = append(replicaDBs, sqlDB)
+		replicas = append(replicas, mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}))
+	}
+
+	err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   replicaPolicy(viper.GetString("mysql.replica_policy")),
+	}))
+	if err != nil {
+		return err
+	}
+	log.Infof("[model] replicas registered, count: %d", len(replicas))
+	return nil
+}
+
+// replicaPolicy 从库的选择策略，默认随机
+func replicaPolicy(name string) dbresolver.Policy {
+	if name == ReplicaPolicyRoundRobin {
+		return dbresolver.StrictRoundRobinPolicy()
+	}
+	return dbresolver.RandomPolicy{}
+}
+
+// HasReplicas 默认数据库是否配置了从库
+func HasReplicas() bool {
+	return len(replicaDBs) > 0
+}
+
+// WithPrimary 标记 ctx 的读取使用主库，用于不能接受从库延迟的读取，如刚写入后的读取
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// isPrimary ctx 的读取是否需要使用主库
+func isPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+// Primary 返回使用主库的 db，用于从库可能还没有同步的读取
+func Primary(db *gorm.DB) *gorm.DB {
+	if db == nil || !HasReplicas() {
+		return db
+	}
+	return db.Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
+// ReadContext 和 WithContext 一样绑定请求 ctx，读操作可以路由到从库，数据可能有短暂的延迟；
+// 未配置从库、ctx 通过 WithPrimary 标记或通过 WithScopedDB 绑定了独立的连接池时使用主库。
+// 事务需要通过 WithContext 开启，从 ReadContext 开启的事务在从库上执行
+func ReadContext(ctx context.Context) *gorm.DB {
+	db := dbFromContext(ctx)
+	if db == nil {
+		return nil
+	}
+	scoped := db != DB
+	db = db.WithContext(deadline.Downstream(ctx))
+	if HasReplicas() && !scoped && !isPrimary(ctx) {
+		db = db.Clauses(dbresolver.Read).Session(&gorm.Session{})
+	}
+	return withTracing(ctx, db)
+}
+
+// closeReplicas 关闭从库的连接池，返回第一个错误
+func closeReplicas() error {
+	var err error
+	for _, sqlDB := range replicaDBs {
+		if e := sqlDB.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
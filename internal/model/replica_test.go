@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 主库和从库中同一个用户的用户名不同，按读到的用户名判断使用了哪个库
+func TestReadContext(t *testing.T) {
+	open := func(name, username string) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AutoMigrate(&UserBaseModel{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&UserBaseModel{ID: 1, Username: username}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	primary := open("primary.db", "primary")
+	replica := open("replica.db", "replica")
+	sqlDB, err := replica.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = primary.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{sqlite.Dialector{Conn: sqlDB}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDB, oldReplicas := DB, replicaDBs
+	defer func() { DB, replicaDBs = oldDB, oldReplicas }()
+	replicaDBs = append(replicaDBs, sqlDB)
+	DB = Primary(primary)
+
+	read := func(db *gorm.DB) string {
+		u := &UserBaseModel{}
+		if err := db.Where("id = ?", 1).First(u).Error; err != nil {
+			t.Fatal(err)
+		}
+		return u.Username
+	}
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		db   *gorm.DB
+		want string
+	}{
+		{"default db", GetDB(), "primary"},
+		{"with context", WithContext(ctx), "primary"},
+		{"read context", ReadContext(ctx), "replica"},
+		{"read context with primary", ReadContext(WithPrimary(ctx)), "primary"},
+		{"primary of read context", Primary(ReadContext(ctx)), "primary"},
+		{"scoped db", ReadContext(WithScopedDB(ctx, open("scoped.db", "scoped"))), "scoped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 同一个 db 多次读取结果一致
+			for i := 0; i < 2; i++ {
+				if got := read(tt.db); got != tt.want {
+					t.Fatalf("read #%d = %q, want %q", i, got, tt.want)
+				}
+			}
+		})
+	}
+
+	tx := WithContext(ctx).Begin()
+	got := read(tx)
+	tx.Rollback()
+	if got != "primary" {
+		t.Fatalf("read in tx = %q, want primary", got)
+	}
+	if err := ReadContext(ctx).Model(&UserBaseModel{}).Where("id = ?", 1).Update("username", "updated").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := read(replica); got != "replica" {
+		t.Fatalf("write through read context went to replica, got %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+type scopedDBKey struct{}
+
+// NewScopedDB 创建连接默认数据库的独立连接池，最多打开 maxOpenConns 个连接，
+// 用于计划任务等后台流程，避免占满与 API 服务共用的连接池
+func NewScopedDB(maxOpenConns int) (*gorm.DB, error) {
+	sqlDB, err := sql.Open("mysql", dsn(viper.GetString("mysql.username"),
+		viper.GetString("mysql.password"),
+		viper.GetString("mysql.addr"),
+		viper.GetString("mysql.name")))
+	if err != nil {
+		return nil, err
+	}
+	db, err := newDB(sqlDB)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	setupDB(sqlDB)
+	// 空闲连接数大于 maxOpenConns 时会被同时调小
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	return db, nil
+}
+
+// WithScopedDB 在 ctx 中绑定独立的连接池，之后通过 WithContext 获取的数据库都使用这个连接池
+func WithScopedDB(ctx context.Context, db *gorm.DB) context.Context {
+	return context.WithValue(ctx, scopedDBKey{}, db)
+}
+
+// dbFromContext 返回 ctx 绑定的连接池，没有时返回默认数据库
+func dbFromContext(ctx context.Context) *gorm.DB {
+	if db, ok := ctx.Value(scopedDBKey{}).(*gorm.DB); ok && db != nil {
+		return db
+	}
+	return DB
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SegmentRules 用户分群规则，多个条件之间是且的关系，零值表示不限制
+type SegmentRules struct {
+	// RegisteredAfter 在此时间之后注册
+	RegisteredAfter *time.Time `json:"registered_after,omitempty"`
+	// RegisteredBefore 在此时间之前注册
+	RegisteredBefore *time.Time `json:"registered_before,omitempty"`
+	// ActiveWithinDays 最近 N 天内有登录
+	ActiveWithinDays int `json:"active_within_days,omitempty"`
+	// InactiveDays 最近 N 天内没有登录，注册不满 N 天的用户不算
+	InactiveDays int `json:"inactive_days,omitempty"`
+	// MinFollowerCount 粉丝数不少于
+	MinFollowerCount int `json:"min_follower_count,omitempty"`
+	// MaxFollowerCount 粉丝数不多于
+	MaxFollowerCount int `json:"max_follower_count,omitempty"`
+	// Plan 套餐
+	Plan string `json:"plan,omitempty"`
+	// Region 地区
+	Region string `json:"region,omitempty"`
+	// Tags 有其中任意一个标签
+	Tags []string `json:"tags,omitempty"`
+}
+
+// SegmentModel 用户分群表
+// 由定时任务按规则计算出用户，保存到 redis 集合中供公告、功能开关等使用
+type SegmentModel struct {
+	ID             uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Name           string     `gorm:"column:name;not null" json:"name"`
+	Description    string     `gorm:"column:description" json:"description"`
+	Rules          string     `gorm:"column:rules" json:"-"`
+	UserCount      int        `gorm:"column:user_count" json:"user_count"`
+	MaterializedAt *time.Time `gorm:"column:materialized_at" json:"materialized_at"`
+	CreatedBy      uint64     `gorm:"column:created_by" json:"created_by"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (s *SegmentModel) TableName() string {
+	return "segment"
+}
+
+// GetRules 解析分群规则
+func (s *SegmentModel) GetRules() (*SegmentRules, error) {
+	rules := &SegmentRules{}
+	if s.Rules == "" {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(s.Rules), rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UserTagModel 用户标签表
+type UserTagModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID    uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	Tag       string    `gorm:"column:tag;not null" json:"tag"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (t *UserTagModel) TableName() string {
+	return "user_tag"
+}
@@ -0,0 +1,52 @@
+package model
+
+// tabler 定义了表名的模型
+type tabler interface {
+	TableName() string
+}
+
+// Tables 返回默认数据库中所有模型对应的表名，按 db.sql 建表后应该都存在，用于 snake doctor 检查
+func Tables() []string {
+	models := models()
+	tables := make([]string, 0, len(models))
+	for _, m := range models {
+		tables = append(tables, m.TableName())
+	}
+	return tables
+}
+
+// models 默认数据库中的所有模型，演示模式按模型自动建表，新增模型时需要加到这里
+func models() []tabler {
+	return []tabler{
+		&AnnouncementModel{},
+		&AuditLogModel{},
+		&FileModel{},
+		&ImageVariantModel{},
+		&ModerationModel{},
+		&NotificationDeliveryModel{},
+		&NotificationModel{},
+		&NotifyPreferenceModel{},
+		&PolicyModel{},
+		&SegmentModel{},
+		&TaskModel{},
+		&UserAPIKeyModel{},
+		&UserActivityModel{},
+		&UserAppealModel{},
+		&UserBanModel{},
+		&UserBaseModel{},
+		&UserBlockModel{},
+		&UserDeviceModel{},
+		&UserEmailChangeModel{},
+		&UserEventModel{},
+		&UserFansModel{},
+		&UserFollowModel{},
+		&UserIdentityModel{},
+		&UserMembershipModel{},
+		&UserOAuthModel{},
+		&UserPolicyModel{},
+		&UserRoleModel{},
+		&UserStatModel{},
+		&UserTagModel{},
+		&UserUsernameHistoryModel{},
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// 异步任务状态
+const (
+	// TaskStatusPending 等待执行
+	TaskStatusPending = 0
+	// TaskStatusRunning 执行中
+	TaskStatusRunning = 1
+	// TaskStatusSucceeded 执行成功
+	TaskStatusSucceeded = 2
+	// TaskStatusFailed 执行失败
+	TaskStatusFailed = 3
+)
+
+// TaskModel 耗时操作的异步任务，提交后立即返回任务id，客户端轮询任务状态
+type TaskModel struct {
+	ID      uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Type    string `gorm:"column:type;not null" json:"type"`
+	OwnerID uint64 `gorm:"column:owner_id;not null" json:"owner_id"`
+	// Payload 任务参数，json 格式，由各任务类型自己解析
+	Payload string `gorm:"column:payload" json:"-"`
+	Status  int    `gorm:"column:status" json:"status"`
+	// Progress 进度百分比 0-100
+	Progress int `gorm:"column:progress" json:"progress"`
+	// Result 执行结果，json 格式
+	Result     string     `gorm:"column:result" json:"result,omitempty"`
+	Error      string     `gorm:"column:error" json:"error,omitempty"`
+	StartedAt  *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (t *TaskModel) TableName() string {
+	return "task"
+}
+
+// IsFinished 是否已经执行完成，成功或失败
+func (t *TaskModel) IsFinished() bool {
+	return t.Status == TaskStatusSucceeded || t.Status == TaskStatusFailed
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/pkg/tracing"
+)
+
+const (
+	// tracingContextKey WithContext 绑定的请求 ctx，用于创建子 span
+	// 查询使用的 ctx 是 deadline.Downstream，可能不包含当前的 span
+	tracingContextKey = "snake:tracing_context"
+	// tracingSpanKey 当前语句的 span
+	tracingSpanKey = "snake:tracing_span"
+)
+
+// Tracing 创建 span 的插件，只有通过 WithContext 获取的 db 会创建 span
+// 默认数据库已经注册，单独打开的数据库通过 db.Use(model.Tracing) 注册
+var Tracing gorm.Plugin = tracingPlugin{}
+
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "snake:tracing"
+}
+
+func (tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:begin_transaction").Register("tracing:before_create", beforeTracing("create")),
+		cb.Create().After("gorm:commit_or_rollback_transaction").Register("tracing:after_create", afterTracing),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", beforeTracing("query")),
+		cb.Query().After("gorm:after_query").Register("tracing:after_query", afterTracing),
+		cb.Update().Before("gorm:begin_transaction").Register("tracing:before_update", beforeTracing("update")),
+		cb.Update().After("gorm:commit_or_rollback_transaction").Register("tracing:after_update", afterTracing),
+		cb.Delete().Before("gorm:begin_transaction").Register("tracing:before_delete", beforeTracing("delete")),
+		cb.Delete().After("gorm:commit_or_rollback_transaction").Register("tracing:after_delete", afterTracing),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", beforeTracing("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", afterTracing),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", beforeTracing("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", afterTracing),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTracing ctx 中有 span 时绑定到 db 上，由回调创建子 span
+func withTracing(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if db == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return db
+	}
+	// Set 返回的 db 不能复用，重新创建会话，同一个 db 可以执行多条语句
+	return db.Set(tracingContextKey, ctx).Session(&gorm.Session{})
+}
+
+func beforeTracing(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.Get(tracingContextKey)
+		if !ok {
+			return
+		}
+		ctx, ok := v.(context.Context)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		_, span := tracing.Start(ctx, "gorm."+operation+" "+table,
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBSQLTableKey.String(table),
+		)
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func afterTracing(db *gorm.DB) {
+	v, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
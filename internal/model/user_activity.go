@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 用户动作类型
+const (
+	// ActivityLogin 登录，detail 中的 method 为登录方式
+	ActivityLogin = "login"
+	// ActivityFollow 关注，detail 中的 followed_uid 为被关注的用户
+	ActivityFollow = "follow"
+	// ActivityUnfollow 取消关注
+	ActivityUnfollow = "unfollow"
+	// ActivityProfileUpdate 修改资料，detail 中的 fields 为修改的字段
+	ActivityProfileUpdate = "profile_update"
+)
+
+// maxActivitySeq 同一用户同一毫秒内的动作不会超过这个数量
+const maxActivitySeq = 1000
+
+// UserActivityModel 用户动作记录，最近的动作在 redis stream 中，由 worker 归档到数据库
+// activity_id 由 stream 的消息 id 换算得到，同一用户内递增，用于分页
+type UserActivityModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
+	UserID     uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	ActivityID uint64    `gorm:"column:activity_id;not null" json:"id"`
+	Action     string    `gorm:"column:action;not null" json:"action"`
+	Detail     string    `gorm:"column:detail" json:"detail"`
+	IP         string    `gorm:"column:ip" json:"ip"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (a *UserActivityModel) TableName() string {
+	return "user_activity"
+}
+
+// ParseActivityID 把 stream 的消息 id (毫秒时间戳-序号) 换算为 activity_id
+func ParseActivityID(streamID string) (uint64, error) {
+	parts := strings.SplitN(streamID, "-", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid stream id: %s", streamID)
+	}
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stream id: %s", streamID)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq >= maxActivitySeq {
+		return 0, fmt.Errorf("invalid stream id: %s", streamID)
+	}
+	return ms*maxActivitySeq + seq, nil
+}
+
+// ActivityStreamID 把 activity_id 换算回 stream 的消息 id
+func ActivityStreamID(activityID uint64) string {
+	return fmt.Sprintf("%d-%d", activityID/maxActivitySeq, activityID%maxActivitySeq)
+}
+
+// ActivityTime activity_id 中的毫秒时间戳即动作时间
+func ActivityTime(activityID uint64) time.Time {
+	return time.Unix(0, int64(activityID/maxActivitySeq)*int64(time.Millisecond))
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// api key 状态
+const (
+	// APIKeyStatusActive 正常
+	APIKeyStatusActive = 0
+	// APIKeyStatusRevoked 已吊销
+	APIKeyStatusRevoked = 1
+)
+
+// UserAPIKeyModel 用户的 api key，只保存 key 的哈希，明文只在创建和轮换时返回一次
+type UserAPIKeyModel struct {
+	ID     uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID uint64 `gorm:"column:user_id;not null" json:"user_id"`
+	Name   string `gorm:"column:name" json:"name"`
+	// Prefix key 的前几位，用于在列表中区分不同的 key
+	Prefix     string     `gorm:"column:prefix" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;not null" json:"-"`
+	Status     int        `gorm:"column:status" json:"status"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	ExpiredAt  time.Time  `gorm:"column:expired_at" json:"expired_at"`
+	// RemindedAt 发送过期提醒的时间，轮换后清空
+	RemindedAt *time.Time `gorm:"column:reminded_at" json:"-"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (k *UserAPIKeyModel) TableName() string {
+	return "user_api_key"
+}
+
+// IsValid 未吊销且未过期
+func (k *UserAPIKeyModel) IsValid(now time.Time) bool {
+	return k.ID > 0 && k.Status == APIKeyStatusActive && now.Before(k.ExpiredAt)
+}
@@ -1,25 +1,66 @@
 package model
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/1024casts/snake/pkg/auth"
 
 	validator "github.com/go-playground/validator/v10"
 )
 
+const (
+	// UserStatusActive 正常
+	UserStatusActive = 0
+	// UserStatusDeactivated 已停用(注销)，同时软删除，所有查询都看不到，宽限期内可以恢复
+	UserStatusDeactivated = 1
+)
+
 // UserBaseModel User represents a registered user.
 type UserBaseModel struct {
-	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
-	Username  string    `json:"username" gorm:"column:username;not null" binding:"required" validate:"min=1,max=32"`
-	Password  string    `json:"password" gorm:"column:password;not null" binding:"required" validate:"min=5,max=128"`
-	Phone     int       `gorm:"column:phone" json:"phone"`
-	Email     string    `gorm:"column:email" json:"email"`
-	Avatar    string    `gorm:"column:avatar" json:"avatar"`
-	Sex       int       `gorm:"column:sex" json:"sex"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"-"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"-"`
+	ID            uint64         `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Username      string         `json:"username" gorm:"column:username;not null" binding:"required" validate:"min=1,max=32"`
+	Password      string         `json:"password" gorm:"column:password;not null" binding:"required" validate:"min=5,max=128"`
+	Phone         int            `gorm:"column:phone" json:"phone"`
+	Email         string         `gorm:"column:email" json:"email"`
+	EmailVerified int            `gorm:"column:email_verified" json:"email_verified"`
+	Avatar        string         `gorm:"column:avatar" json:"avatar"`
+	Bio           string         `gorm:"column:bio" json:"bio"`
+	Sex           int            `gorm:"column:sex" json:"sex"`
+	Plan          string         `gorm:"column:plan" json:"plan"`
+	Region        string         `gorm:"column:region" json:"region"`
+	Birthday      *time.Time     `gorm:"column:birthday" json:"birthday,omitempty"`
+	Version       int            `gorm:"column:version" json:"version"`
+	Status        int            `gorm:"column:status" json:"status"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"-"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at" json:"-"`
+}
+
+// EmailUnverified 是否有未验证的邮箱，手机号注册、没有绑定邮箱的用户不需要验证
+func (u *UserBaseModel) EmailUnverified() bool {
+	return u.Email != "" && u.EmailVerified == 0
+}
+
+// ETag 资料的实体标签，每次修改资料版本号加1，用于 If-Match 条件更新
+func (u *UserBaseModel) ETag() string {
+	return fmt.Sprintf(`"%d-%d"`, u.ID, u.Version)
+}
+
+// ProfileField 获取需要审核的资料字段的值
+func (u *UserBaseModel) ProfileField(field string) string {
+	switch field {
+	case ModerationFieldUsername:
+		return u.Username
+	case ModerationFieldBio:
+		return u.Bio
+	case ModerationFieldAvatar:
+		return u.Avatar
+	}
+	return ""
 }
 
 // Validate the fields.
@@ -34,6 +75,9 @@ type UserFollow struct {
 	FansNum   int `json:"fans_num"`   // 粉丝数
 	IsFollow  int `json:"is_follow"`  // 是否关注 1:是 0:否
 	IsFans    int `json:"is_fans"`    // 是否是粉丝 1:是 0:否
+	// 按查看者的语言格式化的关注数、粉丝数，只在请求 local 格式时返回
+	FollowNumText string `json:"follow_num_text,omitempty"`
+	FansNumText   string `json:"fans_num_text,omitempty"`
 }
 
 // UserInfo 对外暴露的结构体
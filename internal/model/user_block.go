@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 拉黑状态
+const (
+	// BlockStatusNormal 已拉黑
+	BlockStatusNormal = 1
+	// BlockStatusDelete 已取消拉黑
+	BlockStatusDelete = 0
+)
+
+// UserBlockModel 拉黑表，取消拉黑时只修改状态，再次拉黑时恢复
+type UserBlockModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64    `gorm:"column:user_id;uniqueIndex:uniq_uid_buid,priority:1;index:idx_buid_uid,priority:2" json:"user_id"`
+	BlockedUID uint64    `gorm:"column:blocked_uid;uniqueIndex:uniq_uid_buid,priority:2;index:idx_buid_uid,priority:1" json:"blocked_uid"`
+	Status     int       `gorm:"column:status" json:"status"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName sets the insert table name for this struct type
+func (u *UserBlockModel) TableName() string {
+	return "user_block"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserDeviceModel 用户登录设备表
+type UserDeviceModel struct {
+	ID          uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID      uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	DeviceKey   string    `gorm:"column:device_key;not null" json:"-"`
+	DeviceType  string    `gorm:"column:device_type" json:"device_type"`
+	Device      string    `gorm:"column:device" json:"device"`
+	OS          string    `gorm:"column:os" json:"os"`
+	OSVersion   string    `gorm:"column:os_version" json:"os_version"`
+	Browser     string    `gorm:"column:browser" json:"browser"`
+	UserAgent   string    `gorm:"column:user_agent" json:"-"`
+	LastIP      string    `gorm:"column:last_ip" json:"last_ip"`
+	LastLoginAt time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (u *UserDeviceModel) TableName() string {
+	return "user_device"
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+const (
+	// EmailChangeStatusPending 等待确认
+	EmailChangeStatusPending = 0
+	// EmailChangeStatusDone 已完成
+	EmailChangeStatusDone = 1
+	// EmailChangeStatusCanceled 已取消(发起了新的修改申请)
+	EmailChangeStatusCanceled = 2
+	// EmailChangeStatusExpired 已过期
+	EmailChangeStatusExpired = 3
+)
+
+// UserEmailChangeModel 修改邮箱申请表
+// 需要新旧邮箱都点击确认链接后才会生效，token 只保存 sha256 摘要
+type UserEmailChangeModel struct {
+	ID             uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID         uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	OldEmail       string     `gorm:"column:old_email" json:"old_email"`
+	NewEmail       string     `gorm:"column:new_email" json:"new_email"`
+	OldToken       string     `gorm:"column:old_token" json:"-"`
+	NewToken       string     `gorm:"column:new_token" json:"-"`
+	OldConfirmedAt *time.Time `gorm:"column:old_confirmed_at" json:"old_confirmed_at"`
+	NewConfirmedAt *time.Time `gorm:"column:new_confirmed_at" json:"new_confirmed_at"`
+	Status         int        `gorm:"column:status" json:"status"`
+	ExpiredAt      time.Time  `gorm:"column:expired_at" json:"expired_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (u *UserEmailChangeModel) TableName() string {
+	return "user_email_change"
+}
+
+// IsConfirmed 新旧邮箱是否都已确认
+func (u *UserEmailChangeModel) IsConfirmed() bool {
+	return u.OldConfirmedAt != nil && u.NewConfirmedAt != nil
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// 用户事件类型
+const (
+	// UserEventCreated 注册
+	UserEventCreated = "user_created"
+	// UserEventUpdated 修改资料
+	UserEventUpdated = "user_updated"
+	// UserEventFollowed 关注，ref_id 为被关注的用户
+	UserEventFollowed = "user_followed"
+	// UserEventUnfollowed 取消关注，ref_id 为被取消关注的用户
+	UserEventUnfollowed = "user_unfollowed"
+	// UserEventDeactivated 停用帐号，ref_id 为操作人
+	UserEventDeactivated = "user_deactivated"
+	// UserEventReactivated 恢复帐号，ref_id 为操作人
+	UserEventReactivated = "user_reactivated"
+)
+
+// UserEventModel 用户事件表，只追加不修改，id 作为事件的偏移量
+// 缓存、搜索索引等投影出错时可以从某个偏移量开始重放
+type UserEventModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID    uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	EventType string    `gorm:"column:event_type;not null" json:"event_type"`
+	RefID     uint64    `gorm:"column:ref_id" json:"ref_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (e *UserEventModel) TableName() string {
+	return "user_event"
+}
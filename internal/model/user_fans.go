@@ -2,12 +2,31 @@ package model
 
 import "time"
 
+// 粉丝列表的排序方式
+const (
+	// FollowerOrderRecent 按关注时间倒序
+	FollowerOrderRecent = "recent"
+	// FollowerOrderMutual 互相关注的排在前面，再按关注时间倒序
+	FollowerOrderMutual = "mutual"
+	// FollowerOrderPopular 粉丝数多的排在前面，粉丝数相同时按关注时间倒序
+	FollowerOrderPopular = "popular"
+)
+
+// IsFollowerOrder 是否为支持的粉丝列表排序方式
+func IsFollowerOrder(order string) bool {
+	switch order {
+	case FollowerOrderRecent, FollowerOrderMutual, FollowerOrderPopular:
+		return true
+	}
+	return false
+}
+
 // UserFansModel 粉丝表
 type UserFansModel struct {
 	ID          uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
-	FollowerUID uint64    `gorm:"column:follower_uid" json:"follower_uid"`
+	FollowerUID uint64    `gorm:"column:follower_uid;uniqueIndex:idx_uid_fid,priority:2" json:"follower_uid"`
 	Status      int       `gorm:"column:status" json:"status"`
-	UserID      uint64    `gorm:"column:user_id" json:"user_id"`
+	UserID      uint64    `gorm:"column:user_id;uniqueIndex:idx_uid_fid,priority:1" json:"user_id"`
 	CreatedAt   time.Time `gorm:"column:created_at" json:"-"`
 	UpdatedAt   time.Time `gorm:"column:updated_at" json:"-"`
 }
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// IdentityProviderEmail 邮箱
+	IdentityProviderEmail = "email"
+	// IdentityProviderPhone 手机号
+	IdentityProviderPhone = "phone"
+)
+
+// UserIdentityModel 用户登录身份表
+// 一个用户可以绑定多种登录方式(邮箱、手机号、第三方帐号)，每种方式的标识全局唯一
+// 软删除的身份在回收前仍然占用标识，回收后标识改为墓碑值，见 repository/user/tombstone.go
+type UserIdentityModel struct {
+	ID         uint64         `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64         `gorm:"column:user_id;not null" json:"user_id"`
+	Provider   string         `gorm:"column:provider;not null" json:"provider"`
+	Identifier string         `gorm:"column:identifier;not null" json:"identifier"`
+	Verified   int            `gorm:"column:verified" json:"verified"`
+	CreatedAt  time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at" json:"-"`
+	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+}
+
+// TableName 表名
+func (u *UserIdentityModel) TableName() string {
+	return "user_identity"
+}
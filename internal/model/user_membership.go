@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// UserMembershipModel 用户付费会员记录，每个订单一条
+type UserMembershipModel struct {
+	ID        uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID    uint64    `gorm:"column:user_id;not null" json:"user_id"`
+	Plan      string    `gorm:"column:plan;not null" json:"plan"`
+	OrderNo   string    `gorm:"column:order_no;not null" json:"order_no"`
+	StartedAt time.Time `gorm:"column:started_at" json:"started_at"`
+	ExpiredAt time.Time `gorm:"column:expired_at" json:"expired_at"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (m *UserMembershipModel) TableName() string {
+	return "user_membership"
+}
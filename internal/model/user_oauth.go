@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserOAuthModel 用户绑定的第三方帐号，provider 见 pkg/oauth
+// 微信同一开放平台下网站应用和移动应用的 open_id 不同，通过 union_id 识别为同一个用户
+type UserOAuthModel struct {
+	ID           uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID       uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	Provider     string     `gorm:"column:provider;not null" json:"provider"`
+	OpenID       string     `gorm:"column:open_id;not null" json:"-"`
+	UnionID      string     `gorm:"column:union_id" json:"-"`
+	Nickname     string     `gorm:"column:nickname" json:"nickname"`
+	Avatar       string     `gorm:"column:avatar" json:"avatar"`
+	AccessToken  string     `gorm:"column:access_token" json:"-"`
+	RefreshToken string     `gorm:"column:refresh_token" json:"-"`
+	ExpiredAt    *time.Time `gorm:"column:expired_at" json:"-"`
+	CreatedAt    time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at" json:"-"`
+}
+
+// TableName 表名
+func (u *UserOAuthModel) TableName() string {
+	return "user_oauth"
+}
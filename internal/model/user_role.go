@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// UserRoleModel 用户拥有的角色，角色的权限在配置 authz.roles 中定义
+type UserRoleModel struct {
+	ID         uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID     uint64    `gorm:"column:user_id;not null;uniqueIndex:uniq_user_role,priority:1" json:"user_id"`
+	Role       string    `gorm:"column:role;not null;uniqueIndex:uniq_user_role,priority:2" json:"role"`
+	OperatorID uint64    `gorm:"column:operator_id" json:"operator_id"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (r *UserRoleModel) TableName() string {
+	return "user_role"
+}
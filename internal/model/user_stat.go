@@ -5,9 +5,10 @@ import "time"
 // UserStatModel 用户数据统计表
 type UserStatModel struct {
 	ID            uint64    `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"-"`
-	UserID        uint64    `gorm:"column:user_id;not null" json:"user_id" binding:"required"`
+	UserID        uint64    `gorm:"column:user_id;not null;uniqueIndex:uniq_uid" json:"user_id" binding:"required"`
 	FollowCount   int       `gorm:"column:follow_count" json:"follow_count"`
 	FollowerCount int       `gorm:"column:follower_count" json:"follower_count"`
+	ViewCount     int       `gorm:"column:view_count" json:"view_count"`
 	CreatedAt     time.Time `gorm:"column:created_at" json:"-"`
 	UpdatedAt     time.Time `gorm:"column:updated_at" json:"-"`
 }
@@ -16,3 +17,13 @@ type UserStatModel struct {
 func (u *UserStatModel) TableName() string {
 	return "user_stat"
 }
+
+// FollowCounts 用户的关注数和粉丝数，包含还未写入数据库的增量
+type FollowCounts struct {
+	UserID        uint64 `json:"user_id"`
+	FollowCount   int    `json:"follow_num"`
+	FollowerCount int    `json:"fans_num"`
+	// 按查看者的语言格式化的关注数、粉丝数，只在请求 local 格式时返回
+	FollowCountText   string `json:"follow_num_text,omitempty"`
+	FollowerCountText string `json:"fans_num_text,omitempty"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// UserUsernameHistoryModel 用户名修改记录，旧用户名的主页链接重定向到新用户名
+type UserUsernameHistoryModel struct {
+	ID     uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	UserID uint64 `gorm:"column:user_id;not null" json:"user_id"`
+	// Username 修改前的用户名
+	Username  string    `gorm:"column:username;not null" json:"username"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (u *UserUsernameHistoryModel) TableName() string {
+	return "user_username_history"
+}
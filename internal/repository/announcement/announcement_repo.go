@@ -0,0 +1,83 @@
+package announcement
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义公告仓库接口
+type Repo interface {
+	CreateAnnouncement(db *gorm.DB, announcement *model.AnnouncementModel) (id uint64, err error)
+	GetAnnouncement(db *gorm.DB, id uint64) (*model.AnnouncementModel, error)
+	GetAnnouncementList(db *gorm.DB, lastID uint64, limit int) ([]*model.AnnouncementModel, error)
+	GetUnfinishedAnnouncements(db *gorm.DB, limit int) ([]*model.AnnouncementModel, error)
+	UpdateAnnouncement(db *gorm.DB, id uint64, data map[string]interface{}) error
+}
+
+// announcementRepo 公告仓库
+type announcementRepo struct{}
+
+// NewAnnouncementRepo 实例化公告仓库
+func NewAnnouncementRepo() Repo {
+	return &announcementRepo{}
+}
+
+// CreateAnnouncement 新增公告
+func (repo *announcementRepo) CreateAnnouncement(db *gorm.DB, announcement *model.AnnouncementModel) (id uint64, err error) {
+	err = db.Create(announcement).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[announcement_repo] create announcement err")
+	}
+
+	return announcement.ID, nil
+}
+
+// GetAnnouncement 获取公告，不存在时返回空结构体
+func (repo *announcementRepo) GetAnnouncement(db *gorm.DB, id uint64) (*model.AnnouncementModel, error) {
+	announcement := model.AnnouncementModel{}
+	err := db.Where("id = ?", id).First(&announcement).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[announcement_repo] get announcement err")
+	}
+
+	return &announcement, nil
+}
+
+// GetAnnouncementList 获取公告列表，按id倒序
+func (repo *announcementRepo) GetAnnouncementList(db *gorm.DB, lastID uint64, limit int) ([]*model.AnnouncementModel, error) {
+	announcements := make([]*model.AnnouncementModel, 0)
+	query := db
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&announcements).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[announcement_repo] get announcement list err")
+	}
+
+	return announcements, nil
+}
+
+// GetUnfinishedAnnouncements 获取待发送和发送中的公告，按id正序
+func (repo *announcementRepo) GetUnfinishedAnnouncements(db *gorm.DB, limit int) ([]*model.AnnouncementModel, error) {
+	announcements := make([]*model.AnnouncementModel, 0)
+	err := db.Where("status in (?)", []int{model.AnnouncementStatusPending, model.AnnouncementStatusSending}).
+		Order("id asc").Limit(limit).Find(&announcements).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[announcement_repo] get unfinished announcements err")
+	}
+
+	return announcements, nil
+}
+
+// UpdateAnnouncement 更新公告
+func (repo *announcementRepo) UpdateAnnouncement(db *gorm.DB, id uint64, data map[string]interface{}) error {
+	err := db.Model(&model.AnnouncementModel{}).Where("id = ?", id).Updates(data).Error
+	if err != nil {
+		return errors.Wrap(err, "[announcement_repo] update announcement err")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义 api key 仓库接口
+type Repo interface {
+	CreateKey(db *gorm.DB, key *model.UserAPIKeyModel) (id uint64, err error)
+	GetKey(db *gorm.DB, id uint64) (*model.UserAPIKeyModel, error)
+	GetKeyByHash(db *gorm.DB, keyHash string) (*model.UserAPIKeyModel, error)
+	GetKeyList(db *gorm.DB, userID uint64) ([]*model.UserAPIKeyModel, error)
+	CountActiveKeys(db *gorm.DB, userID uint64, now time.Time) (int, error)
+	UpdateKey(db *gorm.DB, id uint64, data map[string]interface{}) error
+	GetExpiringKeys(db *gorm.DB, now, before time.Time, limit int) ([]*model.UserAPIKeyModel, error)
+}
+
+// apiKeyRepo api key 仓库
+type apiKeyRepo struct{}
+
+// NewAPIKeyRepo 实例化 api key 仓库
+func NewAPIKeyRepo() Repo {
+	return &apiKeyRepo{}
+}
+
+// CreateKey 新增 api key
+func (repo *apiKeyRepo) CreateKey(db *gorm.DB, key *model.UserAPIKeyModel) (id uint64, err error) {
+	err = db.Create(key).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[api_key_repo] create api key err")
+	}
+
+	return key.ID, nil
+}
+
+// GetKey 获取 api key，不存在时返回空结构体
+func (repo *apiKeyRepo) GetKey(db *gorm.DB, id uint64) (*model.UserAPIKeyModel, error) {
+	key := model.UserAPIKeyModel{}
+	err := db.Where("id = ?", id).First(&key).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[api_key_repo] get api key err")
+	}
+
+	return &key, nil
+}
+
+// GetKeyByHash 按 key 的哈希获取，不存在时返回空结构体
+func (repo *apiKeyRepo) GetKeyByHash(db *gorm.DB, keyHash string) (*model.UserAPIKeyModel, error) {
+	key := model.UserAPIKeyModel{}
+	err := db.Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[api_key_repo] get api key by hash err")
+	}
+
+	return &key, nil
+}
+
+// GetKeyList 获取用户所有的 api key，包括已吊销的，按id倒序
+func (repo *apiKeyRepo) GetKeyList(db *gorm.DB, userID uint64) ([]*model.UserAPIKeyModel, error) {
+	keys := make([]*model.UserAPIKeyModel, 0)
+	err := db.Where("user_id = ?", userID).Order("id desc").Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[api_key_repo] get api key list err")
+	}
+
+	return keys, nil
+}
+
+// CountActiveKeys 未吊销且未过期的 api key 数量
+func (repo *apiKeyRepo) CountActiveKeys(db *gorm.DB, userID uint64, now time.Time) (int, error) {
+	var count int64
+	err := db.Model(&model.UserAPIKeyModel{}).
+		Where("user_id = ? AND status = ? AND expired_at > ?", userID, model.APIKeyStatusActive, now).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[api_key_repo] count active api keys err")
+	}
+
+	return int(count), nil
+}
+
+// UpdateKey 修改 api key
+func (repo *apiKeyRepo) UpdateKey(db *gorm.DB, id uint64, data map[string]interface{}) error {
+	err := db.Model(&model.UserAPIKeyModel{}).Where("id = ?", id).Updates(data).Error
+	if err != nil {
+		return errors.Wrapf(err, "[api_key_repo] update api key err, id: %d", id)
+	}
+
+	return nil
+}
+
+// GetExpiringKeys 获取 before 之前过期、还没有发送过提醒的 api key，按过期时间顺序
+func (repo *apiKeyRepo) GetExpiringKeys(db *gorm.DB, now, before time.Time, limit int) ([]*model.UserAPIKeyModel, error) {
+	keys := make([]*model.UserAPIKeyModel, 0)
+	err := db.Where("status = ? AND expired_at > ? AND expired_at <= ? AND reminded_at IS NULL",
+		model.APIKeyStatusActive, now, before).
+		Order("expired_at asc").Limit(limit).Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[api_key_repo] get expiring api keys err")
+	}
+
+	return keys, nil
+}
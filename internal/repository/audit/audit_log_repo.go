@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义审计日志仓库接口
+type Repo interface {
+	CreateAuditLog(db *gorm.DB, auditLog *model.AuditLogModel) (id uint64, err error)
+	GetAuditLogList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.AuditLogModel, error)
+	GetLatestAuditLog(db *gorm.DB, userID uint64, action string) (*model.AuditLogModel, error)
+}
+
+// auditLogRepo 审计日志仓库
+type auditLogRepo struct{}
+
+// NewAuditLogRepo 实例化审计日志仓库
+func NewAuditLogRepo() Repo {
+	return &auditLogRepo{}
+}
+
+// CreateAuditLog 新增审计日志
+func (repo *auditLogRepo) CreateAuditLog(db *gorm.DB, auditLog *model.AuditLogModel) (id uint64, err error) {
+	err = db.Create(auditLog).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[audit_log_repo] create audit log err")
+	}
+
+	return auditLog.ID, nil
+}
+
+// GetAuditLogList 获取用户的审计日志，按id倒序
+func (repo *auditLogRepo) GetAuditLogList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.AuditLogModel, error) {
+	logs := make([]*model.AuditLogModel, 0)
+	query := db.Where("user_id = ?", userID)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&logs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[audit_log_repo] get audit log list err")
+	}
+
+	return logs, nil
+}
+
+// GetLatestAuditLog 获取用户某个操作最近一次的审计日志，不存在时返回空结构体
+func (repo *auditLogRepo) GetLatestAuditLog(db *gorm.DB, userID uint64, action string) (*model.AuditLogModel, error) {
+	auditLog := model.AuditLogModel{}
+	err := db.Where("user_id = ? and action = ?", userID, action).Order("id desc").First(&auditLog).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[audit_log_repo] get latest audit log err")
+	}
+
+	return &auditLog, nil
+}
@@ -0,0 +1,78 @@
+package ban
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// AppealRepo 定义申诉仓库接口
+type AppealRepo interface {
+	CreateAppeal(db *gorm.DB, appeal *model.UserAppealModel) (id uint64, err error)
+	GetAppeal(db *gorm.DB, id uint64) (*model.UserAppealModel, error)
+	GetPendingAppeal(db *gorm.DB, banID uint64) (*model.UserAppealModel, error)
+	GetAppealList(db *gorm.DB, status int, lastID uint64, limit int) ([]*model.UserAppealModel, error)
+	UpdateAppealStatus(db *gorm.DB, id uint64, fromStatus int, data map[string]interface{}) (bool, error)
+}
+
+// appealRepo 申诉仓库
+type appealRepo struct{}
+
+// NewAppealRepo 实例化申诉仓库
+func NewAppealRepo() AppealRepo {
+	return &appealRepo{}
+}
+
+// CreateAppeal 新增申诉
+func (repo *appealRepo) CreateAppeal(db *gorm.DB, appeal *model.UserAppealModel) (id uint64, err error) {
+	err = db.Create(appeal).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[appeal_repo] create appeal err")
+	}
+
+	return appeal.ID, nil
+}
+
+// GetAppeal 获取申诉，不存在时返回空结构体
+func (repo *appealRepo) GetAppeal(db *gorm.DB, id uint64) (*model.UserAppealModel, error) {
+	appeal := model.UserAppealModel{}
+	err := db.Where("id = ?", id).First(&appeal).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[appeal_repo] get appeal err")
+	}
+
+	return &appeal, nil
+}
+
+// GetPendingAppeal 获取封禁待处理的申诉，不存在时返回空结构体
+func (repo *appealRepo) GetPendingAppeal(db *gorm.DB, banID uint64) (*model.UserAppealModel, error) {
+	appeal := model.UserAppealModel{}
+	err := db.Where("ban_id = ? and status = ?", banID, model.AppealStatusPending).First(&appeal).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[appeal_repo] get pending appeal err")
+	}
+
+	return &appeal, nil
+}
+
+// GetAppealList 获取某个状态的申诉，按id正序，先提交的先处理
+func (repo *appealRepo) GetAppealList(db *gorm.DB, status int, lastID uint64, limit int) ([]*model.UserAppealModel, error) {
+	appeals := make([]*model.UserAppealModel, 0)
+	err := db.Where("status = ? and id > ?", status, lastID).Order("id asc").Limit(limit).Find(&appeals).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[appeal_repo] get appeal list err")
+	}
+
+	return appeals, nil
+}
+
+// UpdateAppealStatus 更新申诉状态，只有当前状态为 fromStatus 时才更新，避免重复处理
+func (repo *appealRepo) UpdateAppealStatus(db *gorm.DB, id uint64, fromStatus int, data map[string]interface{}) (bool, error) {
+	result := db.Model(&model.UserAppealModel{}).Where("id = ? and status = ?", id, fromStatus).Updates(data)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[appeal_repo] update appeal status err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
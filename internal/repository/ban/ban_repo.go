@@ -0,0 +1,90 @@
+package ban
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义封禁仓库接口
+type Repo interface {
+	CreateBan(db *gorm.DB, ban *model.UserBanModel) (id uint64, err error)
+	GetBan(db *gorm.DB, id uint64) (*model.UserBanModel, error)
+	GetActiveBan(db *gorm.DB, userID uint64) (*model.UserBanModel, error)
+	GetBanList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.UserBanModel, error)
+	LiftBan(db *gorm.DB, id uint64, operatorID uint64) (bool, error)
+}
+
+// banRepo 封禁仓库
+type banRepo struct{}
+
+// NewBanRepo 实例化封禁仓库
+func NewBanRepo() Repo {
+	return &banRepo{}
+}
+
+// CreateBan 新增封禁
+func (repo *banRepo) CreateBan(db *gorm.DB, ban *model.UserBanModel) (id uint64, err error) {
+	err = db.Create(ban).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[ban_repo] create ban err")
+	}
+
+	return ban.ID, nil
+}
+
+// GetBan 获取封禁，不存在时返回空结构体
+func (repo *banRepo) GetBan(db *gorm.DB, id uint64) (*model.UserBanModel, error) {
+	ban := model.UserBanModel{}
+	err := db.Where("id = ?", id).First(&ban).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[ban_repo] get ban err")
+	}
+
+	return &ban, nil
+}
+
+// GetActiveBan 获取用户最新的封禁中的记录，不存在时返回空结构体
+func (repo *banRepo) GetActiveBan(db *gorm.DB, userID uint64) (*model.UserBanModel, error) {
+	ban := model.UserBanModel{}
+	err := db.Where("user_id = ? and status = ?", userID, model.BanStatusActive).Order("id desc").First(&ban).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[ban_repo] get active ban err")
+	}
+
+	return &ban, nil
+}
+
+// GetBanList 获取用户的封禁记录，按id倒序
+func (repo *banRepo) GetBanList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.UserBanModel, error) {
+	bans := make([]*model.UserBanModel, 0)
+	query := db.Where("user_id = ?", userID)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&bans).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[ban_repo] get ban list err")
+	}
+
+	return bans, nil
+}
+
+// LiftBan 解封，只有封禁中的记录才更新，避免重复解封
+func (repo *banRepo) LiftBan(db *gorm.DB, id uint64, operatorID uint64) (bool, error) {
+	now := time.Now()
+	result := db.Model(&model.UserBanModel{}).Where("id = ? and status = ?", id, model.BanStatusActive).Updates(map[string]interface{}{
+		"status":     model.BanStatusLifted,
+		"lifted_by":  operatorID,
+		"lifted_at":  now,
+		"updated_at": now,
+	})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[ban_repo] lift ban err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
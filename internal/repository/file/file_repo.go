@@ -0,0 +1,126 @@
+package file
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义文件仓库接口
+type Repo interface {
+	CreateFile(db *gorm.DB, file *model.FileModel) (id uint64, err error)
+	GetFile(db *gorm.DB, id uint64) (*model.FileModel, error)
+	GetFileByKey(db *gorm.DB, key string) (*model.FileModel, error)
+	GetFileByHash(db *gorm.DB, hash string, size int64) (*model.FileModel, error)
+	IncrRefCount(db *gorm.DB, key string, delta int) error
+	GetOrphanFiles(db *gorm.DB, before time.Time, limit int) ([]*model.FileModel, error)
+	DeleteOrphanFile(db *gorm.DB, id uint64, before time.Time) (bool, error)
+	UpdateScanResult(db *gorm.DB, id uint64, status int, result string) (bool, error)
+}
+
+// fileRepo 文件仓库
+type fileRepo struct{}
+
+// NewFileRepo 实例化文件仓库
+func NewFileRepo() Repo {
+	return &fileRepo{}
+}
+
+// CreateFile 新增文件记录
+func (repo *fileRepo) CreateFile(db *gorm.DB, file *model.FileModel) (id uint64, err error) {
+	err = db.Create(file).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[file_repo] create file err")
+	}
+
+	return file.ID, nil
+}
+
+// GetFile 获取文件，不存在时返回空结构体
+func (repo *fileRepo) GetFile(db *gorm.DB, id uint64) (*model.FileModel, error) {
+	file := model.FileModel{}
+	err := db.Where("id = ?", id).First(&file).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[file_repo] get file err")
+	}
+
+	return &file, nil
+}
+
+// GetFileByKey 按存储路径获取文件，不存在时返回空结构体
+func (repo *fileRepo) GetFileByKey(db *gorm.DB, key string) (*model.FileModel, error) {
+	file := model.FileModel{}
+	err := db.Where("storage_key = ?", key).First(&file).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[file_repo] get file by key err")
+	}
+
+	return &file, nil
+}
+
+// GetFileByHash 按内容哈希和大小获取文件，用于去重，已感染的文件不参与去重，不存在时返回空结构体
+func (repo *fileRepo) GetFileByHash(db *gorm.DB, hash string, size int64) (*model.FileModel, error) {
+	file := model.FileModel{}
+	err := db.Where("hash = ? and size = ? and scan_status <> ?", hash, size, model.FileScanInfected).Order("id asc").First(&file).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[file_repo] get file by hash err")
+	}
+
+	return &file, nil
+}
+
+// IncrRefCount 增减引用次数，不会小于0
+func (repo *fileRepo) IncrRefCount(db *gorm.DB, key string, delta int) error {
+	query := db.Model(&model.FileModel{}).Where("storage_key = ?", key)
+	if delta < 0 {
+		query = query.Where("ref_count >= ?", -delta)
+	}
+	err := query.Updates(map[string]interface{}{
+		"ref_count":  gorm.Expr("ref_count + ?", delta),
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "[file_repo] incr ref count err")
+	}
+
+	return nil
+}
+
+// GetOrphanFiles 获取没有被引用且在 before 之后没有变化的文件
+func (repo *fileRepo) GetOrphanFiles(db *gorm.DB, before time.Time, limit int) ([]*model.FileModel, error) {
+	files := make([]*model.FileModel, 0)
+	err := db.Where("ref_count = 0 and updated_at < ?", before).Order("id asc").Limit(limit).Find(&files).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[file_repo] get orphan files err")
+	}
+
+	return files, nil
+}
+
+// DeleteOrphanFile 删除文件记录，查询后又被引用的文件不会删除
+func (repo *fileRepo) DeleteOrphanFile(db *gorm.DB, id uint64, before time.Time) (bool, error) {
+	result := db.Where("id = ? and ref_count = 0 and updated_at < ?", id, before).Delete(&model.FileModel{})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[file_repo] delete orphan file err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateScanResult 记录扫描结果，只更新待扫描的文件，避免重复消费时覆盖
+func (repo *fileRepo) UpdateScanResult(db *gorm.DB, id uint64, status int, result string) (bool, error) {
+	ret := db.Model(&model.FileModel{}).Where("id = ? and scan_status = ?", id, model.FileScanPending).
+		Updates(map[string]interface{}{
+			"scan_status": status,
+			"scan_result": result,
+			"updated_at":  time.Now(),
+		})
+	if ret.Error != nil {
+		return false, errors.Wrap(ret.Error, "[file_repo] update scan result err")
+	}
+
+	return ret.RowsAffected > 0, nil
+}
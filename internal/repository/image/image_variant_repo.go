@@ -0,0 +1,88 @@
+package image
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// VariantRepo 定义图片版本仓库接口
+type VariantRepo interface {
+	SaveVariant(db *gorm.DB, variant *model.ImageVariantModel) error
+	GetVariant(db *gorm.DB, sourceKey string, size int, format string) (*model.ImageVariantModel, error)
+	GetVariants(db *gorm.DB, sourceKey string) ([]*model.ImageVariantModel, error)
+	DeleteVariants(db *gorm.DB, sourceKey string) error
+}
+
+// variantRepo 图片版本仓库
+type variantRepo struct{}
+
+// NewVariantRepo 实例化图片版本仓库
+func NewVariantRepo() VariantRepo {
+	return &variantRepo{}
+}
+
+// SaveVariant 保存图片版本，同一尺寸、格式已存在时更新，重复处理同一张图片时不会产生多条记录
+func (repo *variantRepo) SaveVariant(db *gorm.DB, variant *model.ImageVariantModel) error {
+	existing, err := repo.GetVariant(db, variant.SourceKey, variant.Size, variant.Format)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if existing.ID > 0 {
+		err = db.Model(existing).Updates(map[string]interface{}{
+			"storage_key": variant.StorageKey,
+			"width":       variant.Width,
+			"height":      variant.Height,
+			"file_size":   variant.FileSize,
+			"updated_at":  now,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "[image_variant_repo] update variant err")
+		}
+		return nil
+	}
+
+	variant.CreatedAt = now
+	variant.UpdatedAt = now
+	if err := db.Create(variant).Error; err != nil {
+		return errors.Wrap(err, "[image_variant_repo] create variant err")
+	}
+	return nil
+}
+
+// GetVariant 获取图片的某个版本，不存在时返回空结构体
+func (repo *variantRepo) GetVariant(db *gorm.DB, sourceKey string, size int, format string) (*model.ImageVariantModel, error) {
+	variant := model.ImageVariantModel{}
+	err := db.Where("source_key = ? and size = ? and format = ?", sourceKey, size, format).First(&variant).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[image_variant_repo] get variant err")
+	}
+
+	return &variant, nil
+}
+
+// GetVariants 获取图片的所有版本
+func (repo *variantRepo) GetVariants(db *gorm.DB, sourceKey string) ([]*model.ImageVariantModel, error) {
+	variants := make([]*model.ImageVariantModel, 0)
+	err := db.Where("source_key = ?", sourceKey).Order("size asc").Find(&variants).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[image_variant_repo] get variants err")
+	}
+
+	return variants, nil
+}
+
+// DeleteVariants 删除图片的所有版本
+func (repo *variantRepo) DeleteVariants(db *gorm.DB, sourceKey string) error {
+	err := db.Where("source_key = ?", sourceKey).Delete(&model.ImageVariantModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "[image_variant_repo] delete variants err")
+	}
+
+	return nil
+}
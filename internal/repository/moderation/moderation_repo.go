@@ -0,0 +1,66 @@
+package moderation
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义资料审核仓库接口
+type Repo interface {
+	CreateModeration(db *gorm.DB, item *model.ModerationModel) (id uint64, err error)
+	GetModeration(db *gorm.DB, id uint64) (*model.ModerationModel, error)
+	GetModerationList(db *gorm.DB, status int, lastID uint64, limit int) ([]*model.ModerationModel, error)
+	UpdateModerationStatus(db *gorm.DB, id uint64, fromStatus int, data map[string]interface{}) (bool, error)
+}
+
+// moderationRepo 资料审核仓库
+type moderationRepo struct{}
+
+// NewModerationRepo 实例化资料审核仓库
+func NewModerationRepo() Repo {
+	return &moderationRepo{}
+}
+
+// CreateModeration 新增待审核记录
+func (repo *moderationRepo) CreateModeration(db *gorm.DB, item *model.ModerationModel) (id uint64, err error) {
+	err = db.Create(item).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[moderation_repo] create moderation err")
+	}
+
+	return item.ID, nil
+}
+
+// GetModeration 获取审核记录，不存在时返回空结构体
+func (repo *moderationRepo) GetModeration(db *gorm.DB, id uint64) (*model.ModerationModel, error) {
+	item := model.ModerationModel{}
+	err := db.Where("id = ?", id).First(&item).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[moderation_repo] get moderation err")
+	}
+
+	return &item, nil
+}
+
+// GetModerationList 获取某个状态的审核记录，按id正序，先提交的先审核
+func (repo *moderationRepo) GetModerationList(db *gorm.DB, status int, lastID uint64, limit int) ([]*model.ModerationModel, error) {
+	items := make([]*model.ModerationModel, 0)
+	err := db.Where("status = ? and id > ?", status, lastID).Order("id asc").Limit(limit).Find(&items).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[moderation_repo] get moderation list err")
+	}
+
+	return items, nil
+}
+
+// UpdateModerationStatus 更新审核状态，只有当前状态为 fromStatus 时才更新，避免重复审核
+func (repo *moderationRepo) UpdateModerationStatus(db *gorm.DB, id uint64, fromStatus int, data map[string]interface{}) (bool, error) {
+	result := db.Model(&model.ModerationModel{}).Where("id = ? and status = ?", id, fromStatus).Updates(data)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[moderation_repo] update moderation status err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,49 @@
+package notification
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// DeliveryRepo 定义通知投递记录仓库接口
+type DeliveryRepo interface {
+	CreateDeliveries(db *gorm.DB, deliveries []*model.NotificationDeliveryModel) error
+	GetDeliveryList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.NotificationDeliveryModel, error)
+}
+
+// deliveryRepo 通知投递记录仓库
+type deliveryRepo struct{}
+
+// NewDeliveryRepo 实例化通知投递记录仓库
+func NewDeliveryRepo() DeliveryRepo {
+	return &deliveryRepo{}
+}
+
+// CreateDeliveries 批量新增一次通知在各个渠道的投递记录
+func (repo *deliveryRepo) CreateDeliveries(db *gorm.DB, deliveries []*model.NotificationDeliveryModel) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := db.Create(&deliveries).Error; err != nil {
+		return errors.Wrap(err, "[delivery_repo] create deliveries err")
+	}
+
+	return nil
+}
+
+// GetDeliveryList 获取用户的通知投递记录，按id倒序
+func (repo *deliveryRepo) GetDeliveryList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.NotificationDeliveryModel, error) {
+	deliveries := make([]*model.NotificationDeliveryModel, 0)
+	query := db.Where("user_id = ?", userID)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&deliveries).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[delivery_repo] get delivery list err")
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义站内通知仓库接口
+type Repo interface {
+	CreateNotification(db *gorm.DB, notification *model.NotificationModel) (id uint64, err error)
+	GetNotificationList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.NotificationModel, error)
+	GetUnreadNotifications(db *gorm.DB, userID uint64, eventType string, limit int) ([]*model.NotificationModel, error)
+	MarkRead(db *gorm.DB, userID uint64, id uint64) (bool, error)
+}
+
+// notificationRepo 站内通知仓库
+type notificationRepo struct{}
+
+// NewNotificationRepo 实例化站内通知仓库
+func NewNotificationRepo() Repo {
+	return &notificationRepo{}
+}
+
+// CreateNotification 新增站内通知
+func (repo *notificationRepo) CreateNotification(db *gorm.DB, notification *model.NotificationModel) (id uint64, err error) {
+	err = db.Create(notification).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[notification_repo] create notification err")
+	}
+
+	return notification.ID, nil
+}
+
+// GetNotificationList 获取用户的站内通知，按id倒序
+func (repo *notificationRepo) GetNotificationList(db *gorm.DB, userID uint64, lastID uint64, limit int) ([]*model.NotificationModel, error) {
+	notifications := make([]*model.NotificationModel, 0)
+	query := db.Where("user_id = ?", userID)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&notifications).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[notification_repo] get notification list err")
+	}
+
+	return notifications, nil
+}
+
+// GetUnreadNotifications 获取用户某种事件的未读通知，按id倒序
+func (repo *notificationRepo) GetUnreadNotifications(db *gorm.DB, userID uint64, eventType string, limit int) ([]*model.NotificationModel, error) {
+	notifications := make([]*model.NotificationModel, 0)
+	err := db.Where("user_id = ? and event_type = ? and is_read = 0", userID, eventType).
+		Order("id desc").Limit(limit).Find(&notifications).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[notification_repo] get unread notifications err")
+	}
+
+	return notifications, nil
+}
+
+// MarkRead 标记用户的通知为已读，通知不存在或已读时返回 false
+func (repo *notificationRepo) MarkRead(db *gorm.DB, userID uint64, id uint64) (bool, error) {
+	result := db.Model(&model.NotificationModel{}).
+		Where("id = ? and user_id = ? and is_read = 0", id, userID).
+		Update("is_read", 1)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[notification_repo] mark read err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// PreferenceRepo 定义通知偏好仓库接口
+type PreferenceRepo interface {
+	GetUserPreferences(db *gorm.DB, userID uint64) ([]*model.NotifyPreferenceModel, error)
+	SaveUserPreference(db *gorm.DB, userID uint64, eventType, channel string, enabled bool) error
+}
+
+// preferenceRepo 通知偏好仓库
+type preferenceRepo struct{}
+
+// NewPreferenceRepo 实例化通知偏好仓库
+func NewPreferenceRepo() PreferenceRepo {
+	return &preferenceRepo{}
+}
+
+// GetUserPreferences 获取用户修改过的通知偏好
+func (repo *preferenceRepo) GetUserPreferences(db *gorm.DB, userID uint64) ([]*model.NotifyPreferenceModel, error) {
+	prefs := make([]*model.NotifyPreferenceModel, 0)
+	err := db.Where("user_id = ?", userID).Find(&prefs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[notify_preference_repo] get user preferences err")
+	}
+
+	return prefs, nil
+}
+
+// SaveUserPreference 保存用户某个事件在某个渠道的通知偏好，已存在时更新
+func (repo *preferenceRepo) SaveUserPreference(db *gorm.DB, userID uint64, eventType, channel string, enabled bool) error {
+	enabledVal := 0
+	if enabled {
+		enabledVal = 1
+	}
+
+	pref := model.NotifyPreferenceModel{}
+	err := db.Where("user_id = ? and event_type = ? and channel = ?", userID, eventType, channel).First(&pref).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return errors.Wrap(err, "[notify_preference_repo] get user preference err")
+	}
+
+	now := time.Now()
+	if pref.ID == 0 {
+		err = db.Create(&model.NotifyPreferenceModel{
+			UserID:    userID,
+			EventType: eventType,
+			Channel:   channel,
+			Enabled:   enabledVal,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "[notify_preference_repo] create user preference err")
+		}
+		return nil
+	}
+
+	err = db.Model(&pref).Updates(map[string]interface{}{
+		"enabled":    enabledVal,
+		"updated_at": now,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "[notify_preference_repo] update user preference err")
+	}
+	return nil
+}
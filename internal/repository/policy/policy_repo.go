@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义协议仓库接口
+type Repo interface {
+	CreatePolicy(db *gorm.DB, policy *model.PolicyModel) (id uint64, err error)
+	GetLatestPolicy(db *gorm.DB, typ string) (*model.PolicyModel, error)
+	GetPolicyByVersion(db *gorm.DB, typ, version string) (*model.PolicyModel, error)
+	GetPolicyList(db *gorm.DB, typ string, lastID uint64, limit int) ([]*model.PolicyModel, error)
+	CreateUserPolicy(db *gorm.DB, up *model.UserPolicyModel) error
+	GetUserPolicy(db *gorm.DB, userID, policyID uint64) (*model.UserPolicyModel, error)
+}
+
+// policyRepo 协议仓库
+type policyRepo struct{}
+
+// NewPolicyRepo 实例化协议仓库
+func NewPolicyRepo() Repo {
+	return &policyRepo{}
+}
+
+// CreatePolicy 发布新版本的协议
+func (repo *policyRepo) CreatePolicy(db *gorm.DB, policy *model.PolicyModel) (id uint64, err error) {
+	err = db.Create(policy).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[policy_repo] create policy err")
+	}
+
+	return policy.ID, nil
+}
+
+// GetLatestPolicy 获取某个类型的当前版本，没有发布过时返回空结构体
+func (repo *policyRepo) GetLatestPolicy(db *gorm.DB, typ string) (*model.PolicyModel, error) {
+	policy := model.PolicyModel{}
+	err := db.Where("type = ?", typ).Order("id desc").First(&policy).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[policy_repo] get latest policy err")
+	}
+
+	return &policy, nil
+}
+
+// GetPolicyByVersion 获取某个版本的协议，不存在时返回空结构体
+func (repo *policyRepo) GetPolicyByVersion(db *gorm.DB, typ, version string) (*model.PolicyModel, error) {
+	policy := model.PolicyModel{}
+	err := db.Where("type = ? and version = ?", typ, version).First(&policy).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[policy_repo] get policy by version err")
+	}
+
+	return &policy, nil
+}
+
+// GetPolicyList 获取某个类型的历史版本，按id倒序
+func (repo *policyRepo) GetPolicyList(db *gorm.DB, typ string, lastID uint64, limit int) ([]*model.PolicyModel, error) {
+	policies := make([]*model.PolicyModel, 0)
+	query := db.Where("type = ?", typ)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&policies).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[policy_repo] get policy list err")
+	}
+
+	return policies, nil
+}
+
+// CreateUserPolicy 记录用户同意协议
+func (repo *policyRepo) CreateUserPolicy(db *gorm.DB, up *model.UserPolicyModel) error {
+	err := db.Create(up).Error
+	if err != nil {
+		return errors.Wrap(err, "[policy_repo] create user policy err")
+	}
+
+	return nil
+}
+
+// GetUserPolicy 获取用户同意某个版本的记录，没有同意过时返回空结构体
+func (repo *policyRepo) GetUserPolicy(db *gorm.DB, userID, policyID uint64) (*model.UserPolicyModel, error) {
+	up := model.UserPolicyModel{}
+	err := db.Where("user_id = ? and policy_id = ?", userID, policyID).First(&up).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[policy_repo] get user policy err")
+	}
+
+	return &up, nil
+}
@@ -0,0 +1,128 @@
+package segment
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义用户分群仓库接口
+type Repo interface {
+	CreateSegment(db *gorm.DB, segment *model.SegmentModel) (id uint64, err error)
+	GetSegment(db *gorm.DB, id uint64) (*model.SegmentModel, error)
+	GetSegmentByName(db *gorm.DB, name string) (*model.SegmentModel, error)
+	GetSegmentList(db *gorm.DB) ([]*model.SegmentModel, error)
+	UpdateSegment(db *gorm.DB, id uint64, data map[string]interface{}) error
+	ScanSegmentUserIDs(db *gorm.DB, rules *model.SegmentRules, lastID uint64, limit int) ([]uint64, error)
+}
+
+// segmentRepo 用户分群仓库
+type segmentRepo struct{}
+
+// NewSegmentRepo 实例化用户分群仓库
+func NewSegmentRepo() Repo {
+	return &segmentRepo{}
+}
+
+// CreateSegment 新增分群
+func (repo *segmentRepo) CreateSegment(db *gorm.DB, segment *model.SegmentModel) (id uint64, err error) {
+	err = db.Create(segment).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[segment_repo] create segment err")
+	}
+
+	return segment.ID, nil
+}
+
+// GetSegment 获取分群，不存在时返回空结构体
+func (repo *segmentRepo) GetSegment(db *gorm.DB, id uint64) (*model.SegmentModel, error) {
+	segment := model.SegmentModel{}
+	err := db.Where("id = ?", id).First(&segment).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[segment_repo] get segment err")
+	}
+
+	return &segment, nil
+}
+
+// GetSegmentByName 通过名称获取分群，不存在时返回空结构体
+func (repo *segmentRepo) GetSegmentByName(db *gorm.DB, name string) (*model.SegmentModel, error) {
+	segment := model.SegmentModel{}
+	err := db.Where("name = ?", name).First(&segment).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[segment_repo] get segment by name err")
+	}
+
+	return &segment, nil
+}
+
+// GetSegmentList 获取所有分群
+func (repo *segmentRepo) GetSegmentList(db *gorm.DB) ([]*model.SegmentModel, error) {
+	segments := make([]*model.SegmentModel, 0)
+	err := db.Order("id desc").Find(&segments).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[segment_repo] get segment list err")
+	}
+
+	return segments, nil
+}
+
+// UpdateSegment 更新分群
+func (repo *segmentRepo) UpdateSegment(db *gorm.DB, id uint64, data map[string]interface{}) error {
+	err := db.Model(&model.SegmentModel{}).Where("id = ?", id).Updates(data).Error
+	if err != nil {
+		return errors.Wrap(err, "[segment_repo] update segment err")
+	}
+
+	return nil
+}
+
+// ScanSegmentUserIDs 按id正序分批获取符合分群规则的用户id
+func (repo *segmentRepo) ScanSegmentUserIDs(db *gorm.DB, rules *model.SegmentRules, lastID uint64, limit int) ([]uint64, error) {
+	query := db.Table("user_base").Where("user_base.id > ?", lastID)
+	if rules.RegisteredAfter != nil {
+		query = query.Where("user_base.created_at >= ?", rules.RegisteredAfter)
+	}
+	if rules.RegisteredBefore != nil {
+		query = query.Where("user_base.created_at < ?", rules.RegisteredBefore)
+	}
+	if rules.Plan != "" {
+		query = query.Where("user_base.plan = ?", rules.Plan)
+	}
+	if rules.Region != "" {
+		query = query.Where("user_base.region = ?", rules.Region)
+	}
+	if rules.ActiveWithinDays > 0 {
+		activeAt := time.Now().AddDate(0, 0, -rules.ActiveWithinDays)
+		query = query.Where("user_base.id in (select user_id from user_device where last_login_at >= ?)", activeAt)
+	}
+	if rules.InactiveDays > 0 {
+		inactiveAt := time.Now().AddDate(0, 0, -rules.InactiveDays)
+		query = query.Where("user_base.created_at < ?", inactiveAt).
+			Where("user_base.id not in (select user_id from user_device where last_login_at >= ?)", inactiveAt)
+	}
+	if rules.MinFollowerCount > 0 || rules.MaxFollowerCount > 0 {
+		// 没有统计记录的用户粉丝数为0
+		query = query.Joins("left join user_stat on user_stat.user_id = user_base.id")
+		if rules.MinFollowerCount > 0 {
+			query = query.Where("user_stat.follower_count >= ?", rules.MinFollowerCount)
+		}
+		if rules.MaxFollowerCount > 0 {
+			query = query.Where("ifnull(user_stat.follower_count, 0) <= ?", rules.MaxFollowerCount)
+		}
+	}
+	if len(rules.Tags) > 0 {
+		query = query.Where("user_base.id in (select user_id from user_tag where tag in (?))", rules.Tags)
+	}
+
+	userIDs := make([]uint64, 0)
+	err := query.Order("user_base.id asc").Limit(limit).Pluck("user_base.id", &userIDs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[segment_repo] scan segment user ids err")
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// Repo 定义异步任务仓库接口
+type Repo interface {
+	CreateTask(db *gorm.DB, task *model.TaskModel) (id uint64, err error)
+	GetTask(db *gorm.DB, id uint64) (*model.TaskModel, error)
+	GetTaskList(db *gorm.DB, where map[string]interface{}, lastID uint64, limit int) ([]*model.TaskModel, error)
+	UpdateTask(db *gorm.DB, id uint64, data map[string]interface{}) error
+	// UpdateTaskStatus 只更新状态为 fromStatus 的任务，返回是否更新成功
+	UpdateTaskStatus(db *gorm.DB, id uint64, fromStatus []int, data map[string]interface{}) (bool, error)
+}
+
+// taskRepo 异步任务仓库
+type taskRepo struct{}
+
+// NewTaskRepo 实例化异步任务仓库
+func NewTaskRepo() Repo {
+	return &taskRepo{}
+}
+
+// CreateTask 创建任务
+func (repo *taskRepo) CreateTask(db *gorm.DB, task *model.TaskModel) (id uint64, err error) {
+	err = db.Create(task).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[task_repo] create task err")
+	}
+
+	return task.ID, nil
+}
+
+// GetTask 获取任务，不存在时返回空结构体
+func (repo *taskRepo) GetTask(db *gorm.DB, id uint64) (*model.TaskModel, error) {
+	task := model.TaskModel{}
+	err := db.Where("id = ?", id).First(&task).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[task_repo] get task err")
+	}
+
+	return &task, nil
+}
+
+// GetTaskList 按id倒序获取任务
+func (repo *taskRepo) GetTaskList(db *gorm.DB, where map[string]interface{}, lastID uint64, limit int) ([]*model.TaskModel, error) {
+	tasks := make([]*model.TaskModel, 0)
+	query := db.Where(where)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&tasks).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[task_repo] get task list err")
+	}
+
+	return tasks, nil
+}
+
+// UpdateTask 更新任务
+func (repo *taskRepo) UpdateTask(db *gorm.DB, id uint64, data map[string]interface{}) error {
+	err := db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(data).Error
+	if err != nil {
+		return errors.Wrap(err, "[task_repo] update task err")
+	}
+
+	return nil
+}
+
+// UpdateTaskStatus 按状态更新任务，避免重复执行或覆盖已完成的结果
+func (repo *taskRepo) UpdateTaskStatus(db *gorm.DB, id uint64, fromStatus []int, data map[string]interface{}) (bool, error) {
+	result := db.Model(&model.TaskModel{}).Where("id = ? and status in (?)", id, fromStatus).Updates(data)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[task_repo] update task status err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,34 @@
+package user
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 用户名、邮箱、手机号等标识有唯一索引，软删除的记录仍然占用标识
+// 宽限期内保留原来的标识，用户可以恢复帐号，其他人不能注册；
+// 超过宽限期后回收：把标识改为墓碑值释放唯一索引，记录保留用于审计，之后不能再恢复
+
+const (
+	// tombstonePrefix 墓碑值的前缀，格式为 deleted:<记录id>:<原标识>
+	// 记录id保证墓碑值唯一，注册时拒绝以该前缀开头的用户名、邮箱，不会和正常的标识冲突
+	tombstonePrefix = "deleted:"
+	// tombstoneMaxLen 标识字段的长度，超出时截断原标识
+	tombstoneMaxLen = 255
+)
+
+// tombstone 生成回收后的墓碑值
+func tombstone(value string, id uint64) string {
+	prefix := tombstonePrefix + strconv.FormatUint(id, 10) + ":"
+	max := tombstoneMaxLen - utf8.RuneCountInString(prefix)
+	if utf8.RuneCountInString(value) > max {
+		value = string([]rune(value)[:max])
+	}
+	return prefix + value
+}
+
+// IsTombstone 是否是墓碑值
+func IsTombstone(value string) bool {
+	return strings.HasPrefix(value, tombstonePrefix)
+}
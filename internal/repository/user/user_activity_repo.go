@@ -0,0 +1,87 @@
+package user
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/cache/user"
+	"github.com/1024casts/snake/internal/model"
+)
+
+// ActivityRepo 定义用户动作仓库接口
+type ActivityRepo interface {
+	AddActivity(a *model.UserActivityModel) error
+	ArchiveActivity(db *gorm.DB, a *model.UserActivityModel) (created bool, err error)
+	GetActivityList(db *gorm.DB, userID uint64, beforeID uint64, limit int) ([]*model.UserActivityModel, error)
+}
+
+// userActivityRepo 用户动作仓库
+type userActivityRepo struct {
+	userCache *user.Cache
+}
+
+// NewUserActivityRepo 实例化用户动作仓库
+func NewUserActivityRepo() ActivityRepo {
+	return &userActivityRepo{
+		userCache: user.NewUserCache(),
+	}
+}
+
+// AddActivity 写入用户动作 stream，成功后 a.ActivityID 为换算后的消息 id
+func (repo *userActivityRepo) AddActivity(a *model.UserActivityModel) error {
+	streamID, err := repo.userCache.AddUserActivity(a)
+	if err != nil {
+		return errors.Wrapf(err, "[user_activity_repo] add user activity err, uid: %d", a.UserID)
+	}
+	a.ActivityID, err = model.ParseActivityID(streamID)
+	if err != nil {
+		return errors.Wrap(err, "[user_activity_repo] parse activity id err")
+	}
+	a.CreatedAt = model.ActivityTime(a.ActivityID)
+	return nil
+}
+
+// ArchiveActivity 归档到数据库，重复归档同一条动作时 created 为 false
+func (repo *userActivityRepo) ArchiveActivity(db *gorm.DB, a *model.UserActivityModel) (bool, error) {
+	var count int64
+	err := db.Model(&model.UserActivityModel{}).
+		Where("user_id = ? AND activity_id = ?", a.UserID, a.ActivityID).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "[user_activity_repo] check user activity err")
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	if err := db.Create(a).Error; err != nil {
+		return false, errors.Wrap(err, "[user_activity_repo] archive user activity err")
+	}
+	return true, nil
+}
+
+// GetActivityList 按时间倒序获取 activity_id 小于 beforeID 的动作，beforeID 为0时从最新的开始
+// 先查 stream，stream 中的条数不够时(已被裁剪或过期)再从数据库归档中查更早的
+func (repo *userActivityRepo) GetActivityList(db *gorm.DB, userID uint64, beforeID uint64, limit int) ([]*model.UserActivityModel, error) {
+	activities, err := repo.userCache.GetUserActivity(userID, beforeID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[user_activity_repo] get user activity from cache err, uid: %d", userID)
+	}
+	if len(activities) >= limit {
+		return activities, nil
+	}
+	if len(activities) > 0 {
+		beforeID = activities[len(activities)-1].ActivityID
+	}
+
+	archived := make([]*model.UserActivityModel, 0)
+	query := db.Where("user_id = ?", userID)
+	if beforeID > 0 {
+		query = query.Where("activity_id < ?", beforeID)
+	}
+	err = query.Order("activity_id desc").Limit(limit - len(activities)).Find(&archived).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_activity_repo] get user activity list err")
+	}
+
+	return append(activities, archived...), nil
+}
@@ -1,38 +1,55 @@
 package user
 
 import (
-	"fmt"
+	"sort"
 	"time"
 
-	"github.com/1024casts/snake/pkg/redis"
-
-	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 
 	"github.com/1024casts/snake/internal/cache/user"
 	"github.com/1024casts/snake/internal/model"
-	"github.com/1024casts/snake/pkg/log"
 )
 
 // BaseRepo 定义用户仓库接口
 type BaseRepo interface {
 	Create(db *gorm.DB, user model.UserBaseModel) (id uint64, err error)
 	Update(db *gorm.DB, id uint64, userMap map[string]interface{}) error
+	UpdateWithVersion(db *gorm.DB, id uint64, version int, userMap map[string]interface{}) (bool, error)
 	GetUserByID(db *gorm.DB, id uint64) (*model.UserBaseModel, error)
 	GetUsersByIds(db *gorm.DB, ids []uint64) ([]*model.UserBaseModel, error)
 	GetUserByPhone(db *gorm.DB, phone int) (*model.UserBaseModel, error)
 	GetUserByEmail(db *gorm.DB, email string) (*model.UserBaseModel, error)
+	GetUserByUsername(db *gorm.DB, username string) (*model.UserBaseModel, error)
+	ScanUserIDs(db *gorm.DB, where map[string]interface{}, lastID uint64, limit int) ([]uint64, error)
+	CountUsers(db *gorm.DB, where map[string]interface{}) (int, error)
+	ReclaimDeletedUsers(db *gorm.DB, before time.Time, limit int) ([]uint64, error)
+
+	// 停用、恢复帐号
+	Deactivate(db *gorm.DB, id uint64, at time.Time) (bool, error)
+	Reactivate(db *gorm.DB, id uint64) (bool, error)
+	GetUserByIDUnscoped(db *gorm.DB, id uint64) (*model.UserBaseModel, error)
+	IsUsernameTaken(db *gorm.DB, username string, exceptID uint64) (bool, error)
+
+	// 热点用户
+	GetHotUserIDs(limit int) ([]uint64, error)
+	RefreshUserCache(db *gorm.DB, id uint64) error
+	DecayHotUsers(limit int) error
 }
 
-// userRepo 用户仓库
-type userRepo struct {
-	userCache *user.Cache
-}
+// ConsistencyScope 用户资料写入标记的 scope，修改资料后本人的读取在标记有效期内读取最新数据
+const ConsistencyScope = "user"
+
+// userRepo 用户仓库，只读写数据库，缓存由 cachedUserRepo 处理
+type userRepo struct{}
 
 // NewUserRepo 实例化用户仓库
 func NewUserRepo() BaseRepo {
-	return &userRepo{
-		userCache: user.NewUserCache(),
+	return &shadowUserRepo{
+		cachedUserRepo: &cachedUserRepo{
+			userRepo:  &userRepo{},
+			userCache: user.NewUserCache(),
+		},
 	}
 }
 
@@ -48,82 +65,57 @@ func (repo *userRepo) Create(db *gorm.DB, user model.UserBaseModel) (id uint64,
 
 // Update 更新用户信息
 func (repo *userRepo) Update(db *gorm.DB, id uint64, userMap map[string]interface{}) error {
-	user, err := repo.GetUserByID(db, id)
-	if err != nil {
-		return errors.Wrap(err, "[user_repo] update user data err")
-	}
-
-	// 删除cache
-	err = repo.userCache.DelUserBaseCache(id)
-	if err != nil {
-		log.Warnf("[user_repo] delete user cache err: %v", err)
-	}
+	_, err := repo.update(db.Where("id = ?", id), userMap)
+	return err
+}
 
-	return db.Model(user).Updates(userMap).Error
+// UpdateWithVersion 版本号一致时才更新用户信息，版本号已变化时返回 false
+func (repo *userRepo) UpdateWithVersion(db *gorm.DB, id uint64, version int, userMap map[string]interface{}) (bool, error) {
+	return repo.update(db.Where("id = ? AND version = ?", id, version), userMap)
 }
 
-// GetUserByID 获取用户
-func (repo *userRepo) GetUserByID(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
-	// 从cache获取
-	userModel, err := repo.userCache.GetUserBaseCache(id)
-	if err != nil {
-		return nil, errors.Wrap(err, "[user_repo] get user cache data err")
+// update 按条件更新用户信息，每次更新版本号加1
+func (repo *userRepo) update(query *gorm.DB, userMap map[string]interface{}) (bool, error) {
+	data := make(map[string]interface{}, len(userMap)+1)
+	for k, v := range userMap {
+		data[k] = v
 	}
-	if userModel != nil && userModel.ID > 0 {
-		return userModel, nil
+	data["version"] = gorm.Expr("version + 1")
+	res := query.Model(&model.UserBaseModel{}).Updates(data)
+	if res.Error != nil {
+		return false, errors.Wrap(res.Error, "[user_repo] update user data err")
 	}
+	return res.RowsAffected > 0, nil
+}
 
-	// 加锁，防止缓存击穿
-	key := fmt.Sprintf("uid:%d", id)
-	lock := redis.NewLock(redis.RedisClient, key, 3*time.Second)
-	token := lock.GenToken()
-
-	isLock, err := lock.Lock(token)
-	if err != nil || !isLock {
-		return nil, errors.Wrap(err, "[user_repo] lock err")
-	}
-	defer lock.Unlock(token)
-
+// GetUserByID 获取用户，不存在时返回空结构体
+func (repo *userRepo) GetUserByID(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
 	data := &model.UserBaseModel{}
-	if isLock {
-		// 从数据库中获取
-		err = db.Where(&model.UserBaseModel{ID: id}).First(data).Error
-		if err != nil && err != gorm.ErrRecordNotFound {
-			return nil, errors.Wrap(err, "[user_repo] get user data err")
-		}
-
-		// 写入cache
-		err = repo.userCache.SetUserBaseCache(id, data)
-		if err != nil {
-			return data, errors.Wrap(err, "[user_repo] set user data err")
-		}
+	err := db.Where("id = ?", id).First(data).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_repo] get user data err")
 	}
 	return data, nil
 }
 
-// GetUsersByIds 批量获取用户
+// GetUsersByIds 批量获取用户，按 userIDs 的顺序返回，不存在的用户不返回
 func (repo *userRepo) GetUsersByIds(db *gorm.DB, userIDs []uint64) ([]*model.UserBaseModel, error) {
 	users := make([]*model.UserBaseModel, 0)
-
-	// 从cache批量获取
-	userCacheMap, err := repo.userCache.MultiGetUserBaseCache(userIDs)
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	err := db.Where("id in (?)", userIDs).Find(&users).Error
 	if err != nil {
-		return users, errors.Wrap(err, "[user_repo] multi get user cache data err")
-	}
-
-	// 查询未命中
-	for _, userID := range userIDs {
-		idx := repo.userCache.GetUserBaseCacheKey(userID)
-		userModel, ok := userCacheMap[idx]
-		if !ok {
-			userModel, err = repo.GetUserByID(db, userID)
-			if err != nil {
-				log.Warnf("get user model err: %v", err)
-				continue
-			}
+		return nil, errors.Wrap(err, "[user_repo] get users data err")
+	}
+
+	pos := make(map[uint64]int, len(userIDs))
+	for i, id := range userIDs {
+		if _, ok := pos[id]; !ok {
+			pos[id] = i
 		}
-		users = append(users, userModel)
 	}
+	sort.Slice(users, func(i, j int) bool { return pos[users[i].ID] < pos[users[j].ID] })
 	return users, nil
 }
 
@@ -148,3 +140,99 @@ func (repo *userRepo) GetUserByEmail(db *gorm.DB, email string) (*model.UserBase
 
 	return &user, nil
 }
+
+// GetUserByUsername 根据用户名获取用户，不存在时返回空结构体
+func (repo *userRepo) GetUserByUsername(db *gorm.DB, username string) (*model.UserBaseModel, error) {
+	user := &model.UserBaseModel{}
+	err := db.Where("username = ?", username).First(user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_repo] get user err by username")
+	}
+
+	return user, nil
+}
+
+// ScanUserIDs 按id正序分批获取满足条件的用户id，用于遍历用户
+func (repo *userRepo) ScanUserIDs(db *gorm.DB, where map[string]interface{}, lastID uint64, limit int) ([]uint64, error) {
+	userIDs := make([]uint64, 0)
+	err := db.Model(&model.UserBaseModel{}).Where(where).Where("id > ?", lastID).
+		Order("id asc").Limit(limit).Pluck("id", &userIDs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_repo] scan user ids err")
+	}
+
+	return userIDs, nil
+}
+
+// CountUsers 获取满足条件的用户数
+func (repo *userRepo) CountUsers(db *gorm.DB, where map[string]interface{}) (int, error) {
+	var count int64
+	err := db.Model(&model.UserBaseModel{}).Where(where).Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_repo] count users err")
+	}
+
+	return int(count), nil
+}
+
+// ReclaimDeletedUsers 回收删除时间早于 before 的用户的用户名和邮箱，改为墓碑值后可以被重新注册，返回回收的用户id
+func (repo *userRepo) ReclaimDeletedUsers(db *gorm.DB, before time.Time, limit int) ([]uint64, error) {
+	users := make([]*model.UserBaseModel, 0)
+	err := db.Unscoped().Where("deleted_at < ? and username not like ?", before, tombstonePrefix+"%").
+		Order("id asc").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_repo] get deleted users err")
+	}
+
+	userIDs := make([]uint64, 0, len(users))
+	for _, u := range users {
+		userMap := map[string]interface{}{"username": tombstone(u.Username, u.ID)}
+		if u.Email != "" {
+			userMap["email"] = tombstone(u.Email, u.ID)
+		}
+		if _, err := repo.update(db.Unscoped().Where("id = ?", u.ID), userMap); err != nil {
+			return userIDs, errors.Wrapf(err, "[user_repo] reclaim user err, uid: %d", u.ID)
+		}
+		userIDs = append(userIDs, u.ID)
+	}
+	return userIDs, nil
+}
+
+// Deactivate 停用帐号并软删除，之后所有查询都看不到该用户，已停用的用户返回 false
+func (repo *userRepo) Deactivate(db *gorm.DB, id uint64, at time.Time) (bool, error) {
+	return repo.update(db.Where("id = ?", id), map[string]interface{}{
+		"status":     model.UserStatusDeactivated,
+		"deleted_at": at,
+	})
+}
+
+// Reactivate 恢复已停用的帐号，没有停用或用户名已回收时返回 false
+func (repo *userRepo) Reactivate(db *gorm.DB, id uint64) (bool, error) {
+	query := db.Unscoped().Where("id = ? and deleted_at is not null and username not like ?", id, tombstonePrefix+"%")
+	return repo.update(query, map[string]interface{}{
+		"status":     model.UserStatusActive,
+		"deleted_at": nil,
+	})
+}
+
+// GetUserByIDUnscoped 获取用户，包括已停用的用户，不存在时返回空结构体
+func (repo *userRepo) GetUserByIDUnscoped(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
+	data := &model.UserBaseModel{}
+	err := db.Unscoped().Where("id = ?", id).First(data).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_repo] get user data unscoped err")
+	}
+	return data, nil
+}
+
+// IsUsernameTaken 用户名是否已被 exceptID 以外的用户占用，已停用但还没有回收的用户仍然占用用户名
+func (repo *userRepo) IsUsernameTaken(db *gorm.DB, username string, exceptID uint64) (bool, error) {
+	var count int64
+	err := db.Unscoped().Model(&model.UserBaseModel{}).
+		Where("username = ? and id <> ?", username, exceptID).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "[user_repo] count username err")
+	}
+
+	return count > 0, nil
+}
@@ -8,9 +8,10 @@ import (
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-test/deep"
-	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 
 	"github.com/1024casts/snake/internal/model"
 )
@@ -32,11 +33,10 @@ func (s *Suite) SetupSuite() {
 	db, s.mock, err = sqlmock.New()
 	require.NoError(s.T(), err)
 
-	gdb, err := gorm.Open("postgres", db)
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
 	require.NoError(s.T(), err)
 
 	s.db = gdb
-	s.db.LogMode(true)
 
 	s.repository = NewUserRepo()
 }
@@ -62,13 +62,12 @@ func (s *Suite) Test_repository_Create() {
 		UpdatedAt: time.Now(),
 	}
 
-	const sqlInsert = `INSERT INTO "users" ("username","password","phone","email","avatar","sex","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "users"."id"`
+	const sqlInsert = "INSERT INTO `user_base` (`username`,`password`,`phone`,`email`,"
 	const newID = 1
 
 	s.mock.ExpectBegin()
-	s.mock.ExpectQuery(regexp.QuoteMeta(sqlInsert)).
-		WithArgs(user.Username, user.Password, user.Phone, user.Email, user.Avatar, user.Sex, user.CreatedAt, user.UpdatedAt).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(newID))
+	s.mock.ExpectExec(regexp.QuoteMeta(sqlInsert)).
+		WillReturnResult(sqlmock.NewResult(newID, 1))
 	s.mock.ExpectCommit()
 
 	_, err := s.repository.Create(s.db, user)
@@ -83,8 +82,8 @@ func (s *Suite) Test_repository_GetUserByID() {
 	)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "users" WHERE (id = $1)`)).
-		WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(id, username))
+		"SELECT * FROM `user_base` WHERE id = ?")).
+		WithArgs(id, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(id, username))
 
 	res, err := s.repository.GetUserByID(s.db, id)
 
@@ -100,8 +99,8 @@ func (s *Suite) Test_repository_GetUserByPhone() {
 	)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "users" WHERE (phone = $1)`)).
-		WithArgs(phone).WillReturnRows(sqlmock.NewRows([]string{"id", "username", "phone"}).AddRow(id, username, phone))
+		"SELECT * FROM `user_base` WHERE phone = ?")).
+		WithArgs(phone, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "username", "phone"}).AddRow(id, username, phone))
 
 	res, err := s.repository.GetUserByPhone(s.db, phone)
 
@@ -117,8 +116,8 @@ func (s *Suite) Test_repository_GetUserByEmail() {
 	)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "users" WHERE (email = $1)`)).
-		WithArgs(email).WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}).AddRow(id, username, email))
+		"SELECT * FROM `user_base` WHERE email = ?")).
+		WithArgs(email, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}).AddRow(id, username, email))
 
 	res, err := s.repository.GetUserByEmail(s.db, email)
 
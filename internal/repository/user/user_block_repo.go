@@ -0,0 +1,76 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// BlockRepo 定义拉黑仓库接口
+type BlockRepo interface {
+	CreateUserBlock(db *gorm.DB, userID, blockedUID uint64) error
+	DeleteUserBlock(db *gorm.DB, userID, blockedUID uint64) (bool, error)
+	IsBlockedEither(db *gorm.DB, userID, otherUID uint64) (bool, error)
+	GetBlockedList(db *gorm.DB, userID, lastID uint64, limit int) ([]*model.UserBlockModel, error)
+}
+
+// userBlockRepo 拉黑仓库
+type userBlockRepo struct{}
+
+// NewUserBlockRepo 实例化拉黑仓库
+func NewUserBlockRepo() BlockRepo {
+	return &userBlockRepo{}
+}
+
+// CreateUserBlock 拉黑用户，已经取消的拉黑恢复为正常状态
+func (repo *userBlockRepo) CreateUserBlock(db *gorm.DB, userID, blockedUID uint64) error {
+	now := time.Now()
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "blocked_uid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"status": model.BlockStatusNormal, "updated_at": now}),
+	}).Create(&model.UserBlockModel{UserID: userID, BlockedUID: blockedUID, Status: model.BlockStatusNormal,
+		CreatedAt: now, UpdatedAt: now}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_block_repo] create user block err")
+	}
+	return nil
+}
+
+// DeleteUserBlock 取消拉黑，没有拉黑时返回 false
+func (repo *userBlockRepo) DeleteUserBlock(db *gorm.DB, userID, blockedUID uint64) (bool, error) {
+	res := db.Model(&model.UserBlockModel{}).
+		Where("user_id = ? AND blocked_uid = ? AND status = ?", userID, blockedUID, model.BlockStatusNormal).
+		Updates(map[string]interface{}{"status": model.BlockStatusDelete, "updated_at": time.Now()})
+	if res.Error != nil {
+		return false, errors.Wrap(res.Error, "[user_block_repo] delete user block err")
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// IsBlockedEither 两个用户之间是否有一方拉黑了另一方
+func (repo *userBlockRepo) IsBlockedEither(db *gorm.DB, userID, otherUID uint64) (bool, error) {
+	var count int64
+	err := db.Model(&model.UserBlockModel{}).
+		Where("((user_id = ? AND blocked_uid = ?) OR (user_id = ? AND blocked_uid = ?)) AND status = ?",
+			userID, otherUID, otherUID, userID, model.BlockStatusNormal).
+		Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "[user_block_repo] count user block err")
+	}
+	return count > 0, nil
+}
+
+// GetBlockedList 获取拉黑列表，lastID 为上一页最后一条记录的id
+func (repo *userBlockRepo) GetBlockedList(db *gorm.DB, userID, lastID uint64, limit int) ([]*model.UserBlockModel, error) {
+	list := make([]*model.UserBlockModel, 0)
+	err := db.Where("user_id = ? AND id < ? AND status = ?", userID, lastID, model.BlockStatusNormal).
+		Order("id desc").Limit(limit).Find(&list).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_block_repo] get blocked list err")
+	}
+	return list, nil
+}
@@ -0,0 +1,306 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/cache/user"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/cache/typed"
+	"github.com/1024casts/snake/pkg/consistency"
+	"github.com/1024casts/snake/pkg/lock"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// cachedUserRepo 用户资料的旁路缓存，读取时先查 redis，未命中时查库并回写，过期时间带随机浮动
+// redis 不可用时直接查库，修改资料时删除缓存
+type cachedUserRepo struct {
+	*userRepo
+	userCache *user.Cache
+}
+
+// Create 创建用户，删除用户名不存在时缓存的空对象
+func (repo *cachedUserRepo) Create(db *gorm.DB, u model.UserBaseModel) (uint64, error) {
+	id, err := repo.userRepo.Create(db, u)
+	if err != nil {
+		return 0, err
+	}
+	repo.delUsernameCache(u.Username)
+	return id, nil
+}
+
+// Update 更新用户信息并删除缓存
+func (repo *cachedUserRepo) Update(db *gorm.DB, id uint64, userMap map[string]interface{}) error {
+	repo.delCache(id)
+	if err := repo.userRepo.Update(db, id, userMap); err != nil {
+		return err
+	}
+	repo.pin(id)
+	if username, ok := userMap["username"].(string); ok {
+		repo.delUsernameCache(username)
+	}
+	return nil
+}
+
+// UpdateWithVersion 版本号一致时才更新用户信息并删除缓存
+func (repo *cachedUserRepo) UpdateWithVersion(db *gorm.DB, id uint64, version int, userMap map[string]interface{}) (bool, error) {
+	repo.delCache(id)
+	ok, err := repo.userRepo.UpdateWithVersion(db, id, version, userMap)
+	if err != nil || !ok {
+		return ok, err
+	}
+	repo.pin(id)
+	if username, ok := userMap["username"].(string); ok {
+		repo.delUsernameCache(username)
+	}
+	return true, nil
+}
+
+// ReclaimDeletedUsers 回收已删除用户的用户名和邮箱并删除缓存
+func (repo *cachedUserRepo) ReclaimDeletedUsers(db *gorm.DB, before time.Time, limit int) ([]uint64, error) {
+	userIDs, err := repo.userRepo.ReclaimDeletedUsers(db, before, limit)
+	for _, id := range userIDs {
+		repo.delCache(id)
+	}
+	return userIDs, err
+}
+
+// Deactivate 停用帐号并删除缓存，用户名映射到的用户读取为空，不需要删除
+func (repo *cachedUserRepo) Deactivate(db *gorm.DB, id uint64, at time.Time) (bool, error) {
+	repo.delCache(id)
+	ok, err := repo.userRepo.Deactivate(db, id, at)
+	if err != nil || !ok {
+		return ok, err
+	}
+	repo.pin(id)
+	return true, nil
+}
+
+// Reactivate 恢复帐号并删除缓存，停用期间查询用户名时可能缓存了不存在
+func (repo *cachedUserRepo) Reactivate(db *gorm.DB, id uint64) (bool, error) {
+	repo.delCache(id)
+	ok, err := repo.userRepo.Reactivate(db, id)
+	if err != nil || !ok {
+		return ok, err
+	}
+	repo.pin(id)
+	u, err := repo.userRepo.GetUserByID(db, id)
+	if err != nil {
+		return true, err
+	}
+	repo.delUsernameCache(u.Username)
+	return true, nil
+}
+
+// delCache 删除用户缓存，失败时只记录日志，由 pin 保证本人读到最新数据
+func (repo *cachedUserRepo) delCache(id uint64) {
+	if err := repo.userCache.DelUserBaseCache(id); err != nil {
+		log.Warnf("[user_repo] delete user cache err: %v, uid: %d", err, id)
+	}
+}
+
+// delUsernameCache 删除新用户名的映射，之前查询时可能缓存了不存在
+// 旧用户名的映射不需要删除，GetUserByUsername 会校验映射到的用户当前的用户名
+func (repo *cachedUserRepo) delUsernameCache(username string) {
+	if err := repo.userCache.DelUsernameCache(username); err != nil {
+		log.Warnf("[user_repo] delete username cache err: %v, username: %s", err, username)
+	}
+}
+
+// pin 删除缓存后、更新完成前的读取可能会把旧数据写回缓存，标记后的读取直接查库并刷新缓存
+func (repo *cachedUserRepo) pin(id uint64) {
+	if err := consistency.Pin(ConsistencyScope, id); err != nil {
+		log.Warnf("[user_repo] pin user err: %v, uid: %d", err, id)
+	}
+}
+
+// GetUserByID 获取用户，不存在时返回空结构体
+func (repo *cachedUserRepo) GetUserByID(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
+	// 记录访问次数，用于热点用户预热
+	if err := repo.userCache.IncrUserAccess(id); err != nil {
+		log.Warnf("[user_repo] incr user access err: %v", err)
+	}
+
+	// 刚修改过的用户直接读取最新数据
+	if consistency.Pinned(ConsistencyScope, id) {
+		return repo.getFreshUser(db, id)
+	}
+
+	// 从cache获取
+	userModel, err := repo.userCache.GetUserBaseCache(id)
+	if err == nil {
+		return userModel, nil
+	}
+	// 命中空对象，用户不存在
+	if err == typed.ErrNotFound {
+		return &model.UserBaseModel{}, nil
+	}
+	if err != typed.ErrCacheMiss {
+		log.Warnf("[user_repo] get user cache err: %v, uid: %d", err, id)
+		return repo.userRepo.GetUserByID(db, id)
+	}
+
+	// 加锁，防止缓存击穿
+	l := lock.New(redis.RedisClient, fmt.Sprintf("uid:%d", id), lock.WithTTL(3*time.Second))
+	isLock, err := l.TryLock()
+	if err != nil {
+		log.Warnf("[user_repo] lock err: %v, uid: %d", err, id)
+		return repo.userRepo.GetUserByID(db, id)
+	}
+	if !isLock {
+		return nil, errors.Wrap(lock.ErrNotAcquired, "[user_repo] lock err")
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("[user_repo] release lock err: %v", err)
+		}
+	}()
+
+	data, err := repo.userRepo.GetUserByID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.userCache.SetUserBaseCache(id, data); err != nil {
+		return data, errors.Wrap(err, "[user_repo] set user data err")
+	}
+	return data, nil
+}
+
+// GetUserByUsername 根据用户名获取用户，不存在时返回空结构体
+// 缓存用户名到用户id的映射，再按id读取用户缓存；映射到的用户已改名时视为未命中，重新查库
+func (repo *cachedUserRepo) GetUserByUsername(db *gorm.DB, username string) (*model.UserBaseModel, error) {
+	id, err := repo.userCache.GetUsernameCache(username)
+	switch err {
+	case nil:
+		u, err := repo.GetUserByID(db, id)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(u.Username, username) {
+			return u, nil
+		}
+	case typed.ErrNotFound:
+		return &model.UserBaseModel{}, nil
+	case typed.ErrCacheMiss:
+	default:
+		log.Warnf("[user_repo] get username cache err: %v, username: %s", err, username)
+		return repo.userRepo.GetUserByUsername(db, username)
+	}
+
+	u, err := repo.userRepo.GetUserByUsername(db, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.userCache.SetUsernameCache(username, u.ID); err != nil {
+		log.Warnf("[user_repo] set username cache err: %v, username: %s", err, username)
+	}
+	return u, nil
+}
+
+// GetUsersByIds 批量获取用户，按 userIDs 的顺序返回，不存在的用户不返回
+// 未命中和刚修改过的用户一次查库，查到的用户和不存在的用户一起回写缓存
+func (repo *cachedUserRepo) GetUsersByIds(db *gorm.DB, userIDs []uint64) ([]*model.UserBaseModel, error) {
+	if len(userIDs) == 0 {
+		return make([]*model.UserBaseModel, 0), nil
+	}
+
+	userMap, notFound, err := repo.userCache.MultiGetUserBaseCache(userIDs)
+	if err != nil {
+		log.Warnf("[user_repo] multi get user cache err: %v", err)
+		return repo.userRepo.GetUsersByIds(db, userIDs)
+	}
+
+	pinned := consistency.PinnedSet(ConsistencyScope, userIDs)
+	missed := make([]uint64, 0)
+	seen := make(map[uint64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, ok := userMap[id]; (!ok && !notFound[id]) || pinned[id] {
+			missed = append(missed, id)
+		}
+	}
+
+	if len(missed) > 0 {
+		// 有刚修改过的用户时从主库读取，避免从库延迟
+		if len(pinned) > 0 {
+			db = model.Primary(db)
+		}
+		users, err := repo.userRepo.GetUsersByIds(db, missed)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			userMap[u.ID] = u
+		}
+		absent := make([]uint64, 0)
+		for _, id := range missed {
+			if _, ok := userMap[id]; !ok {
+				absent = append(absent, id)
+			}
+		}
+		if err := repo.userCache.MultiSetUserBaseCache(users, absent); err != nil {
+			log.Warnf("[user_repo] multi set user cache err: %v", err)
+		}
+	}
+
+	users := make([]*model.UserBaseModel, 0, len(userIDs))
+	for _, id := range userIDs {
+		if u, ok := userMap[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// GetHotUserIDs 获取访问次数最多的用户id
+func (repo *cachedUserRepo) GetHotUserIDs(limit int) ([]uint64, error) {
+	userIDs, err := repo.userCache.GetHotUserIDs(limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_repo] get hot user ids err")
+	}
+	return userIDs, nil
+}
+
+// RefreshUserCache 从数据库重新加载用户并写入cache
+func (repo *cachedUserRepo) RefreshUserCache(db *gorm.DB, id uint64) error {
+	data, err := repo.userRepo.GetUserByID(db, id)
+	if err != nil {
+		return err
+	}
+
+	err = repo.userCache.SetUserBaseCache(id, data)
+	if err != nil {
+		return errors.Wrap(err, "[user_repo] set user data err")
+	}
+	return nil
+}
+
+// getFreshUser 从主库读取最新数据并刷新cache，刷新失败不影响返回
+func (repo *cachedUserRepo) getFreshUser(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
+	data, err := repo.userRepo.GetUserByID(model.Primary(db), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.userCache.SetUserBaseCache(id, data); err != nil {
+		log.Warnf("[user_repo] set user cache err: %v, uid: %d", err, id)
+	}
+	return data, nil
+}
+
+// DecayHotUsers 衰减热点用户的访问计数
+func (repo *cachedUserRepo) DecayHotUsers(limit int) error {
+	err := repo.userCache.DecayUserAccess(limit)
+	if err != nil {
+		return errors.Wrap(err, "[user_repo] decay hot users err")
+	}
+	return nil
+}
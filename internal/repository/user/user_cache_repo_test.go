@@ -0,0 +1,66 @@
+package user
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/cache/user"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	os.Exit(m.Run())
+}
+
+func TestCachedUserRepo_GetUsersByIds(t *testing.T) {
+	repo := &cachedUserRepo{userRepo: &userRepo{}, userCache: user.NewUserCache()}
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	redis.RedisClient = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	query := regexp.QuoteMeta("SELECT * FROM `user_base` WHERE id in (?,?,?)")
+	mock.ExpectQuery(query).WithArgs(2, 1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "snake").AddRow(2, "eagle"))
+
+	// 未命中时一次查库，按传入的顺序返回，不存在的用户不返回
+	users, err := repo.GetUsersByIds(db, []uint64{2, 1, 3})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "eagle", users[0].Username)
+	assert.Equal(t, "snake", users[1].Username)
+
+	// 再次读取全部命中缓存，不存在的用户命中空对象
+	users, err = repo.GetUsersByIds(db, []uint64{3, 1, 2, 1})
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	assert.Equal(t, []uint64{1, 2, 1}, []uint64{users[0].ID, users[1].ID, users[2].ID})
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// redis 不可用时直接查库
+	mr.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user_base` WHERE id in (?)")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "snake"))
+	users, err = repo.GetUsersByIds(db, []uint64{1})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "snake", users[0].Username)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// DeviceRepo 定义用户设备仓库接口
+type DeviceRepo interface {
+	CreateUserDevice(db *gorm.DB, device *model.UserDeviceModel) (id uint64, err error)
+	UpdateUserDeviceLogin(db *gorm.DB, id uint64, userAgent, ip string) error
+	GetUserDevice(db *gorm.DB, userID uint64, deviceKey string) (*model.UserDeviceModel, error)
+	GetUserDeviceList(db *gorm.DB, userID uint64) ([]*model.UserDeviceModel, error)
+	CountActiveUsers(db *gorm.DB, since time.Time) (int, error)
+}
+
+// userDeviceRepo 用户设备仓库
+type userDeviceRepo struct{}
+
+// NewUserDeviceRepo 实例化用户设备仓库
+func NewUserDeviceRepo() DeviceRepo {
+	return &userDeviceRepo{}
+}
+
+// CreateUserDevice 新增设备
+func (repo *userDeviceRepo) CreateUserDevice(db *gorm.DB, device *model.UserDeviceModel) (id uint64, err error) {
+	err = db.Create(device).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_device_repo] create user device err")
+	}
+
+	return device.ID, nil
+}
+
+// UpdateUserDeviceLogin 更新设备最近一次登录信息
+func (repo *userDeviceRepo) UpdateUserDeviceLogin(db *gorm.DB, id uint64, userAgent, ip string) error {
+	device := model.UserDeviceModel{}
+	err := db.Model(&device).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"user_agent":    userAgent,
+			"last_ip":       ip,
+			"last_login_at": time.Now(),
+			"updated_at":    time.Now(),
+		}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_device_repo] update user device err")
+	}
+
+	return nil
+}
+
+// GetUserDevice 根据设备标识获取用户设备
+func (repo *userDeviceRepo) GetUserDevice(db *gorm.DB, userID uint64, deviceKey string) (*model.UserDeviceModel, error) {
+	device := model.UserDeviceModel{}
+	err := db.Where("user_id = ? AND device_key = ?", userID, deviceKey).First(&device).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_device_repo] get user device err")
+	}
+
+	return &device, nil
+}
+
+// GetUserDeviceList 获取用户的设备列表，最近登录的在前
+func (repo *userDeviceRepo) GetUserDeviceList(db *gorm.DB, userID uint64) ([]*model.UserDeviceModel, error) {
+	devices := make([]*model.UserDeviceModel, 0)
+	err := db.Where("user_id = ?", userID).Order("last_login_at desc").Find(&devices).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_device_repo] get user device list err")
+	}
+
+	return devices, nil
+}
+
+// CountActiveUsers 获取 since 之后有登录的用户数
+func (repo *userDeviceRepo) CountActiveUsers(db *gorm.DB, since time.Time) (int, error) {
+	var count int64
+	err := db.Model(&model.UserDeviceModel{}).Where("last_login_at >= ?", since).
+		Distinct("user_id").Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_device_repo] count active users err")
+	}
+
+	return int(count), nil
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// EmailChangeRepo 定义修改邮箱申请仓库接口
+type EmailChangeRepo interface {
+	CreateEmailChange(db *gorm.DB, change *model.UserEmailChangeModel) (id uint64, err error)
+	GetEmailChangeByToken(db *gorm.DB, token string) (*model.UserEmailChangeModel, error)
+	LockEmailChange(db *gorm.DB, id uint64) (*model.UserEmailChangeModel, error)
+	UpdateEmailChange(db *gorm.DB, id uint64, changeMap map[string]interface{}) error
+	CancelPendingEmailChange(db *gorm.DB, userID uint64) error
+}
+
+// userEmailChangeRepo 修改邮箱申请仓库
+type userEmailChangeRepo struct{}
+
+// NewUserEmailChangeRepo 实例化修改邮箱申请仓库
+func NewUserEmailChangeRepo() EmailChangeRepo {
+	return &userEmailChangeRepo{}
+}
+
+// CreateEmailChange 新增修改邮箱申请
+func (repo *userEmailChangeRepo) CreateEmailChange(db *gorm.DB, change *model.UserEmailChangeModel) (id uint64, err error) {
+	err = db.Create(change).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_email_change_repo] create email change err")
+	}
+
+	return change.ID, nil
+}
+
+// GetEmailChangeByToken 通过新邮箱或旧邮箱的 token 摘要获取申请，不存在时返回空结构体
+func (repo *userEmailChangeRepo) GetEmailChangeByToken(db *gorm.DB, token string) (*model.UserEmailChangeModel, error) {
+	change := model.UserEmailChangeModel{}
+	err := db.Where("old_token = ? or new_token = ?", token, token).First(&change).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_email_change_repo] get email change err")
+	}
+
+	return &change, nil
+}
+
+// LockEmailChange 在事务中加行锁读取申请，不存在时返回空结构体
+func (repo *userEmailChangeRepo) LockEmailChange(db *gorm.DB, id uint64) (*model.UserEmailChangeModel, error) {
+	change := model.UserEmailChangeModel{}
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&change).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_email_change_repo] lock email change err")
+	}
+
+	return &change, nil
+}
+
+// UpdateEmailChange 更新申请
+func (repo *userEmailChangeRepo) UpdateEmailChange(db *gorm.DB, id uint64, changeMap map[string]interface{}) error {
+	changeMap["updated_at"] = time.Now()
+	err := db.Model(&model.UserEmailChangeModel{}).Where("id = ?", id).Updates(changeMap).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_email_change_repo] update email change err")
+	}
+
+	return nil
+}
+
+// CancelPendingEmailChange 取消用户所有等待确认的申请
+func (repo *userEmailChangeRepo) CancelPendingEmailChange(db *gorm.DB, userID uint64) error {
+	err := db.Model(&model.UserEmailChangeModel{}).
+		Where("user_id = ? and status = ?", userID, model.EmailChangeStatusPending).
+		Updates(map[string]interface{}{"status": model.EmailChangeStatusCanceled, "updated_at": time.Now()}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_email_change_repo] cancel pending email change err")
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// EventRepo 定义用户事件仓库接口
+type EventRepo interface {
+	CreateUserEvent(db *gorm.DB, event *model.UserEventModel) (id uint64, err error)
+	GetUserEvents(db *gorm.DB, afterID uint64, limit int) ([]*model.UserEventModel, error)
+}
+
+// userEventRepo 用户事件仓库
+type userEventRepo struct{}
+
+// NewUserEventRepo 实例化用户事件仓库
+func NewUserEventRepo() EventRepo {
+	return &userEventRepo{}
+}
+
+// CreateUserEvent 追加用户事件
+func (repo *userEventRepo) CreateUserEvent(db *gorm.DB, event *model.UserEventModel) (id uint64, err error) {
+	err = db.Create(event).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_event_repo] create user event err")
+	}
+
+	return event.ID, nil
+}
+
+// GetUserEvents 按偏移量顺序获取 afterID 之后的事件
+func (repo *userEventRepo) GetUserEvents(db *gorm.DB, afterID uint64, limit int) ([]*model.UserEventModel, error) {
+	events := make([]*model.UserEventModel, 0)
+	err := db.Where("id > ?", afterID).Order("id asc").Limit(limit).Find(&events).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_event_repo] get user events err")
+	}
+
+	return events, nil
+}
@@ -1,10 +1,12 @@
 package user
 
 import (
+	"database/sql"
 	"time"
 
-	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/pkg/log"
@@ -16,10 +18,14 @@ type FollowRepo interface {
 	CreateUserFans(db *gorm.DB, userID, followerUID uint64) error
 	UpdateUserFollowStatus(db *gorm.DB, userID, followedUID uint64, status int) error
 	UpdateUserFansStatus(db *gorm.DB, userID, followerUID uint64, status int) error
-	GetFollowingUserList(userID, lastID uint64, limit int) ([]*model.UserFollowModel, error)
-	GetFollowerUserList(userID, lastID uint64, limit int) ([]*model.UserFansModel, error)
-	GetFollowByUIds(userID uint64, followingUID []uint64) (map[uint64]*model.UserFollowModel, error)
-	GetFansByUIds(userID uint64, followerUID []uint64) (map[uint64]*model.UserFansModel, error)
+	GetFollowingUserList(db *gorm.DB, userID, lastID uint64, limit int) ([]*model.UserFollowModel, error)
+	GetFollowerUserList(db *gorm.DB, userID, lastID uint64, limit int, order string) ([]*model.UserFansModel, error)
+	GetFollowByUIds(db *gorm.DB, userID uint64, followingUID []uint64) (map[uint64]*model.UserFollowModel, error)
+	GetFansByUIds(db *gorm.DB, userID uint64, followerUID []uint64) (map[uint64]*model.UserFansModel, error)
+	CountFollows(db *gorm.DB) (int, error)
+	CountFollowsByUIds(db *gorm.DB, userIDs []uint64) (map[uint64]int, error)
+	CountFansByUIds(db *gorm.DB, userIDs []uint64) (map[uint64]int, error)
+	ScanFollows(db *gorm.DB, lastID uint64, limit int) ([]*model.UserFollowModel, error)
 }
 
 // userFollowRepo 用户仓库
@@ -30,14 +36,23 @@ func NewUserFollowRepo() FollowRepo {
 	return &userFollowRepo{}
 }
 
+// CreateUserFollow 新增关注，已经取消的关注恢复为正常状态
+// mysql 生成 on duplicate key update，sqlite 生成 on conflict do update
 func (repo *userFollowRepo) CreateUserFollow(db *gorm.DB, userID, followedUID uint64) error {
-	return db.Exec("insert into user_follow set user_id=?, followed_uid=?, status=1, created_at=? on duplicate key update status=1, updated_at=?",
-		userID, followedUID, time.Now(), time.Now()).Error
+	now := time.Now()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "followed_uid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"status": 1, "updated_at": now}),
+	}).Create(&model.UserFollowModel{UserID: userID, FollowedUID: followedUID, Status: 1, CreatedAt: now}).Error
 }
 
+// CreateUserFans 新增粉丝，已经取消的关注恢复为正常状态
 func (repo *userFollowRepo) CreateUserFans(db *gorm.DB, userID, followerUID uint64) error {
-	return db.Exec("insert into user_fans set user_id=?, follower_uid=?, status=1, created_at=? on duplicate key update status=1, updated_at=?",
-		userID, followerUID, time.Now(), time.Now()).Error
+	now := time.Now()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "follower_uid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"status": 1, "updated_at": now}),
+	}).Create(&model.UserFansModel{UserID: userID, FollowerUID: followerUID, Status: 1, CreatedAt: now}).Error
 }
 
 func (repo *userFollowRepo) UpdateUserFollowStatus(db *gorm.DB, userID, followedUID uint64, status int) error {
@@ -52,11 +67,13 @@ func (repo *userFollowRepo) UpdateUserFansStatus(db *gorm.DB, userID, followerUI
 		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
 }
 
-func (repo *userFollowRepo) GetFollowingUserList(userID, lastID uint64, limit int) ([]*model.UserFollowModel, error) {
+// GetFollowingUserList 获取关注列表，lastID 为上一页最后一条记录的id，过滤已注销的用户
+func (repo *userFollowRepo) GetFollowingUserList(db *gorm.DB, userID, lastID uint64, limit int) ([]*model.UserFollowModel, error) {
 	userFollowList := make([]*model.UserFollowModel, 0)
-	db := model.GetDB()
-	result := db.Where("user_id=? AND id<=? and status=1", userID, lastID).
-		Order("id desc").
+	result := db.Table("user_follow f").Select("f.*").
+		Where("f.user_id=? AND f.id<? and f.status=1", userID, lastID).
+		Where(followingVisible).
+		Order("f.id desc").
 		Limit(limit).Find(&userFollowList)
 
 	if err := result.Error; err != nil {
@@ -67,27 +84,64 @@ func (repo *userFollowRepo) GetFollowingUserList(userID, lastID uint64, limit in
 	return userFollowList, nil
 }
 
-func (repo *userFollowRepo) GetFollowerUserList(userID, lastID uint64, limit int) ([]*model.UserFansModel, error) {
-	userFollowerList := make([]*model.UserFansModel, 0)
-	db := model.GetDB()
-	result := db.Where("user_id=? AND id<=? and status=1", userID, lastID).
-		Order("id desc").
-		Limit(limit).Find(&userFollowerList)
+// followingVisible 过滤已注销的关注用户
+const followingVisible = "NOT EXISTS (SELECT 1 FROM user_base b WHERE b.id = f.followed_uid AND b.deleted_at IS NOT NULL)"
+
+// followerVisible 过滤已注销和封禁中的粉丝
+const followerVisible = "NOT EXISTS (SELECT 1 FROM user_base b WHERE b.id = f.follower_uid AND b.deleted_at IS NOT NULL) " +
+	"AND NOT EXISTS (SELECT 1 FROM user_ban n WHERE n.user_id = f.follower_uid AND n.status = ? AND (n.expired_at IS NULL OR n.expired_at > ?))"
+
+// followerOrders 粉丝列表排序方式需要关联的表和排序值，排序值相同时按记录id倒序
+var followerOrders = map[string]struct{ join, key string }{
+	model.FollowerOrderMutual: {
+		join: "LEFT JOIN user_follow m ON m.user_id = f.user_id AND m.followed_uid = f.follower_uid AND m.status = 1",
+		key:  "CASE WHEN m.id IS NULL THEN 0 ELSE 1 END",
+	},
+	model.FollowerOrderPopular: {
+		join: "LEFT JOIN user_stat s ON s.user_id = f.follower_uid",
+		key:  "COALESCE(s.follower_count, 0)",
+	},
+}
 
-	if err := result.Error; err != nil {
-		log.Warnf("[userFollow_service] get user follow list err, %v", err)
-		return nil, err
+// GetFollowerUserList 获取粉丝列表，lastID 为上一页最后一条记录的id，过滤已注销和封禁中的粉丝
+// 互关优先、粉丝数优先时按 lastID 对应记录当前的排序值翻页，翻页期间排序值变化的记录可能重复或遗漏
+func (repo *userFollowRepo) GetFollowerUserList(db *gorm.DB, userID, lastID uint64, limit int, order string) ([]*model.UserFansModel, error) {
+	query := db.Table("user_fans f").Select("f.*").
+		Where("f.user_id = ? AND f.status = 1", userID).
+		Where(followerVisible, model.BanStatusActive, time.Now())
+
+	o, ok := followerOrders[order]
+	if !ok {
+		query = query.Where("f.id < ?", lastID).Order("f.id desc")
+	} else {
+		query = query.Joins(o.join).Order(o.key + " desc, f.id desc")
+		// 第一页的 lastID 为最大值，找不到记录
+		var key int64
+		err := db.Table("user_fans f").Joins(o.join).Where("f.id = ? AND f.user_id = ?", lastID, userID).
+			Select(o.key).Row().Scan(&key)
+		switch err {
+		case nil:
+			query = query.Where("("+o.key+" < ?) OR ("+o.key+" = ? AND f.id < ?)", key, key, lastID)
+		case sql.ErrNoRows:
+		default:
+			return nil, errors.Wrap(err, "[user_follow_repo] get follower cursor err")
+		}
+	}
+
+	userFollowerList := make([]*model.UserFansModel, 0)
+	if err := query.Limit(limit).Find(&userFollowerList).Error; err != nil {
+		return nil, errors.Wrap(err, "[user_follow_repo] get user follower list err")
 	}
 
 	return userFollowerList, nil
 }
 
 // 获取自己对关注列表的关注信息
-func (repo *userFollowRepo) GetFollowByUIds(userID uint64, followingUID []uint64) (map[uint64]*model.UserFollowModel, error) {
+func (repo *userFollowRepo) GetFollowByUIds(db *gorm.DB, userID uint64, followingUID []uint64) (map[uint64]*model.UserFollowModel, error) {
 	userFollowModel := make([]*model.UserFollowModel, 0)
 	retMap := make(map[uint64]*model.UserFollowModel)
 
-	err := model.GetDB().
+	err := db.
 		Where("user_id=? AND followed_uid in (?) ", userID, followingUID).
 		Find(&userFollowModel).Error
 
@@ -103,11 +157,11 @@ func (repo *userFollowRepo) GetFollowByUIds(userID uint64, followingUID []uint64
 }
 
 // 获取自己对关注列表的被关注信息
-func (repo *userFollowRepo) GetFansByUIds(userID uint64, followerUID []uint64) (map[uint64]*model.UserFansModel, error) {
+func (repo *userFollowRepo) GetFansByUIds(db *gorm.DB, userID uint64, followerUID []uint64) (map[uint64]*model.UserFansModel, error) {
 	userFansModel := make([]*model.UserFansModel, 0)
 	retMap := make(map[uint64]*model.UserFansModel)
 
-	err := model.GetDB().
+	err := db.
 		Where("user_id=? AND follower_uid in (?) ", userID, followerUID).
 		Find(&userFansModel).Error
 
@@ -116,8 +170,65 @@ func (repo *userFollowRepo) GetFansByUIds(userID uint64, followerUID []uint64) (
 	}
 
 	for _, v := range userFansModel {
-		retMap[v.UserID] = v
+		retMap[v.FollowerUID] = v
 	}
 
 	return retMap, nil
 }
+
+// CountFollows 获取有效的关注关系数
+func (repo *userFollowRepo) CountFollows(db *gorm.DB) (int, error) {
+	var count int64
+	err := db.Model(&model.UserFollowModel{}).Where("status = ?", 1).Count(&count).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_follow_repo] count follows err")
+	}
+
+	return int(count), nil
+}
+
+// CountFollowsByUIds 按关注表统计每个用户的关注数，没有关注的用户不在返回值中
+func (repo *userFollowRepo) CountFollowsByUIds(db *gorm.DB, userIDs []uint64) (map[uint64]int, error) {
+	return countByUIds(db, &model.UserFollowModel{}, userIDs)
+}
+
+// CountFansByUIds 按粉丝表统计每个用户的粉丝数，没有粉丝的用户不在返回值中
+func (repo *userFollowRepo) CountFansByUIds(db *gorm.DB, userIDs []uint64) (map[uint64]int, error) {
+	return countByUIds(db, &model.UserFansModel{}, userIDs)
+}
+
+// countByUIds 按 user_id 分组统计有效的记录数
+func countByUIds(db *gorm.DB, table interface{}, userIDs []uint64) (map[uint64]int, error) {
+	ret := make(map[uint64]int, len(userIDs))
+	if len(userIDs) == 0 {
+		return ret, nil
+	}
+	rows := make([]struct {
+		UserID uint64
+		Count  int
+	}, 0)
+	err := db.Model(table).Select("user_id, count(*) as count").
+		Where("user_id in (?) AND status = ?", userIDs, 1).
+		Group("user_id").Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_follow_repo] count by uids err")
+	}
+	for _, r := range rows {
+		ret[r.UserID] = r.Count
+	}
+	return ret, nil
+}
+
+// ScanFollows 按id正序分批获取有效的关注关系，用于导出关注关系图
+func (repo *userFollowRepo) ScanFollows(db *gorm.DB, lastID uint64, limit int) ([]*model.UserFollowModel, error) {
+	follows := make([]*model.UserFollowModel, 0)
+	err := db.Select("id, user_id, followed_uid").
+		Where("id > ? AND status = ?", lastID, 1).
+		Order("id asc").
+		Limit(limit).Find(&follows).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_follow_repo] scan follows err")
+	}
+
+	return follows, nil
+}
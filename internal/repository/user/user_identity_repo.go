@@ -0,0 +1,161 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// IdentityRepo 定义用户登录身份仓库接口
+type IdentityRepo interface {
+	GetIdentity(db *gorm.DB, provider, identifier string) (*model.UserIdentityModel, error)
+	GetUserIdentities(db *gorm.DB, userID uint64) ([]*model.UserIdentityModel, error)
+	SaveUserIdentity(db *gorm.DB, userID uint64, provider, identifier string, verified bool) error
+	DeleteIdentity(db *gorm.DB, id uint64) error
+
+	// 软删除和回收
+	IsIdentifierTaken(db *gorm.DB, provider, identifier string) (bool, error)
+	SoftDeleteUserIdentities(db *gorm.DB, userID uint64) error
+	RestoreUserIdentities(db *gorm.DB, userID uint64) (int64, error)
+	ReclaimDeletedIdentities(db *gorm.DB, before time.Time, limit int) (int, error)
+}
+
+// userIdentityRepo 用户登录身份仓库
+type userIdentityRepo struct{}
+
+// NewUserIdentityRepo 实例化用户登录身份仓库
+func NewUserIdentityRepo() IdentityRepo {
+	return &userIdentityRepo{}
+}
+
+// GetIdentity 获取登录身份，不存在或已软删除时返回空结构体
+func (repo *userIdentityRepo) GetIdentity(db *gorm.DB, provider, identifier string) (*model.UserIdentityModel, error) {
+	identity := model.UserIdentityModel{}
+	err := db.Where("provider = ? and identifier = ?", provider, identifier).First(&identity).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_identity_repo] get identity err")
+	}
+
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户绑定的所有登录身份
+func (repo *userIdentityRepo) GetUserIdentities(db *gorm.DB, userID uint64) ([]*model.UserIdentityModel, error) {
+	identities := make([]*model.UserIdentityModel, 0)
+	err := db.Where("user_id = ?", userID).Order("id asc").Find(&identities).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_identity_repo] get user identities err")
+	}
+
+	return identities, nil
+}
+
+// SaveUserIdentity 保存用户某种登录方式的身份，已存在时更新标识
+// 该登录方式已软删除时复用原来的记录，避免和 uniq_uid_provider 冲突
+func (repo *userIdentityRepo) SaveUserIdentity(db *gorm.DB, userID uint64, provider, identifier string, verified bool) error {
+	verifiedVal := 0
+	if verified {
+		verifiedVal = 1
+	}
+
+	identity := model.UserIdentityModel{}
+	err := db.Unscoped().Where("user_id = ? and provider = ?", userID, provider).First(&identity).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return errors.Wrap(err, "[user_identity_repo] get user identity err")
+	}
+
+	now := time.Now()
+	if identity.ID == 0 {
+		err = db.Create(&model.UserIdentityModel{
+			UserID:     userID,
+			Provider:   provider,
+			Identifier: identifier,
+			Verified:   verifiedVal,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "[user_identity_repo] create user identity err")
+		}
+		return nil
+	}
+
+	err = db.Unscoped().Model(&identity).Updates(map[string]interface{}{
+		"identifier": identifier,
+		"verified":   verifiedVal,
+		"updated_at": now,
+		"deleted_at": nil,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_identity_repo] update user identity err")
+	}
+	return nil
+}
+
+// DeleteIdentity 删除登录身份，解绑后标识立即释放，可以被其他帐号绑定
+func (repo *userIdentityRepo) DeleteIdentity(db *gorm.DB, id uint64) error {
+	err := db.Unscoped().Where("id = ?", id).Delete(&model.UserIdentityModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_identity_repo] delete identity err")
+	}
+
+	return nil
+}
+
+// IsIdentifierTaken 标识是否已被使用，软删除但还没有回收的身份仍然占用标识
+func (repo *userIdentityRepo) IsIdentifierTaken(db *gorm.DB, provider, identifier string) (bool, error) {
+	var count int64
+	err := db.Unscoped().Model(&model.UserIdentityModel{}).
+		Where("provider = ? and identifier = ?", provider, identifier).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "[user_identity_repo] count identity err")
+	}
+
+	return count > 0, nil
+}
+
+// SoftDeleteUserIdentities 软删除用户的所有登录身份，注销帐号时调用
+// 宽限期内标识仍然被占用，可以通过 RestoreUserIdentities 恢复
+func (repo *userIdentityRepo) SoftDeleteUserIdentities(db *gorm.DB, userID uint64) error {
+	err := db.Where("user_id = ?", userID).Delete(&model.UserIdentityModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_identity_repo] soft delete user identities err")
+	}
+
+	return nil
+}
+
+// RestoreUserIdentities 恢复用户软删除的登录身份，返回恢复的个数，已回收的身份不能恢复
+func (repo *userIdentityRepo) RestoreUserIdentities(db *gorm.DB, userID uint64) (int64, error) {
+	res := db.Unscoped().Model(&model.UserIdentityModel{}).
+		Where("user_id = ? and deleted_at is not null and identifier not like ?", userID, tombstonePrefix+"%").
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()})
+	if res.Error != nil {
+		return 0, errors.Wrap(res.Error, "[user_identity_repo] restore user identities err")
+	}
+
+	return res.RowsAffected, nil
+}
+
+// ReclaimDeletedIdentities 回收软删除时间早于 before 的身份，标识改为墓碑值，返回回收的个数
+func (repo *userIdentityRepo) ReclaimDeletedIdentities(db *gorm.DB, before time.Time, limit int) (int, error) {
+	identities := make([]*model.UserIdentityModel, 0)
+	err := db.Unscoped().Where("deleted_at < ? and identifier not like ?", before, tombstonePrefix+"%").
+		Order("id asc").Limit(limit).Find(&identities).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_identity_repo] get deleted identities err")
+	}
+
+	for i, v := range identities {
+		err := db.Unscoped().Model(&model.UserIdentityModel{}).Where("id = ?", v.ID).
+			UpdateColumn("identifier", tombstone(v.Identifier, v.ID)).Error
+		if err != nil {
+			return i, errors.Wrapf(err, "[user_identity_repo] reclaim identity err, id: %d", v.ID)
+		}
+	}
+
+	return len(identities), nil
+}
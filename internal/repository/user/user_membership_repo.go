@@ -0,0 +1,66 @@
+package user
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// MembershipRepo 定义会员仓库接口
+type MembershipRepo interface {
+	CreateMembership(db *gorm.DB, m *model.UserMembershipModel) (id uint64, err error)
+	GetMembershipByOrderNo(db *gorm.DB, orderNo string) (*model.UserMembershipModel, error)
+	GetLatestMembership(db *gorm.DB, userID uint64) (*model.UserMembershipModel, error)
+	DeleteMembershipByOrderNo(db *gorm.DB, orderNo string) error
+}
+
+// userMembershipRepo 会员仓库
+type userMembershipRepo struct{}
+
+// NewUserMembershipRepo 实例化会员仓库
+func NewUserMembershipRepo() MembershipRepo {
+	return &userMembershipRepo{}
+}
+
+// CreateMembership 新增会员记录
+func (repo *userMembershipRepo) CreateMembership(db *gorm.DB, m *model.UserMembershipModel) (id uint64, err error) {
+	err = db.Create(m).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_membership_repo] create membership err")
+	}
+
+	return m.ID, nil
+}
+
+// GetMembershipByOrderNo 按订单号获取会员记录，不存在时返回空结构体
+func (repo *userMembershipRepo) GetMembershipByOrderNo(db *gorm.DB, orderNo string) (*model.UserMembershipModel, error) {
+	m := model.UserMembershipModel{}
+	err := db.Where("order_no = ?", orderNo).First(&m).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_membership_repo] get membership by order no err")
+	}
+
+	return &m, nil
+}
+
+// GetLatestMembership 获取用户到期时间最晚的会员记录，不存在时返回空结构体
+func (repo *userMembershipRepo) GetLatestMembership(db *gorm.DB, userID uint64) (*model.UserMembershipModel, error) {
+	m := model.UserMembershipModel{}
+	err := db.Where("user_id = ?", userID).Order("expired_at desc").First(&m).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_membership_repo] get latest membership err")
+	}
+
+	return &m, nil
+}
+
+// DeleteMembershipByOrderNo 删除会员记录，用于开通失败时回滚
+func (repo *userMembershipRepo) DeleteMembershipByOrderNo(db *gorm.DB, orderNo string) error {
+	err := db.Where("order_no = ?", orderNo).Delete(&model.UserMembershipModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_membership_repo] delete membership err")
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// OAuthRepo 定义第三方帐号仓库接口
+type OAuthRepo interface {
+	GetOAuth(db *gorm.DB, provider, openID string) (*model.UserOAuthModel, error)
+	GetOAuthByUnionID(db *gorm.DB, provider, unionID string) (*model.UserOAuthModel, error)
+	CreateOAuth(db *gorm.DB, oauth *model.UserOAuthModel) error
+	UpdateOAuth(db *gorm.DB, id uint64, oauthMap map[string]interface{}) error
+}
+
+// userOAuthRepo 第三方帐号仓库
+type userOAuthRepo struct{}
+
+// NewUserOAuthRepo 实例化第三方帐号仓库
+func NewUserOAuthRepo() OAuthRepo {
+	return &userOAuthRepo{}
+}
+
+// GetOAuth 通过第三方帐号的 open_id 获取绑定记录，不存在时返回空结构体
+func (repo *userOAuthRepo) GetOAuth(db *gorm.DB, provider, openID string) (*model.UserOAuthModel, error) {
+	oauth := model.UserOAuthModel{}
+	err := db.Where("provider = ? and open_id = ?", provider, openID).First(&oauth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_oauth_repo] get oauth err")
+	}
+	return &oauth, nil
+}
+
+// GetOAuthByUnionID 通过 union_id 获取最早的绑定记录，不存在时返回空结构体
+func (repo *userOAuthRepo) GetOAuthByUnionID(db *gorm.DB, provider, unionID string) (*model.UserOAuthModel, error) {
+	oauth := model.UserOAuthModel{}
+	err := db.Where("provider = ? and union_id = ?", provider, unionID).Order("id asc").First(&oauth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_oauth_repo] get oauth by union id err")
+	}
+	return &oauth, nil
+}
+
+// CreateOAuth 绑定第三方帐号
+func (repo *userOAuthRepo) CreateOAuth(db *gorm.DB, oauth *model.UserOAuthModel) error {
+	now := time.Now()
+	oauth.CreatedAt, oauth.UpdatedAt = now, now
+	if err := db.Create(oauth).Error; err != nil {
+		return errors.Wrap(err, "[user_oauth_repo] create oauth err")
+	}
+	return nil
+}
+
+// UpdateOAuth 更新第三方帐号的资料和 token
+func (repo *userOAuthRepo) UpdateOAuth(db *gorm.DB, id uint64, oauthMap map[string]interface{}) error {
+	oauthMap["updated_at"] = time.Now()
+	err := db.Model(&model.UserOAuthModel{}).Where("id = ?", id).Updates(oauthMap).Error
+	if err != nil {
+		return errors.Wrap(err, "[user_oauth_repo] update oauth err")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// RoleRepo 定义用户角色仓库接口
+type RoleRepo interface {
+	GetUserRoles(db *gorm.DB, userID uint64) ([]*model.UserRoleModel, error)
+	AddUserRole(db *gorm.DB, userID uint64, role string, operatorID uint64) (bool, error)
+	DeleteUserRole(db *gorm.DB, userID uint64, role string) (bool, error)
+}
+
+// userRoleRepo 用户角色仓库
+type userRoleRepo struct{}
+
+// NewUserRoleRepo 实例化用户角色仓库
+func NewUserRoleRepo() RoleRepo {
+	return &userRoleRepo{}
+}
+
+// GetUserRoles 获取用户的角色，按授予时间排序
+func (repo *userRoleRepo) GetUserRoles(db *gorm.DB, userID uint64) ([]*model.UserRoleModel, error) {
+	roles := make([]*model.UserRoleModel, 0)
+	err := db.Where("user_id = ?", userID).Order("id asc").Find(&roles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[user_role_repo] get user roles err")
+	}
+
+	return roles, nil
+}
+
+// AddUserRole 授予角色，已拥有该角色时返回 false
+// 依赖 uniq_user_role 唯一索引忽略重复授予，mysql 和 sqlite(演示模式)都支持
+func (repo *userRoleRepo) AddUserRole(db *gorm.DB, userID uint64, role string, operatorID uint64) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserRoleModel{
+		UserID:     userID,
+		Role:       role,
+		OperatorID: operatorID,
+		CreatedAt:  time.Now(),
+	})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[user_role_repo] add user role err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteUserRole 收回角色，没有该角色时返回 false
+func (repo *userRoleRepo) DeleteUserRole(db *gorm.DB, userID uint64, role string) (bool, error) {
+	result := db.Where("user_id = ? and role = ?", userID, role).Delete(&model.UserRoleModel{})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "[user_role_repo] delete user role err")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/shadow"
+)
+
+// ShadowProfile 用户资料的影子读名称，对应配置 shadow.user_profile
+const ShadowProfile = "user_profile"
+
+// shadowUserRepo 用户资料的影子读，control 直接查库，candidate 为读穿缓存
+// 未开启时保持读穿缓存，开启后按 serve 返回其中一条路径的结果，按比例对比两条路径
+type shadowUserRepo struct {
+	*cachedUserRepo
+}
+
+// GetUserByID 获取用户
+func (repo *shadowUserRepo) GetUserByID(db *gorm.DB, id uint64) (*model.UserBaseModel, error) {
+	if !shadow.Get(ShadowProfile).Enable {
+		return repo.cachedUserRepo.GetUserByID(db, id)
+	}
+	return shadow.Read(ShadowProfile,
+		func() (*model.UserBaseModel, error) { return repo.userRepo.GetUserByID(db, id) },
+		func() (*model.UserBaseModel, error) { return repo.cachedUserRepo.GetUserByID(db, id) },
+		diffUser,
+	)
+}
+
+// GetUsersByIds 批量获取用户
+func (repo *shadowUserRepo) GetUsersByIds(db *gorm.DB, userIDs []uint64) ([]*model.UserBaseModel, error) {
+	if !shadow.Get(ShadowProfile).Enable {
+		return repo.cachedUserRepo.GetUsersByIds(db, userIDs)
+	}
+	return shadow.Read(ShadowProfile,
+		func() ([]*model.UserBaseModel, error) { return repo.userRepo.GetUsersByIds(db, userIDs) },
+		func() ([]*model.UserBaseModel, error) { return repo.cachedUserRepo.GetUsersByIds(db, userIDs) },
+		diffUsers,
+	)
+}
+
+// diffUser 对比接口返回的资料字段，创建、更新时间不在缓存中
+func diffUser(control, candidate *model.UserBaseModel) []string {
+	fields := make([]string, 0)
+	if control.ID != candidate.ID {
+		fields = append(fields, "id")
+	}
+	if control.Username != candidate.Username {
+		fields = append(fields, "username")
+	}
+	if control.Password != candidate.Password {
+		fields = append(fields, "password")
+	}
+	if control.Phone != candidate.Phone {
+		fields = append(fields, "phone")
+	}
+	if control.Email != candidate.Email {
+		fields = append(fields, "email")
+	}
+	if control.Avatar != candidate.Avatar {
+		fields = append(fields, "avatar")
+	}
+	if control.Bio != candidate.Bio {
+		fields = append(fields, "bio")
+	}
+	if control.Sex != candidate.Sex {
+		fields = append(fields, "sex")
+	}
+	if control.Plan != candidate.Plan {
+		fields = append(fields, "plan")
+	}
+	if control.Region != candidate.Region {
+		fields = append(fields, "region")
+	}
+	return fields
+}
+
+// diffUsers 按用户id对比，字段名带上用户id，如 12.username
+func diffUsers(control, candidate []*model.UserBaseModel) []string {
+	candidates := make(map[uint64]*model.UserBaseModel, len(candidate))
+	for _, u := range candidate {
+		candidates[u.ID] = u
+	}
+
+	fields := make([]string, 0)
+	seen := make(map[uint64]bool, len(control))
+	for _, u := range control {
+		seen[u.ID] = true
+		c, ok := candidates[u.ID]
+		if !ok {
+			fields = append(fields, fmt.Sprintf("%d.missing", u.ID))
+			continue
+		}
+		for _, f := range diffUser(u, c) {
+			fields = append(fields, fmt.Sprintf("%d.%s", u.ID, f))
+		}
+	}
+	for _, u := range candidate {
+		if !seen[u.ID] {
+			fields = append(fields, fmt.Sprintf("%d.extra", u.ID))
+		}
+	}
+	return fields
+}
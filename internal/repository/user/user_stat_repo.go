@@ -1,57 +1,181 @@
 package user
 
 import (
+	"context"
+	"sort"
 	"time"
 
-	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/1024casts/snake/internal/cache/user"
 	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/counter"
+	"github.com/1024casts/snake/pkg/lock"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// StatFieldFollowCount 关注数
+	StatFieldFollowCount = "follow_count"
+	// StatFieldFollowerCount 粉丝数
+	StatFieldFollowerCount = "follower_count"
+	// StatFieldViewCount 主页浏览数
+	StatFieldViewCount = "view_count"
+
+	// flushBatchSize 每个事务写入的用户数
+	flushBatchSize = 100
+
+	// statLockKey FlushStat 和 ReconcileFollowCounts 共用的锁，修正时不能有正在写入的增量
+	statLockKey = "user_stat:flush"
+	// statLockTTL 锁的过期时间，持有期间自动续期
+	statLockTTL = 30 * time.Second
 )
 
 // StatRepo 定义用户仓库接口
 type StatRepo interface {
-	IncrFollowCount(db *gorm.DB, userID uint64, step int) error
-	IncrFollowerCount(db *gorm.DB, userID uint64, step int) error
+	IncrFollowCount(userID uint64, step int) error
+	IncrFollowerCount(userID uint64, step int) error
+	IncrViewCount(userID uint64, step int) error
+	FlushStat(db *gorm.DB) (int, error)
 	GetUserStatByID(db *gorm.DB, userID uint64) (*model.UserStatModel, error)
 	GetUserStatByIDs(db *gorm.DB, userID []uint64) (map[uint64]*model.UserStatModel, error)
+	GetFollowCounts(db *gorm.DB, userIDs []uint64) (map[uint64]*model.FollowCounts, error)
+	ReconcileFollowCounts(db *gorm.DB, actual []*model.FollowCounts) (int, error)
 }
 
 // userRepo 用户仓库
 type userStatRepo struct {
-	userCache *user.Cache
+	userCache  *user.Cache
+	statBuffer *counter.Buffer
 }
 
 // NewUserStatRepo 实例化用户仓库
 func NewUserStatRepo() StatRepo {
 	return &userStatRepo{
-		userCache: user.NewUserCache(),
+		userCache:  user.NewUserCache(),
+		statBuffer: counter.NewBuffer(nil, "user_stat"),
 	}
 }
 
-// IncrFollowCount 增加关注数
-func (repo *userStatRepo) IncrFollowCount(db *gorm.DB, userID uint64, step int) error {
-	err := db.Exec("insert into user_stat set user_id=?, follow_count=1, created_at=? on duplicate key update "+
-		"follow_count=follow_count+?, updated_at=?",
-		userID, time.Now(), step, time.Now()).Error
+// IncrFollowCount 增加关注数，先写入缓冲，由 FlushStat 批量写入数据库
+func (repo *userStatRepo) IncrFollowCount(userID uint64, step int) error {
+	err := repo.statBuffer.Incr(userID, StatFieldFollowCount, int64(step))
 	if err != nil {
 		return errors.Wrap(err, "[user_stat_repo] incr user follow count")
 	}
 	return nil
 }
 
-// IncrFollowerCount 增加粉丝数
-func (repo *userStatRepo) IncrFollowerCount(db *gorm.DB, userID uint64, step int) error {
-	err := db.Exec("insert into user_stat set user_id=?, follower_count=1, created_at=? on duplicate key update "+
-		"follower_count=follower_count+?, updated_at=?",
-		userID, time.Now(), step, time.Now()).Error
+// IncrFollowerCount 增加粉丝数，先写入缓冲，由 FlushStat 批量写入数据库
+func (repo *userStatRepo) IncrFollowerCount(userID uint64, step int) error {
+	err := repo.statBuffer.Incr(userID, StatFieldFollowerCount, int64(step))
 	if err != nil {
 		return errors.Wrap(err, "[user_stat_repo] incr user follower count")
 	}
 	return nil
 }
 
+// IncrViewCount 增加主页浏览数，先写入缓冲，由 FlushStat 批量写入数据库
+func (repo *userStatRepo) IncrViewCount(userID uint64, step int) error {
+	err := repo.statBuffer.Incr(userID, StatFieldViewCount, int64(step))
+	if err != nil {
+		return errors.Wrap(err, "[user_stat_repo] incr user view count")
+	}
+	return nil
+}
+
+// FlushStat 将缓冲中的增量批量写入数据库，返回写入的用户数
+// 每批一个事务，某一批失败时只还原这一批和之后的增量；其他实例正在写入或修正时跳过
+func (repo *userStatRepo) FlushStat(db *gorm.DB) (int, error) {
+	l := lock.New(redis.RedisClient, statLockKey, lock.WithTTL(statLockTTL), lock.WithWatchdog())
+	ok, err := l.TryLock()
+	if err != nil || !ok {
+		return 0, err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("[user_stat_repo] release stat lock err: %v", err)
+		}
+	}()
+
+	return repo.statBuffer.Flush(func(deltas counter.Deltas) error {
+		userIDs := make([]uint64, 0, len(deltas))
+		for userID := range deltas {
+			userIDs = append(userIDs, userID)
+		}
+		// 按用户id排序，避免并发写入时死锁
+		sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+		for i := 0; i < len(userIDs); i += flushBatchSize {
+			end := i + flushBatchSize
+			if end > len(userIDs) {
+				end = len(userIDs)
+			}
+			if err := repo.flushStatBatch(db, userIDs[i:end], deltas); err != nil {
+				return err
+			}
+			// 已经提交的批次不再还原
+			for _, userID := range userIDs[i:end] {
+				delete(deltas, userID)
+			}
+			// 增量已写入数据库，cache 中的值需要重新加载
+			if err := repo.userCache.MultiDelFollowCountsCache(userIDs[i:end]); err != nil {
+				log.Warnf("[user_stat_repo] del follow counts cache err: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// flushStatBatch 在一个事务中写入一批用户的增量
+func (repo *userStatRepo) flushStatBatch(db *gorm.DB, userIDs []uint64, deltas counter.Deltas) error {
+	tx := db.Begin()
+	now := time.Now()
+	for _, userID := range userIDs {
+		d := deltas[userID]
+		follow, follower, view := d[StatFieldFollowCount], d[StatFieldFollowerCount], d[StatFieldViewCount]
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"follow_count":   incrNonNegative("follow_count", follow),
+				"follower_count": incrNonNegative("follower_count", follower),
+				"view_count":     incrNonNegative("view_count", view),
+				"updated_at":     now,
+			}),
+		}).Create(&model.UserStatModel{
+			UserID:        userID,
+			FollowCount:   int(nonNegative(follow)),
+			FollowerCount: int(nonNegative(follower)),
+			ViewCount:     int(nonNegative(view)),
+			CreatedAt:     now,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "[user_stat_repo] flush user stat err, uid: %d", userID)
+		}
+	}
+	return tx.Commit().Error
+}
+
+// incrNonNegative 累加增量，结果小于0时为0，和新增时的 nonNegative 一致
+// 计数列是 unsigned，直接加负数在 mysql 中会超出范围报错，整批回滚后一直重试，所以只在够减时才做减法
+func incrNonNegative(column string, delta int64) clause.Expr {
+	if delta >= 0 {
+		return gorm.Expr(column+" + ?", delta)
+	}
+	return gorm.Expr("CASE WHEN "+column+" > ? THEN "+column+" - ? ELSE 0 END", -delta, -delta)
+}
+
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
 // GetUserStatByID 获取用户统计数据
 func (repo *userStatRepo) GetUserStatByID(db *gorm.DB, userID uint64) (*model.UserStatModel, error) {
 	userStat := model.UserStatModel{}
@@ -79,3 +203,134 @@ func (repo *userStatRepo) GetUserStatByIDs(db *gorm.DB, userID []uint64) (map[ui
 
 	return retMap, nil
 }
+
+// GetFollowCounts 批量获取关注数和粉丝数，不用查询用户资料和关注关系
+// 数据库中的值经过 cache，再加上计数缓冲中还未写入的增量；flush 写入数据库到删除 cache 之间会短暂少算这部分增量
+func (repo *userStatRepo) GetFollowCounts(db *gorm.DB, userIDs []uint64) (map[uint64]*model.FollowCounts, error) {
+	counts, err := repo.userCache.MultiGetFollowCountsCache(userIDs)
+	if err != nil {
+		log.Warnf("[user_stat_repo] multi get follow counts cache err: %v", err)
+		counts = make(map[uint64]*model.FollowCounts)
+	}
+
+	missed := make([]uint64, 0)
+	for _, id := range userIDs {
+		if _, ok := counts[id]; !ok {
+			missed = append(missed, id)
+		}
+	}
+	if len(missed) > 0 {
+		stats, err := repo.GetUserStatByIDs(db, missed)
+		if err != nil {
+			return nil, err
+		}
+		loaded := make([]*model.FollowCounts, 0, len(missed))
+		for _, id := range missed {
+			c := &model.FollowCounts{UserID: id}
+			if s, ok := stats[id]; ok {
+				c.FollowCount, c.FollowerCount = s.FollowCount, s.FollowerCount
+			}
+			counts[id] = c
+			loaded = append(loaded, c)
+		}
+		if err := repo.userCache.MultiSetFollowCountsCache(loaded); err != nil {
+			log.Warnf("[user_stat_repo] multi set follow counts cache err: %v", err)
+		}
+	}
+
+	pending, err := repo.statBuffer.MultiPending(userIDs, StatFieldFollowCount, StatFieldFollowerCount)
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_stat_repo] get pending follow counts err")
+	}
+	ret := make(map[uint64]*model.FollowCounts, len(counts))
+	for id, c := range counts {
+		d := pending[id]
+		ret[id] = &model.FollowCounts{
+			UserID:        id,
+			FollowCount:   int(nonNegative(int64(c.FollowCount) + d[StatFieldFollowCount])),
+			FollowerCount: int(nonNegative(int64(c.FollowerCount) + d[StatFieldFollowerCount])),
+		}
+	}
+	return ret, nil
+}
+
+// ReconcileFollowCounts 按关注关系统计出的实际值修正关注数和粉丝数，返回修正的用户数
+// 计数缓冲中还未写入的增量会在之后 flush，数据库中写入实际值减去这部分增量；
+// 修正期间持有和 FlushStat 共用的锁，没有正在写入的增量，之前写入中断留下的增量先合并回缓冲；
+// 统计和修正之间发生的关注会少算或多算一次，下次修正时恢复
+func (repo *userStatRepo) ReconcileFollowCounts(db *gorm.DB, actual []*model.FollowCounts) (int, error) {
+	if len(actual) == 0 {
+		return 0, nil
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, statLockTTL)
+	defer cancel()
+	l := lock.New(redis.RedisClient, statLockKey, lock.WithTTL(statLockTTL), lock.WithWatchdog())
+	if err := l.Acquire(ctx); err != nil {
+		return 0, errors.Wrap(err, "[user_stat_repo] acquire stat lock err")
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("[user_stat_repo] release stat lock err: %v", err)
+		}
+	}()
+	if _, err := repo.statBuffer.Recover(0); err != nil {
+		return 0, errors.Wrap(err, "[user_stat_repo] recover flushing stat err")
+	}
+
+	userIDs := make([]uint64, 0, len(actual))
+	for _, c := range actual {
+		userIDs = append(userIDs, c.UserID)
+	}
+	stats, err := repo.GetUserStatByIDs(db, userIDs)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := repo.statBuffer.MultiPending(userIDs, StatFieldFollowCount, StatFieldFollowerCount)
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_stat_repo] get pending follow counts err")
+	}
+
+	fixed := make([]uint64, 0)
+	now := time.Now()
+	for _, c := range actual {
+		d := pending[c.UserID]
+		follow := nonNegative(int64(c.FollowCount) - d[StatFieldFollowCount])
+		follower := nonNegative(int64(c.FollowerCount) - d[StatFieldFollowerCount])
+		s, ok := stats[c.UserID]
+		if ok && int64(s.FollowCount) == follow && int64(s.FollowerCount) == follower {
+			continue
+		}
+		if !ok && follow == 0 && follower == 0 {
+			continue
+		}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"follow_count":   follow,
+				"follower_count": follower,
+				"updated_at":     now,
+			}),
+		}).Create(&model.UserStatModel{
+			UserID:        c.UserID,
+			FollowCount:   int(follow),
+			FollowerCount: int(follower),
+			CreatedAt:     now,
+		}).Error
+		if err != nil {
+			return len(fixed), errors.Wrapf(err, "[user_stat_repo] reconcile user stat err, uid: %d", c.UserID)
+		}
+		fixed = append(fixed, c.UserID)
+	}
+
+	if len(fixed) > 0 {
+		if err := repo.userCache.MultiDelFollowCountsCache(fixed); err != nil {
+			log.Warnf("[user_stat_repo] del follow counts cache err: %v", err)
+		}
+	}
+	return len(fixed), nil
+}
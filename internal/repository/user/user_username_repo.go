@@ -0,0 +1,43 @@
+package user
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// UsernameHistoryRepo 定义用户名修改记录仓库接口
+type UsernameHistoryRepo interface {
+	AddUsernameHistory(db *gorm.DB, userID uint64, oldUsername string) error
+	GetUserIDByOldUsername(db *gorm.DB, username string) (uint64, error)
+}
+
+// userUsernameHistoryRepo 用户名修改记录仓库
+type userUsernameHistoryRepo struct{}
+
+// NewUserUsernameHistoryRepo 实例化用户名修改记录仓库
+func NewUserUsernameHistoryRepo() UsernameHistoryRepo {
+	return &userUsernameHistoryRepo{}
+}
+
+// AddUsernameHistory 记录修改前的用户名
+func (repo *userUsernameHistoryRepo) AddUsernameHistory(db *gorm.DB, userID uint64, oldUsername string) error {
+	history := model.UserUsernameHistoryModel{UserID: userID, Username: oldUsername, CreatedAt: time.Now()}
+	if err := db.Create(&history).Error; err != nil {
+		return errors.Wrap(err, "[user_username_repo] add username history err")
+	}
+	return nil
+}
+
+// GetUserIDByOldUsername 获取最近一次改掉该用户名的用户id，没有记录时返回0
+func (repo *userUsernameHistoryRepo) GetUserIDByOldUsername(db *gorm.DB, username string) (uint64, error) {
+	history := model.UserUsernameHistoryModel{}
+	err := db.Where("username = ?", username).Order("id desc").First(&history).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, errors.Wrap(err, "[user_username_repo] get username history err")
+	}
+	return history.UserID, nil
+}
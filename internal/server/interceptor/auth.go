@@ -0,0 +1,65 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// healthPrefix 健康检查不需要认证
+const healthPrefix = "/grpc.health.v1.Health/"
+
+type callerKey struct{}
+
+// Auth 校验调用方的 token，调用方在 metadata 中传入 authorization: Bearer <token>
+// tokens 为调用方名称到 token 的映射，没有配置时拒绝所有调用；需要放在 Logging 之前，日志中才有调用方
+func Auth(tokens map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthPrefix) {
+			return handler(ctx, req)
+		}
+
+		caller, ok := authenticate(ctx, tokens)
+		if !ok {
+			log.Warnf("[grpc] %s unauthenticated", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "invalid caller token")
+		}
+		return handler(context.WithValue(ctx, callerKey{}, caller), req)
+	}
+}
+
+// authenticate 返回 token 对应的调用方
+func authenticate(ctx context.Context, tokens map[string]string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+	if token == "" {
+		return "", false
+	}
+
+	for caller, expected := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return caller, true
+		}
+	}
+	return "", false
+}
+
+// Caller 返回通过认证的调用方名称，未认证时返回空
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
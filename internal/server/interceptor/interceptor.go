@@ -0,0 +1,114 @@
+// Package interceptor gRPC 服务的拦截器，和 HTTP 服务的中间件对应
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/util"
+)
+
+// ErrnoCodeKey trailer 中的业务错误码，和 HTTP 接口返回的 code 一致
+const ErrnoCodeKey = "x-errno-code"
+
+// kindCodes 错误分类对应的 status code
+var kindCodes = map[errno.Kind]codes.Code{
+	errno.KindInvalid:     codes.InvalidArgument,
+	errno.KindNotFound:    codes.NotFound,
+	errno.KindConflict:    codes.FailedPrecondition,
+	errno.KindUnavailable: codes.Unavailable,
+	errno.KindInternal:    codes.Internal,
+}
+
+// requestIDKey metadata 中的请求id，和 HTTP 的 X-Request-ID 对应
+const requestIDKey = "x-request-id"
+
+// RequestID 透传调用方的请求id，没有时生成一个，并通过 header 返回给调用方
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(requestIDKey); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if requestID == "" {
+			requestID = util.GenUUID()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+		return handler(log.NewRequestIDContext(ctx, requestID), req)
+	}
+}
+
+// accessLog 调用日志，可以通过 log.module_levels 单独调整级别
+var accessLog = log.Named("grpc")
+
+// Logging 记录每次调用的方法、调用方、耗时和 status code
+func Logging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		if code == codes.OK || code == codes.InvalidArgument || code == codes.NotFound {
+			accessLog.WithContext(ctx).Infow("call",
+				"method", info.FullMethod, "caller", Caller(ctx), "code", code.String(), "cost", time.Since(start))
+		} else {
+			accessLog.WithContext(ctx).Warnw("call",
+				"method", info.FullMethod, "caller", Caller(ctx), "code", code.String(), "cost", time.Since(start), "err", err)
+		}
+		return resp, err
+	}
+}
+
+// Recovery 捕获 panic，返回 codes.Internal，避免进程退出
+func Recovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("[grpc] %s panic: %v\n%s", info.FullMethod, r, debug.Stack())
+				err = status.Error(codes.Internal, errno.InternalServerError.Message)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// Errors 把 service 层返回的错误转换为 status，和 handler.SendError 的处理一致：
+// 业务错误按分类返回，错误码放在 trailer 中；未分类的错误记录日志后统一返回内部错误
+func Errors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := status.FromError(err); ok {
+			return resp, err
+		}
+		return resp, toStatus(ctx, info.FullMethod, err)
+	}
+}
+
+// toStatus 转换为 status
+func toStatus(ctx context.Context, method string, err error) error {
+	kind := errno.KindOf(err)
+	e, ok := errors.Cause(err).(*errno.Errno)
+	if !ok {
+		log.Warnf("[grpc] %s service err: %+v", method, err)
+		e = errno.InternalServerError
+		if kind == errno.KindUnavailable {
+			e = errno.ErrDependencyUnavailable
+		}
+	}
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(ErrnoCodeKey, strconv.Itoa(e.Code)))
+	return status.Error(kindCodes[kind], e.Message)
+}
@@ -0,0 +1,70 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	os.Exit(m.Run())
+}
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/user.v0.User/GetUser"}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{errno.ErrUserNotFound, codes.NotFound},
+		{errno.ErrParam, codes.InvalidArgument},
+		{context.DeadlineExceeded, codes.Unavailable},
+		{errors.New("boom"), codes.Internal},
+		{status.Error(codes.PermissionDenied, "denied"), codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		_, err := Errors()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, tt.err
+		})
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("err %v: got code %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	auth := Auth(map[string]string{"feed": "secret"})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return Caller(ctx), nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	caller, err := auth(ctx, nil, info, handler)
+	if err != nil || caller != "feed" {
+		t.Fatalf("got caller %v, err %v", caller, err)
+	}
+
+	for _, md := range []metadata.MD{nil, metadata.Pairs("authorization", "Bearer wrong"), metadata.Pairs("authorization", "secret")} {
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		if _, err := auth(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("md %v: got %v, want Unauthenticated", md, err)
+		}
+	}
+
+	// 健康检查不需要认证
+	health := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	if _, err := auth(context.Background(), nil, health, handler); err != nil {
+		t.Errorf("health check err: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package userserver0
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+
+	pb "github.com/1024casts/snake/internal/rpc/user/v0"
+)
+
+const (
+	// defaultListLimit 列表默认每页的数量
+	defaultListLimit = 20
+	// maxListLimit 列表每页最多的数量
+	maxListLimit = 100
+	// maxBatchSize 批量获取用户的最大数量
+	maxBatchSize = 100
+)
+
+// UserServer 用户服务，调用 service 层完成业务逻辑
+type UserServer struct {
+	pb.UnimplementedUserServer
+}
+
+// GetUser 获取用户信息
+func (s *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, errno.ErrParam
+	}
+
+	users, err := s.batchGetUsers(ctx, req.GetViewerId(), []uint64{req.GetUserId()})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errno.ErrUserNotFound
+	}
+
+	return &pb.GetUserResponse{User: users[0]}, nil
+}
+
+// BatchGetUsers 批量获取用户信息
+func (s *UserServer) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	if len(req.GetUserIds()) > maxBatchSize {
+		return nil, errno.ErrParam
+	}
+
+	users, err := s.batchGetUsers(ctx, req.GetViewerId(), req.GetUserIds())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BatchGetUsersResponse{Users: users}, nil
+}
+
+// IsFollowed 是否已关注
+func (s *UserServer) IsFollowed(ctx context.Context, req *pb.FollowRequest) (*pb.IsFollowedResponse, error) {
+	if req.GetUserId() == 0 || req.GetFollowedUid() == 0 {
+		return nil, errno.ErrParam
+	}
+
+	return &pb.IsFollowedResponse{Followed: user.Svc.IsFollowedUser(ctx, req.GetUserId(), req.GetFollowedUid())}, nil
+}
+
+// Follow 关注用户，和 HTTP 接口的检查一致
+func (s *UserServer) Follow(ctx context.Context, req *pb.FollowRequest) (*pb.FollowResponse, error) {
+	if err := s.checkFollow(ctx, req); err != nil {
+		return nil, err
+	}
+	if user.Svc.IsFollowedUser(ctx, req.GetUserId(), req.GetFollowedUid()) {
+		return &pb.FollowResponse{}, nil
+	}
+
+	if err := user.Svc.AddUserFollow(ctx, req.GetUserId(), req.GetFollowedUid()); err != nil {
+		return nil, err
+	}
+	return &pb.FollowResponse{}, nil
+}
+
+// Unfollow 取消关注
+func (s *UserServer) Unfollow(ctx context.Context, req *pb.FollowRequest) (*pb.FollowResponse, error) {
+	if err := s.checkFollow(ctx, req); err != nil {
+		return nil, err
+	}
+	if !user.Svc.IsFollowedUser(ctx, req.GetUserId(), req.GetFollowedUid()) {
+		return &pb.FollowResponse{}, nil
+	}
+
+	if err := user.Svc.CancelUserFollow(ctx, req.GetUserId(), req.GetFollowedUid()); err != nil {
+		return nil, err
+	}
+	return &pb.FollowResponse{}, nil
+}
+
+// ListFollowing 正在关注的用户列表
+func (s *UserServer) ListFollowing(ctx context.Context, req *pb.ListFollowRequest) (*pb.ListFollowResponse, error) {
+	limit, err := s.checkList(req)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := user.Svc.GetFollowingUserList(ctx, req.GetUserId(), req.GetLastId(), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListFollowResponse{}
+	if len(list) > limit {
+		resp.HasMore = true
+		list = list[:limit]
+	}
+	userIDs := make([]uint64, 0, len(list))
+	for _, f := range list {
+		userIDs = append(userIDs, f.FollowedUID)
+		resp.LastId = f.ID
+	}
+
+	if resp.Users, err = s.batchGetUsers(ctx, req.GetViewerId(), userIDs); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListFollowers 粉丝列表
+func (s *UserServer) ListFollowers(ctx context.Context, req *pb.ListFollowRequest) (*pb.ListFollowResponse, error) {
+	limit, err := s.checkList(req)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := user.Svc.GetFollowerUserList(ctx, req.GetUserId(), req.GetLastId(), limit+1, model.FollowerOrderRecent)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListFollowResponse{}
+	if len(list) > limit {
+		resp.HasMore = true
+		list = list[:limit]
+	}
+	userIDs := make([]uint64, 0, len(list))
+	for _, f := range list {
+		userIDs = append(userIDs, f.FollowerUID)
+		resp.LastId = f.ID
+	}
+
+	if resp.Users, err = s.batchGetUsers(ctx, req.GetViewerId(), userIDs); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// checkFollow 用户需要存在，不能关注自己
+func (s *UserServer) checkFollow(ctx context.Context, req *pb.FollowRequest) error {
+	if req.GetUserId() == 0 || req.GetFollowedUid() == 0 || req.GetUserId() == req.GetFollowedUid() {
+		return errno.ErrParam
+	}
+	u, err := user.Svc.GetUserByID(ctx, req.GetFollowedUid())
+	if err != nil {
+		return err
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+	return nil
+}
+
+// checkList 检查参数并返回每页的数量，用户受限模式下的列表不对内部服务隐藏，由调用方处理
+func (s *UserServer) checkList(req *pb.ListFollowRequest) (int, error) {
+	if req.GetUserId() == 0 || req.GetLimit() < 0 || req.GetLimit() > maxListLimit {
+		return 0, errno.ErrParam
+	}
+	if req.GetLimit() == 0 {
+		return defaultListLimit, nil
+	}
+	return int(req.GetLimit()), nil
+}
+
+// batchGetUsers 获取用户信息并转换为 pb 结构，不存在的用户被忽略
+func (s *UserServer) batchGetUsers(ctx context.Context, viewerID uint64, userIDs []uint64) ([]*pb.UserInfo, error) {
+	users := make([]*pb.UserInfo, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	infos, err := user.Svc.BatchGetUsers(ctx, viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info == nil || info.ID == 0 {
+			continue
+		}
+		users = append(users, toUserInfo(info))
+	}
+	return users, nil
+}
+
+// toUserInfo 转换为 pb 结构
+func toUserInfo(info *model.UserInfo) *pb.UserInfo {
+	u := &pb.UserInfo{
+		Id:       info.ID,
+		Username: info.Username,
+		Avatar:   info.Avatar,
+		Sex:      int32(info.Sex),
+	}
+	if f := info.UserFollow; f != nil {
+		u.FollowNum = int32(f.FollowNum)
+		u.FansNum = int32(f.FansNum)
+		u.IsFollow = f.IsFollow == 1
+		u.IsFans = f.IsFans == 1
+	}
+	return u
+}
@@ -0,0 +1,211 @@
+package announcement
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/announcement"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/internal/service/segment"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/quota"
+)
+
+// Service 公告服务接口定义
+type Service interface {
+	CreateAnnouncement(ctx context.Context, adminID uint64, title, content, segment, region, segmentName string) (*model.AnnouncementModel, error)
+	GetAnnouncement(ctx context.Context, id uint64) (*model.AnnouncementModel, error)
+	GetAnnouncementList(ctx context.Context, lastID uint64, limit int) ([]*model.AnnouncementModel, error)
+	// SendAnnouncements 分批发送未完成的公告，由定时任务调用
+	SendAnnouncements(ctx context.Context, batchSize int) (int, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewAnnouncementService()
+
+type announcementService struct {
+	announcementRepo announcement.Repo
+	userRepo         user.BaseRepo
+}
+
+// NewAnnouncementService 实例化一个公告服务
+func NewAnnouncementService() Service {
+	return &announcementService{
+		announcementRepo: announcement.NewAnnouncementRepo(),
+		userRepo:         user.NewUserRepo(),
+	}
+}
+
+// CreateAnnouncement 创建公告，创建后由定时任务发送
+func (srv *announcementService) CreateAnnouncement(ctx context.Context, adminID uint64, title, content, segment, region, segmentName string) (*model.AnnouncementModel, error) {
+	if title == "" {
+		return nil, errno.ErrParam
+	}
+
+	now := time.Now()
+	a := &model.AnnouncementModel{
+		Title:       title,
+		Content:     content,
+		Segment:     segment,
+		Region:      region,
+		SegmentName: segmentName,
+		Status:      model.AnnouncementStatusPending,
+		CreatedBy:   adminID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	total, err := srv.countUsers(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	a.Total = total
+
+	if _, err := srv.announcementRepo.CreateAnnouncement(model.WithContext(ctx), a); err != nil {
+		return nil, errors.Wrap(err, "[announcement_service] create announcement err")
+	}
+
+	return a, nil
+}
+
+// GetAnnouncement 获取公告及发送进度
+func (srv *announcementService) GetAnnouncement(ctx context.Context, id uint64) (*model.AnnouncementModel, error) {
+	a, err := srv.announcementRepo.GetAnnouncement(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[announcement_service] get announcement err, id: %d", id)
+	}
+	if a.ID == 0 {
+		return nil, errno.ErrAnnouncementNotFound
+	}
+
+	return a, nil
+}
+
+// GetAnnouncementList 获取公告列表
+func (srv *announcementService) GetAnnouncementList(ctx context.Context, lastID uint64, limit int) ([]*model.AnnouncementModel, error) {
+	announcements, err := srv.announcementRepo.GetAnnouncementList(model.WithContext(ctx), lastID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "[announcement_service] get announcement list err")
+	}
+
+	return announcements, nil
+}
+
+// SendAnnouncements 发送未完成的公告，返回本次发送的用户数
+func (srv *announcementService) SendAnnouncements(ctx context.Context, batchSize int) (int, error) {
+	announcements, err := srv.announcementRepo.GetUnfinishedAnnouncements(model.WithContext(ctx), 10)
+	if err != nil {
+		return 0, errors.Wrap(err, "[announcement_service] get unfinished announcements err")
+	}
+
+	sent := 0
+	for _, a := range announcements {
+		n, err := srv.sendAnnouncement(ctx, a, batchSize)
+		sent += n
+		if err != nil {
+			return sent, errors.Wrapf(err, "[announcement_service] send announcement err, id: %d", a.ID)
+		}
+	}
+
+	return sent, nil
+}
+
+// sendAnnouncement 按用户id分批发送，每批发送后记录进度，中断后从上次的位置继续
+func (srv *announcementService) sendAnnouncement(ctx context.Context, a *model.AnnouncementModel, batchSize int) (int, error) {
+	sent := 0
+	for {
+		userIDs, err := srv.scanUserIDs(ctx, a, a.LastUserID, batchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		for _, userID := range userIDs {
+			err := notification.Svc.Notify(ctx, userID, &notification.Message{
+				EventType: model.NotifyEventAnnouncement,
+				RefID:     a.ID,
+				Title:     a.Title,
+				Content:   a.Content,
+			})
+			if err != nil {
+				log.Warnf("[announcement_service] notify err: %v, id: %d, uid: %d", err, a.ID, userID)
+			}
+		}
+
+		a.LastUserID = userIDs[len(userIDs)-1]
+		a.Sent += len(userIDs)
+		sent += len(userIDs)
+		err = srv.announcementRepo.UpdateAnnouncement(model.WithContext(ctx), a.ID, map[string]interface{}{
+			"status":       model.AnnouncementStatusSending,
+			"sent":         a.Sent,
+			"last_user_id": a.LastUserID,
+			"updated_at":   time.Now(),
+		})
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	now := time.Now()
+	err := srv.announcementRepo.UpdateAnnouncement(model.WithContext(ctx), a.ID, map[string]interface{}{
+		"status":      model.AnnouncementStatusDone,
+		"updated_at":  now,
+		"finished_at": now,
+	})
+	return sent, err
+}
+
+// countUsers 目标用户数
+func (srv *announcementService) countUsers(ctx context.Context, a *model.AnnouncementModel) (int, error) {
+	if a.Segment == model.AnnouncementSegmentCustom {
+		if a.SegmentName == "" {
+			return 0, errno.ErrParam
+		}
+		return segment.Svc.CountUsers(a.SegmentName)
+	}
+
+	where, ok := segmentWhere(a.Segment, a.Region)
+	if !ok {
+		return 0, errno.ErrParam
+	}
+	total, err := srv.userRepo.CountUsers(model.WithContext(ctx), where)
+	if err != nil {
+		return 0, errors.Wrap(err, "[announcement_service] count segment users err")
+	}
+	return total, nil
+}
+
+// scanUserIDs 按id正序分批获取目标用户
+func (srv *announcementService) scanUserIDs(ctx context.Context, a *model.AnnouncementModel, lastID uint64, limit int) ([]uint64, error) {
+	if a.Segment == model.AnnouncementSegmentCustom {
+		return segment.Svc.ScanUserIDs(a.SegmentName, lastID, limit)
+	}
+
+	where, ok := segmentWhere(a.Segment, a.Region)
+	if !ok {
+		return nil, errno.WithKind(errno.KindInvalid, errors.Errorf("invalid segment: %s", a.Segment))
+	}
+	return srv.userRepo.ScanUserIDs(model.WithContext(ctx), where, lastID, limit)
+}
+
+// segmentWhere 目标用户的查询条件
+func segmentWhere(segment, region string) (map[string]interface{}, bool) {
+	switch segment {
+	case model.AnnouncementSegmentAll:
+		return map[string]interface{}{}, true
+	case model.AnnouncementSegmentVIP:
+		return map[string]interface{}{"plan": quota.PlanVIP}, true
+	case model.AnnouncementSegmentRegion:
+		if region == "" {
+			return nil, false
+		}
+		return map[string]interface{}{"region": region}, true
+	}
+	return nil, false
+}
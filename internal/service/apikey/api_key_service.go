@@ -0,0 +1,298 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/apikey"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/clock"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/quota"
+)
+
+const (
+	// KeyPrefix api key 的固定前缀，便于识别和代码扫描
+	KeyPrefix = "sk_"
+
+	// defaultTTL 默认有效期
+	defaultTTL = 90 * 24 * time.Hour
+	// defaultMaxKeys 每个用户默认最多可用的 key 数量
+	defaultMaxKeys = 5
+	// defaultRemindBefore 默认提前多久发送过期提醒
+	defaultRemindBefore = 7 * 24 * time.Hour
+	// lastUsedInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+	lastUsedInterval = time.Minute
+	// prefixLen 列表中展示的 key 的长度
+	prefixLen = 10
+)
+
+// Service api key 服务接口定义
+type Service interface {
+	// CreateKey 创建 api key，secret 为 key 的明文，只在创建时返回
+	CreateKey(ctx context.Context, userID uint64, name, ip string) (key *model.UserAPIKeyModel, secret string, err error)
+	// RotateKey 轮换 api key，旧的 key 立即失效，有效期重新计算
+	RotateKey(ctx context.Context, userID, id uint64, ip string) (key *model.UserAPIKeyModel, secret string, err error)
+	// RevokeKey 吊销 api key，吊销后不能恢复
+	RevokeKey(ctx context.Context, userID, id uint64, ip string) error
+	GetKeyList(ctx context.Context, userID uint64) ([]*model.UserAPIKeyModel, error)
+	// GetUsage 获取 api key 本日、本月的请求次数，配额按用户的套餐计算
+	GetUsage(ctx context.Context, userID, id uint64) (*quota.Usage, error)
+	// Authenticate 校验请求中的 api key，无效时返回 errno.ErrAPIKeyInvalid
+	Authenticate(ctx context.Context, secret string) (*model.UserAPIKeyModel, error)
+	// SendExpiryReminders 给即将过期的 api key 发送提醒，每个 key 只提醒一次，由定时任务调用
+	SendExpiryReminders(ctx context.Context, limit int) (int, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewAPIKeyService()
+
+type apiKeyService struct {
+	apiKeyRepo apikey.Repo
+	userRepo   user.BaseRepo
+	clock      clock.Clock
+}
+
+// NewAPIKeyService 实例化一个 api key 服务
+func NewAPIKeyService() Service {
+	return &apiKeyService{
+		apiKeyRepo: apikey.NewAPIKeyRepo(),
+		userRepo:   user.NewUserRepo(),
+		clock:      clock.Real,
+	}
+}
+
+// CreateKey 超过 apikey.max_keys 时不能再创建，已吊销、已过期的不计算在内
+func (srv *apiKeyService) CreateKey(ctx context.Context, userID uint64, name, ip string) (*model.UserAPIKeyModel, string, error) {
+	now := srv.clock.Now()
+	count, err := srv.apiKeyRepo.CountActiveKeys(model.WithContext(ctx), userID, now)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "[api_key_service] count api keys err, uid: %d", userID)
+	}
+	if count >= maxKeys() {
+		return nil, "", errno.ErrAPIKeyLimit
+	}
+
+	secret, err := genSecret()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "[api_key_service] gen api key err")
+	}
+	key := &model.UserAPIKeyModel{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:prefixLen],
+		KeyHash:   hashSecret(secret),
+		Status:    model.APIKeyStatusActive,
+		ExpiredAt: now.Add(ttl()),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := srv.apiKeyRepo.CreateKey(model.WithContext(ctx), key); err != nil {
+		return nil, "", errors.Wrapf(err, "[api_key_service] create api key err, uid: %d", userID)
+	}
+	srv.recordAudit(ctx, userID, audit.ActionAPIKeyCreate, ip, key)
+
+	return key, secret, nil
+}
+
+// RotateKey 已吊销的 key 不能轮换，已过期的可以
+func (srv *apiKeyService) RotateKey(ctx context.Context, userID, id uint64, ip string) (*model.UserAPIKeyModel, string, error) {
+	key, err := srv.getUserKey(ctx, userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.Status == model.APIKeyStatusRevoked {
+		return nil, "", errno.ErrAPIKeyRevoked
+	}
+
+	secret, err := genSecret()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "[api_key_service] gen api key err")
+	}
+	now := srv.clock.Now()
+	data := map[string]interface{}{
+		"prefix":      secret[:prefixLen],
+		"key_hash":    hashSecret(secret),
+		"expired_at":  now.Add(ttl()),
+		"reminded_at": nil,
+		"updated_at":  now,
+	}
+	if err := srv.apiKeyRepo.UpdateKey(model.WithContext(ctx), id, data); err != nil {
+		return nil, "", errors.Wrapf(err, "[api_key_service] rotate api key err, id: %d", id)
+	}
+	key.Prefix = secret[:prefixLen]
+	key.ExpiredAt = now.Add(ttl())
+	key.RemindedAt = nil
+	key.UpdatedAt = now
+	srv.recordAudit(ctx, userID, audit.ActionAPIKeyRotate, ip, key)
+
+	return key, secret, nil
+}
+
+// RevokeKey 重复吊销不做处理
+func (srv *apiKeyService) RevokeKey(ctx context.Context, userID, id uint64, ip string) error {
+	key, err := srv.getUserKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if key.Status == model.APIKeyStatusRevoked {
+		return nil
+	}
+
+	data := map[string]interface{}{"status": model.APIKeyStatusRevoked, "updated_at": srv.clock.Now()}
+	if err := srv.apiKeyRepo.UpdateKey(model.WithContext(ctx), id, data); err != nil {
+		return errors.Wrapf(err, "[api_key_service] revoke api key err, id: %d", id)
+	}
+	srv.recordAudit(ctx, userID, audit.ActionAPIKeyRevoke, ip, key)
+
+	return nil
+}
+
+// GetKeyList 获取用户的 api key 列表
+func (srv *apiKeyService) GetKeyList(ctx context.Context, userID uint64) ([]*model.UserAPIKeyModel, error) {
+	keys, err := srv.apiKeyRepo.GetKeyList(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[api_key_service] get api key list err, uid: %d", userID)
+	}
+	return keys, nil
+}
+
+// GetUsage 未开启配额时返回 errno.ErrRateLimitDisabled
+func (srv *apiKeyService) GetUsage(ctx context.Context, userID, id uint64) (*quota.Usage, error) {
+	if quota.Client == nil {
+		return nil, errno.ErrRateLimitDisabled
+	}
+	if _, err := srv.getUserKey(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[api_key_service] get user err, uid: %d", userID)
+	}
+
+	usage, err := quota.Client.Get(quota.APIKeySubject(id), u.Plan)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[api_key_service] get api key usage err, id: %d", id)
+	}
+	return usage, nil
+}
+
+// Authenticate 按 key 的哈希查找，最近使用时间更新失败不影响请求
+func (srv *apiKeyService) Authenticate(ctx context.Context, secret string) (*model.UserAPIKeyModel, error) {
+	if !strings.HasPrefix(secret, KeyPrefix) {
+		return nil, errno.ErrAPIKeyInvalid
+	}
+	key, err := srv.apiKeyRepo.GetKeyByHash(model.WithContext(ctx), hashSecret(secret))
+	if err != nil {
+		return nil, errors.Wrap(err, "[api_key_service] get api key err")
+	}
+	now := srv.clock.Now()
+	if !key.IsValid(now) {
+		return nil, errno.ErrAPIKeyInvalid
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := srv.apiKeyRepo.UpdateKey(model.WithContext(ctx), key.ID, map[string]interface{}{"last_used_at": now}); err != nil {
+			log.Warnf("[api_key_service] update last used at err: %v, id: %d", err, key.ID)
+		}
+	}
+	return key, nil
+}
+
+// SendExpiryReminders 提前 apikey.remind_before 通过通知系统提醒，用户可以在通知偏好中关闭
+func (srv *apiKeyService) SendExpiryReminders(ctx context.Context, limit int) (int, error) {
+	now := srv.clock.Now()
+	keys, err := srv.apiKeyRepo.GetExpiringKeys(model.WithContext(ctx), now, now.Add(remindBefore()), limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "[api_key_service] get expiring api keys err")
+	}
+
+	count := 0
+	for _, key := range keys {
+		err := notification.Svc.Notify(ctx, key.UserID, &notification.Message{
+			EventType: model.NotifyEventAPIKeyExpiring,
+			RefID:     key.ID,
+			Title:     "API key 即将过期",
+			Content: fmt.Sprintf("你的 API key %s（%s...）将于 %s 过期，请及时轮换",
+				key.Name, key.Prefix, key.ExpiredAt.Format("2006-01-02 15:04")),
+			CreatedAt: now,
+		})
+		// 某个渠道发送失败时其他渠道已经发送，仍然标记为已提醒，避免每次执行都重复提醒
+		if err != nil {
+			log.Warnf("[api_key_service] notify api key expiring err: %v, id: %d", err, key.ID)
+		}
+		if err := srv.apiKeyRepo.UpdateKey(model.WithContext(ctx), key.ID, map[string]interface{}{"reminded_at": now}); err != nil {
+			return count, errors.Wrapf(err, "[api_key_service] update reminded at err, id: %d", key.ID)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// getUserKey 获取用户自己的 key，不存在或属于其他用户时返回 errno.ErrAPIKeyNotFound
+func (srv *apiKeyService) getUserKey(ctx context.Context, userID, id uint64) (*model.UserAPIKeyModel, error) {
+	key, err := srv.apiKeyRepo.GetKey(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[api_key_service] get api key err, id: %d", id)
+	}
+	if key.ID == 0 || key.UserID != userID {
+		return nil, errno.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// recordAudit 审计日志中只记录 key 的id和前缀
+func (srv *apiKeyService) recordAudit(ctx context.Context, userID uint64, action, ip string, key *model.UserAPIKeyModel) {
+	detail := map[string]interface{}{"id": key.ID, "prefix": key.Prefix}
+	if err := audit.Svc.Record(ctx, userID, userID, action, ip, detail); err != nil {
+		log.Warnf("[api_key_service] record audit log err: %v, uid: %d, action: %s", err, userID, action)
+	}
+}
+
+// genSecret 生成随机的 key
+func genSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return KeyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashSecret key 是高熵的随机数，直接使用 sha256 即可，不需要慢哈希
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func ttl() time.Duration {
+	if d := viper.GetDuration("apikey.ttl"); d > 0 {
+		return d
+	}
+	return defaultTTL
+}
+
+func maxKeys() int {
+	if n := viper.GetInt("apikey.max_keys"); n > 0 {
+		return n
+	}
+	return defaultMaxKeys
+}
+
+func remindBefore() time.Duration {
+	if d := viper.GetDuration("apikey.remind_before"); d > 0 {
+		return d
+	}
+	return defaultRemindBefore
+}
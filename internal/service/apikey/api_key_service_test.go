@@ -0,0 +1,90 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/clock"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 创建、轮换、吊销后只有当前的 key 可以通过校验，过期的 key 不能使用也不占用数量
+func TestAPIKeyService_Lifecycle(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	db, err := gorm.Open(sqlite.Open("file:apikey?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	_ = db.AutoMigrate(&model.UserAPIKeyModel{}, &model.AuditLogModel{})
+	model.DB = db
+	viper.Set("apikey.max_keys", 2)
+	t.Cleanup(func() { viper.Set("apikey.max_keys", nil) })
+
+	fake := clock.NewFake(time.Now())
+	srv := NewAPIKeyService().(*apiKeyService)
+	srv.clock = fake
+	ctx := context.Background()
+	userID := uint64(time.Now().UnixNano() % 1e12)
+
+	key, secret, err := srv.CreateKey(ctx, userID, "ci", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := srv.Authenticate(ctx, secret); err != nil || got.ID != key.ID || got.UserID != userID {
+		t.Fatalf("Authenticate() = %+v, %v", got, err)
+	}
+	if _, err := srv.Authenticate(ctx, secret+"x"); err != errno.ErrAPIKeyInvalid {
+		t.Fatalf("Authenticate() wrong key = %v, want ErrAPIKeyInvalid", err)
+	}
+	// 其他用户不能轮换
+	if _, _, err := srv.RotateKey(ctx, userID+1, key.ID, ""); err != errno.ErrAPIKeyNotFound {
+		t.Fatalf("RotateKey() other user = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	_, rotated, err := srv.RotateKey(ctx, userID, key.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Authenticate(ctx, secret); err != errno.ErrAPIKeyInvalid {
+		t.Fatalf("Authenticate() old key = %v, want ErrAPIKeyInvalid", err)
+	}
+	if _, err := srv.Authenticate(ctx, rotated); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := srv.CreateKey(ctx, userID, "deploy", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := srv.CreateKey(ctx, userID, "extra", ""); err != errno.ErrAPIKeyLimit {
+		t.Fatalf("CreateKey() over limit = %v, want ErrAPIKeyLimit", err)
+	}
+
+	if err := srv.RevokeKey(ctx, userID, key.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Authenticate(ctx, rotated); err != errno.ErrAPIKeyInvalid {
+		t.Fatalf("Authenticate() revoked key = %v, want ErrAPIKeyInvalid", err)
+	}
+	if _, _, err := srv.RotateKey(ctx, userID, key.ID, ""); err != errno.ErrAPIKeyRevoked {
+		t.Fatalf("RotateKey() revoked key = %v, want ErrAPIKeyRevoked", err)
+	}
+
+	// 过期后可以再创建
+	fake.Add(ttl())
+	if _, _, err := srv.CreateKey(ctx, userID, "extra", ""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/audit"
+)
+
+// 审计动作
+const (
+	// ActionEmailChangeRequest 申请修改邮箱
+	ActionEmailChangeRequest = "email_change_request"
+	// ActionEmailChangeConfirm 确认修改邮箱(新或旧邮箱中的一个)
+	ActionEmailChangeConfirm = "email_change_confirm"
+	// ActionEmailChanged 邮箱修改完成
+	ActionEmailChanged = "email_changed"
+	// ActionPasswordResetRequest 申请重置密码
+	ActionPasswordResetRequest = "password_reset_request"
+	// ActionPasswordReset 通过邮件重置了密码
+	ActionPasswordReset = "password_reset"
+	// ActionEmailVerified 验证邮箱
+	ActionEmailVerified = "email_verified"
+	// ActionPhoneChanged 手机号修改完成
+	ActionPhoneChanged = "phone_changed"
+	// ActionIdentityUnlinked 解绑登录方式
+	ActionIdentityUnlinked = "identity_unlinked"
+	// ActionOAuthLinked 第三方帐号通过已验证的邮箱关联到已有用户
+	ActionOAuthLinked = "oauth_linked"
+	// ActionImpersonate 管理员模拟用户登录
+	ActionImpersonate = "impersonate"
+	// ActionModerationApprove 资料审核通过
+	ActionModerationApprove = "moderation_approve"
+	// ActionModerationReject 资料审核拒绝
+	ActionModerationReject = "moderation_reject"
+	// ActionMembershipActivate 开通会员
+	ActionMembershipActivate = "membership_activate"
+	// ActionUserBan 封禁用户
+	ActionUserBan = "user_ban"
+	// ActionUserUnban 解封用户，包括到期自动解封
+	ActionUserUnban = "user_unban"
+	// ActionUserDeactivate 停用帐号
+	ActionUserDeactivate = "user_deactivate"
+	// ActionUserReactivate 恢复帐号
+	ActionUserReactivate = "user_reactivate"
+	// ActionAppealApprove 申诉通过
+	ActionAppealApprove = "appeal_approve"
+	// ActionAppealReject 申诉驳回
+	ActionAppealReject = "appeal_reject"
+	// ActionPolicyPublish 发布新版本的协议
+	ActionPolicyPublish = "policy_publish"
+	// ActionRoleGrant 授予角色
+	ActionRoleGrant = "role_grant"
+	// ActionRoleRevoke 收回角色
+	ActionRoleRevoke = "role_revoke"
+	// ActionAPIKeyCreate 创建 api key
+	ActionAPIKeyCreate = "api_key_create"
+	// ActionAPIKeyRotate 轮换 api key
+	ActionAPIKeyRotate = "api_key_rotate"
+	// ActionAPIKeyRevoke 吊销 api key
+	ActionAPIKeyRevoke = "api_key_revoke"
+	// 登录、关注、修改资料等用户动作归档时也会写入，action 为 model.Activity*
+)
+
+// Service 审计服务接口定义
+type Service interface {
+	// Record 记录审计日志, operatorID 为执行操作的用户，一般就是 userID 本人
+	Record(ctx context.Context, userID, operatorID uint64, action, ip string, detail interface{}) error
+	GetAuditLogList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.AuditLogModel, error)
+	GetLatestAuditLog(ctx context.Context, userID uint64, action string) (*model.AuditLogModel, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewAuditService()
+
+type auditService struct {
+	auditRepo audit.Repo
+}
+
+// NewAuditService 实例化一个审计服务
+func NewAuditService() Service {
+	return &auditService{
+		auditRepo: audit.NewAuditLogRepo(),
+	}
+}
+
+// Record 记录审计日志
+func (srv *auditService) Record(ctx context.Context, userID, operatorID uint64, action, ip string, detail interface{}) error {
+	var detailStr string
+	if detail != nil {
+		b, err := json.Marshal(detail)
+		if err != nil {
+			return errors.Wrap(err, "[audit_service] marshal detail err")
+		}
+		detailStr = string(b)
+	}
+
+	_, err := srv.auditRepo.CreateAuditLog(model.WithContext(ctx), &model.AuditLogModel{
+		UserID:     userID,
+		OperatorID: operatorID,
+		Action:     action,
+		Detail:     detailStr,
+		IP:         ip,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[audit_service] record audit log err, uid: %d, action: %s", userID, action)
+	}
+	return nil
+}
+
+// GetAuditLogList 获取用户的审计日志
+func (srv *auditService) GetAuditLogList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.AuditLogModel, error) {
+	logs, err := srv.auditRepo.GetAuditLogList(model.WithContext(ctx), userID, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[audit_service] get audit log list err, uid: %d", userID)
+	}
+	return logs, nil
+}
+
+// GetLatestAuditLog 获取用户某个操作最近一次的审计日志，不存在时返回空结构体
+func (srv *auditService) GetLatestAuditLog(ctx context.Context, userID uint64, action string) (*model.AuditLogModel, error) {
+	auditLog, err := srv.auditRepo.GetLatestAuditLog(model.WithContext(ctx), userID, action)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[audit_service] get latest audit log err, uid: %d", userID)
+	}
+	return auditLog, nil
+}
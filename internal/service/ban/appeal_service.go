@@ -0,0 +1,121 @@
+package ban
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// maxAppealLength 申诉内容的最大长度
+const maxAppealLength = 1000
+
+// SubmitAppeal 对生效中的封禁提交申诉，同一封禁同时只能有一个待处理的申诉
+func (srv *banService) SubmitAppeal(ctx context.Context, userID uint64, content string) (*model.UserAppealModel, error) {
+	if content == "" || utf8.RuneCountInString(content) > maxAppealLength {
+		return nil, errno.ErrParam
+	}
+	b, err := srv.GetActiveBan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if b.ID == 0 {
+		return nil, errno.ErrBanNotFound
+	}
+
+	pending, err := srv.appealRepo.GetPendingAppeal(model.WithContext(ctx), b.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending.ID > 0 {
+		return nil, errno.ErrAppealExist
+	}
+
+	now := time.Now()
+	appeal := &model.UserAppealModel{
+		BanID:     b.ID,
+		UserID:    userID,
+		Content:   content,
+		Status:    model.AppealStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := srv.appealRepo.CreateAppeal(model.WithContext(ctx), appeal); err != nil {
+		return nil, errors.Wrapf(err, "[ban_service] create appeal err, uid: %d", userID)
+	}
+	return appeal, nil
+}
+
+// GetAppealList 获取某个状态的申诉
+func (srv *banService) GetAppealList(ctx context.Context, status int, lastID uint64, limit int) ([]*model.UserAppealModel, error) {
+	appeals, err := srv.appealRepo.GetAppealList(model.WithContext(ctx), status, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "[ban_service] get appeal list err")
+	}
+	return appeals, nil
+}
+
+// ReviewAppeal 处理申诉，通过时解封
+func (srv *banService) ReviewAppeal(ctx context.Context, id, reviewerID uint64, approve bool, note, ip string) (*model.UserAppealModel, error) {
+	appeal, err := srv.appealRepo.GetAppeal(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if appeal.ID == 0 {
+		return nil, errno.ErrAppealNotFound
+	}
+	if appeal.Status != model.AppealStatusPending {
+		return nil, errno.ErrAppealReviewed
+	}
+
+	status, action := model.AppealStatusRejected, audit.ActionAppealReject
+	if approve {
+		status, action = model.AppealStatusApproved, audit.ActionAppealApprove
+	}
+	now := time.Now()
+	ok, err := srv.appealRepo.UpdateAppealStatus(model.WithContext(ctx), id, model.AppealStatusPending, map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewerID,
+		"review_note": note,
+		"reviewed_at": now,
+		"updated_at":  now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errno.ErrAppealReviewed
+	}
+	appeal.Status = status
+	appeal.ReviewerID = reviewerID
+	appeal.ReviewNote = note
+	appeal.ReviewedAt = &now
+
+	if approve {
+		b, err := srv.banRepo.GetBan(model.WithContext(ctx), appeal.BanID)
+		if err != nil {
+			return nil, err
+		}
+		if b.ID > 0 {
+			if err := srv.lift(ctx, b, reviewerID, ip, "appeal"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = audit.Svc.Record(ctx, appeal.UserID, reviewerID, action, ip, map[string]interface{}{
+		"appeal_id": appeal.ID,
+		"ban_id":    appeal.BanID,
+		"note":      note,
+	})
+	if err != nil {
+		log.Warnf("[ban_service] record audit log err: %v", err)
+	}
+	return appeal, nil
+}
@@ -0,0 +1,234 @@
+package ban
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	banCache "github.com/1024casts/snake/internal/cache/ban"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/ban"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// TopicBanExpired 临时封禁到期，由 worker 自动解封
+const TopicBanExpired = "ban.expired"
+
+// ExpiredEvent 封禁到期事件
+type ExpiredEvent struct {
+	BanID  uint64 `json:"ban_id"`
+	UserID uint64 `json:"user_id"`
+}
+
+// Service 封禁服务接口定义
+type Service interface {
+	// Ban 封禁用户, duration 为0时永久封禁，已在封禁中时先解封旧的记录
+	Ban(ctx context.Context, userID, operatorID uint64, reason string, duration time.Duration, ip string) (*model.UserBanModel, error)
+	// Unban 管理员解封
+	Unban(ctx context.Context, userID, operatorID uint64, ip string) error
+	// LiftExpired 到期自动解封，由 worker 调用
+	LiftExpired(ctx context.Context, banID uint64) error
+	// GetActiveBan 获取用户生效中的封禁，没有时返回空结构体
+	GetActiveBan(ctx context.Context, userID uint64) (*model.UserBanModel, error)
+	// CheckBan 用户被封禁时返回 errno.ErrUserBanned
+	CheckBan(ctx context.Context, userID uint64) error
+	GetBanList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.UserBanModel, error)
+
+	// 申诉
+	SubmitAppeal(ctx context.Context, userID uint64, content string) (*model.UserAppealModel, error)
+	GetAppealList(ctx context.Context, status int, lastID uint64, limit int) ([]*model.UserAppealModel, error)
+	ReviewAppeal(ctx context.Context, id, reviewerID uint64, approve bool, note, ip string) (*model.UserAppealModel, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewBanService()
+
+type banService struct {
+	banRepo    ban.Repo
+	appealRepo ban.AppealRepo
+	userRepo   user.BaseRepo
+	banCache   *banCache.Cache
+}
+
+// NewBanService 实例化一个封禁服务
+func NewBanService() Service {
+	return &banService{
+		banRepo:    ban.NewBanRepo(),
+		appealRepo: ban.NewAppealRepo(),
+		userRepo:   user.NewUserRepo(),
+		banCache:   banCache.NewBanCache(),
+	}
+}
+
+// Ban 封禁用户，临时封禁发布到期的延迟消息，到期后自动解封
+func (srv *banService) Ban(ctx context.Context, userID, operatorID uint64, reason string, duration time.Duration, ip string) (*model.UserBanModel, error) {
+	if reason == "" || duration < 0 {
+		return nil, errno.ErrParam
+	}
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[ban_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return nil, errno.ErrUserNotFound
+	}
+
+	now := time.Now()
+	b := &model.UserBanModel{
+		UserID:     userID,
+		Reason:     reason,
+		Status:     model.BanStatusActive,
+		OperatorID: operatorID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if duration > 0 {
+		expiredAt := now.Add(duration)
+		b.ExpiredAt = &expiredAt
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	old, err := srv.banRepo.GetActiveBan(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if old.ID > 0 {
+		if _, err := srv.banRepo.LiftBan(tx, old.ID, operatorID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if _, err := srv.banRepo.CreateBan(tx, b); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[ban_service] tx commit err")
+	}
+	srv.delCache(userID)
+
+	if b.ExpiredAt != nil {
+		_, err := queue.PublishAt(ctx, TopicBanExpired, &ExpiredEvent{BanID: b.ID, UserID: userID}, *b.ExpiredAt)
+		if err != nil {
+			// 到期后封禁不再生效，只是状态没有更新
+			log.Warnf("[ban_service] publish ban expired event err: %v, id: %d", err, b.ID)
+		}
+	}
+
+	err = audit.Svc.Record(ctx, userID, operatorID, audit.ActionUserBan, ip, map[string]interface{}{
+		"ban_id":     b.ID,
+		"reason":     reason,
+		"expired_at": b.ExpiredAt,
+	})
+	if err != nil {
+		log.Warnf("[ban_service] record audit log err: %v", err)
+	}
+	return b, nil
+}
+
+// Unban 管理员解封
+func (srv *banService) Unban(ctx context.Context, userID, operatorID uint64, ip string) error {
+	b, err := srv.banRepo.GetActiveBan(model.WithContext(ctx), userID)
+	if err != nil {
+		return err
+	}
+	if b.ID == 0 {
+		return errno.ErrBanNotFound
+	}
+
+	return srv.lift(ctx, b, operatorID, ip, "unban")
+}
+
+// LiftExpired 到期自动解封，封禁已被解封或重新封禁时忽略
+func (srv *banService) LiftExpired(ctx context.Context, banID uint64) error {
+	b, err := srv.banRepo.GetBan(model.WithContext(ctx), banID)
+	if err != nil {
+		return err
+	}
+	if b.ID == 0 || b.Status != model.BanStatusActive || b.ExpiredAt == nil {
+		return nil
+	}
+	if b.ExpiredAt.After(time.Now()) {
+		log.Warnf("[ban_service] ban %d not expired yet, expired at: %s", b.ID, b.ExpiredAt)
+		return nil
+	}
+
+	return srv.lift(ctx, b, 0, "", "expired")
+}
+
+// lift 解封并记录审计日志, operatorID 为0表示系统自动解封
+func (srv *banService) lift(ctx context.Context, b *model.UserBanModel, operatorID uint64, ip, source string) error {
+	ok, err := srv.banRepo.LiftBan(model.WithContext(ctx), b.ID, operatorID)
+	if err != nil {
+		return err
+	}
+	srv.delCache(b.UserID)
+	if !ok {
+		return nil
+	}
+
+	err = audit.Svc.Record(ctx, b.UserID, operatorID, audit.ActionUserUnban, ip, map[string]interface{}{
+		"ban_id": b.ID,
+		"source": source,
+	})
+	if err != nil {
+		log.Warnf("[ban_service] record audit log err: %v", err)
+	}
+	return nil
+}
+
+// GetActiveBan 获取生效中的封禁，先查缓存
+func (srv *banService) GetActiveBan(ctx context.Context, userID uint64) (*model.UserBanModel, error) {
+	b, ok, err := srv.banCache.GetBan(userID)
+	if err != nil {
+		log.Warnf("[ban_service] get ban cache err: %v, uid: %d", err, userID)
+	}
+	if !ok {
+		b, err = srv.banRepo.GetActiveBan(model.WithContext(ctx), userID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[ban_service] get active ban err, uid: %d", userID)
+		}
+		if err := srv.banCache.SetBan(userID, b); err != nil {
+			log.Warnf("[ban_service] set ban cache err: %v, uid: %d", err, userID)
+		}
+	}
+
+	if !b.IsActive(time.Now()) {
+		return &model.UserBanModel{}, nil
+	}
+	return b, nil
+}
+
+// CheckBan 用户被封禁时返回 errno.ErrUserBanned
+func (srv *banService) CheckBan(ctx context.Context, userID uint64) error {
+	b, err := srv.GetActiveBan(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if b.ID > 0 {
+		return errno.ErrUserBanned
+	}
+	return nil
+}
+
+// GetBanList 获取用户的封禁记录
+func (srv *banService) GetBanList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.UserBanModel, error) {
+	bans, err := srv.banRepo.GetBanList(model.WithContext(ctx), userID, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[ban_service] get ban list err, uid: %d", userID)
+	}
+	return bans, nil
+}
+
+func (srv *banService) delCache(userID uint64) {
+	if err := srv.banCache.DelBan(userID); err != nil {
+		log.Warnf("[ban_service] delete ban cache err: %v, uid: %d", err, userID)
+	}
+}
@@ -0,0 +1,253 @@
+package campaign
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/internal/service/segment"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/nonce"
+)
+
+// 发送结果，记录到指标和曝光日志
+const (
+	OutcomeSent      = "sent"
+	OutcomeHoldout   = "holdout"
+	OutcomeDuplicate = "duplicate"
+	OutcomeThrottled = "throttled"
+	OutcomeFailed    = "failed"
+)
+
+const (
+	defaultCooldown    = 30 * 24 * time.Hour
+	defaultMinInterval = 7 * 24 * time.Hour
+	defaultRate        = 20
+	defaultMaxPerRun   = 5000
+	batchSize          = 100
+	cursorTTL          = 30 * 24 * time.Hour
+)
+
+var sendTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "snake_campaign_sends_total",
+	Help: "Total number of re-engagement campaign sends by outcome.",
+}, []string{"campaign", "outcome"})
+
+// Campaign 召回活动，对应配置 campaign.campaigns 下的一项
+type Campaign struct {
+	Name string
+	// Segment 目标用户分群，一般使用 inactive_days 规则
+	Segment string
+	// Title、Content 通知模板，可以使用 {{.Username}}
+	Title   string
+	Content string
+	// Experiment 可选，用于评估召回效果的实验，分到对照组的用户不发送
+	Experiment string
+	// Cooldown 同一用户再次收到该活动的间隔
+	Cooldown time.Duration
+}
+
+// Result 一次发送的统计
+type Result struct {
+	Campaign string         `json:"campaign"`
+	Outcomes map[string]int `json:"outcomes"`
+}
+
+// Service 召回活动服务接口定义
+type Service interface {
+	// Run 按配置发送所有活动，由定时任务调用
+	Run(ctx context.Context) ([]*Result, error)
+	RunCampaign(ctx context.Context, c *Campaign, limit int) (*Result, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewCampaignService()
+
+type campaignService struct {
+	userRepo user.BaseRepo
+}
+
+// NewCampaignService 实例化一个召回活动服务
+func NewCampaignService() Service {
+	return &campaignService{
+		userRepo: user.NewUserRepo(),
+	}
+}
+
+// Run 每次运行每个活动最多发送 max_per_run 个用户，分群遍历完后下次从头开始
+func (srv *campaignService) Run(ctx context.Context) ([]*Result, error) {
+	var campaigns []*Campaign
+	if err := viper.UnmarshalKey("campaign.campaigns", &campaigns); err != nil {
+		return nil, errors.Wrap(err, "[campaign_service] unmarshal campaigns err")
+	}
+	limit := viper.GetInt("campaign.max_per_run")
+	if limit <= 0 {
+		limit = defaultMaxPerRun
+	}
+
+	results := make([]*Result, 0, len(campaigns))
+	for _, c := range campaigns {
+		ret, err := srv.RunCampaign(ctx, c, limit)
+		if ret != nil {
+			results = append(results, ret)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// RunCampaign 按用户id分批遍历分群，进度记录在 nonce 存储中，中断后继续
+// 发送按 campaign.rate 限速，同一用户在 cooldown 内只收到一次该活动，min_interval 内最多收到一个活动
+func (srv *campaignService) RunCampaign(ctx context.Context, c *Campaign, limit int) (*Result, error) {
+	title, err := template.New("title").Parse(c.Title)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[campaign_service] parse title err, campaign: %s", c.Name)
+	}
+	content, err := template.New("content").Parse(c.Content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[campaign_service] parse content err, campaign: %s", c.Name)
+	}
+	var exp *experiment.Experiment
+	if c.Experiment != "" {
+		exp, err = experiment.Client.Get(c.Experiment)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[campaign_service] get experiment err, campaign: %s", c.Name)
+		}
+	}
+
+	rate := viper.GetInt("campaign.rate")
+	if rate <= 0 {
+		rate = defaultRate
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	cursorKey := "campaign:" + c.Name + ":cursor"
+	cursor, err := nonce.Client.Get(cursorKey)
+	if err != nil {
+		return nil, err
+	}
+	lastID, _ := strconv.ParseUint(cursor, 10, 64)
+
+	ret := &Result{Campaign: c.Name, Outcomes: make(map[string]int)}
+	processed := 0
+	for processed < limit {
+		userIDs, err := segment.Svc.ScanUserIDs(c.Segment, lastID, batchSize)
+		if err != nil {
+			return ret, errors.Wrapf(err, "[campaign_service] scan segment err, campaign: %s", c.Name)
+		}
+		if len(userIDs) == 0 {
+			lastID = 0
+			break
+		}
+		users, err := srv.userRepo.GetUsersByIds(model.WithContext(ctx), userIDs)
+		if err != nil {
+			return ret, errors.Wrapf(err, "[campaign_service] get users err, campaign: %s", c.Name)
+		}
+
+		for _, u := range users {
+			if u.ID == 0 {
+				continue
+			}
+			outcome := srv.send(ctx, c, exp, u, title, content, ticker)
+			ret.Outcomes[outcome]++
+			sendTotal.WithLabelValues(c.Name, outcome).Inc()
+		}
+		lastID = userIDs[len(userIDs)-1]
+		processed += len(userIDs)
+		if err := nonce.Client.Set(cursorKey, strconv.FormatUint(lastID, 10), cursorTTL); err != nil {
+			return ret, err
+		}
+		if err := ctx.Err(); err != nil {
+			return ret, err
+		}
+	}
+	if lastID == 0 {
+		if err := nonce.Client.Del(cursorKey); err != nil {
+			return ret, err
+		}
+	}
+
+	log.Infof("[campaign_service] campaign %s done, outcomes: %v", c.Name, ret.Outcomes)
+	return ret, nil
+}
+
+// send 给单个用户发送，返回发送结果
+func (srv *campaignService) send(ctx context.Context, c *Campaign, exp *experiment.Experiment, u *model.UserBaseModel,
+	title, content *template.Template, ticker *time.Ticker) string {
+	cooldown := c.Cooldown
+	if cooldown <= 0 {
+		cooldown = defaultCooldown
+	}
+	minInterval := viper.GetDuration("campaign.min_interval")
+	if minInterval <= 0 {
+		minInterval = defaultMinInterval
+	}
+
+	dedupKey := "campaign:" + c.Name + ":" + strconv.FormatUint(u.ID, 10)
+	ok, err := nonce.Client.SetNX(dedupKey, "1", cooldown)
+	if err != nil {
+		log.Warnf("[campaign_service] dedup err: %v, campaign: %s, uid: %d", err, c.Name, u.ID)
+		return OutcomeFailed
+	}
+	if !ok {
+		return OutcomeDuplicate
+	}
+
+	// 对照组同样占用 cooldown，保证评估期间不会收到该活动，实验停止后全部发送
+	if exp != nil && exp.Running {
+		variant := exp.Assign(u.ID)
+		experiment.LogExposure(&experiment.Exposure{Experiment: exp.Name, Variant: variant, UserID: u.ID, Path: "campaign/" + c.Name})
+		if variant == exp.Control() {
+			return OutcomeHoldout
+		}
+	}
+
+	ok, err = nonce.Client.SetNX("campaign:user:"+strconv.FormatUint(u.ID, 10), c.Name, minInterval)
+	if err != nil || !ok {
+		if err := nonce.Client.Del(dedupKey); err != nil {
+			log.Warnf("[campaign_service] del dedup key err: %v, campaign: %s, uid: %d", err, c.Name, u.ID)
+		}
+		return OutcomeThrottled
+	}
+
+	select {
+	case <-ticker.C:
+	case <-ctx.Done():
+		return OutcomeFailed
+	}
+
+	msg := &notification.Message{EventType: model.NotifyEventReengagement}
+	if msg.Title, err = render(title, u); err == nil {
+		msg.Content, err = render(content, u)
+	}
+	if err == nil {
+		err = notification.Svc.Notify(ctx, u.ID, msg)
+	}
+	if err != nil {
+		log.Warnf("[campaign_service] send err: %v, campaign: %s, uid: %d", err, c.Name, u.ID)
+		return OutcomeFailed
+	}
+	return OutcomeSent
+}
+
+func render(t *template.Template, u *model.UserBaseModel) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]interface{}{"Username": u.Username}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
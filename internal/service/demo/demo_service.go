@@ -0,0 +1,88 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/demo"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/saga"
+)
+
+// Service 演示数据服务接口定义
+type Service interface {
+	// Seed 预置演示用户，已存在的用户不会重复创建，返回所有演示用户
+	Seed(ctx context.Context) ([]*model.UserBaseModel, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewDemoService()
+
+type demoService struct{}
+
+// NewDemoService 实例化一个演示数据服务
+func NewDemoService() Service {
+	return &demoService{}
+}
+
+// Username 第 n 个演示用户的用户名，从 1 开始
+func Username(n int) string {
+	return fmt.Sprintf("demo%d", n)
+}
+
+// Email 第 n 个演示用户的邮箱
+func Email(n int) string {
+	return fmt.Sprintf("demo%d@example.com", n)
+}
+
+// Seed 通过注册流程创建演示用户并标记邮箱已验证，新创建的用户会被 demo1 关注
+func (srv *demoService) Seed(ctx context.Context) ([]*model.UserBaseModel, error) {
+	// saga 的表不在 model 中，单独建表
+	if err := model.WithContext(ctx).AutoMigrate(&saga.Record{}); err != nil {
+		return nil, errors.Wrap(err, "[demo_service] migrate saga record err")
+	}
+
+	users := make([]*model.UserBaseModel, 0, demo.Users())
+	for n := 1; n <= demo.Users(); n++ {
+		u, created, err := srv.ensureUser(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+		if !created || n == 1 {
+			continue
+		}
+		if err := user.Svc.AddUserFollow(ctx, users[0].ID, u.ID); err != nil {
+			log.Warnf("[demo_service] follow user err: %v, uid: %d", err, u.ID)
+		}
+	}
+	return users, nil
+}
+
+// ensureUser 用户不存在时注册，返回用户和是否为新创建
+func (srv *demoService) ensureUser(ctx context.Context, n int) (*model.UserBaseModel, bool, error) {
+	u, err := user.Svc.GetUserByUsername(ctx, Username(n))
+	if err != nil {
+		return nil, false, err
+	}
+	if u.ID > 0 {
+		return u, false, nil
+	}
+
+	if err := user.Svc.Register(ctx, Username(n), Email(n), demo.Password()); err != nil {
+		return nil, false, errors.Wrapf(err, "[demo_service] register user err, username: %s", Username(n))
+	}
+	u, err = user.Svc.GetUserByUsername(ctx, Username(n))
+	if err != nil {
+		return nil, false, err
+	}
+	if err := user.Svc.UpdateUser(ctx, u.ID, map[string]interface{}{"email_verified": 1}); err != nil {
+		return nil, false, errors.Wrapf(err, "[demo_service] verify email err, uid: %d", u.ID)
+	}
+	u.EmailVerified = 1
+	return u, true, nil
+}
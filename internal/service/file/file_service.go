@@ -0,0 +1,179 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/file"
+	"github.com/1024casts/snake/internal/service/image"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// Service 文件服务接口定义
+type Service interface {
+	// FindDuplicate 按内容查找已上传的文件，不存在时返回nil
+	FindDuplicate(ctx context.Context, hash string, size int64) (*model.FileModel, error)
+	Create(ctx context.Context, f *model.FileModel) error
+	// Track 读取已上传的文件计算哈希后记录，内容重复时删除新文件并返回已有的文件
+	Track(ctx context.Context, userID uint64, category, key, contentType string) (*model.FileModel, error)
+	// ReplaceRef 引用从 oldURL 换成 newURL，不是默认存储的地址会被忽略
+	ReplaceRef(ctx context.Context, oldURL, newURL string)
+	// CleanOrphans 清理没有被引用且超过保留时间的文件，由定时任务调用
+	CleanOrphans(ctx context.Context, grace time.Duration, limit int) (int, error)
+	// Scan 扫描文件是否有病毒，感染的文件会被隔离并通知上传者，由 worker 调用
+	Scan(ctx context.Context, id uint64) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewFileService()
+
+type fileService struct {
+	fileRepo file.Repo
+}
+
+// NewFileService 实例化一个文件服务
+func NewFileService() Service {
+	return &fileService{
+		fileRepo: file.NewFileRepo(),
+	}
+}
+
+// Hash 计算内容的 sha256 和大小
+func Hash(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// FindDuplicate 按内容查找已上传的文件
+func (srv *fileService) FindDuplicate(ctx context.Context, hash string, size int64) (*model.FileModel, error) {
+	f, err := srv.fileRepo.GetFileByHash(model.WithContext(ctx), hash, size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[file_service] get file by hash err, hash: %s", hash)
+	}
+	if f.ID == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Create 记录文件，新文件引用次数为0，被使用时再增加
+// 记录后发送上传事件，由 worker 异步扫描病毒
+func (srv *fileService) Create(ctx context.Context, f *model.FileModel) error {
+	now := time.Now()
+	f.CreatedAt = now
+	f.UpdatedAt = now
+	if _, err := srv.fileRepo.CreateFile(model.WithContext(ctx), f); err != nil {
+		return errors.Wrapf(err, "[file_service] create file err, key: %s", f.StorageKey)
+	}
+
+	event := &UploadedEvent{FileID: f.ID, UserID: f.UserID, Key: f.StorageKey}
+	if err := queue.Publish(ctx, TopicFileUploaded, event); err != nil {
+		log.Warnf("[file_service] publish file uploaded err: %v, key: %s", err, f.StorageKey)
+	}
+	return nil
+}
+
+// Track 用于分片上传、直传等上传时无法计算哈希的文件
+func (srv *fileService) Track(ctx context.Context, userID uint64, category, key, contentType string) (*model.FileModel, error) {
+	existing, err := srv.fileRepo.GetFileByKey(model.WithContext(ctx), key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[file_service] get file by key err, key: %s", key)
+	}
+	if existing.ID > 0 {
+		return existing, nil
+	}
+
+	r, err := storage.Default.Get(ctx, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[file_service] get object err, key: %s", key)
+	}
+	hash, size, err := Hash(r)
+	r.Close()
+	if err != nil {
+		return nil, errors.Wrapf(err, "[file_service] hash object err, key: %s", key)
+	}
+
+	dup, err := srv.FindDuplicate(ctx, hash, size)
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			log.Warnf("[file_service] delete duplicate object err: %v, key: %s", err, key)
+		}
+		return dup, nil
+	}
+
+	f := &model.FileModel{
+		UserID:      userID,
+		Category:    category,
+		StorageKey:  key,
+		Hash:        hash,
+		Size:        size,
+		ContentType: contentType,
+	}
+	if err := srv.Create(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReplaceRef 引用计数出错不影响业务，最多导致文件晚一些被清理或需要重新上传
+func (srv *fileService) ReplaceRef(ctx context.Context, oldURL, newURL string) {
+	if oldURL == newURL {
+		return
+	}
+	if key, ok := storage.KeyFromURL(newURL); ok {
+		if err := srv.fileRepo.IncrRefCount(model.WithContext(ctx), key, 1); err != nil {
+			log.Warnf("[file_service] incr ref count err: %v, key: %s", err, key)
+		}
+	}
+	if key, ok := storage.KeyFromURL(oldURL); ok {
+		if err := srv.fileRepo.IncrRefCount(model.WithContext(ctx), key, -1); err != nil {
+			log.Warnf("[file_service] decr ref count err: %v, key: %s", err, key)
+		}
+	}
+}
+
+// CleanOrphans 先删除记录再删除文件，删除记录时会再次检查引用次数，避免删除刚被引用的文件
+func (srv *fileService) CleanOrphans(ctx context.Context, grace time.Duration, limit int) (int, error) {
+	before := time.Now().Add(-grace)
+	files, err := srv.fileRepo.GetOrphanFiles(model.WithContext(ctx), before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "[file_service] get orphan files err")
+	}
+
+	count := 0
+	for _, f := range files {
+		ok, err := srv.fileRepo.DeleteOrphanFile(model.WithContext(ctx), f.ID, before)
+		if err != nil {
+			return count, errors.Wrapf(err, "[file_service] delete orphan file err, id: %d", f.ID)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := storage.Default.Delete(ctx, f.StorageKey); err != nil {
+			log.Warnf("[file_service] delete object err: %v, key: %s", err, f.StorageKey)
+			continue
+		}
+		if err := image.Svc.DeleteVariants(ctx, f.StorageKey); err != nil {
+			log.Warnf("[file_service] delete image variants err: %v, key: %s", err, f.StorageKey)
+		}
+		count++
+	}
+
+	return count, nil
+}
@@ -0,0 +1,113 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/image"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/antivirus"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// TopicFileUploaded 文件记录后发送的消息主题
+const TopicFileUploaded = "file.uploaded"
+
+// quarantinePrefix 隔离区的存储路径前缀，感染的文件移到这里保留，供人工排查
+const quarantinePrefix = "quarantine/"
+
+// UploadedEvent 文件上传事件
+type UploadedEvent struct {
+	FileID uint64 `json:"file_id"`
+	UserID uint64 `json:"user_id"`
+	Key    string `json:"key"`
+}
+
+// scanTotal 扫描结果统计，result 为 infected 时需要告警
+var scanTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "snake_upload_scans_total",
+	Help: "Total number of uploaded file virus scans.",
+}, []string{"category", "result"})
+
+// Scan 没有配置扫描服务时保持待扫描状态，配置后可以重新投递消息补扫
+func (srv *fileService) Scan(ctx context.Context, id uint64) error {
+	if antivirus.Client == nil {
+		return nil
+	}
+
+	f, err := srv.fileRepo.GetFile(model.WithContext(ctx), id)
+	if err != nil {
+		return errors.Wrapf(err, "[file_service] get file err, id: %d", id)
+	}
+	if f.ID == 0 || f.ScanStatus != model.FileScanPending {
+		return nil
+	}
+
+	r, err := storage.Default.Get(ctx, f.StorageKey)
+	if err != nil {
+		return errors.Wrapf(err, "[file_service] get object err, key: %s", f.StorageKey)
+	}
+	result, err := antivirus.Client.Scan(ctx, r)
+	r.Close()
+	if err != nil {
+		scanTotal.WithLabelValues(f.Category, "error").Inc()
+		return errors.Wrapf(err, "[file_service] scan file err, key: %s", f.StorageKey)
+	}
+
+	if !result.Infected {
+		scanTotal.WithLabelValues(f.Category, "clean").Inc()
+		if _, err := srv.fileRepo.UpdateScanResult(model.WithContext(ctx), f.ID, model.FileScanClean, ""); err != nil {
+			return errors.Wrapf(err, "[file_service] update scan result err, id: %d", f.ID)
+		}
+		return nil
+	}
+
+	scanTotal.WithLabelValues(f.Category, "infected").Inc()
+	log.Errorf("[file_service] infected file found, id: %d, uid: %d, key: %s, signature: %s",
+		f.ID, f.UserID, f.StorageKey, result.Signature)
+	return srv.quarantine(ctx, f, result.Signature)
+}
+
+// quarantine 先复制到隔离区再删除原文件，最后标记记录，中途失败时重新消费可以继续
+func (srv *fileService) quarantine(ctx context.Context, f *model.FileModel, signature string) error {
+	r, err := storage.Default.Get(ctx, f.StorageKey)
+	if err != nil {
+		return errors.Wrapf(err, "[file_service] get object err, key: %s", f.StorageKey)
+	}
+	err = storage.Default.Put(ctx, quarantinePrefix+f.StorageKey, r, f.Size, f.ContentType)
+	r.Close()
+	if err != nil {
+		return errors.Wrapf(err, "[file_service] put quarantine object err, key: %s", f.StorageKey)
+	}
+	if err := storage.Default.Delete(ctx, f.StorageKey); err != nil {
+		return errors.Wrapf(err, "[file_service] delete infected object err, key: %s", f.StorageKey)
+	}
+	if err := image.Svc.DeleteVariants(ctx, f.StorageKey); err != nil {
+		log.Warnf("[file_service] delete image variants err: %v, key: %s", err, f.StorageKey)
+	}
+
+	ok, err := srv.fileRepo.UpdateScanResult(model.WithContext(ctx), f.ID, model.FileScanInfected, signature)
+	if err != nil {
+		return errors.Wrapf(err, "[file_service] update scan result err, id: %d", f.ID)
+	}
+	if !ok {
+		return nil
+	}
+
+	err = notification.Svc.Notify(ctx, f.UserID, &notification.Message{
+		EventType: model.NotifyEventFileQuarantined,
+		RefID:     f.ID,
+		Title:     "文件已被隔离",
+		Content:   fmt.Sprintf("你上传的文件检测到病毒（%s），已被删除，请检查设备安全后重新上传", signature),
+	})
+	if err != nil {
+		log.Warnf("[file_service] notify user err: %v, uid: %d", err, f.UserID)
+	}
+	return nil
+}
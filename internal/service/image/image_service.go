@@ -0,0 +1,161 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/image"
+	"github.com/1024casts/snake/pkg/imageproc"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+var (
+	// defaultSizes 默认生成的尺寸，客户端可以按需请求 avatar@64/128/256
+	defaultSizes = []int{64, 128, 256}
+	// defaultFormats 默认生成的格式
+	defaultFormats = []string{imageproc.FormatJPEG, imageproc.FormatWebP}
+)
+
+// Service 图片处理服务接口定义
+type Service interface {
+	// ProcessImage 生成各尺寸、格式的缩略图，由 worker 在图片上传后调用
+	ProcessImage(ctx context.Context, key string) error
+	// GetVariantURL 获取图片某个尺寸的地址，还没有生成时返回原图地址
+	GetVariantURL(ctx context.Context, sourceURL string, size int, format string) (string, error)
+	// DeleteVariants 删除图片的所有缩略图，原图被清理时调用
+	DeleteVariants(ctx context.Context, key string) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewImageService()
+
+type imageService struct {
+	variantRepo image.VariantRepo
+}
+
+// NewImageService 实例化一个图片处理服务
+func NewImageService() Service {
+	return &imageService{
+		variantRepo: image.NewVariantRepo(),
+	}
+}
+
+// ProcessImage 生成正方形缩略图，重新编码时会去掉 EXIF
+// 某个格式不支持时跳过，比如没有安装 cwebp
+func (srv *imageService) ProcessImage(ctx context.Context, key string) error {
+	r, err := storage.Default.Get(ctx, key)
+	if err != nil {
+		return errors.Wrapf(err, "[image_service] get image err, key: %s", key)
+	}
+	src, _, err := imageproc.Decode(r)
+	r.Close()
+	if err != nil {
+		// 无法解码的图片重试也没有用
+		log.Warnf("[image_service] decode image err: %v, key: %s", err, key)
+		return nil
+	}
+
+	for _, size := range sizes() {
+		thumb := imageproc.Thumbnail(src, size, size)
+		for _, format := range formats() {
+			var buf bytes.Buffer
+			err := imageproc.Encode(&buf, thumb, format, viper.GetInt("image.quality"))
+			if err == imageproc.ErrWebPUnavailable || err == imageproc.ErrUnsupportedFormat {
+				log.Warnf("[image_service] skip format %s: %v", format, err)
+				continue
+			}
+			if err != nil {
+				return errors.Wrapf(err, "[image_service] encode image err, key: %s, size: %d", key, size)
+			}
+
+			variantKey := VariantKey(key, size, format)
+			fileSize := int64(buf.Len())
+			err = storage.Default.Put(ctx, variantKey, &buf, fileSize, imageproc.ContentType(format))
+			if err != nil {
+				return errors.Wrapf(err, "[image_service] put variant err, key: %s", variantKey)
+			}
+
+			err = srv.variantRepo.SaveVariant(model.WithContext(ctx), &model.ImageVariantModel{
+				SourceKey:  key,
+				Size:       size,
+				Format:     format,
+				StorageKey: variantKey,
+				Width:      size,
+				Height:     size,
+				FileSize:   fileSize,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "[image_service] save variant err, key: %s", variantKey)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetVariantURL 只有通过上传服务上传的图片才有缩略图
+func (srv *imageService) GetVariantURL(ctx context.Context, sourceURL string, size int, format string) (string, error) {
+	if format == "" {
+		format = imageproc.FormatJPEG
+	}
+	key, ok := storage.KeyFromURL(sourceURL)
+	if !ok {
+		return sourceURL, nil
+	}
+
+	variant, err := srv.variantRepo.GetVariant(model.WithContext(ctx), key, size, format)
+	if err != nil {
+		return "", errors.Wrapf(err, "[image_service] get variant err, key: %s", key)
+	}
+	if variant.ID == 0 {
+		return sourceURL, nil
+	}
+	return storage.Default.URL(variant.StorageKey), nil
+}
+
+// DeleteVariants 先删除文件再删除记录，中途失败时下次清理会重试
+func (srv *imageService) DeleteVariants(ctx context.Context, key string) error {
+	variants, err := srv.variantRepo.GetVariants(model.WithContext(ctx), key)
+	if err != nil {
+		return errors.Wrapf(err, "[image_service] get variants err, key: %s", key)
+	}
+	for _, variant := range variants {
+		if err := storage.Default.Delete(ctx, variant.StorageKey); err != nil {
+			return errors.Wrapf(err, "[image_service] delete variant err, key: %s", variant.StorageKey)
+		}
+	}
+
+	if err := srv.variantRepo.DeleteVariants(model.WithContext(ctx), key); err != nil {
+		return errors.Wrapf(err, "[image_service] delete variant records err, key: %s", key)
+	}
+	return nil
+}
+
+// VariantKey 缩略图的存储路径，如 avatar/1/xxx.jpg 的 64 尺寸为 avatar/1/xxx@64.jpg
+func VariantKey(key string, size int, format string) string {
+	base := strings.TrimSuffix(key, path.Ext(key))
+	return fmt.Sprintf("%s@%d%s", base, size, imageproc.Ext(format))
+}
+
+func sizes() []int {
+	if s := cast.ToIntSlice(viper.Get("image.sizes")); len(s) > 0 {
+		return s
+	}
+	return defaultSizes
+}
+
+func formats() []string {
+	if f := viper.GetStringSlice("image.formats"); len(f) > 0 {
+		return f
+	}
+	return defaultFormats
+}
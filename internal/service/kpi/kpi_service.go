@@ -0,0 +1,259 @@
+// Package kpi 业务指标，和系统指标一起暴露在 /metrics 中，可以在同一个 grafana 中查看
+// 计数器(注册数、登录数、关注数)在业务代码中实时累加，多实例时用 sum(rate(...)) 聚合
+// 总量类的指标(用户总数、今日注册数、活跃用户数等)由 Collect 定时从数据库、redis 中统计，
+// 最近一次的结果通过 Stats 提供给运营面板
+package kpi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/log"
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// prefixLoginKey 每日登录次数，field 为 success、fail
+	prefixLoginKey = "snake:kpi:login:%s"
+	// loginKeyExpire 保留两天，跨天时还能统计前一天
+	loginKeyExpire = 48 * time.Hour
+
+	// DefaultCollectInterval 默认统计间隔
+	DefaultCollectInterval = time.Minute
+)
+
+var (
+	registrationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_user_registrations_total",
+		Help: "Total number of user registrations.",
+	}, []string{"method"})
+	loginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_user_logins_total",
+		Help: "Total number of user logins.",
+	}, []string{"method", "result"})
+	followsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_user_follows_total",
+		Help: "Total number of follow and unfollow actions.",
+	}, []string{"action"})
+
+	usersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snake_users",
+		Help: "Number of registered users.",
+	})
+	registeredTodayGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snake_users_registered_today",
+		Help: "Number of users registered today.",
+	})
+	activeUsersGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "snake_users_active",
+		Help: "Number of users logged in within the window.",
+	}, []string{"window"})
+	followsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snake_follows",
+		Help: "Number of follow relations.",
+	})
+	followsTodayGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snake_follows_today",
+		Help: "Number of follow relations made today.",
+	})
+	loginSuccessRatioGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snake_user_login_success_ratio_today",
+		Help: "Ratio of successful logins today.",
+	})
+)
+
+// RecordRegistration 记录一次注册, method 为 email、phone 等
+func RecordRegistration(method string) {
+	registrationsTotal.WithLabelValues(method).Inc()
+}
+
+// RecordLogin 记录一次登录，同时累加当天的登录次数，用于统计登录成功率
+func RecordLogin(method string, success bool) {
+	result := "success"
+	if !success {
+		result = "fail"
+	}
+	loginsTotal.WithLabelValues(method, result).Inc()
+
+	if redis2.RedisClient == nil {
+		return
+	}
+	key := fmt.Sprintf(prefixLoginKey, time.Now().Format("20060102"))
+	pipe := redis2.RedisClient.Pipeline()
+	pipe.HIncrBy(key, result, 1)
+	pipe.Expire(key, loginKeyExpire)
+	if _, err := pipe.Exec(); err != nil {
+		log.Warnf("[kpi] incr login count err: %v", err)
+	}
+}
+
+// RecordFollow 记录一次关注或取消关注, action 为 follow、unfollow
+func RecordFollow(action string) {
+	followsTotal.WithLabelValues(action).Inc()
+}
+
+// Stats 总量类指标的最近一次统计结果
+type Stats struct {
+	Users           int `json:"users"`
+	RegisteredToday int `json:"registered_today"`
+	Follows         int `json:"follows"`
+	FollowsToday    int `json:"follows_today"`
+	// ActiveUsers 按窗口 1d、7d、30d 统计的活跃用户数
+	ActiveUsers       map[string]int `json:"active_users"`
+	LoginSuccessRatio float64        `json:"login_success_ratio"`
+	CollectedAt       time.Time      `json:"collected_at"`
+}
+
+// Service 业务指标服务接口定义
+type Service interface {
+	// Collect 统计总量类指标，由 counter.Worker 定时调用
+	Collect(ctx context.Context) (int, error)
+	// Stats 获取最近一次的统计结果，超过 maxAge 时重新统计
+	Stats(ctx context.Context, maxAge time.Duration) (*Stats, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewKPIService()
+
+type kpiService struct {
+	userRepo   user.BaseRepo
+	followRepo user.FollowRepo
+	deviceRepo user.DeviceRepo
+
+	mu   sync.RWMutex
+	last *Stats
+}
+
+// NewKPIService 实例化一个业务指标服务
+func NewKPIService() Service {
+	return &kpiService{
+		userRepo:   user.NewUserRepo(),
+		followRepo: user.NewUserFollowRepo(),
+		deviceRepo: user.NewUserDeviceRepo(),
+	}
+}
+
+// Collect 统计总量类指标，返回更新的指标数
+func (srv *kpiService) Collect(ctx context.Context) (int, error) {
+	stats, err := srv.collect(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	usersGauge.Set(float64(stats.Users))
+	registeredTodayGauge.Set(float64(stats.RegisteredToday))
+	followsGauge.Set(float64(stats.Follows))
+	followsTodayGauge.Set(float64(stats.FollowsToday))
+	for window, active := range stats.ActiveUsers {
+		activeUsersGauge.WithLabelValues(window).Set(float64(active))
+	}
+	loginSuccessRatioGauge.Set(stats.LoginSuccessRatio)
+
+	srv.mu.Lock()
+	srv.last = stats
+	srv.mu.Unlock()
+	return 5 + len(stats.ActiveUsers), nil
+}
+
+// Stats 获取最近一次的统计结果，kpi 统计任务没有在当前进程运行或结果已过期时重新统计
+func (srv *kpiService) Stats(ctx context.Context, maxAge time.Duration) (*Stats, error) {
+	srv.mu.RLock()
+	last := srv.last
+	srv.mu.RUnlock()
+	if last != nil && time.Since(last.CollectedAt) <= maxAge {
+		return last, nil
+	}
+
+	if _, err := srv.Collect(ctx); err != nil {
+		return nil, err
+	}
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	return srv.last, nil
+}
+
+func (srv *kpiService) collect(ctx context.Context) (*Stats, error) {
+	db := model.WithContext(ctx)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	stats := &Stats{ActiveUsers: make(map[string]int, 3), CollectedAt: now}
+
+	var err error
+	stats.Users, err = srv.userRepo.CountUsers(db, map[string]interface{}{})
+	if err != nil {
+		return nil, errors.Wrap(err, "[kpi_service] count users err")
+	}
+
+	stats.RegisteredToday, err = srv.userRepo.CountUsers(db.Where("created_at >= ?", today), map[string]interface{}{})
+	if err != nil {
+		return nil, errors.Wrap(err, "[kpi_service] count registered users err")
+	}
+
+	stats.Follows, err = srv.followRepo.CountFollows(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "[kpi_service] count follows err")
+	}
+
+	// 取消后重新关注会更新 updated_at，按 updated_at 统计当天新建立的关注
+	stats.FollowsToday, err = srv.followRepo.CountFollows(db.Where("updated_at >= ?", today))
+	if err != nil {
+		return nil, errors.Wrap(err, "[kpi_service] count follows today err")
+	}
+
+	windows := []struct {
+		name  string
+		since time.Time
+	}{
+		{"1d", now.AddDate(0, 0, -1)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"30d", now.AddDate(0, 0, -30)},
+	}
+	for _, w := range windows {
+		// 登录设备按地区分库保存，需要汇总各地区的数据
+		total := 0
+		for _, regionDB := range model.GetAllDBs() {
+			active, err := srv.deviceRepo.CountActiveUsers(regionDB, w.since)
+			if err != nil {
+				return nil, errors.Wrapf(err, "[kpi_service] count active users err, window: %s", w.name)
+			}
+			total += active
+		}
+		stats.ActiveUsers[w.name] = total
+	}
+
+	stats.LoginSuccessRatio, err = loginSuccessRatio(now)
+	if err != nil {
+		return nil, errors.Wrap(err, "[kpi_service] get login success ratio err")
+	}
+
+	return stats, nil
+}
+
+// loginSuccessRatio 当天的登录成功率，没有登录时为1
+func loginSuccessRatio(now time.Time) (float64, error) {
+	key := fmt.Sprintf(prefixLoginKey, now.Format("20060102"))
+	values, err := redis2.RedisClient.HMGet(key, "success", "fail").Result()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	counts := make([]float64, 2)
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			_, _ = fmt.Sscan(s, &counts[i])
+		}
+	}
+	if counts[0]+counts[1] == 0 {
+		return 1, nil
+	}
+	return counts[0] / (counts[0] + counts[1]), nil
+}
@@ -0,0 +1,310 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/moderation"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/imageaudit"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/sensitive"
+)
+
+// fieldNames 资料字段的名称，用于通知用户
+var fieldNames = map[string]string{
+	model.ModerationFieldUsername: "用户名",
+	model.ModerationFieldBio:      "个人简介",
+	model.ModerationFieldAvatar:   "头像",
+}
+
+// imageCheckTimeout 单张图片审核的超时时间
+const imageCheckTimeout = 10 * time.Second
+
+// Service 资料审核服务接口定义
+type Service interface {
+	// Check 检查资料修改是否需要人工审核，需要时返回进入审核的来源和原因
+	Check(field, value string) (flagged bool, source, reason string)
+	// CheckImageAsync 异步审核图片，被标记的图片会隔离并恢复为修改前的内容
+	CheckImageAsync(ctx context.Context, userID uint64, field, oldValue, newValue string)
+	Submit(ctx context.Context, userID uint64, field, oldValue, newValue, source, reason string) error
+
+	GetModerationList(ctx context.Context, status int, lastID uint64, limit int) ([]*model.ModerationModel, error)
+	Approve(ctx context.Context, id, reviewerID uint64, ip string) error
+	Reject(ctx context.Context, id, reviewerID uint64, reason, ip string) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewModerationService()
+
+type moderationService struct {
+	moderationRepo moderation.Repo
+	userRepo       user.BaseRepo
+}
+
+// NewModerationService 实例化一个资料审核服务
+func NewModerationService() Service {
+	return &moderationService{
+		moderationRepo: moderation.NewModerationRepo(),
+		userRepo:       user.NewUserRepo(),
+	}
+}
+
+// Check 文本字段检查敏感词，图片由 CheckImageAsync 异步审核
+func (srv *moderationService) Check(field, value string) (flagged bool, source, reason string) {
+	if value == "" {
+		return false, "", ""
+	}
+
+	switch field {
+	case model.ModerationFieldUsername, model.ModerationFieldBio:
+		if words := sensitive.Default.Match(value); len(words) > 0 {
+			return true, model.ModerationSourceSensitiveWord, strings.Join(words, ",")
+		}
+	}
+	return false, "", ""
+}
+
+// CheckImageAsync 异步审核图片，未配置图片审核服务时不审核
+func (srv *moderationService) CheckImageAsync(ctx context.Context, userID uint64, field, oldValue, newValue string) {
+	provider := imageaudit.Client
+	if provider == nil || newValue == "" {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("[moderation_service] check image panic: %v, uid: %d", r, userID)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), imageCheckTimeout)
+		defer cancel()
+
+		// 审核服务出错时也进入人工审核
+		reason := "图片审核服务出错"
+		ret, err := provider.Check(ctx, newValue)
+		if err != nil {
+			log.Warnf("[moderation_service] %s check image err: %v, url: %s", provider.Name(), err, newValue)
+		} else if !ret.Flagged {
+			return
+		} else {
+			reason = ret.Label
+		}
+
+		if err := srv.quarantine(ctx, userID, field, oldValue, newValue, reason); err != nil {
+			log.Warnf("[moderation_service] quarantine image err: %v, uid: %d", err, userID)
+		}
+	}()
+}
+
+// quarantine 隔离图片并进入审核队列，如果用户还在使用该图片则恢复为修改前的内容
+func (srv *moderationService) quarantine(ctx context.Context, userID uint64, field, oldValue, newValue, reason string) error {
+	tx := model.WithContext(ctx).Begin()
+	now := time.Now()
+	_, err := srv.moderationRepo.CreateModeration(tx, &model.ModerationModel{
+		UserID:      userID,
+		Field:       field,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		Source:      model.ModerationSourceImage,
+		Reason:      reason,
+		Status:      model.ModerationStatusPending,
+		Quarantined: 1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[moderation_service] create moderation err, uid: %d", userID)
+	}
+
+	if err := srv.replaceField(ctx, tx, userID, field, newValue, oldValue); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[moderation_service] tx commit err")
+	}
+
+	return nil
+}
+
+// replaceField 字段当前值为 from 时修改为 to，用户之后又修改过该字段则不处理
+func (srv *moderationService) replaceField(ctx context.Context, db *gorm.DB, userID uint64, field, from, to string) error {
+	u, err := srv.userRepo.GetUserByID(db, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[moderation_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 || u.ProfileField(field) != from {
+		return nil
+	}
+
+	err = srv.userRepo.Update(db, userID, map[string]interface{}{field: to})
+	if err != nil {
+		return errors.Wrapf(err, "[moderation_service] update user %s err, uid: %d", field, userID)
+	}
+	if field == model.ModerationFieldAvatar {
+		file.Svc.ReplaceRef(ctx, from, to)
+	}
+	return nil
+}
+
+// Submit 提交到审核队列
+func (srv *moderationService) Submit(ctx context.Context, userID uint64, field, oldValue, newValue, source, reason string) error {
+	now := time.Now()
+	_, err := srv.moderationRepo.CreateModeration(model.WithContext(ctx), &model.ModerationModel{
+		UserID:    userID,
+		Field:     field,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		Source:    source,
+		Reason:    reason,
+		Status:    model.ModerationStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[moderation_service] submit moderation err, uid: %d", userID)
+	}
+
+	return nil
+}
+
+// GetModerationList 获取审核队列
+func (srv *moderationService) GetModerationList(ctx context.Context, status int, lastID uint64, limit int) ([]*model.ModerationModel, error) {
+	items, err := srv.moderationRepo.GetModerationList(model.WithContext(ctx), status, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "[moderation_service] get moderation list err")
+	}
+
+	return items, nil
+}
+
+// Approve 审核通过，保留修改后的内容，已隔离的修改在此时生效
+func (srv *moderationService) Approve(ctx context.Context, id, reviewerID uint64, ip string) error {
+	item, err := srv.getPending(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	now := time.Now()
+	ok, err := srv.moderationRepo.UpdateModerationStatus(tx, id, model.ModerationStatusPending, map[string]interface{}{
+		"status":      model.ModerationStatusApproved,
+		"reviewer_id": reviewerID,
+		"reviewed_at": now,
+		"updated_at":  now,
+	})
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[moderation_service] approve moderation err, id: %d", id)
+	}
+	if !ok {
+		tx.Rollback()
+		return errno.ErrModerationReviewed
+	}
+
+	if item.Quarantined == 1 {
+		if err := srv.replaceField(ctx, tx, item.UserID, item.Field, item.OldValue, item.NewValue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[moderation_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, item, reviewerID, audit.ActionModerationApprove, ip, "")
+	return nil
+}
+
+// Reject 审核拒绝，恢复为修改前的内容并通知用户
+// 如果用户之后又修改过该字段，则不再恢复
+func (srv *moderationService) Reject(ctx context.Context, id, reviewerID uint64, reason, ip string) error {
+	item, err := srv.getPending(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	now := time.Now()
+	ok, err := srv.moderationRepo.UpdateModerationStatus(tx, id, model.ModerationStatusPending, map[string]interface{}{
+		"status":        model.ModerationStatusRejected,
+		"reviewer_id":   reviewerID,
+		"review_reason": reason,
+		"reviewed_at":   now,
+		"updated_at":    now,
+	})
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[moderation_service] reject moderation err, id: %d", id)
+	}
+	if !ok {
+		tx.Rollback()
+		return errno.ErrModerationReviewed
+	}
+
+	if err := srv.replaceField(ctx, tx, item.UserID, item.Field, item.NewValue, item.OldValue); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[moderation_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, item, reviewerID, audit.ActionModerationReject, ip, reason)
+
+	content := "你修改的" + fieldNames[item.Field] + "未通过审核，已恢复为修改前的内容"
+	if reason != "" {
+		content += "，原因：" + reason
+	}
+	err = notification.Svc.Notify(ctx, item.UserID, &notification.Message{
+		EventType: model.NotifyEventModerationRejected,
+		RefID:     item.ID,
+		Title:     "资料审核未通过",
+		Content:   content,
+	})
+	if err != nil {
+		log.Warnf("[moderation_service] notify user err: %v, uid: %d", err, item.UserID)
+	}
+	return nil
+}
+
+// getPending 获取待审核的记录
+func (srv *moderationService) getPending(ctx context.Context, id uint64) (*model.ModerationModel, error) {
+	item, err := srv.moderationRepo.GetModeration(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[moderation_service] get moderation err, id: %d", id)
+	}
+	if item.ID == 0 {
+		return nil, errno.ErrModerationNotFound
+	}
+	if item.Status != model.ModerationStatusPending {
+		return nil, errno.ErrModerationReviewed
+	}
+	return item, nil
+}
+
+// recordAudit 记录审核操作，失败不影响审核结果
+func (srv *moderationService) recordAudit(ctx context.Context, item *model.ModerationModel, reviewerID uint64, action, ip, reason string) {
+	err := audit.Svc.Record(ctx, item.UserID, reviewerID, action, ip, map[string]interface{}{
+		"moderation_id": item.ID, "field": item.Field, "reason": reason,
+	})
+	if err != nil {
+		log.Warnf("[moderation_service] record audit err: %v, id: %d", err, item.ID)
+	}
+}
@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"context"
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/notification"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/ws"
+)
+
+// Channel 通知渠道
+type Channel interface {
+	Send(ctx context.Context, userID uint64, msg *Message) error
+}
+
+// ackEvents 重要的通知，通过 websocket 推送时需要客户端确认，未确认的在重连后重新投递
+var ackEvents = map[string]bool{
+	model.NotifyEventAnnouncement:       true,
+	model.NotifyEventModerationRejected: true,
+	model.NotifyEventFileQuarantined:    true,
+	model.NotifyEventSLOAlert:           true,
+	model.NotifyEventAPIKeyExpiring:     true,
+}
+
+// inAppChannel 站内信，写入通知表
+type inAppChannel struct {
+	notificationRepo notification.Repo
+}
+
+// Send 发送站内信，保存后的通知用于 websocket 推送
+func (ch *inAppChannel) Send(ctx context.Context, userID uint64, msg *Message) error {
+	n := &model.NotificationModel{
+		UserID:    userID,
+		EventType: msg.EventType,
+		RefID:     msg.RefID,
+		Title:     msg.Title,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+	}
+	if _, err := ch.notificationRepo.CreateNotification(model.WithContext(ctx), n); err != nil {
+		return err
+	}
+	msg.notification = n
+	return nil
+}
+
+// wsChannel 通过 websocket 把站内信推送给在线的用户
+type wsChannel struct{}
+
+// Send 推送站内信，需要确认的通知用户离线时保存到待确认队列，上线后补发
+func (ch *wsChannel) Send(ctx context.Context, userID uint64, msg *Message) error {
+	if ws.Client == nil || msg.notification == nil {
+		return errors.New("websocket is disabled or notification not saved")
+	}
+	return ws.Client.Send(userID, ws.TypeNotification, msg.notification, ackEvents[msg.EventType])
+}
+
+// pushChannel 推送
+// 暂未接入推送服务，只记录日志
+type pushChannel struct{}
+
+// Send 发送推送
+func (ch *pushChannel) Send(ctx context.Context, userID uint64, msg *Message) error {
+	log.Infof("[notification] push to user %d, event: %s, title: %s", userID, msg.EventType, msg.Title)
+	return nil
+}
+
+// emailChannel 邮件，没有绑定邮箱的用户不发送
+type emailChannel struct {
+	userRepo user.BaseRepo
+}
+
+// Send 发送邮件
+func (ch *emailChannel) Send(ctx context.Context, userID uint64, msg *Message) error {
+	u, err := ch.userRepo.GetUserByID(model.WithContext(ctx), userID)
+	if err != nil {
+		return errors.Wrapf(err, "[notification] get user err, uid: %d", userID)
+	}
+	if u.Email == "" {
+		return nil
+	}
+
+	return email.Send(u.Email, msg.Title, "Hi, "+u.Username+"<br>"+msg.Content)
+}
@@ -0,0 +1,96 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/counter"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// digestTemplate 摘要通知的标题和内容，内容中的 %d 为窗口内的事件数
+type digestTemplate struct {
+	title   string
+	content string
+}
+
+// digestTemplates 可以合并成摘要的高频事件
+var digestTemplates = map[string]digestTemplate{
+	model.NotifyEventNewFollower: {title: "新粉丝", content: "最近有 %d 位新用户关注了你"},
+}
+
+// isDigestEvent 事件是否合并成摘要发送，由 notification.digest 开启
+func isDigestEvent(eventType string) bool {
+	if !viper.GetBool("notification.digest") {
+		return false
+	}
+	_, ok := digestTemplates[eventType]
+	return ok
+}
+
+// SendDigest 把上次发送之后累计的事件合并成摘要，按用户的通知偏好发送，返回发送的用户数
+// 发送失败只记录日志，不会再次发送，避免部分渠道重复收到
+// 之后发送离线用户的邮件摘要，返回的用户数包括两者
+func (srv *notificationService) SendDigest(ctx context.Context) (int, error) {
+	count, err := srv.digestBuffer.Flush(func(deltas counter.Deltas) error {
+		for userID, events := range deltas {
+			for eventType, num := range events {
+				tpl, ok := digestTemplates[eventType]
+				if !ok || num <= 0 {
+					continue
+				}
+				err := srv.deliver(ctx, userID, &Message{
+					EventType: eventType,
+					Title:     tpl.title,
+					Content:   fmt.Sprintf(tpl.content, num),
+				})
+				if err != nil {
+					log.Warnf("[notification_service] send digest err: %v, uid: %d, event: %s", err, userID, eventType)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "[notification_service] flush digest err")
+	}
+
+	emailCount, err := srv.sendEmailDigest(ctx)
+	if err != nil {
+		return count, err
+	}
+
+	return count + emailCount, nil
+}
+
+// sendEmailDigest 把离线期间的通知合并成一封邮件发送，返回发送的用户数
+func (srv *notificationService) sendEmailDigest(ctx context.Context) (int, error) {
+	count, err := srv.emailDigestBuffer.Flush(func(deltas counter.Deltas) error {
+		for userID, events := range deltas {
+			var total int64
+			for _, num := range events {
+				total += num
+			}
+			if total <= 0 {
+				continue
+			}
+			err := srv.channels[model.NotifyChannelEmail].Send(ctx, userID, &Message{
+				Title:   "未读通知",
+				Content: fmt.Sprintf("你有 %d 条未读通知，登录后查看", total),
+			})
+			if err != nil {
+				log.Warnf("[notification_service] send email digest err: %v, uid: %d", err, userID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "[notification_service] flush email digest err")
+	}
+
+	return count, nil
+}
@@ -0,0 +1,187 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/ws"
+)
+
+// TopicNotificationFanout 通知发布后由 worker 分发到各个渠道
+const TopicNotificationFanout = "notification.fanout"
+
+// FanoutEvent 待分发的通知
+type FanoutEvent struct {
+	UserID  uint64   `json:"user_id"`
+	Message *Message `json:"message"`
+}
+
+// Notify 发布通知
+// 开启摘要时，高频事件先计数，由定时任务合并成一条摘要再发送
+// 发布到队列失败时直接分发，避免通知丢失
+func (srv *notificationService) Notify(ctx context.Context, userID uint64, msg *Message) error {
+	if _, ok := defaultPreferences[msg.EventType]; !ok {
+		return errors.Errorf("[notification_service] unknown event type: %s", msg.EventType)
+	}
+
+	if isDigestEvent(msg.EventType) {
+		err := srv.digestBuffer.Incr(userID, msg.EventType, 1)
+		if err != nil {
+			return errors.Wrapf(err, "[notification_service] incr digest err, uid: %d", userID)
+		}
+		return nil
+	}
+
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	err := queue.Publish(ctx, TopicNotificationFanout, &FanoutEvent{UserID: userID, Message: msg})
+	if err != nil {
+		log.Warnf("[notification_service] publish fanout event err: %v, deliver directly, uid: %d", err, userID)
+		return srv.deliver(ctx, userID, msg)
+	}
+	return nil
+}
+
+// Deliver 分发通知
+func (srv *notificationService) Deliver(ctx context.Context, userID uint64, msg *Message) error {
+	if _, ok := defaultPreferences[msg.EventType]; !ok {
+		return errors.Errorf("[notification_service] unknown event type: %s", msg.EventType)
+	}
+	return srv.deliver(ctx, userID, msg)
+}
+
+// deliver 发送到用户开启的渠道，并记录每个渠道的投递状态
+// 开启 notification.presence_routing 时按在线状态路由：在线的用户通过 websocket 送达后不再发送推送和邮件，
+// 离线或 websocket 推送失败时再发送推送和邮件
+// 某个渠道发送失败不影响其他渠道，也不返回错误，避免 worker 重试时其他渠道重复发送
+func (srv *notificationService) deliver(ctx context.Context, userID uint64, msg *Message) error {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+
+	enabled, err := srv.getEnabledChannels(ctx, userID, msg.EventType)
+	if err != nil {
+		return errors.Wrapf(err, "[notification_service] get enabled channels err, uid: %d", userID)
+	}
+
+	r := &deliveryRecorder{userID: userID, msg: msg}
+	routing := viper.GetBool("notification.presence_routing") && ws.Client != nil
+	online := srv.isOnline(userID)
+	// reached 已经通过 websocket 实时送达
+	reached := false
+
+	if enabled[model.NotifyChannelInApp] {
+		r.record(model.NotifyChannelInApp, srv.send(ctx, model.NotifyChannelInApp, userID, msg))
+	}
+
+	// websocket 跟随站内信，离线时只投递需要确认的通知，保存到待确认队列，上线后补发
+	if ws.Client != nil && msg.notification != nil {
+		if online || ackEvents[msg.EventType] {
+			err := srv.send(ctx, model.NotifyChannelWebSocket, userID, msg)
+			switch {
+			case err != nil:
+				// 推送失败时按离线处理，通过推送和邮件送达
+				r.record(model.NotifyChannelWebSocket, err)
+			case !online:
+				r.add(model.NotifyChannelWebSocket, model.DeliveryStatusQueued, "offline")
+			default:
+				r.record(model.NotifyChannelWebSocket, nil)
+				reached = true
+			}
+		} else {
+			r.add(model.NotifyChannelWebSocket, model.DeliveryStatusSkipped, "offline")
+		}
+	}
+
+	for _, name := range []string{model.NotifyChannelPush, model.NotifyChannelEmail} {
+		if !enabled[name] {
+			continue
+		}
+		if routing && reached {
+			r.add(name, model.DeliveryStatusSkipped, "online")
+			continue
+		}
+		if routing && name == model.NotifyChannelEmail && viper.GetBool("notification.offline_email_digest") {
+			if err := srv.emailDigestBuffer.Incr(userID, msg.EventType, 1); err != nil {
+				r.record(name, err)
+				continue
+			}
+			r.add(name, model.DeliveryStatusQueued, "email digest")
+			continue
+		}
+		r.record(name, srv.send(ctx, name, userID, msg))
+	}
+
+	if len(r.deliveries) == 0 {
+		return nil
+	}
+	if err := srv.deliveryRepo.CreateDeliveries(model.WithContext(ctx), r.deliveries); err != nil {
+		log.Warnf("[notification_service] save deliveries err: %v, uid: %d", err, userID)
+	}
+	return nil
+}
+
+// send 通过某个渠道发送，失败时记录日志
+func (srv *notificationService) send(ctx context.Context, name string, userID uint64, msg *Message) error {
+	err := srv.channels[name].Send(ctx, userID, msg)
+	if err != nil {
+		log.Warnf("[notification_service] send %s notification err: %v, uid: %d", name, err, userID)
+	}
+	return err
+}
+
+// isOnline 用户是否在线，查询失败时按离线处理，宁可多发也不漏发
+func (srv *notificationService) isOnline(userID uint64) bool {
+	if ws.Client == nil {
+		return false
+	}
+	online, err := ws.Client.IsOnline(userID)
+	if err != nil {
+		log.Warnf("[notification_service] get presence err: %v, uid: %d", err, userID)
+		return false
+	}
+	return online
+}
+
+// deliveryRecorder 收集一次分发在各个渠道的投递状态
+type deliveryRecorder struct {
+	userID     uint64
+	msg        *Message
+	deliveries []*model.NotificationDeliveryModel
+}
+
+// record 按发送结果记录为已发送或失败
+func (r *deliveryRecorder) record(channel string, err error) {
+	if err != nil {
+		reason := err.Error()
+		if len(reason) > 255 {
+			reason = reason[:255]
+		}
+		r.add(channel, model.DeliveryStatusFailed, reason)
+		return
+	}
+	r.add(channel, model.DeliveryStatusSent, "")
+}
+
+func (r *deliveryRecorder) add(channel, status, reason string) {
+	d := &model.NotificationDeliveryModel{
+		UserID:    r.userID,
+		EventType: r.msg.EventType,
+		RefID:     r.msg.RefID,
+		Channel:   channel,
+		Status:    status,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if r.msg.notification != nil {
+		d.NotificationID = r.msg.notification.ID
+	}
+	r.deliveries = append(r.deliveries, d)
+}
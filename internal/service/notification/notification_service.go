@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/notification"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/counter"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// Message 通知内容
+type Message struct {
+	EventType string `json:"event_type"`
+	// RefID 关联对象的id，如公告id
+	RefID     uint64    `json:"ref_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// notification 站内信保存后的记录，websocket 推送时使用
+	notification *model.NotificationModel
+}
+
+// Service 通知服务接口定义
+type Service interface {
+	// Notify 发布通知，由 worker 按用户的通知偏好和在线状态分发到各个渠道
+	Notify(ctx context.Context, userID uint64, msg *Message) error
+	// Deliver 分发通知，由 worker 消费 fan-out 消息时调用
+	Deliver(ctx context.Context, userID uint64, msg *Message) error
+	GetNotificationList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.NotificationModel, error)
+	GetUnreadNotifications(ctx context.Context, userID uint64, eventType string, limit int) ([]*model.NotificationModel, error)
+	MarkRead(ctx context.Context, userID uint64, id uint64) error
+	// GetDeliveryList 获取用户的通知投递记录，用于排查用户没有收到通知的问题
+	GetDeliveryList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.NotificationDeliveryModel, error)
+	// SendDigest 发送摘要通知，由定时任务调用
+	SendDigest(ctx context.Context) (int, error)
+
+	// 通知偏好
+	GetPreferences(ctx context.Context, userID uint64) ([]*Preference, error)
+	UpdatePreferences(ctx context.Context, userID uint64, prefs []*Preference) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewNotificationService()
+
+type notificationService struct {
+	notificationRepo notification.Repo
+	preferenceRepo   notification.PreferenceRepo
+	deliveryRepo     notification.DeliveryRepo
+	channels         map[string]Channel
+	digestBuffer     *counter.Buffer
+	// emailDigestBuffer 离线用户的邮件合并成摘要发送，见 notification.offline_email_digest
+	emailDigestBuffer *counter.Buffer
+}
+
+// NewNotificationService 实例化一个通知服务
+func NewNotificationService() Service {
+	notificationRepo := notification.NewNotificationRepo()
+	userRepo := user.NewUserRepo()
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		preferenceRepo:   notification.NewPreferenceRepo(),
+		deliveryRepo:     notification.NewDeliveryRepo(),
+		channels: map[string]Channel{
+			model.NotifyChannelInApp:     &inAppChannel{notificationRepo: notificationRepo},
+			model.NotifyChannelWebSocket: &wsChannel{},
+			model.NotifyChannelPush:      &pushChannel{},
+			model.NotifyChannelEmail:     &emailChannel{userRepo: userRepo},
+		},
+		digestBuffer:      counter.NewBuffer(nil, "notify_digest"),
+		emailDigestBuffer: counter.NewBuffer(nil, "notify_email_digest"),
+	}
+}
+
+// GetNotificationList 获取用户的站内通知
+func (srv *notificationService) GetNotificationList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.NotificationModel, error) {
+	notifications, err := srv.notificationRepo.GetNotificationList(model.WithContext(ctx), userID, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[notification_service] get notification list err, uid: %d", userID)
+	}
+
+	return notifications, nil
+}
+
+// GetUnreadNotifications 获取用户某种事件的未读通知
+func (srv *notificationService) GetUnreadNotifications(ctx context.Context, userID uint64, eventType string, limit int) ([]*model.NotificationModel, error) {
+	notifications, err := srv.notificationRepo.GetUnreadNotifications(model.WithContext(ctx), userID, eventType, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[notification_service] get unread notifications err, uid: %d", userID)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead 标记通知为已读
+func (srv *notificationService) MarkRead(ctx context.Context, userID uint64, id uint64) error {
+	ok, err := srv.notificationRepo.MarkRead(model.WithContext(ctx), userID, id)
+	if err != nil {
+		return errors.Wrapf(err, "[notification_service] mark read err, uid: %d", userID)
+	}
+	if !ok {
+		return errno.ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// GetDeliveryList 获取用户的通知投递记录
+func (srv *notificationService) GetDeliveryList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.NotificationDeliveryModel, error) {
+	deliveries, err := srv.deliveryRepo.GetDeliveryList(model.WithContext(ctx), userID, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[notification_service] get delivery list err, uid: %d", userID)
+	}
+
+	return deliveries, nil
+}
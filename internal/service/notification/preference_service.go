@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"context"
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// channels 所有的通知渠道，也是分发的顺序
+var channels = []string{model.NotifyChannelInApp, model.NotifyChannelPush, model.NotifyChannelEmail}
+
+// defaultPreferences 每种事件默认开启的渠道，用户没有修改过时使用
+var defaultPreferences = map[string]map[string]bool{
+	model.NotifyEventNewFollower: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  true,
+		model.NotifyChannelEmail: true,
+	},
+	model.NotifyEventAnnouncement: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  true,
+		model.NotifyChannelEmail: false,
+	},
+	model.NotifyEventModerationRejected: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  false,
+		model.NotifyChannelEmail: true,
+	},
+	model.NotifyEventFileQuarantined: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  false,
+		model.NotifyChannelEmail: true,
+	},
+	model.NotifyEventReengagement: {
+		model.NotifyChannelInApp: false,
+		model.NotifyChannelPush:  true,
+		model.NotifyChannelEmail: true,
+	},
+	model.NotifyEventSLOAlert: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  true,
+		model.NotifyChannelEmail: true,
+	},
+	model.NotifyEventAPIKeyExpiring: {
+		model.NotifyChannelInApp: true,
+		model.NotifyChannelPush:  false,
+		model.NotifyChannelEmail: true,
+	},
+}
+
+// eventTypes 事件类型，返回偏好设置时保持固定的顺序
+var eventTypes = []string{model.NotifyEventNewFollower, model.NotifyEventAnnouncement, model.NotifyEventModerationRejected,
+	model.NotifyEventFileQuarantined, model.NotifyEventReengagement, model.NotifyEventSLOAlert, model.NotifyEventAPIKeyExpiring}
+
+// Preference 某个事件在某个渠道的通知偏好
+type Preference struct {
+	EventType string `json:"event_type" binding:"required" example:"new_follower"`
+	Channel   string `json:"channel" binding:"required" example:"email"`
+	Enabled   bool   `json:"enabled" example:"false"`
+}
+
+// GetPreferences 获取用户所有事件、所有渠道的通知偏好
+func (srv *notificationService) GetPreferences(ctx context.Context, userID uint64) ([]*Preference, error) {
+	settings, err := srv.getPreferenceSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make([]*Preference, 0, len(eventTypes)*len(channels))
+	for _, eventType := range eventTypes {
+		for _, channel := range channels {
+			prefs = append(prefs, &Preference{
+				EventType: eventType,
+				Channel:   channel,
+				Enabled:   settings[eventType][channel],
+			})
+		}
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences 批量修改通知偏好
+func (srv *notificationService) UpdatePreferences(ctx context.Context, userID uint64, prefs []*Preference) error {
+	for _, pref := range prefs {
+		if _, ok := defaultPreferences[pref.EventType][pref.Channel]; !ok {
+			return errno.ErrParam
+		}
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	for _, pref := range prefs {
+		err := srv.preferenceRepo.SaveUserPreference(tx, userID, pref.EventType, pref.Channel, pref.Enabled)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "[notification_service] save preference err, uid: %d", userID)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[notification_service] tx commit err")
+	}
+
+	return nil
+}
+
+// getEnabledChannels 获取用户某个事件开启的渠道
+func (srv *notificationService) getEnabledChannels(ctx context.Context, userID uint64, eventType string) (map[string]bool, error) {
+	settings, err := srv.getPreferenceSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return settings[eventType], nil
+}
+
+// getPreferenceSettings 合并默认设置和用户修改过的设置, event_type => channel => enabled
+func (srv *notificationService) getPreferenceSettings(ctx context.Context, userID uint64) (map[string]map[string]bool, error) {
+	settings := make(map[string]map[string]bool, len(defaultPreferences))
+	for eventType, defaults := range defaultPreferences {
+		settings[eventType] = make(map[string]bool, len(defaults))
+		for channel, enabled := range defaults {
+			settings[eventType][channel] = enabled
+		}
+	}
+
+	userPrefs, err := srv.preferenceRepo.GetUserPreferences(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[notification_service] get user preferences err, uid: %d", userID)
+	}
+	for _, pref := range userPrefs {
+		if _, ok := settings[pref.EventType][pref.Channel]; ok {
+			settings[pref.EventType][pref.Channel] = pref.Enabled == 1
+		}
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/slo"
+)
+
+// SLONotifier 把 SLO 告警通知给所有管理员
+var SLONotifier = slo.NotifierFunc(func(alert *slo.Alert) error {
+	log.Warnf("[slo] %s", alert)
+
+	msg := &Message{
+		EventType: model.NotifyEventSLOAlert,
+		Title:     "SLO 告警: " + alert.Objective.Name() + " " + alert.Kind,
+		Content:   alert.String(),
+		CreatedAt: alert.FiredAt,
+	}
+	var lastErr error
+	for _, uid := range cast.ToIntSlice(viper.Get("admin.uids")) {
+		if err := Svc.Notify(context.Background(), uint64(uid), msg); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+})
@@ -0,0 +1,218 @@
+// Package overview 运营面板的概览数据，汇总业务指标、请求错误率、队列积压和计划任务的执行状态
+// 各项数据的统计成本不同，整体在进程内缓存一小段时间，面板频繁刷新时不会反复查询数据库和 redis
+package overview
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/internal/service/kpi"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/internal/service/task"
+	"github.com/1024casts/snake/internal/service/upload"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/metrics"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/recorder"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// DefaultCacheTTL 默认的缓存时间
+	DefaultCacheTTL = 30 * time.Second
+	// DefaultErrorWindow 默认统计错误率的时间窗口
+	DefaultErrorWindow = 5 * time.Minute
+
+	// prefixJobStatus 计划任务最近一次执行的状态，由 cmd/job 写入，和 cmd/job/scheduler.go 中的一致
+	prefixJobStatus = "snake:job:status:"
+	// jobStatusFailed 最近一次执行失败
+	jobStatusFailed = "failed"
+	// scanCount 每次 SCAN 的数量
+	scanCount = 100
+)
+
+// topics 统计积压的队列主题，和 cmd/worker 中注册的处理函数一致，新增主题时需要加到这里
+var topics = []string{
+	user.TopicUserEvent,
+	user.TopicUserActivity,
+	file.TopicFileUploaded,
+	upload.TopicImageUploaded,
+	ban.TopicBanExpired,
+	task.TopicTaskCreated,
+	notification.TopicNotificationFanout,
+	recorder.TopicRequestRecorded,
+}
+
+// Overview 运营面板的概览数据
+type Overview struct {
+	Users           int `json:"users"`
+	RegisteredToday int `json:"registered_today"`
+	// DAU 最近 24 小时内登录过的用户数
+	DAU          int `json:"dau"`
+	FollowsToday int `json:"follows_today"`
+
+	// Requests、ErrorRate 当前实例最近 ErrorWindow 内的请求数和错误率，5xx 和服务端错误码记为失败
+	Requests    int64   `json:"requests"`
+	ErrorRate   float64 `json:"error_rate"`
+	ErrorWindow string  `json:"error_window"`
+
+	// QueueBacklog 各主题等待消费的消息数，队列驱动不支持统计时为空
+	QueueBacklog map[string]int64 `json:"queue_backlog"`
+	// QueueDead 死信队列中的消息总数
+	QueueDead int64 `json:"queue_dead"`
+
+	// FailedJobs 最近一次执行失败的计划任务
+	FailedJobs []*FailedJob `json:"failed_jobs"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// FailedJob 执行失败的计划任务
+type FailedJob struct {
+	Name       string    `json:"name"`
+	Error      string    `json:"error"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Service 概览服务接口定义
+type Service interface {
+	// Get 获取概览数据，在缓存时间 admin.overview_cache_ttl 内返回同一份结果
+	Get(ctx context.Context) (*Overview, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewOverviewService()
+
+type overviewService struct {
+	mu        sync.Mutex
+	cached    *Overview
+	expiredAt time.Time
+}
+
+// NewOverviewService 实例化一个概览服务
+func NewOverviewService() Service {
+	return &overviewService{}
+}
+
+// Get 获取概览数据，业务指标出错时返回错误，错误率、队列、任务状态出错时只记录日志，对应的项为空
+func (srv *overviewService) Get(ctx context.Context) (*Overview, error) {
+	// 缓存过期时只有一个请求去统计，其他请求等待结果
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.cached != nil && time.Now().Before(srv.expiredAt) {
+		return srv.cached, nil
+	}
+
+	ttl := viper.GetDuration("admin.overview_cache_ttl")
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	ret, err := srv.build(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+	srv.cached = ret
+	srv.expiredAt = time.Now().Add(ttl)
+	return ret, nil
+}
+
+func (srv *overviewService) build(ctx context.Context, ttl time.Duration) (*Overview, error) {
+	// kpi 统计任务在当前进程运行时直接使用它的结果
+	stats, err := kpi.Svc.Stats(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Overview{
+		Users:           stats.Users,
+		RegisteredToday: stats.RegisteredToday,
+		DAU:             stats.ActiveUsers["1d"],
+		FollowsToday:    stats.FollowsToday,
+		ErrorWindow:     DefaultErrorWindow.String(),
+		QueueBacklog:    make(map[string]int64, len(topics)),
+		FailedJobs:      make([]*FailedJob, 0),
+		GeneratedAt:     time.Now(),
+	}
+	ret.Requests, ret.ErrorRate = metrics.Default().ErrorRate(DefaultErrorWindow)
+
+	if err := queueStats(ctx, ret); err != nil {
+		log.Warnf("[overview_service] get queue stats err: %v", err)
+	}
+	if err := failedJobs(ret); err != nil {
+		log.Warnf("[overview_service] get failed jobs err: %v", err)
+	}
+	return ret, nil
+}
+
+// queueStats 统计各主题的积压和死信消息数
+func queueStats(ctx context.Context, ret *Overview) error {
+	if queue.Default == nil {
+		return nil
+	}
+
+	if b, err := queue.Backlogs(); err == nil {
+		for _, topic := range topics {
+			n, err := b.Backlog(ctx, topic)
+			if err != nil {
+				return err
+			}
+			ret.QueueBacklog[topic] = n
+		}
+	}
+
+	dl, err := queue.DeadLetters()
+	if err != nil {
+		return nil
+	}
+	deadTopics, err := dl.DeadTopics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range deadTopics {
+		ret.QueueDead += t.Count
+	}
+	return nil
+}
+
+// failedJobs 读取计划任务最近一次的执行状态，返回失败的任务
+func failedJobs(ret *Overview) error {
+	if redis.RedisClient == nil {
+		return nil
+	}
+
+	iter := redis.RedisClient.Scan(0, prefixJobStatus+"*", scanCount).Iterator()
+	for iter.Next() {
+		key := iter.Val()
+		data, err := redis.RedisClient.Get(key).Bytes()
+		if err != nil {
+			continue
+		}
+		var status struct {
+			Result     string    `json:"result"`
+			Error      string    `json:"error"`
+			StartedAt  time.Time `json:"started_at"`
+			FinishedAt time.Time `json:"finished_at"`
+		}
+		if err := json.Unmarshal(data, &status); err != nil || status.Result != jobStatusFailed {
+			continue
+		}
+		ret.FailedJobs = append(ret.FailedJobs, &FailedJob{
+			Name:       strings.TrimPrefix(key, prefixJobStatus),
+			Error:      status.Error,
+			StartedAt:  status.StartedAt,
+			FinishedAt: status.FinishedAt,
+		})
+	}
+	sort.Slice(ret.FailedJobs, func(i, j int) bool { return ret.FailedJobs[i].Name < ret.FailedJobs[j].Name })
+	return iter.Err()
+}
@@ -0,0 +1,166 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	roleCache "github.com/1024casts/snake/internal/cache/role"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/authz"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Service 权限服务接口定义
+type Service interface {
+	// GetRoles 获取用户的角色，配置在 admin.uids 中的用户总是拥有 admin 角色
+	GetRoles(ctx context.Context, userID uint64) ([]string, error)
+	// HasPermission 用户的角色中是否有角色包含该权限
+	HasPermission(ctx context.Context, userID uint64, perm authz.Permission) (bool, error)
+	// GrantRole 授予角色，角色需要在 authz.roles 中定义，已拥有时不做处理
+	GrantRole(ctx context.Context, userID uint64, role string, operatorID uint64, ip string) error
+	// RevokeRole 收回角色，配置在 admin.uids 中的用户的 admin 角色不能收回
+	RevokeRole(ctx context.Context, userID uint64, role string, operatorID uint64, ip string) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewPermissionService()
+
+type permissionService struct {
+	roleRepo  user.RoleRepo
+	userRepo  user.BaseRepo
+	roleCache *roleCache.Cache
+}
+
+// NewPermissionService 实例化一个权限服务
+func NewPermissionService() Service {
+	return &permissionService{
+		roleRepo:  user.NewUserRoleRepo(),
+		userRepo:  user.NewUserRepo(),
+		roleCache: roleCache.NewRoleCache(),
+	}
+}
+
+// GetRoles 先读缓存，缓存失败时不影响查询
+func (srv *permissionService) GetRoles(ctx context.Context, userID uint64) ([]string, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+
+	roles, ok, err := srv.roleCache.GetRoles(ctx, userID)
+	if err != nil {
+		log.Warnf("[permission_service] get roles from cache err: %v, uid: %d", err, userID)
+	}
+	if !ok {
+		list, err := srv.roleRepo.GetUserRoles(model.WithContext(ctx), userID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[permission_service] get user roles err, uid: %d", userID)
+		}
+		roles = make([]string, 0, len(list))
+		for _, r := range list {
+			roles = append(roles, r.Role)
+		}
+		if err := srv.roleCache.SetRoles(ctx, userID, roles); err != nil {
+			log.Warnf("[permission_service] set roles cache err: %v, uid: %d", err, userID)
+		}
+	}
+
+	if isConfigAdmin(userID) && !contains(roles, authz.RoleAdmin) {
+		roles = append([]string{authz.RoleAdmin}, roles...)
+	}
+	return roles, nil
+}
+
+// HasPermission 按当前的策略检查
+func (srv *permissionService) HasPermission(ctx context.Context, userID uint64, perm authz.Permission) (bool, error) {
+	roles, err := srv.GetRoles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return authz.Default().Allowed(roles, perm), nil
+}
+
+// GrantRole 授予角色并记录审计日志
+func (srv *permissionService) GrantRole(ctx context.Context, userID uint64, role string, operatorID uint64, ip string) error {
+	if !authz.Default().HasRole(role) {
+		return errno.ErrRoleNotFound
+	}
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), userID)
+	if err != nil {
+		return errors.Wrapf(err, "[permission_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+
+	added, err := srv.roleRepo.AddUserRole(model.WithContext(ctx), userID, role, operatorID)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return nil
+	}
+	srv.delCache(userID)
+
+	err = audit.Svc.Record(ctx, userID, operatorID, audit.ActionRoleGrant, ip, map[string]interface{}{
+		"role": role,
+	})
+	if err != nil {
+		log.Warnf("[permission_service] record audit log err: %v", err)
+	}
+	return nil
+}
+
+// RevokeRole 收回角色并记录审计日志，配置中已删除的角色也可以收回
+func (srv *permissionService) RevokeRole(ctx context.Context, userID uint64, role string, operatorID uint64, ip string) error {
+	if role == authz.RoleAdmin && isConfigAdmin(userID) {
+		return errno.ErrPermissionDenied
+	}
+
+	deleted, err := srv.roleRepo.DeleteUserRole(model.WithContext(ctx), userID, role)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errno.ErrRoleNotFound
+	}
+	srv.delCache(userID)
+
+	err = audit.Svc.Record(ctx, userID, operatorID, audit.ActionRoleRevoke, ip, map[string]interface{}{
+		"role": role,
+	})
+	if err != nil {
+		log.Warnf("[permission_service] record audit log err: %v", err)
+	}
+	return nil
+}
+
+func (srv *permissionService) delCache(userID uint64) {
+	if err := srv.roleCache.DelRoles(userID); err != nil {
+		log.Warnf("[permission_service] del roles cache err: %v, uid: %d", err, userID)
+	}
+}
+
+// isConfigAdmin 是否是配置在 admin.uids 中的管理员
+func isConfigAdmin(userID uint64) bool {
+	for _, uid := range cast.ToIntSlice(viper.Get("admin.uids")) {
+		if uint64(uid) == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package policy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	policyCache "github.com/1024casts/snake/internal/cache/policy"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/policy"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// latestTTL 本地缓存当前版本的时间，发布新版本后最多这么久在所有实例上生效
+const latestTTL = time.Minute
+
+// Service 协议服务接口定义
+type Service interface {
+	// Publish 发布新版本，发布后需要用户重新同意
+	Publish(ctx context.Context, adminID uint64, typ, version, title, content, ip string) (*model.PolicyModel, error)
+	// GetLatest 获取当前版本
+	GetLatest(ctx context.Context, typ string) (*model.PolicyModel, error)
+	GetPolicyList(ctx context.Context, typ string, lastID uint64, limit int) ([]*model.PolicyModel, error)
+	// Accept 同意协议，只能同意当前版本
+	Accept(ctx context.Context, userID uint64, typ, version, ip string) (*model.UserPolicyModel, error)
+	// Pending 获取用户还没有同意的当前版本，只检查配置 policy.required 中的类型
+	Pending(ctx context.Context, userID uint64) ([]*model.PolicyModel, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewPolicyService()
+
+type policyService struct {
+	policyRepo  policy.Repo
+	policyCache *policyCache.Cache
+
+	mu     sync.RWMutex
+	latest map[string]*latestPolicy
+}
+
+// latestPolicy 本地缓存的当前版本，没有发布过时 policy 为空结构体
+type latestPolicy struct {
+	policy   *model.PolicyModel
+	loadedAt time.Time
+}
+
+// NewPolicyService 实例化一个协议服务
+func NewPolicyService() Service {
+	return &policyService{
+		policyRepo:  policy.NewPolicyRepo(),
+		policyCache: policyCache.NewPolicyCache(),
+		latest:      make(map[string]*latestPolicy),
+	}
+}
+
+// Publish 发布新版本
+func (srv *policyService) Publish(ctx context.Context, adminID uint64, typ, version, title, content, ip string) (*model.PolicyModel, error) {
+	if !validType(typ) || version == "" || title == "" {
+		return nil, errno.ErrParam
+	}
+	old, err := srv.policyRepo.GetPolicyByVersion(model.WithContext(ctx), typ, version)
+	if err != nil {
+		return nil, err
+	}
+	if old.ID > 0 {
+		return nil, errno.ErrPolicyVersionExist
+	}
+
+	now := time.Now()
+	p := &model.PolicyModel{
+		Type:      typ,
+		Version:   version,
+		Title:     title,
+		Content:   content,
+		CreatedBy: adminID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := srv.policyRepo.CreatePolicy(model.WithContext(ctx), p); err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	delete(srv.latest, typ)
+	srv.mu.Unlock()
+
+	err = audit.Svc.Record(ctx, 0, adminID, audit.ActionPolicyPublish, ip, map[string]interface{}{
+		"policy_id": p.ID,
+		"type":      typ,
+		"version":   version,
+	})
+	if err != nil {
+		log.Warnf("[policy_service] record audit log err: %v", err)
+	}
+	return p, nil
+}
+
+// GetLatest 获取当前版本
+func (srv *policyService) GetLatest(ctx context.Context, typ string) (*model.PolicyModel, error) {
+	if !validType(typ) {
+		return nil, errno.ErrPolicyNotFound
+	}
+	p, err := srv.latestPolicy(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+	if p.ID == 0 {
+		return nil, errno.ErrPolicyNotFound
+	}
+	return p, nil
+}
+
+// GetPolicyList 获取历史版本
+func (srv *policyService) GetPolicyList(ctx context.Context, typ string, lastID uint64, limit int) ([]*model.PolicyModel, error) {
+	policies, err := srv.policyRepo.GetPolicyList(model.WithContext(ctx), typ, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[policy_service] get policy list err, type: %s", typ)
+	}
+	return policies, nil
+}
+
+// Accept 同意协议，重复同意时返回之前的记录
+func (srv *policyService) Accept(ctx context.Context, userID uint64, typ, version, ip string) (*model.UserPolicyModel, error) {
+	p, err := srv.GetLatest(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != version {
+		return nil, errno.ErrPolicyOutdated
+	}
+
+	up, err := srv.policyRepo.GetUserPolicy(model.WithContext(ctx), userID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if up.ID == 0 {
+		up = &model.UserPolicyModel{
+			UserID:     userID,
+			PolicyID:   p.ID,
+			Type:       p.Type,
+			Version:    p.Version,
+			IP:         ip,
+			AcceptedAt: time.Now(),
+		}
+		if err := srv.policyRepo.CreateUserPolicy(model.WithContext(ctx), up); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := srv.policyCache.SetAccepted(userID, p.ID, true); err != nil {
+		log.Warnf("[policy_service] set accepted cache err: %v, uid: %d", err, userID)
+	}
+	return up, nil
+}
+
+// Pending 获取用户还没有同意的当前版本
+func (srv *policyService) Pending(ctx context.Context, userID uint64) ([]*model.PolicyModel, error) {
+	pending := make([]*model.PolicyModel, 0)
+	for _, typ := range viper.GetStringSlice("policy.required") {
+		p, err := srv.latestPolicy(ctx, typ)
+		if err != nil {
+			return nil, err
+		}
+		if p.ID == 0 {
+			continue
+		}
+
+		accepted, err := srv.accepted(ctx, userID, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !accepted {
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+// accepted 用户是否同意了某个版本，先查缓存
+func (srv *policyService) accepted(ctx context.Context, userID, policyID uint64) (bool, error) {
+	accepted, ok, err := srv.policyCache.GetAccepted(userID, policyID)
+	if err != nil {
+		log.Warnf("[policy_service] get accepted cache err: %v, uid: %d", err, userID)
+	}
+	if ok {
+		return accepted, nil
+	}
+
+	up, err := srv.policyRepo.GetUserPolicy(model.WithContext(ctx), userID, policyID)
+	if err != nil {
+		return false, errors.Wrapf(err, "[policy_service] get user policy err, uid: %d", userID)
+	}
+	accepted = up.ID > 0
+	if err := srv.policyCache.SetAccepted(userID, policyID, accepted); err != nil {
+		log.Warnf("[policy_service] set accepted cache err: %v, uid: %d", err, userID)
+	}
+	return accepted, nil
+}
+
+// latestPolicy 获取当前版本，本地缓存 latestTTL
+func (srv *policyService) latestPolicy(ctx context.Context, typ string) (*model.PolicyModel, error) {
+	srv.mu.RLock()
+	l, ok := srv.latest[typ]
+	srv.mu.RUnlock()
+	if ok && time.Since(l.loadedAt) < latestTTL {
+		return l.policy, nil
+	}
+
+	p, err := srv.policyRepo.GetLatestPolicy(model.WithContext(ctx), typ)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[policy_service] get latest policy err, type: %s", typ)
+	}
+	srv.mu.Lock()
+	srv.latest[typ] = &latestPolicy{policy: p, loadedAt: time.Now()}
+	srv.mu.Unlock()
+	return p, nil
+}
+
+func validType(typ string) bool {
+	for _, t := range model.PolicyTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
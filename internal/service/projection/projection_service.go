@@ -0,0 +1,115 @@
+package projection
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// replayBatchSize 重放时每次读取的事件数
+const replayBatchSize = 500
+
+// Projector 根据用户事件更新的数据视图，Apply 需要可以重复执行
+type Projector interface {
+	Name() string
+	Apply(ctx context.Context, event *model.UserEventModel) error
+}
+
+// Service 投影服务接口定义
+type Service interface {
+	// Apply 把事件应用到所有投影，由 worker 消费用户事件时调用
+	Apply(ctx context.Context, event *model.UserEventModel) error
+	// Replay 从偏移量 fromID(不含) 开始重放事件到指定的投影，names 为空时重放所有投影
+	// 返回最后处理的偏移量，中断后可以从该偏移量继续
+	Replay(ctx context.Context, fromID uint64, names []string) (lastID uint64, err error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewProjectionService()
+
+type projectionService struct {
+	userEventRepo user.EventRepo
+	projectors    []Projector
+}
+
+// NewProjectionService 实例化一个投影服务
+func NewProjectionService() Service {
+	userRepo := user.NewUserRepo()
+	return &projectionService{
+		userEventRepo: user.NewUserEventRepo(),
+		projectors: []Projector{
+			newCacheProjector(userRepo),
+			newSearchProjector(userRepo),
+			newFeedProjector(),
+		},
+	}
+}
+
+// Apply 某个投影失败时继续处理其他投影，最后返回错误让消息重试
+func (srv *projectionService) Apply(ctx context.Context, event *model.UserEventModel) error {
+	return srv.apply(ctx, srv.projectors, event)
+}
+
+// Replay 按偏移量顺序重放
+func (srv *projectionService) Replay(ctx context.Context, fromID uint64, names []string) (uint64, error) {
+	projectors, err := srv.selectProjectors(names)
+	if err != nil {
+		return fromID, err
+	}
+
+	lastID := fromID
+	for {
+		if err := ctx.Err(); err != nil {
+			return lastID, err
+		}
+		events, err := srv.userEventRepo.GetUserEvents(model.WithContext(ctx), lastID, replayBatchSize)
+		if err != nil {
+			return lastID, errors.Wrapf(err, "[projection_service] get user events err, after: %d", lastID)
+		}
+		if len(events) == 0 {
+			return lastID, nil
+		}
+		for _, event := range events {
+			if err := srv.apply(ctx, projectors, event); err != nil {
+				return lastID, err
+			}
+			lastID = event.ID
+		}
+		log.Infof("[projection_service] replayed to offset: %d", lastID)
+	}
+}
+
+func (srv *projectionService) apply(ctx context.Context, projectors []Projector, event *model.UserEventModel) error {
+	var lastErr error
+	for _, p := range projectors {
+		if err := p.Apply(ctx, event); err != nil {
+			log.Warnf("[projection_service] apply %s err: %v, event id: %d", p.Name(), err, event.ID)
+			lastErr = errors.Wrapf(err, "[projection_service] apply %s err, event id: %d", p.Name(), event.ID)
+		}
+	}
+	return lastErr
+}
+
+func (srv *projectionService) selectProjectors(names []string) ([]Projector, error) {
+	if len(names) == 0 {
+		return srv.projectors, nil
+	}
+	byName := make(map[string]Projector, len(srv.projectors))
+	for _, p := range srv.projectors {
+		byName[p.Name()] = p
+	}
+	projectors := make([]Projector, 0, len(names))
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, errno.WithKind(errno.KindInvalid, errors.Errorf("[projection_service] unknown projection: %s", name))
+		}
+		projectors = append(projectors, p)
+	}
+	return projectors, nil
+}
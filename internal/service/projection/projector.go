@@ -0,0 +1,122 @@
+package projection
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/spf13/viper"
+
+	cacheuser "github.com/1024casts/snake/internal/cache/user"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/search"
+)
+
+// 投影名称，重放时用于指定投影
+const (
+	// NameCache 用户信息缓存
+	NameCache = "cache"
+	// NameSearch 用户搜索索引
+	NameSearch = "search"
+	// NameFeed 用户动态
+	NameFeed = "feed"
+
+	defaultUserIndex = "snake_user"
+)
+
+// profileChanged 是否是改变用户资料的事件，停用后按id读取为空，缓存写入空对象，索引删除文档
+func profileChanged(eventType string) bool {
+	switch eventType {
+	case model.UserEventCreated, model.UserEventUpdated, model.UserEventDeactivated, model.UserEventReactivated:
+		return true
+	}
+	return false
+}
+
+// cacheProjector 资料变化后从数据库重新加载用户缓存
+type cacheProjector struct {
+	userRepo user.BaseRepo
+}
+
+func newCacheProjector(userRepo user.BaseRepo) Projector {
+	return &cacheProjector{userRepo: userRepo}
+}
+
+func (p *cacheProjector) Name() string {
+	return NameCache
+}
+
+func (p *cacheProjector) Apply(ctx context.Context, event *model.UserEventModel) error {
+	if !profileChanged(event.EventType) {
+		return nil
+	}
+	return p.userRepo.RefreshUserCache(model.WithContext(ctx), event.UserID)
+}
+
+// userDocument 搜索索引中的用户文档
+type userDocument struct {
+	ID       uint64 `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Bio      string `json:"bio"`
+	Region   string `json:"region"`
+}
+
+// searchProjector 使用数据库中的最新数据写入索引，重放旧事件也不会写入过期的资料
+type searchProjector struct {
+	userRepo user.BaseRepo
+}
+
+func newSearchProjector(userRepo user.BaseRepo) Projector {
+	return &searchProjector{userRepo: userRepo}
+}
+
+func (p *searchProjector) Name() string {
+	return NameSearch
+}
+
+func (p *searchProjector) Apply(ctx context.Context, event *model.UserEventModel) error {
+	if search.Client == nil {
+		return nil
+	}
+	if !profileChanged(event.EventType) {
+		return nil
+	}
+
+	index := viper.GetString("search.user_index")
+	if index == "" {
+		index = defaultUserIndex
+	}
+	id := strconv.FormatUint(event.UserID, 10)
+	u, err := p.userRepo.GetUserByID(model.WithContext(ctx), event.UserID)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.ID == 0 {
+		return search.Client.Delete(ctx, index, id)
+	}
+	return search.Client.Index(ctx, index, id, &userDocument{
+		ID:       u.ID,
+		Username: u.Username,
+		Avatar:   u.Avatar,
+		Bio:      u.Bio,
+		Region:   u.Region,
+	})
+}
+
+// feedProjector 把事件写入用户的动态
+type feedProjector struct {
+	userCache *cacheuser.Cache
+}
+
+func newFeedProjector() Projector {
+	return &feedProjector{userCache: cacheuser.NewUserCache()}
+}
+
+func (p *feedProjector) Name() string {
+	return NameFeed
+}
+
+func (p *feedProjector) Apply(ctx context.Context, event *model.UserEventModel) error {
+	return p.userCache.AddUserFeed(event)
+}
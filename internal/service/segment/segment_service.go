@@ -0,0 +1,258 @@
+package segment
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/cache/segment"
+	"github.com/1024casts/snake/internal/model"
+	repo "github.com/1024casts/snake/internal/repository/segment"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// materializeBatchSize 计算分群时每批查询的用户数
+const materializeBatchSize = 1000
+
+// TaskTypeMaterialize 手动触发计算分群的异步任务类型
+const TaskTypeMaterialize = "segment.materialize"
+
+// MaterializeTaskPayload 计算分群任务的参数
+type MaterializeTaskPayload struct {
+	SegmentID uint64 `json:"segment_id"`
+}
+
+// nameRegexp 分群名称，用于 redis key 和其他模块的配置中
+var nameRegexp = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// Service 用户分群服务接口定义
+type Service interface {
+	CreateSegment(ctx context.Context, adminID uint64, name, description string, rules *model.SegmentRules) (*model.SegmentModel, error)
+	UpdateSegment(ctx context.Context, id uint64, description string, rules *model.SegmentRules) error
+	GetSegment(ctx context.Context, id uint64) (*model.SegmentModel, error)
+	GetSegmentList(ctx context.Context) ([]*model.SegmentModel, error)
+
+	// MaterializeSegment 按规则计算分群的用户并保存到 redis
+	MaterializeSegment(ctx context.Context, id uint64) (int, error)
+	// MaterializeAll 计算所有分群，由定时任务调用
+	MaterializeAll(ctx context.Context) (int, error)
+
+	// 使用分群，只读取已经计算好的结果
+	IsMember(name string, userID uint64) bool
+	CountUsers(name string) (int, error)
+	ScanUserIDs(name string, lastID uint64, limit int) ([]uint64, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewSegmentService()
+
+type segmentService struct {
+	segmentRepo  repo.Repo
+	segmentCache *segment.Cache
+}
+
+// NewSegmentService 实例化一个用户分群服务
+func NewSegmentService() Service {
+	return &segmentService{
+		segmentRepo:  repo.NewSegmentRepo(),
+		segmentCache: segment.NewSegmentCache(),
+	}
+}
+
+// CreateSegment 创建分群，创建后立即计算一次
+func (srv *segmentService) CreateSegment(ctx context.Context, adminID uint64, name, description string, rules *model.SegmentRules) (*model.SegmentModel, error) {
+	if !nameRegexp.MatchString(name) || rules == nil {
+		return nil, errno.ErrParam
+	}
+
+	s, err := srv.segmentRepo.GetSegmentByName(model.WithContext(ctx), name)
+	if err != nil {
+		return nil, errors.Wrap(err, "[segment_service] get segment by name err")
+	}
+	if s.ID > 0 {
+		return nil, errno.ErrSegmentExist
+	}
+
+	rulesStr, err := json.Marshal(rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "[segment_service] marshal rules err")
+	}
+	now := time.Now()
+	s = &model.SegmentModel{
+		Name:        name,
+		Description: description,
+		Rules:       string(rulesStr),
+		CreatedBy:   adminID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := srv.segmentRepo.CreateSegment(model.WithContext(ctx), s); err != nil {
+		return nil, errors.Wrap(err, "[segment_service] create segment err")
+	}
+
+	if err := srv.materialize(ctx, s); err != nil {
+		log.Warnf("[segment_service] materialize segment err: %v, name: %s", err, name)
+	}
+	return s, nil
+}
+
+// UpdateSegment 修改分群规则，修改后立即重新计算
+func (srv *segmentService) UpdateSegment(ctx context.Context, id uint64, description string, rules *model.SegmentRules) error {
+	if rules == nil {
+		return errno.ErrParam
+	}
+	s, err := srv.GetSegment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rulesStr, err := json.Marshal(rules)
+	if err != nil {
+		return errors.Wrap(err, "[segment_service] marshal rules err")
+	}
+	err = srv.segmentRepo.UpdateSegment(model.WithContext(ctx), id, map[string]interface{}{
+		"description": description,
+		"rules":       string(rulesStr),
+		"updated_at":  time.Now(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[segment_service] update segment err, id: %d", id)
+	}
+
+	s.Rules = string(rulesStr)
+	if err := srv.materialize(ctx, s); err != nil {
+		log.Warnf("[segment_service] materialize segment err: %v, name: %s", err, s.Name)
+	}
+	return nil
+}
+
+// GetSegment 获取分群
+func (srv *segmentService) GetSegment(ctx context.Context, id uint64) (*model.SegmentModel, error) {
+	s, err := srv.segmentRepo.GetSegment(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[segment_service] get segment err, id: %d", id)
+	}
+	if s.ID == 0 {
+		return nil, errno.ErrSegmentNotFound
+	}
+
+	return s, nil
+}
+
+// GetSegmentList 获取所有分群
+func (srv *segmentService) GetSegmentList(ctx context.Context) ([]*model.SegmentModel, error) {
+	segments, err := srv.segmentRepo.GetSegmentList(model.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "[segment_service] get segment list err")
+	}
+
+	return segments, nil
+}
+
+// MaterializeSegment 计算分群，返回分群的用户数
+func (srv *segmentService) MaterializeSegment(ctx context.Context, id uint64) (int, error) {
+	s, err := srv.GetSegment(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := srv.materialize(ctx, s); err != nil {
+		return 0, errors.Wrapf(err, "[segment_service] materialize segment err, name: %s", s.Name)
+	}
+
+	return s.UserCount, nil
+}
+
+// MaterializeAll 计算所有分群，某个分群失败不影响其他分群，返回计算成功的分群数
+func (srv *segmentService) MaterializeAll(ctx context.Context) (int, error) {
+	segments, err := srv.GetSegmentList(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, s := range segments {
+		if err := srv.materialize(ctx, s); err != nil {
+			log.Warnf("[segment_service] materialize segment err: %v, name: %s", err, s.Name)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// materialize 分批查询符合规则的用户写入临时集合，全部完成后替换分群集合，失败时删除临时集合
+func (srv *segmentService) materialize(ctx context.Context, s *model.SegmentModel) (err error) {
+	rules, err := s.GetRules()
+	if err != nil {
+		return errors.Wrap(err, "parse rules err")
+	}
+
+	buildingKey := srv.segmentCache.GetBuildingCacheKey(s.Name)
+	defer func() {
+		if err == nil {
+			return
+		}
+		if e := srv.segmentCache.DelBuilding(buildingKey); e != nil {
+			log.Warnf("[segment_service] del building segment err: %v, key: %s", e, buildingKey)
+		}
+	}()
+	var lastID uint64
+	total := 0
+	for {
+		userIDs, err := srv.segmentRepo.ScanSegmentUserIDs(model.WithContext(ctx), rules, lastID, materializeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+		if err := srv.segmentCache.AddMembers(buildingKey, userIDs); err != nil {
+			return err
+		}
+		lastID = userIDs[len(userIDs)-1]
+		total += len(userIDs)
+	}
+	if err := srv.segmentCache.Replace(s.Name, buildingKey); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.UserCount = total
+	s.MaterializedAt = &now
+	return srv.segmentRepo.UpdateSegment(model.WithContext(ctx), s.ID, map[string]interface{}{
+		"user_count":      total,
+		"materialized_at": now,
+	})
+}
+
+// IsMember 用户是否在分群中，出错时当作不在分群中
+func (srv *segmentService) IsMember(name string, userID uint64) bool {
+	ok, err := srv.segmentCache.IsMember(name, userID)
+	if err != nil {
+		log.Warnf("[segment_service] check segment member err: %v, name: %s", err, name)
+		return false
+	}
+	return ok
+}
+
+// CountUsers 分群的用户数
+func (srv *segmentService) CountUsers(name string) (int, error) {
+	count, err := srv.segmentCache.Count(name)
+	if err != nil {
+		return 0, errors.Wrapf(err, "[segment_service] count segment users err, name: %s", name)
+	}
+	return int(count), nil
+}
+
+// ScanUserIDs 按id正序分批获取分群中的用户
+func (srv *segmentService) ScanUserIDs(name string, lastID uint64, limit int) ([]uint64, error) {
+	userIDs, err := srv.segmentCache.Range(name, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[segment_service] get segment members err, name: %s", name)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,222 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/task"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// TopicTaskCreated 任务创建后由 worker 执行
+const TopicTaskCreated = "task.created"
+
+// CreatedEvent 任务创建事件
+type CreatedEvent struct {
+	TaskID uint64 `json:"task_id"`
+	Type   string `json:"type"`
+}
+
+// Reporter 执行中上报进度
+type Reporter interface {
+	// Progress 上报进度百分比，执行中最多为99，完成后自动设置为100
+	Progress(percent int) error
+}
+
+// Runner 任务的执行函数，返回的结果会被序列化为json保存，返回错误时任务失败
+type Runner func(ctx context.Context, t *model.TaskModel, r Reporter) (result interface{}, err error)
+
+var (
+	runnersMu sync.RWMutex
+	runners   = make(map[string]Runner)
+)
+
+// Register 注册任务类型的执行函数，由执行任务的进程(worker)在启动时注册
+func Register(typ string, runner Runner) {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	runners[typ] = runner
+}
+
+func getRunner(typ string) (Runner, bool) {
+	runnersMu.RLock()
+	defer runnersMu.RUnlock()
+	r, ok := runners[typ]
+	return r, ok
+}
+
+// Service 异步任务服务接口定义
+type Service interface {
+	// Submit 创建任务并投递到队列，立即返回任务
+	Submit(ctx context.Context, typ string, ownerID uint64, payload interface{}) (*model.TaskModel, error)
+	GetTask(ctx context.Context, id uint64) (*model.TaskModel, error)
+	GetTaskList(ctx context.Context, where map[string]interface{}, lastID uint64, limit int) ([]*model.TaskModel, error)
+	// Run 执行任务，由 worker 调用，已完成的任务不会重复执行
+	Run(ctx context.Context, id uint64) error
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewTaskService()
+
+type taskService struct {
+	taskRepo task.Repo
+}
+
+// NewTaskService 实例化一个异步任务服务
+func NewTaskService() Service {
+	return &taskService{
+		taskRepo: task.NewTaskRepo(),
+	}
+}
+
+// Submit 创建任务并投递到队列
+func (srv *taskService) Submit(ctx context.Context, typ string, ownerID uint64, payload interface{}) (*model.TaskModel, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "[task_service] marshal payload err")
+	}
+
+	now := time.Now()
+	t := &model.TaskModel{
+		Type:      typ,
+		OwnerID:   ownerID,
+		Payload:   string(b),
+		Status:    model.TaskStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := srv.taskRepo.CreateTask(model.WithContext(ctx), t); err != nil {
+		return nil, err
+	}
+
+	err = queue.Publish(ctx, TopicTaskCreated, &CreatedEvent{TaskID: t.ID, Type: typ})
+	if err != nil {
+		srv.fail(t.ID, err)
+		return nil, errors.Wrapf(err, "[task_service] publish task err, id: %d", t.ID)
+	}
+	return t, nil
+}
+
+// GetTask 获取任务
+func (srv *taskService) GetTask(ctx context.Context, id uint64) (*model.TaskModel, error) {
+	t, err := srv.taskRepo.GetTask(model.WithContext(ctx), id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[task_service] get task err, id: %d", id)
+	}
+	if t.ID == 0 {
+		return nil, errno.ErrTaskNotFound
+	}
+	return t, nil
+}
+
+// GetTaskList 获取任务列表
+func (srv *taskService) GetTaskList(ctx context.Context, where map[string]interface{}, lastID uint64, limit int) ([]*model.TaskModel, error) {
+	tasks, err := srv.taskRepo.GetTaskList(model.WithContext(ctx), where, lastID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "[task_service] get task list err")
+	}
+	return tasks, nil
+}
+
+// Run 执行任务，执行中的任务被重复投递时(如 worker 重启)重新执行
+func (srv *taskService) Run(ctx context.Context, id uint64) error {
+	t, err := srv.taskRepo.GetTask(model.WithContext(ctx), id)
+	if err != nil {
+		return err
+	}
+	if t.ID == 0 || t.IsFinished() {
+		return nil
+	}
+	runner, ok := getRunner(t.Type)
+	if !ok {
+		srv.fail(t.ID, fmt.Errorf("unknown task type: %s", t.Type))
+		return nil
+	}
+
+	now := time.Now()
+	ok, err = srv.taskRepo.UpdateTaskStatus(model.WithContext(ctx), t.ID,
+		[]int{model.TaskStatusPending, model.TaskStatusRunning}, map[string]interface{}{
+			"status":     model.TaskStatusRunning,
+			"progress":   0,
+			"started_at": now,
+			"updated_at": now,
+		})
+	if err != nil || !ok {
+		return err
+	}
+	t.Status = model.TaskStatusRunning
+
+	result, err := runner(ctx, t, &reporter{ctx: ctx, srv: srv, id: t.ID})
+	if err != nil {
+		log.Warnf("[task_service] run task err: %v, id: %d, type: %s", err, t.ID, t.Type)
+		srv.fail(t.ID, err)
+		return nil
+	}
+	return srv.succeed(ctx, t.ID, result)
+}
+
+// succeed 执行成功，保存结果
+func (srv *taskService) succeed(ctx context.Context, id uint64, result interface{}) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		srv.fail(id, err)
+		return nil
+	}
+	now := time.Now()
+	_, err = srv.taskRepo.UpdateTaskStatus(model.WithContext(ctx), id, []int{model.TaskStatusRunning}, map[string]interface{}{
+		"status":      model.TaskStatusSucceeded,
+		"progress":    100,
+		"result":      string(b),
+		"finished_at": now,
+		"updated_at":  now,
+	})
+	return err
+}
+
+// fail 执行失败，记录失败原因，任务可能因为 ctx 超时失败，不使用 ctx，确保失败状态能写入
+func (srv *taskService) fail(id uint64, cause error) {
+	msg := cause.Error()
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+	now := time.Now()
+	_, err := srv.taskRepo.UpdateTaskStatus(model.GetDB(), id,
+		[]int{model.TaskStatusPending, model.TaskStatusRunning}, map[string]interface{}{
+			"status":      model.TaskStatusFailed,
+			"error":       msg,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	if err != nil {
+		log.Warnf("[task_service] update failed task err: %v, id: %d", err, id)
+	}
+}
+
+// reporter 更新执行中任务的进度
+type reporter struct {
+	ctx context.Context
+	srv *taskService
+	id  uint64
+}
+
+// Progress 上报进度
+func (r *reporter) Progress(percent int) error {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 99 {
+		percent = 99
+	}
+	return r.srv.taskRepo.UpdateTask(model.WithContext(r.ctx), r.id, map[string]interface{}{
+		"progress":   percent,
+		"updated_at": time.Now(),
+	})
+}
@@ -0,0 +1,195 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	cacheupload "github.com/1024casts/snake/internal/cache/upload"
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// CategoryMedia 音视频等大文件
+const CategoryMedia = "media"
+
+const (
+	// defaultMaxFileSize 分片上传默认最大 2GB
+	defaultMaxFileSize = 2 << 30
+	// defaultMultipartTTL 分片上传需要在一天内完成
+	defaultMultipartTTL = 24 * time.Hour
+)
+
+// extRegexp 只保留常见的扩展名字符
+var extRegexp = regexp.MustCompile(`^\.[a-zA-Z0-9]{1,10}$`)
+
+// InitMultipart 初始化分片上传，分片大小固定，客户端按 PartSize 切分文件
+func (srv *uploadService) InitMultipart(ctx context.Context, userID uint64, filename, contentType string, size int64) (*cacheupload.MultipartUpload, error) {
+	maxSize := viper.GetInt64("upload.max_file_size")
+	if maxSize <= 0 {
+		maxSize = defaultMaxFileSize
+	}
+	if size <= 0 {
+		return nil, errno.ErrParam
+	}
+	if size > maxSize {
+		return nil, errno.ErrUploadTooLarge
+	}
+
+	m, err := storage.Multipart(storage.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+	if !extRegexp.MatchString(ext) {
+		ext = ""
+	}
+	key := fmt.Sprintf("%s/%d/%s%s", CategoryMedia, userID, uuid.New().String(), ext)
+	uploadID, err := m.InitMultipart(ctx, key, contentType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] init multipart err, key: %s", key)
+	}
+
+	ttl := viper.GetDuration("upload.multipart_ttl")
+	if ttl <= 0 {
+		ttl = defaultMultipartTTL
+	}
+	upload := &cacheupload.MultipartUpload{
+		UploadID:    uploadID,
+		UserID:      userID,
+		Category:    CategoryMedia,
+		Key:         key,
+		Filename:    path.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+		PartSize:    storage.PartSize,
+		TotalParts:  int((size + storage.PartSize - 1) / storage.PartSize),
+		ExpiredAt:   time.Now().Add(ttl),
+	}
+	if err := srv.multipartCache.SetUpload(upload, ttl); err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] save multipart upload err, upload_id: %s", uploadID)
+	}
+
+	return upload, nil
+}
+
+// UploadPart 上传分片，除最后一片外大小必须等于 PartSize
+// 分片可以乱序、重复上传，失败后重新上传该分片即可
+func (srv *uploadService) UploadPart(ctx context.Context, userID uint64, uploadID string, number int, r io.Reader, size int64) (*storage.Part, error) {
+	upload, err := srv.getMultipart(userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if number < 1 || number > upload.TotalParts || size != partSize(upload, number) {
+		return nil, errno.ErrUploadPart
+	}
+
+	m, err := storage.Multipart(storage.Default)
+	if err != nil {
+		return nil, err
+	}
+	etag, err := m.UploadPart(ctx, upload.Key, uploadID, number, io.LimitReader(r, size), size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] upload part err, upload_id: %s, part: %d", uploadID, number)
+	}
+
+	part := &storage.Part{Number: number, ETag: etag, Size: size}
+	if err := srv.multipartCache.SetPart(uploadID, part); err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] save part err, upload_id: %s, part: %d", uploadID, number)
+	}
+
+	return part, nil
+}
+
+// GetMultipart 获取上传信息和已上传的分片，用于断点续传
+func (srv *uploadService) GetMultipart(userID uint64, uploadID string) (*cacheupload.MultipartUpload, []storage.Part, error) {
+	upload, err := srv.getMultipart(userID, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parts, err := srv.multipartCache.GetParts(uploadID)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "[upload_service] get parts err, upload_id: %s", uploadID)
+	}
+	return upload, parts, nil
+}
+
+// CompleteMultipart 所有分片上传后合并为一个文件
+func (srv *uploadService) CompleteMultipart(ctx context.Context, userID uint64, uploadID string) (*File, error) {
+	upload, parts, err := srv.GetMultipart(userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != upload.TotalParts {
+		return nil, errno.ErrUploadIncomplete
+	}
+
+	m, err := storage.Multipart(storage.Default)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CompleteMultipart(ctx, upload.Key, uploadID, parts); err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] complete multipart err, upload_id: %s", uploadID)
+	}
+
+	if err := srv.multipartCache.Delete(uploadID); err != nil {
+		log.Warnf("[upload_service] delete multipart upload err: %v, upload_id: %s", err, uploadID)
+	}
+
+	// 合并后才能计算哈希，内容重复时返回已有的文件
+	f, err := file.Svc.Track(ctx, userID, upload.Category, upload.Key, upload.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Key: f.StorageKey, URL: storage.Default.URL(f.StorageKey), Size: f.Size, ContentType: f.ContentType}, nil
+}
+
+// AbortMultipart 取消上传，删除已上传的分片
+func (srv *uploadService) AbortMultipart(ctx context.Context, userID uint64, uploadID string) error {
+	upload, err := srv.getMultipart(userID, uploadID)
+	if err != nil {
+		return err
+	}
+
+	m, err := storage.Multipart(storage.Default)
+	if err != nil {
+		return err
+	}
+	if err := m.AbortMultipart(ctx, upload.Key, uploadID); err != nil {
+		return errors.Wrapf(err, "[upload_service] abort multipart err, upload_id: %s", uploadID)
+	}
+
+	return srv.multipartCache.Delete(uploadID)
+}
+
+// getMultipart 获取自己的上传，过期或不属于该用户时返回不存在
+func (srv *uploadService) getMultipart(userID uint64, uploadID string) (*cacheupload.MultipartUpload, error) {
+	upload, err := srv.multipartCache.GetUpload(uploadID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] get multipart upload err, upload_id: %s", uploadID)
+	}
+	if upload == nil || upload.UserID != userID {
+		return nil, errno.ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// partSize 分片应有的大小
+func partSize(upload *cacheupload.MultipartUpload, number int) int64 {
+	if number < upload.TotalParts {
+		return upload.PartSize
+	}
+	return upload.Size - upload.PartSize*int64(upload.TotalParts-1)
+}
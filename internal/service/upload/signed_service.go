@@ -0,0 +1,107 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// defaultSignedURLTTL 签名地址默认15分钟内有效
+const defaultSignedURLTTL = 15 * time.Minute
+
+// SignedURL 签名地址，客户端直接请求存储
+type SignedURL struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiredAt time.Time         `json:"expired_at"`
+}
+
+// PresignUpload 生成直传的签名地址，文件不经过 API 服务
+func (srv *uploadService) PresignUpload(ctx context.Context, userID uint64, filename, contentType string, size int64) (*SignedURL, error) {
+	maxSize := viper.GetInt64("upload.max_file_size")
+	if maxSize <= 0 {
+		maxSize = defaultMaxFileSize
+	}
+	if size <= 0 {
+		return nil, errno.ErrParam
+	}
+	if size > maxSize {
+		return nil, errno.ErrUploadTooLarge
+	}
+
+	ext := strings.ToLower(path.Ext(filename))
+	if !extRegexp.MatchString(ext) {
+		ext = ""
+	}
+	key := fmt.Sprintf("%s/%d/%s%s", CategoryMedia, userID, uuid.New().String(), ext)
+
+	ttl := signedURLTTL()
+	url, err := storage.SignURL(http.MethodPut, key, ttl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] sign put url err, key: %s", key)
+	}
+
+	signed := &SignedURL{Key: key, URL: url, Method: http.MethodPut, ExpiredAt: time.Now().Add(ttl)}
+	if contentType != "" {
+		signed.Headers = map[string]string{"Content-Type": contentType}
+	}
+	return signed, nil
+}
+
+// CompletePresignedUpload 直传不经过 API 服务，需要客户端上传完成后通知
+func (srv *uploadService) CompletePresignedUpload(ctx context.Context, userID uint64, key, contentType string) (*File, error) {
+	if !isOwner(userID, key) {
+		return nil, errno.ErrPermissionDenied
+	}
+
+	f, err := file.Svc.Track(ctx, userID, CategoryMedia, key, contentType)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return nil, errno.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &File{Key: f.StorageKey, URL: storage.Default.URL(f.StorageKey), Size: f.Size, ContentType: f.ContentType}, nil
+}
+
+// SignDownloadURL 生成私有文件的下载地址，只能下载自己上传的文件
+func (srv *uploadService) SignDownloadURL(userID uint64, key string) (*SignedURL, error) {
+	if !isOwner(userID, key) {
+		return nil, errno.ErrPermissionDenied
+	}
+
+	ttl := signedURLTTL()
+	url, err := storage.SignURL(http.MethodGet, key, ttl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] sign get url err, key: %s", key)
+	}
+
+	return &SignedURL{Key: key, URL: url, Method: http.MethodGet, ExpiredAt: time.Now().Add(ttl)}, nil
+}
+
+// isOwner 文件路径为 分类/用户id/文件名
+func isOwner(userID uint64, key string) bool {
+	parts := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	return len(parts) >= 3 && parts[1] == strconv.FormatUint(userID, 10) && !strings.Contains(key, "..")
+}
+
+func signedURLTTL() time.Duration {
+	if ttl := viper.GetDuration("upload.signed_url_ttl"); ttl > 0 {
+		return ttl
+	}
+	return defaultSignedURLTTL
+}
@@ -0,0 +1,147 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	cacheupload "github.com/1024casts/snake/internal/cache/upload"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/storage"
+)
+
+// TopicImageUploaded 图片上传完成的消息主题，由 worker 生成缩略图
+const TopicImageUploaded = "image.uploaded"
+
+// CategoryAvatar 头像
+const CategoryAvatar = "avatar"
+
+// defaultMaxImageSize 默认最大 5MB
+const defaultMaxImageSize = 5 << 20
+
+// imageTypes 允许上传的图片类型和扩展名
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ImageUploadedEvent 图片上传完成的消息内容
+type ImageUploadedEvent struct {
+	UserID   uint64 `json:"user_id"`
+	Category string `json:"category"`
+	Key      string `json:"key"`
+}
+
+// File 上传后的文件
+type File struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// Service 上传服务接口定义
+type Service interface {
+	// UploadImage 上传图片，上传后异步生成缩略图
+	UploadImage(ctx context.Context, userID uint64, category string, r io.Reader, size int64) (*File, error)
+
+	// 分片上传，用于大文件和弱网环境下的断点续传
+	InitMultipart(ctx context.Context, userID uint64, filename, contentType string, size int64) (*cacheupload.MultipartUpload, error)
+	UploadPart(ctx context.Context, userID uint64, uploadID string, number int, r io.Reader, size int64) (*storage.Part, error)
+	GetMultipart(userID uint64, uploadID string) (*cacheupload.MultipartUpload, []storage.Part, error)
+	CompleteMultipart(ctx context.Context, userID uint64, uploadID string) (*File, error)
+	AbortMultipart(ctx context.Context, userID uint64, uploadID string) error
+
+	// 签名地址，客户端直接上传、下载，不经过 API 服务
+	PresignUpload(ctx context.Context, userID uint64, filename, contentType string, size int64) (*SignedURL, error)
+	// CompletePresignedUpload 直传完成后记录文件
+	CompletePresignedUpload(ctx context.Context, userID uint64, key, contentType string) (*File, error)
+	SignDownloadURL(userID uint64, key string) (*SignedURL, error)
+}
+
+// Svc 直接初始化，可以避免在使用时再实例化
+var Svc = NewUploadService()
+
+type uploadService struct {
+	multipartCache *cacheupload.MultipartCache
+}
+
+// NewUploadService 实例化一个上传服务
+func NewUploadService() Service {
+	return &uploadService{
+		multipartCache: cacheupload.NewMultipartCache(),
+	}
+}
+
+// UploadImage 按文件内容判断类型，不信任客户端传的扩展名和 Content-Type
+// 内容相同的图片直接返回已上传的文件
+func (srv *uploadService) UploadImage(ctx context.Context, userID uint64, category string, r io.Reader, size int64) (*File, error) {
+	maxSize := viper.GetInt64("upload.max_image_size")
+	if maxSize <= 0 {
+		maxSize = defaultMaxImageSize
+	}
+	if size > maxSize {
+		return nil, errno.ErrUploadTooLarge
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "[upload_service] read file err")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errno.ErrUploadTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		return nil, errno.ErrUploadType
+	}
+
+	hash, size, err := file.Hash(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "[upload_service] hash file err")
+	}
+	dup, err := file.Svc.FindDuplicate(ctx, hash, size)
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		return &File{Key: dup.StorageKey, URL: storage.Default.URL(dup.StorageKey), Size: dup.Size, ContentType: dup.ContentType}, nil
+	}
+
+	key := fmt.Sprintf("%s/%d/%s%s", category, userID, uuid.New().String(), ext)
+	if err := storage.Default.Put(ctx, key, bytes.NewReader(data), size, contentType); err != nil {
+		return nil, errors.Wrapf(err, "[upload_service] put file err, key: %s", key)
+	}
+	err = file.Svc.Create(ctx, &model.FileModel{
+		UserID:      userID,
+		Category:    category,
+		StorageKey:  key,
+		Hash:        hash,
+		Size:        size,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 缩略图生成失败不影响上传，客户端会使用原图
+	err = queue.Publish(ctx, TopicImageUploaded, &ImageUploadedEvent{UserID: userID, Category: category, Key: key})
+	if err != nil {
+		log.Warnf("[upload_service] publish image uploaded err: %v, key: %s", err, key)
+	}
+
+	return &File{Key: key, URL: storage.Default.URL(key), Size: size, ContentType: contentType}, nil
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/tracing"
+)
+
+// TopicUserActivity 用户动作的消息主题，由 worker 归档到数据库并写入审计日志
+const TopicUserActivity = "user.activity"
+
+// recordActivity 记录用户动作，失败只记录日志，不影响登录、关注等主流程
+func (srv *userService) recordActivity(ctx context.Context, userID uint64, action, ip string, detail map[string]interface{}) {
+	a := &model.UserActivityModel{UserID: userID, Action: action, IP: ip}
+	if detail != nil {
+		b, err := json.Marshal(detail)
+		if err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] marshal activity detail err: %v, uid: %d", err, userID)
+			return
+		}
+		a.Detail = string(b)
+	}
+
+	if err := srv.userActivityRepo.AddActivity(a); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] add user activity err: %v", err)
+		return
+	}
+	// 发送失败时该动作只保留在 stream 中，被裁剪后无法再查到
+	if err := queue.Publish(ctx, TopicUserActivity, a); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] publish user activity err: %v, uid: %d, id: %d", err, userID, a.ActivityID)
+	}
+}
+
+// GetMyActivity 获取自己的动作记录，按时间倒序返回 activity_id 小于 beforeID 的记录，beforeID 为0时从最新的开始
+func (srv *userService) GetMyActivity(ctx context.Context, userID uint64, beforeID uint64, limit int) (_ []*model.UserActivityModel, err error) {
+	_, span := tracing.Start(ctx, "userService.GetMyActivity", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	activities, err := srv.userActivityRepo.GetActivityList(model.WithContext(ctx), userID, beforeID, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[user_service] get user activity err, uid: %d", userID)
+	}
+	return activities, nil
+}
+
+// ArchiveActivity 归档用户动作，首次归档时同时写入审计日志，供管理后台查看
+// 消息重复投递时不会重复写入
+func (srv *userService) ArchiveActivity(ctx context.Context, a *model.UserActivityModel) error {
+	created, err := srv.userActivityRepo.ArchiveActivity(model.WithContext(ctx), a)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] archive user activity err, uid: %d, id: %d", a.UserID, a.ActivityID)
+	}
+	if !created {
+		return nil
+	}
+
+	var detail interface{}
+	if a.Detail != "" {
+		detail = json.RawMessage(a.Detail)
+	}
+	return audit.Svc.Record(ctx, a.UserID, a.UserID, a.Action, a.IP, detail)
+}
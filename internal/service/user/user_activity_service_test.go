@@ -0,0 +1,80 @@
+package user
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+
+	usercache "github.com/1024casts/snake/internal/cache/user"
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 最近的动作从 stream 中查询，被裁剪的从数据库归档中查询，两部分连续分页
+func TestUserService_GetMyActivity(t *testing.T) {
+	// miniredis 不支持 stream，设置 SNAKE_TEST_REDIS_ADDR 后使用真实的 redis 测试
+	addr := os.Getenv("SNAKE_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("SNAKE_TEST_REDIS_ADDR not set")
+	}
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	client := redis.RedisClient
+	redis.RedisClient = goredis.NewClient(&goredis.Options{Addr: addr})
+	t.Cleanup(func() { redis.RedisClient = client })
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserActivityModel{}, &model.AuditLogModel{})
+	model.DB = db
+
+	userID := uint64(time.Now().UnixNano() % 1e12 * 10)
+	actions := []string{model.ActivityLogin, model.ActivityFollow, model.ActivityProfileUpdate}
+	ids := make([]uint64, 0, len(actions))
+	for _, action := range actions {
+		a := &model.UserActivityModel{UserID: userID, Action: action}
+		if err := srv.userActivityRepo.AddActivity(a); err != nil {
+			t.Fatal(err)
+		}
+		if created, err := srv.userActivityRepo.ArchiveActivity(db, a); err != nil || !created {
+			t.Fatalf("ArchiveActivity() = %v, %v", created, err)
+		}
+		ids = append(ids, a.ActivityID)
+	}
+	// 重复归档不会重复写入
+	if created, _ := srv.userActivityRepo.ArchiveActivity(db, &model.UserActivityModel{UserID: userID, ActivityID: ids[0]}); created {
+		t.Fatal("ArchiveActivity() archived twice")
+	}
+	// stream 中只保留最新的一条
+	key := (&usercache.Cache{}).GetUserActivityCacheKey(userID)
+	if err := redis.RedisClient.XTrim(key, 1).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []uint64
+	var before uint64
+	for {
+		activities, err := srv.GetMyActivity(context.Background(), userID, before, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activities) == 0 {
+			break
+		}
+		for _, a := range activities {
+			got = append(got, a.ActivityID)
+		}
+		before = activities[len(activities)-1].ActivityID
+	}
+	want := []uint64{ids[2], ids[1], ids[0]}
+	if len(got) != len(want) {
+		t.Fatalf("GetMyActivity() ids = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("GetMyActivity() ids = %v, want %v", got, want)
+		}
+	}
+}
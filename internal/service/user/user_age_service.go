@@ -0,0 +1,60 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/agegate"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// SetBirthday 设置生日，按用户所在地区校验最小年龄，设置后不能自己修改
+func (srv *userService) SetBirthday(ctx context.Context, userID uint64, birthday string) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	t, err := srv.checkBirthday(u, birthday)
+	if err != nil {
+		return err
+	}
+
+	err = srv.userRepo.Update(model.WithContext(ctx), userID, map[string]interface{}{"birthday": t})
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] set birthday err, uid: %d", userID)
+	}
+	return nil
+}
+
+// checkBirthday 校验生日，返回解析后的日期
+func (srv *userService) checkBirthday(u *model.UserBaseModel, birthday string) (time.Time, error) {
+	if u.Birthday != nil {
+		return time.Time{}, errno.ErrBirthdayAlreadySet
+	}
+
+	gate := agegate.Client
+	if gate == nil {
+		// 未开启年龄验证时只校验格式
+		gate = agegate.New(agegate.Config{})
+	}
+	t, err := gate.ParseBirthday(birthday, u.Region, srv.clock.Now())
+	switch err {
+	case nil:
+		return t, nil
+	case agegate.ErrTooYoung:
+		return time.Time{}, errno.ErrAgeTooYoung
+	default:
+		return time.Time{}, errno.ErrBirthdayInvalid
+	}
+}
+
+// HideFollowList 用户的关注、粉丝列表是否对他人隐藏，未成年人受限模式下隐藏
+func (srv *userService) HideFollowList(u *model.UserBaseModel) bool {
+	if agegate.Client == nil {
+		return false
+	}
+	return agegate.Client.HideFollowList(u.Birthday, u.Region, srv.clock.Now())
+}
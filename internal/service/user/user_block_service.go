@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/pagination"
+)
+
+// BlockUser 拉黑用户，同时取消双方之间的关注，之后双方都不能再关注对方
+func (srv *userService) BlockUser(ctx context.Context, userID uint64, blockedUID uint64) error {
+	if userID == blockedUID {
+		return errno.ErrParam
+	}
+	u, err := srv.GetUserByID(ctx, blockedUID)
+	if err != nil {
+		return err
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+
+	db := model.WithContext(ctx)
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := srv.userBlockRepo.CreateUserBlock(tx, userID, blockedUID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 只取消有效的关注，避免重复减少关注数和粉丝数
+	type unfollow struct {
+		from, to uint64
+		event    *model.UserEventModel
+	}
+	unfollows := make([]*unfollow, 0, 2)
+	for _, p := range [][2]uint64{{userID, blockedUID}, {blockedUID, userID}} {
+		following, err := srv.isFollowing(tx, p[0], p[1])
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if !following {
+			continue
+		}
+		event, err := srv.cancelFollow(tx, p[0], p[1])
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		unfollows = append(unfollows, &unfollow{from: p[0], to: p[1], event: event})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "tx commit err")
+	}
+	for _, f := range unfollows {
+		srv.afterUnfollow(ctx, f.event, f.from, f.to)
+	}
+
+	return nil
+}
+
+// UnblockUser 取消拉黑，不会恢复拉黑时取消的关注，没有拉黑时直接返回
+func (srv *userService) UnblockUser(ctx context.Context, userID uint64, blockedUID uint64) error {
+	_, err := srv.userBlockRepo.DeleteUserBlock(model.WithContext(ctx), userID, blockedUID)
+	return err
+}
+
+// GetBlockedList 获取拉黑列表，分页方式同 GetFollowingUserList
+func (srv *userService) GetBlockedList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.UserBlockModel, error) {
+	return srv.userBlockRepo.GetBlockedList(model.WithContext(ctx), userID, pagination.BeforeID(lastID), limit)
+}
+
+// isBlocked 双方之间是否有拉黑关系，查询出错时视为没有拉黑
+func (srv *userService) isBlocked(ctx context.Context, userID uint64, otherUID uint64) bool {
+	blocked, err := srv.userBlockRepo.IsBlockedEither(model.WithContext(ctx), userID, otherUID)
+	if err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] get user block err: %v", err)
+		return false
+	}
+	return blocked
+}
+
+// isFollowing 在事务中查询 userID 是否正在关注 followedUID
+func (srv *userService) isFollowing(tx *gorm.DB, userID uint64, followedUID uint64) (bool, error) {
+	follows, err := srv.userFollowRepo.GetFollowByUIds(tx, userID, []uint64{followedUID})
+	if err != nil {
+		return false, err
+	}
+	f, ok := follows[followedUID]
+	return ok && f.Status == FollowStatusNormal, nil
+}
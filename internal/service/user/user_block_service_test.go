@@ -0,0 +1,93 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 拉黑后取消双方的关注，双方都不能再关注，取消拉黑后可以重新关注
+func TestUserService_BlockUser(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	queue.Default = queue.NewMemoryQueue(0, "")
+	db, _ := openBenchDB(t)
+	model.DB = db
+
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	a := &model.UserBaseModel{Username: fmt.Sprintf("block_a_%d", suffix)}
+	b := &model.UserBaseModel{Username: fmt.Sprintf("block_b_%d", suffix)}
+	for _, u := range []*model.UserBaseModel{a, b} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.AddUserFollow(ctx, a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddUserFollow(ctx, b.ID, a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	relation := func() (int, int) {
+		infos, err := srv.BatchGetUsers(ctx, a.ID, []uint64{b.ID})
+		if err != nil || len(infos) != 1 {
+			t.Fatalf("BatchGetUsers = %v, %v", infos, err)
+		}
+		return infos[0].UserFollow.IsFollow, infos[0].UserFollow.IsFans
+	}
+	if isFollow, isFans := relation(); isFollow != 1 || isFans != 1 {
+		t.Fatalf("relation before block = %d, %d, want 1, 1", isFollow, isFans)
+	}
+
+	if err := srv.BlockUser(ctx, a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if isFollow, isFans := relation(); isFollow != 0 || isFans != 0 {
+		t.Fatalf("relation after block = %d, %d, want 0, 0", isFollow, isFans)
+	}
+	if srv.IsFollowedUser(ctx, a.ID, b.ID) || srv.IsFollowedUser(ctx, b.ID, a.ID) {
+		t.Fatal("follows not cancelled after block")
+	}
+	// 被拉黑的一方也不能关注
+	if err := srv.AddUserFollow(ctx, b.ID, a.ID); err != errno.ErrUserBlocked {
+		t.Fatalf("AddUserFollow err = %v, want ErrUserBlocked", err)
+	}
+	// 重复拉黑不报错
+	if err := srv.BlockUser(ctx, a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	list, err := srv.GetBlockedList(ctx, a.ID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].BlockedUID != b.ID {
+		t.Fatalf("blocked list = %+v, want [%d]", list, b.ID)
+	}
+
+	if err := srv.UnblockUser(ctx, a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddUserFollow(ctx, b.ID, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.IsFollowedUser(ctx, b.ID, a.ID) {
+		t.Fatal("follow after unblock not found")
+	}
+	if list, _ := srv.GetBlockedList(ctx, a.ID, 0, 10); len(list) != 0 {
+		t.Fatalf("blocked list after unblock = %d, want 0", len(list))
+	}
+
+	if err := srv.BlockUser(ctx, a.ID, a.ID); err != errno.ErrParam {
+		t.Fatalf("block self err = %v, want ErrParam", err)
+	}
+}
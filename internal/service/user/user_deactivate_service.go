@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// DeactivateUser 停用帐号，用户记录和登录身份一起软删除，之后按id、邮箱、手机号、用户名都查不到该用户，
+// 关注和粉丝列表也不再返回；用户名、邮箱等标识在宽限期内仍然被占用，可以通过 ReactivateUser 恢复
+func (srv *userService) DeactivateUser(ctx context.Context, userID, operatorID uint64, ip string) error {
+	db := model.WithContext(ctx)
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	ok, err := srv.userRepo.Deactivate(tx, userID, srv.clock.Now())
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] deactivate user err, uid: %d", userID)
+	}
+	// 不存在或已经停用
+	if !ok {
+		tx.Rollback()
+		return errno.ErrUserNotFound
+	}
+
+	if err := srv.userIdentityRepo.SoftDeleteUserIdentities(tx, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event, err := srv.recordEvent(tx, userID, model.UserEventDeactivated, operatorID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "tx commit err")
+	}
+	srv.publishEvent(ctx, event)
+
+	if err := audit.Svc.Record(ctx, userID, operatorID, audit.ActionUserDeactivate, ip, nil); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] record audit log err: %v, uid: %d", err, userID)
+	}
+	return nil
+}
+
+// ReactivateUser 恢复已停用的帐号和登录身份，超过宽限期 account.deleted_grace_period 后不能恢复
+// 宽限期后的标识可能已经被 user_reclaim 任务回收，其他人可以使用
+func (srv *userService) ReactivateUser(ctx context.Context, userID, operatorID uint64, ip string) error {
+	db := model.WithContext(ctx)
+	u, err := srv.userRepo.GetUserByIDUnscoped(db, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+	if !u.DeletedAt.Valid {
+		return errno.ErrUserNotDeactivated
+	}
+	grace := viper.GetDuration("account.deleted_grace_period")
+	if grace > 0 && srv.clock.Now().Sub(u.DeletedAt.Time) > grace {
+		return errno.ErrUserReclaimed
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	ok, err := srv.userRepo.Reactivate(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] reactivate user err, uid: %d", userID)
+	}
+	// 并发恢复或用户名已被回收
+	if !ok {
+		tx.Rollback()
+		return errno.ErrUserReclaimed
+	}
+
+	if _, err := srv.userIdentityRepo.RestoreUserIdentities(tx, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event, err := srv.recordEvent(tx, userID, model.UserEventReactivated, operatorID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "tx commit err")
+	}
+	srv.publishEvent(ctx, event)
+
+	if err := audit.Svc.Record(ctx, userID, operatorID, audit.ActionUserReactivate, ip, nil); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] record audit log err: %v, uid: %d", err, userID)
+	}
+	return nil
+}
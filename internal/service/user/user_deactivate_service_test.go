@@ -0,0 +1,108 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/clock"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 停用后按id、邮箱、批量获取、关注列表都查不到，用户名仍然被占用，宽限期内可以恢复
+func TestUserService_DeactivateUser(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	fake := clock.NewFake(time.Now())
+	srv.clock = fake
+	redis.InitTestRedis()
+	queue.Default = queue.NewMemoryQueue(0, "")
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserIdentityModel{}, &model.AuditLogModel{})
+	model.DB = db
+	viper.Set("account.deleted_grace_period", time.Hour)
+	t.Cleanup(func() { viper.Set("account.deleted_grace_period", nil) })
+
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	fan := &model.UserBaseModel{Username: fmt.Sprintf("fan_%d", suffix)}
+	if err := db.Create(fan).Error; err != nil {
+		t.Fatal(err)
+	}
+	addr := fmt.Sprintf("target_%d@example.com", suffix)
+	target := &model.UserBaseModel{Username: fmt.Sprintf("target_%d", suffix), Email: addr}
+	if err := db.Create(target).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.userIdentityRepo.SaveUserIdentity(db, target.ID, model.IdentityProviderEmail, addr, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddUserFollow(ctx, fan.ID, target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	visible := func() bool {
+		u, err := srv.GetUserByID(ctx, target.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos, err := srv.BatchGetUsers(ctx, fan.ID, []uint64{target.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		follows, err := srv.GetFollowingUserList(ctx, fan.ID, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, emailErr := srv.GetUserByEmail(ctx, addr)
+		found := []bool{u.ID == target.ID, len(infos) == 1, len(follows) == 1, emailErr == nil}
+		for _, v := range found[1:] {
+			if v != found[0] {
+				t.Fatalf("lookups disagree: id, batch, following, email = %v", found)
+			}
+		}
+		return found[0]
+	}
+
+	if !visible() {
+		t.Fatal("user not visible before deactivate")
+	}
+	if err := srv.DeactivateUser(ctx, target.ID, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if visible() {
+		t.Fatal("user visible after deactivate")
+	}
+	if err := srv.checkUsername(ctx, fan.ID, target.Username); err != errno.ErrUsernameExist {
+		t.Fatalf("checkUsername() = %v, want ErrUsernameExist", err)
+	}
+	if err := srv.DeactivateUser(ctx, target.ID, 1, ""); err != errno.ErrUserNotFound {
+		t.Fatalf("DeactivateUser() twice = %v, want ErrUserNotFound", err)
+	}
+
+	if err := srv.ReactivateUser(ctx, target.ID, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !visible() {
+		t.Fatal("user not visible after reactivate")
+	}
+	if err := srv.ReactivateUser(ctx, target.ID, 1, ""); err != errno.ErrUserNotDeactivated {
+		t.Fatalf("ReactivateUser() active = %v, want ErrUserNotDeactivated", err)
+	}
+
+	// 超过宽限期不能恢复
+	if err := srv.DeactivateUser(ctx, target.ID, target.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	fake.Add(2 * time.Hour)
+	if err := srv.ReactivateUser(ctx, target.ID, 1, ""); err != errno.ErrUserReclaimed {
+		t.Fatalf("ReactivateUser() after grace = %v, want ErrUserReclaimed", err)
+	}
+}
@@ -0,0 +1,93 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/ua"
+)
+
+// RecordUserDevice 记录用户登录设备
+// 同一设备(忽略版本号)只保留一条记录，如果是新设备且之前有登录过其他设备，则发送登录提醒
+// 登录设备包含ip等个人信息，保存在用户所在地区的数据库
+func (srv *userService) RecordUserDevice(ctx context.Context, u *model.UserBaseModel, userAgent, ip string) error {
+	if u == nil || u.ID == 0 {
+		return nil
+	}
+
+	agent := ua.Parse(userAgent)
+	db := model.GetRegionDB(u.Region)
+
+	device, err := srv.userDeviceRepo.GetUserDevice(db, u.ID, agent.Key())
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user device err, uid: %d", u.ID)
+	}
+
+	// 已登录过的设备，只更新最近登录信息
+	if device.ID > 0 {
+		return srv.userDeviceRepo.UpdateUserDeviceLogin(db, device.ID, userAgent, ip)
+	}
+
+	devices, err := srv.userDeviceRepo.GetUserDeviceList(db, u.ID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user device list err, uid: %d", u.ID)
+	}
+
+	now := srv.clock.Now()
+	_, err = srv.userDeviceRepo.CreateUserDevice(db, &model.UserDeviceModel{
+		UserID:      u.ID,
+		DeviceKey:   agent.Key(),
+		DeviceType:  agent.DeviceType,
+		Device:      agent.Device,
+		OS:          agent.OS,
+		OSVersion:   agent.OSVersion,
+		Browser:     agent.Browser,
+		UserAgent:   userAgent,
+		LastIP:      ip,
+		LastLoginAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] create user device err, uid: %d", u.ID)
+	}
+
+	// 首次登录不提醒，登录提醒异步发送，不阻塞登录接口
+	if len(devices) > 0 && u.Email != "" {
+		subject, body := email.NewLoginNoticeEmail(u.Username, agent.Name(), ip, now)
+		go func(uid uint64, to string) {
+			if err := email.Send(to, subject, body); err != nil {
+				logger.WithContext(ctx).Warnf("[user_service] send login notice email err: %v, uid: %d", err, uid)
+			}
+		}(u.ID, u.Email)
+	}
+
+	return nil
+}
+
+// GetUserDeviceList 获取用户登录过的设备列表
+// region 为 token 中的地区，旧的 token 没有地区时从用户资料中获取
+func (srv *userService) GetUserDeviceList(ctx context.Context, userID uint64, region string) ([]*model.UserDeviceModel, error) {
+	if region == "" {
+		u, err := srv.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+		}
+		region = u.Region
+	}
+
+	var devices []*model.UserDeviceModel
+	err := model.ReadRegion(region, func(db *gorm.DB) (err error) {
+		devices, err = srv.userDeviceRepo.GetUserDeviceList(db, userID)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[user_service] get user device list err, uid: %d", userID)
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,259 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/auth"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+const (
+	// emailChangeExpireTime 修改邮箱申请的有效期
+	emailChangeExpireTime = 24 * time.Hour
+	// emailChangeConfirmPath 确认链接的路径
+	emailChangeConfirmPath = "/v1/email/confirm?token=%s"
+)
+
+// RequestEmailChange 申请修改邮箱
+// 需要验证当前密码，确认链接会同时发送到新旧邮箱，两个都确认后才会生效
+// 没有绑定过邮箱的用户只需要确认新邮箱
+func (srv *userService) RequestEmailChange(ctx context.Context, userID uint64, newEmail, password, ip string) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+	if err := auth.Compare(u.Password, password); err != nil {
+		return errno.ErrPasswordIncorrect
+	}
+	if newEmail == u.Email {
+		return errno.ErrParam
+	}
+	if err := srv.checkEmailAvailable(ctx, newEmail); err != nil {
+		return err
+	}
+
+	oldToken, err := genEmailChangeToken()
+	if err != nil {
+		return errors.Wrap(err, "[user_service] gen email change token err")
+	}
+	newToken, err := genEmailChangeToken()
+	if err != nil {
+		return errors.Wrap(err, "[user_service] gen email change token err")
+	}
+
+	// 同一时间只保留一个有效的申请
+	db := model.WithContext(ctx)
+	if err := srv.userEmailChangeRepo.CancelPendingEmailChange(db, userID); err != nil {
+		return errors.Wrapf(err, "[user_service] cancel pending email change err, uid: %d", userID)
+	}
+
+	now := srv.clock.Now()
+	change := &model.UserEmailChangeModel{
+		UserID:    userID,
+		OldEmail:  u.Email,
+		NewEmail:  newEmail,
+		OldToken:  hashEmailChangeToken(oldToken),
+		NewToken:  hashEmailChangeToken(newToken),
+		Status:    model.EmailChangeStatusPending,
+		ExpiredAt: now.Add(emailChangeExpireTime),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if u.Email == "" {
+		change.OldConfirmedAt = &now
+	}
+	if _, err := srv.userEmailChangeRepo.CreateEmailChange(db, change); err != nil {
+		return errors.Wrapf(err, "[user_service] create email change err, uid: %d", userID)
+	}
+
+	srv.recordAudit(ctx, userID, audit.ActionEmailChangeRequest, ip, map[string]interface{}{
+		"change_id": change.ID, "old_email": u.Email, "new_email": newEmail,
+	})
+
+	if u.Email != "" {
+		subject, body := email.NewEmailChangeConfirmEmail(u.Username, u.Email, newEmail, emailChangeConfirmURL(oldToken), true)
+		if err := email.Send(u.Email, subject, body); err != nil {
+			return errors.Wrapf(err, "[user_service] send email change confirm email to old email err, uid: %d", userID)
+		}
+	}
+	subject, body := email.NewEmailChangeConfirmEmail(u.Username, u.Email, newEmail, emailChangeConfirmURL(newToken), false)
+	if err := email.Send(newEmail, subject, body); err != nil {
+		return errors.Wrapf(err, "[user_service] send email change confirm email to new email err, uid: %d", userID)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange 通过邮件中的链接确认修改邮箱，返回修改是否已完成
+// 新旧邮箱的链接可能同时点击，在事务中锁住申请后再确认，保证后确认的一方能看到双方都已确认
+func (srv *userService) ConfirmEmailChange(ctx context.Context, token, ip string) (bool, error) {
+	db := model.WithContext(ctx)
+	tokenHash := hashEmailChangeToken(token)
+	change, err := srv.userEmailChangeRepo.GetEmailChangeByToken(db, tokenHash)
+	if err != nil {
+		return false, errors.Wrap(err, "[user_service] get email change err")
+	}
+	if change.ID == 0 {
+		return false, errno.ErrEmailChangeInvalid
+	}
+
+	tx := db.Begin()
+	change, err = srv.userEmailChangeRepo.LockEmailChange(tx, change.ID)
+	if err != nil {
+		tx.Rollback()
+		return false, errors.Wrap(err, "[user_service] lock email change err")
+	}
+	if change.ID == 0 || change.Status != model.EmailChangeStatusPending {
+		tx.Rollback()
+		return false, errno.ErrEmailChangeInvalid
+	}
+
+	now := srv.clock.Now()
+	if now.After(change.ExpiredAt) {
+		err := srv.userEmailChangeRepo.UpdateEmailChange(tx, change.ID, map[string]interface{}{
+			"status": model.EmailChangeStatusExpired,
+		})
+		if err == nil {
+			err = tx.Commit().Error
+		}
+		if err != nil {
+			tx.Rollback()
+			logger.WithContext(ctx).Warnf("[user_service] expire email change err: %v, id: %d", err, change.ID)
+		}
+		return false, errno.ErrEmailChangeExpired
+	}
+
+	// 确认对应的一方，重复点击同一个链接不做处理
+	isOld := change.OldToken == tokenHash
+	field := "new_confirmed_at"
+	if isOld {
+		field = "old_confirmed_at"
+		if change.OldConfirmedAt == nil {
+			change.OldConfirmedAt = &now
+		}
+	} else if change.NewConfirmedAt == nil {
+		change.NewConfirmedAt = &now
+	}
+	err = srv.userEmailChangeRepo.UpdateEmailChange(tx, change.ID, map[string]interface{}{field: now})
+	if err != nil {
+		tx.Rollback()
+		return false, errors.Wrapf(err, "[user_service] confirm email change err, id: %d", change.ID)
+	}
+
+	done := change.IsConfirmed()
+	if done {
+		if err := srv.applyEmailChange(ctx, tx, change); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return false, errors.Wrap(err, "[user_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, change.UserID, audit.ActionEmailChangeConfirm, ip, map[string]interface{}{
+		"change_id": change.ID, "is_old": isOld,
+	})
+	if done {
+		srv.notifyEmailChanged(ctx, change, ip)
+	}
+	return done, nil
+}
+
+// applyEmailChange 新旧邮箱都确认后在确认的事务中修改用户邮箱
+func (srv *userService) applyEmailChange(ctx context.Context, tx *gorm.DB, change *model.UserEmailChangeModel) error {
+	// 确认期间新邮箱可能已被其他帐号使用
+	if err := srv.checkEmailAvailable(ctx, change.NewEmail); err != nil {
+		return err
+	}
+
+	err := srv.userRepo.Update(tx, change.UserID, map[string]interface{}{"email": change.NewEmail, "email_verified": 1})
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] update user email err, uid: %d", change.UserID)
+	}
+	err = srv.userIdentityRepo.SaveUserIdentity(tx, change.UserID, model.IdentityProviderEmail, change.NewEmail, true)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] save user email identity err, uid: %d", change.UserID)
+	}
+	err = srv.userEmailChangeRepo.UpdateEmailChange(tx, change.ID, map[string]interface{}{
+		"status": model.EmailChangeStatusDone,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] update email change status err, id: %d", change.ID)
+	}
+	return nil
+}
+
+// notifyEmailChanged 修改完成后记录审计日志并通知旧邮箱
+func (srv *userService) notifyEmailChanged(ctx context.Context, change *model.UserEmailChangeModel, ip string) {
+	srv.recordAudit(ctx, change.UserID, audit.ActionEmailChanged, ip, map[string]interface{}{
+		"change_id": change.ID, "old_email": change.OldEmail, "new_email": change.NewEmail,
+	})
+
+	// 通知旧邮箱
+	if change.OldEmail != "" {
+		u, err := srv.GetUserByID(ctx, change.UserID)
+		if err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] get user err: %v, uid: %d", err, change.UserID)
+			return
+		}
+		subject, body := email.NewEmailChangedNoticeEmail(u.Username, change.NewEmail, srv.clock.Now())
+		if err := email.Send(change.OldEmail, subject, body); err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] send email changed notice err: %v, uid: %d", err, change.UserID)
+		}
+	}
+}
+
+// checkEmailAvailable 检查邮箱是否未被使用，已注销但还没有回收的邮箱也视为已使用
+func (srv *userService) checkEmailAvailable(ctx context.Context, emailAddr string) error {
+	taken, err := srv.userIdentityRepo.IsIdentifierTaken(model.WithContext(ctx), model.IdentityProviderEmail, emailAddr)
+	if err != nil {
+		return errors.Wrap(err, "[user_service] check email identity err")
+	}
+	if taken {
+		return errno.ErrEmailExist
+	}
+	return nil
+}
+
+// recordAudit 记录审计日志，失败时只记录日志，不影响业务
+func (srv *userService) recordAudit(ctx context.Context, userID uint64, action, ip string, detail interface{}) {
+	if err := audit.Svc.Record(ctx, userID, userID, action, ip, detail); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] record audit log err: %v, uid: %d, action: %s", err, userID, action)
+	}
+}
+
+// genEmailChangeToken 生成随机的确认 token
+func genEmailChangeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashEmailChangeToken 数据库中只保存 token 的摘要，避免数据泄露后被直接使用
+func hashEmailChangeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func emailChangeConfirmURL(token string) string {
+	return viper.GetString("app.url") + fmt.Sprintf(emailChangeConfirmPath, token)
+}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/auth"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 新旧邮箱都确认后修改生效，后确认的一方在锁住申请后能看到先确认的一方
+func TestUserService_ConfirmEmailChange(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserIdentityModel{}, &model.AuditLogModel{}, &model.UserEmailChangeModel{})
+	model.DB = db
+
+	mailer := &fakeMailer{}
+	email.Lock.Lock()
+	old := email.Client
+	email.Client = mailer
+	email.Lock.Unlock()
+	t.Cleanup(func() {
+		email.Lock.Lock()
+		email.Client = old
+		email.Lock.Unlock()
+	})
+
+	suffix := time.Now().UnixNano()
+	oldAddr, newAddr := fmt.Sprintf("old_%d@example.com", suffix), fmt.Sprintf("new_%d@example.com", suffix)
+	password, _ := auth.Encrypt("password")
+	u := &model.UserBaseModel{Username: fmt.Sprintf("change_%d", suffix), Email: oldAddr, Password: password}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := srv.RequestEmailChange(ctx, u.ID, newAddr, "password", "127.0.0.1"); err != nil {
+		t.Fatalf("RequestEmailChange() err: %v", err)
+	}
+	if len(mailer.to) != 2 || mailer.to[0] != oldAddr || mailer.to[1] != newAddr {
+		t.Fatalf("sent to %v, want old and new email", mailer.to)
+	}
+	oldToken := resetTokenRe.FindStringSubmatch(mailer.body[0])[1]
+	newToken := resetTokenRe.FindStringSubmatch(mailer.body[1])[1]
+
+	if done, err := srv.ConfirmEmailChange(ctx, newToken, "127.0.0.1"); err != nil || done {
+		t.Fatalf("ConfirmEmailChange() new = %v, %v, want false, nil", done, err)
+	}
+	if done, err := srv.ConfirmEmailChange(ctx, oldToken, "127.0.0.1"); err != nil || !done {
+		t.Fatalf("ConfirmEmailChange() old = %v, %v, want true, nil", done, err)
+	}
+	// 修改完成后链接失效
+	if _, err := srv.ConfirmEmailChange(ctx, newToken, "127.0.0.1"); err != errno.ErrEmailChangeInvalid {
+		t.Fatalf("ConfirmEmailChange() after done = %v, want ErrEmailChangeInvalid", err)
+	}
+
+	got := model.UserBaseModel{}
+	if err := db.First(&got, u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != newAddr {
+		t.Fatalf("email = %q, want %q", got.Email, newAddr)
+	}
+}
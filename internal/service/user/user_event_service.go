@@ -0,0 +1,35 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// TopicUserEvent 用户事件的消息主题，由 worker 更新缓存、搜索索引和动态
+const TopicUserEvent = "user.event"
+
+// recordEvent 追加用户事件，和业务数据在同一个事务中写入，事务提交后再调用 publishEvent
+func (srv *userService) recordEvent(db *gorm.DB, userID uint64, eventType string, refID uint64) (*model.UserEventModel, error) {
+	event := &model.UserEventModel{
+		UserID:    userID,
+		EventType: eventType,
+		RefID:     refID,
+		CreatedAt: srv.clock.Now(),
+	}
+	if _, err := srv.userEventRepo.CreateUserEvent(db, event); err != nil {
+		return nil, errors.Wrapf(err, "[user_service] record user event err, uid: %d, type: %s", userID, eventType)
+	}
+	return event, nil
+}
+
+// publishEvent 发送失败时投影会暂时落后，可以通过 worker 从偏移量重放补齐
+func (srv *userService) publishEvent(ctx context.Context, event *model.UserEventModel) {
+	if err := queue.Publish(ctx, TopicUserEvent, event); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] publish user event err: %v, id: %d", err, event.ID)
+	}
+}
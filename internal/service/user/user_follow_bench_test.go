@@ -0,0 +1,201 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 关注相关接口的基准测试，每次调用的 sql 语句数和内存分配次数超出预算时失败，用于发现 N+1 查询等性能退化
+// 默认使用内存中的 sqlite，设置 SNAKE_BENCH_MYSQL_DSN 时使用 mysql，可以先 docker-compose up -d db
+// make bench 或 go test -run=^$ -bench=Follow -benchmem ./internal/service/user/
+
+// benchUsers 预置的用户数，BatchGetUsers 一次获取所有用户
+const benchUsers = 20
+
+// queryCount 执行的 sql 语句数，在驱动层统计，包括事务中和原生 sql 的语句
+var queryCount int64
+
+// countingDriver 统计语句数的驱动，没有实现 ExecerContext、QueryerContext，每条语句都会经过 Prepare
+type countingDriver struct {
+	driver.Driver
+}
+
+func (d countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+}
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddInt64(&queryCount, 1)
+	return c.Conn.Prepare(query)
+}
+
+var registerOnce sync.Once
+
+// openBenchDB 打开基准测试使用的数据库，mysql 需要已经按 db.sql 建表，测试结束后关闭
+func openBenchDB(tb testing.TB) (*gorm.DB, string) {
+	registerOnce.Do(func() {
+		sqlite, _ := sql.Open("sqlite3", "")
+		sql.Register("counting-sqlite3", countingDriver{sqlite.Driver()})
+		sql.Register("counting-mysql", countingDriver{&mysqldriver.MySQLDriver{}})
+	})
+
+	dialect, dsn := "sqlite3", "file:bench?mode=memory&cache=shared"
+	if v := os.Getenv("SNAKE_BENCH_MYSQL_DSN"); v != "" {
+		dialect, dsn = "mysql", v
+	}
+	sqlDB, err := sql.Open("counting-"+dialect, dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = sqlDB.Close() })
+
+	dialector := gorm.Dialector(&sqlite.Dialector{Conn: sqlDB})
+	if dialect == "mysql" {
+		dialector = mysql.New(mysql.Config{Conn: sqlDB})
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.Use(model.Tracing); err != nil {
+		tb.Fatal(err)
+	}
+	if dialect == "sqlite3" {
+		// 内存数据库在最后一个连接关闭时销毁
+		sqlDB.SetMaxIdleConns(1)
+		_ = db.AutoMigrate(&model.UserBaseModel{}, &model.UserStatModel{}, &model.UserFollowModel{},
+			&model.UserFansModel{}, &model.UserEventModel{}, &model.UserBanModel{}, &model.UserBlockModel{})
+	}
+	return db, dialect
+}
+
+// setupBench 准备 benchUsers 个用户，第一个用户关注其他所有用户
+func setupBench(b *testing.B) (*userService, []uint64, string) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	// NewUserCache 会按配置重新创建 redis client，需要在之后替换为 miniredis
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	queue.Default = queue.NewMemoryQueue(0, "")
+
+	db, dialect := openBenchDB(b)
+	model.DB = db
+
+	ids := make([]uint64, 0, benchUsers)
+	for i := 0; i < benchUsers; i++ {
+		u := &model.UserBaseModel{Username: fmt.Sprintf("bench_%d_%d", time.Now().UnixNano(), i)}
+		if err := db.Create(u).Error; err != nil {
+			b.Fatal(err)
+		}
+		ids = append(ids, u.ID)
+		if i == 0 {
+			continue
+		}
+		if err := db.Create(&model.UserFollowModel{UserID: ids[0], FollowedUID: u.ID, Status: FollowStatusNormal}).Error; err != nil {
+			b.Fatal(err)
+		}
+		if err := db.Create(&model.UserFansModel{UserID: u.ID, FollowerUID: ids[0], Status: FollowStatusNormal}).Error; err != nil {
+			b.Fatal(err)
+		}
+	}
+	return srv, ids, dialect
+}
+
+// measure 执行 b.N 次 fn，校验平均每次的语句数和内存分配次数
+func measure(b *testing.B, maxQueries, maxAllocs float64, fn func(i int)) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := atomic.LoadInt64(&queryCount)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		fn(i)
+	}
+
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	queries := float64(atomic.LoadInt64(&queryCount)-start) / float64(b.N)
+	allocs := float64(after.Mallocs-before.Mallocs) / float64(b.N)
+	b.ReportMetric(queries, "queries/op")
+	if queries > maxQueries {
+		b.Errorf("queries/op = %.2f, want <= %.0f", queries, maxQueries)
+	}
+	if allocs > maxAllocs {
+		b.Errorf("allocs/op = %.0f, want <= %.0f", allocs, maxAllocs)
+	}
+}
+
+// IsFollowedUser 查询关注记录，正在关注时再查询一次拉黑关系
+func BenchmarkFollow_IsFollowedUser(b *testing.B) {
+	srv, ids, _ := setupBench(b)
+
+	measure(b, 2, 300, func(i int) {
+		if !srv.IsFollowedUser(context.Background(), ids[0], ids[1+i%(benchUsers-1)]) {
+			b.Fatal("IsFollowedUser() = false, want true")
+		}
+	})
+}
+
+// BatchGetUsers 的语句数和用户数无关：用户资料走缓存，关注、粉丝、统计各一次批量查询
+func BenchmarkFollow_BatchGetUsers(b *testing.B) {
+	srv, ids, _ := setupBench(b)
+	ctx := context.Background()
+	// 预热用户缓存
+	if _, err := srv.BatchGetUsers(ctx, ids[0], ids[1:]); err != nil {
+		b.Fatal(err)
+	}
+
+	measure(b, 3, 3000, func(i int) {
+		users, err := srv.BatchGetUsers(ctx, ids[0], ids[1:])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(users) != benchUsers-1 || users[0].UserFollow.IsFollow != 1 {
+			b.Fatalf("BatchGetUsers() = %d users, want %d followed users", len(users), benchUsers-1)
+		}
+	})
+}
+
+// AddUserFollow 查询一次拉黑关系，写关注表、粉丝表和用户事件各一次，关注数和新粉丝通知在 redis 中缓冲
+func BenchmarkFollow_AddUserFollow(b *testing.B) {
+	srv, ids, _ := setupBench(b)
+	// 预热用户缓存，新粉丝通知需要读取关注者的资料
+	for _, id := range ids {
+		if _, err := srv.GetUserByID(context.Background(), id); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	measure(b, 4, 3000, func(i int) {
+		if err := srv.AddUserFollow(context.Background(), ids[1+i%(benchUsers-1)], ids[0]); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
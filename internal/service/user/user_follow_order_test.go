@@ -0,0 +1,75 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 粉丝列表按不同方式排序，逐页获取的结果和一次获取的一致，且不包含已注销和封禁中的粉丝
+func TestUserService_GetFollowerUserListOrder(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	model.DB = db
+
+	userID := uint64(time.Now().UnixNano() % 1e12 * 10)
+	plain, mutual, banned, deleted, unbanned, mutualNew := userID+1, userID+2, userID+3, userID+4, userID+5, userID+6
+	for _, uid := range []uint64{plain, mutual, banned, deleted, unbanned, mutualNew} {
+		db.Create(&model.UserFansModel{UserID: userID, FollowerUID: uid, Status: FollowStatusNormal})
+	}
+	for _, uid := range []uint64{mutual, mutualNew} {
+		db.Create(&model.UserFollowModel{UserID: userID, FollowedUID: uid, Status: FollowStatusNormal})
+	}
+	// 已取消的关注不算互关
+	db.Create(&model.UserFollowModel{UserID: userID, FollowedUID: plain, Status: FollowStatusDelete})
+	for uid, count := range map[uint64]int{plain: 5, mutual: 1, unbanned: 10} {
+		db.Create(&model.UserStatModel{UserID: uid, FollowerCount: count})
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	db.Create(&model.UserBanModel{UserID: banned, Status: model.BanStatusActive})
+	db.Create(&model.UserBanModel{UserID: unbanned, Status: model.BanStatusActive, ExpiredAt: &expired})
+	db.Create(&model.UserBaseModel{ID: deleted, Username: fmt.Sprintf("deleted_%d", deleted)})
+	db.Exec("UPDATE user_base SET deleted_at = ? WHERE id = ?", time.Now(), deleted)
+
+	tests := []struct {
+		order string
+		want  []uint64
+	}{
+		{model.FollowerOrderRecent, []uint64{mutualNew, unbanned, mutual, plain}},
+		{model.FollowerOrderMutual, []uint64{mutualNew, mutual, unbanned, plain}},
+		{model.FollowerOrderPopular, []uint64{unbanned, plain, mutual, mutualNew}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			for _, limit := range []int{1, 3, 10} {
+				var got []uint64
+				lastID := uint64(0)
+				for i := 0; i < 10; i++ {
+					list, err := srv.GetFollowerUserList(context.Background(), userID, lastID, limit, tt.order)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, v := range list {
+						got = append(got, v.FollowerUID)
+					}
+					if len(list) < limit {
+						break
+					}
+					lastID = list[len(list)-1].ID
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("limit %d: got %v, want %v", limit, got, tt.want)
+				}
+			}
+		})
+	}
+}
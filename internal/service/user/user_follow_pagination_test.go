@@ -0,0 +1,224 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+	"pgregory.net/rapid"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 关注、粉丝列表分页的性质测试：按 handler 的方式逐页遍历，每页之间随机插入关注、取消关注、重新关注，
+// 遍历开始时存在且期间没有取消的记录必须恰好出现一次，任何记录都不能重复出现
+
+const (
+	propUserID  uint64 = 1
+	propOtherID uint64 = 2
+)
+
+// followList 关注列表或粉丝列表，屏蔽两张表的差异
+type followList struct {
+	name string
+	// create 创建一条关注记录，返回记录id
+	create func(db *gorm.DB, userID, uid uint64, status int) (uint64, error)
+	// setStatus 修改记录状态
+	setStatus func(db *gorm.DB, id uint64, status int) error
+	// page 获取一页，返回记录id
+	page func(srv *userService, userID, lastID uint64, limit int) ([]uint64, error)
+}
+
+var followLists = []followList{
+	{
+		name: "following",
+		create: func(db *gorm.DB, userID, uid uint64, status int) (uint64, error) {
+			m := &model.UserFollowModel{UserID: userID, FollowedUID: uid, Status: status}
+			err := db.Create(m).Error
+			return m.ID, err
+		},
+		setStatus: func(db *gorm.DB, id uint64, status int) error {
+			return db.Model(&model.UserFollowModel{}).Where("id=?", id).Update("status", status).Error
+		},
+		page: func(srv *userService, userID, lastID uint64, limit int) ([]uint64, error) {
+			list, err := srv.GetFollowingUserList(context.Background(), userID, lastID, limit)
+			ids := make([]uint64, 0, len(list))
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			return ids, err
+		},
+	},
+	{
+		name: "followers",
+		create: func(db *gorm.DB, userID, uid uint64, status int) (uint64, error) {
+			m := &model.UserFansModel{UserID: userID, FollowerUID: uid, Status: status}
+			err := db.Create(m).Error
+			return m.ID, err
+		},
+		setStatus: func(db *gorm.DB, id uint64, status int) error {
+			return db.Model(&model.UserFansModel{}).Where("id=?", id).Update("status", status).Error
+		},
+		page: func(srv *userService, userID, lastID uint64, limit int) ([]uint64, error) {
+			list, err := srv.GetFollowerUserList(context.Background(), userID, lastID, limit, model.FollowerOrderRecent)
+			ids := make([]uint64, 0, len(list))
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			return ids, err
+		},
+	},
+}
+
+func TestFollowList_Pagination(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := &userService{userFollowRepo: user.NewUserFollowRepo()}
+	db, _ := openBenchDB(t)
+	model.DB = db
+
+	for _, fl := range followLists {
+		fl := fl
+		t.Run(fl.name, func(t *testing.T) {
+			rapid.Check(t, func(rt *rapid.T) {
+				checkPagination(rt, db, srv, fl)
+			})
+		})
+	}
+}
+
+// checkPagination 生成一组记录，逐页遍历并在页与页之间随机修改，校验不遗漏、不重复
+func checkPagination(t *rapid.T, db *gorm.DB, srv *userService, fl followList) {
+	for _, table := range []string{"user_follow", "user_fans"} {
+		if err := db.Exec("delete from " + table).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// status 记录当前用户所有记录的状态，包括已取消的
+	status := make(map[uint64]int)
+	var nextUID uint64 = 100
+	create := func(userID uint64, s int) {
+		nextUID++
+		id, err := fl.create(db, userID, nextUID, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID == propUserID {
+			status[id] = s
+		}
+	}
+	n := rapid.IntRange(0, 40).Draw(t, "rows")
+	for i := 0; i < n; i++ {
+		// 其他用户的记录穿插其中，当前用户的id不连续
+		if rapid.Bool().Draw(t, "other") {
+			create(propOtherID, FollowStatusNormal)
+		}
+		create(propUserID, rapid.SampledFrom([]int{FollowStatusNormal, FollowStatusNormal, FollowStatusDelete}).Draw(t, "status"))
+	}
+
+	// must 遍历开始时正常且期间没有被取消过的记录
+	must := make(map[uint64]bool)
+	for id, s := range status {
+		if s == FollowStatusNormal {
+			must[id] = true
+		}
+	}
+
+	limit := rapid.IntRange(1, 8).Draw(t, "limit")
+	seen := make(map[uint64]bool)
+	var lastID uint64
+	for pages := 0; ; pages++ {
+		if pages > n+1 {
+			t.Fatalf("too many pages, last_id %d", lastID)
+		}
+		ids, err := fl.page(srv, propUserID, lastID, limit+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 和 handler 一样多取一条判断是否还有下一页
+		hasMore := len(ids) > limit
+		if hasMore {
+			ids = ids[:limit]
+		}
+		for i, id := range ids {
+			if s, ok := status[id]; !ok || s != FollowStatusNormal {
+				t.Fatalf("page returned id %d with status %d, want current user's normal row", id, s)
+			}
+			if lastID > 0 && id >= lastID {
+				t.Fatalf("page after last_id %d returned id %d", lastID, id)
+			}
+			if i > 0 && id >= ids[i-1] {
+				t.Fatalf("page not in id desc order: %v", ids)
+			}
+			if seen[id] {
+				t.Fatalf("id %d returned twice", id)
+			}
+			seen[id] = true
+		}
+		if !hasMore {
+			break
+		}
+		lastID = ids[len(ids)-1]
+
+		// 翻页之间的并发修改，重点是分页边界上的记录
+		for i, ops := 0, rapid.IntRange(0, 3).Draw(t, "ops"); i < ops; i++ {
+			switch rapid.IntRange(0, 3).Draw(t, "op") {
+			case 0:
+				// 新的关注，id 比已有的都大
+				create(propUserID, FollowStatusNormal)
+			case 1:
+				// 取消关注，可能是 last_id 本身或紧挨着的下一条
+				id := pickID(t, status, lastID)
+				if err := fl.setStatus(db, id, FollowStatusDelete); err != nil {
+					t.Fatal(err)
+				}
+				status[id] = FollowStatusDelete
+				delete(must, id)
+			case 2:
+				// 重新关注，记录保留原来的id，可能出现在未遍历的部分
+				id := pickID(t, status, lastID)
+				if err := fl.setStatus(db, id, FollowStatusNormal); err != nil {
+					t.Fatal(err)
+				}
+				status[id] = FollowStatusNormal
+			case 3:
+				create(propOtherID, FollowStatusNormal)
+			}
+		}
+	}
+
+	for id := range must {
+		if !seen[id] {
+			t.Fatalf("id %d skipped, limit %d", id, limit)
+		}
+	}
+}
+
+// pickID 随机选择一条记录，一半概率选择 last_id 附近的记录
+func pickID(t *rapid.T, status map[uint64]int, lastID uint64) uint64 {
+	if _, ok := status[lastID]; ok && rapid.Bool().Draw(t, "boundary") {
+		// last_id 本身或比它小的第一条
+		if rapid.Bool().Draw(t, "self") {
+			return lastID
+		}
+		var below uint64
+		for id := range status {
+			if id < lastID && id > below {
+				below = id
+			}
+		}
+		if below > 0 {
+			return below
+		}
+		return lastID
+	}
+	ids := make([]uint64, 0, len(status))
+	for id := range status {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return rapid.SampledFrom(ids).Draw(t, "id")
+}
@@ -0,0 +1,140 @@
+package user
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/internal/service/kpi"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// getUserByIdentity 通过登录身份获取用户，不存在时返回 gorm.ErrRecordNotFound
+func (srv *userService) getUserByIdentity(ctx context.Context, provider, identifier string) (*model.UserBaseModel, error) {
+	identity, err := srv.userIdentityRepo.GetIdentity(model.WithContext(ctx), provider, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if identity.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+// createPhoneUser 手机号首次登录时创建用户，已注销但还没有回收的手机号不能创建
+func (srv *userService) createPhoneUser(ctx context.Context, phone int) (*model.UserBaseModel, error) {
+	taken, err := srv.userIdentityRepo.IsIdentifierTaken(model.WithContext(ctx), model.IdentityProviderPhone, strconv.Itoa(phone))
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_service] check phone identity err")
+	}
+	if taken {
+		return nil, errno.ErrPhoneExist
+	}
+
+	u := model.UserBaseModel{
+		Phone:     phone,
+		Username:  strconv.Itoa(phone),
+		CreatedAt: srv.clock.Now(),
+		UpdatedAt: srv.clock.Now(),
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	userID, err := srv.userRepo.Create(tx, u)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[user_service] create user err")
+	}
+	err = srv.userIdentityRepo.SaveUserIdentity(tx, userID, model.IdentityProviderPhone, strconv.Itoa(phone), true)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[user_service] create user identity err")
+	}
+	event, err := srv.recordEvent(tx, userID, model.UserEventCreated, 0)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[user_service] tx commit err")
+	}
+	srv.publishEvent(ctx, event)
+	kpi.RecordRegistration("phone")
+
+	u.ID = userID
+	return &u, nil
+}
+
+// GetUserIdentities 获取用户绑定的登录方式
+func (srv *userService) GetUserIdentities(ctx context.Context, userID uint64) ([]*model.UserIdentityModel, error) {
+	identities, err := srv.userIdentityRepo.GetUserIdentities(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[user_service] get user identities err, uid: %d", userID)
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity 解绑登录方式，至少需要保留一种
+func (srv *userService) UnlinkIdentity(ctx context.Context, userID, identityID uint64, ip string) error {
+	identities, err := srv.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var identity *model.UserIdentityModel
+	for _, v := range identities {
+		if v.ID == identityID {
+			identity = v
+			break
+		}
+	}
+	if identity == nil {
+		return errno.ErrIdentityNotFound
+	}
+	if len(identities) <= 1 {
+		return errno.ErrLastIdentity
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	if err := srv.userIdentityRepo.DeleteIdentity(tx, identity.ID); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] delete identity err, uid: %d", userID)
+	}
+
+	// 邮箱和手机号同时保存在用户表中，需要一起清除
+	userMap := make(map[string]interface{})
+	switch identity.Provider {
+	case model.IdentityProviderEmail:
+		userMap["email"] = ""
+	case model.IdentityProviderPhone:
+		userMap["phone"] = 0
+	}
+	if len(userMap) > 0 {
+		if err := srv.userRepo.Update(tx, userID, userMap); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "[user_service] update user err, uid: %d", userID)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[user_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, userID, audit.ActionIdentityUnlinked, ip, map[string]interface{}{
+		"provider": identity.Provider, "identifier": identity.Identifier,
+	})
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+// defaultImpersonateTTL 模拟登录 token 的默认有效期
+const defaultImpersonateTTL = 15 * time.Minute
+
+// Impersonate 管理员模拟用户登录，用于排查用户问题
+// 签发的 token 有效期很短，并在 claims 中标识了管理员id，必须填写原因并记录到审计日志
+func (srv *userService) Impersonate(ctx context.Context, adminID, userID uint64, reason, ip string) (tokenStr string, expiresAt time.Time, err error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || adminID == userID {
+		return "", time.Time{}, errno.ErrParam
+	}
+
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return "", time.Time{}, errno.ErrUserNotFound
+	}
+
+	ttl := viper.GetDuration("admin.impersonate_ttl")
+	if ttl <= 0 {
+		ttl = defaultImpersonateTTL
+	}
+	expiresAt = srv.clock.Now().Add(ttl)
+
+	// 先记录审计日志，记录失败时不签发 token
+	err = audit.Svc.Record(ctx, userID, adminID, audit.ActionImpersonate, ip, map[string]interface{}{
+		"reason": reason, "expires_at": expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "[user_service] record impersonate audit err, uid: %d", userID)
+	}
+
+	tokenStr, err = srv.signToken(context.Background(), token.Context{
+		UserID:         u.ID,
+		Username:       u.Username,
+		ExpiresAt:      expiresAt.Unix(),
+		ImpersonatorID: adminID,
+		Region:         u.Region,
+	})
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "[user_service] gen impersonate token err, uid: %d", userID)
+	}
+
+	logger.WithContext(ctx).Infof("[user_service] admin %d impersonate user %d, reason: %s", adminID, userID, reason)
+	return tokenStr, expiresAt, nil
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 邮件链接中的一次性 token，如重置密码、验证邮箱
+// token 格式为 uid.nonce.sign，redis 中只保存用户当前有效的 nonce 摘要，同一时间只有最后发送的链接有效
+
+// consumeLinkTokenScript 摘要一致时删除并返回1，保证 token 只能使用一次
+var consumeLinkTokenScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// consumeLinkToken 使用 token，已过期、已使用或已发送了新的链接时返回 false
+func consumeLinkToken(key, nonce string) (bool, error) {
+	n, err := consumeLinkTokenScript.Run(redis.RedisClient, []string{key}, hashEmailChangeToken(nonce)).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// signLinkToken 生成 uid.nonce.sign 格式的 token，签名错误的 token 不需要查询 redis
+// purpose 区分不同用途的链接，一种用途的 token 不能用于其他用途
+func signLinkToken(purpose string, userID uint64, nonce string) string {
+	payload := strconv.FormatUint(userID, 10) + "." + nonce
+	return payload + "." + linkTokenSign(purpose, payload)
+}
+
+// parseLinkToken 校验签名并解析出用户id和 nonce
+func parseLinkToken(purpose, tokenStr string) (uint64, string, bool) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(linkTokenSign(purpose, payload))) {
+		return 0, "", false
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || userID == 0 {
+		return 0, "", false
+	}
+	return userID, parts[1], true
+}
+
+func linkTokenSign(purpose, payload string) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("app.jwt_secret")))
+	mac.Write([]byte(purpose + ":" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// linkURL 邮件中的链接，path 为前端页面的路径，页面从链接中读取 token 后调用对应的接口
+func linkURL(path, tokenStr string) string {
+	return viper.GetString("app.url") + fmt.Sprintf(path, tokenStr)
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/saga"
+)
+
+// sagaActivateMembership 开通会员，先记录会员再修改用户套餐，任一步失败时回滚已完成的步骤
+const sagaActivateMembership = "activate_membership"
+
+// membershipData 开通会员时在步骤之间传递的数据
+type membershipData struct {
+	UserID    uint64    `json:"user_id"`
+	Plan      string    `json:"plan"`
+	OrderNo   string    `json:"order_no"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+	// OldPlan 修改前的套餐，补偿时恢复
+	OldPlan string `json:"old_plan"`
+}
+
+func init() {
+	srv := Svc.(*userService)
+	saga.Register(&saga.Definition{
+		Name: sagaActivateMembership,
+		Steps: []*saga.Step{
+			{Name: "create_membership", Action: srv.createMembership, Compensate: srv.deleteMembership},
+			{Name: "upgrade_plan", Action: srv.upgradePlan, Compensate: srv.restorePlan},
+		},
+	})
+}
+
+// ActivateMembership 支付成功后开通会员，同一个订单重复调用只开通一次
+// 已是会员时从当前到期时间开始续期
+func (srv *userService) ActivateMembership(ctx context.Context, userID uint64, plan, orderNo string, days int, operatorID uint64, ip string) error {
+	if _, ok := viper.GetStringMap("quota.plans")[plan]; !ok || days <= 0 || orderNo == "" {
+		return errno.ErrParam
+	}
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+
+	existing, err := srv.userMembershipRepo.GetMembershipByOrderNo(model.WithContext(ctx), orderNo)
+	if err != nil {
+		return err
+	}
+	if existing.ID > 0 {
+		return nil
+	}
+	latest, err := srv.userMembershipRepo.GetLatestMembership(model.WithContext(ctx), userID)
+	if err != nil {
+		return err
+	}
+	startedAt := srv.clock.Now()
+	if latest.ExpiredAt.After(startedAt) {
+		startedAt = latest.ExpiredAt
+	}
+
+	data := saga.Data{}
+	err = data.Set("membership", &membershipData{
+		UserID:    userID,
+		Plan:      plan,
+		OrderNo:   orderNo,
+		StartedAt: startedAt,
+		ExpiredAt: startedAt.AddDate(0, 0, days),
+	})
+	if err != nil {
+		return err
+	}
+	if err := saga.Run(ctx, sagaActivateMembership, data); err != nil {
+		return errors.Wrapf(err, "[user_service] activate membership err, uid: %d, order: %s", userID, orderNo)
+	}
+
+	err = audit.Svc.Record(ctx, userID, operatorID, audit.ActionMembershipActivate, ip, map[string]interface{}{
+		"plan": plan, "order_no": orderNo, "days": days,
+	})
+	if err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] record audit log err: %v, uid: %d", err, userID)
+	}
+	return nil
+}
+
+func (srv *userService) createMembership(ctx context.Context, data saga.Data) error {
+	var d membershipData
+	if err := data.Get("membership", &d); err != nil {
+		return err
+	}
+	_, err := srv.userMembershipRepo.CreateMembership(model.WithContext(ctx), &model.UserMembershipModel{
+		UserID:    d.UserID,
+		Plan:      d.Plan,
+		OrderNo:   d.OrderNo,
+		StartedAt: d.StartedAt,
+		ExpiredAt: d.ExpiredAt,
+		CreatedAt: srv.clock.Now(),
+	})
+	return err
+}
+
+func (srv *userService) deleteMembership(ctx context.Context, data saga.Data) error {
+	var d membershipData
+	if err := data.Get("membership", &d); err != nil {
+		return err
+	}
+	return srv.userMembershipRepo.DeleteMembershipByOrderNo(model.WithContext(ctx), d.OrderNo)
+}
+
+func (srv *userService) upgradePlan(ctx context.Context, data saga.Data) error {
+	var d membershipData
+	if err := data.Get("membership", &d); err != nil {
+		return err
+	}
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), d.UserID)
+	if err != nil {
+		return err
+	}
+	d.OldPlan = u.Plan
+	if err := data.Set("membership", &d); err != nil {
+		return err
+	}
+	return srv.userRepo.Update(model.WithContext(ctx), d.UserID, map[string]interface{}{"plan": d.Plan})
+}
+
+func (srv *userService) restorePlan(ctx context.Context, data saga.Data) error {
+	var d membershipData
+	if err := data.Get("membership", &d); err != nil {
+		return err
+	}
+	return srv.userRepo.Update(model.WithContext(ctx), d.UserID, map[string]interface{}{"plan": d.OldPlan})
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"context"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/notification"
+)
+
+// notifyNewFollower 通知被关注的用户有了新粉丝
+// 通知失败不影响关注操作，只记录日志
+func (srv *userService) notifyNewFollower(ctx context.Context, userID uint64, followedUID uint64) {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil || u.ID == 0 {
+		logger.WithContext(ctx).Warnf("[user_service] get follower err: %v, uid: %d", err, userID)
+		return
+	}
+
+	err = notification.Svc.Notify(ctx, followedUID, &notification.Message{
+		EventType: model.NotifyEventNewFollower,
+		Title:     "新粉丝",
+		Content:   u.Username + " 关注了你",
+	})
+	if err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] notify new follower err: %v, uid: %d", err, followedUID)
+	}
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/internal/service/kpi"
+	"github.com/1024casts/snake/pkg/oauth"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+// OAuthLogin 第三方帐号登录，info 为 oauth.Provider.Exchange 返回的帐号信息
+// 已绑定的直接登录；没有绑定时，第三方已验证的邮箱和本地已验证的邮箱一致则关联到该用户，否则创建新用户
+func (srv *userService) OAuthLogin(ctx context.Context, info *oauth.UserInfo, userAgent, ip string) (tokenStr string, err error) {
+	defer func() { kpi.RecordLogin(info.Provider, err == nil) }()
+
+	u, err := srv.getOAuthUser(ctx, info, ip)
+	if err != nil {
+		return "", errors.Wrapf(err, "[login] get oauth user err, provider: %s", info.Provider)
+	}
+	if u.ID == 0 {
+		u, err = srv.createOAuthUser(ctx, info)
+		if err != nil {
+			return "", errors.Wrapf(err, "[login] create oauth user err, provider: %s", info.Provider)
+		}
+	}
+
+	// 封禁中的用户不能登录
+	if err := ban.Svc.CheckBan(ctx, u.ID); err != nil {
+		return "", err
+	}
+
+	tokenStr, err = srv.signToken(ctx, token.Context{UserID: u.ID, Username: u.Username, LoginType: token.LoginTypeOAuth, Region: u.Region})
+	if err != nil {
+		return "", errors.Wrapf(err, "[login] gen token sign err")
+	}
+
+	// 记录登录设备
+	if err := srv.RecordUserDevice(ctx, u, userAgent, ip); err != nil {
+		logger.WithContext(ctx).Warnf("[login] record user device err: %v", err)
+	}
+	srv.recordActivity(ctx, u.ID, model.ActivityLogin, ip, map[string]interface{}{"method": token.LoginTypeOAuth, "provider": info.Provider})
+
+	return tokenStr, nil
+}
+
+// getOAuthUser 获取第三方帐号绑定或可以关联的用户，都没有时返回空结构体
+func (srv *userService) getOAuthUser(ctx context.Context, info *oauth.UserInfo, ip string) (*model.UserBaseModel, error) {
+	o, err := srv.userOAuthRepo.GetOAuth(model.WithContext(ctx), info.Provider, info.OpenID)
+	if err != nil {
+		return nil, err
+	}
+	if o.ID > 0 {
+		// 更新第三方的资料和 token，失败不影响登录
+		if err := srv.userOAuthRepo.UpdateOAuth(model.WithContext(ctx), o.ID, oauthFields(info)); err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] update oauth err: %v, id: %d", err, o.ID)
+		}
+		return srv.getOAuthBoundUser(ctx, o.UserID)
+	}
+
+	// 同一开放平台下其他应用已绑定过
+	if info.UnionID != "" {
+		o, err = srv.userOAuthRepo.GetOAuthByUnionID(model.WithContext(ctx), info.Provider, info.UnionID)
+		if err != nil {
+			return nil, err
+		}
+		if o.ID > 0 {
+			if err := srv.userOAuthRepo.CreateOAuth(model.WithContext(ctx), newOAuthModel(o.UserID, info)); err != nil {
+				return nil, err
+			}
+			return srv.getOAuthBoundUser(ctx, o.UserID)
+		}
+	}
+
+	// 双方都验证过的邮箱才能关联，避免通过未验证的邮箱占用他人帐号
+	if info.Email == "" || !info.EmailVerified {
+		return &model.UserBaseModel{}, nil
+	}
+	u, err := srv.GetUserByEmail(ctx, info.Email)
+	if err != nil && errors.Cause(err) != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err != nil || u.EmailVerified == 0 {
+		return &model.UserBaseModel{}, nil
+	}
+	if err := srv.userOAuthRepo.CreateOAuth(model.WithContext(ctx), newOAuthModel(u.ID, info)); err != nil {
+		return nil, err
+	}
+	srv.recordAudit(ctx, u.ID, audit.ActionOAuthLinked, ip, map[string]interface{}{"provider": info.Provider})
+
+	return u, nil
+}
+
+// getOAuthBoundUser 获取第三方帐号绑定的用户，用户不存在时返回 gorm.ErrRecordNotFound
+func (srv *userService) getOAuthBoundUser(ctx context.Context, userID uint64) (*model.UserBaseModel, error) {
+	u, err := srv.userRepo.GetUserByID(model.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return u, nil
+}
+
+// createOAuthUser 第三方帐号首次登录时创建用户，用户名由平台名和随机id生成，之后可以修改
+func (srv *userService) createOAuthUser(ctx context.Context, info *oauth.UserInfo) (*model.UserBaseModel, error) {
+	id, err := srv.idGen.NextID()
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_service] gen username err")
+	}
+	u := model.UserBaseModel{
+		Username:  fmt.Sprintf("%s_%d", info.Provider, id),
+		Avatar:    info.Avatar,
+		CreatedAt: srv.clock.Now(),
+		UpdatedAt: srv.clock.Now(),
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	userID, err := srv.userRepo.Create(tx, u)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[user_service] create user err")
+	}
+	if err := srv.userOAuthRepo.CreateOAuth(tx, newOAuthModel(userID, info)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	event, err := srv.recordEvent(tx, userID, model.UserEventCreated, 0)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "[user_service] tx commit err")
+	}
+	srv.publishEvent(ctx, event)
+	kpi.RecordRegistration(info.Provider)
+
+	u.ID = userID
+	return &u, nil
+}
+
+// newOAuthModel 用第三方帐号信息生成绑定记录
+func newOAuthModel(userID uint64, info *oauth.UserInfo) *model.UserOAuthModel {
+	return &model.UserOAuthModel{
+		UserID:       userID,
+		Provider:     info.Provider,
+		OpenID:       info.OpenID,
+		UnionID:      info.UnionID,
+		Nickname:     info.Nickname,
+		Avatar:       info.Avatar,
+		AccessToken:  info.AccessToken,
+		RefreshToken: info.RefreshToken,
+		ExpiredAt:    oauthExpiredAt(info),
+	}
+}
+
+// oauthFields 每次登录时更新的字段
+func oauthFields(info *oauth.UserInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"union_id":      info.UnionID,
+		"nickname":      info.Nickname,
+		"avatar":        info.Avatar,
+		"access_token":  info.AccessToken,
+		"refresh_token": info.RefreshToken,
+		"expired_at":    oauthExpiredAt(info),
+	}
+}
+
+// oauthExpiredAt access_token 的过期时间，GitHub 的 token 不过期
+func oauthExpiredAt(info *oauth.UserInfo) *time.Time {
+	if info.ExpiresIn <= 0 {
+		return nil
+	}
+	t := time.Now().Add(time.Duration(info.ExpiresIn) * time.Second)
+	return &t
+}
@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/oauth"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+// 第三方帐号首次登录创建用户，再次登录、同一 union_id 的其他应用登录都是同一个用户；已验证的邮箱关联到已有用户
+func TestUserService_OAuthLogin(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserOAuthModel{}, &model.UserIdentityModel{}, &model.UserDeviceModel{}, &model.AuditLogModel{})
+	model.DB = db
+
+	suffix := time.Now().UnixNano()
+	login := func(info *oauth.UserInfo) uint64 {
+		t.Helper()
+		tokenStr, err := srv.OAuthLogin(context.Background(), info, "", "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := token.Parse(tokenStr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.LoginType != token.LoginTypeOAuth {
+			t.Fatalf("login type = %s, want %s", c.LoginType, token.LoginTypeOAuth)
+		}
+		return c.UserID
+	}
+
+	web := &oauth.UserInfo{Provider: oauth.ProviderWechat, OpenID: fmt.Sprintf("web_%d", suffix),
+		UnionID: fmt.Sprintf("union_%d", suffix), Avatar: "https://example.com/a.png"}
+	uid := login(web)
+	u, err := srv.GetUserByID(context.Background(), uid)
+	if err != nil || u.Avatar != web.Avatar {
+		t.Fatalf("GetUserByID(%d) = %+v, %v", uid, u, err)
+	}
+	if got := login(web); got != uid {
+		t.Fatalf("login again uid = %d, want %d", got, uid)
+	}
+	app := *web
+	app.OpenID = fmt.Sprintf("app_%d", suffix)
+	if got := login(&app); got != uid {
+		t.Fatalf("login with same union id uid = %d, want %d", got, uid)
+	}
+
+	verified := &model.UserBaseModel{Username: fmt.Sprintf("verified_%d", suffix), Email: fmt.Sprintf("v_%d@example.com", suffix), EmailVerified: 1}
+	unverified := &model.UserBaseModel{Username: fmt.Sprintf("unverified_%d", suffix), Email: fmt.Sprintf("u_%d@example.com", suffix)}
+	for _, v := range []*model.UserBaseModel{verified, unverified} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.userIdentityRepo.SaveUserIdentity(db, v.ID, model.IdentityProviderEmail, v.Email, v.EmailVerified == 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		info   *oauth.UserInfo
+		linked uint64
+	}{
+		{"verified email", &oauth.UserInfo{Provider: oauth.ProviderGithub, OpenID: fmt.Sprintf("gh_%d", suffix),
+			Email: verified.Email, EmailVerified: true}, verified.ID},
+		{"email not verified by provider", &oauth.UserInfo{Provider: oauth.ProviderGoogle, OpenID: fmt.Sprintf("g1_%d", suffix),
+			Email: verified.Email}, 0},
+		{"email not verified locally", &oauth.UserInfo{Provider: oauth.ProviderGoogle, OpenID: fmt.Sprintf("g2_%d", suffix),
+			Email: unverified.Email, EmailVerified: true}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := login(tt.info)
+			if tt.linked > 0 && got != tt.linked {
+				t.Fatalf("uid = %d, want linked %d", got, tt.linked)
+			}
+			if tt.linked == 0 && (got == verified.ID || got == unverified.ID) {
+				t.Fatalf("uid = %d, should create new user", got)
+			}
+		})
+	}
+}
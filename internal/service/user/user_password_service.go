@@ -0,0 +1,95 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/auth"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+const (
+	// passwordResetExpireTime 重置密码链接的有效期
+	passwordResetExpireTime = 30 * time.Minute
+	// prefixPasswordResetKey 用户当前有效的重置 token 摘要，同一时间只有最后发送的链接有效
+	prefixPasswordResetKey = "snake:user:password_reset:%d"
+	// passwordResetPath 重置密码页面的路径，页面从链接中读取 token 后调用 POST /v1/password/reset
+	passwordResetPath = "/password/reset?token=%s"
+	// passwordResetPurpose 重置密码 token 的用途
+	passwordResetPurpose = "password_reset"
+)
+
+// ForgotPassword 发送重置密码邮件
+// 邮箱未注册时也返回成功，避免通过该接口判断邮箱是否已注册
+func (srv *userService) ForgotPassword(ctx context.Context, emailAddr, ip string) error {
+	u, err := srv.GetUserByEmail(ctx, emailAddr)
+	if errors.Cause(err) == gorm.ErrRecordNotFound {
+		logger.WithContext(ctx).Infof("[user_service] forgot password for unknown email, ip: %s", ip)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "[user_service] get user by email err")
+	}
+
+	nonce, err := genEmailChangeToken()
+	if err != nil {
+		return errors.Wrap(err, "[user_service] gen password reset token err")
+	}
+	key := fmt.Sprintf(prefixPasswordResetKey, u.ID)
+	if err := redis.RedisClient.Set(key, hashEmailChangeToken(nonce), passwordResetExpireTime).Err(); err != nil {
+		return errors.Wrapf(err, "[user_service] save password reset token err, uid: %d", u.ID)
+	}
+
+	srv.recordAudit(ctx, u.ID, audit.ActionPasswordResetRequest, ip, nil)
+
+	subject, body := email.NewResetPasswordEmail(u.Username, linkURL(passwordResetPath, signLinkToken(passwordResetPurpose, u.ID, nonce)))
+	if err := email.Send(u.Email, subject, body); err != nil {
+		return errors.Wrapf(err, "[user_service] send password reset email err, uid: %d", u.ID)
+	}
+	return nil
+}
+
+// ResetPassword 通过邮件中的 token 重置密码，token 使用后失效
+// 重置成功后，之前签发的所有登录 token 都会失效
+func (srv *userService) ResetPassword(ctx context.Context, tokenStr, password, ip string) error {
+	userID, nonce, ok := parseLinkToken(passwordResetPurpose, tokenStr)
+	if !ok {
+		return errno.ErrPasswordResetInvalid
+	}
+
+	key := fmt.Sprintf(prefixPasswordResetKey, userID)
+	ok, err := consumeLinkToken(key, nonce)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] consume password reset token err, uid: %d", userID)
+	}
+	// 已过期、已使用或已发送了新的链接
+	if !ok {
+		return errno.ErrPasswordResetInvalid
+	}
+
+	pwd, err := auth.Encrypt(password)
+	if err != nil {
+		return errors.Wrap(err, "[user_service] encrypt password err")
+	}
+	if err := srv.UpdateUser(ctx, userID, map[string]interface{}{"password": pwd}); err != nil {
+		return errors.Wrapf(err, "[user_service] update password err, uid: %d", userID)
+	}
+
+	srv.recordAudit(ctx, userID, audit.ActionPasswordReset, ip, nil)
+
+	// 密码可能已泄露，所有登录方式的会话都需要失效
+	for _, loginType := range []string{token.LoginTypeEmail, token.LoginTypePhone} {
+		if err := token.Revoke(userID, loginType); err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] revoke %s login token err: %v, uid: %d", loginType, err, userID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/auth"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// fakeMailer 记录发送的邮件
+type fakeMailer struct {
+	to, body []string
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.body = append(m.body, body)
+	return nil
+}
+
+func (m *fakeMailer) Close() {}
+
+var resetTokenRe = regexp.MustCompile(`token=([^'"]+)`)
+
+func TestUserService_ResetPassword(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserIdentityModel{}, &model.AuditLogModel{})
+	model.DB = db
+
+	mailer := &fakeMailer{}
+	email.Lock.Lock()
+	old := email.Client
+	email.Client = mailer
+	email.Lock.Unlock()
+	t.Cleanup(func() {
+		email.Lock.Lock()
+		email.Client = old
+		email.Lock.Unlock()
+	})
+
+	addr := fmt.Sprintf("reset_%d@example.com", time.Now().UnixNano())
+	u := &model.UserBaseModel{Username: "reset", Email: addr}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.userIdentityRepo.SaveUserIdentity(db, u.ID, model.IdentityProviderEmail, addr, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未注册的邮箱不发送邮件，也不返回错误
+	if err := srv.ForgotPassword(context.Background(), "unknown@example.com", "127.0.0.1"); err != nil || len(mailer.to) != 0 {
+		t.Fatalf("ForgotPassword() unknown email = %v, sent %d, want nil and no mail", err, len(mailer.to))
+	}
+
+	// 只有最后发送的链接有效
+	for i := 0; i < 2; i++ {
+		if err := srv.ForgotPassword(context.Background(), addr, "127.0.0.1"); err != nil {
+			t.Fatalf("ForgotPassword() err: %v", err)
+		}
+	}
+	if len(mailer.to) != 2 || mailer.to[1] != addr {
+		t.Fatalf("sent to %v, want %s twice", mailer.to, addr)
+	}
+	first := resetTokenRe.FindStringSubmatch(mailer.body[0])[1]
+	last := resetTokenRe.FindStringSubmatch(mailer.body[1])[1]
+
+	if err := srv.ResetPassword(context.Background(), first, "new-password", "127.0.0.1"); err != errno.ErrPasswordResetInvalid {
+		t.Fatalf("ResetPassword() with replaced token = %v, want ErrPasswordResetInvalid", err)
+	}
+	forged := last[:len(last)-1] + "0"
+	if forged == last {
+		forged = last[:len(last)-1] + "1"
+	}
+	if err := srv.ResetPassword(context.Background(), forged, "new-password", "127.0.0.1"); err != errno.ErrPasswordResetInvalid {
+		t.Fatalf("ResetPassword() with forged token = %v, want ErrPasswordResetInvalid", err)
+	}
+	if err := srv.ResetPassword(context.Background(), last, "new-password", "127.0.0.1"); err != nil {
+		t.Fatalf("ResetPassword() err: %v", err)
+	}
+	// token 只能使用一次
+	if err := srv.ResetPassword(context.Background(), last, "other-password", "127.0.0.1"); err != errno.ErrPasswordResetInvalid {
+		t.Fatalf("ResetPassword() reuse token = %v, want ErrPasswordResetInvalid", err)
+	}
+
+	got := model.UserBaseModel{}
+	if err := db.First(&got, u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Compare(got.Password, "new-password"); err != nil {
+		t.Fatalf("password is not updated: %v", err)
+	}
+}
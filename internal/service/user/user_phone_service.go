@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/otp"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+// phoneChangeCooldown 两次修改手机号的最小间隔
+const phoneChangeCooldown = 30 * 24 * time.Hour
+
+// ChangePhone 修改手机号
+// 需要当前手机号和新手机号的短信验证码，没有绑定过手机号的用户只需要验证新手机号
+// 修改成功后，之前通过手机号登录签发的 token 都会失效
+func (srv *userService) ChangePhone(ctx context.Context, userID uint64, oldVerifyCode, newPhone, newVerifyCode int, ip string) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+	if newPhone == u.Phone {
+		return errno.ErrParam
+	}
+
+	// 同时验证新旧手机号，都正确时才作废，其中一个输错时另一个还可以继续使用
+	codes := map[int]int{newPhone: newVerifyCode}
+	if u.Phone > 0 {
+		codes[u.Phone] = oldVerifyCode
+	}
+	if err := verifyPhoneCodes(ctx, otp.PurposeRebind, codes); err != nil {
+		return err
+	}
+
+	// 冷却时间内不能再次修改
+	lastChange, err := audit.Svc.GetLatestAuditLog(ctx, userID, audit.ActionPhoneChanged)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get latest phone change err, uid: %d", userID)
+	}
+	if lastChange.ID > 0 && time.Since(lastChange.CreatedAt) < phoneChangeCooldown {
+		return errno.ErrPhoneChangeTooOften
+	}
+
+	// 新手机号不能被其他帐号使用
+	identity, err := srv.userIdentityRepo.GetIdentity(model.WithContext(ctx), model.IdentityProviderPhone, strconv.Itoa(newPhone))
+	if err != nil {
+		return errors.Wrap(err, "[user_service] get phone identity err")
+	}
+	if identity.ID > 0 {
+		return errno.ErrPhoneExist
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	err = srv.userRepo.Update(tx, userID, map[string]interface{}{"phone": newPhone})
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] update user phone err, uid: %d", userID)
+	}
+	err = srv.userIdentityRepo.SaveUserIdentity(tx, userID, model.IdentityProviderPhone, strconv.Itoa(newPhone), true)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] save user phone identity err, uid: %d", userID)
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[user_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, userID, audit.ActionPhoneChanged, ip, map[string]interface{}{
+		"old_phone": u.Phone, "new_phone": newPhone,
+	})
+
+	// 手机号已换绑，通过旧手机号登录的会话需要失效
+	if err := token.Revoke(userID, token.LoginTypePhone); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] revoke phone login token err: %v, uid: %d", err, userID)
+	}
+
+	return nil
+}
+
+// verifyPhoneCodes 校验一组手机号的短信验证码，key 为手机号，全部正确时验证码一起作废
+func verifyPhoneCodes(ctx context.Context, purpose string, codes map[int]int) error {
+	targets := make(map[string]string, len(codes))
+	for phone, verifyCode := range codes {
+		targets[strconv.Itoa(phone)] = strconv.Itoa(verifyCode)
+	}
+	err := otp.Default().VerifyAll(ctx, purpose, targets)
+	switch err {
+	case nil:
+		return nil
+	case otp.ErrInvalid:
+		return errno.ErrVerifyCode
+	case otp.ErrTooManyAttempts:
+		return errno.ErrVerifyCodeAttempts
+	default:
+		return errors.Wrap(err, "[user_service] verify phone code err")
+	}
+}
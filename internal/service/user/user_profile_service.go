@@ -0,0 +1,108 @@
+package user
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/file"
+	"github.com/1024casts/snake/internal/service/moderation"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// moderatedFields 需要审核的资料字段
+var moderatedFields = []string{model.ModerationFieldUsername, model.ModerationFieldBio, model.ModerationFieldAvatar}
+
+// UpdateProfile 修改资料
+// 修改立即生效，用户名、简介命中敏感词时进入人工审核队列，审核拒绝后会恢复
+// 头像修改后异步送图片审核，被标记时先恢复为修改前的头像，审核通过后再生效
+func (srv *userService) UpdateProfile(ctx context.Context, userID uint64, userMap map[string]interface{}) error {
+	return srv.updateProfile(ctx, userID, -1, userMap)
+}
+
+// UpdateProfileIfMatch 资料版本号一致时才修改，防止多端同时修改时互相覆盖
+// userMap 中的 birthday 为生日字符串，校验后和其他字段一起更新
+func (srv *userService) UpdateProfileIfMatch(ctx context.Context, userID uint64, version int, userMap map[string]interface{}) error {
+	return srv.updateProfile(ctx, userID, version, userMap)
+}
+
+// updateProfile 修改资料，version 小于0时不校验版本号
+func (srv *userService) updateProfile(ctx context.Context, userID uint64, version int, userMap map[string]interface{}) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if version >= 0 && u.Version != version {
+		return errno.ErrVersionConflict
+	}
+	if birthday, ok := userMap["birthday"].(string); ok {
+		t, err := srv.checkBirthday(u, birthday)
+		if err != nil {
+			return err
+		}
+		userMap["birthday"] = t
+	}
+	if username, ok := userMap["username"].(string); ok && username != u.Username {
+		if err := srv.checkUsername(ctx, userID, username); err != nil {
+			return err
+		}
+	}
+	// 更新后会删除缓存，先保存修改前的值
+	oldValues := make(map[string]string, len(moderatedFields))
+	for _, field := range moderatedFields {
+		oldValues[field] = u.ProfileField(field)
+	}
+
+	if version >= 0 {
+		var updated bool
+		updated, err = srv.userRepo.UpdateWithVersion(model.WithContext(ctx), userID, version, userMap)
+		if err == nil && !updated {
+			return errno.ErrVersionConflict
+		}
+	} else {
+		err = srv.userRepo.Update(model.WithContext(ctx), userID, userMap)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] update profile err, uid: %d", userID)
+	}
+	if event, err := srv.recordEvent(model.WithContext(ctx), userID, model.UserEventUpdated, 0); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] %v", err)
+	} else {
+		srv.publishEvent(ctx, event)
+	}
+	fields := make([]string, 0, len(userMap))
+	for field := range userMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	srv.recordActivity(ctx, userID, model.ActivityProfileUpdate, "", map[string]interface{}{"fields": fields})
+
+	// 旧用户名的主页链接重定向到新用户名
+	if username, ok := userMap["username"].(string); ok && username != oldValues[model.ModerationFieldUsername] {
+		srv.recordUsernameChange(ctx, userID, oldValues[model.ModerationFieldUsername])
+	}
+
+	for _, field := range moderatedFields {
+		newValue, ok := userMap[field].(string)
+		if !ok || newValue == oldValues[field] {
+			continue
+		}
+		if field == model.ModerationFieldAvatar {
+			file.Svc.ReplaceRef(ctx, oldValues[field], newValue)
+			moderation.Svc.CheckImageAsync(ctx, userID, field, oldValues[field], newValue)
+			continue
+		}
+		flagged, source, reason := moderation.Svc.Check(field, newValue)
+		if !flagged {
+			continue
+		}
+		err := moderation.Svc.Submit(ctx, userID, field, oldValues[field], newValue, source, reason)
+		if err != nil {
+			logger.WithContext(ctx).Warnf("[user_service] submit moderation err: %v, uid: %d, field: %s", err, userID, field)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// ReclaimDeletedIdentifiers 回收删除超过 grace 的帐号和登录身份占用的用户名、邮箱、手机号，由定时任务调用
+// 回收后其他人可以使用这些标识注册，原帐号不能再恢复，返回回收的记录数
+func (srv *userService) ReclaimDeletedIdentifiers(ctx context.Context, grace time.Duration, limit int) (int, error) {
+	before := srv.clock.Now().Add(-grace)
+	db := model.WithContext(ctx)
+
+	userIDs, err := srv.userRepo.ReclaimDeletedUsers(db, before, limit)
+	if err != nil {
+		return len(userIDs), errors.Wrap(err, "[user_service] reclaim deleted users err")
+	}
+	count, err := srv.userIdentityRepo.ReclaimDeletedIdentities(db, before, limit)
+	if err != nil {
+		return len(userIDs) + count, errors.Wrap(err, "[user_service] reclaim deleted identities err")
+	}
+	return len(userIDs) + count, nil
+}
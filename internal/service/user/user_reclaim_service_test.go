@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	repo "github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 注销的邮箱在宽限期内不能注册，可以恢复，回收后可以被重新注册
+func TestUserService_ReclaimDeletedIdentifiers(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserBaseModel{}, &model.UserIdentityModel{})
+	model.DB = db
+
+	suffix := time.Now().UnixNano()
+	create := func(name string, deletedAt time.Time) (uint64, string) {
+		addr := fmt.Sprintf("%s_%d@example.com", name, suffix)
+		u := &model.UserBaseModel{Username: fmt.Sprintf("%s_%d", name, suffix), Email: addr}
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.userIdentityRepo.SaveUserIdentity(db, u.ID, model.IdentityProviderEmail, addr, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.userIdentityRepo.SoftDeleteUserIdentities(db, u.ID); err != nil {
+			t.Fatal(err)
+		}
+		db.Exec("UPDATE user_base SET deleted_at = ? WHERE id = ?", deletedAt, u.ID)
+		db.Exec("UPDATE user_identity SET deleted_at = ? WHERE user_id = ?", deletedAt, u.ID)
+		return u.ID, addr
+	}
+	grace := 30 * 24 * time.Hour
+	expiredUID, expired := create("expired", time.Now().Add(-grace-time.Hour))
+	recentUID, recent := create("recent", time.Now().Add(-time.Hour))
+
+	// 宽限期内邮箱仍然被占用，但不能用于登录
+	for _, addr := range []string{expired, recent} {
+		if err := srv.checkEmailAvailable(context.Background(), addr); err != errno.ErrEmailExist {
+			t.Fatalf("checkEmailAvailable(%s) = %v, want ErrEmailExist", addr, err)
+		}
+		if _, err := srv.getUserByIdentity(context.Background(), model.IdentityProviderEmail, addr); err == nil {
+			t.Fatalf("getUserByIdentity(%s) found a deleted identity", addr)
+		}
+	}
+
+	count, err := srv.ReclaimDeletedIdentifiers(context.Background(), grace, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 过期帐号的用户记录和登录身份各一条
+	if count != 2 {
+		t.Fatalf("ReclaimDeletedIdentifiers() = %d, want 2", count)
+	}
+	if err := srv.checkEmailAvailable(context.Background(), expired); err != nil {
+		t.Fatalf("checkEmailAvailable() after reclaim = %v, want nil", err)
+	}
+	if err := srv.checkEmailAvailable(context.Background(), recent); err != errno.ErrEmailExist {
+		t.Fatalf("checkEmailAvailable() within grace = %v, want ErrEmailExist", err)
+	}
+
+	u := model.UserBaseModel{}
+	if err := db.Unscoped().First(&u, expiredUID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !repo.IsTombstone(u.Username) || !repo.IsTombstone(u.Email) {
+		t.Fatalf("user = %s %s, want tombstones", u.Username, u.Email)
+	}
+
+	// 已回收的身份不能恢复，宽限期内的可以恢复
+	if n, err := srv.userIdentityRepo.RestoreUserIdentities(db, expiredUID); err != nil || n != 0 {
+		t.Fatalf("RestoreUserIdentities() reclaimed = %d, %v, want 0", n, err)
+	}
+	if n, err := srv.userIdentityRepo.RestoreUserIdentities(db, recentUID); err != nil || n != 1 {
+		t.Fatalf("RestoreUserIdentities() = %d, %v, want 1", n, err)
+	}
+	if got, err := srv.userIdentityRepo.GetIdentity(db, model.IdentityProviderEmail, recent); err != nil || got.ID == 0 {
+		t.Fatalf("GetIdentity() after restore = %+v, %v", got, err)
+	}
+}
@@ -1,20 +1,31 @@
 package user
 
 import (
+	"context"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 
 	"github.com/1024casts/snake/internal/idl"
 	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/internal/service/kpi"
 	"github.com/1024casts/snake/pkg/auth"
+	"github.com/1024casts/snake/pkg/clock"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/graph"
+	"github.com/1024casts/snake/pkg/idgen"
 	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/oauth"
+	"github.com/1024casts/snake/pkg/otp"
+	"github.com/1024casts/snake/pkg/pagination"
 	"github.com/1024casts/snake/pkg/token"
+	"github.com/1024casts/snake/pkg/tracing"
 )
 
 const (
@@ -22,40 +33,122 @@ const (
 	FollowStatusNormal int = 1 // 正常
 	// FollowStatusDelete 关注状态-删除
 	FollowStatusDelete = 0 // 删除
-
-	// MaxID 最大id
-	MaxID = 0xffffffffffff
 )
 
 // Service 用户服务接口定义
 // 使用大写的service对外保留方法
 type Service interface {
-	Register(ctx *gin.Context, username, email, password string) error
-	EmailLogin(ctx *gin.Context, email, password string) (tokenStr string, err error)
-	PhoneLogin(ctx *gin.Context, phone int, verifyCode int) (tokenStr string, err error)
-	GetUserByID(id uint64) (*model.UserBaseModel, error)
+	Register(ctx context.Context, username, email, password string) error
+	EmailLogin(ctx context.Context, email, password, userAgent, ip string) (tokenStr string, err error)
+	PhoneLogin(ctx context.Context, phone int, verifyCode int, userAgent, ip string) (tokenStr string, err error)
+	GetUserByID(ctx context.Context, id uint64) (*model.UserBaseModel, error)
 	GetUserInfoByID(id uint64) (*model.UserInfo, error)
-	GetUserByPhone(phone int) (*model.UserBaseModel, error)
-	GetUserByEmail(email string) (*model.UserBaseModel, error)
-	UpdateUser(id uint64, userMap map[string]interface{}) error
-	BatchGetUsers(userID uint64, userIDs []uint64) ([]*model.UserInfo, error)
+	GetUserByPhone(ctx context.Context, phone int) (*model.UserBaseModel, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.UserBaseModel, error)
+	GetUserByUsername(ctx context.Context, username string) (*model.UserBaseModel, error)
+	ResolveUsername(ctx context.Context, username string) (u *model.UserBaseModel, moved bool, err error)
+	UpdateUser(ctx context.Context, id uint64, userMap map[string]interface{}) error
+	UpdateProfile(ctx context.Context, userID uint64, userMap map[string]interface{}) error
+	UpdateProfileIfMatch(ctx context.Context, userID uint64, version int, userMap map[string]interface{}) error
+	BatchGetUsers(ctx context.Context, userID uint64, userIDs []uint64) ([]*model.UserInfo, error)
+
+	// 年龄验证
+	SetBirthday(ctx context.Context, userID uint64, birthday string) error
+	HideFollowList(u *model.UserBaseModel) bool
 
 	// 关注
-	IsFollowedUser(userID uint64, followedUID uint64) bool
-	AddUserFollow(userID uint64, followedUID uint64) error
-	CancelUserFollow(userID uint64, followedUID uint64) error
-	GetFollowingUserList(userID uint64, lastID uint64, limit int) ([]*model.UserFollowModel, error)
-	GetFollowerUserList(userID uint64, lastID uint64, limit int) ([]*model.UserFansModel, error)
+	IsFollowedUser(ctx context.Context, userID uint64, followedUID uint64) bool
+	AddUserFollow(ctx context.Context, userID uint64, followedUID uint64) error
+	CancelUserFollow(ctx context.Context, userID uint64, followedUID uint64) error
+	GetFollowingUserList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.UserFollowModel, error)
+	GetFollowerUserList(ctx context.Context, userID uint64, lastID uint64, limit int, order string) ([]*model.UserFansModel, error)
+	ExportFollowGraph(ctx context.Context, w graph.Writer, batchSize int) (int, error)
+
+	// 拉黑
+	BlockUser(ctx context.Context, userID uint64, blockedUID uint64) error
+	UnblockUser(ctx context.Context, userID uint64, blockedUID uint64) error
+	GetBlockedList(ctx context.Context, userID uint64, lastID uint64, limit int) ([]*model.UserBlockModel, error)
+
+	// 登录设备
+	RecordUserDevice(ctx context.Context, u *model.UserBaseModel, userAgent, ip string) error
+	GetUserDeviceList(ctx context.Context, userID uint64, region string) ([]*model.UserDeviceModel, error)
+
+	// 修改邮箱
+	RequestEmailChange(ctx context.Context, userID uint64, newEmail, password, ip string) error
+	ConfirmEmailChange(ctx context.Context, token, ip string) (bool, error)
+
+	// 重置密码
+	ForgotPassword(ctx context.Context, email, ip string) error
+	ResetPassword(ctx context.Context, token, password, ip string) error
+
+	// 验证邮箱
+	SendVerificationEmail(ctx context.Context, userID uint64) error
+	VerifyEmail(ctx context.Context, token, ip string) error
+	CheckEmailVerified(ctx context.Context, userID uint64) error
+
+	// 修改手机号
+	ChangePhone(ctx context.Context, userID uint64, oldVerifyCode, newPhone, newVerifyCode int, ip string) error
+
+	// 登录身份
+	GetUserIdentities(ctx context.Context, userID uint64) ([]*model.UserIdentityModel, error)
+	UnlinkIdentity(ctx context.Context, userID, identityID uint64, ip string) error
+
+	// 第三方帐号登录
+	OAuthLogin(ctx context.Context, info *oauth.UserInfo, userAgent, ip string) (tokenStr string, err error)
+
+	// 动作记录
+	GetMyActivity(ctx context.Context, userID uint64, beforeID uint64, limit int) ([]*model.UserActivityModel, error)
+	ArchiveActivity(ctx context.Context, a *model.UserActivityModel) error
+
+	// 付费会员
+	ActivateMembership(ctx context.Context, userID uint64, plan, orderNo string, days int, operatorID uint64, ip string) error
+
+	// 管理员模拟登录
+	Impersonate(ctx context.Context, adminID, userID uint64, reason, ip string) (tokenStr string, expiresAt time.Time, err error)
+
+	// 用户统计
+	IncrUserViewCount(userID uint64) error
+	GetFollowCounts(ctx context.Context, userIDs []uint64) (map[uint64]*model.FollowCounts, error)
+	FlushUserStat(ctx context.Context) (int, error)
+	ReconcileFollowCounts(ctx context.Context, batchSize int) (int, error)
+
+	// 热点用户cache预热
+	WarmHotUserCache(ctx context.Context, limit int) (int, error)
+
+	// 停用、恢复帐号
+	DeactivateUser(ctx context.Context, userID, operatorID uint64, ip string) error
+	ReactivateUser(ctx context.Context, userID, operatorID uint64, ip string) error
+
+	// 回收已删除帐号的标识
+	ReclaimDeletedIdentifiers(ctx context.Context, grace time.Duration, limit int) (int, error)
 }
 
 // Svc 直接初始化，可以避免在使用时再实例化
 var Svc = NewUserService()
 
+// logger 用户服务的日志，可以通过 log.module_levels 单独调整级别
+var logger = log.Named("user_service")
+
 // 用小写的 service 实现接口中定义的方法
 type userService struct {
 	userRepo       user.BaseRepo
 	userFollowRepo user.FollowRepo
 	userStatRepo   user.StatRepo
+	userDeviceRepo user.DeviceRepo
+	userBlockRepo  user.BlockRepo
+
+	userEmailChangeRepo user.EmailChangeRepo
+	userIdentityRepo    user.IdentityRepo
+	userEventRepo       user.EventRepo
+	userMembershipRepo  user.MembershipRepo
+
+	userUsernameHistoryRepo user.UsernameHistoryRepo
+	userOAuthRepo           user.OAuthRepo
+	userActivityRepo        user.ActivityRepo
+
+	// clock 和 idGen 在测试中替换为 clock.Fake、idgen.Sequence，使过期时间、签发时间等可预期
+	clock clock.Clock
+	idGen idgen.IDGenerator
 }
 
 // NewUserService 实例化一个userService
@@ -66,11 +159,40 @@ func NewUserService() Service {
 		userRepo:       user.NewUserRepo(),
 		userFollowRepo: user.NewUserFollowRepo(),
 		userStatRepo:   user.NewUserStatRepo(),
+		userDeviceRepo: user.NewUserDeviceRepo(),
+		userBlockRepo:  user.NewUserBlockRepo(),
+
+		userEmailChangeRepo: user.NewUserEmailChangeRepo(),
+		userIdentityRepo:    user.NewUserIdentityRepo(),
+		userEventRepo:       user.NewUserEventRepo(),
+		userMembershipRepo:  user.NewUserMembershipRepo(),
+
+		userUsernameHistoryRepo: user.NewUserUsernameHistoryRepo(),
+		userOAuthRepo:           user.NewUserOAuthRepo(),
+		userActivityRepo:        user.NewUserActivityRepo(),
+
+		clock: clock.Real,
+		idGen: idgen.Default,
 	}
 }
 
 // Register 注册用户
-func (srv *userService) Register(ctx *gin.Context, username, email, password string) error {
+func (srv *userService) Register(ctx context.Context, username, email, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "userService.Register")
+	defer func() { tracing.End(span, err) }()
+
+	// 墓碑值只用于已回收的标识，不能注册
+	if user.IsTombstone(email) {
+		return errno.ErrParam
+	}
+	if err := srv.checkUsername(ctx, 0, username); err != nil {
+		return err
+	}
+	// 已注销但还在宽限期内的邮箱也不能注册
+	if err := srv.checkEmailAvailable(ctx, email); err != nil {
+		return err
+	}
+
 	pwd, err := auth.Encrypt(password)
 	if err != nil {
 		return errors.Wrapf(err, "encrypt password err")
@@ -80,19 +202,45 @@ func (srv *userService) Register(ctx *gin.Context, username, email, password str
 		Username:  username,
 		Password:  pwd,
 		Email:     email,
-		CreatedAt: time.Time{},
-		UpdatedAt: time.Time{},
+		CreatedAt: srv.clock.Now(),
+		UpdatedAt: srv.clock.Now(),
 	}
-	_, err = srv.userRepo.Create(model.GetDB(), u)
+	tx := model.WithContext(ctx).Begin()
+	userID, err := srv.userRepo.Create(tx, u)
 	if err != nil {
+		tx.Rollback()
 		return errors.Wrapf(err, "create user")
 	}
+	err = srv.userIdentityRepo.SaveUserIdentity(tx, userID, model.IdentityProviderEmail, email, false)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "create user identity")
+	}
+	event, err := srv.recordEvent(tx, userID, model.UserEventCreated, 0)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	srv.publishEvent(ctx, event)
+	kpi.RecordRegistration("email")
+
+	// 发送失败时可以在登录后重新发送
+	u.ID = userID
+	if err := srv.sendVerificationEmail(&u); err != nil {
+		logger.WithContext(ctx).Warnf("[register] send verification email err: %v, uid: %d", err, userID)
+	}
 	return nil
 }
 
-// EmailLogin 邮箱登录
-func (srv *userService) EmailLogin(ctx *gin.Context, email, password string) (tokenStr string, err error) {
-	u, err := srv.GetUserByEmail(email)
+// EmailLogin 邮箱登录，userAgent 和 ip 用于记录登录设备
+func (srv *userService) EmailLogin(ctx context.Context, email, password, userAgent, ip string) (tokenStr string, err error) {
+	defer func() { kpi.RecordLogin("email", err == nil) }()
+
+	u, err := srv.GetUserByEmail(ctx, email)
 	if err != nil {
 		return "", errors.Wrapf(err, "get user info err by email")
 	}
@@ -103,45 +251,82 @@ func (srv *userService) EmailLogin(ctx *gin.Context, email, password string) (to
 		return "", errors.Wrapf(err, "password compare err")
 	}
 
+	// 封禁中的用户不能登录
+	if err := ban.Svc.CheckBan(ctx, u.ID); err != nil {
+		return "", err
+	}
+
 	// 签发签名 Sign the json web token.
-	tokenStr, err = token.Sign(ctx, token.Context{UserID: u.ID, Username: u.Username}, "")
+	tokenStr, err = srv.signToken(ctx, token.Context{UserID: u.ID, Username: u.Username, LoginType: token.LoginTypeEmail, Region: u.Region})
 	if err != nil {
 		return "", errors.Wrapf(err, "gen token sign err")
 	}
 
+	// 记录登录设备
+	if err := srv.RecordUserDevice(ctx, u, userAgent, ip); err != nil {
+		logger.WithContext(ctx).Warnf("[login] record user device err: %v", err)
+	}
+	srv.recordActivity(ctx, u.ID, model.ActivityLogin, ip, map[string]interface{}{"method": token.LoginTypeEmail})
+
 	return tokenStr, nil
 }
 
-// PhoneLogin 邮箱登录
-func (srv *userService) PhoneLogin(ctx *gin.Context, phone int, verifyCode int) (tokenStr string, err error) {
+// PhoneLogin 手机登录，userAgent 和 ip 用于记录登录设备
+// 验证码错误时返回 errno.ErrVerifyCode，错误次数过多时返回 errno.ErrVerifyCodeAttempts
+func (srv *userService) PhoneLogin(ctx context.Context, phone int, verifyCode int, userAgent, ip string) (tokenStr string, err error) {
+	defer func() { kpi.RecordLogin("phone", err == nil) }()
+
+	if err := verifyPhoneCodes(ctx, otp.PurposeLogin, map[int]int{phone: verifyCode}); err != nil {
+		return "", err
+	}
+
 	// 如果是已经注册用户，则通过手机号获取用户信息
-	u, err := srv.GetUserByPhone(phone)
-	if err != nil {
+	u, err := srv.GetUserByPhone(ctx, phone)
+	if err != nil && errors.Cause(err) != gorm.ErrRecordNotFound {
 		return "", errors.Wrapf(err, "[login] get u info err")
 	}
 
 	// 否则新建用户信息, 并取得用户信息
-	if u.ID == 0 {
-		u := model.UserBaseModel{
-			Phone:    phone,
-			Username: strconv.Itoa(phone),
-		}
-		u.ID, err = srv.userRepo.Create(model.GetDB(), u)
+	if err != nil {
+		u, err = srv.createPhoneUser(ctx, phone)
 		if err != nil {
 			return "", errors.Wrapf(err, "[login] create user err")
 		}
 	}
 
+	// 封禁中的用户不能登录
+	if err := ban.Svc.CheckBan(ctx, u.ID); err != nil {
+		return "", err
+	}
+
 	// 签发签名 Sign the json web token.
-	tokenStr, err = token.Sign(ctx, token.Context{UserID: u.ID, Username: u.Username}, "")
+	tokenStr, err = srv.signToken(ctx, token.Context{UserID: u.ID, Username: u.Username, LoginType: token.LoginTypePhone, Region: u.Region})
 	if err != nil {
 		return "", errors.Wrapf(err, "[login] gen token sign err")
 	}
+
+	// 记录登录设备
+	if err := srv.RecordUserDevice(ctx, u, userAgent, ip); err != nil {
+		logger.WithContext(ctx).Warnf("[login] record user device err: %v", err)
+	}
+	srv.recordActivity(ctx, u.ID, model.ActivityLogin, ip, map[string]interface{}{"method": token.LoginTypePhone})
+
 	return tokenStr, nil
 }
 
-func (srv *userService) UpdateUser(id uint64, userMap map[string]interface{}) error {
-	err := srv.userRepo.Update(model.GetDB(), id, userMap)
+// signToken 签发 token，签发时间和 token id 由注入的 clock、idGen 生成
+func (srv *userService) signToken(ctx context.Context, c token.Context) (string, error) {
+	id, err := srv.idGen.NextID()
+	if err != nil {
+		return "", errors.Wrap(err, "[user_service] gen token id err")
+	}
+	c.ID = strconv.FormatUint(id, 10)
+	c.IssuedAt = srv.clock.Now().Unix()
+	return token.Sign(ctx, c, "")
+}
+
+func (srv *userService) UpdateUser(ctx context.Context, id uint64, userMap map[string]interface{}) error {
+	err := srv.userRepo.Update(model.WithContext(ctx), id, userMap)
 
 	if err != nil {
 		return err
@@ -150,9 +335,9 @@ func (srv *userService) UpdateUser(id uint64, userMap map[string]interface{}) er
 	return nil
 }
 
-// GetUserByID 获取单条用户信息
-func (srv *userService) GetUserByID(id uint64) (*model.UserBaseModel, error) {
-	userModel, err := srv.userRepo.GetUserByID(model.GetDB(), id)
+// GetUserByID 获取单条用户信息，可以读从库，不能接受延迟时使用 model.WithPrimary 标记 ctx
+func (srv *userService) GetUserByID(ctx context.Context, id uint64) (*model.UserBaseModel, error) {
+	userModel, err := srv.userRepo.GetUserByID(model.ReadContext(ctx), id)
 	if err != nil {
 		return userModel, errors.Wrapf(err, "get user info err from db by id: %d", id)
 	}
@@ -162,26 +347,35 @@ func (srv *userService) GetUserByID(id uint64) (*model.UserBaseModel, error) {
 
 // GetUserInfoByID 获取组装好的用户数据
 func (srv *userService) GetUserInfoByID(id uint64) (*model.UserInfo, error) {
-	userInfos, err := srv.BatchGetUsers(id, []uint64{id})
+	userInfos, err := srv.BatchGetUsers(context.Background(), id, []uint64{id})
 	if err != nil {
 		return nil, err
 	}
+	if len(userInfos) == 0 {
+		return nil, errno.ErrUserNotFound
+	}
 	return userInfos[0], nil
 }
 
 // BatchGetUsers 批量获取用户信息
 // 1. 处理关注和被关注状态
 // 2. 获取关注和粉丝数据
-func (srv *userService) BatchGetUsers(userID uint64, userIDs []uint64) ([]*model.UserInfo, error) {
-	infos := make([]*model.UserInfo, 0)
+// 数据库查询使用请求 ctx 的预算，超时或客户端断开后停止，可以读从库
+func (srv *userService) BatchGetUsers(ctx context.Context, userID uint64, userIDs []uint64) (infos []*model.UserInfo, err error) {
+	ctx, span := tracing.Start(ctx, "userService.BatchGetUsers",
+		attribute.Int64("user.id", int64(userID)), attribute.Int("user.count", len(userIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	infos = make([]*model.UserInfo, 0)
+	db := model.ReadContext(ctx)
 	// 批量获取用户信息
-	users, err := srv.userRepo.GetUsersByIds(model.GetDB(), userIDs)
+	users, err := srv.userRepo.GetUsersByIds(db, userIDs)
 	if err != nil {
 		return nil, errors.Wrap(err, "[user_service] batch get user err")
 	}
 
 	// 获取当前用户信息
-	curUser, err := srv.userRepo.GetUserByID(model.GetDB(), userID)
+	curUser, err := srv.userRepo.GetUserByID(db, userID)
 	if err != nil {
 		return nil, errors.Wrap(err, "[user_service] get one user err")
 	}
@@ -199,19 +393,19 @@ func (srv *userService) BatchGetUsers(userID uint64, userIDs []uint64) ([]*model
 	finished := make(chan bool, 1)
 
 	// 获取自己对关注列表的关注状态
-	userFollowMap, err := srv.userFollowRepo.GetFollowByUIds(userID, userIDs)
+	userFollowMap, err := srv.userFollowRepo.GetFollowByUIds(db, userID, userIDs)
 	if err != nil {
 		errChan <- err
 	}
 
 	// 获取自己对关注列表的被关注状态
-	userFansMap, err := srv.userFollowRepo.GetFansByUIds(userID, userIDs)
+	userFansMap, err := srv.userFollowRepo.GetFansByUIds(db, userID, userIDs)
 	if err != nil {
 		errChan <- err
 	}
 
-	// 获取用户统计
-	userStatMap, err := srv.userStatRepo.GetUserStatByIDs(model.GetDB(), userIDs)
+	// 获取关注数和粉丝数
+	followCountsMap, err := srv.userStatRepo.GetFollowCounts(db, userIDs)
 	if err != nil {
 		errChan <- err
 	}
@@ -225,27 +419,26 @@ func (srv *userService) BatchGetUsers(userID uint64, userIDs []uint64) ([]*model
 			userList.Lock.Lock()
 			defer userList.Lock.Unlock()
 
+			// 取消关注和拉黑只修改状态，记录仍然存在
 			isFollow := 0
-			_, ok := userFollowMap[u.ID]
-			if ok {
+			if f, ok := userFollowMap[u.ID]; ok && f.Status == FollowStatusNormal {
 				isFollow = 1
 			}
 
 			isFollowed := 0
-			_, ok = userFansMap[u.ID]
-			if ok {
+			if f, ok := userFansMap[u.ID]; ok && f.Status == FollowStatusNormal {
 				isFollowed = 1
 			}
 
-			userStatMap, ok := userStatMap[u.ID]
-			if !ok {
-				userStatMap = nil
+			var userStat *model.UserStatModel
+			if c, ok := followCountsMap[u.ID]; ok {
+				userStat = &model.UserStatModel{UserID: u.ID, FollowCount: c.FollowCount, FollowerCount: c.FollowerCount}
 			}
 
 			transInput := &idl.TransferUserInput{
 				CurUser:  curUser,
 				User:     u,
-				UserStat: userStatMap,
+				UserStat: userStat,
 				IsFollow: isFollow,
 				IsFans:   isFollowed,
 			}
@@ -266,30 +459,32 @@ func (srv *userService) BatchGetUsers(userID uint64, userIDs []uint64) ([]*model
 	select {
 	case <-finished:
 	case err := <-errChan:
-		log.Warnf("[user_service] batch get user err chan: %v", err)
+		logger.WithContext(ctx).Warnf("[user_service] batch get user err chan: %v", err)
 		return nil, err
 	}
 
-	// 根据原有id合并数据
+	// 根据原有id合并数据，不存在和已停用的用户不返回
 	for _, id := range ids {
-		infos = append(infos, userList.IDMap[id])
+		if info, ok := userList.IDMap[id]; ok {
+			infos = append(infos, info)
+		}
 	}
 
 	return infos, nil
 }
 
-func (srv *userService) GetUserByPhone(phone int) (*model.UserBaseModel, error) {
-	userModel, err := srv.userRepo.GetUserByPhone(model.GetDB(), phone)
-	if err != nil || gorm.IsRecordNotFoundError(err) {
+func (srv *userService) GetUserByPhone(ctx context.Context, phone int) (*model.UserBaseModel, error) {
+	userModel, err := srv.getUserByIdentity(ctx, model.IdentityProviderPhone, strconv.Itoa(phone))
+	if err != nil {
 		return userModel, errors.Wrapf(err, "get user info err from db by phone: %d", phone)
 	}
 
 	return userModel, nil
 }
 
-func (srv *userService) GetUserByEmail(email string) (*model.UserBaseModel, error) {
-	userModel, err := srv.userRepo.GetUserByEmail(model.GetDB(), email)
-	if err != nil || gorm.IsRecordNotFoundError(err) {
+func (srv *userService) GetUserByEmail(ctx context.Context, email string) (*model.UserBaseModel, error) {
+	userModel, err := srv.getUserByIdentity(ctx, model.IdentityProviderEmail, email)
+	if err != nil {
 		return userModel, errors.Wrapf(err, "get user info err from db by email: %s", email)
 	}
 
@@ -297,37 +492,46 @@ func (srv *userService) GetUserByEmail(email string) (*model.UserBaseModel, erro
 }
 
 // 获取用户关注
-func (srv *userService) GetFollowUser(userID uint64, followedUID uint64) (*model.UserFollowModel, error) {
+func (srv *userService) GetFollowUser(ctx context.Context, userID uint64, followedUID uint64) (*model.UserFollowModel, error) {
 	userFollowModel := &model.UserFollowModel{}
-	result := model.GetDB().
+	result := model.WithContext(ctx).
 		Where("user_id=? AND followed_uid=? ", userID, followedUID).
 		Find(userFollowModel)
 
 	return userFollowModel, result.Error
 }
 
-// IsFollowedUser 是否关注过某用户
-func (srv *userService) IsFollowedUser(userID uint64, followedUID uint64) bool {
+// IsFollowedUser 是否关注过某用户，双方有拉黑关系时返回 false
+func (srv *userService) IsFollowedUser(ctx context.Context, userID uint64, followedUID uint64) bool {
 	userFollowModel := &model.UserFollowModel{}
-	result := model.GetDB().
+	result := model.WithContext(ctx).
 		Where("user_id=? AND followed_uid=? ", userID, followedUID).
 		Find(userFollowModel)
 
 	if err := result.Error; err != nil {
-		log.Warnf("[user_service] get user follow err, %v", err)
+		logger.WithContext(ctx).Warnf("[user_service] get user follow err, %v", err)
 		return false
 	}
 
-	if userFollowModel.ID > 0 && userFollowModel.Status == FollowStatusNormal {
-		return true
+	if userFollowModel.ID == 0 || userFollowModel.Status != FollowStatusNormal {
+		return false
 	}
 
-	return false
+	// 拉黑时会取消双方的关注，这里再排除拉黑后残留的关注
+	return !srv.isBlocked(ctx, userID, followedUID)
 }
 
-// AddUserFollow 添加关注
-func (srv *userService) AddUserFollow(userID uint64, followedUID uint64) error {
-	db := model.GetDB()
+// AddUserFollow 添加关注，一方拉黑了另一方时返回 errno.ErrUserBlocked
+func (srv *userService) AddUserFollow(ctx context.Context, userID uint64, followedUID uint64) error {
+	db := model.WithContext(ctx)
+	blocked, err := srv.userBlockRepo.IsBlockedEither(db, userID, followedUID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errno.ErrUserBlocked
+	}
+
 	tx := db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -336,7 +540,7 @@ func (srv *userService) AddUserFollow(userID uint64, followedUID uint64) error {
 	}()
 
 	// 添加到关注表
-	err := srv.userFollowRepo.CreateUserFollow(tx, userID, followedUID)
+	err = srv.userFollowRepo.CreateUserFollow(tx, userID, followedUID)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "insert into user follow err")
@@ -349,17 +553,10 @@ func (srv *userService) AddUserFollow(userID uint64, followedUID uint64) error {
 		return errors.Wrap(err, "insert into user fans err")
 	}
 
-	// 添加关注数
-	err = srv.userStatRepo.IncrFollowCount(tx, userID, 1)
+	event, err := srv.recordEvent(tx, userID, model.UserEventFollowed, followedUID)
 	if err != nil {
 		tx.Rollback()
-		return errors.Wrap(err, "update user follow count err")
-	}
-
-	// 添加粉丝数
-	err = srv.userStatRepo.IncrFollowerCount(tx, followedUID, 1)
-	if err != nil {
-		return errors.Wrap(err, "update user fans count err")
+		return err
 	}
 
 	err = tx.Commit().Error
@@ -367,13 +564,22 @@ func (srv *userService) AddUserFollow(userID uint64, followedUID uint64) error {
 		tx.Rollback()
 		return errors.Wrap(err, "tx commit err")
 	}
+	srv.publishEvent(ctx, event)
+	kpi.RecordFollow("follow")
+
+	// 添加关注数和粉丝数，事务提交后写入计数缓冲
+	srv.incrFollowStat(ctx, userID, followedUID, 1)
+
+	// 通知被关注的用户
+	srv.notifyNewFollower(ctx, userID, followedUID)
+	srv.recordActivity(ctx, userID, model.ActivityFollow, "", map[string]interface{}{"followed_uid": followedUID})
 
 	return nil
 }
 
 // CancelUserFollow 取消用户关注
-func (srv *userService) CancelUserFollow(userID uint64, followedUID uint64) error {
-	db := model.GetDB()
+func (srv *userService) CancelUserFollow(ctx context.Context, userID uint64, followedUID uint64) error {
+	db := model.WithContext(ctx)
 	tx := db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -381,49 +587,55 @@ func (srv *userService) CancelUserFollow(userID uint64, followedUID uint64) erro
 		}
 	}()
 
-	// 删除关注
-	err := srv.userFollowRepo.UpdateUserFollowStatus(tx, userID, followedUID, FollowStatusDelete)
+	event, err := srv.cancelFollow(tx, userID, followedUID)
 	if err != nil {
 		tx.Rollback()
-		return errors.Wrap(err, "update user follow err")
+		return err
 	}
 
-	// 删除粉丝
-	err = srv.userFollowRepo.UpdateUserFansStatus(tx, followedUID, userID, FollowStatusDelete)
+	err = tx.Commit().Error
 	if err != nil {
 		tx.Rollback()
-		return errors.Wrap(err, "update user follow err")
+		return errors.Wrap(err, "tx commit err")
 	}
+	srv.afterUnfollow(ctx, event, userID, followedUID)
 
-	// 减少关注数
-	err = srv.userStatRepo.IncrFollowCount(tx, userID, -1)
-	if err != nil {
-		tx.Rollback()
-		return errors.Wrap(err, "update user follow count err")
-	}
+	return nil
+}
 
-	// 减少粉丝数
-	err = srv.userStatRepo.IncrFollowerCount(tx, followedUID, -1)
+// cancelFollow 在事务中删除关注和粉丝，记录取消关注事件
+func (srv *userService) cancelFollow(tx *gorm.DB, userID uint64, followedUID uint64) (*model.UserEventModel, error) {
+	// 删除关注
+	err := srv.userFollowRepo.UpdateUserFollowStatus(tx, userID, followedUID, FollowStatusDelete)
 	if err != nil {
-		tx.Rollback()
-		return errors.Wrap(err, "update user fans count err")
+		return nil, errors.Wrap(err, "update user follow err")
 	}
 
-	err = tx.Commit().Error
+	// 删除粉丝
+	err = srv.userFollowRepo.UpdateUserFansStatus(tx, followedUID, userID, FollowStatusDelete)
 	if err != nil {
-		tx.Rollback()
-		return errors.Wrap(err, "tx commit err")
+		return nil, errors.Wrap(err, "update user follow err")
 	}
 
-	return nil
+	return srv.recordEvent(tx, userID, model.UserEventUnfollowed, followedUID)
 }
 
-// GetFollowingUserList 获取正在关注的用户列表
-func (srv *userService) GetFollowingUserList(userID uint64, lastID uint64, limit int) ([]*model.UserFollowModel, error) {
-	if lastID == 0 {
-		lastID = MaxID
-	}
-	userFollowList, err := srv.userFollowRepo.GetFollowingUserList(userID, lastID, limit)
+// afterUnfollow 取消关注的事务提交后发布事件、更新计数
+func (srv *userService) afterUnfollow(ctx context.Context, event *model.UserEventModel, userID uint64, followedUID uint64) {
+	srv.publishEvent(ctx, event)
+	kpi.RecordFollow("unfollow")
+
+	// 减少关注数和粉丝数，事务提交后写入计数缓冲
+	srv.incrFollowStat(ctx, userID, followedUID, -1)
+	srv.recordActivity(ctx, userID, model.ActivityUnfollow, "", map[string]interface{}{"followed_uid": followedUID})
+}
+
+// GetFollowingUserList 获取正在关注的用户列表，按id倒序返回id小于lastID的记录，lastID为0时从头开始
+func (srv *userService) GetFollowingUserList(ctx context.Context, userID uint64, lastID uint64, limit int) (_ []*model.UserFollowModel, err error) {
+	ctx, span := tracing.Start(ctx, "userService.GetFollowingUserList", attribute.Int64("user.id", int64(userID)))
+	defer func() { tracing.End(span, err) }()
+
+	userFollowList, err := srv.userFollowRepo.GetFollowingUserList(model.ReadContext(ctx), userID, pagination.BeforeID(lastID), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -431,15 +643,46 @@ func (srv *userService) GetFollowingUserList(userID uint64, lastID uint64, limit
 	return userFollowList, nil
 }
 
-// GetFollowerUserList 获取粉丝用户列表
-func (srv *userService) GetFollowerUserList(userID uint64, lastID uint64, limit int) ([]*model.UserFansModel, error) {
-	if lastID == 0 {
-		lastID = MaxID
-	}
-	userFollowerList, err := srv.userFollowRepo.GetFollowerUserList(userID, lastID, limit)
+// GetFollowerUserList 获取粉丝用户列表，分页方式同 GetFollowingUserList
+// order 为排序方式，见 model.FollowerOrderRecent 等，不返回已注销和封禁中的粉丝
+func (srv *userService) GetFollowerUserList(ctx context.Context, userID uint64, lastID uint64, limit int, order string) (_ []*model.UserFansModel, err error) {
+	ctx, span := tracing.Start(ctx, "userService.GetFollowerUserList",
+		attribute.Int64("user.id", int64(userID)), attribute.String("order", order))
+	defer func() { tracing.End(span, err) }()
+
+	userFollowerList, err := srv.userFollowRepo.GetFollowerUserList(model.ReadContext(ctx), userID, pagination.BeforeID(lastID), limit, order)
 	if err != nil {
 		return nil, err
 	}
 
 	return userFollowerList, nil
 }
+
+// ExportFollowGraph 分批导出所有有效的关注关系，边从关注者指向被关注者，返回导出的边数
+// 每批写完后 flush，ctx 取消时中断导出
+func (srv *userService) ExportFollowGraph(ctx context.Context, w graph.Writer, batchSize int) (int, error) {
+	var lastID uint64
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		follows, err := srv.userFollowRepo.ScanFollows(model.WithContext(ctx), lastID, batchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, f := range follows {
+			if err := w.WriteEdge(f.UserID, f.FollowedUID); err != nil {
+				return total, errors.Wrap(err, "[user_service] write follow edge err")
+			}
+		}
+		total += len(follows)
+		if err := graph.Flush(w); err != nil {
+			return total, errors.Wrap(err, "[user_service] flush follow graph err")
+		}
+		if len(follows) < batchSize {
+			return total, nil
+		}
+		lastID = follows[len(follows)-1].ID
+	}
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/clock"
+	"github.com/1024casts/snake/pkg/idgen"
+	"github.com/1024casts/snake/pkg/token"
+)
+
+func TestUserService_signToken(t *testing.T) {
+	now := time.Date(2020, 10, 1, 8, 0, 0, 0, time.UTC)
+	srv := &userService{clock: clock.NewFake(now), idGen: idgen.NewSequence(100)}
+
+	for _, wantID := range []string{"100", "101"} {
+		tokenStr, err := srv.signToken(context.Background(), token.Context{UserID: 1, Username: "snake"})
+		if err != nil {
+			t.Fatalf("signToken() err: %v", err)
+		}
+		ctx, err := token.Parse(tokenStr, "")
+		if err != nil {
+			t.Fatalf("Parse() err: %v", err)
+		}
+		if ctx.ID != wantID || ctx.IssuedAt != now.Unix() {
+			t.Errorf("signToken() = %+v, want id %s and iat %d", ctx, wantID, now.Unix())
+		}
+	}
+}
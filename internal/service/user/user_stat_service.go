@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/tracing"
+)
+
+// incrFollowStat 更新关注数和粉丝数
+// 计数写入缓冲失败不影响关注操作，只记录日志
+func (srv *userService) incrFollowStat(ctx context.Context, userID uint64, followedUID uint64, step int) {
+	if err := srv.userStatRepo.IncrFollowCount(userID, step); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] incr follow count err: %v, uid: %d", err, userID)
+	}
+	if err := srv.userStatRepo.IncrFollowerCount(followedUID, step); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] incr follower count err: %v, uid: %d", err, followedUID)
+	}
+}
+
+// IncrUserViewCount 增加用户主页浏览数
+func (srv *userService) IncrUserViewCount(userID uint64) error {
+	err := srv.userStatRepo.IncrViewCount(userID, 1)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] incr user view count err, uid: %d", userID)
+	}
+	return nil
+}
+
+// GetFollowCounts 批量获取关注数和粉丝数，用于主页头部等只需要计数的地方
+func (srv *userService) GetFollowCounts(ctx context.Context, userIDs []uint64) (_ map[uint64]*model.FollowCounts, err error) {
+	_, span := tracing.Start(ctx, "userService.GetFollowCounts", attribute.Int("user.count", len(userIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(userIDs) == 0 {
+		return make(map[uint64]*model.FollowCounts), nil
+	}
+	counts, err := srv.userStatRepo.GetFollowCounts(model.WithContext(ctx), userIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "[user_service] get follow counts err")
+	}
+	return counts, nil
+}
+
+// FlushUserStat 将计数缓冲中的用户统计数据写入数据库
+func (srv *userService) FlushUserStat(ctx context.Context) (int, error) {
+	count, err := srv.userStatRepo.FlushStat(model.WithContext(ctx))
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_service] flush user stat err")
+	}
+	return count, nil
+}
+
+// ReconcileFollowCounts 按关注表、粉丝表重新统计所有用户的关注数和粉丝数，修正计数缓冲丢失增量等原因造成的偏差
+// 由定时任务调用，每批 batchSize 个用户，返回修正的用户数
+func (srv *userService) ReconcileFollowCounts(ctx context.Context, batchSize int) (int, error) {
+	db := model.WithContext(ctx)
+	var lastID uint64
+	fixed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return fixed, err
+		}
+		userIDs, err := srv.userRepo.ScanUserIDs(db, map[string]interface{}{}, lastID, batchSize)
+		if err != nil {
+			return fixed, err
+		}
+		if len(userIDs) == 0 {
+			return fixed, nil
+		}
+		lastID = userIDs[len(userIDs)-1]
+
+		follows, err := srv.userFollowRepo.CountFollowsByUIds(db, userIDs)
+		if err != nil {
+			return fixed, err
+		}
+		fans, err := srv.userFollowRepo.CountFansByUIds(db, userIDs)
+		if err != nil {
+			return fixed, err
+		}
+		actual := make([]*model.FollowCounts, 0, len(userIDs))
+		for _, id := range userIDs {
+			actual = append(actual, &model.FollowCounts{UserID: id, FollowCount: follows[id], FollowerCount: fans[id]})
+		}
+		n, err := srv.userStatRepo.ReconcileFollowCounts(db, actual)
+		fixed += n
+		if err != nil {
+			return fixed, errors.Wrap(err, "[user_service] reconcile follow counts err")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/counter"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 关注数和粉丝数为数据库中的值加上计数缓冲中的增量，数据库中的值在 cache 删除前不再查询
+func TestUserService_GetFollowCounts(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	// 用户 cache 实例化时会重新连接 redis.addr，计数缓冲绑定的是重新连接后的客户端
+	addr := viper.GetString("redis.addr")
+	viper.Set("redis.addr", redis.RedisClient.Options().Addr)
+	t.Cleanup(func() { viper.Set("redis.addr", addr) })
+	srv.userStatRepo = user.NewUserStatRepo()
+	db, _ := openBenchDB(t)
+	model.DB = db
+
+	base := uint64(time.Now().UnixNano() % 1e12 * 10)
+	withStat, withoutStat, negative := base+1, base+2, base+3
+	db.Create(&model.UserStatModel{UserID: withStat, FollowCount: 3, FollowerCount: 10})
+	_ = srv.userStatRepo.IncrFollowerCount(withStat, 2)
+	_ = srv.userStatRepo.IncrFollowCount(withoutStat, 1)
+	// 增量为负数时不返回负数
+	_ = srv.userStatRepo.IncrFollowerCount(negative, -1)
+
+	want := map[uint64][2]int{withStat: {3, 12}, withoutStat: {1, 0}, negative: {0, 0}}
+	check := func() {
+		t.Helper()
+		counts, err := srv.GetFollowCounts(context.Background(), []uint64{withStat, withoutStat, negative})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, w := range want {
+			c := counts[id]
+			if c == nil || c.FollowCount != w[0] || c.FollowerCount != w[1] {
+				t.Fatalf("counts[%d] = %+v, want %v", id, c, w)
+			}
+		}
+	}
+	check()
+
+	// 命中 cache 时不再查询数据库，只有缓冲中的增量生效
+	db.Model(&model.UserStatModel{}).Where("user_id = ?", withStat).Update("follower_count", 100)
+	_ = srv.userStatRepo.IncrFollowerCount(withStat, 1)
+	want[withStat] = [2]int{3, 13}
+	check()
+}
+
+// 修正后关注数、粉丝数和关注关系一致，计数缓冲和写入中断留下的临时key中的增量不会被重复计算
+func TestUserService_ReconcileFollowCounts(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	addr := viper.GetString("redis.addr")
+	viper.Set("redis.addr", redis.RedisClient.Options().Addr)
+	t.Cleanup(func() { viper.Set("redis.addr", addr) })
+	srv.userStatRepo = user.NewUserStatRepo()
+	db, _ := openBenchDB(t)
+	model.DB = db
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	a := &model.UserBaseModel{Username: fmt.Sprintf("reconcile_a_%d", suffix)}
+	b := &model.UserBaseModel{Username: fmt.Sprintf("reconcile_b_%d", suffix)}
+	for _, u := range []*model.UserBaseModel{a, b} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a 关注了 b，数据库中的计数有偏差，之前写入中断留下的临时key中还有一次未写入的关注
+	db.Create(&model.UserFollowModel{UserID: a.ID, FollowedUID: b.ID, Status: FollowStatusNormal})
+	db.Create(&model.UserFansModel{UserID: b.ID, FollowerUID: a.ID, Status: FollowStatusNormal})
+	db.Create(&model.UserStatModel{UserID: a.ID, FollowCount: 5, FollowerCount: 2})
+	redis.RedisClient.HSet(fmt.Sprintf("%s:user_stat:flushing:%d", counter.PrefixCounterKey, suffix), fmt.Sprintf("%d:follower_count", b.ID), 1)
+
+	fixed, err := srv.ReconcileFollowCounts(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 1 {
+		t.Fatalf("fixed = %d, want 1", fixed)
+	}
+	want := map[uint64][2]int{a.ID: {1, 0}, b.ID: {0, 1}}
+	counts, err := srv.GetFollowCounts(ctx, []uint64{a.ID, b.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, w := range want {
+		if c := counts[id]; c.FollowCount != w[0] || c.FollowerCount != w[1] {
+			t.Fatalf("counts[%d] = %+v, want %v", id, c, w)
+		}
+	}
+
+	// 写入缓冲后仍然一致，再次修正时没有偏差
+	if _, err := srv.FlushUserStat(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fixed, _ := srv.ReconcileFollowCounts(ctx, 10); fixed != 0 {
+		t.Fatalf("fixed after flush = %d, want 0", fixed)
+	}
+}
+
+// 数据库中的计数偏小时，写入负的增量后为0，不会超出 unsigned 的范围
+func TestUserService_FlushUserStat_NonNegative(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	addr := viper.GetString("redis.addr")
+	viper.Set("redis.addr", redis.RedisClient.Options().Addr)
+	t.Cleanup(func() { viper.Set("redis.addr", addr) })
+	srv.userStatRepo = user.NewUserStatRepo()
+	db, _ := openBenchDB(t)
+	model.DB = db
+	ctx := context.Background()
+
+	userID := uint64(time.Now().UnixNano()%1e12*10 + 4)
+	db.Create(&model.UserStatModel{UserID: userID, FollowCount: 2, FollowerCount: 1})
+	_ = srv.userStatRepo.IncrFollowCount(userID, 1)
+	_ = srv.userStatRepo.IncrFollowerCount(userID, -3)
+	if _, err := srv.FlushUserStat(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stat := model.UserStatModel{}
+	if err := db.Where("user_id = ?", userID).First(&stat).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stat.FollowCount != 3 || stat.FollowerCount != 0 {
+		t.Fatalf("stat = %d, %d, want 3, 0", stat.FollowCount, stat.FollowerCount)
+	}
+}
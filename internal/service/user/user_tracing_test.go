@@ -0,0 +1,50 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/tracing"
+)
+
+// 请求 ctx 中的 span 经过 service 传递到数据库查询
+func TestTracing_ServiceToRepository(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := &userService{userFollowRepo: user.NewUserFollowRepo()}
+	db, _ := openBenchDB(t)
+	model.DB = db
+
+	sr := tracetest.NewSpanRecorder()
+	tracing.SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer tracing.SetProvider(sdktrace.NewTracerProvider())
+
+	ctx, root := tracing.StartServer(context.Background(), "GET /v1/users/:id/following")
+	if _, err := srv.GetFollowingUserList(ctx, 1, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	parents := make(map[string]string)
+	ids := make(map[string]string)
+	for _, s := range sr.Ended() {
+		name := s.Name()
+		if strings.HasPrefix(name, "gorm.") {
+			name = "gorm"
+		}
+		ids[name] = s.SpanContext().SpanID().String()
+		parents[name] = s.Parent().SpanID().String()
+	}
+	if parents["userService.GetFollowingUserList"] != ids["GET /v1/users/:id/following"] {
+		t.Fatalf("service span parent = %q, want request span %q", parents["userService.GetFollowingUserList"], ids["GET /v1/users/:id/following"])
+	}
+	if parents["gorm"] != ids["userService.GetFollowingUserList"] {
+		t.Fatalf("gorm span parent = %q, want service span %q, spans: %v", parents["gorm"], ids["userService.GetFollowingUserList"], ids)
+	}
+}
@@ -0,0 +1,90 @@
+package user
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/repository/user"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// defaultReservedUsernames 内置的保留用户名，和系统功能、页面路径重名，可以通过 account.reserved_usernames 追加
+var defaultReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "official", "snake",
+	"api", "u", "users", "v1", "static", "swagger", "debug",
+	"login", "logout", "register", "signup", "settings", "help", "support", "me",
+}
+
+// isReservedUsername 是否为保留用户名，不区分大小写
+func isReservedUsername(username string) bool {
+	for _, names := range [][]string{defaultReservedUsernames, viper.GetStringSlice("account.reserved_usernames")} {
+		for _, name := range names {
+			if strings.EqualFold(name, username) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkUsername 检查用户名是否可以被 userID 使用，注册时 userID 为0
+func (srv *userService) checkUsername(ctx context.Context, userID uint64, username string) error {
+	// 墓碑值只用于已回收的标识
+	if strings.TrimSpace(username) == "" || user.IsTombstone(username) {
+		return errno.ErrParam
+	}
+	if isReservedUsername(username) {
+		return errno.ErrUsernameReserved
+	}
+	// 已停用的帐号在回收前仍然占用用户名，直接查库
+	taken, err := srv.userRepo.IsUsernameTaken(model.WithContext(ctx), username, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] check username err, username: %s", username)
+	}
+	if taken {
+		return errno.ErrUsernameExist
+	}
+	return nil
+}
+
+// GetUserByUsername 通过用户名获取用户，不存在时返回空结构体
+func (srv *userService) GetUserByUsername(ctx context.Context, username string) (*model.UserBaseModel, error) {
+	u, err := srv.userRepo.GetUserByUsername(model.WithContext(ctx), username)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[user_service] get user by username err, username: %s", username)
+	}
+	return u, nil
+}
+
+// ResolveUsername 通过用户名获取用户，用于主页链接
+// 没有用户使用该用户名时查找改掉该用户名的用户，moved 为 true，调用方重定向到当前的用户名
+func (srv *userService) ResolveUsername(ctx context.Context, username string) (u *model.UserBaseModel, moved bool, err error) {
+	u, err = srv.GetUserByUsername(ctx, username)
+	if err != nil || u.ID > 0 {
+		return u, false, err
+	}
+
+	userID, err := srv.userUsernameHistoryRepo.GetUserIDByOldUsername(model.WithContext(ctx), username)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "[user_service] get username history err, username: %s", username)
+	}
+	if userID == 0 {
+		return u, false, nil
+	}
+	u, err = srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	return u, u.ID > 0, nil
+}
+
+// recordUsernameChange 记录修改前的用户名，失败时只记录日志，旧的主页链接不能再重定向
+func (srv *userService) recordUsernameChange(ctx context.Context, userID uint64, oldUsername string) {
+	if err := srv.userUsernameHistoryRepo.AddUsernameHistory(model.WithContext(ctx), userID, oldUsername); err != nil {
+		logger.WithContext(ctx).Warnf("[user_service] add username history err: %v, uid: %d", err, userID)
+	}
+}
@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// 修改用户名后新用户名立即可以访问，旧用户名重定向到新用户名，被其他用户使用后不再重定向
+func TestUserService_ResolveUsername(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserBaseModel{}, &model.UserEventModel{}, &model.UserUsernameHistoryModel{})
+	model.DB = db
+
+	suffix := time.Now().UnixNano()
+	oldName := fmt.Sprintf("old_%d", suffix)
+	newName := fmt.Sprintf("new_%d", suffix)
+	u := &model.UserBaseModel{Username: oldName}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 查询还不存在的用户名，缓存空对象
+	if got, moved, err := srv.ResolveUsername(context.Background(), newName); err != nil || got.ID != 0 || moved {
+		t.Fatalf("ResolveUsername(%s) before rename = %+v, %v, %v", newName, got, moved, err)
+	}
+	if got, moved, err := srv.ResolveUsername(context.Background(), oldName); err != nil || got.ID != u.ID || moved {
+		t.Fatalf("ResolveUsername(%s) = %+v, %v, %v", oldName, got, moved, err)
+	}
+
+	if err := srv.UpdateProfile(context.Background(), u.ID, map[string]interface{}{"username": newName}); err != nil {
+		t.Fatal(err)
+	}
+	if got, moved, err := srv.ResolveUsername(context.Background(), newName); err != nil || got.ID != u.ID || moved {
+		t.Fatalf("ResolveUsername(%s) after rename = %+v, %v, %v", newName, got, moved, err)
+	}
+	// 用户缓存删除时使用的是替换 miniredis 之前的客户端，这里直接清空
+	redis.RedisClient.FlushAll()
+	got, moved, err := srv.ResolveUsername(context.Background(), oldName)
+	if err != nil || got.ID != u.ID || !moved || got.Username != newName {
+		t.Fatalf("ResolveUsername(%s) after rename = %+v, %v, %v, want moved", oldName, got, moved, err)
+	}
+
+	// 旧用户名被其他用户使用后归新用户所有
+	otherID, err := srv.userRepo.Create(db, model.UserBaseModel{Username: oldName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &model.UserBaseModel{ID: otherID}
+	if got, moved, err := srv.ResolveUsername(context.Background(), oldName); err != nil || got.ID != other.ID || moved {
+		t.Fatalf("ResolveUsername(%s) after reuse = %+v, %v, %v", oldName, got, moved, err)
+	}
+	if err := srv.UpdateProfile(context.Background(), other.ID, map[string]interface{}{"username": newName}); err != errno.ErrUsernameExist {
+		t.Fatalf("UpdateProfile() to taken username = %v, want ErrUsernameExist", err)
+	}
+}
+
+func TestUserService_checkUsername(t *testing.T) {
+	srv := NewUserService().(*userService)
+	viper.Set("account.reserved_usernames", []string{"Snake_Team"})
+	t.Cleanup(func() { viper.Set("account.reserved_usernames", nil) })
+
+	for _, name := range []string{"admin", "Settings", "snake_team"} {
+		if err := srv.checkUsername(context.Background(), 0, name); err != errno.ErrUsernameReserved {
+			t.Errorf("checkUsername(%s) = %v, want ErrUsernameReserved", name, err)
+		}
+	}
+	for _, name := range []string{"", " ", "deleted:1:alice"} {
+		if err := srv.checkUsername(context.Background(), 0, name); err != errno.ErrParam {
+			t.Errorf("checkUsername(%q) = %v, want ErrParam", name, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/internal/service/audit"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// emailVerifyExpireTime 验证邮件中链接的有效期
+	emailVerifyExpireTime = 24 * time.Hour
+	// prefixEmailVerifyKey 用户当前有效的验证 token 摘要，重新发送后之前的链接失效
+	prefixEmailVerifyKey = "snake:user:email_verify:%d"
+	// emailVerifyPath 验证邮箱页面的路径，页面从链接中读取 token 后调用 POST /v1/users/verify
+	emailVerifyPath = "/users/verify?token=%s"
+	// emailVerifyPurpose 验证邮箱 token 的用途
+	emailVerifyPurpose = "email_verify"
+)
+
+// SendVerificationEmail 重新发送验证邮件，之前发送的链接失效
+func (srv *userService) SendVerificationEmail(ctx context.Context, userID uint64) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 {
+		return errno.ErrUserNotFound
+	}
+	if u.Email == "" {
+		return errno.ErrParam
+	}
+	if !u.EmailUnverified() {
+		return errno.ErrEmailVerified
+	}
+	return srv.sendVerificationEmail(u)
+}
+
+// sendVerificationEmail 生成新的验证 token 并发送邮件
+func (srv *userService) sendVerificationEmail(u *model.UserBaseModel) error {
+	nonce, err := genEmailChangeToken()
+	if err != nil {
+		return errors.Wrap(err, "[user_service] gen email verify token err")
+	}
+	key := fmt.Sprintf(prefixEmailVerifyKey, u.ID)
+	if err := redis.RedisClient.Set(key, hashEmailChangeToken(nonce), emailVerifyExpireTime).Err(); err != nil {
+		return errors.Wrapf(err, "[user_service] save email verify token err, uid: %d", u.ID)
+	}
+
+	subject, body := email.NewActivationEmail(u.Username, linkURL(emailVerifyPath, signLinkToken(emailVerifyPurpose, u.ID, nonce)))
+	if err := email.Send(u.Email, subject, body); err != nil {
+		return errors.Wrapf(err, "[user_service] send email verify email err, uid: %d", u.ID)
+	}
+	return nil
+}
+
+// VerifyEmail 通过验证邮件中的 token 验证邮箱，token 使用后失效
+func (srv *userService) VerifyEmail(ctx context.Context, tokenStr, ip string) error {
+	userID, nonce, ok := parseLinkToken(emailVerifyPurpose, tokenStr)
+	if !ok {
+		return errno.ErrEmailVerifyInvalid
+	}
+
+	ok, err := consumeLinkToken(fmt.Sprintf(prefixEmailVerifyKey, userID), nonce)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] consume email verify token err, uid: %d", userID)
+	}
+	// 已过期、已使用或已重新发送
+	if !ok {
+		return errno.ErrEmailVerifyInvalid
+	}
+
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.ID == 0 || u.Email == "" {
+		return errno.ErrEmailVerifyInvalid
+	}
+
+	tx := model.WithContext(ctx).Begin()
+	if err := srv.userRepo.Update(tx, userID, map[string]interface{}{"email_verified": 1}); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] update email verified err, uid: %d", userID)
+	}
+	err = srv.userIdentityRepo.SaveUserIdentity(tx, userID, model.IdentityProviderEmail, u.Email, true)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "[user_service] update email identity err, uid: %d", userID)
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "[user_service] tx commit err")
+	}
+
+	srv.recordAudit(ctx, userID, audit.ActionEmailVerified, ip, map[string]interface{}{"email": u.Email})
+	return nil
+}
+
+// CheckEmailVerified 有未验证的邮箱时返回 ErrEmailNotVerified，用于限制关注等操作
+func (srv *userService) CheckEmailVerified(ctx context.Context, userID uint64) error {
+	u, err := srv.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "[user_service] get user err, uid: %d", userID)
+	}
+	if u.EmailUnverified() {
+		return errno.ErrEmailNotVerified
+	}
+	return nil
+}
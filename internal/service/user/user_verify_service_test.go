@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/email"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	srv := NewUserService().(*userService)
+	redis.InitTestRedis()
+	db, _ := openBenchDB(t)
+	_ = db.AutoMigrate(&model.UserBaseModel{}, &model.UserIdentityModel{}, &model.AuditLogModel{})
+	model.DB = db
+
+	mailer := &fakeMailer{}
+	email.Lock.Lock()
+	old := email.Client
+	email.Client = mailer
+	email.Lock.Unlock()
+	t.Cleanup(func() {
+		email.Lock.Lock()
+		email.Client = old
+		email.Lock.Unlock()
+	})
+
+	addr := fmt.Sprintf("verify_%d@example.com", time.Now().UnixNano())
+	u := &model.UserBaseModel{Username: addr, Email: addr}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CheckEmailVerified(context.Background(), u.ID); err != errno.ErrEmailNotVerified {
+		t.Fatalf("CheckEmailVerified() = %v, want ErrEmailNotVerified", err)
+	}
+
+	// 只有最后发送的链接有效
+	for i := 0; i < 2; i++ {
+		if err := srv.SendVerificationEmail(context.Background(), u.ID); err != nil {
+			t.Fatalf("SendVerificationEmail() err: %v", err)
+		}
+	}
+	first := resetTokenRe.FindStringSubmatch(mailer.body[0])[1]
+	last := resetTokenRe.FindStringSubmatch(mailer.body[1])[1]
+	if err := srv.VerifyEmail(context.Background(), first, "127.0.0.1"); err != errno.ErrEmailVerifyInvalid {
+		t.Fatalf("VerifyEmail() with replaced token = %v, want ErrEmailVerifyInvalid", err)
+	}
+	// 重置密码的 token 不能用于验证邮箱
+	userID, nonce, _ := parseLinkToken(emailVerifyPurpose, last)
+	if err := srv.VerifyEmail(context.Background(), signLinkToken(passwordResetPurpose, userID, nonce), "127.0.0.1"); err != errno.ErrEmailVerifyInvalid {
+		t.Fatalf("VerifyEmail() with password reset token = %v, want ErrEmailVerifyInvalid", err)
+	}
+	if err := srv.VerifyEmail(context.Background(), last, "127.0.0.1"); err != nil {
+		t.Fatalf("VerifyEmail() err: %v", err)
+	}
+	if err := srv.VerifyEmail(context.Background(), last, "127.0.0.1"); err != errno.ErrEmailVerifyInvalid {
+		t.Fatalf("VerifyEmail() reuse token = %v, want ErrEmailVerifyInvalid", err)
+	}
+
+	// 用户缓存删除时使用的是替换 miniredis 之前的客户端，这里直接清空
+	redis.RedisClient.FlushAll()
+	if err := srv.CheckEmailVerified(context.Background(), u.ID); err != nil {
+		t.Fatalf("CheckEmailVerified() after verify = %v, want nil", err)
+	}
+	if err := srv.SendVerificationEmail(context.Background(), u.ID); err != errno.ErrEmailVerified {
+		t.Fatalf("SendVerificationEmail() after verify = %v, want ErrEmailVerified", err)
+	}
+	identity, err := srv.userIdentityRepo.GetIdentity(db, model.IdentityProviderEmail, addr)
+	if err != nil || identity.Verified != 1 {
+		t.Fatalf("email identity = %+v, %v, want verified", identity, err)
+	}
+}
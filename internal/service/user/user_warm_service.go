@@ -0,0 +1,33 @@
+package user
+
+import (
+	"context"
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// WarmHotUserCache 预热访问最多的用户cache, 返回成功预热的数量
+// 在cache过期前主动刷新，避免热点用户cache失效时大量请求回源数据库
+func (srv *userService) WarmHotUserCache(ctx context.Context, limit int) (int, error) {
+	userIDs, err := srv.userRepo.GetHotUserIDs(limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "[user_service] get hot user ids err")
+	}
+
+	count := 0
+	for _, userID := range userIDs {
+		if err := srv.userRepo.RefreshUserCache(model.WithContext(ctx), userID); err != nil {
+			logger.Warnf("[user_service] refresh user cache err: %v, uid: %d", err, userID)
+			continue
+		}
+		count++
+	}
+
+	// 衰减访问计数，只保留一定数量的候选用户
+	if err := srv.userRepo.DecayHotUsers(limit * 2); err != nil {
+		logger.Warnf("[user_service] decay hot users err: %v", err)
+	}
+
+	return count, nil
+}
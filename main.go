@@ -9,9 +9,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -19,8 +21,21 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/1024casts/snake/handler"
+	demosvc "github.com/1024casts/snake/internal/service/demo"
+	"github.com/1024casts/snake/internal/service/kpi"
+	"github.com/1024casts/snake/internal/service/notification"
+	"github.com/1024casts/snake/internal/service/segment"
+	"github.com/1024casts/snake/internal/service/user"
 	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/counter"
+	"github.com/1024casts/snake/pkg/demo"
+	"github.com/1024casts/snake/pkg/doctor"
+	"github.com/1024casts/snake/pkg/feature"
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/slo"
 	"github.com/1024casts/snake/pkg/snake"
+	"github.com/1024casts/snake/pkg/startup"
 	v "github.com/1024casts/snake/pkg/version"
 	routers "github.com/1024casts/snake/router"
 )
@@ -28,6 +43,10 @@ import (
 var (
 	cfg     = pflag.StringP("config", "c", "", "snake config file path.")
 	version = pflag.BoolP("version", "v", false, "show version info.")
+	// 演示模式: go run . --demo，使用内嵌的 sqlite 和进程内的 redis，不依赖外部服务
+	demoMode = pflag.Bool("demo", false, "run in demo mode with embedded sqlite, in-memory redis and seeded users.")
+	// snake doctor 每项检查的超时时间
+	checkTimeout = pflag.Duration("check-timeout", 5*time.Second, "timeout of each doctor check.")
 )
 
 // @title snake docs api
@@ -54,8 +73,17 @@ func main() {
 		return
 	}
 
+	// 部署自检: snake doctor -c config.yaml
+	if args := pflag.Args(); len(args) > 0 && args[0] == "doctor" {
+		os.Exit(runDoctor())
+	}
+
 	// init config
-	if err := conf.Init(*cfg); err != nil {
+	initConf := conf.Init
+	if *demoMode {
+		initConf = conf.InitDemo
+	}
+	if err := initConf(*cfg); err != nil {
 		panic(err)
 	}
 
@@ -74,12 +102,80 @@ func main() {
 
 	// HealthCheck 健康检查路由
 	router.GET("/health", handler.HealthCheck)
+	// ReadyCheck 就绪检查，依赖未就绪时返回 503
+	router.GET("/ready", handler.ReadyCheck)
 	// metrics router 可以在 prometheus 中进行监控
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API Routes.
 	routers.Load(router)
 
+	// 功能开关支持按用户分群放量
+	feature.SegmentMatcher = segment.Svc.IsMember
+
+	// SLO 告警通知给管理员
+	if slo.Client != nil {
+		slo.Client.SetNotifier(notification.SLONotifier)
+	}
+
+	// 定时将用户计数缓冲写入数据库
+	statWorker := counter.NewWorker(viper.GetDuration("counter.flush_interval"), user.Svc.FlushUserStat)
+
+	// 定时统计业务指标，和系统指标一起在 /metrics 中暴露
+	kpiWorker := counter.NewWorker(viper.GetDuration("kpi.collect_interval"), kpi.Svc.Collect)
+
+	// 等待 mysql、redis 就绪后再启动后台任务，超过重试次数后退出，等待期间 http 服务只开放健康检查
+	// 退出时在 http 服务停止后停止后台任务，最后写入一次计数缓冲
+	lifecycle.Client.Add(lifecycle.Hook{
+		Name: "workers",
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				if err := startup.Client.Start(context.Background()); err != nil {
+					log.Fatalf("%v", err)
+				}
+				statWorker.Start()
+				kpiWorker.Start()
+			}()
+			return nil
+		},
+		OnStop: lifecycle.Func(func() {
+			statWorker.Stop()
+			kpiWorker.Stop()
+		}),
+	})
+	if slo.Client != nil {
+		lifecycle.Client.Append("slo", lifecycle.Func(slo.Client.Stop))
+	}
+
+	// 演示模式预置演示用户，也可以在配置中按环境开启 demo.enable
+	if demo.Enabled() {
+		seedDemo()
+	}
+
 	// start server
+	snake.PrintBanner(os.Stdout)
 	snake.App.Run()
 }
+
+// seedDemo 预置演示用户并输出登录方式
+func seedDemo() {
+	users, err := demosvc.Svc.Seed(context.Background())
+	if err != nil {
+		log.Fatalf("seed demo data err: %v", err)
+	}
+	fmt.Printf("%s\n", demo.Banner())
+	fmt.Printf("demo users: %s ~ %s, email: %s, password: %s\n",
+		users[0].Username, users[len(users)-1].Username, users[0].Email, demo.Password())
+}
+
+// runDoctor 检查配置和依赖，输出检查报告，有检查未通过时返回 1
+func runDoctor() int {
+	// 日志只输出到标准输出，避免按配置创建日志文件
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut}, log.InstanceZapLogger)
+
+	results := doctor.Run(context.Background(), doctor.Checks(*cfg), *checkTimeout)
+	if doctor.Report(os.Stdout, results) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+// Package agegate 年龄验证和未成年人保护
+// 按用户所在地区(司法管辖区)配置最小注册年龄、成年年龄和未成年人的受限模式，
+// 配置的路由只允许达到年龄的用户访问
+package agegate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// BirthdayLayout 生日的格式
+const BirthdayLayout = "2006-01-02"
+
+// maxAge 合法生日的最大年龄
+const maxAge = 150
+
+var (
+	// ErrInvalidBirthday 生日格式有误或不在合理范围内
+	ErrInvalidBirthday = errors.New("invalid birthday")
+	// ErrTooYoung 未达到地区的最小年龄
+	ErrTooYoung = errors.New("too young")
+)
+
+// Client 全局的年龄验证配置，未开启时为 nil
+var Client *Gate
+
+// Jurisdiction 地区的年龄规定
+type Jurisdiction struct {
+	// MinAge 最小使用年龄，小于该年龄不能设置生日
+	MinAge int `mapstructure:"min_age" json:"min_age"`
+	// AdultAge 成年年龄，小于该年龄为未成年人
+	AdultAge int `mapstructure:"adult_age" json:"adult_age"`
+	// HideFollowList 未成年人的关注、粉丝列表不对他人公开
+	HideFollowList bool `mapstructure:"hide_follow_list" json:"hide_follow_list"`
+}
+
+// Rule 路由的年龄限制
+type Rule struct {
+	// Method 为空时匹配所有方法
+	Method string `mapstructure:"method" json:"method"`
+	// Route 路由，和 gin 注册的路由一致，如 /v1/users/follow
+	Route string `mapstructure:"route" json:"route"`
+	// MinAge 需要的年龄，为0时使用地区的成年年龄
+	MinAge int `mapstructure:"min_age" json:"min_age"`
+}
+
+// match 规则是否匹配请求
+func (r *Rule) match(method, route string) bool {
+	return (r.Method == "" || r.Method == method) && r.Route == route
+}
+
+// Config 年龄验证配置
+type Config struct {
+	// Default 没有单独配置的地区使用的规定
+	Default Jurisdiction `mapstructure:"default"`
+	// Regions 按地区单独配置，key 和用户的 region 一致
+	Regions map[string]Jurisdiction `mapstructure:"regions"`
+	// Routes 需要限制年龄的路由
+	Routes []Rule `mapstructure:"routes"`
+}
+
+// Gate 年龄验证
+type Gate struct {
+	cfg Config
+}
+
+// Init 初始化全局的年龄验证
+func Init() *Gate {
+	if !viper.GetBool("agegate.enable") {
+		return nil
+	}
+
+	cfg := Config{}
+	if err := viper.UnmarshalKey("agegate", &cfg); err != nil {
+		log.Warnf("[agegate] unmarshal config err: %v", err)
+		return nil
+	}
+	Client = New(cfg)
+	return Client
+}
+
+// New 实例化年龄验证
+func New(cfg Config) *Gate {
+	return &Gate{cfg: cfg}
+}
+
+// Jurisdiction 获取地区的年龄规定
+func (g *Gate) Jurisdiction(region string) Jurisdiction {
+	if j, ok := g.cfg.Regions[region]; ok {
+		return j
+	}
+	return g.cfg.Default
+}
+
+// ParseBirthday 解析并校验生日，不能晚于今天，也不能早于 maxAge 年前
+func (g *Gate) ParseBirthday(birthday, region string, now time.Time) (time.Time, error) {
+	t, err := time.ParseInLocation(BirthdayLayout, birthday, now.Location())
+	if err != nil {
+		return time.Time{}, ErrInvalidBirthday
+	}
+	age := Age(t, now)
+	if t.After(now) || age > maxAge {
+		return time.Time{}, ErrInvalidBirthday
+	}
+	if age < g.Jurisdiction(region).MinAge {
+		return time.Time{}, ErrTooYoung
+	}
+	return t, nil
+}
+
+// IsMinor 是否是未成年人，没有填写生日时返回 false
+func (g *Gate) IsMinor(birthday *time.Time, region string, now time.Time) bool {
+	if birthday == nil {
+		return false
+	}
+	return Age(*birthday, now) < g.Jurisdiction(region).AdultAge
+}
+
+// HideFollowList 是否对他人隐藏用户的关注、粉丝列表
+func (g *Gate) HideFollowList(birthday *time.Time, region string, now time.Time) bool {
+	return g.IsMinor(birthday, region, now) && g.Jurisdiction(region).HideFollowList
+}
+
+// Match 返回请求匹配的年龄限制，按配置顺序匹配第一个命中的规则
+func (g *Gate) Match(method, route string) (Rule, bool) {
+	for _, r := range g.cfg.Routes {
+		if r.match(method, route) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Allow 用户是否可以访问受限的路由，没有填写生日时不允许
+func (g *Gate) Allow(rule Rule, birthday *time.Time, region string, now time.Time) bool {
+	if birthday == nil {
+		return false
+	}
+	minAge := rule.MinAge
+	if minAge == 0 {
+		minAge = g.Jurisdiction(region).AdultAge
+	}
+	return Age(*birthday, now) >= minAge
+}
+
+// Age 计算周岁
+func Age(birthday, now time.Time) int {
+	age := now.Year() - birthday.Year()
+	if now.Month() < birthday.Month() || (now.Month() == birthday.Month() && now.Day() < birthday.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,60 @@
+package agegate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAge(t *testing.T) {
+	asserts := assert.New(t)
+
+	now := time.Date(2020, 10, 15, 0, 0, 0, 0, time.Local)
+	asserts.Equal(18, Age(time.Date(2002, 10, 15, 0, 0, 0, 0, time.Local), now))
+	asserts.Equal(17, Age(time.Date(2002, 10, 16, 0, 0, 0, 0, time.Local), now))
+	asserts.Equal(17, Age(time.Date(2002, 11, 1, 0, 0, 0, 0, time.Local), now))
+}
+
+func TestGate(t *testing.T) {
+	asserts := assert.New(t)
+
+	g := New(Config{
+		Default: Jurisdiction{MinAge: 13, AdultAge: 18, HideFollowList: true},
+		Regions: map[string]Jurisdiction{
+			"KR": {MinAge: 14, AdultAge: 19},
+		},
+		Routes: []Rule{
+			{Method: "POST", Route: "/v1/users/follow"},
+			{Route: "/v1/uploads/presign", MinAge: 16},
+		},
+	})
+	now := time.Date(2020, 10, 15, 0, 0, 0, 0, time.Local)
+
+	_, err := g.ParseBirthday("2020-13-01", "", now)
+	asserts.Equal(ErrInvalidBirthday, err)
+	_, err = g.ParseBirthday("2021-01-01", "", now)
+	asserts.Equal(ErrInvalidBirthday, err)
+	_, err = g.ParseBirthday("2007-01-01", "", now)
+	asserts.NoError(err)
+	_, err = g.ParseBirthday("2007-01-01", "KR", now)
+	asserts.Equal(ErrTooYoung, err)
+
+	b, _ := g.ParseBirthday("2002-01-01", "", now)
+	asserts.False(g.IsMinor(&b, "", now))
+	asserts.True(g.IsMinor(&b, "KR", now))
+	asserts.False(g.HideFollowList(&b, "KR", now))
+	asserts.False(g.IsMinor(nil, "", now))
+
+	r, ok := g.Match("POST", "/v1/users/follow")
+	asserts.True(ok)
+	asserts.True(g.Allow(r, &b, "", now))
+	asserts.False(g.Allow(r, &b, "KR", now))
+	asserts.False(g.Allow(r, nil, "", now))
+
+	_, ok = g.Match("GET", "/v1/users/follow")
+	asserts.False(ok)
+	r, ok = g.Match("PUT", "/v1/uploads/presign")
+	asserts.True(ok)
+	asserts.Equal(16, r.MinAge)
+}
@@ -0,0 +1,67 @@
+// Package antivirus 文件病毒扫描，支持 ClamAV 和 HTTP 扫描服务，通过 antivirus.driver 选择，为空时不扫描
+package antivirus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DriverClamAV clamd 的 INSTREAM 命令
+	DriverClamAV = "clamav"
+	// DriverHTTP 云端或自建的 HTTP 扫描服务
+	DriverHTTP = "http"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// ErrUnknownDriver 未知的扫描驱动
+var ErrUnknownDriver = errors.New("antivirus: unknown driver")
+
+// Client 全局的扫描服务，未配置时为nil
+var Client Scanner
+
+// Result 扫描结果
+type Result struct {
+	Infected bool `json:"infected"`
+	// Signature 命中的病毒特征名
+	Signature string `json:"signature"`
+}
+
+// Scanner 病毒扫描
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// Init 按配置初始化全局的扫描服务
+func Init() Scanner {
+	s, err := New(viper.GetString("antivirus.driver"))
+	if err != nil {
+		panic(err)
+	}
+	Client = s
+	return Client
+}
+
+// New 按驱动名称实例化扫描服务，名称为空时返回nil
+func New(driver string) (Scanner, error) {
+	timeout := viper.GetDuration("antivirus.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch driver {
+	case "":
+		return nil, nil
+	case DriverClamAV:
+		return NewClamAV(viper.GetString("antivirus.clamav.addr"), timeout), nil
+	case DriverHTTP:
+		return NewHTTP(viper.GetString("antivirus.http.endpoint"), viper.GetString("antivirus.http.token"), timeout), nil
+	}
+	return nil, ErrUnknownDriver
+}
@@ -0,0 +1,79 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeClamd 读取 INSTREAM 内容，包含 EICAR 时返回感染
+func fakeClamd(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if _, err := r.ReadString(0); err != nil {
+					return
+				}
+				var data bytes.Buffer
+				size := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(r, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, r, int64(n)); err != nil {
+						return
+					}
+				}
+				if bytes.Contains(data.Bytes(), []byte("EICAR")) {
+					_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					return
+				}
+				_, _ = conn.Write([]byte("stream: OK\x00"))
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	s := NewClamAV(fakeClamd(t), time.Second)
+
+	tests := []struct {
+		data      string
+		infected  bool
+		signature string
+	}{
+		{"hello snake", false, ""},
+		{"X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*", true, "Eicar-Test-Signature"},
+	}
+	for _, tt := range tests {
+		ret, err := s.Scan(context.Background(), bytes.NewReader([]byte(tt.data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Infected != tt.infected || ret.Signature != tt.signature {
+			t.Errorf("scan %q got %+v", tt.data, ret)
+		}
+	}
+}
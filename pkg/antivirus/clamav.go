@@ -0,0 +1,94 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// see: https://linux.die.net/man/8/clamd
+
+const clamChunkSize = 64 << 10
+
+// clamAV 通过 TCP 连接 clamd 扫描
+type clamAV struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAV 实例化 ClamAV 扫描，addr 为 clamd 的 TCP 地址，如 127.0.0.1:3310
+func NewClamAV(addr string, timeout time.Duration) Scanner {
+	return &clamAV{addr: addr, timeout: timeout}
+}
+
+// Name 名称
+func (c *clamAV) Name() string {
+	return DriverClamAV
+}
+
+// Scan 使用 INSTREAM 命令分块发送文件内容，每块前是4字节大端长度，长度为0表示结束
+// 返回 "stream: OK" 表示没有病毒，"stream: <特征名> FOUND" 表示感染
+func (c *clamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	var d net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	conn, err := d.DialContext(dialCtx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, clamChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return parseClamReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+func parseClamReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("antivirus: clamd err: %s", reply)
+}
@@ -0,0 +1,59 @@
+package antivirus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// httpScanner 将文件内容 POST 到扫描服务，返回 {"infected": true, "signature": "Eicar-Test-Signature"}
+type httpScanner struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewHTTP 实例化 HTTP 扫描服务，token 不为空时通过 Authorization: Bearer 传递
+func NewHTTP(endpoint, token string, timeout time.Duration) Scanner {
+	return &httpScanner{endpoint: endpoint, token: token, client: &http.Client{Timeout: timeout}}
+}
+
+// Name 名称
+func (h *httpScanner) Name() string {
+	return DriverHTTP
+}
+
+// Scan 扫描文件
+func (h *httpScanner) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	req, err := http.NewRequest(http.MethodPost, h.endpoint, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("antivirus: http scan status: %d, body: %s", resp.StatusCode, b)
+	}
+
+	result := &Result{}
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil, fmt.Errorf("antivirus: unmarshal resp err: %v, body: %s", err, b)
+	}
+	return result, nil
+}
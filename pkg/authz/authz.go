@@ -0,0 +1,111 @@
+// Package authz 基于角色的权限控制
+// 角色和权限的对应关系在配置 authz.roles 中定义，用户拥有的角色保存在数据库中，
+// 接口通过 middleware.RequirePermission 声明需要的权限
+package authz
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// RoleAdmin 内置的超级管理员角色，拥有所有权限，配置在 admin.uids 中的用户默认拥有该角色
+const RoleAdmin = "admin"
+
+// Wildcard 匹配所有权限
+const Wildcard = "*"
+
+// Permission 权限，格式为 资源:操作，如 user:ban
+type Permission string
+
+// Resource 返回权限的资源部分
+func (p Permission) Resource() string {
+	if i := strings.IndexByte(string(p), ':'); i >= 0 {
+		return string(p)[:i]
+	}
+	return string(p)
+}
+
+// match 授予的权限是否包含需要的权限，支持 * 和 资源:*
+func match(granted, required Permission) bool {
+	if granted == Wildcard || granted == required {
+		return true
+	}
+	return strings.HasSuffix(string(granted), ":*") && granted.Resource() == required.Resource()
+}
+
+// Policy 角色及其拥有的权限
+type Policy struct {
+	roles map[string][]Permission
+}
+
+// NewPolicy 按角色名和权限列表创建策略，总是包含内置的 admin 角色
+func NewPolicy(roles map[string][]string) *Policy {
+	p := &Policy{roles: make(map[string][]Permission, len(roles)+1)}
+	for role, perms := range roles {
+		for _, perm := range perms {
+			p.roles[role] = append(p.roles[role], Permission(perm))
+		}
+	}
+	p.roles[RoleAdmin] = []Permission{Wildcard}
+	return p
+}
+
+// HasRole 角色是否存在
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+// Roles 返回所有角色名，按名称排序
+func (p *Policy) Roles() []string {
+	roles := make([]string, 0, len(p.roles))
+	for role := range p.roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Permissions 返回角色拥有的权限，角色不存在时返回空
+func (p *Policy) Permissions(role string) []Permission {
+	return p.roles[role]
+}
+
+// Allowed 拥有的角色中是否有角色包含需要的权限，不存在的角色被忽略
+func (p *Policy) Allowed(roles []string, required Permission) bool {
+	for _, role := range roles {
+		for _, granted := range p.roles[role] {
+			if match(granted, required) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	mu      sync.RWMutex
+	current = NewPolicy(nil)
+)
+
+// Init 按配置 authz.roles 加载策略
+func Init() {
+	SetPolicy(NewPolicy(viper.GetStringMapStringSlice("authz.roles")))
+}
+
+// SetPolicy 替换当前的策略
+func SetPolicy(p *Policy) {
+	mu.Lock()
+	current = p
+	mu.Unlock()
+}
+
+// Default 返回当前的策略，未初始化时只有内置的 admin 角色
+func Default() *Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
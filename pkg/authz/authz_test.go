@@ -0,0 +1,42 @@
+package authz
+
+import "testing"
+
+func TestPolicy_Allowed(t *testing.T) {
+	p := NewPolicy(map[string][]string{
+		"operator": {"user:ban", "appeal:*"},
+		"analyst":  {"segment:read"},
+	})
+
+	tests := []struct {
+		roles []string
+		perm  Permission
+		want  bool
+	}{
+		{[]string{RoleAdmin}, PermRoleWrite, true},
+		{[]string{"operator"}, PermUserBan, true},
+		{[]string{"operator"}, PermAppealReview, true},
+		{[]string{"operator"}, PermUserImpersonate, false},
+		{[]string{"analyst"}, PermSegmentRead, true},
+		{[]string{"analyst"}, PermSegmentWrite, false},
+		{[]string{"analyst", "operator"}, PermUserBan, true},
+		{[]string{"unknown"}, PermTaskRead, false},
+		{nil, PermTaskRead, false},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(tt.roles, tt.perm); got != tt.want {
+			t.Errorf("Allowed(%v, %s) = %v, want %v", tt.roles, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestNewPolicy_Admin(t *testing.T) {
+	// 配置中的 admin 不能覆盖内置的权限
+	p := NewPolicy(map[string][]string{RoleAdmin: {"task:read"}})
+	if !p.Allowed([]string{RoleAdmin}, PermUserBan) {
+		t.Error("admin should have all permissions")
+	}
+	if got := p.Roles(); len(got) != 1 || got[0] != RoleAdmin {
+		t.Errorf("Roles() = %v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package authz
+
+// 管理后台的权限，read 为查看，write 为新增、修改等操作
+const (
+	PermUserImpersonate   Permission = "user:impersonate"
+	PermUserMembership    Permission = "user:membership"
+	PermUserBan           Permission = "user:ban"
+	PermUserDeactivate    Permission = "user:deactivate"
+	PermFollowExport      Permission = "follow:export"
+	PermAppealRead        Permission = "appeal:read"
+	PermAppealReview      Permission = "appeal:review"
+	PermPolicyRead        Permission = "policy:read"
+	PermPolicyWrite       Permission = "policy:write"
+	PermTaskRead          Permission = "task:read"
+	PermAnnouncementRead  Permission = "announcement:read"
+	PermAnnouncementWrite Permission = "announcement:write"
+	PermSegmentRead       Permission = "segment:read"
+	PermSegmentWrite      Permission = "segment:write"
+	PermExperimentRead    Permission = "experiment:read"
+	PermExperimentWrite   Permission = "experiment:write"
+	PermModerationRead    Permission = "moderation:read"
+	PermModerationReview  Permission = "moderation:review"
+	PermQueueRead         Permission = "queue:read"
+	PermQueueWrite        Permission = "queue:write"
+	PermRateLimitRead     Permission = "ratelimit:read"
+	PermRateLimitWrite    Permission = "ratelimit:write"
+	PermRoleRead          Permission = "role:read"
+	PermRoleWrite         Permission = "role:write"
+	PermAuditRead         Permission = "audit:read"
+	PermLogRead           Permission = "log:read"
+	PermLogWrite          Permission = "log:write"
+	PermNotificationRead  Permission = "notification:read"
+	PermOverviewRead      Permission = "overview:read"
+)
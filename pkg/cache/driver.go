@@ -9,6 +9,8 @@ const (
 	DefaultExpireTime = 60 * time.Second
 	// PrefixCacheKey 业务cache key
 	PrefixCacheKey = "snake"
+	// NilPlaceholder 空对象占位符，用于缓存不存在的数据，防止缓存穿透
+	NilPlaceholder = "*"
 )
 
 // Client 生成一个缓存客户端，其中keyPrefix 一般为业务前缀
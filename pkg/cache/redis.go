@@ -135,7 +135,8 @@ func (c *redisCache) MultiGet(keys []string, value interface{}) error {
 	// 通过反射注入到map
 	valueMap := reflect.ValueOf(value)
 	for i, value := range values {
-		if value == nil {
+		// 跳过不存在的key和空对象
+		if value == nil || value == NilPlaceholder {
 			continue
 		}
 		object := c.newObject()
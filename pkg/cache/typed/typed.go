@@ -0,0 +1,287 @@
+// Package typed 基于泛型的redis缓存辅助函数，统一处理编解码、过期时间浮动、空对象缓存及监控
+package typed
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/1024casts/snake/pkg/cache"
+)
+
+const (
+	// DefaultNilExpireTime 空对象默认过期时间
+	DefaultNilExpireTime = 60 * time.Second
+	// DefaultJitter 过期时间默认随机浮动比例，防止缓存雪崩
+	DefaultJitter = 0.1
+)
+
+var (
+	// ErrCacheMiss 缓存中没有该key
+	ErrCacheMiss = errors.New("[cache] cache miss")
+	// ErrNotFound 数据不存在(命中了空对象或loader返回不存在)
+	ErrNotFound = errors.New("[cache] not found")
+)
+
+var (
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "snake",
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Total number of typed cache requests by result.",
+	}, []string{"name", "result"})
+
+	cacheLoads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "snake",
+		Subsystem: "cache",
+		Name:      "loads_total",
+		Help:      "Total number of typed cache loader calls by result.",
+	}, []string{"name", "result"})
+)
+
+// options 泛型缓存的可选参数
+type options struct {
+	name   string
+	prefix string
+	codec  cache.Encoding
+	jitter float64
+	nilTTL time.Duration
+}
+
+// Option 设置可选参数
+type Option func(*options)
+
+// WithName 设置名称，用于监控指标区分业务，不要使用key等高基数的值
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithPrefix 设置key前缀，默认为 cache.PrefixCacheKey
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithCodec 设置编解码方式，默认为json
+func WithCodec(codec cache.Encoding) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithJitter 设置过期时间的随机浮动比例，0 表示不浮动
+func WithJitter(jitter float64) Option {
+	return func(o *options) {
+		o.jitter = jitter
+	}
+}
+
+// WithNilTTL 设置空对象的过期时间
+func WithNilTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.nilTTL = ttl
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		name:   "default",
+		prefix: cache.PrefixCacheKey,
+		codec:  cache.JSONEncoding{},
+		jitter: DefaultJitter,
+		nilTTL: DefaultNilExpireTime,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// jitterTTL 在过期时间上增加 [0, ttl*jitter) 的随机值
+func jitterTTL(ttl time.Duration, jitter float64) time.Duration {
+	if ttl <= 0 || jitter <= 0 {
+		return ttl
+	}
+	delta := int64(float64(ttl) * jitter)
+	if delta <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(delta))
+}
+
+// Get 从redis获取数据并解码为 T
+// key 不存在时返回 ErrCacheMiss，命中空对象时返回 ErrNotFound
+func Get[T any](client *redis.Client, key string, opts ...Option) (T, error) {
+	var val T
+	o := newOptions(opts...)
+
+	cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+	if err != nil {
+		return val, err
+	}
+	data, err := client.Get(cacheKey).Bytes()
+	if err == redis.Nil {
+		cacheRequests.WithLabelValues(o.name, "miss").Inc()
+		return val, ErrCacheMiss
+	}
+	if err != nil {
+		cacheRequests.WithLabelValues(o.name, "error").Inc()
+		return val, err
+	}
+	if string(data) == cache.NilPlaceholder {
+		cacheRequests.WithLabelValues(o.name, "nil").Inc()
+		return val, ErrNotFound
+	}
+
+	err = cache.Unmarshal(o.codec, data, &val)
+	if err != nil {
+		cacheRequests.WithLabelValues(o.name, "error").Inc()
+		return val, err
+	}
+	cacheRequests.WithLabelValues(o.name, "hit").Inc()
+	return val, nil
+}
+
+// Set 编码后写入redis, ttl 为 0 时使用默认过期时间，实际过期时间会有随机浮动
+func Set[T any](client *redis.Client, key string, val T, ttl time.Duration, opts ...Option) error {
+	o := newOptions(opts...)
+
+	cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+	if err != nil {
+		return err
+	}
+	buf, err := cache.Marshal(o.codec, val)
+	if err != nil {
+		return err
+	}
+	if ttl == 0 {
+		ttl = cache.DefaultExpireTime
+	}
+	return client.Set(cacheKey, buf, jitterTTL(ttl, o.jitter)).Err()
+}
+
+// SetNil 写入空对象
+func SetNil(client *redis.Client, key string, opts ...Option) error {
+	o := newOptions(opts...)
+
+	cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+	if err != nil {
+		return err
+	}
+	return client.Set(cacheKey, cache.NilPlaceholder, jitterTTL(o.nilTTL, o.jitter)).Err()
+}
+
+// MultiGet 批量获取并解码为 T，返回命中的数据和命中空对象的 key，其余的 key 为未命中
+func MultiGet[T any](client *redis.Client, keys []string, opts ...Option) (map[string]T, map[string]bool, error) {
+	vals := make(map[string]T, len(keys))
+	notFound := make(map[string]bool)
+	if len(keys) == 0 {
+		return vals, notFound, nil
+	}
+	o := newOptions(opts...)
+
+	cacheKeys := make([]string, len(keys))
+	for i, key := range keys {
+		cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		cacheKeys[i] = cacheKey
+	}
+	data, err := client.MGet(cacheKeys...).Result()
+	if err != nil {
+		cacheRequests.WithLabelValues(o.name, "error").Add(float64(len(keys)))
+		return nil, nil, err
+	}
+
+	for i, v := range data {
+		s, ok := v.(string)
+		if !ok {
+			cacheRequests.WithLabelValues(o.name, "miss").Inc()
+			continue
+		}
+		if s == cache.NilPlaceholder {
+			cacheRequests.WithLabelValues(o.name, "nil").Inc()
+			notFound[keys[i]] = true
+			continue
+		}
+		var val T
+		// 解码失败时按未命中处理，由调用方重新加载
+		if err := cache.Unmarshal(o.codec, []byte(s), &val); err != nil {
+			cacheRequests.WithLabelValues(o.name, "error").Inc()
+			continue
+		}
+		cacheRequests.WithLabelValues(o.name, "hit").Inc()
+		vals[keys[i]] = val
+	}
+	return vals, notFound, nil
+}
+
+// MultiSet 通过 pipeline 批量写入，nilKeys 写入空对象，每个 key 的过期时间单独浮动
+func MultiSet[T any](client *redis.Client, vals map[string]T, nilKeys []string, ttl time.Duration,
+	opts ...Option) error {
+	if len(vals) == 0 && len(nilKeys) == 0 {
+		return nil
+	}
+	o := newOptions(opts...)
+	if ttl == 0 {
+		ttl = cache.DefaultExpireTime
+	}
+
+	pipe := client.Pipeline()
+	defer pipe.Close()
+	for key, val := range vals {
+		cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+		if err != nil {
+			return err
+		}
+		buf, err := cache.Marshal(o.codec, val)
+		if err != nil {
+			return err
+		}
+		pipe.Set(cacheKey, buf, jitterTTL(ttl, o.jitter))
+	}
+	for _, key := range nilKeys {
+		cacheKey, err := cache.BuildCacheKey(o.prefix, key)
+		if err != nil {
+			return err
+		}
+		pipe.Set(cacheKey, cache.NilPlaceholder, jitterTTL(o.nilTTL, o.jitter))
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// GetOrLoad 先从缓存获取，未命中时调用 loader 加载并回写缓存
+// loader 返回 ErrNotFound 时会缓存空对象，在 nilTTL 内不会再次调用 loader
+func GetOrLoad[T any](client *redis.Client, key string, ttl time.Duration, loader func() (T, error),
+	opts ...Option) (T, error) {
+	val, err := Get[T](client, key, opts...)
+	if err != ErrCacheMiss {
+		return val, err
+	}
+
+	o := newOptions(opts...)
+	val, err = loader()
+	if errors.Is(err, ErrNotFound) {
+		cacheLoads.WithLabelValues(o.name, "not_found").Inc()
+		if err := SetNil(client, key, opts...); err != nil {
+			return val, err
+		}
+		return val, ErrNotFound
+	}
+	if err != nil {
+		cacheLoads.WithLabelValues(o.name, "error").Inc()
+		return val, err
+	}
+	cacheLoads.WithLabelValues(o.name, "success").Inc()
+
+	return val, Set(client, key, val, ttl, opts...)
+}
@@ -0,0 +1,107 @@
+package typed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/cache"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+type user struct {
+	ID       uint64 `json:"id" msgpack:"id"`
+	Username string `json:"username" msgpack:"username"`
+}
+
+func TestSetGet(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	codecs := []cache.Encoding{cache.JSONEncoding{}, cache.MsgPackEncoding{}}
+	for _, codec := range codecs {
+		_, err := Get[*user](redis.RedisClient, "typed:user:1", WithCodec(codec))
+		asserts.Equal(ErrCacheMiss, err)
+
+		err = Set(redis.RedisClient, "typed:user:1", &user{ID: 1, Username: "snake"}, time.Minute, WithCodec(codec))
+		asserts.NoError(err)
+
+		u, err := Get[*user](redis.RedisClient, "typed:user:1", WithCodec(codec))
+		asserts.NoError(err)
+		asserts.Equal(&user{ID: 1, Username: "snake"}, u)
+
+		asserts.NoError(redis.RedisClient.Del(cache.PrefixCacheKey + ":typed:user:1").Err())
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	calls := 0
+	loader := func() (user, error) {
+		calls++
+		return user{ID: 2, Username: "snake"}, nil
+	}
+	for i := 0; i < 3; i++ {
+		u, err := GetOrLoad(redis.RedisClient, "typed:user:2", time.Minute, loader)
+		asserts.NoError(err)
+		asserts.Equal(uint64(2), u.ID)
+	}
+	asserts.Equal(1, calls)
+
+	// 不存在的数据缓存空对象
+	calls = 0
+	notFound := func() (user, error) {
+		calls++
+		return user{}, ErrNotFound
+	}
+	for i := 0; i < 3; i++ {
+		_, err := GetOrLoad(redis.RedisClient, "typed:user:3", time.Minute, notFound)
+		asserts.Equal(ErrNotFound, err)
+	}
+	asserts.Equal(1, calls)
+
+	// loader 出错时不写缓存
+	_, err := GetOrLoad(redis.RedisClient, "typed:user:4", time.Minute, func() (user, error) {
+		return user{}, errors.New("db error")
+	})
+	asserts.Error(err)
+	_, err = Get[user](redis.RedisClient, "typed:user:4")
+	asserts.Equal(ErrCacheMiss, err)
+}
+
+func TestMultiGetSet(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	vals := map[string]*user{
+		"typed:user:5": {ID: 5, Username: "snake"},
+		"typed:user:6": {ID: 6, Username: "eagle"},
+	}
+	err := MultiSet(redis.RedisClient, vals, []string{"typed:user:7"}, time.Minute)
+	asserts.NoError(err)
+
+	got, notFound, err := MultiGet[*user](redis.RedisClient, []string{"typed:user:5", "typed:user:6", "typed:user:7", "typed:user:8"})
+	asserts.NoError(err)
+	asserts.Equal(vals, got)
+	asserts.Equal(map[string]bool{"typed:user:7": true}, notFound)
+
+	ttl := redis.RedisClient.TTL(cache.PrefixCacheKey + ":typed:user:5").Val()
+	asserts.True(ttl >= time.Minute && ttl < time.Minute+6*time.Second, "ttl: %v", ttl)
+}
+
+func TestJitterTTL(t *testing.T) {
+	ttl := time.Hour
+	for i := 0; i < 100; i++ {
+		got := jitterTTL(ttl, 0.1)
+		if got < ttl || got >= ttl+6*time.Minute {
+			t.Fatalf("jitterTTL() = %v, out of range", got)
+		}
+	}
+	if got := jitterTTL(ttl, 0); got != ttl {
+		t.Errorf("jitterTTL() = %v, want %v", got, ttl)
+	}
+}
@@ -1,13 +1,30 @@
+// Package captcha 图形验证码，验证码的生成、有效期和校验次数限制使用 pkg/otp
+// 调用方负责把验证码渲染为图片，校验时带上 New 返回的 id
 package captcha
 
-//import "github.com/gin-gonic/gin"
-//
-//// see: https://github.com/wangsongyan/wblog/blob/master/controllers/captcha.go
-//func CaptchaGet() {
-//	session := sessions.Default(context)
-//	captchaId := captcha.captcha.NewLen(4)
-//	session.Delete(SESSION_CAPTCHA)
-//	session.Set(SESSION_CAPTCHA, captchaId)SESSION_CAPTCHA, captchaId)
-//	session.Save()
-//	captcha.captcha.WriteImage(context.Writer, captchaId,  captchaId, 100, 40)
-//}
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/1024casts/snake/pkg/otp"
+)
+
+// New 生成验证码，返回验证码id和验证码
+func New(ctx context.Context) (id string, code string, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(b)
+	code, err = otp.Default().Generate(ctx, otp.PurposeCaptcha, id)
+	if err != nil {
+		return "", "", err
+	}
+	return id, code, nil
+}
+
+// Verify 校验验证码，通过后作废，错误时返回 otp.ErrInvalid，错误次数过多时返回 otp.ErrTooManyAttempts
+func Verify(ctx context.Context, id, code string) error {
+	return otp.Default().Verify(ctx, otp.PurposeCaptcha, id, code)
+}
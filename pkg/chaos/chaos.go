@@ -0,0 +1,125 @@
+// Package chaos 故障注入，用于在测试环境验证客户端的超时、重试等容错逻辑
+// 按路由配置故障类型和比例，只能在非 release 模式下开启
+package chaos
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 故障类型
+const (
+	// FaultLatency 增加延迟后继续处理请求
+	FaultLatency = "latency"
+	// FaultError 直接返回错误
+	FaultError = "error"
+	// FaultDrop 直接断开连接，不返回任何内容
+	FaultDrop = "drop"
+)
+
+// Client 全局的故障注入配置，未开启时为 nil
+var Client *Injector
+
+// Rule 故障规则
+type Rule struct {
+	// Method 为空时匹配所有方法
+	Method string `mapstructure:"method" json:"method"`
+	// Route 路由，和 gin 注册的路由一致，如 /v1/users/:id，为空时匹配所有路由
+	Route string `mapstructure:"route" json:"route"`
+	// Fault 故障类型 latency、error、drop
+	Fault string `mapstructure:"fault" json:"fault"`
+	// Percentage 注入故障的请求比例，0-100
+	Percentage float64 `mapstructure:"percentage" json:"percentage"`
+	// Latency 注入的延迟，fault 为 latency 时有效
+	Latency time.Duration `mapstructure:"latency" json:"latency"`
+	// Status 返回的 http 状态码，fault 为 error 时有效，默认 500
+	Status int `mapstructure:"status" json:"status"`
+}
+
+// match 规则是否匹配请求
+func (r *Rule) match(method, route string) bool {
+	return (r.Method == "" || r.Method == method) && (r.Route == "" || r.Route == route)
+}
+
+// Injector 按规则决定请求是否注入故障
+type Injector struct {
+	rules []Rule
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Init 初始化全局的故障注入，只在 debug、test 模式下生效
+// run_mode 为空或其他值时按 release 模式启动，即使开启也不生效
+func Init() *Injector {
+	if !viper.GetBool("chaos.enable") {
+		return nil
+	}
+	if mode := viper.GetString("app.run_mode"); mode != "debug" && mode != "test" {
+		log.Warnf("[chaos] fault injection is only enabled in debug or test mode, run mode: %q", mode)
+		return nil
+	}
+
+	rules := make([]Rule, 0)
+	if err := viper.UnmarshalKey("chaos.rules", &rules); err != nil {
+		log.Warnf("[chaos] unmarshal rules err: %v", err)
+		return nil
+	}
+	Client = New(rules)
+	log.Warnf("[chaos] fault injection is enabled, rules: %d", len(rules))
+	return Client
+}
+
+// New 实例化故障注入，忽略不合法的规则
+func New(rules []Rule) *Injector {
+	valid := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		switch r.Fault {
+		case FaultLatency:
+			if r.Latency <= 0 {
+				continue
+			}
+		case FaultError:
+			if r.Status == 0 {
+				r.Status = 500
+			}
+		case FaultDrop:
+		default:
+			continue
+		}
+		if r.Percentage <= 0 {
+			continue
+		}
+		valid = append(valid, r)
+	}
+	return &Injector{
+		rules: valid,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Pick 返回请求要注入的故障，按配置顺序匹配第一个命中的规则，不注入时返回 nil
+func (i *Injector) Pick(method, route string) *Rule {
+	for idx := range i.rules {
+		r := &i.rules[idx]
+		if !r.match(method, route) {
+			continue
+		}
+		if i.float64()*100 < r.Percentage {
+			return r
+		}
+	}
+	return nil
+}
+
+// float64 rand.Rand 不是并发安全的
+func (i *Injector) float64() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64()
+}
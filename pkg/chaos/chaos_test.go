@@ -0,0 +1,53 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestInjector_Pick(t *testing.T) {
+	asserts := assert.New(t)
+
+	i := New([]Rule{
+		{Route: "/v1/users/:id", Fault: FaultLatency, Percentage: 100},
+		{Method: "POST", Route: "/v1/users/follow", Fault: FaultError, Percentage: 100},
+		{Route: "/v1/users/:id", Fault: FaultDrop, Percentage: 100, Latency: time.Second},
+		{Fault: "unknown", Percentage: 100},
+		{Route: "/v1/vcode", Fault: FaultDrop, Percentage: 0},
+	})
+
+	// 没有延迟的 latency 规则被忽略
+	r := i.Pick("GET", "/v1/users/:id")
+	asserts.NotNil(r)
+	asserts.Equal(FaultDrop, r.Fault)
+
+	r = i.Pick("POST", "/v1/users/follow")
+	asserts.NotNil(r)
+	asserts.Equal(FaultError, r.Fault)
+	asserts.Equal(500, r.Status)
+
+	asserts.Nil(i.Pick("GET", "/v1/users/follow"))
+	asserts.Nil(i.Pick("GET", "/v1/vcode"))
+}
+
+func TestInit_RunMode(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	asserts := assert.New(t)
+	viper.Set("chaos.enable", true)
+	t.Cleanup(func() {
+		viper.Set("chaos.enable", false)
+		viper.Set("app.run_mode", "")
+		Client = nil
+	})
+
+	// 只有明确配置为 debug、test 时生效，未配置或其他值都按 release 处理
+	for mode, enabled := range map[string]bool{"debug": true, "test": true, "release": false, "": false, "prod": false} {
+		viper.Set("app.run_mode", mode)
+		asserts.Equal(enabled, Init() != nil, "run mode: %q", mode)
+	}
+}
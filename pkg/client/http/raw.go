@@ -38,6 +38,10 @@ func (r *rawClient) Get(url string, params map[string]string, duration time.Dura
 		log.Warnf("read body: %s by ioutil, err: %s", b, err)
 		return target, err
 	}
+	if err := checkStatus(resp.StatusCode, b); err != nil {
+		log.Warnf("request url: %s, err: %s", url, err)
+		return target, err
+	}
 
 	if err := json.Unmarshal(b, &target); err != nil {
 		log.Warnf("can't unmarshal to target err: %s, body: %s", err, b)
@@ -64,6 +68,10 @@ func (r *rawClient) Post(url string, data []byte, duration time.Duration) ([]byt
 		log.Warnf("read body: %s by ioutil, err: %s", b, err)
 		return target, err
 	}
+	if err := checkStatus(resp.StatusCode, b); err != nil {
+		log.Warnf("request url: %s, err: %s", url, err)
+		return target, err
+	}
 
 	log.Infof("resp: %+v", string(b))
 	if err := json.Unmarshal(b, &target); err != nil {
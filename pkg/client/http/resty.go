@@ -39,6 +39,10 @@ func (r *restyClient) Get(url string, params map[string]string, duration time.Du
 		log.Warnf("get url: %s err: %s", url, err)
 		return nil, err
 	}
+	if err := checkStatus(resp.StatusCode(), resp.Body()); err != nil {
+		log.Warnf("get url: %s err: %s", url, err)
+		return nil, err
+	}
 
 	return resp.Body(), nil
 }
@@ -62,6 +66,10 @@ func (r *restyClient) Post(url string, data []byte, duration time.Duration) ([]b
 		log.Warnf("post url: %s err: %s", url, err)
 		return nil, err
 	}
+	if err := checkStatus(resp.StatusCode(), resp.Body()); err != nil {
+		log.Warnf("post url: %s err: %s", url, err)
+		return nil, err
+	}
 
 	return resp.Body(), nil
 }
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// StatusError 服务端返回了错误的状态码
+type StatusError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d, body: %s", e.Status, e.Body)
+}
+
+// checkStatus 状态码有误时返回按状态码分类的错误
+func checkStatus(status int, body []byte) error {
+	if status < http.StatusBadRequest {
+		return nil
+	}
+	return errno.WithKind(errno.KindOfStatus(status), &StatusError{Status: status, Body: body})
+}
+
+// RetryPolicy 重试策略，按错误分类决定是否重试
+// Unavailable 的错误总是重试，Internal 的错误只重试幂等的 Get，其他分类不重试
+type RetryPolicy struct {
+	// MaxAttempts 最多请求的次数，包括第一次
+	MaxAttempts int
+	// Backoff 第 n 次重试前等待 n*Backoff
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy 默认的重试策略
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 100 * time.Millisecond}
+
+// retryClient 按重试策略包装的 client
+type retryClient struct {
+	client Client
+	policy RetryPolicy
+}
+
+// NewRetryClient 按重试策略包装 client
+func NewRetryClient(client Client, policy RetryPolicy) Client {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	return &retryClient{client: client, policy: policy}
+}
+
+// Get 请求失败且错误可以重试时按策略重试
+func (r *retryClient) Get(url string, params map[string]string, duration time.Duration) ([]byte, error) {
+	return r.do(url, true, func() ([]byte, error) {
+		return r.client.Get(url, params, duration)
+	})
+}
+
+// Post 只在依赖不可用时重试，避免内部错误时重复提交
+func (r *retryClient) Post(url string, data []byte, duration time.Duration) ([]byte, error) {
+	return r.do(url, false, func() ([]byte, error) {
+		return r.client.Post(url, data, duration)
+	})
+}
+
+func (r *retryClient) do(url string, idempotent bool, fn func() ([]byte, error)) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	for attempt := 1; ; attempt++ {
+		b, err = fn()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.shouldRetry(err, idempotent) {
+			return b, err
+		}
+		log.Warnf("[http] retry url: %s, attempt: %d, kind: %s, err: %v", url, attempt, errno.KindOf(err), err)
+		time.Sleep(time.Duration(attempt) * r.policy.Backoff)
+	}
+}
+
+func (r *retryClient) shouldRetry(err error, idempotent bool) bool {
+	switch errno.KindOf(err) {
+	case errno.KindUnavailable:
+		return true
+	case errno.KindInternal:
+		return idempotent
+	}
+	return false
+}
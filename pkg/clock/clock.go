@@ -0,0 +1,53 @@
+// Package clock 时钟接口，业务代码通过注入的 Clock 获取当前时间，测试时替换为 Fake 控制时间
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟
+type Clock interface {
+	Now() time.Time
+}
+
+// Real 系统时钟
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fake 测试用的时钟，时间只在调用 Set、Add 时变化
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake 实例化一个从 now 开始的时钟
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now 当前时间
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set 设置当前时间
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Add 时间前进 d，用于模拟过期等场景
+func (f *Fake) Add(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
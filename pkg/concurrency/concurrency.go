@@ -0,0 +1,85 @@
+// Package concurrency 按名称限制同时处理的请求数，用于导出、搜索等耗时的接口
+// 和 ratelimit 不同，ratelimit 限制的是单位时间内的请求数，这里限制的是同一时刻正在处理的请求数，
+// 慢请求堆积时直接拒绝，避免占满数据库连接和内存
+
+package concurrency
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	inFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "snake_concurrency_in_flight",
+		Help: "Number of in-flight requests held by the concurrency limiter.",
+	}, []string{"name"})
+	rejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_concurrency_rejected_total",
+		Help: "Total number of requests rejected by the concurrency limiter.",
+	}, []string{"name"})
+)
+
+// Default 全局的并发限制
+var Default = New()
+
+// Limiter 按名称管理信号量，名称由调用方保证取值有限，如路由规则
+type Limiter struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// New 实例化
+func New() *Limiter {
+	return &Limiter{sems: make(map[string]chan struct{})}
+}
+
+// Acquire 获取一个名额，已满时不等待，返回 false
+// 获取成功后必须调用 release 归还，max 修改后使用新的信号量，已获取的名额归还到原来的信号量，max 不大于0时总是拒绝
+func (l *Limiter) Acquire(name string, max int) (release func(), ok bool) {
+	if max <= 0 {
+		rejected.WithLabelValues(name).Inc()
+		return nil, false
+	}
+	sem := l.sem(name, max)
+	select {
+	case sem <- struct{}{}:
+	default:
+		rejected.WithLabelValues(name).Inc()
+		return nil, false
+	}
+
+	inFlight.WithLabelValues(name).Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-sem
+			inFlight.WithLabelValues(name).Dec()
+		})
+	}, true
+}
+
+// InFlight 正在处理的请求数
+func (l *Limiter) InFlight(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.sems[name])
+}
+
+func (l *Limiter) sem(name string, max int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[name]
+	if !ok || cap(sem) != max {
+		sem = make(chan struct{}, max)
+		l.sems[name] = sem
+	}
+	return sem
+}
+
+// Acquire 在全局的并发限制中获取一个名额
+func Acquire(name string, max int) (release func(), ok bool) {
+	return Default.Acquire(name, max)
+}
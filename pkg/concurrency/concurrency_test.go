@@ -0,0 +1,62 @@
+package concurrency
+
+import "testing"
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := New()
+	r1, ok := l.Acquire("export", 2)
+	if !ok {
+		t.Fatal("Acquire() = false, want true")
+	}
+	r2, ok := l.Acquire("export", 2)
+	if !ok {
+		t.Fatal("Acquire() = false, want true")
+	}
+	if _, ok := l.Acquire("export", 2); ok {
+		t.Fatal("Acquire() = true when saturated, want false")
+	}
+	// 不同名称互不影响
+	if r, ok := l.Acquire("search", 1); !ok {
+		t.Fatal("Acquire() other name = false, want true")
+	} else {
+		r()
+	}
+
+	r1()
+	// 重复归还不会多释放名额
+	r1()
+	if got := l.InFlight("export"); got != 1 {
+		t.Fatalf("InFlight() = %d, want 1", got)
+	}
+	r3, ok := l.Acquire("export", 2)
+	if !ok {
+		t.Fatal("Acquire() after release = false, want true")
+	}
+	if _, ok := l.Acquire("export", 2); ok {
+		t.Fatal("Acquire() = true when saturated, want false")
+	}
+	r2()
+	r3()
+
+	if _, ok := l.Acquire("disabled", 0); ok {
+		t.Fatal("Acquire() with max 0 = true, want false")
+	}
+}
+
+// 修改 max 后使用新的信号量，原来的名额归还到原来的信号量
+func TestLimiter_Resize(t *testing.T) {
+	l := New()
+	old, _ := l.Acquire("export", 1)
+	if _, ok := l.Acquire("export", 1); ok {
+		t.Fatal("Acquire() = true when saturated, want false")
+	}
+	r, ok := l.Acquire("export", 3)
+	if !ok {
+		t.Fatal("Acquire() after resize = false, want true")
+	}
+	old()
+	r()
+	if got := l.InFlight("export"); got != 0 {
+		t.Fatalf("InFlight() = %d, want 0", got)
+	}
+}
@@ -1,18 +1,36 @@
 package conf
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 
+	"github.com/1024casts/snake/pkg/demo"
 	"github.com/1024casts/snake/pkg/log"
 )
 
+const (
+	// envPrefix 环境变量的前缀，如 SNAKE_MYSQL_ADDR 覆盖 mysql.addr
+	envPrefix = "snake"
+)
+
 var (
+	// Conf 启动时加载的配置，热加载时不会修改，可以并发读取
+	// 需要热加载的模块通过 OnChange 注册回调，在回调中从 viper 读取最新的配置
 	Conf *Config
+
+	// envKeyReplacer 配置项转为环境变量名时替换的字符
+	envKeyReplacer = strings.NewReplacer(".", "_")
+
+	// changeHooks 配置文件变化后执行的回调
+	changeHooks []func()
 )
 
 // Init init config
@@ -32,14 +50,16 @@ func initConfig(confPath string) error {
 		viper.AddConfigPath("conf") // 如果没有指定配置文件，则解析默认的配置文件
 		viper.SetConfigName("config.local")
 	}
-	viper.SetConfigType("yaml") // 设置配置文件格式为YAML
-	viper.AutomaticEnv()        // 读取匹配的环境变量
-	viper.SetEnvPrefix("snake") // 读取环境变量的前缀为 snake
-	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvKeyReplacer(replacer)
+	viper.SetConfigType("yaml")   // 设置配置文件格式为YAML
+	viper.AutomaticEnv()          // 读取匹配的环境变量
+	viper.SetEnvPrefix(envPrefix) // 读取环境变量的前缀为 snake
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	if err := viper.ReadInConfig(); err != nil { // viper解析配置文件
 		return errors.WithStack(err)
 	}
+	if err := mergeEnv(); err != nil {
+		return err
+	}
 
 	// parse to config struct
 	err := viper.Unmarshal(&Conf)
@@ -52,23 +72,205 @@ func initConfig(confPath string) error {
 	return nil
 }
 
+// InitDemo 演示模式，指定了配置文件或者存在默认的配置文件时按环境的配置启动，否则使用内置的演示配置
+// 无论配置中是否开启，都以演示模式运行
+func InitDemo(confPath string) error {
+	if confPath != "" {
+		if err := initConfig(confPath); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat("conf/config.local.yaml"); err == nil {
+		if err := initConfig(""); err != nil {
+			return err
+		}
+	} else {
+		viper.SetConfigType("yaml")
+		viper.AutomaticEnv()
+		viper.SetEnvPrefix(envPrefix)
+		viper.SetEnvKeyReplacer(envKeyReplacer)
+		if err := viper.ReadConfig(bytes.NewReader(demo.Config)); err != nil {
+			return errors.WithStack(err)
+		}
+		file := viper.New()
+		file.SetConfigType("yaml")
+		if err := file.ReadConfig(bytes.NewReader(demo.Config)); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := mergeEnvFrom(file); err != nil {
+			return err
+		}
+	}
+
+	viper.Set("demo.enable", true)
+	return viper.Unmarshal(&Conf)
+}
+
+// mergeEnv 把 SNAKE_ 开头的环境变量合并到配置中，用于容器部署时覆盖配置文件
+// AutomaticEnv 只对 viper.Get 单个配置项生效，UnmarshalKey 读取整个配置段时不会使用环境变量，
+// 合并后两种方式读到的值一致。只能覆盖配置文件中已有的配置项，按配置文件中的类型转换，
+// 列表用逗号分隔，对象列表不支持覆盖
+func mergeEnv() error {
+	// 单独读取配置文件，viper.Get 返回的已经是环境变量的值，无法判断原来的类型
+	file := viper.New()
+	file.SetConfigFile(viper.ConfigFileUsed())
+	file.SetConfigType("yaml")
+	if err := file.ReadInConfig(); err != nil {
+		return errors.WithStack(err)
+	}
+	return mergeEnvFrom(file)
+}
+
+// mergeEnvFrom 按 file 中的配置项和类型合并环境变量
+func mergeEnvFrom(file *viper.Viper) error {
+	overrides := make(map[string]interface{})
+	for _, key := range file.AllKeys() {
+		name := strings.ToUpper(envPrefix + "_" + envKeyReplacer.Replace(key))
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		v, err := castEnv(file.Get(key), val)
+		if err != nil {
+			return fmt.Errorf("env %s: %v", name, err)
+		}
+
+		m := overrides
+		parts := strings.Split(key, ".")
+		for _, p := range parts[:len(parts)-1] {
+			sub, ok := m[p].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				m[p] = sub
+			}
+			m = sub
+		}
+		m[parts[len(parts)-1]] = v
+	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	return viper.MergeConfigMap(overrides)
+}
+
+// castEnv 把环境变量的值转换为配置文件中的类型，类型不一致时 viper 不会合并
+func castEnv(origin interface{}, val string) (interface{}, error) {
+	switch o := origin.(type) {
+	case bool:
+		return cast.ToBoolE(val)
+	case int:
+		return cast.ToIntE(val)
+	case float64:
+		return cast.ToFloat64E(val)
+	case []interface{}:
+		for _, item := range o {
+			switch item.(type) {
+			case map[string]interface{}, map[interface{}]interface{}:
+				return nil, errors.New("list of objects can not be overridden")
+			}
+		}
+		list := make([]interface{}, 0)
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	default:
+		return val, nil
+	}
+}
+
 // 监控配置文件变化并热加载程序
+// 重新读取配置文件后合并环境变量，更新日志级别，再执行各模块注册的回调
 func watchConfig() {
 	viper.WatchConfig()
 	viper.OnConfigChange(func(e fsnotify.Event) {
 		log.Infof("Config file changed: %s", e.Name)
+		if err := reload(); err != nil {
+			log.Warnf("[conf] reload config err: %v", err)
+			return
+		}
+		for _, hook := range changeHooks {
+			hook()
+		}
 	})
 }
 
+// reload 配置文件变化后重新合并环境变量，更新日志级别
+// Conf 不会更新，其他配置由 OnChange 注册的回调从 viper 读取后生效
+func reload() error {
+	if err := mergeEnv(); err != nil {
+		return err
+	}
+
+	if err := log.SetLevel(viper.GetString("log.logger_level")); err != nil {
+		log.Warnf("[conf] invalid log.logger_level, keep %s: %v", log.GetLevel(), err)
+	}
+	if err := log.SetModuleLevels(viper.GetStringMapString("log.module_levels")); err != nil {
+		log.Warnf("[conf] invalid log.module_levels, keep %v: %v", log.GetModuleLevels(), err)
+	}
+	log.Infof("[conf] config reloaded, log level: %s", log.GetLevel())
+	return nil
+}
+
+// OnChange 注册配置文件变化后的回调，需要热加载的模块在初始化时注册
+// viper 只保留最后一个 OnConfigChange 回调，所以统一在这里分发
+func OnChange(hook func()) {
+	changeHooks = append(changeHooks, hook)
+}
+
 // Config global config
 // include common and biz config
 type Config struct {
 	// common
-	App   AppConfig
-	Log   LogConfig
-	MySQL MySQLConfig
-	Redis RedisConfig
-	Cache CacheConfig
+	App          AppConfig
+	Log          LogConfig
+	MySQL        MySQLConfig
+	Redis        RedisConfig
+	Residency    ResidencyConfig
+	Startup      StartupConfig
+	Timeout      TimeoutConfig
+	Consistency  ConsistencyConfig
+	Cache        CacheConfig
+	Nonce        NonceConfig
+	Counter      CounterConfig
+	KPI          KPIConfig
+	Job          JobConfig
+	Quota        QuotaConfig
+	APIKey       APIKeyConfig
+	WS           WSConfig
+	RateLimit    RateLimitConfig
+	Middleware   MiddlewareConfig
+	Metrics      MetricsConfig
+	SLO          SLOConfig
+	Trace        TraceConfig
+	Chaos        ChaosConfig
+	Admin        AdminConfig
+	Authz        AuthzConfig
+	GRPC         GRPCConfig
+	Policy       PolicyConfig
+	AgeGate      AgeGateConfig
+	Feature      FeatureConfig
+	Notification NotificationConfig
+	Sensitive    SensitiveConfig
+	ImageAudit   ImageAuditConfig
+	Storage      StorageConfig
+	Queue        QueueConfig
+	Task         TaskConfig
+	Batch        BatchConfig
+	Record       RecordConfig
+	Shadow       map[string]ShadowConfig
+	Upload       UploadConfig
+	Image        ImageConfig
+	Antivirus    AntivirusConfig
+	Search       SearchConfig
+	Campaign     CampaignConfig
+	Account      AccountConfig
+	I18n         I18nConfig
+	SMS          SMSConfig
+	OTP          OTPConfig
+	OAuth        OAuthConfig
+	Demo         DemoConfig
 }
 
 // AppConfig
@@ -78,6 +280,12 @@ type AppConfig struct {
 	Addr      string
 	Url       string
 	JwtSecret string
+	// ShutdownTimeout 退出时等待处理中的请求完成的总时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// ShutdownDelay 退出时就绪检查返回 503 后，停止接收请求前的等待时间
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+	// StartTimeout 启动时每个模块的启动回调的超时时间
+	StartTimeout time.Duration `mapstructure:"start_timeout"`
 }
 
 // LogConfig
@@ -92,6 +300,10 @@ type LogConfig struct {
 	LogRotateDate    int
 	LogRotateSize    int
 	LogBackupCount   int
+	// ModuleLevels 按模块单独设置的日志级别，eg: access: warn
+	ModuleLevels map[string]string `mapstructure:"module_levels"`
+	// Sinks 输出配置，配置后替代 writers，可同时输出到 console、file、syslog
+	Sinks []log.SinkConfig `mapstructure:"sinks"`
 }
 
 // MySQLConfig
@@ -104,6 +316,18 @@ type MySQLConfig struct {
 	MaxIdleConn     int
 	MaxOpenConn     int
 	ConnMaxLifeTime int
+	Replicas        []MySQLReplicaConfig
+	ReplicaPolicy   string `mapstructure:"replica_policy"`
+	// RequestQueryThreshold 单个请求执行的语句条数超过时输出警告，为0时不检查
+	RequestQueryThreshold int `mapstructure:"request_query_threshold"`
+}
+
+// MySQLReplicaConfig 从库配置，用户名、密码和库名为空时和主库相同
+type MySQLReplicaConfig struct {
+	Addr     string
+	Username string
+	Password string
+	Name     string
 }
 
 // RedisConfig
@@ -117,12 +341,512 @@ type RedisConfig struct {
 	PoolSize     int
 }
 
+// ResidencyConfig 数据驻留配置，按地区使用单独的数据库和 redis
+type ResidencyConfig struct {
+	Enable   bool
+	Fallback bool
+	Regions  map[string]RegionStoreConfig
+}
+
+// RegionStoreConfig 地区的数据库和 redis
+type RegionStoreConfig struct {
+	MySQL MySQLConfig
+	Redis RedisConfig
+}
+
+// StartupConfig 启动时依赖探测的重试配置
+type StartupConfig struct {
+	StartupRetryConfig `mapstructure:",squash"`
+	Dependencies       map[string]StartupRetryConfig
+}
+
+// StartupRetryConfig 依赖探测的重试配置
+type StartupRetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// TimeoutConfig 请求超时预算配置
+type TimeoutConfig struct {
+	Request time.Duration
+	Margin  time.Duration
+}
+
+// ConsistencyConfig 读己之写一致性配置
+type ConsistencyConfig struct {
+	Enable bool
+	PinTTL time.Duration `mapstructure:"pin_ttl"`
+}
+
 // CacheConfig
 type CacheConfig struct {
 	Driver string
 	Prefix string
 }
 
+// NonceConfig 防重放、幂等存储配置
+type NonceConfig struct {
+	Driver string
+}
+
+// CounterConfig 计数缓冲配置
+type CounterConfig struct {
+	FlushInterval time.Duration
+}
+
+// KPIConfig 业务指标配置
+type KPIConfig struct {
+	CollectInterval time.Duration `mapstructure:"collect_interval"`
+}
+
+// MiddlewareConfig 按路由声明的中间件配置
+type MiddlewareConfig struct {
+	Routes []RouteMiddlewareConfig
+}
+
+// RouteMiddlewareConfig 路由中间件规则
+type RouteMiddlewareConfig struct {
+	Method         string
+	Route          string
+	Use            []string
+	Limit          int64
+	Window         time.Duration
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	MaxConcurrency int           `mapstructure:"max_concurrency"`
+	RetryAfter     time.Duration `mapstructure:"retry_after"`
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Enable   bool
+	Limit    int64
+	Window   time.Duration
+	Soft     bool
+	GraceTTL time.Duration
+}
+
+// SLOConfig SLO 告警配置
+type SLOConfig struct {
+	Enable        bool
+	ShortWindow   time.Duration `mapstructure:"short_window"`
+	LongWindow    time.Duration `mapstructure:"long_window"`
+	BurnRate      float64       `mapstructure:"burn_rate"`
+	MinRequests   int64         `mapstructure:"min_requests"`
+	Cooldown      time.Duration
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	Objectives    []SLOObjectiveConfig
+}
+
+// MetricsConfig http 请求指标配置
+type MetricsConfig struct {
+	TenantHeader string `mapstructure:"tenant_header"`
+	Tenants      []string
+	MaxTenants   int `mapstructure:"max_tenants"`
+	MaxRoutes    int `mapstructure:"max_routes"`
+}
+
+// TraceConfig 链路追踪配置
+type TraceConfig struct {
+	Enable      bool
+	ServiceName string `mapstructure:"service_name"`
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// SLOObjectiveConfig 路由的 SLO 目标
+type SLOObjectiveConfig struct {
+	Method        string
+	Route         string
+	Availability  float64
+	Latency       time.Duration
+	LatencyTarget float64 `mapstructure:"latency_target"`
+}
+
+// ChaosConfig 故障注入配置
+type ChaosConfig struct {
+	Enable bool
+	Rules  []ChaosRuleConfig
+}
+
+// ChaosRuleConfig 故障规则
+type ChaosRuleConfig struct {
+	Method     string
+	Route      string
+	Fault      string
+	Percentage float64
+	Latency    time.Duration
+	Status     int
+}
+
+// AdminConfig 管理员配置
+type AdminConfig struct {
+	UIDs             []uint64
+	ImpersonateTTL   time.Duration
+	OverviewCacheTTL time.Duration `mapstructure:"overview_cache_ttl"`
+}
+
+// GRPCConfig gRPC 服务配置
+type GRPCConfig struct {
+	Addr            string
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// Callers 调用方名称到 token 的映射
+	Callers map[string]string
+}
+
+// AuthzConfig 权限配置
+type AuthzConfig struct {
+	// Roles 角色及其拥有的权限
+	Roles map[string][]string
+}
+
+// PolicyConfig 协议配置
+type PolicyConfig struct {
+	// Required 需要用户同意当前版本的协议类型
+	Required []string
+}
+
+// AgeGateConfig 年龄验证配置
+type AgeGateConfig struct {
+	Enable  bool
+	Default AgeJurisdictionConfig
+	Regions map[string]AgeJurisdictionConfig
+	Routes  []AgeRouteConfig
+}
+
+// AgeJurisdictionConfig 地区的年龄规定
+type AgeJurisdictionConfig struct {
+	MinAge         int  `mapstructure:"min_age"`
+	AdultAge       int  `mapstructure:"adult_age"`
+	HideFollowList bool `mapstructure:"hide_follow_list"`
+}
+
+// AgeRouteConfig 路由的年龄限制
+type AgeRouteConfig struct {
+	Method string
+	Route  string
+	MinAge int `mapstructure:"min_age"`
+}
+
+// FeatureConfig 功能开关配置
+type FeatureConfig struct {
+	Flags map[string]FeatureFlagConfig
+}
+
+// FeatureFlagConfig 功能开关，白名单用户和放量比例
+type FeatureFlagConfig struct {
+	Enable     bool
+	AllowUIDs  []uint64
+	Segments   []string
+	Percentage int
+}
+
+// NotificationConfig 通知配置
+type NotificationConfig struct {
+	Digest bool
+	// PresenceRouting 按在线状态路由，在线的用户通过 websocket 送达后不再发送推送和邮件
+	PresenceRouting bool `mapstructure:"presence_routing"`
+	// OfflineEmailDigest 离线用户的邮件合并到摘要中发送
+	OfflineEmailDigest bool `mapstructure:"offline_email_digest"`
+}
+
+// SensitiveConfig 敏感词配置
+type SensitiveConfig struct {
+	Words []string
+}
+
+// ImageAuditConfig 图片审核配置
+type ImageAuditConfig struct {
+	Provider string
+	Timeout  time.Duration
+	Aliyun   struct {
+		AccessKeyID     string `mapstructure:"access_key_id"`
+		AccessKeySecret string `mapstructure:"access_key_secret"`
+		Endpoint        string
+		Scenes          []string
+	}
+	Tencent struct {
+		SecretID  string `mapstructure:"secret_id"`
+		SecretKey string `mapstructure:"secret_key"`
+		Bucket    string
+		Region    string
+	}
+	NSFW struct {
+		Endpoint  string
+		Threshold float64
+	}
+}
+
+// StorageConfig 对象存储配置
+type StorageConfig struct {
+	Driver string
+	Local  struct {
+		Root       string
+		BaseURL    string `mapstructure:"base_url"`
+		SignSecret string `mapstructure:"sign_secret"`
+	}
+	Qiniu struct {
+		Bucket string
+		Domain string
+	}
+	S3 struct {
+		Endpoint        string
+		Region          string
+		Bucket          string
+		AccessKeyID     string `mapstructure:"access_key_id"`
+		SecretAccessKey string `mapstructure:"secret_access_key"`
+		PathStyle       bool   `mapstructure:"path_style"`
+		PublicURL       string `mapstructure:"public_url"`
+	}
+}
+
+// QueueConfig 消息队列配置
+type QueueConfig struct {
+	Driver         string
+	MaxRetries     int           `mapstructure:"max_retries"`
+	IdempotencyTTL time.Duration `mapstructure:"idempotency_ttl"`
+	Stream         QueueStreamConfig
+	Memory         QueueMemoryConfig
+}
+
+// QueueMemoryConfig 进程内队列配置
+type QueueMemoryConfig struct {
+	Mode string
+}
+
+// QueueStreamConfig redis stream 队列配置
+type QueueStreamConfig struct {
+	Group     string
+	Consumer  string
+	MaxLen    int64         `mapstructure:"max_len"`
+	ClaimIdle time.Duration `mapstructure:"claim_idle"`
+}
+
+// BatchConfig 批量请求配置
+type BatchConfig struct {
+	MaxRequests    int `mapstructure:"max_requests"`
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+}
+
+// TaskConfig 异步任务配置
+type TaskConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// RecordConfig 请求录制配置
+type RecordConfig struct {
+	Enable     bool
+	Driver     string
+	File       string
+	SampleRate float64  `mapstructure:"sample_rate"`
+	MaxBody    int64    `mapstructure:"max_body"`
+	MaskFields []string `mapstructure:"mask_fields"`
+}
+
+// ShadowConfig 影子读配置
+type ShadowConfig struct {
+	Enable     bool
+	Serve      string
+	SampleRate float64 `mapstructure:"sample_rate"`
+}
+
+// UploadConfig 上传配置
+type UploadConfig struct {
+	MaxImageSize int64         `mapstructure:"max_image_size"`
+	MaxFileSize  int64         `mapstructure:"max_file_size"`
+	MultipartTTL time.Duration `mapstructure:"multipart_ttl"`
+	SignedURLTTL time.Duration `mapstructure:"signed_url_ttl"`
+}
+
+// ImageConfig 图片处理配置
+type ImageConfig struct {
+	Sizes   []int
+	Formats []string
+	Quality int
+}
+
+// AntivirusConfig 病毒扫描配置
+type AntivirusConfig struct {
+	Driver  string
+	Timeout time.Duration
+	ClamAV  struct {
+		Addr string
+	}
+	HTTP struct {
+		Endpoint string
+		Token    string
+	}
+}
+
+// SearchConfig 搜索索引配置
+type SearchConfig struct {
+	Driver        string
+	Timeout       time.Duration
+	UserIndex     string `mapstructure:"user_index"`
+	Elasticsearch struct {
+		Addr     string
+		Username string
+		Password string
+	}
+}
+
+// JobConfig 计划任务配置
+type JobConfig struct {
+	Timezone   string
+	MaxDBConns int `mapstructure:"max_db_conns"`
+	RedisRate  int `mapstructure:"redis_rate"`
+	Policies   map[string]JobPolicyConfig
+	// HTTPAddr 暴露 /metrics 和任务管理接口的地址，为空时不开启
+	HTTPAddr string `mapstructure:"http_addr"`
+	// AdminToken 任务管理接口的 Bearer token，为空时不开启管理接口
+	AdminToken string `mapstructure:"admin_token"`
+	Lock       JobLockConfig
+}
+
+// JobLockConfig 计划任务的分布式锁，多副本部署时同一次调度只有一个实例执行
+type JobLockConfig struct {
+	Disable bool
+	TTL     time.Duration
+}
+
+// JobPolicyConfig 单个任务的资源上限
+type JobPolicyConfig struct {
+	MaxDBConns int `mapstructure:"max_db_conns"`
+	RedisRate  int `mapstructure:"redis_rate"`
+}
+
+// I18nConfig 响应中时间、数字的本地化配置
+type I18nConfig struct {
+	DefaultLocale   string            `mapstructure:"default_locale"`
+	DefaultTimezone string            `mapstructure:"default_timezone"`
+	Timezones       map[string]string `mapstructure:"timezones"`
+}
+
+// SMSTemplateConfig 短信模板，id 为服务商的模板id，params 为模板变量名
+type SMSTemplateConfig struct {
+	ID     string
+	Params []string
+}
+
+// SMSConfig 短信配置
+type SMSConfig struct {
+	Providers        []string
+	Timeout          time.Duration
+	FailoverCooldown time.Duration `mapstructure:"failover_cooldown"`
+	Limits           []struct {
+		Window time.Duration
+		Max    int64
+	}
+	Aliyun struct {
+		AccessKeyID     string `mapstructure:"access_key_id"`
+		AccessKeySecret string `mapstructure:"access_key_secret"`
+		Endpoint        string
+		RegionID        string `mapstructure:"region_id"`
+		SignName        string `mapstructure:"sign_name"`
+		Templates       map[string]SMSTemplateConfig
+	}
+	Tencent struct {
+		SecretID  string `mapstructure:"secret_id"`
+		SecretKey string `mapstructure:"secret_key"`
+		Endpoint  string
+		Region    string
+		SdkAppID  string `mapstructure:"sdk_app_id"`
+		SignName  string `mapstructure:"sign_name"`
+		Templates map[string]SMSTemplateConfig
+	}
+	Qiniu struct {
+		SignatureID string `mapstructure:"signature_id"`
+		Templates   map[string]SMSTemplateConfig
+	}
+}
+
+// OTPConfig 验证码配置，短信和图形验证码共用
+type OTPConfig struct {
+	Length         int
+	TTL            time.Duration
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	ResendInterval time.Duration `mapstructure:"resend_interval"`
+	TestTargets    []string      `mapstructure:"test_targets"`
+}
+
+// OAuthClientConfig 第三方应用配置，client_id 为空时不启用
+type OAuthClientConfig struct {
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	RedirectURL  string `mapstructure:"redirect_url"`
+	Scopes       []string
+}
+
+// OAuthConfig 第三方帐号登录配置
+type OAuthConfig struct {
+	Timeout time.Duration
+	Wechat  OAuthClientConfig
+	Github  OAuthClientConfig
+	Google  OAuthClientConfig
+}
+
+// DemoConfig 演示模式配置，开启后使用内嵌的 sqlite 和内存中的 redis，并预置演示用户
+type DemoConfig struct {
+	Enable   bool
+	DSN      string `mapstructure:"dsn"`
+	Users    int
+	Password string
+	Banner   string
+}
+
+// AccountConfig 帐号配置
+type AccountConfig struct {
+	DeletedGracePeriod time.Duration `mapstructure:"deleted_grace_period"`
+	ReservedUsernames  []string      `mapstructure:"reserved_usernames"`
+}
+
+// CampaignConfig 召回活动配置
+type CampaignConfig struct {
+	Rate        int
+	MaxPerRun   int           `mapstructure:"max_per_run"`
+	MinInterval time.Duration `mapstructure:"min_interval"`
+	Campaigns   []struct {
+		Name       string
+		Segment    string
+		Title      string
+		Content    string
+		Experiment string
+		Cooldown   time.Duration
+	}
+}
+
+// QuotaConfig 套餐配额配置
+type QuotaConfig struct {
+	Enable bool
+	Plans  map[string]QuotaPlanConfig
+}
+
+// QuotaPlanConfig 套餐的每天、每月请求次数
+type QuotaPlanConfig struct {
+	Daily   int64
+	Monthly int64
+}
+
+// APIKeyConfig api key 配置
+type APIKeyConfig struct {
+	TTL          time.Duration
+	MaxKeys      int           `mapstructure:"max_keys"`
+	RemindBefore time.Duration `mapstructure:"remind_before"`
+}
+
+// WSConfig websocket 配置
+type WSConfig struct {
+	Enable         bool
+	Heartbeat      time.Duration
+	WriteTimeout   time.Duration `mapstructure:"write_timeout"`
+	SendBuffer     int           `mapstructure:"send_buffer"`
+	BacklogSize    int64         `mapstructure:"backlog_size"`
+	BacklogTTL     time.Duration `mapstructure:"backlog_ttl"`
+	AllowedOrigins []string      `mapstructure:"allowed_origins"`
+}
+
 // init log
 func InitLog() {
 	config := log.Config{
@@ -136,6 +860,10 @@ func InitLog() {
 		LogRotateDate:    viper.GetInt("log.log_rotate_date"),
 		LogRotateSize:    viper.GetInt("log.log_rotate_size"),
 		LogBackupCount:   viper.GetInt("log.log_backup_count"),
+		ModuleLevels:     viper.GetStringMapString("log.module_levels"),
+	}
+	if err := viper.UnmarshalKey("log.sinks", &config.Sinks); err != nil {
+		fmt.Printf("unmarshal log.sinks err: %v", err)
 	}
 	err := log.NewLogger(&config, log.InstanceZapLogger)
 	if err != nil {
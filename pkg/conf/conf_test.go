@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const testConfig = `
+app:
+  name: snake
+log:
+  logger_level: %s
+ratelimit:
+  enable: true
+  limit: 100
+feature:
+  flags:
+    user_identity:
+      enable: false
+      percentage: 10
+metrics:
+  tenants: [a]
+`
+
+// 环境变量覆盖配置文件，修改配置文件后热加载并保留环境变量的覆盖
+func TestInit_EnvAndReload(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(level string) {
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testConfig, level)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("error")
+
+	t.Setenv("SNAKE_APP_NAME", "snake-env")
+	t.Setenv("SNAKE_FEATURE_FLAGS_USER_IDENTITY_ENABLE", "true")
+	t.Setenv("SNAKE_METRICS_TENANTS", "a,b")
+	if err := Init(path); err != nil {
+		t.Fatal(err)
+	}
+	if Conf.App.Name != "snake-env" {
+		t.Fatalf("Conf.App.Name = %q, want snake-env", Conf.App.Name)
+	}
+	var flag struct {
+		Enable     bool
+		Percentage int
+	}
+	if err := viper.UnmarshalKey("feature.flags.user_identity", &flag); err != nil || !flag.Enable || flag.Percentage != 10 {
+		t.Fatalf("UnmarshalKey() = %+v, %v, want enable and percentage 10", flag, err)
+	}
+	if !reflect.DeepEqual(Conf.Metrics.Tenants, []string{"a", "b"}) {
+		t.Fatalf("Conf.Metrics.Tenants = %v, want [a b]", Conf.Metrics.Tenants)
+	}
+
+	changed := make(chan struct{}, 1)
+	OnChange(func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	write("warn")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change hook is not called")
+	}
+	if got := log.GetLevel(); got != "warn" {
+		t.Fatalf("log level = %q, want warn", got)
+	}
+	if Conf.App.Name != "snake-env" || viper.GetString("app.name") != "snake-env" || !viper.GetBool("feature.flags.user_identity.enable") {
+		t.Fatal("env overrides are lost after reload")
+	}
+}
+
+// 没有配置文件时使用内置的演示配置，环境变量仍然可以覆盖
+func TestInitDemo_Builtin(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Setenv("SNAKE_DEMO_USERS", "3")
+	if err := InitDemo(""); err != nil {
+		t.Fatal(err)
+	}
+	if !Conf.Demo.Enable || Conf.Demo.Users != 3 || Conf.Demo.Password == "" {
+		t.Fatalf("Conf.Demo = %+v, want enable and 3 users", Conf.Demo)
+	}
+	if Conf.Cache.Driver != "memory" {
+		t.Fatalf("Conf.Cache.Driver = %q, want memory", Conf.Cache.Driver)
+	}
+}
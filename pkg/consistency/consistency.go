@@ -0,0 +1,116 @@
+// Package consistency 读己之写一致性
+// 写入后在 redis 中为该对象设置一个短时间的标记，标记有效期内的读取绕过缓存直接读主库，
+// 并用读到的数据刷新缓存，避免用户刚保存的修改因为缓存或从库延迟而看不到
+package consistency
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixPinKey 写入标记的 key 前缀，完整的 key 为 snake:consistency:<scope>:<id>
+	PrefixPinKey = "snake:consistency"
+	// DefaultPinTTL 默认的标记有效期，需要大于缓存删除、从库同步的延迟
+	DefaultPinTTL = 5 * time.Second
+)
+
+// Client 全局的写入标记，未开启时为 nil
+var Client *Pinner
+
+// Pinner 写入标记
+type Pinner struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// Init 初始化全局的写入标记
+func Init() *Pinner {
+	if !viper.GetBool("consistency.enable") {
+		return nil
+	}
+	Client = New(redis2.RedisClient, viper.GetDuration("consistency.pin_ttl"))
+	return Client
+}
+
+// New 实例化写入标记，ttl 小于等于0时使用默认值
+func New(client *redis.Client, ttl time.Duration) *Pinner {
+	if ttl <= 0 {
+		ttl = DefaultPinTTL
+	}
+	return &Pinner{client: client, ttl: ttl}
+}
+
+func (p *Pinner) key(scope string, id uint64) string {
+	return fmt.Sprintf("%s:%s:%d", PrefixPinKey, scope, id)
+}
+
+// Pin 写入后调用，有效期内的读取需要读取最新数据
+func (p *Pinner) Pin(scope string, id uint64) error {
+	return p.client.Set(p.key(scope, id), 1, p.ttl).Err()
+}
+
+// Pinned 是否需要读取最新数据
+func (p *Pinner) Pinned(scope string, id uint64) (bool, error) {
+	n, err := p.client.Exists(p.key(scope, id)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// PinnedSet 批量获取需要读取最新数据的id
+func (p *Pinner) PinnedSet(scope string, ids []uint64) (map[uint64]bool, error) {
+	pinned := make(map[uint64]bool)
+	if len(ids) == 0 {
+		return pinned, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, p.key(scope, id))
+	}
+	values, err := p.client.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if v != nil {
+			pinned[ids[i]] = true
+		}
+	}
+	return pinned, nil
+}
+
+// Pin 使用全局的写入标记，未开启时不做处理
+func Pin(scope string, id uint64) error {
+	if Client == nil {
+		return nil
+	}
+	return Client.Pin(scope, id)
+}
+
+// Pinned 使用全局的写入标记，未开启或查询出错时返回 false，按正常路径读取
+func Pinned(scope string, id uint64) bool {
+	if Client == nil {
+		return false
+	}
+	pinned, err := Client.Pinned(scope, id)
+	return err == nil && pinned
+}
+
+// PinnedSet 使用全局的写入标记，未开启或查询出错时返回空
+func PinnedSet(scope string, ids []uint64) map[uint64]bool {
+	if Client == nil {
+		return map[uint64]bool{}
+	}
+	pinned, err := Client.PinnedSet(scope, ids)
+	if err != nil {
+		return map[uint64]bool{}
+	}
+	return pinned
+}
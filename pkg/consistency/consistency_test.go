@@ -0,0 +1,48 @@
+package consistency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinner(t *testing.T) {
+	asserts := assert.New(t)
+
+	mr, err := miniredis.Run()
+	asserts.NoError(err)
+	defer mr.Close()
+	p := New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Second)
+
+	pinned, err := p.Pinned("user", 1)
+	asserts.NoError(err)
+	asserts.False(pinned)
+
+	asserts.NoError(p.Pin("user", 1))
+	asserts.NoError(p.Pin("user", 3))
+	pinned, err = p.Pinned("user", 1)
+	asserts.NoError(err)
+	asserts.True(pinned)
+
+	set, err := p.PinnedSet("user", []uint64{1, 2, 3})
+	asserts.NoError(err)
+	asserts.Equal(map[uint64]bool{1: true, 3: true}, set)
+
+	// 标记过期后按正常路径读取
+	mr.FastForward(2 * time.Second)
+	pinned, err = p.Pinned("user", 1)
+	asserts.NoError(err)
+	asserts.False(pinned)
+}
+
+func TestPinned_Disabled(t *testing.T) {
+	asserts := assert.New(t)
+
+	Client = nil
+	asserts.NoError(Pin("user", 1))
+	asserts.False(Pinned("user", 1))
+	asserts.Empty(PinnedSet("user", []uint64{1}))
+}
@@ -11,4 +11,54 @@ const (
 
 	// XRequestID 全局唯一ID key
 	XRequestID = "X-Request-ID"
+
+	// XIdempotencyKey 幂等 key
+	XIdempotencyKey = "Idempotency-Key"
+	// XIdempotentReplayed 标识响应来自幂等缓存
+	XIdempotentReplayed = "Idempotent-Replayed"
+
+	// XQuotaLimit 配额总次数
+	XQuotaLimit = "X-Quota-Limit"
+	// XQuotaRemaining 配额剩余次数
+	XQuotaRemaining = "X-Quota-Remaining"
+	// XQuotaReset 配额重置时间, unix 时间戳
+	XQuotaReset = "X-Quota-Reset"
+
+	// XRateLimitLimit 限流窗口内允许的请求数
+	XRateLimitLimit = "X-RateLimit-Limit"
+	// XRateLimitRemaining 限流窗口内剩余的请求数
+	XRateLimitRemaining = "X-RateLimit-Remaining"
+	// XRateLimitReset 限流窗口重置时间, unix 时间戳
+	XRateLimitReset = "X-RateLimit-Reset"
+	// XWarning 警告信息
+	XWarning = "Warning"
+
+	// XAPIKey 通过 api key 访问时携带 key 的请求头
+	XAPIKey = "X-Api-Key"
+
+	// XImpersonatedBy 模拟登录的管理员id
+	XImpersonatedBy = "X-Impersonated-By"
+
+	// XChaosFault 故障注入中间件注入的故障类型
+	XChaosFault = "X-Chaos-Fault"
+
+	// XSnakeDemo 演示模式的提示，数据会在重启后重置
+	XSnakeDemo = "X-Snake-Demo"
+
+	// XDBQueryCount 请求执行的数据库语句条数，只在 debug 模式下返回
+	XDBQueryCount = "X-DB-Query-Count"
+
+	// XLocation 异步任务的状态查询地址
+	XLocation = "Location"
+	// XRetryAfter 建议客户端下次轮询的间隔，单位秒
+	XRetryAfter = "Retry-After"
+
+	// XETag 资源的实体标签
+	XETag = "ETag"
+	// XIfMatch 条件更新，实体标签一致时才更新
+	XIfMatch = "If-Match"
+	// XTimeFormat 响应中时间的格式 rfc3339、epoch、local
+	XTimeFormat = "X-Time-Format"
+	// XTimezone 查看者的时区，IANA 名称如 Asia/Shanghai
+	XTimezone = "X-Timezone"
 )
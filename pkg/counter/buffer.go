@@ -0,0 +1,225 @@
+// Package counter 计数缓冲，先在redis中累加计数的增量，再由 Worker 定时批量写入数据库
+// 适用于关注数、粉丝数、浏览数等高频更新的计数，减少数据库行锁的竞争
+package counter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+// PrefixCounterKey 计数缓冲key前缀
+const PrefixCounterKey = "snake:counter"
+
+// Deltas 待写入的增量, id -> 字段 -> 增量
+type Deltas map[uint64]map[string]int64
+
+// FlushFunc 将增量写入存储，返回错误时 deltas 中剩余的增量会被还原，下次重试
+// 分批写入时需要从 deltas 中删除已经写入的id，避免还原后重复写入
+type FlushFunc func(deltas Deltas) error
+
+// staleFlushAge 取出后超过这个时间还没有删除的临时key，认为写入过程中进程已退出，合并回缓冲
+const staleFlushAge = time.Minute
+
+// mergeScript 将临时key中的增量合并回缓冲并删除临时key，多个实例同时合并时只有一个生效
+var mergeScript = redis.NewScript(`
+local values = redis.call("HGETALL", KEYS[1])
+for i = 1, #values, 2 do
+	redis.call("HINCRBY", KEYS[2], values[i], values[i + 1])
+end
+redis.call("DEL", KEYS[1])
+return #values / 2
+`)
+
+// Buffer 基于redis hash的计数缓冲
+// field 格式为 id:字段名, value 为累加的增量
+type Buffer struct {
+	client *redis.Client
+	key    string
+}
+
+// NewBuffer 实例化一个计数缓冲, name 一般为表名
+// client 为 nil 时每次使用默认的 redis client，包初始化时创建的缓冲需要传 nil，这时配置还没有加载
+func NewBuffer(client *redis.Client, name string) *Buffer {
+	return &Buffer{
+		client: client,
+		key:    fmt.Sprintf("%s:%s", PrefixCounterKey, name),
+	}
+}
+
+func (b *Buffer) redis() *redis.Client {
+	if b.client != nil {
+		return b.client
+	}
+	return redis2.RedisClient
+}
+
+// Incr 累加增量
+func (b *Buffer) Incr(id uint64, field string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	return b.redis().HIncrBy(b.key, buildField(id, field), delta).Err()
+}
+
+// Pending 获取还未写入存储的增量
+func (b *Buffer) Pending(id uint64, field string) (int64, error) {
+	val, err := b.redis().HGet(b.key, buildField(id, field)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return val, err
+}
+
+// MultiPending 批量获取还未写入存储的增量，没有增量的id和字段不在返回值中
+func (b *Buffer) MultiPending(ids []uint64, fields ...string) (Deltas, error) {
+	deltas := make(Deltas)
+	if len(ids) == 0 || len(fields) == 0 {
+		return deltas, nil
+	}
+	keys := make([]string, 0, len(ids)*len(fields))
+	for _, id := range ids {
+		for _, field := range fields {
+			keys = append(keys, buildField(id, field))
+		}
+	}
+	values, err := b.redis().HMGet(b.key, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		delta, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || delta == 0 {
+			continue
+		}
+		id, field, _ := parseField(keys[i])
+		if _, ok := deltas[id]; !ok {
+			deltas[id] = make(map[string]int64)
+		}
+		deltas[id][field] = delta
+	}
+	return deltas, nil
+}
+
+// Flush 取出所有增量并交给 fn 写入存储，返回写入的id数量
+// 先将 hash 重命名为临时key，保证取出期间新的增量不会丢失；
+// 取出后进程退出或 redis 出错时临时key会保留，之后的 Flush 在 staleFlushAge 后合并回缓冲
+func (b *Buffer) Flush(fn FlushFunc) (int, error) {
+	if _, err := b.Recover(staleFlushAge); err != nil {
+		return 0, err
+	}
+
+	flushKey := b.flushKey(time.Now())
+	err := b.redis().Rename(b.key, flushKey).Err()
+	if err != nil {
+		// 没有待写入的数据
+		if strings.Contains(err.Error(), "no such key") {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	values, err := b.redis().HGetAll(flushKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	deltas := make(Deltas)
+	for k, v := range values {
+		id, field, ok := parseField(k)
+		if !ok {
+			continue
+		}
+		delta, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || delta == 0 {
+			continue
+		}
+		if _, ok := deltas[id]; !ok {
+			deltas[id] = make(map[string]int64)
+		}
+		deltas[id][field] = delta
+	}
+
+	total := len(deltas)
+	if total > 0 {
+		if err := fn(deltas); err != nil {
+			// 写入失败，将还没有写入的增量还原到缓冲中，还原失败时临时key保留，之后合并回缓冲
+			if e := b.restore(flushKey, deltas); e != nil {
+				return total - len(deltas), e
+			}
+			return total - len(deltas), err
+		}
+	}
+
+	return total, b.redis().Del(flushKey).Err()
+}
+
+// Recover 将取出后超过 olderThan 还没有删除的临时key合并回缓冲，返回合并的key数量
+// 持有和 Flush 共用的锁时可以传0，合并所有临时key
+func (b *Buffer) Recover(olderThan time.Duration) (int, error) {
+	prefix := b.key + ":flushing:"
+	deadline := time.Now().Add(-olderThan).UnixNano()
+	recovered := 0
+	var cursor uint64
+	for {
+		keys, next, err := b.redis().Scan(cursor, prefix+"*", 100).Result()
+		if err != nil {
+			return recovered, err
+		}
+		for _, k := range keys {
+			nanos, err := strconv.ParseInt(strings.TrimPrefix(k, prefix), 10, 64)
+			if err != nil || nanos > deadline {
+				continue
+			}
+			if err := mergeScript.Run(b.redis(), []string{k, b.key}).Err(); err != nil {
+				return recovered, err
+			}
+			recovered++
+		}
+		if next == 0 {
+			return recovered, nil
+		}
+		cursor = next
+	}
+}
+
+func (b *Buffer) flushKey(t time.Time) string {
+	return fmt.Sprintf("%s:flushing:%d", b.key, t.UnixNano())
+}
+
+// restore 在一个事务中还原增量并删除临时key
+func (b *Buffer) restore(flushKey string, deltas Deltas) error {
+	pipe := b.redis().TxPipeline()
+	for id, fields := range deltas {
+		for field, delta := range fields {
+			pipe.HIncrBy(b.key, buildField(id, field), delta)
+		}
+	}
+	pipe.Del(flushKey)
+	_, err := pipe.Exec()
+	return err
+}
+
+func buildField(id uint64, field string) string {
+	return strconv.FormatUint(id, 10) + ":" + field
+}
+
+func parseField(s string) (id uint64, field string, ok bool) {
+	idx := strings.Index(s, ":")
+	if idx <= 0 {
+		return 0, "", false
+	}
+	id, err := strconv.ParseUint(s[:idx], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return id, s[idx+1:], true
+}
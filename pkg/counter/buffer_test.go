@@ -0,0 +1,130 @@
+package counter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestBuffer_Flush(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	b := NewBuffer(redis.RedisClient, "user_stat")
+
+	// 没有数据
+	n, err := b.Flush(func(deltas Deltas) error {
+		t.Fatal("should not be called")
+		return nil
+	})
+	asserts.NoError(err)
+	asserts.Equal(0, n)
+
+	asserts.NoError(b.Incr(1, "follow_count", 1))
+	asserts.NoError(b.Incr(1, "follow_count", 1))
+	asserts.NoError(b.Incr(1, "follower_count", -1))
+	asserts.NoError(b.Incr(2, "follow_count", 3))
+
+	pending, err := b.Pending(1, "follow_count")
+	asserts.NoError(err)
+	asserts.Equal(int64(2), pending)
+
+	// 写入失败时还原
+	_, err = b.Flush(func(deltas Deltas) error {
+		return errors.New("db error")
+	})
+	asserts.Error(err)
+	pending, err = b.Pending(2, "follow_count")
+	asserts.NoError(err)
+	asserts.Equal(int64(3), pending)
+
+	var got Deltas
+	n, err = b.Flush(func(deltas Deltas) error {
+		got = deltas
+		return nil
+	})
+	asserts.NoError(err)
+	asserts.Equal(2, n)
+	asserts.Equal(Deltas{
+		1: {"follow_count": 2, "follower_count": -1},
+		2: {"follow_count": 3},
+	}, got)
+
+	pending, err = b.Pending(1, "follow_count")
+	asserts.NoError(err)
+	asserts.Equal(int64(0), pending)
+}
+
+func TestBuffer_MultiPending(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	b := NewBuffer(redis.RedisClient, "user_stat")
+	asserts.NoError(b.Incr(1, "follow_count", 2))
+	asserts.NoError(b.Incr(1, "view_count", 5))
+	asserts.NoError(b.Incr(2, "follower_count", -1))
+
+	got, err := b.MultiPending([]uint64{1, 2, 3}, "follow_count", "follower_count")
+	asserts.NoError(err)
+	asserts.Equal(Deltas{
+		1: {"follow_count": 2},
+		2: {"follower_count": -1},
+	}, got)
+}
+
+func TestBuffer_FlushPartial(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	b := NewBuffer(redis.RedisClient, "user_stat")
+	asserts.NoError(b.Incr(1, "follow_count", 1))
+	asserts.NoError(b.Incr(2, "follow_count", 2))
+
+	// 第一批写入成功后第二批失败，只还原第二批
+	n, err := b.Flush(func(deltas Deltas) error {
+		delete(deltas, 1)
+		return errors.New("db error")
+	})
+	asserts.Error(err)
+	asserts.Equal(1, n)
+
+	got, err := b.MultiPending([]uint64{1, 2}, "follow_count")
+	asserts.NoError(err)
+	asserts.Equal(Deltas{2: {"follow_count": 2}}, got)
+}
+
+func TestBuffer_Recover(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	b := NewBuffer(redis.RedisClient, "user_stat")
+	// 模拟取出后进程退出留下的临时key
+	stale := b.flushKey(time.Now().Add(-2 * staleFlushAge))
+	asserts.NoError(redis.RedisClient.HSet(stale, "1:follow_count", 3).Err())
+	fresh := b.flushKey(time.Now())
+	asserts.NoError(redis.RedisClient.HSet(fresh, "2:follow_count", 1).Err())
+	asserts.NoError(b.Incr(1, "follow_count", 1))
+
+	// 超过 staleFlushAge 的临时key在 Flush 时合并回缓冲，其他实例正在写入的临时key不受影响
+	var got Deltas
+	_, err := b.Flush(func(deltas Deltas) error {
+		got = deltas
+		return nil
+	})
+	asserts.NoError(err)
+	asserts.Equal(Deltas{1: {"follow_count": 4}}, got)
+	asserts.Equal(int64(0), redis.RedisClient.Exists(stale).Val())
+	asserts.Equal(int64(1), redis.RedisClient.Exists(fresh).Val())
+
+	// 持有锁时合并所有临时key
+	n, err := b.Recover(0)
+	asserts.NoError(err)
+	asserts.Equal(1, n)
+	pending, err := b.Pending(2, "follow_count")
+	asserts.NoError(err)
+	asserts.Equal(int64(1), pending)
+}
@@ -0,0 +1,72 @@
+package counter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// DefaultFlushInterval 默认写入间隔
+const DefaultFlushInterval = 5 * time.Second
+
+// Worker 定时执行 flush
+type Worker struct {
+	interval time.Duration
+	flush    func(ctx context.Context) (int, error)
+	stop     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewWorker 实例化一个worker, interval 为0时使用默认间隔
+func NewWorker(interval time.Duration, flush func(ctx context.Context) (int, error)) *Worker {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &Worker{
+		interval: interval,
+		flush:    flush,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 启动worker
+func (w *Worker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.run()
+			case <-w.stop:
+				// 退出前再写入一次，尽量不丢数据
+				w.run()
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止worker，会等待最后一次写入完成
+func (w *Worker) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	w.wg.Wait()
+}
+
+func (w *Worker) run() {
+	count, err := w.flush(context.Background())
+	if err != nil {
+		log.Warnf("[counter] flush err: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Infof("[counter] flush %d items", count)
+	}
+}
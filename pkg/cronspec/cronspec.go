@@ -0,0 +1,63 @@
+// Package cronspec 解析和检查计划任务的 cron 表达式，部署前发现写错的表达式
+package cronspec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Parse 按标准的 5 个字段解析，支持 @every、@daily 等描述符，并检查容易写错的表达式
+func Parse(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := Lint(spec); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Lint 检查语法正确但很可能写错的表达式
+// 如 "* */5 * * *" 会在每 5 个小时中的每一分钟都执行，本意通常是每 5 分钟执行一次
+func Lint(spec string) error {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil
+	}
+	if fields[0] != "*" {
+		return nil
+	}
+	for _, f := range fields[1:] {
+		if f != "*" && f != "?" {
+			return fmt.Errorf("spec %q runs every minute when other fields match, "+
+				"set the minute field explicitly, e.g. \"0 %s\"", spec, strings.Join(fields[1:], " "))
+		}
+	}
+	return nil
+}
+
+// Location 解析时区，为空时使用本地时区
+func Location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Next 从 from 开始接下来 n 次执行的时间
+func Next(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	ret := make([]time.Time, 0, n)
+	t := from
+	for i := 0; i < n; i++ {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		ret = append(ret, t)
+	}
+	return ret
+}
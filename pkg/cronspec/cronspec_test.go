@@ -0,0 +1,56 @@
+package cronspec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"*/5 * * * *", false},
+		{"0 10 * * *", false},
+		{"@every 1h", false},
+		{"@daily", false},
+		{"* * * * *", false},
+		// 每 5 个小时中的每一分钟
+		{"* */5 * * *", true},
+		{"* 10 * * *", true},
+		{"60 * * * *", true},
+		{"@every", true},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	loc, err := Location("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	schedule, err := Parse("0 10 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2020, 1, 1, 11, 0, 0, 0, loc)
+	got := Next(schedule, from, 2)
+	want := []time.Time{
+		time.Date(2020, 1, 2, 10, 0, 0, 0, loc),
+		time.Date(2020, 1, 3, 10, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("got %s, want %s", got[i], want[i])
+		}
+	}
+}
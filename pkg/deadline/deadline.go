@@ -0,0 +1,52 @@
+// 请求的超时预算，中间件给每个请求设置总的超时时间，DB、Redis 调用使用比剩余时间少 margin 的预算，
+// 超时后留出时间返回错误；客户端断开连接时请求的 ctx 被取消，之后的 DB、Redis 调用不再执行
+
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultMargin 每一跳预留的时间
+const DefaultMargin = 50 * time.Millisecond
+
+// margin 每一跳预留的时间，对应配置 timeout.margin
+var margin = DefaultMargin
+
+// downstreamKey 保存 DB、Redis 调用使用的 ctx
+type downstreamKey struct{}
+
+// Init 按配置初始化每一跳预留的时间
+func Init() {
+	if viper.IsSet("timeout.margin") {
+		margin = viper.GetDuration("timeout.margin")
+	}
+}
+
+// Hop 下一跳的预算，截止时间比 ctx 早 margin，ctx 没有截止时间时只继承取消
+func Hop(ctx context.Context) (context.Context, context.CancelFunc) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, d.Add(-margin))
+}
+
+// WithDownstream 保存 DB、Redis 调用使用的 ctx
+func WithDownstream(ctx, downstream context.Context) context.Context {
+	return context.WithValue(ctx, downstreamKey{}, downstream)
+}
+
+// Downstream 返回 DB、Redis 调用使用的 ctx，没有保存时返回 ctx 本身
+func Downstream(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	if downstream, ok := ctx.Value(downstreamKey{}).(context.Context); ok {
+		return downstream
+	}
+	return ctx
+}
@@ -0,0 +1,43 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHop(t *testing.T) {
+	asserts := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	want, _ := ctx.Deadline()
+
+	hop, hopCancel := Hop(ctx)
+	defer hopCancel()
+	got, ok := hop.Deadline()
+	asserts.True(ok)
+	asserts.Equal(want.Add(-DefaultMargin), got)
+
+	// 没有截止时间时只继承取消
+	parent, parentCancel := context.WithCancel(context.Background())
+	hop, hopCancel = Hop(parent)
+	defer hopCancel()
+	_, ok = hop.Deadline()
+	asserts.False(ok)
+	parentCancel()
+	asserts.Equal(context.Canceled, hop.Err())
+}
+
+func TestDownstream(t *testing.T) {
+	asserts := assert.New(t)
+
+	ctx := context.Background()
+	asserts.Equal(ctx, Downstream(ctx))
+
+	downstream, cancel := context.WithCancel(ctx)
+	defer cancel()
+	asserts.Equal(downstream, Downstream(WithDownstream(ctx, downstream)))
+}
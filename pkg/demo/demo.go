@@ -0,0 +1,62 @@
+// Package demo 演示模式，使用内嵌的 sqlite 和内存中的 redis，预置演示用户，不依赖任何外部服务
+// go run . --demo 即可启动，用于快速体验，数据默认在重启后重置，不能用于生产环境
+package demo
+
+import (
+	_ "embed" // 内嵌演示配置
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultDSN 默认的 sqlite 连接串，数据在内存中，重启后重置
+	DefaultDSN = "file:snake_demo?mode=memory&cache=shared"
+	// DefaultUsers 默认预置的用户数
+	DefaultUsers = 10
+	// DefaultPassword 预置用户的默认密码
+	DefaultPassword = "snake123"
+	// DefaultBanner 默认的演示提示，通过响应头返回
+	DefaultBanner = "snake demo mode, data is reset on restart"
+)
+
+// Config 没有配置文件时使用的演示配置
+//
+//go:embed config.demo.yaml
+var Config []byte
+
+// Enabled 是否开启演示模式
+func Enabled() bool {
+	return viper.GetBool("demo.enable")
+}
+
+// DSN sqlite 连接串，配置为文件路径时数据在重启后保留
+func DSN() string {
+	if dsn := viper.GetString("demo.dsn"); dsn != "" {
+		return dsn
+	}
+	return DefaultDSN
+}
+
+// Users 预置的用户数
+func Users() int {
+	if n := viper.GetInt("demo.users"); n > 0 {
+		return n
+	}
+	return DefaultUsers
+}
+
+// Password 预置用户的密码
+func Password() string {
+	if p := viper.GetString("demo.password"); p != "" {
+		return p
+	}
+	return DefaultPassword
+}
+
+// Banner 演示提示
+func Banner() string {
+	if b := viper.GetString("demo.banner"); b != "" {
+		return b
+	}
+	return DefaultBanner
+}
@@ -0,0 +1,233 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/cronspec"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// sampleJWTSecret conf/config.sample.yaml 中的 jwt_secret，部署时需要修改
+	sampleJWTSecret = "Rtg8BPKNEf2mB4mgvKONGPZZQSaJWNLijxR42qRgq0iBb5"
+	// minJWTSecretLen jwt_secret 的最小长度
+	minJWTSecretLen = 32
+
+	// clockSkewWarn 时钟偏差超过该值时告警，签名、token 等依赖时间的校验可能出错
+	clockSkewWarn = 500 * time.Millisecond
+	// clockSkewFail 时钟偏差超过该值时检查不通过
+	clockSkewFail = 5 * time.Second
+)
+
+// requiredKeys 必须配置的项
+var requiredKeys = []string{"app.addr", "mysql.addr", "mysql.name", "mysql.username", "redis.addr"}
+
+// Checks 默认的检查项，cfg 为配置文件路径，为空时使用 conf/config.local.yaml
+func Checks(cfg string) []Check {
+	config := []string{"config"}
+	return []Check{
+		{Name: "config", Run: checkConfig(cfg)},
+		{Name: "jwt", Run: checkJWT, Requires: config},
+		{Name: "log", Run: checkLog, Requires: config},
+		{Name: "mysql", Run: checkMySQL, Requires: config},
+		{Name: "redis", Run: checkRedis, Requires: config},
+		{Name: "tables", Run: checkTables, Requires: []string{"mysql"}},
+		{Name: "clock", Run: checkClock, Requires: []string{"mysql", "redis"}},
+	}
+}
+
+// checkConfig 配置文件可以解析，必须的配置项都有值
+func checkConfig(cfg string) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		if err := conf.Init(cfg); err != nil {
+			return StatusFail, err.Error()
+		}
+
+		var missing []string
+		for _, key := range requiredKeys {
+			if viper.GetString(key) == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			return StatusFail, "missing " + strings.Join(missing, ", ")
+		}
+		if _, err := cronspec.Location(viper.GetString("job.timezone")); err != nil {
+			return StatusFail, fmt.Sprintf("invalid job.timezone: %v", err)
+		}
+
+		switch mode := viper.GetString("app.run_mode"); mode {
+		case "release":
+		case "debug", "test":
+			return StatusWarn, fmt.Sprintf("%s, app.run_mode is %s", viper.ConfigFileUsed(), mode)
+		default:
+			return StatusFail, fmt.Sprintf("invalid app.run_mode: %q", mode)
+		}
+		return StatusPass, viper.ConfigFileUsed()
+	}
+}
+
+// checkJWT 签发 token 的密钥不能为空，也不能使用示例配置中的值
+func checkJWT(ctx context.Context) (Status, string) {
+	secret := viper.GetString("app.jwt_secret")
+	switch {
+	case secret == "":
+		return StatusFail, "app.jwt_secret is empty"
+	case secret == sampleJWTSecret:
+		return StatusWarn, "app.jwt_secret is the sample value, anyone can sign tokens"
+	case len(secret) < minJWTSecretLen:
+		return StatusWarn, fmt.Sprintf("app.jwt_secret is shorter than %d", minJWTSecretLen)
+	}
+	return StatusPass, ""
+}
+
+// checkLog 输出到文件时，日志文件所在的目录可写
+func checkLog(ctx context.Context) (Status, string) {
+	if !strings.Contains(viper.GetString("log.writers"), "file") {
+		return StatusPass, "file writer disabled"
+	}
+
+	var failed []string
+	for _, key := range []string{"log.logger_file", "log.logger_warn_file", "log.logger_error_file"} {
+		path := viper.GetString(key)
+		if path == "" {
+			failed = append(failed, key+" is empty")
+			continue
+		}
+		if err := writable(path); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(failed) > 0 {
+		return StatusFail, strings.Join(failed, "; ")
+	}
+	return StatusPass, filepath.Dir(viper.GetString("log.logger_file"))
+}
+
+// writable 文件可以追加写入，不存在时目录可以创建文件
+func writable(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkMySQL 默认数据库和各地区的数据库都可以连接
+func checkMySQL(ctx context.Context) (Status, string) {
+	model.Init()
+	model.InitRegions()
+	if err := model.Ping(); err != nil {
+		return StatusFail, err.Error()
+	}
+	return StatusPass, viper.GetString("mysql.addr")
+}
+
+// checkRedis 默认的 redis 和各地区的 redis 都可以连接
+func checkRedis(ctx context.Context) (Status, string) {
+	redis.Connect()
+	redis.InitRegions()
+	if err := redis.Ping(); err != nil {
+		return StatusFail, err.Error()
+	}
+	return StatusPass, viper.GetString("redis.addr")
+}
+
+// checkTables 模型对应的表都已创建，项目没有迁移工具，缺少的表需要按 db.sql 创建
+func checkTables(ctx context.Context) (Status, string) {
+	rows, err := model.WithContext(ctx).Raw("SHOW TABLES").Rows()
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return StatusFail, err.Error()
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return StatusFail, err.Error()
+	}
+
+	var missing []string
+	for _, table := range model.Tables() {
+		if !existing[table] {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return StatusFail, fmt.Sprintf("missing tables: %s, create them from db.sql", strings.Join(missing, ", "))
+	}
+	return StatusPass, fmt.Sprintf("%d tables", len(model.Tables()))
+}
+
+// checkClock 本机和 mysql、redis 的时钟偏差，按往返时间的一半修正
+func checkClock(ctx context.Context) (Status, string) {
+	start := time.Now()
+	var unix float64
+	if err := model.WithContext(ctx).Raw("SELECT UNIX_TIMESTAMP(NOW(3))").Row().Scan(&unix); err != nil {
+		return StatusFail, fmt.Sprintf("mysql: %v", err)
+	}
+	dbSkew := skew(start, time.Now(), time.Unix(0, int64(unix*float64(time.Second))))
+
+	start = time.Now()
+	redisTime, err := redis.WithContext(ctx).Time().Result()
+	if err != nil {
+		return StatusFail, fmt.Sprintf("redis: %v", err)
+	}
+	redisSkew := skew(start, time.Now(), redisTime)
+
+	detail := fmt.Sprintf("mysql: %s, redis: %s", dbSkew, redisSkew)
+	max := abs(dbSkew)
+	if d := abs(redisSkew); d > max {
+		max = d
+	}
+	switch {
+	case max > clockSkewFail:
+		return StatusFail, detail
+	case max > clockSkewWarn:
+		return StatusWarn, detail
+	}
+	return StatusPass, detail
+}
+
+// skew 远端时间相对本机的偏差，假设远端的时间在请求的中间点获取
+func skew(start, end, remote time.Time) time.Duration {
+	local := start.Add(end.Sub(start) / 2)
+	return remote.Sub(local).Round(time.Millisecond)
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,127 @@
+// Package doctor 部署自检，检查配置、依赖的连通性、表结构、时钟偏差等，输出检查报告
+// 首次部署或排查环境问题时通过 snake doctor 执行
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Status 检查结果
+type Status int
+
+const (
+	// StatusPass 通过
+	StatusPass Status = iota
+	// StatusWarn 可以运行，但建议修改
+	StatusWarn
+	// StatusFail 未通过，服务不能正常运行
+	StatusFail
+	// StatusSkip 依赖的检查未通过，跳过
+	StatusSkip
+)
+
+var statusNames = map[Status]string{
+	StatusPass: "PASS",
+	StatusWarn: "WARN",
+	StatusFail: "FAIL",
+	StatusSkip: "SKIP",
+}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+// Check 一项检查，返回结果和说明
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string)
+	// Requires 依赖的检查，依赖的检查未通过时跳过
+	Requires []string
+}
+
+// Result 检查结果
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	Cost   time.Duration
+}
+
+// Run 按顺序执行检查，每项检查最多执行 timeout
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	results := make([]Result, 0, len(checks))
+	passed := make(map[string]bool, len(checks))
+	for _, c := range checks {
+		if missing := firstFailed(c.Requires, passed); missing != "" {
+			results = append(results, Result{Name: c.Name, Status: StatusSkip, Detail: missing + " not passed"})
+			continue
+		}
+
+		start := time.Now()
+		status, detail := runCheck(ctx, c, timeout)
+		passed[c.Name] = status == StatusPass || status == StatusWarn
+		results = append(results, Result{Name: c.Name, Status: status, Detail: detail, Cost: time.Since(start)})
+	}
+	return results
+}
+
+// runCheck 执行一项检查，超时或 panic 时返回 StatusFail
+func runCheck(ctx context.Context, c Check, timeout time.Duration) (status Status, detail string) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		status Status
+		detail string
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{StatusFail, fmt.Sprintf("panic: %v", r)}
+			}
+		}()
+		s, d := c.Run(ctx)
+		done <- result{s, d}
+	}()
+
+	select {
+	case r := <-done:
+		return r.status, r.detail
+	case <-ctx.Done():
+		return StatusFail, fmt.Sprintf("timed out after %s", timeout)
+	}
+}
+
+func firstFailed(requires []string, passed map[string]bool) string {
+	for _, name := range requires {
+		if !passed[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// Report 输出检查报告，返回未通过的检查数
+func Report(w io.Writer, results []Result) (failed int) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tCOST\tDETAIL")
+	warned := 0
+	for _, r := range results {
+		switch r.Status {
+		case StatusFail:
+			failed++
+		case StatusWarn:
+			warned++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, r.Status, r.Cost.Round(time.Millisecond), r.Detail)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(w, "\n%d checks, %d failed, %d warnings\n", len(results), failed, warned)
+	return failed
+}
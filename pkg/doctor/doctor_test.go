@@ -0,0 +1,39 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	checks := []Check{
+		{Name: "config", Run: func(ctx context.Context) (Status, string) { return StatusPass, "" }},
+		{Name: "mysql", Run: func(ctx context.Context) (Status, string) { return StatusFail, "connection refused" }},
+		{Name: "tables", Requires: []string{"mysql"}, Run: func(ctx context.Context) (Status, string) { return StatusPass, "" }},
+		{Name: "slow", Run: func(ctx context.Context) (Status, string) {
+			time.Sleep(time.Second)
+			return StatusPass, ""
+		}},
+		{Name: "panic", Run: func(ctx context.Context) (Status, string) { panic("boom") }},
+		{Name: "jwt", Requires: []string{"config"}, Run: func(ctx context.Context) (Status, string) { return StatusWarn, "too short" }},
+	}
+
+	results := Run(context.Background(), checks, 50*time.Millisecond)
+	want := []Status{StatusPass, StatusFail, StatusSkip, StatusFail, StatusFail, StatusWarn}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("%s: got %s, want %s", r.Name, r.Status, want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if failed := Report(&buf, results); failed != 3 {
+		t.Errorf("failed = %d, want 3", failed)
+	}
+	if !strings.Contains(buf.String(), "6 checks, 3 failed, 1 warnings") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+}
@@ -3,6 +3,7 @@ package email
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"html/template"
 	"io/ioutil"
 	"time"
@@ -66,6 +67,32 @@ func NewResetPasswordHTMLEmail(username, resetURL string) (subject string, body
 	return "密码重置", mailTplContent
 }
 
+// NewLoginNoticeEmail 新设备登录提醒邮件
+// 设备名称来自客户端的 User-Agent，和其他参数一样需要转义，避免在邮件中插入链接等内容
+func NewLoginNoticeEmail(username, device, ip string, loginAt time.Time) (subject string, body string) {
+	return "新设备登录提醒", "Hi, " + html.EscapeString(username) + "<br>您的帐号于 " + loginAt.Format("2006-01-02 15:04:05") +
+		" 在新设备 " + html.EscapeString(device) + " 上登录，IP: " + html.EscapeString(ip) + "<br>如果不是您本人操作，请及时修改密码。"
+}
+
+// NewEmailChangeConfirmEmail 修改邮箱确认邮件，新旧邮箱都会收到
+func NewEmailChangeConfirmEmail(username, oldEmail, newEmail, confirmURL string, isOld bool) (subject string, body string) {
+	tip := "您正在将帐号的邮箱由 " + html.EscapeString(oldEmail) + " 修改为 " + html.EscapeString(newEmail)
+	if oldEmail == "" {
+		tip = "您正在为帐号绑定邮箱 " + html.EscapeString(newEmail)
+	}
+	if isOld {
+		tip += "，需要新旧邮箱都确认后才会生效<br>如果不是您本人操作，请不要点击链接并及时修改密码"
+	}
+	confirmURL = html.EscapeString(confirmURL)
+	return "修改邮箱确认", "Hi, " + html.EscapeString(username) + "<br>" + tip + "<br>请点击链接确认： <a href = '" + confirmURL + "'>" + confirmURL + "</a>"
+}
+
+// NewEmailChangedNoticeEmail 邮箱修改完成通知邮件，发送到旧邮箱
+func NewEmailChangedNoticeEmail(username, newEmail string, changedAt time.Time) (subject string, body string) {
+	return "邮箱已修改", "Hi, " + html.EscapeString(username) + "<br>您的帐号邮箱已于 " + changedAt.Format("2006-01-02 15:04:05") +
+		" 修改为 " + html.EscapeString(newEmail) + "<br>如果不是您本人操作，请及时联系我们。"
+}
+
 // getEmailHTMLContent 获取邮件模板
 func getEmailHTMLContent(tplPath string, mailData interface{}) string {
 	b, err := ioutil.ReadFile(tplPath)
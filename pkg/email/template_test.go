@@ -0,0 +1,27 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 邮件内容中来自用户和客户端的值需要转义
+func TestNewLoginNoticeEmail_Escape(t *testing.T) {
+	_, body := NewLoginNoticeEmail("<b>u</b>", `<a href="https://evil.example">Chrome</a>`, "1.1.1.1", time.Now())
+	if strings.Contains(body, "<a ") || strings.Contains(body, "<b>") {
+		t.Fatalf("body is not escaped: %s", body)
+	}
+	if !strings.Contains(body, "&lt;a href=&#34;https://evil.example&#34;&gt;Chrome&lt;/a&gt;") {
+		t.Fatalf("body = %s, want escaped device", body)
+	}
+
+	_, body = NewEmailChangeConfirmEmail("<b>u</b>", "old@example.com", "<i>new</i>@example.com", "https://example.com/confirm?token=t", true)
+	if strings.Contains(body, "<b>") || strings.Contains(body, "<i>") {
+		t.Fatalf("body is not escaped: %s", body)
+	}
+	_, body = NewEmailChangedNoticeEmail("<b>u</b>", "<i>new</i>@example.com", time.Now())
+	if strings.Contains(body, "<b>") || strings.Contains(body, "<i>") {
+		t.Fatalf("body is not escaped: %s", body)
+	}
+}
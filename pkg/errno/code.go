@@ -1,31 +1,136 @@
 package errno
 
-//nolint: golint
+// nolint: golint
 var (
 	// Common errors
-	OK                  = &Errno{Code: 0, Message: "OK"}
-	InternalServerError = &Errno{Code: 10001, Message: "Internal server error"}
-	ErrBind             = &Errno{Code: 10002, Message: "Error occurred while binding the request body to the struct."}
-	ErrParam            = &Errno{Code: 10003, Message: "参数有误"}
-	ErrSignParam        = &Errno{Code: 10004, Message: "签名参数有误"}
+	OK                       = &Errno{Code: 0, Message: "OK"}
+	InternalServerError      = &Errno{Code: 10001, Message: "Internal server error", Kind: KindInternal}
+	ErrBind                  = &Errno{Code: 10002, Message: "Error occurred while binding the request body to the struct."}
+	ErrParam                 = &Errno{Code: 10003, Message: "参数有误"}
+	ErrSignParam             = &Errno{Code: 10004, Message: "签名参数有误"}
+	ErrPermissionDenied      = &Errno{Code: 10005, Message: "没有权限"}
+	ErrDuplicateRequest      = &Errno{Code: 10006, Message: "请求正在处理中，请勿重复提交", Kind: KindConflict}
+	ErrReplayRequest         = &Errno{Code: 10007, Message: "重复的请求", Kind: KindConflict}
+	ErrQuotaExceeded         = &Errno{Code: 10008, Message: "请求次数已超出套餐限额"}
+	ErrTooManyRequests       = &Errno{Code: 10009, Message: "请求过于频繁，请稍后再试", Kind: KindUnavailable}
+	ErrImpersonationReadOnly = &Errno{Code: 10010, Message: "模拟登录只能进行只读操作"}
+	ErrComingSoon            = &Errno{Code: 10011, Message: "功能即将上线，敬请期待"}
+	ErrPreconditionRequired  = &Errno{Code: 10012, Message: "缺少 If-Match 请求头"}
+	ErrServiceUnavailable    = &Errno{Code: 10013, Message: "服务启动中，请稍后再试", Kind: KindUnavailable}
+	ErrDependencyUnavailable = &Errno{Code: 10014, Message: "服务暂时不可用，请稍后再试", Kind: KindUnavailable}
 
 	ErrValidation         = &Errno{Code: 20001, Message: "Validation failed."}
-	ErrDatabase           = &Errno{Code: 20002, Message: "Database error."}
-	ErrToken              = &Errno{Code: 20003, Message: "Error occurred while signing the JSON web token."}
-	ErrInvalidTransaction = &Errno{Code: 20004, Message: "invalid transaction."}
+	ErrDatabase           = &Errno{Code: 20002, Message: "Database error.", Kind: KindInternal}
+	ErrToken              = &Errno{Code: 20003, Message: "Error occurred while signing the JSON web token.", Kind: KindInternal}
+	ErrInvalidTransaction = &Errno{Code: 20004, Message: "invalid transaction.", Kind: KindInternal}
 
 	// user errors
-	ErrEncrypt               = &Errno{Code: 20101, Message: "Error occurred while encrypting the user password."}
-	ErrUserNotFound          = &Errno{Code: 20102, Message: "The user was not found."}
+	ErrEncrypt               = &Errno{Code: 20101, Message: "Error occurred while encrypting the user password.", Kind: KindInternal}
+	ErrUserNotFound          = &Errno{Code: 20102, Message: "The user was not found.", Kind: KindNotFound}
 	ErrTokenInvalid          = &Errno{Code: 20103, Message: "The token was invalid."}
 	ErrPasswordIncorrect     = &Errno{Code: 20104, Message: "The password was incorrect."}
 	ErrAreaCodeEmpty         = &Errno{Code: 20105, Message: "手机区号不能为空"}
 	ErrPhoneEmpty            = &Errno{Code: 20106, Message: "手机号不能为空"}
-	ErrGenVCode              = &Errno{Code: 20107, Message: "生成验证码错误"}
-	ErrSendSMS               = &Errno{Code: 20108, Message: "发送短信错误"}
+	ErrGenVCode              = &Errno{Code: 20107, Message: "生成验证码错误", Kind: KindInternal}
+	ErrSendSMS               = &Errno{Code: 20108, Message: "发送短信错误", Kind: KindInternal}
 	ErrSendSMSTooMany        = &Errno{Code: 20109, Message: "已超出当日限制，请明天再试"}
 	ErrVerifyCode            = &Errno{Code: 20110, Message: "验证码错误"}
 	ErrEmailOrPassword       = &Errno{Code: 20111, Message: "邮箱或密码错误"}
 	ErrTwicePasswordNotMatch = &Errno{Code: 20112, Message: "两次密码输入不一致"}
-	ErrRegisterFailed        = &Errno{Code: 20113, Message: "注册失败"}
+	ErrRegisterFailed        = &Errno{Code: 20113, Message: "注册失败", Kind: KindInternal}
+	ErrEmailExist            = &Errno{Code: 20114, Message: "邮箱已被使用", Kind: KindConflict}
+	ErrEmailChangeInvalid    = &Errno{Code: 20115, Message: "邮箱确认链接无效"}
+	ErrEmailChangeExpired    = &Errno{Code: 20116, Message: "邮箱确认链接已过期，请重新申请"}
+	ErrPhoneExist            = &Errno{Code: 20117, Message: "手机号已被使用", Kind: KindConflict}
+	ErrPhoneChangeTooOften   = &Errno{Code: 20118, Message: "修改手机号过于频繁，请稍后再试"}
+	ErrIdentityNotFound      = &Errno{Code: 20119, Message: "登录方式不存在", Kind: KindNotFound}
+	ErrLastIdentity          = &Errno{Code: 20120, Message: "至少需要保留一种登录方式"}
+	ErrUserBanned            = &Errno{Code: 20121, Message: "账号已被封禁"}
+	ErrVersionConflict       = &Errno{Code: 20122, Message: "资料已在其他地方被修改，请刷新后重试", Kind: KindConflict}
+	ErrPasswordResetInvalid  = &Errno{Code: 20123, Message: "重置密码链接无效或已过期，请重新申请"}
+	ErrEmailNotVerified      = &Errno{Code: 20124, Message: "请先验证邮箱"}
+	ErrEmailVerifyInvalid    = &Errno{Code: 20125, Message: "邮箱验证链接无效或已过期，请重新发送"}
+	ErrEmailVerified         = &Errno{Code: 20126, Message: "邮箱已验证", Kind: KindConflict}
+	ErrSendSMSTooFrequent    = &Errno{Code: 20127, Message: "验证码发送过于频繁，请稍后再试"}
+	ErrUsernameReserved      = &Errno{Code: 20128, Message: "该用户名为保留名称，不能使用"}
+	ErrUsernameExist         = &Errno{Code: 20129, Message: "用户名已被使用", Kind: KindConflict}
+	ErrOAuthProvider         = &Errno{Code: 20130, Message: "不支持该第三方帐号登录"}
+	ErrOAuthState            = &Errno{Code: 20131, Message: "授权已过期，请重新登录"}
+	ErrOAuthLogin            = &Errno{Code: 20132, Message: "第三方帐号登录失败，请重试"}
+	ErrUserNotDeactivated    = &Errno{Code: 20133, Message: "帐号未停用", Kind: KindConflict}
+	ErrUserReclaimed         = &Errno{Code: 20134, Message: "帐号已超过恢复期限，不能恢复", Kind: KindConflict}
+	ErrUserBlocked           = &Errno{Code: 20135, Message: "你已拉黑对方或已被对方拉黑"}
+	ErrVerifyCodeAttempts    = &Errno{Code: 20136, Message: "验证码错误次数过多，请重新获取"}
+
+	// notification errors
+	ErrNotificationNotFound = &Errno{Code: 20201, Message: "通知不存在", Kind: KindNotFound}
+	ErrAnnouncementNotFound = &Errno{Code: 20202, Message: "公告不存在", Kind: KindNotFound}
+
+	// segment errors
+	ErrSegmentNotFound = &Errno{Code: 20301, Message: "用户分群不存在", Kind: KindNotFound}
+	ErrSegmentExist    = &Errno{Code: 20302, Message: "用户分群名称已存在", Kind: KindConflict}
+
+	// experiment errors
+	ErrExperimentNotFound = &Errno{Code: 20401, Message: "实验不存在", Kind: KindNotFound}
+
+	// moderation errors
+	ErrModerationNotFound = &Errno{Code: 20501, Message: "审核记录不存在", Kind: KindNotFound}
+	ErrModerationReviewed = &Errno{Code: 20502, Message: "该记录已审核", Kind: KindConflict}
+
+	// upload errors
+	ErrUploadTooLarge   = &Errno{Code: 20601, Message: "文件大小超出限制"}
+	ErrUploadType       = &Errno{Code: 20602, Message: "不支持的文件类型"}
+	ErrUploadNotFound   = &Errno{Code: 20603, Message: "上传不存在或已过期", Kind: KindNotFound}
+	ErrUploadPart       = &Errno{Code: 20604, Message: "分片序号或大小有误"}
+	ErrUploadIncomplete = &Errno{Code: 20605, Message: "还有分片未上传"}
+
+	// queue errors
+	ErrDeadMessageNotFound = &Errno{Code: 20701, Message: "死信消息不存在", Kind: KindNotFound}
+
+	// ratelimit errors
+	ErrRateLimitDisabled = &Errno{Code: 20801, Message: "限流或配额未开启"}
+
+	// ban errors
+	ErrBanNotFound    = &Errno{Code: 20901, Message: "账号未被封禁", Kind: KindNotFound}
+	ErrAppealNotFound = &Errno{Code: 20902, Message: "申诉不存在", Kind: KindNotFound}
+	ErrAppealExist    = &Errno{Code: 20903, Message: "申诉正在处理中，请勿重复提交", Kind: KindConflict}
+	ErrAppealReviewed = &Errno{Code: 20904, Message: "该申诉已处理", Kind: KindConflict}
+
+	// policy errors
+	ErrPolicyNotFound     = &Errno{Code: 21001, Message: "协议不存在", Kind: KindNotFound}
+	ErrPolicyNotAccepted  = &Errno{Code: 21002, Message: "协议已更新，请阅读并同意后继续使用"}
+	ErrPolicyVersionExist = &Errno{Code: 21003, Message: "协议版本已存在", Kind: KindConflict}
+	ErrPolicyOutdated     = &Errno{Code: 21004, Message: "协议版本已过期，请同意最新版本"}
+
+	// age gate errors
+	ErrBirthdayInvalid    = &Errno{Code: 21101, Message: "生日有误"}
+	ErrBirthdayAlreadySet = &Errno{Code: 21102, Message: "生日设置后不能修改", Kind: KindConflict}
+	ErrBirthdayRequired   = &Errno{Code: 21103, Message: "请先填写生日"}
+	ErrAgeTooYoung        = &Errno{Code: 21104, Message: "未达到最小使用年龄"}
+	ErrAgeRestricted      = &Errno{Code: 21105, Message: "未达到该功能的年龄要求"}
+	ErrFollowListHidden   = &Errno{Code: 21106, Message: "该用户的关注列表不公开"}
+
+	// task errors
+	ErrTaskNotFound = &Errno{Code: 21201, Message: "任务不存在", Kind: KindNotFound}
+
+	// batch errors
+	ErrBatchTooMany = &Errno{Code: 21301, Message: "批量请求的数量超出限制"}
+
+	// authz errors
+	ErrRoleNotFound = &Errno{Code: 21401, Message: "角色不存在", Kind: KindNotFound}
+
+	// job errors
+	ErrJobNotFound = &Errno{Code: 21501, Message: "计划任务不存在", Kind: KindNotFound}
+	ErrJobRunning  = &Errno{Code: 21502, Message: "计划任务正在执行", Kind: KindConflict}
+
+	// api key errors
+	ErrAPIKeyNotFound   = &Errno{Code: 21601, Message: "API key 不存在", Kind: KindNotFound}
+	ErrAPIKeyLimit      = &Errno{Code: 21602, Message: "API key 数量已达上限", Kind: KindConflict}
+	ErrAPIKeyInvalid    = &Errno{Code: 21603, Message: "API key 无效或已过期"}
+	ErrAPIKeyRevoked    = &Errno{Code: 21604, Message: "API key 已吊销", Kind: KindConflict}
+	ErrAPIKeyForbidden  = &Errno{Code: 21605, Message: "不能通过 API key 管理 API key"}
+	ErrAPIKeyNotAllowed = &Errno{Code: 21606, Message: "该接口不能通过 API key 访问，请使用登录 token"}
+
+	// websocket errors
+	ErrWebSocketDisabled = &Errno{Code: 21701, Message: "实时消息未开启"}
 )
@@ -6,6 +6,8 @@ import "fmt"
 type Errno struct {
 	Code    int
 	Message string
+	// Kind 错误分类，业务错误默认为 KindInvalid
+	Kind Kind
 }
 
 func (err Errno) Error() string {
@@ -0,0 +1,134 @@
+package errno
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"net/http"
+)
+
+// Kind 错误分类，handler、队列消费者和客户端按分类决定返回的状态码和是否重试
+type Kind int
+
+// 业务错误码未指定分类时为 KindInvalid
+const (
+	// KindInvalid 请求本身有误，如参数错误、没有权限，重试也会得到同样的结果
+	KindInvalid Kind = iota
+	// KindNotFound 请求的资源不存在
+	KindNotFound
+	// KindConflict 和资源当前的状态冲突，如已存在、已处理、版本不一致
+	KindConflict
+	// KindUnavailable 依赖暂时不可用或超时，稍后重试可能成功
+	KindUnavailable
+	// KindInternal 未知的内部错误
+	KindInternal
+)
+
+var kindNames = map[Kind]string{
+	KindInvalid:     "invalid",
+	KindNotFound:    "not_found",
+	KindConflict:    "conflict",
+	KindUnavailable: "unavailable",
+	KindInternal:    "internal",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return kindNames[KindInternal]
+}
+
+// Retryable 重试可能成功的分类，Invalid、NotFound、Conflict 重试没有意义
+func (k Kind) Retryable() bool {
+	return k == KindUnavailable || k == KindInternal
+}
+
+// ParseKind 解析分类名称，未知的名称为 KindInternal
+func ParseKind(name string) Kind {
+	for k, n := range kindNames {
+		if n == name {
+			return k
+		}
+	}
+	return KindInternal
+}
+
+// kindError 给非业务错误指定分类
+type kindError struct {
+	kind Kind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+// Cause 兼容 errors.Cause
+func (e *kindError) Cause() error {
+	return e.err
+}
+
+// Unwrap 兼容 errors.Is/As
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// WithKind 给错误指定分类，如消息无法解析时指定为 KindInvalid，让消费者不再重试
+func WithKind(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// KindOf 返回错误的分类，沿着 Cause/Unwrap 找到第一个有分类的错误
+// 超时、连接失败等错误为 KindUnavailable，其他未分类的错误为 KindInternal
+func KindOf(err error) Kind {
+	for err != nil {
+		switch typed := err.(type) {
+		case *Errno:
+			return typed.Kind
+		case *kindError:
+			return typed.kind
+		case net.Error:
+			return KindUnavailable
+		}
+		if err == context.DeadlineExceeded || err == context.Canceled || err == driver.ErrBadConn {
+			return KindUnavailable
+		}
+
+		switch typed := err.(type) {
+		case interface{ Cause() error }:
+			err = typed.Cause()
+		case interface{ Unwrap() error }:
+			err = typed.Unwrap()
+		default:
+			return KindInternal
+		}
+	}
+	return KindInternal
+}
+
+// IsRetryable 错误是否值得重试
+func IsRetryable(err error) bool {
+	return err != nil && KindOf(err).Retryable()
+}
+
+// KindOfStatus 按 http 状态码推断分类，用于客户端处理非本项目或网关返回的错误
+func KindOfStatus(status int) Kind {
+	switch {
+	case status == http.StatusNotFound:
+		return KindNotFound
+	case status == http.StatusConflict || status == http.StatusPreconditionFailed:
+		return KindConflict
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests:
+		return KindUnavailable
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout:
+		return KindUnavailable
+	case status >= http.StatusInternalServerError:
+		return KindInternal
+	}
+	return KindInvalid
+}
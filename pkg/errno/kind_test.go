@@ -0,0 +1,78 @@
+package errno
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"default business error", ErrParam, KindInvalid},
+		{"not found", ErrUserNotFound, KindNotFound},
+		{"conflict", ErrVersionConflict, KindConflict},
+		{"unavailable", ErrServiceUnavailable, KindUnavailable},
+		{"internal", InternalServerError, KindInternal},
+		{"wrapped errno", pkgerrors.Wrap(ErrTaskNotFound, "get task"), KindNotFound},
+		{"std wrapped errno", fmt.Errorf("get task: %w", ErrTaskNotFound), KindNotFound},
+		{"with kind", pkgerrors.Wrap(WithKind(KindInvalid, errors.New("bad json")), "decode"), KindInvalid},
+		{"deadline", pkgerrors.Wrap(context.DeadlineExceeded, "query"), KindUnavailable},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, KindUnavailable},
+		{"plain error", errors.New("boom"), KindInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if IsRetryable(nil) {
+		t.Error("nil should not be retryable")
+	}
+	if IsRetryable(ErrParam) || IsRetryable(ErrUserNotFound) || IsRetryable(ErrVersionConflict) {
+		t.Error("invalid, not found and conflict should not be retryable")
+	}
+	if !IsRetryable(ErrServiceUnavailable) || !IsRetryable(errors.New("boom")) {
+		t.Error("unavailable and internal should be retryable")
+	}
+}
+
+func TestKindOfStatus(t *testing.T) {
+	tests := map[int]Kind{
+		http.StatusBadRequest:          KindInvalid,
+		http.StatusNotFound:            KindNotFound,
+		http.StatusPreconditionFailed:  KindConflict,
+		http.StatusTooManyRequests:     KindUnavailable,
+		http.StatusServiceUnavailable:  KindUnavailable,
+		http.StatusInternalServerError: KindInternal,
+	}
+	for status, want := range tests {
+		if got := KindOfStatus(status); got != want {
+			t.Errorf("KindOfStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for k := KindInvalid; k <= KindInternal; k++ {
+		if got := ParseKind(k.String()); got != k {
+			t.Errorf("ParseKind(%s) = %s", k, got)
+		}
+	}
+	if got := ParseKind("unknown"); got != KindInternal {
+		t.Errorf("ParseKind(unknown) = %s, want internal", got)
+	}
+}
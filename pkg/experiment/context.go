@@ -0,0 +1,16 @@
+package experiment
+
+import "github.com/gin-gonic/gin"
+
+// contextKeyPrefix 分组保存在 gin.Context 中的 key 前缀
+const contextKeyPrefix = "experiment:"
+
+// SetVariant 保存用户在实验中的分组，由中间件调用
+func SetVariant(c *gin.Context, name, variant string) {
+	c.Set(contextKeyPrefix+name, variant)
+}
+
+// GetVariant 获取当前用户在实验中的分组，没有经过实验中间件时返回空字符串
+func GetVariant(c *gin.Context, name string) string {
+	return c.GetString(contextKeyPrefix + name)
+}
@@ -0,0 +1,195 @@
+// A/B 实验，按用户id和实验名称的哈希稳定地把用户分配到某个分组
+// 实验配置保存在 redis 中，由管理后台开始、停止，停止后所有用户都使用对照组(第一个分组)
+
+package experiment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+// PrefixExperimentKey 保存所有实验的 hash，field 为实验名称
+const PrefixExperimentKey = "snake:experiment"
+
+var (
+	// ErrNotFound 实验不存在
+	ErrNotFound = errors.New("experiment not found")
+	// ErrInvalid 实验配置有误
+	ErrInvalid = errors.New("invalid experiment")
+
+	nameRegexp = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+)
+
+// Client 全局的实验客户端
+var Client *Manager
+
+// Variant 实验分组，按权重分配流量
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Experiment 实验
+type Experiment struct {
+	Name      string     `json:"name"`
+	Variants  []Variant  `json:"variants"`
+	Running   bool       `json:"running"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+// Control 对照组，第一个分组
+func (e *Experiment) Control() string {
+	if len(e.Variants) == 0 {
+		return ""
+	}
+	return e.Variants[0].Name
+}
+
+// Assign 分配用户到分组，同一用户在同一实验中的结果不变
+// 实验停止后所有用户都分配到对照组
+func (e *Experiment) Assign(userID uint64) string {
+	if !e.Running {
+		return e.Control()
+	}
+
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return e.Control()
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fmt.Sprintf("%s:%d", e.Name, userID)))
+	bucket := int(h.Sum32() % uint32(total))
+	for _, v := range e.Variants {
+		if bucket < v.Weight {
+			return v.Name
+		}
+		bucket -= v.Weight
+	}
+	return e.Control()
+}
+
+// validate 校验实验配置
+func (e *Experiment) validate() error {
+	if !nameRegexp.MatchString(e.Name) || len(e.Variants) < 2 {
+		return ErrInvalid
+	}
+	names := make(map[string]bool, len(e.Variants))
+	for _, v := range e.Variants {
+		if v.Name == "" || v.Weight < 0 || names[v.Name] {
+			return ErrInvalid
+		}
+		names[v.Name] = true
+	}
+	return nil
+}
+
+// Manager 管理实验
+type Manager struct {
+	client *redis.Client
+}
+
+// Init 初始化全局的实验客户端
+func Init() *Manager {
+	Client = New(redis2.RedisClient)
+	return Client
+}
+
+// New 实例化实验管理
+func New(client *redis.Client) *Manager {
+	return &Manager{client: client}
+}
+
+// Start 开始实验，已存在的实验会被覆盖并重新开始
+func (m *Manager) Start(name string, variants []Variant) (*Experiment, error) {
+	e := &Experiment{
+		Name:      name,
+		Variants:  variants,
+		Running:   true,
+		StartedAt: time.Now(),
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	if err := m.save(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Stop 停止实验
+func (m *Manager) Stop(name string) (*Experiment, error) {
+	e, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Running {
+		return e, nil
+	}
+
+	now := time.Now()
+	e.Running = false
+	e.StoppedAt = &now
+	if err := m.save(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Get 获取实验
+func (m *Manager) Get(name string) (*Experiment, error) {
+	val, err := m.client.HGet(PrefixExperimentKey, name).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Experiment{}
+	if err := json.Unmarshal([]byte(val), e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// List 获取所有实验，按名称排序
+func (m *Manager) List() ([]*Experiment, error) {
+	values, err := m.client.HGetAll(PrefixExperimentKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	experiments := make([]*Experiment, 0, len(values))
+	for _, val := range values {
+		e := &Experiment{}
+		if err := json.Unmarshal([]byte(val), e); err != nil {
+			continue
+		}
+		experiments = append(experiments, e)
+	}
+	sort.Slice(experiments, func(i, j int) bool { return experiments[i].Name < experiments[j].Name })
+	return experiments, nil
+}
+
+// save 保存实验
+func (m *Manager) save(e *Experiment) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return m.client.HSet(PrefixExperimentKey, e.Name, b).Err()
+}
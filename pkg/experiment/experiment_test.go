@@ -0,0 +1,67 @@
+package experiment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestAssign(t *testing.T) {
+	e := &Experiment{
+		Name:     "new_profile",
+		Variants: []Variant{{Name: "control", Weight: 50}, {Name: "treatment", Weight: 50}},
+		Running:  true,
+	}
+
+	counts := make(map[string]int)
+	for uid := uint64(1); uid <= 1000; uid++ {
+		variant := e.Assign(uid)
+		if variant != e.Assign(uid) {
+			t.Fatalf("Assign() not stable for uid %d", uid)
+		}
+		counts[variant]++
+	}
+	if counts["control"] < 400 || counts["treatment"] < 400 {
+		t.Errorf("Assign() counts = %v, want about 500 each", counts)
+	}
+
+	// 停止后都使用对照组
+	e.Running = false
+	for uid := uint64(1); uid <= 100; uid++ {
+		if got := e.Assign(uid); got != "control" {
+			t.Fatalf("Assign() = %s, want control", got)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+	m := New(redis.RedisClient)
+
+	_, err := m.Start("bad name", []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}})
+	asserts.Equal(ErrInvalid, err)
+	_, err = m.Start("one_variant", []Variant{{Name: "a", Weight: 1}})
+	asserts.Equal(ErrInvalid, err)
+
+	_, err = m.Start("new_profile", []Variant{{Name: "control", Weight: 1}, {Name: "treatment", Weight: 1}})
+	asserts.NoError(err)
+
+	e, err := m.Get("new_profile")
+	asserts.NoError(err)
+	asserts.True(e.Running)
+
+	e, err = m.Stop("new_profile")
+	asserts.NoError(err)
+	asserts.False(e.Running)
+	asserts.NotNil(e.StoppedAt)
+
+	list, err := m.List()
+	asserts.NoError(err)
+	asserts.Len(list, 1)
+
+	_, err = m.Stop("missing")
+	asserts.Equal(ErrNotFound, err)
+}
@@ -0,0 +1,55 @@
+package experiment
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Exposure 曝光，用户实际看到了实验某个分组的效果
+type Exposure struct {
+	Experiment string    `json:"experiment"`
+	Variant    string    `json:"variant"`
+	UserID     uint64    `json:"user_id"`
+	Path       string    `json:"path"`
+	Time       time.Time `json:"time"`
+}
+
+// ExposureLogger 曝光日志的写入方式，可以替换为写入消息队列等
+type ExposureLogger interface {
+	Log(e *Exposure)
+}
+
+// Exposures 全局的曝光日志，默认写入应用日志，由日志采集进入数据分析
+var Exposures ExposureLogger = logExposureLogger{}
+
+var exposureTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "snake_experiment_exposures_total",
+	Help: "Total number of experiment exposures.",
+}, []string{"experiment", "variant"})
+
+// LogExposure 记录曝光
+func LogExposure(e *Exposure) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	exposureTotal.WithLabelValues(e.Experiment, e.Variant).Inc()
+	Exposures.Log(e)
+}
+
+// logExposureLogger 写入应用日志，每行一条 json
+type logExposureLogger struct{}
+
+// Log 写入日志
+func (logExposureLogger) Log(e *Exposure) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Warnf("[experiment] marshal exposure err: %v", err)
+		return
+	}
+	log.Infof("[experiment] exposure %s", b)
+}
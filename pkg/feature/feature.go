@@ -0,0 +1,75 @@
+// 功能开关，用于新接口的灰度上线
+// 开关配置在 feature.flags 下，支持白名单用户和按比例放量，修改配置后实时生效
+
+package feature
+
+import (
+	"fmt"
+	"hash/crc32"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Flag 功能开关配置
+type Flag struct {
+	// Enable 总开关，关闭时所有用户都不可用
+	Enable bool `mapstructure:"enable"`
+	// AllowUIDs 白名单用户，不受放量比例限制
+	AllowUIDs []uint64 `mapstructure:"allow_uids"`
+	// Segments 用户分群，在其中任意一个分群的用户可用
+	Segments []string `mapstructure:"segments"`
+	// Percentage 放量比例 0~100，按用户id分桶，100 表示全量
+	Percentage int `mapstructure:"percentage"`
+}
+
+// SegmentMatcher 判断用户是否在分群中，由业务层在启动时注入，未注入时分群配置不生效
+var SegmentMatcher func(segment string, userID uint64) bool
+
+// GetFlag 获取功能开关配置，未配置时返回关闭的开关
+func GetFlag(name string) Flag {
+	var flag Flag
+	if err := viper.UnmarshalKey("feature.flags."+name, &flag); err != nil {
+		log.Warnf("[feature] unmarshal flag %s err: %v", name, err)
+		return Flag{}
+	}
+	return flag
+}
+
+// Enabled 功能对用户是否可用，userID 为0表示未登录用户，只有全量时可用
+func Enabled(name string, userID uint64) bool {
+	return GetFlag(name).Enabled(name, userID)
+}
+
+// Enabled 功能对用户是否可用
+func (f Flag) Enabled(name string, userID uint64) bool {
+	if !f.Enable {
+		return false
+	}
+	if f.Percentage >= 100 {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+	for _, uid := range f.AllowUIDs {
+		if uid == userID {
+			return true
+		}
+	}
+	if SegmentMatcher != nil {
+		for _, segment := range f.Segments {
+			if SegmentMatcher(segment, userID) {
+				return true
+			}
+		}
+	}
+	return bucket(name, userID) < f.Percentage
+}
+
+// bucket 计算用户所在的桶 0~99
+// 加上开关名，让不同功能放量的用户不同，同一用户在同一功能下结果稳定
+func bucket(name string, userID uint64) int {
+	return int(crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s:%d", name, userID))) % 100)
+}
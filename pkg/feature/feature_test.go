@@ -0,0 +1,56 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEnabled(t *testing.T) {
+	viper.Set("feature.flags.test_off", map[string]interface{}{"enable": false, "percentage": 100})
+	viper.Set("feature.flags.test_allow", map[string]interface{}{"enable": true, "allow_uids": []uint64{1, 2}})
+	viper.Set("feature.flags.test_full", map[string]interface{}{"enable": true, "percentage": 100})
+	viper.Set("feature.flags.test_half", map[string]interface{}{"enable": true, "percentage": 50})
+	viper.Set("feature.flags.test_segment", map[string]interface{}{"enable": true, "segments": []string{"beta"}})
+	SegmentMatcher = func(segment string, userID uint64) bool {
+		return segment == "beta" && userID == 5
+	}
+	defer func() { SegmentMatcher = nil }()
+
+	tests := []struct {
+		name   string
+		flag   string
+		userID uint64
+		want   bool
+	}{
+		{"not configured", "test_missing", 1, false},
+		{"disabled", "test_off", 1, false},
+		{"in allowlist", "test_allow", 2, true},
+		{"not in allowlist", "test_allow", 3, false},
+		{"anonymous not full", "test_allow", 0, false},
+		{"full rollout", "test_full", 0, true},
+		{"in segment", "test_segment", 5, true},
+		{"not in segment", "test_segment", 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Enabled(tt.flag, tt.userID); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 按比例放量，结果稳定且比例大致正确
+	enabled := 0
+	for uid := uint64(1); uid <= 1000; uid++ {
+		if Enabled("test_half", uid) {
+			enabled++
+		}
+		if Enabled("test_half", uid) != Enabled("test_half", uid) {
+			t.Fatalf("Enabled() not stable for uid %d", uid)
+		}
+	}
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("Enabled() count = %d, want about 500", enabled)
+	}
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestEdgeListWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatEdgeList, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.WriteEdge(1, 2)
+	_ = w.WriteEdge(2, 3)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "1 2\n2 3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGraphMLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatGraphML, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.WriteEdge(1, 2)
+	_ = w.WriteEdge(2, 1)
+	_ = w.WriteEdge(1, 3)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Graph struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid graphml: %v\n%s", err, buf.String())
+	}
+	if doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("edgedefault got %s", doc.Graph.EdgeDefault)
+	}
+	// 每个节点只写一次
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 3 {
+		t.Fatalf("got %d nodes, %d edges", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	if e := doc.Graph.Edges[1]; e.Source != "n2" || e.Target != "n1" {
+		t.Errorf("edge got %+v", e)
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	if _, err := NewWriter("csv", &bytes.Buffer{}); err != ErrUnknownFormat {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+}
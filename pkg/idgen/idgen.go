@@ -0,0 +1,122 @@
+// Package idgen id 生成器，默认使用 snowflake 算法，测试时替换为 Sequence 得到确定的 id
+package idgen
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/clock"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	// MaxNode 最大的节点id
+	MaxNode     = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Epoch snowflake 的起始时间，修改后会和已生成的 id 重复
+var Epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IDGenerator id 生成器
+type IDGenerator interface {
+	NextID() (uint64, error)
+}
+
+// Func 把函数转换为 IDGenerator
+type Func func() (uint64, error)
+
+// NextID 生成 id
+func (f Func) NextID() (uint64, error) {
+	return f()
+}
+
+var (
+	mu sync.RWMutex
+	// 未初始化时使用节点0，方便单元测试和命令行工具
+	defGen IDGenerator = &Snowflake{clock: clock.Real, lastTime: -1}
+)
+
+// Init 按配置的 app.node_id 初始化默认的生成器，多实例部署时每个实例的 node_id 需要不同
+func Init() IDGenerator {
+	gen, err := NewSnowflake(viper.GetInt64("app.node_id"), clock.Real)
+	if err != nil {
+		panic(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	defGen = gen
+	return gen
+}
+
+// NextID 使用默认的生成器生成 id
+func NextID() (uint64, error) {
+	mu.RLock()
+	gen := defGen
+	mu.RUnlock()
+	return gen.NextID()
+}
+
+// Default 默认的生成器，每次调用时使用 Init 后的配置，可以在初始化前注入到服务中
+var Default IDGenerator = Func(NextID)
+
+// Snowflake 生成趋势递增的 id，由毫秒时间戳、节点id、毫秒内的序号组成
+type Snowflake struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	node     uint64
+	lastTime int64
+	sequence uint64
+}
+
+// NewSnowflake 实例化 snowflake 生成器，node 的范围为 0-1023
+func NewSnowflake(node int64, clk clock.Clock) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("idgen: node id must be between 0 and %d, got %d", MaxNode, node)
+	}
+	return &Snowflake{clock: clk, node: uint64(node), lastTime: -1}, nil
+}
+
+// NextID 生成 id
+// 时钟回拨或同一毫秒内的序号用完时，借用上一个 id 的下一毫秒，保证 id 不重复且递增
+func (s *Snowflake) NextID() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now().Sub(Epoch).Milliseconds()
+	if now < 0 {
+		return 0, fmt.Errorf("idgen: clock is before epoch %s", Epoch)
+	}
+
+	switch {
+	case now > s.lastTime:
+		s.lastTime, s.sequence = now, 0
+	case s.sequence < maxSequence:
+		s.sequence++
+	default:
+		s.lastTime, s.sequence = s.lastTime+1, 0
+	}
+	return uint64(s.lastTime)<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence, nil
+}
+
+// Sequence 测试用的生成器，从 start 开始依次加一
+type Sequence struct {
+	next uint64
+}
+
+// NewSequence 实例化一个从 start 开始的生成器
+func NewSequence(start uint64) *Sequence {
+	return &Sequence{next: start}
+}
+
+// NextID 生成 id
+func (s *Sequence) NextID() (uint64, error) {
+	return atomic.AddUint64(&s.next, 1) - 1, nil
+}
@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/clock"
+)
+
+func TestSnowflake_NextID(t *testing.T) {
+	clk := clock.NewFake(Epoch.Add(time.Hour))
+	s, err := NewSnowflake(3, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := s.NextID()
+	if want := uint64(time.Hour/time.Millisecond)<<22 | 3<<12; id != want {
+		t.Fatalf("NextID() = %d, want %d", id, want)
+	}
+	next, _ := s.NextID()
+	if next != id+1 {
+		t.Fatalf("NextID() in same millisecond = %d, want %d", next, id+1)
+	}
+
+	// 时钟回拨时不重复
+	clk.Add(-time.Minute)
+	back, _ := s.NextID()
+	if back <= next {
+		t.Fatalf("NextID() after clock moved backwards = %d, want > %d", back, next)
+	}
+
+	// 序号用完时借用下一毫秒
+	clk.Add(time.Hour)
+	last := uint64(0)
+	for i := 0; i <= maxSequence+1; i++ {
+		id, _ := s.NextID()
+		if id <= last {
+			t.Fatalf("NextID() = %d, want > %d", id, last)
+		}
+		last = id
+	}
+	if got := last >> 22; got != uint64(clk.Now().Sub(Epoch).Milliseconds())+1 {
+		t.Fatalf("timestamp after sequence overflow = %d", got)
+	}
+}
+
+func TestNewSnowflake(t *testing.T) {
+	if _, err := NewSnowflake(MaxNode+1, clock.Real); err == nil {
+		t.Fatal("NewSnowflake() with invalid node, want err")
+	}
+	s, _ := NewSnowflake(0, clock.NewFake(Epoch.Add(-time.Second)))
+	if _, err := s.NextID(); err == nil {
+		t.Fatal("NextID() before epoch, want err")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var gen IDGenerator = NewSequence(100)
+	for want := uint64(100); want < 103; want++ {
+		if id, _ := gen.NextID(); id != want {
+			t.Fatalf("NextID() = %d, want %d", id, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package imageaudit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// see: https://help.aliyun.com/document_detail/70292.html
+
+const (
+	aliyunDefaultEndpoint = "https://green.cn-shanghai.aliyuncs.com"
+	aliyunImageScanPath   = "/green/image/scan"
+	aliyunAPIVersion      = "2018-05-09"
+	aliyunSuggestionPass  = "pass"
+)
+
+// AliyunConfig 阿里云内容安全配置
+type AliyunConfig struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	// Endpoint 默认为上海区域
+	Endpoint string
+	// Scenes 检测场景，默认 porn、terrorism
+	Scenes  []string
+	Timeout time.Duration
+}
+
+// aliyun 阿里云内容安全图片同步检测
+type aliyun struct {
+	conf   AliyunConfig
+	client *http.Client
+}
+
+// NewAliyun 实例化阿里云内容安全
+func NewAliyun(conf AliyunConfig) Provider {
+	if conf.Endpoint == "" {
+		conf.Endpoint = aliyunDefaultEndpoint
+	}
+	if len(conf.Scenes) == 0 {
+		conf.Scenes = []string{"porn", "terrorism"}
+	}
+	return &aliyun{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (a *aliyun) Name() string {
+	return ProviderAliyun
+}
+
+type aliyunScanResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		Code    int    `json:"code"`
+		Msg     string `json:"msg"`
+		Results []struct {
+			Scene      string  `json:"scene"`
+			Label      string  `json:"label"`
+			Suggestion string  `json:"suggestion"`
+			Rate       float64 `json:"rate"`
+		} `json:"results"`
+	} `json:"data"`
+}
+
+// Check 检测图片，任意场景建议不是 pass 时需要人工审核
+func (a *aliyun) Check(ctx context.Context, url string) (*Result, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"scenes": a.conf.Scenes,
+		"tasks":  []map[string]string{{"dataId": uuid.New().String(), "url": url}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.conf.Endpoint+aliyunImageScanPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	a.sign(req, body)
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var scanResp aliyunScanResponse
+	if err := json.Unmarshal(b, &scanResp); err != nil {
+		return nil, fmt.Errorf("[imageaudit] aliyun unmarshal resp err: %v, body: %s", err, b)
+	}
+	if scanResp.Code != http.StatusOK || len(scanResp.Data) == 0 {
+		return nil, fmt.Errorf("[imageaudit] aliyun scan err, code: %d, msg: %s", scanResp.Code, scanResp.Msg)
+	}
+	task := scanResp.Data[0]
+	if task.Code != http.StatusOK {
+		return nil, fmt.Errorf("[imageaudit] aliyun scan task err, code: %d, msg: %s", task.Code, task.Msg)
+	}
+
+	result := &Result{}
+	for _, r := range task.Results {
+		if r.Suggestion != aliyunSuggestionPass && r.Rate >= result.Score {
+			result.Flagged = true
+			result.Label = r.Scene + ":" + r.Label
+			result.Score = r.Rate
+		}
+	}
+	return result, nil
+}
+
+// sign 按阿里云 ROA 风格签名
+func (a *aliyun) sign(req *http.Request, body []byte) {
+	sum := md5.Sum(body)
+	contentMD5 := base64.StdEncoding.EncodeToString(sum[:])
+	date := time.Now().UTC().Format(http.TimeFormat)
+
+	req.Header.Set("Accept", contentTypeJSON)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Content-MD5", contentMD5)
+	req.Header.Set("Date", date)
+	req.Header.Set("x-acs-version", aliyunAPIVersion)
+	req.Header.Set("x-acs-signature-nonce", uuid.New().String())
+	req.Header.Set("x-acs-signature-version", "1.0")
+	req.Header.Set("x-acs-signature-method", "HMAC-SHA1")
+
+	// 以 x-acs- 开头的 header 按名称排序后参与签名
+	var acsHeaders []string
+	for k := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-acs-") {
+			acsHeaders = append(acsHeaders, k)
+		}
+	}
+	sort.Strings(acsHeaders)
+	var canonicalHeaders strings.Builder
+	for _, k := range acsHeaders {
+		canonicalHeaders.WriteString(k + ":" + req.Header.Get(k) + "\n")
+	}
+
+	stringToSign := strings.Join([]string{
+		http.MethodPost, contentTypeJSON, contentMD5, contentTypeJSON, date,
+	}, "\n") + "\n" + canonicalHeaders.String() + aliyunImageScanPath
+
+	mac := hmac.New(sha1.New, []byte(a.conf.AccessKeySecret))
+	mac.Write([]byte(stringToSign))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("Authorization", "acs "+a.conf.AccessKeyID+":"+signature)
+}
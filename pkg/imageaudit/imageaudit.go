@@ -0,0 +1,92 @@
+// 图片内容审核，支持阿里云内容安全、腾讯云数据万象和自建的 nsfw 模型服务
+// 通过 imageaudit.provider 选择，为空时不审核
+
+package imageaudit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ProviderAliyun 阿里云内容安全
+	ProviderAliyun = "aliyun"
+	// ProviderTencent 腾讯云数据万象
+	ProviderTencent = "tencent"
+	// ProviderNSFW 自建的 nsfw 模型服务
+	ProviderNSFW = "nsfw"
+
+	defaultTimeout  = 5 * time.Second
+	contentTypeJSON = "application/json"
+)
+
+// ErrUnknownProvider 未知的审核服务
+var ErrUnknownProvider = errors.New("unknown image audit provider")
+
+// Client 全局的图片审核服务，未配置时为nil
+var Client Provider
+
+// Result 审核结果
+type Result struct {
+	// Flagged 是否需要人工审核
+	Flagged bool `json:"flagged"`
+	// Label 命中的类型，如 porn、terrorism
+	Label string `json:"label"`
+	// Score 置信度 0~100
+	Score float64 `json:"score"`
+}
+
+// Provider 图片审核服务
+type Provider interface {
+	Name() string
+	Check(ctx context.Context, url string) (*Result, error)
+}
+
+// Init 按配置初始化全局的图片审核服务
+func Init() Provider {
+	provider, err := New(viper.GetString("imageaudit.provider"))
+	if err != nil {
+		panic(err)
+	}
+	Client = provider
+	return Client
+}
+
+// New 按名称实例化图片审核服务，名称为空时返回nil
+func New(name string) (Provider, error) {
+	timeout := viper.GetDuration("imageaudit.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch name {
+	case "":
+		return nil, nil
+	case ProviderAliyun:
+		return NewAliyun(AliyunConfig{
+			AccessKeyID:     viper.GetString("imageaudit.aliyun.access_key_id"),
+			AccessKeySecret: viper.GetString("imageaudit.aliyun.access_key_secret"),
+			Endpoint:        viper.GetString("imageaudit.aliyun.endpoint"),
+			Scenes:          viper.GetStringSlice("imageaudit.aliyun.scenes"),
+			Timeout:         timeout,
+		}), nil
+	case ProviderTencent:
+		return NewTencent(TencentConfig{
+			SecretID:  viper.GetString("imageaudit.tencent.secret_id"),
+			SecretKey: viper.GetString("imageaudit.tencent.secret_key"),
+			Bucket:    viper.GetString("imageaudit.tencent.bucket"),
+			Region:    viper.GetString("imageaudit.tencent.region"),
+			Timeout:   timeout,
+		}), nil
+	case ProviderNSFW:
+		return NewNSFW(NSFWConfig{
+			Endpoint:  viper.GetString("imageaudit.nsfw.endpoint"),
+			Threshold: viper.GetFloat64("imageaudit.nsfw.threshold"),
+			Timeout:   timeout,
+		}), nil
+	}
+	return nil, ErrUnknownProvider
+}
@@ -0,0 +1,101 @@
+package imageaudit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNSFW_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		score := 0.1
+		if strings.Contains(req["url"], "bad") {
+			score = 0.95
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"nsfw": score, "label": "porn"})
+	}))
+	defer srv.Close()
+
+	p := NewNSFW(NSFWConfig{Endpoint: srv.URL})
+	tests := []struct {
+		url     string
+		flagged bool
+	}{
+		{"http://img.example.com/good.png", false},
+		{"http://img.example.com/bad.png", true},
+	}
+	for _, tt := range tests {
+		ret, err := p.Check(context.Background(), tt.url)
+		if err != nil {
+			t.Fatalf("check %s err: %v", tt.url, err)
+		}
+		if ret.Flagged != tt.flagged {
+			t.Errorf("check %s flagged = %v, want %v", tt.url, ret.Flagged, tt.flagged)
+		}
+	}
+}
+
+func TestAliyun_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != aliyunImageScanPath || !strings.HasPrefix(r.Header.Get("Authorization"), "acs ak:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":200,"data":[{"code":200,"results":[
+			{"scene":"porn","label":"normal","suggestion":"pass","rate":99.9},
+			{"scene":"terrorism","label":"bloody","suggestion":"review","rate":80}]}]}`))
+	}))
+	defer srv.Close()
+
+	p := NewAliyun(AliyunConfig{AccessKeyID: "ak", AccessKeySecret: "sk", Endpoint: srv.URL})
+	ret, err := p.Check(context.Background(), "http://img.example.com/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ret.Flagged || ret.Label != "terrorism:bloody" {
+		t.Errorf("got %+v, want flagged terrorism:bloody", ret)
+	}
+}
+
+func TestTencent_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ci-process") != "sensitive-content-recognition" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`<RecognitionResult>
+			<PornInfo><HitFlag>2</HitFlag><Score>75</Score><Label>sexy</Label></PornInfo>
+			<TerroristInfo><HitFlag>0</HitFlag><Score>3</Score></TerroristInfo>
+		</RecognitionResult>`))
+	}))
+	defer srv.Close()
+
+	p := NewTencent(TencentConfig{SecretID: "id", SecretKey: "key", Endpoint: srv.URL})
+	ret, err := p.Check(context.Background(), "http://img.example.com/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ret.Flagged || ret.Label != "porn:sexy" {
+		t.Errorf("got %+v, want flagged porn:sexy", ret)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if p, err := New(""); p != nil || err != nil {
+		t.Errorf("New(\"\") = %v, %v, want nil, nil", p, err)
+	}
+	if _, err := New("unknown"); err != ErrUnknownProvider {
+		t.Errorf("New(unknown) err = %v, want %v", err, ErrUnknownProvider)
+	}
+	for _, name := range []string{ProviderAliyun, ProviderTencent, ProviderNSFW} {
+		p, err := New(name)
+		if err != nil || p.Name() != name {
+			t.Errorf("New(%s) = %v, %v", name, p, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package imageaudit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const nsfwDefaultThreshold = 0.8
+
+// NSFWConfig 自建 nsfw 模型服务配置
+type NSFWConfig struct {
+	// Endpoint 检测接口地址，接收 {"url": ""}，返回 {"nsfw": 0.97, "label": "porn"}
+	Endpoint string
+	// Threshold nsfw 分值超过阈值时需要人工审核，取值 0~1
+	Threshold float64
+	Timeout   time.Duration
+}
+
+// nsfw 调用本地部署的 nsfw 模型，如 open_nsfw、nsfwjs
+type nsfw struct {
+	conf   NSFWConfig
+	client *http.Client
+}
+
+// NewNSFW 实例化 nsfw 模型服务
+func NewNSFW(conf NSFWConfig) Provider {
+	if conf.Threshold <= 0 {
+		conf.Threshold = nsfwDefaultThreshold
+	}
+	return &nsfw{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (n *nsfw) Name() string {
+	return ProviderNSFW
+}
+
+type nsfwResponse struct {
+	NSFW  float64 `json:"nsfw"`
+	Label string  `json:"label"`
+}
+
+// Check 检测图片
+func (n *nsfw) Check(ctx context.Context, url string) (*Result, error) {
+	body, err := json.Marshal(map[string]string{"url": url})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+
+	resp, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[imageaudit] nsfw detect err, status: %d, body: %s", resp.StatusCode, b)
+	}
+
+	var nsfwResp nsfwResponse
+	if err := json.Unmarshal(b, &nsfwResp); err != nil {
+		return nil, fmt.Errorf("[imageaudit] nsfw unmarshal resp err: %v, body: %s", err, b)
+	}
+
+	label := nsfwResp.Label
+	if label == "" {
+		label = ProviderNSFW
+	}
+	return &Result{
+		Flagged: nsfwResp.NSFW >= n.conf.Threshold,
+		Label:   label,
+		Score:   nsfwResp.NSFW * 100,
+	}, nil
+}
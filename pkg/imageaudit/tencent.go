@@ -0,0 +1,176 @@
+package imageaudit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// see: https://cloud.tencent.com/document/product/460/37318
+
+const (
+	tencentDetectType = "porn,terrorist,politics,ads"
+	tencentSignTTL    = time.Hour
+)
+
+// TencentConfig 腾讯云数据万象配置
+type TencentConfig struct {
+	SecretID  string
+	SecretKey string
+	Bucket    string
+	Region    string
+	// Endpoint 为空时使用 bucket 和 region 拼接
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// tencent 腾讯云数据万象图片审核
+type tencent struct {
+	conf   TencentConfig
+	client *http.Client
+}
+
+// NewTencent 实例化腾讯云数据万象
+func NewTencent(conf TencentConfig) Provider {
+	if conf.Endpoint == "" {
+		conf.Endpoint = fmt.Sprintf("https://%s.cos.%s.myqcloud.com", conf.Bucket, conf.Region)
+	}
+	return &tencent{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (t *tencent) Name() string {
+	return ProviderTencent
+}
+
+type tencentDetectInfo struct {
+	HitFlag int     `xml:"HitFlag"`
+	Score   float64 `xml:"Score"`
+	Label   string  `xml:"Label"`
+}
+
+type tencentRecognitionResult struct {
+	XMLName       xml.Name          `xml:"RecognitionResult"`
+	PornInfo      tencentDetectInfo `xml:"PornInfo"`
+	TerroristInfo tencentDetectInfo `xml:"TerroristInfo"`
+	PoliticsInfo  tencentDetectInfo `xml:"PoliticsInfo"`
+	AdsInfo       tencentDetectInfo `xml:"AdsInfo"`
+}
+
+type tencentErrorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// Check 检测图片，HitFlag 为 1(违规) 或 2(疑似) 时需要人工审核
+func (t *tencent) Check(ctx context.Context, imageURL string) (*Result, error) {
+	query := url.Values{}
+	query.Set("ci-process", "sensitive-content-recognition")
+	query.Set("detect-type", tencentDetectType)
+	query.Set("detect-url", imageURL)
+
+	req, err := http.NewRequest(http.MethodGet, t.conf.Endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", t.sign(req, time.Now()))
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp tencentErrorResponse
+		_ = xml.Unmarshal(b, &errResp)
+		return nil, fmt.Errorf("[imageaudit] tencent detect err, status: %d, code: %s, msg: %s",
+			resp.StatusCode, errResp.Code, errResp.Message)
+	}
+
+	var recognition tencentRecognitionResult
+	if err := xml.Unmarshal(b, &recognition); err != nil {
+		return nil, fmt.Errorf("[imageaudit] tencent unmarshal resp err: %v, body: %s", err, b)
+	}
+
+	result := &Result{}
+	infos := map[string]tencentDetectInfo{
+		"porn":      recognition.PornInfo,
+		"terrorist": recognition.TerroristInfo,
+		"politics":  recognition.PoliticsInfo,
+		"ads":       recognition.AdsInfo,
+	}
+	for scene, info := range infos {
+		if info.HitFlag != 0 && info.Score >= result.Score {
+			result.Flagged = true
+			result.Label = scene + ":" + info.Label
+			result.Score = info.Score
+		}
+	}
+	return result, nil
+}
+
+// sign 生成 COS 请求签名
+// see: https://cloud.tencent.com/document/product/436/7778
+func (t *tencent) sign(req *http.Request, now time.Time) string {
+	keyTime := fmt.Sprintf("%d;%d", now.Unix(), now.Add(tencentSignTTL).Unix())
+
+	mac := hmac.New(sha1.New, []byte(t.conf.SecretKey))
+	mac.Write([]byte(keyTime))
+	signKey := hex.EncodeToString(mac.Sum(nil))
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	paramList, params := canonical(req.URL.Query())
+	// 不签名 header，header 部分为空
+	httpString := strings.Join([]string{strings.ToLower(req.Method), path, params, "", ""}, "\n")
+	sum := sha1.Sum([]byte(httpString))
+	stringToSign := strings.Join([]string{"sha1", keyTime, hex.EncodeToString(sum[:]), ""}, "\n")
+
+	mac = hmac.New(sha1.New, []byte(signKey))
+	mac.Write([]byte(stringToSign))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	return strings.Join([]string{
+		"q-sign-algorithm=sha1",
+		"q-ak=" + t.conf.SecretID,
+		"q-sign-time=" + keyTime,
+		"q-key-time=" + keyTime,
+		"q-header-list=",
+		"q-url-param-list=" + paramList,
+		"q-signature=" + signature,
+	}, "&")
+}
+
+// canonical 参数名小写后按字典序排列
+func canonical(values url.Values) (keyList string, params string) {
+	keys := make([]string, 0, len(values))
+	lowered := make(map[string]string, len(values))
+	for k := range values {
+		lk := strings.ToLower(url.QueryEscape(k))
+		keys = append(keys, lk)
+		lowered[lk] = url.QueryEscape(values.Get(k))
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+lowered[k])
+	}
+	return strings.Join(keys, ";"), strings.Join(pairs, "&")
+}
@@ -0,0 +1,175 @@
+// Package imageproc 图片处理：裁剪、缩放、格式转换
+// 图片重新编码后不会保留 EXIF 等元数据，可以避免泄露拍摄地点等隐私信息
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+
+	// 注册 gif 解码
+	_ "image/gif"
+)
+
+// 支持输出的格式
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	// FormatWebP 依赖 cwebp 命令
+	FormatWebP = "webp"
+)
+
+const defaultQuality = 85
+
+var (
+	// ErrUnsupportedFormat 不支持的输出格式
+	ErrUnsupportedFormat = errors.New("imageproc: unsupported format")
+	// ErrWebPUnavailable 没有安装 cwebp
+	ErrWebPUnavailable = errors.New("imageproc: cwebp not found in PATH")
+)
+
+// Decode 解码 jpeg、png、gif 图片，返回图片和原始格式
+func Decode(r io.Reader) (image.Image, string, error) {
+	return image.Decode(r)
+}
+
+// Thumbnail 按目标宽高比从中心裁剪后缩放
+func Thumbnail(src image.Image, width, height int) image.Image {
+	return Resize(CropCenter(src, width, height), width, height)
+}
+
+// CropCenter 按宽高比从中心裁剪
+func CropCenter(src image.Image, width, height int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// 比较 w/h 和 width/height
+	if w*height > h*width {
+		w = h * width / height
+	} else {
+		h = w * height / width
+	}
+	x0 := b.Min.X + (b.Dx()-w)/2
+	y0 := b.Min.Y + (b.Dy()-h)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// Resize 缩放到指定宽高，缩小时取源区域像素的平均值，放大时取最近的像素
+func Resize(src image.Image, width, height int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if sw == 0 || sh == 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		sy0 := b.Min.Y + y*sh/height
+		sy1 := b.Min.Y + (y+1)*sh/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < width; x++ {
+			sx0 := b.Min.X + x*sw/width
+			sx1 := b.Min.X + (x+1)*sw/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// Encode 按格式编码图片，quality 只对 jpeg、webp 有效
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	if quality <= 0 || quality > 100 {
+		quality = defaultQuality
+	}
+
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatWebP:
+		return encodeWebP(w, img, quality)
+	}
+	return ErrUnsupportedFormat
+}
+
+// ContentType 格式对应的 Content-Type
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// Ext 格式对应的文件扩展名
+func Ext(format string) string {
+	if format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// encodeWebP 先编码为 png 再调用 cwebp 转换
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	bin, err := exec.LookPath("cwebp")
+	if err != nil {
+		return ErrWebPUnavailable
+	}
+
+	in, err := ioutil.TempFile("", "imageproc-*.png")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(in.Name())
+	if err := png.Encode(in, img); err != nil {
+		in.Close()
+		return err
+	}
+	if err := in.Close(); err != nil {
+		return err
+	}
+
+	out := in.Name() + ".webp"
+	defer os.Remove(out)
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "-quiet", "-metadata", "none", "-q", strconv.Itoa(quality), in.Name(), "-o", out)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("imageproc: cwebp err: " + err.Error() + ", " + stderr.String())
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
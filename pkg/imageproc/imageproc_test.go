@@ -0,0 +1,49 @@
+package imageproc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	// 300x200，左半部分红色，右半部分蓝色
+	src := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 150 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	dst := Thumbnail(src, 64, 64)
+	if b := dst.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Fatalf("got size %dx%d, want 64x64", b.Dx(), b.Dy())
+	}
+	if r, _, b, _ := dst.At(0, 32).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("left pixel should be red")
+	}
+	if r, _, b, _ := dst.At(63, 32).RGBA(); r != 0 || b>>8 != 255 {
+		t.Errorf("right pixel should be blue")
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, dst, FormatJPEG, 80); err != nil {
+		t.Fatal(err)
+	}
+	img, format, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatJPEG || img.Bounds().Dx() != 64 {
+		t.Errorf("decode got format %s, width %d", format, img.Bounds().Dx())
+	}
+
+	if err := Encode(&buf, dst, "bmp", 0); err != ErrUnsupportedFormat {
+		t.Errorf("encode bmp err = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
@@ -0,0 +1,303 @@
+// Package lifecycle 进程的启动和退出流程，各模块注册 OnStart、OnStop 回调，入口只需要注册后调用 Run
+// 启动时按注册的顺序执行 OnStart，某个模块启动失败时按相反顺序停止已启动的模块；
+// 滚动发布时不中断正在处理的请求：收到 SIGTERM 后先标记为停止中，就绪检查返回 503，等待 delay 让负载均衡摘除实例，
+// 再按注册的相反顺序执行退出回调：停止接收新请求并等待处理中的请求完成、停止后台任务、关闭连接池
+// 所有退出回调共用 timeout 的时间预算，超时后回调应当强制退出
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const (
+	// DefaultTimeout 默认的退出时间预算，需要小于 k8s 的 terminationGracePeriodSeconds
+	DefaultTimeout = 20 * time.Second
+	// DefaultStartTimeout 默认的单个启动回调的超时时间
+	DefaultStartTimeout = 15 * time.Second
+)
+
+// Client 全局的生命周期，未初始化时认为没有在停止
+var Client *Manager
+
+// Config 启动和退出配置
+type Config struct {
+	// Timeout 执行退出回调的总时间
+	Timeout time.Duration
+	// Delay 标记为停止中之后，执行退出回调之前的等待时间，期间照常处理请求
+	Delay time.Duration
+	// StartTimeout 单个启动回调的超时时间，Hook.Timeout 不为0时使用 Hook.Timeout
+	StartTimeout time.Duration
+}
+
+// Hook 模块的启动、退出回调，都可以为空
+// OnStart 不能阻塞，需要一直运行的服务在 goroutine 中执行；OnStop 只在 OnStart 成功后执行，没有 OnStart 时总是执行
+// Timeout 为单个回调的超时时间，退出时不会超过剩余的总时间预算
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+	Timeout time.Duration
+}
+
+// entry 注册的回调和启动状态
+type entry struct {
+	Hook
+	started bool
+}
+
+// Manager 管理启动和退出回调
+type Manager struct {
+	cfg Config
+
+	mu    sync.Mutex
+	hooks []*entry
+
+	stopping int32
+	once     sync.Once
+	done     chan struct{}
+	err      error
+}
+
+// Init 按 app.shutdown_timeout、app.shutdown_delay、app.start_timeout 初始化全局的生命周期
+func Init() *Manager {
+	Client = New(Config{
+		Timeout:      viper.GetDuration("app.shutdown_timeout"),
+		Delay:        viper.GetDuration("app.shutdown_delay"),
+		StartTimeout: viper.GetDuration("app.start_timeout"),
+	})
+	return Client
+}
+
+// New 实例化，Timeout、StartTimeout 为0时使用默认值
+func New(cfg Config) *Manager {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = DefaultStartTimeout
+	}
+	return &Manager{cfg: cfg, done: make(chan struct{})}
+}
+
+// Add 注册回调，先注册的先启动、后停止：先注册连接池，再注册依赖它的后台任务，最后注册 http 服务
+func (m *Manager) Add(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, &entry{Hook: h, started: h.OnStart == nil})
+}
+
+// Append 注册只有退出回调的模块，如初始化时已经创建好的连接池
+func (m *Manager) Append(name string, stop func(ctx context.Context) error) {
+	m.Add(Hook{Name: name, OnStop: stop})
+}
+
+// Stopping 是否已经开始退出
+func (m *Manager) Stopping() bool {
+	return atomic.LoadInt32(&m.stopping) == 1
+}
+
+// Start 按注册的顺序执行启动回调，已启动的模块不重复启动
+// 某个回调出错或超时时不等待退出延迟，按相反顺序停止已启动的模块，返回启动的错误
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := append([]*entry(nil), m.hooks...)
+	m.mu.Unlock()
+	for _, h := range hooks {
+		if m.isStarted(h) {
+			continue
+		}
+		timeout := h.Timeout
+		if timeout <= 0 {
+			timeout = m.cfg.StartTimeout
+		}
+		start := time.Now()
+		if err := call(ctx, timeout, h.OnStart); err != nil {
+			log.Errorf("[lifecycle] start %s err: %v, cost: %s", h.Name, err, time.Since(start))
+			_ = m.shutdown(0)
+			return fmt.Errorf("[lifecycle] start %s: %w", h.Name, err)
+		}
+		m.mu.Lock()
+		h.started = true
+		m.mu.Unlock()
+		log.Infof("[lifecycle] %s started, cost: %s", h.Name, time.Since(start))
+	}
+	return nil
+}
+
+// Run 启动所有模块，等待 SIGINT、SIGTERM 后执行退出流程，返回启动或退出的错误
+func (m *Manager) Run() error {
+	if err := m.Start(context.Background()); err != nil {
+		return err
+	}
+	waitSignal()
+	return m.Shutdown()
+}
+
+// Wait 等待 SIGINT、SIGTERM 后执行退出流程，用于不需要启动回调的场景
+func (m *Manager) Wait() {
+	waitSignal()
+	_ = m.Shutdown()
+}
+
+// waitSignal 等待退出信号
+func waitSignal() {
+	quit := make(chan os.Signal, 1)
+	// kill 命令发送信号 syscall.SIGTERM
+	// kill -2 命令发送信号 syscall.SIGINT
+	// kill -9 命令发送信号 syscall.SIGKILL，无法捕获
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	signal.Stop(quit)
+	log.Infof("[lifecycle] received signal %s, shutting down...", sig)
+}
+
+// Shutdown 执行退出流程，多次调用时只执行一次，其他调用等待执行完成
+// 某个回调出错时记录日志并继续执行后面的回调，返回第一个错误
+func (m *Manager) Shutdown() error {
+	return m.shutdown(m.cfg.Delay)
+}
+
+func (m *Manager) shutdown(delay time.Duration) error {
+	m.once.Do(func() {
+		defer close(m.done)
+		atomic.StoreInt32(&m.stopping, 1)
+		if delay > 0 {
+			log.Infof("[lifecycle] not ready, waiting %s for load balancer to deregister", delay)
+			time.Sleep(delay)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+		defer cancel()
+
+		m.mu.Lock()
+		hooks := make([]Hook, 0, len(m.hooks))
+		for _, h := range m.hooks {
+			if h.started && h.OnStop != nil {
+				hooks = append(hooks, h.Hook)
+			}
+		}
+		m.mu.Unlock()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			h := hooks[i]
+			start := time.Now()
+			if e := call(ctx, h.Timeout, h.OnStop); e != nil {
+				log.Warnf("[lifecycle] stop %s err: %v, cost: %s", h.Name, e, time.Since(start))
+				if m.err == nil {
+					m.err = e
+				}
+				continue
+			}
+			log.Infof("[lifecycle] %s stopped, cost: %s", h.Name, time.Since(start))
+		}
+		log.Info("[lifecycle] shutdown completed")
+	})
+	<-m.done
+	return m.err
+}
+
+// isStarted 模块是否已经启动
+func (m *Manager) isStarted(h *entry) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return h.started
+}
+
+// call 执行回调，timeout 大于0时缩短回调的 ctx，回调需要在 ctx 结束后尽快返回
+func call(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
+
+// Stopping 全局的退出流程是否已经开始，未初始化时返回 false
+func Stopping() bool {
+	if Client == nil {
+		return false
+	}
+	return Client.Stopping()
+}
+
+// HTTP http 服务的回调，启动时监听端口，端口被占用等错误作为启动错误返回
+func HTTP(srv *http.Server) Hook {
+	return Hook{
+		Name: "http",
+		OnStart: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+					log.Errorf("[lifecycle] http serve err: %v", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: Server(srv),
+	}
+}
+
+// Worker 后台任务的回调，start 启动 goroutine 后返回，stop 等待任务退出
+func Worker(name string, start, stop func()) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			start()
+			return nil
+		},
+		OnStop: Func(stop),
+	}
+}
+
+// Server 停止接收新请求，关闭空闲连接，等待处理中的请求完成，超时后强制关闭连接
+func Server(srv *http.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		srv.SetKeepAlivesEnabled(false)
+		if err := srv.Shutdown(ctx); err != nil {
+			_ = srv.Close()
+			return err
+		}
+		return nil
+	}
+}
+
+// Func 执行不支持 ctx 的退出函数，如后台任务的 Stop，超时后不再等待
+func Func(stop func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			stop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Closer 关闭连接池等资源
+func Closer(c io.Closer) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return c.Close()
+	}
+}
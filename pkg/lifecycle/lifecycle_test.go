@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func init() {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+}
+
+func TestShutdown_ReverseOrder(t *testing.T) {
+	m := New(Config{})
+	var got []string
+	for _, name := range []string{"mysql", "redis", "worker", "http"} {
+		name := name
+		m.Append(name, func(ctx context.Context) error {
+			got = append(got, name)
+			if name == "worker" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	if m.Stopping() {
+		t.Fatal("Stopping() = true before shutdown")
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = m.Shutdown()
+		}(i)
+	}
+	wg.Wait()
+
+	want := []string{"http", "worker", "redis", "mysql"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("stop order = %v, want %v", got, want)
+	}
+	for _, err := range errs {
+		if err == nil || err.Error() != "boom" {
+			t.Fatalf("Shutdown() err = %v, want boom", err)
+		}
+	}
+	if !m.Stopping() {
+		t.Fatal("Stopping() = false after shutdown")
+	}
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	m := New(Config{Timeout: 50 * time.Millisecond})
+	closed := false
+	m.Append("pool", func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+	block := make(chan struct{})
+	defer close(block)
+	m.Append("worker", Func(func() { <-block }))
+
+	start := time.Now()
+	err := m.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() err = %v, want deadline exceeded", err)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Fatalf("Shutdown() cost %s, want about timeout", cost)
+	}
+	if !closed {
+		t.Fatal("hooks after timeout are not executed")
+	}
+}
+
+func TestStart_Rollback(t *testing.T) {
+	m := New(Config{Delay: time.Hour})
+	var got []string
+	record := func(s string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			got = append(got, s)
+			return nil
+		}
+	}
+	m.Append("pool", record("stop pool"))
+	m.Add(Hook{Name: "worker", OnStart: record("start worker"), OnStop: record("stop worker")})
+	m.Add(Hook{
+		Name:    "http",
+		OnStart: func(ctx context.Context) error { return errors.New("address already in use") },
+		OnStop:  record("stop http"),
+	})
+	m.Add(Hook{Name: "cron", OnStart: record("start cron"), OnStop: record("stop cron")})
+
+	// 启动失败时不等待退出延迟，只停止已启动的模块
+	err := m.Start(context.Background())
+	if err == nil || err.Error() != "[lifecycle] start http: address already in use" {
+		t.Fatalf("Start() err = %v", err)
+	}
+	want := []string{"start worker", "stop worker", "stop pool"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	if !m.Stopping() {
+		t.Fatal("Stopping() = false after start failed")
+	}
+}
+
+func TestStart_HookTimeout(t *testing.T) {
+	m := New(Config{StartTimeout: time.Hour})
+	m.Add(Hook{
+		Name:    "slow",
+		Timeout: 20 * time.Millisecond,
+		OnStart: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Start() err = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,261 @@
+// 基于 redis 的分布式锁
+// 加锁使用 SET key token NX PX，解锁和续期使用 lua 脚本校验 token，避免误删其他实例持有的锁
+//
+// 使用方式:
+// 		l := lock.New(redis.RedisClient, "job:greeting", lock.WithTTL(10*time.Second), lock.WithWatchdog())
+// 		ok, err := l.TryLock()
+// 		if err != nil || !ok {
+// 			return
+// 		}
+// 		defer l.Release()
+
+package lock
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultTTL 默认锁过期时间
+	DefaultTTL = 10 * time.Second
+	// DefaultRetryInterval Acquire 默认重试间隔
+	DefaultRetryInterval = 50 * time.Millisecond
+
+	lockKeyPrefix = "lock"
+)
+
+var (
+	// ErrNotAcquired 未获取到锁
+	ErrNotAcquired = errors.New("lock: not acquired")
+	// ErrLockNotHeld 锁已经过期或被其他实例持有
+	ErrLockNotHeld = errors.New("lock: not held")
+)
+
+var (
+	// token 一致才删除
+	releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+else
+	return 0
+end`)
+
+	// token 一致才续期
+	refreshScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+else
+	return 0
+end`)
+)
+
+// Option 锁的可选配置
+type Option func(*Lock)
+
+// WithTTL 设置锁的过期时间
+func WithTTL(ttl time.Duration) Option {
+	return func(l *Lock) {
+		l.ttl = ttl
+	}
+}
+
+// WithRetryInterval 设置 Acquire 的重试间隔
+func WithRetryInterval(interval time.Duration) Option {
+	return func(l *Lock) {
+		l.retryInterval = interval
+	}
+}
+
+// WithWatchdog 开启自动续期，每 ttl/3 续期一次，直到 Release
+// 适合执行时间无法预估的任务，例如计划任务、数据修复
+func WithWatchdog() Option {
+	return func(l *Lock) {
+		l.watchdog = true
+	}
+}
+
+// Lock 分布式锁
+type Lock struct {
+	client        *redis.Client
+	key           string
+	token         string
+	ttl           time.Duration
+	retryInterval time.Duration
+	watchdog      bool
+
+	mu     sync.Mutex
+	held   bool
+	stopCh chan struct{}
+}
+
+// New 实例化一个锁, 每个 Lock 实例有唯一的 token
+func New(client *redis.Client, key string, opts ...Option) *Lock {
+	l := &Lock{
+		client:        client,
+		key:           key,
+		token:         genToken(),
+		ttl:           DefaultTTL,
+		retryInterval: DefaultRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// TryLock 尝试加锁一次，不会等待
+func (l *Lock) TryLock() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ok, err := l.client.SetNX(l.GetKey(), l.token, l.ttl).Result()
+	if err == redis.Nil {
+		err = nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "lock: setnx err, key: %s", l.key)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	l.held = true
+	if l.watchdog {
+		l.stopCh = make(chan struct{})
+		go l.watch(l.stopCh)
+	}
+	return true, nil
+}
+
+// Acquire 加锁，获取不到时按间隔重试，直到 ctx 超时或取消
+func (l *Lock) Acquire(ctx context.Context) error {
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := l.TryLock()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrNotAcquired
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh 续期，锁已经不属于自己时返回 ErrLockNotHeld
+func (l *Lock) Refresh() error {
+	ret, err := refreshScript.Run(l.client, []string{l.GetKey()}, l.token, int64(l.ttl/time.Millisecond)).Int64()
+	if err != nil {
+		return errors.Wrapf(err, "lock: refresh err, key: %s", l.key)
+	}
+	if ret == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 解锁，锁已经不属于自己时返回 ErrLockNotHeld
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopCh != nil {
+		close(l.stopCh)
+		l.stopCh = nil
+	}
+	l.held = false
+
+	ret, err := releaseScript.Run(l.client, []string{l.GetKey()}, l.token).Int64()
+	if err != nil {
+		return errors.Wrapf(err, "lock: release err, key: %s", l.key)
+	}
+	if ret == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// ReleaseAfter 停止续期，锁在 d 之后过期，d 小于等于0时立即解锁
+// 用于在一段时间内阻止其他实例重复执行，如多个实例的计划任务触发时间有偏差
+func (l *Lock) ReleaseAfter(d time.Duration) error {
+	if d <= 0 {
+		return l.Release()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopCh != nil {
+		close(l.stopCh)
+		l.stopCh = nil
+	}
+	l.held = false
+
+	ret, err := refreshScript.Run(l.client, []string{l.GetKey()}, l.token, int64(d/time.Millisecond)).Int64()
+	if err != nil {
+		return errors.Wrapf(err, "lock: release err, key: %s", l.key)
+	}
+	if ret == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Held 当前实例是否认为自己持有锁
+// watchdog 续期失败时会置为 false，长任务可以据此提前退出
+func (l *Lock) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held
+}
+
+// GetKey 获取key, 由业务前缀+功能前缀+具体的key组成
+func (l *Lock) GetKey() string {
+	keyPrefix := viper.GetString("name")
+	return strings.Join([]string{keyPrefix, lockKeyPrefix, l.key}, ":")
+}
+
+// watch 自动续期
+func (l *Lock) watch(stopCh chan struct{}) {
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := l.Refresh(); err != nil {
+				l.mu.Lock()
+				l.held = false
+				l.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// genToken 生成唯一token
+func genToken() string {
+	u, _ := uuid.NewRandom()
+	return u.String()
+}
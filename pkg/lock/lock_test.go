@@ -0,0 +1,117 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestLock_TryLock(t *testing.T) {
+	redis.InitTestRedis()
+
+	l1 := New(redis.RedisClient, "test-try-lock")
+	l2 := New(redis.RedisClient, "test-try-lock")
+
+	ok, err := l1.TryLock()
+	if err != nil || !ok {
+		t.Fatalf("l1.TryLock() = %v, %v, want true, nil", ok, err)
+	}
+
+	ok, err = l2.TryLock()
+	if err != nil || ok {
+		t.Fatalf("l2.TryLock() = %v, %v, want false, nil", ok, err)
+	}
+
+	// 不能释放别人的锁
+	if err = l2.Release(); err != ErrLockNotHeld {
+		t.Fatalf("l2.Release() = %v, want %v", err, ErrLockNotHeld)
+	}
+
+	if err = l1.Release(); err != nil {
+		t.Fatalf("l1.Release() = %v", err)
+	}
+
+	ok, err = l2.TryLock()
+	if err != nil || !ok {
+		t.Fatalf("l2.TryLock() after release = %v, %v, want true, nil", ok, err)
+	}
+	_ = l2.Release()
+}
+
+func TestLock_Acquire(t *testing.T) {
+	redis.InitTestRedis()
+
+	l1 := New(redis.RedisClient, "test-acquire")
+	l2 := New(redis.RedisClient, "test-acquire", WithRetryInterval(10*time.Millisecond))
+
+	if err := l1.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l2.Acquire(ctx); err != ErrNotAcquired {
+		t.Fatalf("l2.Acquire() = %v, want %v", err, ErrNotAcquired)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = l1.Release()
+	}()
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if err := l2.Acquire(ctx2); err != nil {
+		t.Fatalf("l2.Acquire() after release = %v", err)
+	}
+	_ = l2.Release()
+}
+
+func TestLock_Refresh(t *testing.T) {
+	redis.InitTestRedis()
+
+	l := New(redis.RedisClient, "test-refresh", WithTTL(time.Minute))
+	if err := l.Refresh(); err != ErrLockNotHeld {
+		t.Fatalf("Refresh() before lock = %v, want %v", err, ErrLockNotHeld)
+	}
+
+	if ok, _ := l.TryLock(); !ok {
+		t.Fatal("TryLock() should success")
+	}
+	if err := l.Refresh(); err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	if ttl := redis.RedisClient.PTTL(l.GetKey()).Val(); ttl <= 0 {
+		t.Fatalf("PTTL() = %v, want > 0", ttl)
+	}
+	_ = l.Release()
+}
+
+func TestLock_ReleaseAfter(t *testing.T) {
+	redis.InitTestRedis()
+
+	l1 := New(redis.RedisClient, "test-release-after", WithTTL(time.Second), WithWatchdog())
+	l2 := New(redis.RedisClient, "test-release-after")
+	if ok, _ := l1.TryLock(); !ok {
+		t.Fatal("TryLock() should success")
+	}
+	if err := l1.ReleaseAfter(time.Minute); err != nil {
+		t.Fatalf("ReleaseAfter() = %v", err)
+	}
+	if l1.Held() {
+		t.Fatal("Held() after ReleaseAfter() = true, want false")
+	}
+	if ttl := redis.RedisClient.PTTL(l1.GetKey()).Val(); ttl <= time.Second {
+		t.Fatalf("PTTL() = %v, want about 1m", ttl)
+	}
+
+	// 过期前其他实例获取不到锁
+	if ok, _ := l2.TryLock(); ok {
+		t.Fatal("l2.TryLock() before expire should fail")
+	}
+	if err := l2.ReleaseAfter(time.Minute); err != ErrLockNotHeld {
+		t.Fatalf("l2.ReleaseAfter() = %v, want %v", err, ErrLockNotHeld)
+	}
+}
@@ -0,0 +1,36 @@
+package log
+
+import "context"
+
+// FieldRequestID 日志中请求id的字段名
+const FieldRequestID = "request_id"
+
+type requestIDKey struct{}
+
+// NewRequestIDContext 把请求id保存到 context 中，由 HTTP 中间件和 gRPC 拦截器设置
+func NewRequestIDContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 获取 context 中的请求id，没有时返回空
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithContext 返回带有请求id的 logger，同一个请求的日志可以通过 request_id 关联起来
+// ctx 中没有请求id时(如计划任务)返回全局的 logger
+// eg: log.WithContext(ctx).Warnf("get user err: %v", err)
+func WithContext(ctx context.Context) Logger {
+	return withRequestID(log, ctx)
+}
+
+func withRequestID(l Logger, ctx context.Context) Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return l.WithFields(Fields{FieldRequestID: requestID})
+	}
+	return l
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	old := log
+	log = &zapLogger{sugaredLogger: zap.New(core).Sugar()}
+	t.Cleanup(func() { log = old })
+
+	WithContext(context.Background()).Info("no request")
+	WithContext(NewRequestIDContext(context.Background(), "req-1")).Infof("uid: %d", 1)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if _, ok := entries[0].ContextMap()[FieldRequestID]; ok {
+		t.Fatalf("entry without request id has field %s", FieldRequestID)
+	}
+	if got := entries[1].ContextMap()[FieldRequestID]; got != "req-1" {
+		t.Fatalf("request_id = %v, want req-1", got)
+	}
+}
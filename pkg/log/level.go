@@ -0,0 +1,147 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// level 日志级别，所有输出共用，修改后立即生效
+var level = zap.NewAtomicLevel()
+
+var (
+	// moduleLevels 按模块单独设置的日志级别，保存不可变的 map，修改时整体替换
+	moduleLevels atomic.Value
+	moduleMu     sync.Mutex
+)
+
+func init() {
+	moduleLevels.Store(map[string]zapcore.Level{})
+}
+
+// parseLevel 解析日志级别，不区分大小写
+func parseLevel(l string) (zapcore.Level, error) {
+	var lvl zapcore.Level
+	err := lvl.UnmarshalText([]byte(strings.ToLower(l)))
+	return lvl, err
+}
+
+// SetLevel 修改日志级别 debug、info、warn、error、fatal，不区分大小写，为空时输出所有级别，用于配置热加载
+func SetLevel(l string) error {
+	if l == "" {
+		level.SetLevel(zapcore.DebugLevel)
+		return nil
+	}
+	lvl, err := parseLevel(l)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(lvl)
+	return nil
+}
+
+// GetLevel 当前的日志级别
+func GetLevel() string {
+	return level.String()
+}
+
+// SetModuleLevel 单独修改某个模块(named logger)的日志级别，l 为空时取消单独设置，使用全局级别
+// eg: 排查问题时只打开 user_service 的 debug 日志
+func SetModuleLevel(module, l string) error {
+	levels := make(map[string]zapcore.Level)
+	if l != "" {
+		lvl, err := parseLevel(l)
+		if err != nil {
+			return err
+		}
+		levels[module] = lvl
+	}
+
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	for name, lvl := range moduleLevels.Load().(map[string]zapcore.Level) {
+		if name != module {
+			levels[name] = lvl
+		}
+	}
+	moduleLevels.Store(levels)
+	return nil
+}
+
+// SetModuleLevels 替换所有模块的日志级别，用于配置热加载，运行时单独修改的级别会被覆盖
+func SetModuleLevels(modules map[string]string) error {
+	levels := make(map[string]zapcore.Level, len(modules))
+	for name, l := range modules {
+		lvl, err := parseLevel(l)
+		if err != nil {
+			return err
+		}
+		levels[name] = lvl
+	}
+
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	moduleLevels.Store(levels)
+	return nil
+}
+
+// GetModuleLevels 单独设置了日志级别的模块
+func GetModuleLevels() map[string]string {
+	levels := moduleLevels.Load().(map[string]zapcore.Level)
+	modules := make(map[string]string, len(levels))
+	for name, lvl := range levels {
+		modules[name] = lvl.String()
+	}
+	return modules
+}
+
+// levelOf 获取模块的日志级别，user_service.follow 没有单独设置时依次使用 user_service 和全局的级别
+func levelOf(name string) zapcore.LevelEnabler {
+	levels := moduleLevels.Load().(map[string]zapcore.Level)
+	for name != "" {
+		if lvl, ok := levels[name]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return level
+}
+
+// levelCore 按日志所属的模块过滤级别，输出到哪个文件由内部的 core 决定
+type levelCore struct {
+	zapcore.Core
+}
+
+// Enabled 只要全局或某个模块开启了该级别就返回 true，具体由 Check 按模块判断
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	if !c.Core.Enabled(lvl) {
+		return false
+	}
+	if level.Enabled(lvl) {
+		return true
+	}
+	for _, l := range moduleLevels.Load().(map[string]zapcore.Level) {
+		if l.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{c.Core.With(fields)}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !levelOf(ent.LoggerName).Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
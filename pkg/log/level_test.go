@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestModuleLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	old, oldLevel := log, GetLevel()
+	log = &zapLogger{sugaredLogger: zap.New(&levelCore{core}).Sugar()}
+	t.Cleanup(func() {
+		log = old
+		_ = SetLevel(oldLevel)
+		_ = SetModuleLevels(nil)
+	})
+
+	if err := SetLevel("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("user_service", "DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("access", "bad"); err == nil {
+		t.Fatal("want error for invalid level")
+	}
+
+	Info("global info")
+	Named("user_service").Debugw("user debug", "user_id", 1)
+	Named("user_service").Named("follow").Debug("follow debug")
+	Named("access").Info("access info")
+	Named("access").Warn("access warn")
+
+	entries := logs.AllUntimed()
+	want := []string{"user debug", "follow debug", "access warn"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Message != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, e.Message, want[i])
+		}
+	}
+	if got := entries[0].ContextMap()["user_id"]; got != int64(1) {
+		t.Errorf("user_id = %v, want 1", got)
+	}
+	if entries[1].LoggerName != "user_service.follow" {
+		t.Errorf("logger name = %q, want user_service.follow", entries[1].LoggerName)
+	}
+
+	if err := SetModuleLevel("user_service", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetModuleLevels(); len(got) != 0 {
+		t.Fatalf("module levels = %v, want empty", got)
+	}
+}
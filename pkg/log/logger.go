@@ -31,6 +31,13 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 	Fatalf(format string, args ...interface{})
 	Panicf(format string, args ...interface{})
+	// Debugw 等方法以 key/value 的形式输出字段，eg: log.Infow("user login", "user_id", 1, "ip", ip)
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+	// Named 返回模块的 logger，日志中带上 module 字段，子模块用 . 连接
+	Named(name string) Logger
 	WithFields(keyValues Fields) Logger
 }
 
@@ -46,6 +53,10 @@ type Config struct {
 	LogRotateDate    int    `yaml:"log_rotate_date"`
 	LogRotateSize    int    `yaml:"log_rotate_size"`
 	LogBackupCount   int    `yaml:"log_backup_count"`
+	// ModuleLevels 按模块单独设置的日志级别，没有设置的模块使用 LoggerLevel
+	ModuleLevels map[string]string `yaml:"module_levels"`
+	// Sinks 输出配置，不为空时替代 Writers 及相关的文件配置
+	Sinks []SinkConfig `yaml:"sinks"`
 }
 
 // NewLogger returns an instance of logger
@@ -118,6 +129,26 @@ func Panicf(format string, args ...interface{}) {
 	log.Panicf(format, args...)
 }
 
+// Debugw logger
+func Debugw(msg string, keysAndValues ...interface{}) {
+	log.Debugw(msg, keysAndValues...)
+}
+
+// Infow logger
+func Infow(msg string, keysAndValues ...interface{}) {
+	log.Infow(msg, keysAndValues...)
+}
+
+// Warnw logger
+func Warnw(msg string, keysAndValues ...interface{}) {
+	log.Warnw(msg, keysAndValues...)
+}
+
+// Errorw logger
+func Errorw(msg string, keysAndValues ...interface{}) {
+	log.Errorw(msg, keysAndValues...)
+}
+
 // WithFields logger
 // output more field, eg:
 // 		contextLogger := log.WithFields(log.Fields{"key1": "value1"})
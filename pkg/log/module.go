@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ModuleLogger 模块的 logger，可以在包初始化时声明，日志初始化或重新初始化后仍然有效
+// eg:
+//
+//	var logger = log.Named("user_service")
+//	logger.WithContext(ctx).Warnf("get user err: %v", err)
+//
+// 模块的日志级别可以通过 log.module_levels 配置或管理后台单独修改
+type ModuleLogger struct {
+	name  string
+	cache atomic.Value
+}
+
+type namedLogger struct {
+	base   Logger
+	logger Logger
+}
+
+// Named 获取模块的 logger
+func Named(name string) *ModuleLogger {
+	return &ModuleLogger{name: name}
+}
+
+// logger 全局的 logger 变化后重新生成
+func (m *ModuleLogger) logger() Logger {
+	base := log
+	if c, ok := m.cache.Load().(namedLogger); ok && c.base == base {
+		return c.logger
+	}
+	l := base.Named(m.name)
+	m.cache.Store(namedLogger{base: base, logger: l})
+	return l
+}
+
+// WithContext 返回带有请求id的模块 logger
+func (m *ModuleLogger) WithContext(ctx context.Context) Logger {
+	return withRequestID(m.logger(), ctx)
+}
+
+func (m *ModuleLogger) Debug(args ...interface{}) {
+	m.logger().Debug(args...)
+}
+
+func (m *ModuleLogger) Info(args ...interface{}) {
+	m.logger().Info(args...)
+}
+
+func (m *ModuleLogger) Warn(args ...interface{}) {
+	m.logger().Warn(args...)
+}
+
+func (m *ModuleLogger) Error(args ...interface{}) {
+	m.logger().Error(args...)
+}
+
+func (m *ModuleLogger) Fatal(args ...interface{}) {
+	m.logger().Fatal(args...)
+}
+
+func (m *ModuleLogger) Debugf(format string, args ...interface{}) {
+	m.logger().Debugf(format, args...)
+}
+
+func (m *ModuleLogger) Infof(format string, args ...interface{}) {
+	m.logger().Infof(format, args...)
+}
+
+func (m *ModuleLogger) Warnf(format string, args ...interface{}) {
+	m.logger().Warnf(format, args...)
+}
+
+func (m *ModuleLogger) Errorf(format string, args ...interface{}) {
+	m.logger().Errorf(format, args...)
+}
+
+func (m *ModuleLogger) Fatalf(format string, args ...interface{}) {
+	m.logger().Fatalf(format, args...)
+}
+
+func (m *ModuleLogger) Panicf(format string, args ...interface{}) {
+	m.logger().Panicf(format, args...)
+}
+
+func (m *ModuleLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	m.logger().Debugw(msg, keysAndValues...)
+}
+
+func (m *ModuleLogger) Infow(msg string, keysAndValues ...interface{}) {
+	m.logger().Infow(msg, keysAndValues...)
+}
+
+func (m *ModuleLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	m.logger().Warnw(msg, keysAndValues...)
+}
+
+func (m *ModuleLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	m.logger().Errorw(msg, keysAndValues...)
+}
+
+// Named 子模块的 logger，名称为 父模块.子模块
+func (m *ModuleLogger) Named(name string) Logger {
+	return Named(m.name + "." + name)
+}
+
+func (m *ModuleLogger) WithFields(keyValues Fields) Logger {
+	return m.logger().WithFields(keyValues)
+}
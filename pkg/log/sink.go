@@ -0,0 +1,232 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// SinkConsole 标准输出
+	SinkConsole = "console"
+	// SinkFile 文件，按大小和时间切割
+	SinkFile = "file"
+	// SinkSyslog 系统日志，windows 下不支持
+	SinkSyslog = "syslog"
+
+	// FormatJSON json 格式
+	FormatJSON = "json"
+	// FormatText plaintext 格式
+	FormatText = "text"
+)
+
+// SinkConfig 日志输出配置，配置了 log.sinks 时替代 writers，可以同时输出到多个地方
+type SinkConfig struct {
+	// Type console、file、syslog，或者通过 RegisterSink 注册的类型
+	Type string `yaml:"type" mapstructure:"type"`
+	// Level 输出的最低级别，为空时只受全局和模块的级别控制
+	Level string `yaml:"level" mapstructure:"level"`
+	// MaxLevel 输出的最高级别，用于按级别拆分文件，eg: info 文件中不包括 warn 以上的日志
+	MaxLevel string `yaml:"max_level" mapstructure:"max_level"`
+	// Format json、text，为空时按 log_format_text
+	Format string `yaml:"format" mapstructure:"format"`
+
+	// Filename 文件路径
+	Filename string `yaml:"filename" mapstructure:"filename"`
+	// MaxSize 单个文件的最大大小(MB)，超过后切割，为0时为100MB
+	MaxSize int `yaml:"max_size" mapstructure:"max_size"`
+	// MaxBackups 保留的旧文件个数，为0时全部保留
+	MaxBackups int `yaml:"max_backups" mapstructure:"max_backups"`
+	// MaxAge 旧文件保留的天数，为0时不按时间删除
+	MaxAge int `yaml:"max_age" mapstructure:"max_age"`
+	// Compress 旧文件是否使用 gzip 压缩
+	Compress bool `yaml:"compress" mapstructure:"compress"`
+	// Rotate 按时间切割 daily、hourly，为空时只按大小切割
+	Rotate string `yaml:"rotate" mapstructure:"rotate"`
+
+	// Network syslog 的连接方式 udp、tcp，为空时连接本机的 syslog
+	Network string `yaml:"network" mapstructure:"network"`
+	// Addr syslog 的地址
+	Addr string `yaml:"addr" mapstructure:"addr"`
+	// Tag syslog 的标签，为空时使用应用名称
+	Tag string `yaml:"tag" mapstructure:"tag"`
+}
+
+// SinkFactory 创建日志输出，返回的 io.Closer 在重新初始化日志时关闭，不需要时返回 nil
+type SinkFactory func(cfg SinkConfig, enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error)
+
+var (
+	sinkMu        sync.Mutex
+	sinkFactories = map[string]SinkFactory{
+		SinkConsole: newConsoleSink,
+		SinkFile:    newFileSink,
+	}
+	// sinkClosers 当前的输出打开的文件、连接
+	sinkClosers []io.Closer
+)
+
+// RegisterSink 注册自定义的输出类型，如 kafka，需要在初始化日志之前注册
+func RegisterSink(typ string, factory SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinkFactories[typ] = factory
+}
+
+// newSinkCores 按配置创建所有的输出，某个输出创建失败时关闭已创建的输出
+func newSinkCores(sinks []SinkConfig, formatText bool) ([]zapcore.Core, []io.Closer, error) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+
+	cores := make([]zapcore.Core, 0, len(sinks))
+	closers := make([]io.Closer, 0, len(sinks))
+	for i, s := range sinks {
+		core, closer, err := newSinkCore(s, formatText)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("log sink %d (%s): %v", i, s.Type, err)
+		}
+		cores = append(cores, core)
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+	return cores, closers, nil
+}
+
+func newSinkCore(s SinkConfig, formatText bool) (zapcore.Core, io.Closer, error) {
+	factory, ok := sinkFactories[s.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown sink type")
+	}
+
+	var enc zapcore.Encoder
+	switch s.Format {
+	case FormatJSON:
+		enc = getJSONEncoder()
+	case FormatText:
+		enc = getConsoleEncoder()
+	case "":
+		enc = getJSONEncoder()
+		if formatText {
+			enc = getConsoleEncoder()
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown format: %s", s.Format)
+	}
+
+	enab, err := sinkLevel(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return factory(s, enc, enab)
+}
+
+// sinkLevel 输出的级别范围，全局和模块的级别由外层的 levelCore 过滤
+func sinkLevel(s SinkConfig) (zapcore.LevelEnabler, error) {
+	min, max := zapcore.DebugLevel, zapcore.FatalLevel
+	var err error
+	if s.Level != "" {
+		if min, err = parseLevel(s.Level); err != nil {
+			return nil, err
+		}
+	}
+	if s.MaxLevel != "" {
+		if max, err = parseLevel(s.MaxLevel); err != nil {
+			return nil, err
+		}
+	}
+	return zapcore.LevelEnabler(levelRange{min: min, max: max}), nil
+}
+
+type levelRange struct {
+	min, max zapcore.Level
+}
+
+func (r levelRange) Enabled(lvl zapcore.Level) bool {
+	return lvl >= r.min && lvl <= r.max
+}
+
+// replaceClosers 日志重新初始化后关闭之前的输出
+func replaceClosers(closers []io.Closer) {
+	sinkMu.Lock()
+	old := sinkClosers
+	sinkClosers = closers
+	sinkMu.Unlock()
+	closeAll(old)
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
+func newConsoleSink(cfg SinkConfig, enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	return zapcore.NewCore(enc, zapcore.Lock(os.Stdout), enab), nil, nil
+}
+
+func newFileSink(cfg SinkConfig, enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	if cfg.Filename == "" {
+		return nil, nil, fmt.Errorf("filename is empty")
+	}
+	if cfg.Rotate != "" && cfg.Rotate != RotateTimeDaily && cfg.Rotate != RotateTimeHourly {
+		return nil, nil, fmt.Errorf("unknown rotate: %s", cfg.Rotate)
+	}
+
+	f := &rotateFile{
+		Logger: &lumberjack.Logger{
+			Filename:   cfg.Filename,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		},
+		stop: make(chan struct{}),
+	}
+	if cfg.Rotate != "" {
+		go f.rotateAt(cfg.Rotate)
+	}
+	return zapcore.NewCore(enc, zapcore.AddSync(f), enab), f, nil
+}
+
+// rotateFile lumberjack 只按大小切割，按时间切割时由定时器在每天或每小时开始时切割
+type rotateFile struct {
+	*lumberjack.Logger
+	stop chan struct{}
+	once sync.Once
+}
+
+func (f *rotateFile) rotateAt(rotate string) {
+	for {
+		timer := time.NewTimer(time.Until(nextRotateTime(time.Now(), rotate)))
+		select {
+		case <-timer.C:
+			if err := f.Rotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "rotate log file %s err: %v\n", f.Filename, err)
+			}
+		case <-f.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Close 停止定时切割并关闭文件
+func (f *rotateFile) Close() error {
+	f.once.Do(func() { close(f.stop) })
+	return f.Logger.Close()
+}
+
+// nextRotateTime 下一次切割的时间，按本地时间的整点或零点切割
+func nextRotateTime(now time.Time, rotate string) time.Time {
+	if rotate == RotateTimeHourly {
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
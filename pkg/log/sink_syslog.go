@@ -0,0 +1,76 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package log
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	RegisterSink(SinkSyslog, newSyslogSink)
+}
+
+func newSyslogSink(cfg SinkConfig, enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = viper.GetString("name")
+	}
+	w, err := syslog.Dial(cfg.Network, cfg.Addr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &syslogCore{LevelEnabler: enab, enc: enc, w: w}, w, nil
+}
+
+// syslogCore 按日志级别写入对应的 syslog 严重级别
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslog.Writer
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &syslogCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), w: c.w}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	msg := buf.String()
+	switch {
+	case ent.Level <= zapcore.DebugLevel:
+		return c.w.Debug(msg)
+	case ent.Level == zapcore.InfoLevel:
+		return c.w.Info(msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.w.Warning(msg)
+	case ent.Level == zapcore.ErrorLevel:
+		return c.w.Err(msg)
+	default:
+		return c.w.Crit(msg)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNextRotateTime(t *testing.T) {
+	now := time.Date(2020, 12, 31, 23, 40, 5, 0, time.Local)
+	if got, want := nextRotateTime(now, RotateTimeDaily), time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("daily = %v, want %v", got, want)
+	}
+	if got, want := nextRotateTime(now, RotateTimeHourly), time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("hourly = %v, want %v", got, want)
+	}
+	now = time.Date(2020, 6, 1, 8, 0, 0, 0, time.Local)
+	if got, want := nextRotateTime(now, RotateTimeHourly), time.Date(2020, 6, 1, 9, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("hourly = %v, want %v", got, want)
+	}
+}
+
+func TestSinkLevel(t *testing.T) {
+	dir := t.TempDir()
+	info, errFile := filepath.Join(dir, "info.log"), filepath.Join(dir, "err.log")
+	cores, closers, err := newSinkCores([]SinkConfig{
+		{Type: SinkFile, Filename: info, MaxLevel: "warn"},
+		{Type: SinkFile, Filename: errFile, Level: "error", Format: FormatText},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(closers)
+
+	for _, c := range cores {
+		l := zap.New(c).Sugar()
+		l.Info("info message")
+		l.Error("error message")
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if s := read(info); !strings.Contains(s, "info message") || strings.Contains(s, "error message") {
+		t.Errorf("info.log = %q", s)
+	}
+	if s := read(errFile); strings.Contains(s, "info message") || !strings.Contains(s, "error message") {
+		t.Errorf("err.log = %q", s)
+	}
+
+	if _, _, err := newSinkCores([]SinkConfig{{Type: "unknown"}}, false); err == nil {
+		t.Error("want error for unknown sink type")
+	}
+}
@@ -36,13 +36,56 @@ type zapLogger struct {
 // newZapLogger new zap logger
 func newZapLogger(cfg *Config) (Logger, error) {
 	encoder := getJSONEncoder()
+	if cfg.LogFormatText {
+		encoder = getConsoleEncoder()
+	}
+	if err := SetLevel(cfg.LoggerLevel); err != nil {
+		return nil, err
+	}
+	if err := SetModuleLevels(cfg.ModuleLevels); err != nil {
+		return nil, err
+	}
 
-	var cores []zapcore.Core
 	var options []zap.Option
 	// 设置初始化字段
 	option := zap.Fields(zap.String("ip", util.GetLocalIP()), zap.String("app", viper.GetString("name")))
 	options = append(options, option)
 
+	// 配置了 sinks 时按 sinks 输出，否则按 writers 输出
+	var cores []zapcore.Core
+	var closers []io.Closer
+	if len(cfg.Sinks) > 0 {
+		var err error
+		if cores, closers, err = newSinkCores(cfg.Sinks, cfg.LogFormatText); err != nil {
+			return nil, err
+		}
+	} else {
+		cores = newWriterCores(cfg, encoder)
+	}
+	replaceClosers(closers)
+
+	combinedCore := &levelCore{zapcore.NewTee(cores...)}
+
+	// 开启开发模式，堆栈跟踪
+	caller := zap.AddCaller()
+	options = append(options, caller)
+	// 开启文件及行号
+	development := zap.Development()
+	options = append(options, development)
+	// 跳过文件调用层数
+	addCallerSkip := zap.AddCallerSkip(2)
+	options = append(options, addCallerSkip)
+
+	// 构造日志
+	logger := zap.New(combinedCore, options...).Sugar()
+
+	return &zapLogger{sugaredLogger: logger}, nil
+}
+
+// newWriterCores 按 writers 输出到标准输出和按级别拆分的文件，文件按时间切割
+func newWriterCores(cfg *Config, encoder zapcore.Encoder) []zapcore.Core {
+	var cores []zapcore.Core
+	// 级别由外层的 levelCore 按模块过滤，这里的 core 只决定输出到哪个文件
 	allLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 		return lvl <= zapcore.FatalLevel
 	})
@@ -65,13 +108,9 @@ func newZapLogger(cfg *Config) (Logger, error) {
 				return lvl <= zapcore.InfoLevel
 			})
 			warnLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-				stacktrace := zap.AddStacktrace(zapcore.WarnLevel)
-				options = append(options, stacktrace)
 				return lvl == zapcore.WarnLevel
 			})
 			errorLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-				stacktrace := zap.AddStacktrace(zapcore.ErrorLevel)
-				options = append(options, stacktrace)
 				return lvl >= zapcore.ErrorLevel
 			})
 
@@ -90,23 +129,7 @@ func newZapLogger(cfg *Config) (Logger, error) {
 			cores = append(cores, core)
 		}
 	}
-
-	combinedCore := zapcore.NewTee(cores...)
-
-	// 开启开发模式，堆栈跟踪
-	caller := zap.AddCaller()
-	options = append(options, caller)
-	// 开启文件及行号
-	development := zap.Development()
-	options = append(options, development)
-	// 跳过文件调用层数
-	addCallerSkip := zap.AddCallerSkip(2)
-	options = append(options, addCallerSkip)
-
-	// 构造日志
-	logger := zap.New(combinedCore, options...).Sugar()
-
-	return &zapLogger{sugaredLogger: logger}, nil
+	return cores
 }
 
 // getJSONEncoder
@@ -116,7 +139,7 @@ func getJSONEncoder() zapcore.Encoder {
 		LevelKey:       "level",
 		TimeKey:        "time",
 		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		NameKey:        "app",
+		NameKey:        "module",
 		CallerKey:      "file",
 		StacktraceKey:  "trace",
 		EncodeCaller:   zapcore.ShortCallerEncoder,
@@ -126,6 +149,23 @@ func getJSONEncoder() zapcore.Encoder {
 	return zapcore.NewJSONEncoder(encoderConfig)
 }
 
+// getConsoleEncoder 输出成 plaintext 格式，便于本地开发时查看
+func getConsoleEncoder() zapcore.Encoder {
+	encoderConfig := zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		TimeKey:        "time",
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		NameKey:        "module",
+		CallerKey:      "file",
+		StacktraceKey:  "trace",
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+	}
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
 // getLogWriterWithTime 按时间(小时)进行切割
 func getLogWriterWithTime(filename string) io.Writer {
 	logFullPath := filename
@@ -197,6 +237,26 @@ func (l *zapLogger) Panicf(format string, args ...interface{}) {
 	l.sugaredLogger.Panicf(format, args...)
 }
 
+func (l *zapLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.sugaredLogger.Debugw(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.sugaredLogger.Infow(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.sugaredLogger.Warnw(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.sugaredLogger.Errorw(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Named(name string) Logger {
+	return &zapLogger{l.sugaredLogger.Named(name)}
+}
+
 func (l *zapLogger) WithFields(fields Fields) Logger {
 	var f = make([]interface{}, 0)
 	for k, v := range fields {
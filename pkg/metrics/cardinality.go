@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Other 超出基数限制的标签值统一归到该值
+const Other = "other"
+
+var labelOverflow = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "snake_metrics_label_overflow_total",
+	Help: "Total number of label values bucketed into other by the cardinality limiter.",
+}, []string{"label"})
+
+// Limiter 限制一个标签的取值个数，避免 prometheus 的时间序列随请求无限增长
+// 白名单中的值始终保留，其他值按出现顺序保留前 max 个，之后出现的新值归到 Other
+type Limiter struct {
+	label string
+	max   int
+
+	mu     sync.RWMutex
+	allow  map[string]struct{}
+	seen   map[string]struct{}
+	warned bool
+}
+
+// NewLimiter 实例化，max 为0时只保留白名单中的值
+func NewLimiter(label string, max int, allow ...string) *Limiter {
+	l := &Limiter{
+		label: label,
+		max:   max,
+		allow: make(map[string]struct{}, len(allow)),
+		seen:  make(map[string]struct{}),
+	}
+	for _, v := range allow {
+		l.allow[v] = struct{}{}
+	}
+	return l
+}
+
+// Value 返回用于标签的值
+func (l *Limiter) Value(v string) string {
+	if _, ok := l.allow[v]; ok {
+		return v
+	}
+	l.mu.RLock()
+	_, ok := l.seen[v]
+	l.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[v]; ok {
+		return v
+	}
+	if len(l.seen) < l.max {
+		l.seen[v] = struct{}{}
+		return v
+	}
+	if !l.warned {
+		l.warned = true
+		log.Warnf("[metrics] label %s exceeds %d values, new values are bucketed into %s", l.label, l.max, Other)
+	}
+	labelOverflow.WithLabelValues(l.label).Inc()
+	return Other
+}
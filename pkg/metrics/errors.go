@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// errorResolution 错误率的统计粒度
+const errorResolution = time.Minute
+
+// maxErrorWindow 错误率最多统计的时间窗口
+const maxErrorWindow = time.Hour
+
+// errorBucket 一分钟内的请求数和失败数
+type errorBucket struct {
+	minute int64
+	total  int64
+	failed int64
+}
+
+// errorWindow 进程内按分钟统计的请求数和失败数，用于运营面板展示最近的错误率
+// 只统计当前实例，多实例时需要在 prometheus 中按 snake_http_requests_total 聚合
+type errorWindow struct {
+	mu      sync.Mutex
+	buckets []errorBucket
+}
+
+func newErrorWindow() *errorWindow {
+	return &errorWindow{buckets: make([]errorBucket, maxErrorWindow/errorResolution)}
+}
+
+func (w *errorWindow) add(now time.Time, failed bool) {
+	minute := now.Unix() / int64(errorResolution/time.Second)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buckets[minute%int64(len(w.buckets))]
+	if b.minute != minute {
+		*b = errorBucket{minute: minute}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+}
+
+func (w *errorWindow) sum(now time.Time, window time.Duration) (total, failed int64) {
+	if window > maxErrorWindow {
+		window = maxErrorWindow
+	}
+	minute := now.Unix() / int64(errorResolution/time.Second)
+	since := minute - int64(window/errorResolution)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range w.buckets {
+		if b.minute > since && b.minute <= minute {
+			total += b.total
+			failed += b.failed
+		}
+	}
+	return total, failed
+}
+
+// RecordResult 记录一次请求是否失败，失败的判断和 SLO 一致
+func (r *Recorder) RecordResult(failed bool) {
+	r.errors.add(time.Now(), failed)
+}
+
+// ErrorRate 最近 window 内当前实例的请求数和错误率，最多统计一小时，没有请求时错误率为0
+func (r *Recorder) ErrorRate(window time.Duration) (int64, float64) {
+	total, failed := r.errors.sum(time.Now(), window)
+	if total == 0 {
+		return 0, 0
+	}
+	return total, float64(failed) / float64(total)
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestErrorWindow(t *testing.T) {
+	w := newErrorWindow()
+	now := time.Date(2020, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	// 一小时前的请求已经被覆盖
+	w.add(now.Add(-time.Hour), true)
+	w.add(now.Add(-10*time.Minute), true)
+	w.add(now.Add(-2*time.Minute), true)
+	w.add(now.Add(-2*time.Minute), false)
+	w.add(now, false)
+
+	if total, failed := w.sum(now, 5*time.Minute); total != 3 || failed != 1 {
+		t.Fatalf("sum(5m) = %d, %d, want 3, 1", total, failed)
+	}
+	if total, failed := w.sum(now, 2*time.Hour); total != 4 || failed != 2 {
+		t.Fatalf("sum(2h) = %d, %d, want 4, 2", total, failed)
+	}
+}
@@ -0,0 +1,145 @@
+// Package metrics http 请求指标，按路由模板、租户、状态码分类统计
+// 标签值都经过基数限制，路由增多或请求带上异常的租户时不会产生大量时间序列
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultMaxRoutes 默认的路由标签取值个数
+	DefaultMaxRoutes = 500
+	// DefaultMaxTenants 默认的租户标签取值个数
+	DefaultMaxTenants = 50
+	// Unmatched 没有匹配到路由的请求，不使用原始路径，避免扫描请求产生大量标签值
+	Unmatched = "unmatched"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_http_requests_total",
+		Help: "Total number of http requests by route template, tenant and status class.",
+	}, []string{"method", "route", "tenant", "status"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "snake_http_request_duration_seconds",
+		Help:    "Http request latency by route template and tenant.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "tenant"})
+	requestQueries = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "snake_http_request_db_queries",
+		Help:    "Number of database queries per http request by route template.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200},
+	}, []string{"method", "route"})
+)
+
+// methods 标准的 http 方法，其他方法归到 Other
+var methods = map[string]struct{}{
+	http.MethodGet: {}, http.MethodHead: {}, http.MethodPost: {}, http.MethodPut: {},
+	http.MethodPatch: {}, http.MethodDelete: {}, http.MethodOptions: {},
+}
+
+// Config 请求指标配置
+type Config struct {
+	// TenantHeader 读取租户的请求头，为空时不按租户统计
+	TenantHeader string `mapstructure:"tenant_header"`
+	// Tenants 已知的租户，始终单独统计，不占用 MaxTenants
+	Tenants []string `mapstructure:"tenants"`
+	// MaxTenants 已知租户之外最多统计的租户个数
+	MaxTenants int `mapstructure:"max_tenants"`
+	// MaxRoutes 最多统计的路由个数
+	MaxRoutes int `mapstructure:"max_routes"`
+}
+
+// Recorder 记录请求指标
+type Recorder struct {
+	cfg     Config
+	routes  *Limiter
+	tenants *Limiter
+	errors  *errorWindow
+}
+
+var (
+	mu     sync.RWMutex
+	client = New(Config{})
+)
+
+// Init 按配置 metrics 初始化
+func Init() *Recorder {
+	var cfg Config
+	_ = viper.UnmarshalKey("metrics", &cfg)
+	r := New(cfg)
+	mu.Lock()
+	client = r
+	mu.Unlock()
+	return r
+}
+
+// Default 返回全局的 Recorder，未初始化时使用默认配置
+func Default() *Recorder {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// New 实例化，限制个数为0时使用默认值
+func New(cfg Config) *Recorder {
+	if cfg.MaxRoutes <= 0 {
+		cfg.MaxRoutes = DefaultMaxRoutes
+	}
+	if cfg.MaxTenants <= 0 {
+		cfg.MaxTenants = DefaultMaxTenants
+	}
+	return &Recorder{
+		cfg:     cfg,
+		routes:  NewLimiter("route", cfg.MaxRoutes, Unmatched),
+		tenants: NewLimiter("tenant", cfg.MaxTenants, append([]string{""}, cfg.Tenants...)...),
+		errors:  newErrorWindow(),
+	}
+}
+
+// Tenant 从请求头读取租户，未配置 tenant_header 时返回空
+func (r *Recorder) Tenant(req *http.Request) string {
+	if r.cfg.TenantHeader == "" {
+		return ""
+	}
+	return req.Header.Get(r.cfg.TenantHeader)
+}
+
+// Observe 记录一次请求，route 为注册的路由模板如 /v1/users/:id，为空时记为 Unmatched
+func (r *Recorder) Observe(method, route, tenant string, status int, cost time.Duration) {
+	method, route, tenant = r.Labels(method, route, tenant)
+	requestsTotal.WithLabelValues(method, route, tenant, StatusClass(status)).Inc()
+	requestDuration.WithLabelValues(method, route, tenant).Observe(cost.Seconds())
+}
+
+// ObserveQueries 记录一次请求执行的数据库语句条数，用于发现 N+1 查询
+func (r *Recorder) ObserveQueries(method, route string, n int) {
+	method, route, _ = r.Labels(method, route, "")
+	requestQueries.WithLabelValues(method, route).Observe(float64(n))
+}
+
+// Labels 返回经过基数限制的标签值
+func (r *Recorder) Labels(method, route, tenant string) (string, string, string) {
+	if _, ok := methods[method]; !ok {
+		method = Other
+	}
+	if route == "" {
+		route = Unmatched
+	}
+	return method, r.routes.Value(route), r.tenants.Value(tenant)
+}
+
+// StatusClass 状态码分类 2xx、4xx、5xx
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return Other
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
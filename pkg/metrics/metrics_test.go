@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func init() {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+}
+
+func TestLimiter_Value(t *testing.T) {
+	l := NewLimiter("tenant", 2, "acme")
+	cases := []struct {
+		in, want string
+	}{
+		{"a", "a"},
+		{"b", "b"},
+		{"c", Other},
+		{"a", "a"},
+		// 白名单不占用个数
+		{"acme", "acme"},
+		{"d", Other},
+	}
+	for _, c := range cases {
+		if got := l.Value(c.in); got != c.want {
+			t.Fatalf("Value(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRecorder_Labels(t *testing.T) {
+	r := New(Config{MaxRoutes: 3, Tenants: []string{"acme"}, MaxTenants: 1})
+	for i := 0; i < 10; i++ {
+		r.Labels("GET", fmt.Sprintf("/v1/r%d", i), "")
+	}
+	if _, route, _ := r.Labels("GET", "/v1/new", ""); route != Other {
+		t.Fatalf("route = %q, want %q after limit", route, Other)
+	}
+	// 没有匹配到路由的请求不占用个数，始终归到 unmatched
+	if _, route, _ := r.Labels("GET", "", ""); route != Unmatched {
+		t.Fatalf("route = %q, want %q", route, Unmatched)
+	}
+	if method, _, _ := r.Labels("PROPFIND", "", ""); method != Other {
+		t.Fatalf("method = %q, want %q", method, Other)
+	}
+	if _, _, tenant := r.Labels("GET", "", "acme"); tenant != "acme" {
+		t.Fatalf("tenant = %q, want acme", tenant)
+	}
+	r.Labels("GET", "", "t1")
+	if _, _, tenant := r.Labels("GET", "", "t2"); tenant != Other {
+		t.Fatalf("tenant = %q, want %q", tenant, Other)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{200: "2xx", 204: "2xx", 302: "3xx", 404: "4xx", 503: "5xx", 0: Other, 999: Other} {
+		if got := StatusClass(status); got != want {
+			t.Fatalf("StatusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package nonce
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// 过期数据的清理间隔
+const cleanupInterval = time.Minute
+
+// memoryItem 带有效期的值
+type memoryItem struct {
+	value   string
+	expires time.Time
+}
+
+// expired 是否已过期, 零值表示永不过期
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && now.After(i.expires)
+}
+
+// memoryStore 内存存储，仅适用于单机部署或本地开发
+type memoryStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	keyPrefix string
+	lastClean time.Time
+}
+
+// NewMemoryStore 实例化一个内存存储
+func NewMemoryStore(keyPrefix string) Store {
+	return &memoryStore{
+		items:     make(map[string]memoryItem),
+		keyPrefix: keyPrefix,
+		lastClean: time.Now(),
+	}
+}
+
+func (s *memoryStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.cleanup(now)
+
+	cacheKey := s.buildKey(key)
+	if item, ok := s.items[cacheKey]; ok && !item.expired(now) {
+		return false, nil
+	}
+	s.items[cacheKey] = newMemoryItem(value, expiration, now)
+	return true, nil
+}
+
+func (s *memoryStore) Set(key, value string, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.cleanup(now)
+	s.items[s.buildKey(key)] = newMemoryItem(value, expiration, now)
+	return nil
+}
+
+func (s *memoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[s.buildKey(key)]
+	if !ok || item.expired(time.Now()) {
+		return "", nil
+	}
+	return item.value, nil
+}
+
+func (s *memoryStore) Del(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, s.buildKey(key))
+	return nil
+}
+
+// cleanup 定期清理过期数据，避免内存无限增长, 调用方需持有锁
+func (s *memoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastClean) < cleanupInterval {
+		return
+	}
+	for k, item := range s.items {
+		if item.expired(now) {
+			delete(s.items, k)
+		}
+	}
+	s.lastClean = now
+}
+
+func (s *memoryStore) buildKey(key string) string {
+	return strings.Join([]string{s.keyPrefix, key}, ":")
+}
+
+func newMemoryItem(value string, expiration time.Duration, now time.Time) memoryItem {
+	item := memoryItem{value: value}
+	if expiration > 0 {
+		item.expires = now.Add(expiration)
+	}
+	return item
+}
@@ -0,0 +1,86 @@
+package nonce
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// nonceRecord nonce 表，过期的记录在写入同一个 key 时清理
+type nonceRecord struct {
+	Key       string    `gorm:"column:key;primary_key"`
+	Value     string    `gorm:"column:value"`
+	ExpiredAt time.Time `gorm:"column:expired_at"`
+}
+
+// TableName 表名
+func (r *nonceRecord) TableName() string {
+	return "nonce"
+}
+
+// mysqlStore mysql 存储，适用于需要持久保存的场景，如消息消费记录
+type mysqlStore struct {
+	db        *gorm.DB
+	keyPrefix string
+}
+
+// NewMySQLStore 实例化一个mysql存储
+func NewMySQLStore(db *gorm.DB, keyPrefix string) Store {
+	return &mysqlStore{
+		db:        db,
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (s *mysqlStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	key = s.buildKey(key)
+	now := time.Now()
+	err := s.db.Where("`key` = ? and expired_at <= ?", key, now).Delete(&nonceRecord{}).Error
+	if err != nil {
+		return false, errors.Wrapf(err, "[nonce] mysql delete expired err, key: %s", key)
+	}
+	ret := s.db.Exec("insert ignore into nonce set `key`=?, value=?, expired_at=?", key, value, now.Add(expiration))
+	if ret.Error != nil {
+		return false, errors.Wrapf(ret.Error, "[nonce] mysql setnx err, key: %s", key)
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (s *mysqlStore) Set(key, value string, expiration time.Duration) error {
+	key = s.buildKey(key)
+	expiredAt := time.Now().Add(expiration)
+	err := s.db.Exec("insert into nonce set `key`=?, value=?, expired_at=? on duplicate key update value=?, expired_at=?",
+		key, value, expiredAt, value, expiredAt).Error
+	if err != nil {
+		return errors.Wrapf(err, "[nonce] mysql set err, key: %s", key)
+	}
+	return nil
+}
+
+func (s *mysqlStore) Get(key string) (string, error) {
+	key = s.buildKey(key)
+	record := nonceRecord{}
+	err := s.db.Where("`key` = ? and expired_at > ?", key, time.Now()).First(&record).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "[nonce] mysql get err, key: %s", key)
+	}
+	return record.Value, nil
+}
+
+func (s *mysqlStore) Del(key string) error {
+	key = s.buildKey(key)
+	err := s.db.Where("`key` = ?", key).Delete(&nonceRecord{}).Error
+	if err != nil {
+		return errors.Wrapf(err, "[nonce] mysql del err, key: %s", key)
+	}
+	return nil
+}
+
+func (s *mysqlStore) buildKey(key string) string {
+	return strings.Join([]string{s.keyPrefix, key}, ":")
+}
@@ -0,0 +1,62 @@
+package nonce
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// redisStore redis 存储
+type redisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisStore 实例化一个redis存储, client 可传入，方便单元测试
+func NewRedisStore(client *redis.Client, keyPrefix string) Store {
+	return &redisStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (s *redisStore) SetNX(key, value string, expiration time.Duration) (bool, error) {
+	ok, err := s.client.SetNX(s.buildKey(key), value, expiration).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "[nonce] redis setnx err, key: %s", key)
+	}
+	return ok, nil
+}
+
+func (s *redisStore) Set(key, value string, expiration time.Duration) error {
+	err := s.client.Set(s.buildKey(key), value, expiration).Err()
+	if err != nil {
+		return errors.Wrapf(err, "[nonce] redis set err, key: %s", key)
+	}
+	return nil
+}
+
+func (s *redisStore) Get(key string) (string, error) {
+	val, err := s.client.Get(s.buildKey(key)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "[nonce] redis get err, key: %s", key)
+	}
+	return val, nil
+}
+
+func (s *redisStore) Del(key string) error {
+	err := s.client.Del(s.buildKey(key)).Err()
+	if err != nil {
+		return errors.Wrapf(err, "[nonce] redis del err, key: %s", key)
+	}
+	return nil
+}
+
+func (s *redisStore) buildKey(key string) string {
+	return strings.Join([]string{s.keyPrefix, key}, ":")
+}
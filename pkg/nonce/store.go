@@ -0,0 +1,62 @@
+// 防重放(anti-replay)和幂等(idempotency)所需的存储
+// 生产环境使用 redis 以便多实例共享，需要持久保存时使用 mysql，单机或本地开发可以使用 memory
+
+package nonce
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// DriverRedis redis 存储
+	DriverRedis = "redis"
+	// DriverMemory 内存存储
+	DriverMemory = "memory"
+	// DriverMySQL mysql 存储
+	DriverMySQL = "mysql"
+
+	// PrefixNonceKey key前缀
+	PrefixNonceKey = "snake:nonce"
+)
+
+// Client 全局的存储客户端
+var Client Store
+
+// Store 定义存储接口
+type Store interface {
+	// SetNX key 不存在时写入，返回是否写入成功
+	SetNX(key, value string, expiration time.Duration) (bool, error)
+	// Set 写入，会覆盖已有的值
+	Set(key, value string, expiration time.Duration) error
+	// Get 获取值，key 不存在时返回空字符串
+	Get(key string) (string, error)
+	// Del 删除
+	Del(key string) error
+}
+
+// Init 根据配置初始化存储，默认 redis
+func Init() Store {
+	Client = NewStore(viper.GetString("nonce.driver"))
+	return Client
+}
+
+// NewStore 根据驱动名实例化存储
+func NewStore(driver string) Store {
+	switch driver {
+	case DriverMemory:
+		return NewMemoryStore(PrefixNonceKey)
+	case DriverMySQL:
+		return NewMySQLStore(model.GetDB(), PrefixNonceKey)
+	case DriverRedis, "":
+		return NewRedisStore(redis.RedisClient, PrefixNonceKey)
+	default:
+		log.Warnf("[nonce] unknown driver: %s, use redis instead", driver)
+		return NewRedisStore(redis.RedisClient, PrefixNonceKey)
+	}
+}
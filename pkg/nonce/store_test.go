@@ -0,0 +1,59 @@
+package nonce
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func testStore(t *testing.T, store Store) {
+	ok, err := store.SetNX("nonce-001", "1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first SetNX() = %v, %v, want true, nil", ok, err)
+	}
+
+	ok, err = store.SetNX("nonce-001", "1", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("second SetNX() = %v, %v, want false, nil", ok, err)
+	}
+
+	if err = store.Set("nonce-001", "done", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	val, err := store.Get("nonce-001")
+	if err != nil || val != "done" {
+		t.Fatalf("Get() = %v, %v, want done, nil", val, err)
+	}
+
+	if err = store.Del("nonce-001"); err != nil {
+		t.Fatal(err)
+	}
+	val, err = store.Get("nonce-001")
+	if err != nil || val != "" {
+		t.Fatalf("Get() after Del() = %v, %v, want empty", val, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore("unit-test"))
+}
+
+func TestMemoryStore_Expire(t *testing.T) {
+	store := NewMemoryStore("unit-test")
+	ok, _ := store.SetNX("nonce-002", "1", 10*time.Millisecond)
+	if !ok {
+		t.Fatal("SetNX() should success")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	ok, _ = store.SetNX("nonce-002", "1", time.Minute)
+	if !ok {
+		t.Error("SetNX() should success after expired")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	redis.InitTestRedis()
+	testStore(t, NewRedisStore(redis.RedisClient, "unit-test"))
+}
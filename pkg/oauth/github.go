@@ -0,0 +1,146 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// see: https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+
+const (
+	githubAuthURL         = "https://github.com/login/oauth/authorize"
+	githubTokenURL        = "https://github.com/login/oauth/access_token"
+	githubDefaultEndpoint = "https://api.github.com"
+)
+
+var githubDefaultScopes = []string{"read:user", "user:email"}
+
+// github GitHub OAuth App 登录
+type github struct {
+	conf     Config
+	tokenURL string
+	client   *http.Client
+}
+
+// NewGithub 实例化 GitHub 登录
+func NewGithub(conf Config) Provider {
+	tokenURL := githubTokenURL
+	if conf.Endpoint == "" {
+		conf.Endpoint = githubDefaultEndpoint
+	} else {
+		tokenURL = conf.Endpoint + "/login/oauth/access_token"
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = githubDefaultScopes
+	}
+	return &github{conf: conf, tokenURL: tokenURL, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (g *github) Name() string {
+	return ProviderGithub
+}
+
+// AuthCodeURL 授权页地址
+func (g *github) AuthCodeURL(state string) string {
+	q := url.Values{}
+	q.Set("client_id", g.conf.ClientID)
+	q.Set("redirect_uri", g.conf.RedirectURL)
+	q.Set("scope", strings.Join(g.conf.Scopes, " "))
+	q.Set("state", state)
+	return githubAuthURL + "?" + q.Encode()
+}
+
+type githubTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type githubUserResponse struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// Exchange 用 code 换取 access_token 和用户信息，邮箱取已验证的主邮箱
+func (g *github) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	form := url.Values{}
+	form.Set("client_id", g.conf.ClientID)
+	form.Set("client_secret", g.conf.ClientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", g.conf.RedirectURL)
+	req, err := http.NewRequest(http.MethodPost, g.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	b, err := doJSON(ctx, g.client, req)
+	if err != nil {
+		return nil, err
+	}
+	// 换取失败时 http 状态码仍为 200
+	var tok githubTokenResponse
+	if err := json.Unmarshal(b, &tok); err != nil {
+		return nil, fmt.Errorf("[oauth] github unmarshal token err: %v, body: %s", err, b)
+	}
+	if tok.Error != "" || tok.AccessToken == "" {
+		return nil, fmt.Errorf("[oauth] github exchange token err: %s, %s", tok.Error, tok.ErrorDescription)
+	}
+
+	var u githubUserResponse
+	if err := g.get(ctx, "/user", tok.AccessToken, &u); err != nil {
+		return nil, err
+	}
+	info := &UserInfo{
+		Provider:    ProviderGithub,
+		OpenID:      strconv.FormatInt(u.ID, 10),
+		Nickname:    u.Name,
+		Avatar:      u.AvatarURL,
+		AccessToken: tok.AccessToken,
+	}
+	if info.Nickname == "" {
+		info.Nickname = u.Login
+	}
+
+	// /user 中的 email 是公开邮箱，不一定验证过
+	var emails []githubEmail
+	if err := g.get(ctx, "/user/emails", tok.AccessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			info.Email, info.EmailVerified = e.Email, true
+			break
+		}
+	}
+	return info, nil
+}
+
+func (g *github) get(ctx context.Context, path, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, g.conf.Endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	b, err := doJSON(ctx, g.client, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("[oauth] github unmarshal %s err: %v, body: %s", path, err, b)
+	}
+	return nil
+}
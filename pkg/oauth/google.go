@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// see: https://developers.google.com/identity/protocols/oauth2/web-server
+
+const (
+	googleAuthURL      = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL     = "https://oauth2.googleapis.com/token"
+	googleUserInfoURL  = "https://openidconnect.googleapis.com/v1/userinfo"
+	googleTokenPath    = "/token"
+	googleUserInfoPath = "/v1/userinfo"
+)
+
+var googleDefaultScopes = []string{"openid", "email", "profile"}
+
+// google Google OpenID Connect 登录
+type google struct {
+	conf        Config
+	tokenURL    string
+	userInfoURL string
+	client      *http.Client
+}
+
+// NewGoogle 实例化 Google 登录
+func NewGoogle(conf Config) Provider {
+	g := &google{conf: conf, tokenURL: googleTokenURL, userInfoURL: googleUserInfoURL,
+		client: &http.Client{Timeout: conf.Timeout}}
+	if conf.Endpoint != "" {
+		g.tokenURL = conf.Endpoint + googleTokenPath
+		g.userInfoURL = conf.Endpoint + googleUserInfoPath
+	}
+	if len(conf.Scopes) == 0 {
+		g.conf.Scopes = googleDefaultScopes
+	}
+	return g
+}
+
+// Name 名称
+func (g *google) Name() string {
+	return ProviderGoogle
+}
+
+// AuthCodeURL 授权页地址
+func (g *google) AuthCodeURL(state string) string {
+	q := url.Values{}
+	q.Set("client_id", g.conf.ClientID)
+	q.Set("redirect_uri", g.conf.RedirectURL)
+	q.Set("response_type", "code")
+	q.Set("scope", strings.Join(g.conf.Scopes, " "))
+	q.Set("state", state)
+	return googleAuthURL + "?" + q.Encode()
+}
+
+type googleTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type googleUserResponse struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// Exchange 用 code 换取 access_token 和用户信息
+func (g *google) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	form := url.Values{}
+	form.Set("client_id", g.conf.ClientID)
+	form.Set("client_secret", g.conf.ClientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", g.conf.RedirectURL)
+	form.Set("grant_type", "authorization_code")
+	req, err := http.NewRequest(http.MethodPost, g.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	b, err := doJSON(ctx, g.client, req)
+	if err != nil {
+		return nil, err
+	}
+	var tok googleTokenResponse
+	if err := json.Unmarshal(b, &tok); err != nil {
+		return nil, fmt.Errorf("[oauth] google unmarshal token err: %v, body: %s", err, b)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, g.userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	b, err = doJSON(ctx, g.client, req)
+	if err != nil {
+		return nil, err
+	}
+	var u googleUserResponse
+	if err := json.Unmarshal(b, &u); err != nil {
+		return nil, fmt.Errorf("[oauth] google unmarshal userinfo err: %v, body: %s", err, b)
+	}
+	if u.Sub == "" {
+		return nil, fmt.Errorf("[oauth] google userinfo without sub, body: %s", b)
+	}
+
+	return &UserInfo{
+		Provider:      ProviderGoogle,
+		OpenID:        u.Sub,
+		Nickname:      u.Name,
+		Avatar:        u.Picture,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		AccessToken:   tok.AccessToken,
+		RefreshToken:  tok.RefreshToken,
+		ExpiresIn:     tok.ExpiresIn,
+	}, nil
+}
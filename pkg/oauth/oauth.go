@@ -0,0 +1,198 @@
+// 第三方帐号登录，支持微信、GitHub 和 Google
+// 客户端跳转到 AuthCodeURL 授权，回调时带回 code 和 state，服务端用 code 换取第三方帐号信息
+
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// ProviderWechat 微信开放平台网站应用
+	ProviderWechat = "wechat"
+	// ProviderGithub GitHub
+	ProviderGithub = "github"
+	// ProviderGoogle Google
+	ProviderGoogle = "google"
+
+	defaultTimeout = 5 * time.Second
+
+	// stateKey 授权时生成的 state，回调时校验并删除，防止 CSRF
+	stateKey = "snake:oauth:state:%s:%s"
+	stateTTL = 10 * time.Minute
+)
+
+var (
+	// ErrUnknownProvider 未知或未配置的第三方登录
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	// ErrInvalidState state 不存在或已使用
+	ErrInvalidState = errors.New("invalid oauth state")
+)
+
+// providers 已配置的第三方登录，key 为名称
+var providers map[string]Provider
+
+// Lock 读写锁
+var Lock sync.RWMutex
+
+// UserInfo 第三方帐号信息
+type UserInfo struct {
+	Provider string
+	// OpenID 第三方帐号在该应用下的唯一标识
+	OpenID string
+	// UnionID 微信同一开放平台下多个应用共用的标识，其他平台为空
+	UnionID  string
+	Nickname string
+	Avatar   string
+	Email    string
+	// EmailVerified 第三方已验证过邮箱，只有已验证的邮箱可以关联本地帐号
+	EmailVerified bool
+
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// Provider 第三方登录
+type Provider interface {
+	Name() string
+	// AuthCodeURL 授权页地址
+	AuthCodeURL(state string) string
+	// Exchange 用授权回调的 code 换取第三方帐号信息
+	Exchange(ctx context.Context, code string) (*UserInfo, error)
+}
+
+// Config 第三方应用配置
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Timeout      time.Duration
+	// Endpoint 接口地址，测试时替换为本地服务，为空时使用第三方的正式地址
+	Endpoint string
+}
+
+// Init 按配置初始化第三方登录，oauth.<name>.client_id 为空的不启用
+func Init() {
+	timeout := viper.GetDuration("oauth.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	m := make(map[string]Provider)
+	for _, name := range []string{ProviderWechat, ProviderGithub, ProviderGoogle} {
+		prefix := "oauth." + name
+		conf := Config{
+			ClientID:     viper.GetString(prefix + ".client_id"),
+			ClientSecret: viper.GetString(prefix + ".client_secret"),
+			RedirectURL:  viper.GetString(prefix + ".redirect_url"),
+			Scopes:       viper.GetStringSlice(prefix + ".scopes"),
+			Timeout:      timeout,
+		}
+		if conf.ClientID == "" {
+			continue
+		}
+		p, err := New(name, conf)
+		if err != nil {
+			panic(fmt.Sprintf("[oauth] new provider %s err: %v", name, err))
+		}
+		m[name] = p
+	}
+
+	Lock.Lock()
+	defer Lock.Unlock()
+	providers = m
+}
+
+// New 按名称实例化第三方登录
+func New(name string, conf Config) (Provider, error) {
+	switch name {
+	case ProviderWechat:
+		return NewWechat(conf), nil
+	case ProviderGithub:
+		return NewGithub(conf), nil
+	case ProviderGoogle:
+		return NewGoogle(conf), nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+// Register 注册第三方登录，同名的会被替换
+func Register(p Provider) {
+	Lock.Lock()
+	defer Lock.Unlock()
+	if providers == nil {
+		providers = make(map[string]Provider)
+	}
+	providers[p.Name()] = p
+}
+
+// Get 获取已配置的第三方登录
+func Get(name string) (Provider, error) {
+	Lock.RLock()
+	defer Lock.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// NewState 生成授权用的 state，在 stateTTL 内回调有效
+func NewState(provider string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b)
+	if err := redis.RedisClient.Set(fmt.Sprintf(stateKey, provider, state), 1, stateTTL).Err(); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// CheckState 校验并删除 state，每个 state 只能使用一次
+func CheckState(provider, state string) error {
+	if state == "" {
+		return ErrInvalidState
+	}
+	n, err := redis.RedisClient.Del(fmt.Sprintf(stateKey, provider, state)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// doJSON 发送请求并读取响应，非 2xx 时返回错误
+func doJSON(ctx context.Context, client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return b, fmt.Errorf("[oauth] %s %s status: %d, body: %s", req.Method, req.URL.Path, resp.StatusCode, b)
+	}
+	return b, nil
+}
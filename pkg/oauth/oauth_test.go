@@ -0,0 +1,141 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestState(t *testing.T) {
+	redis.InitTestRedis()
+
+	state, err := NewState(ProviderGithub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// state 只对生成时的平台有效
+	if err := CheckState(ProviderGoogle, state); err != ErrInvalidState {
+		t.Fatalf("CheckState() other provider = %v, want ErrInvalidState", err)
+	}
+	if err := CheckState(ProviderGithub, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckState(ProviderGithub, state); err != ErrInvalidState {
+		t.Fatalf("CheckState() reused = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestWechat_Exchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/sns/oauth2/access_token":
+			if q.Get("appid") != "wx" || q.Get("secret") != "secret" || q.Get("code") != "code" {
+				_, _ = w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at","expires_in":7200,"refresh_token":"rt","openid":"oid","unionid":"uid"}`))
+		case "/sns/userinfo":
+			if q.Get("access_token") != "at" || q.Get("openid") != "oid" {
+				_, _ = w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"openid":"oid","nickname":"snake","headimgurl":"https://example.com/a.png"}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := NewWechat(Config{ClientID: "wx", ClientSecret: "secret", RedirectURL: "https://example.com/cb", Endpoint: srv.URL})
+	u, err := url.Parse(p.AuthCodeURL("st"))
+	if err != nil || u.Query().Get("scope") != wechatDefaultScope || u.Query().Get("state") != "st" || u.Fragment != "wechat_redirect" {
+		t.Fatalf("AuthCodeURL() = %s, %v", u, err)
+	}
+
+	info, err := p.Exchange(context.Background(), "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.OpenID != "oid" || info.UnionID != "uid" || info.Nickname != "snake" || info.ExpiresIn != 7200 {
+		t.Fatalf("Exchange() = %+v", info)
+	}
+	if _, err := p.Exchange(context.Background(), "bad"); err == nil || !strings.Contains(err.Error(), "40029") {
+		t.Fatalf("Exchange() bad code = %v", err)
+	}
+}
+
+func TestGithub_Exchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login/oauth/access_token" {
+			_ = r.ParseForm()
+			if r.PostForm.Get("code") != "code" {
+				_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at","token_type":"bearer"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer at" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/user":
+			_, _ = w.Write([]byte(`{"id":42,"login":"snake","email":"public@example.com"}`))
+		case "/user/emails":
+			_, _ = w.Write([]byte(`[{"email":"other@example.com","verified":true},{"email":"me@example.com","primary":true,"verified":true}]`))
+		}
+	}))
+	defer srv.Close()
+
+	p := NewGithub(Config{ClientID: "id", ClientSecret: "secret", Endpoint: srv.URL})
+	info, err := p.Exchange(context.Background(), "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有名字时使用登录名，邮箱使用已验证的主邮箱而不是公开邮箱
+	if info.OpenID != "42" || info.Nickname != "snake" || info.Email != "me@example.com" || !info.EmailVerified {
+		t.Fatalf("Exchange() = %+v", info)
+	}
+	if _, err := p.Exchange(context.Background(), "bad"); err == nil || !strings.Contains(err.Error(), "bad_verification_code") {
+		t.Fatalf("Exchange() bad code = %v", err)
+	}
+}
+
+func TestGoogle_Exchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case googleTokenPath:
+			_ = r.ParseForm()
+			if r.PostForm.Get("code") != "code" || r.PostForm.Get("grant_type") != "authorization_code" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at","expires_in":3599,"id_token":"x"}`))
+		case googleUserInfoPath:
+			if r.Header.Get("Authorization") != "Bearer at" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"sub":"1001","name":"Snake","email":"me@example.com","email_verified":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := NewGoogle(Config{ClientID: "id", ClientSecret: "secret", Endpoint: srv.URL})
+	info, err := p.Exchange(context.Background(), "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.OpenID != "1001" || info.Email != "me@example.com" || !info.EmailVerified || info.ExpiresIn != 3599 {
+		t.Fatalf("Exchange() = %+v", info)
+	}
+	if _, err := p.Exchange(context.Background(), "bad"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange() bad code = %v", err)
+	}
+}
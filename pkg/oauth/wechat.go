@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// see: https://developers.weixin.qq.com/doc/oplatform/Website_App/WeChat_Login/Wechat_Login.html
+
+const (
+	wechatAuthURL         = "https://open.weixin.qq.com/connect/qrconnect"
+	wechatDefaultEndpoint = "https://api.weixin.qq.com"
+	wechatDefaultScope    = "snsapi_login"
+)
+
+// wechat 微信开放平台网站应用扫码登录，ClientID 为 appid，ClientSecret 为 appsecret
+// 移动应用通过 sdk 拿到 code 后同样调用 Exchange
+type wechat struct {
+	conf   Config
+	client *http.Client
+}
+
+// NewWechat 实例化微信登录
+func NewWechat(conf Config) Provider {
+	if conf.Endpoint == "" {
+		conf.Endpoint = wechatDefaultEndpoint
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{wechatDefaultScope}
+	}
+	return &wechat{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (w *wechat) Name() string {
+	return ProviderWechat
+}
+
+// AuthCodeURL 扫码授权页地址
+func (w *wechat) AuthCodeURL(state string) string {
+	q := url.Values{}
+	q.Set("appid", w.conf.ClientID)
+	q.Set("redirect_uri", w.conf.RedirectURL)
+	q.Set("response_type", "code")
+	q.Set("scope", strings.Join(w.conf.Scopes, ","))
+	q.Set("state", state)
+	return wechatAuthURL + "?" + q.Encode() + "#wechat_redirect"
+}
+
+// wechatError 微信接口出错时返回 errcode 和 errmsg
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+type wechatTokenResponse struct {
+	wechatError
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	OpenID       string `json:"openid"`
+	UnionID      string `json:"unionid"`
+}
+
+type wechatUserResponse struct {
+	wechatError
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+}
+
+// Exchange 用 code 换取 access_token 和用户信息，微信不返回邮箱
+func (w *wechat) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	q := url.Values{}
+	q.Set("appid", w.conf.ClientID)
+	q.Set("secret", w.conf.ClientSecret)
+	q.Set("code", code)
+	q.Set("grant_type", "authorization_code")
+	var tok wechatTokenResponse
+	if err := w.get(ctx, "/sns/oauth2/access_token", q, &tok); err != nil {
+		return nil, err
+	}
+
+	q = url.Values{}
+	q.Set("access_token", tok.AccessToken)
+	q.Set("openid", tok.OpenID)
+	var u wechatUserResponse
+	if err := w.get(ctx, "/sns/userinfo", q, &u); err != nil {
+		return nil, err
+	}
+
+	unionID := u.UnionID
+	if unionID == "" {
+		unionID = tok.UnionID
+	}
+	return &UserInfo{
+		Provider:     ProviderWechat,
+		OpenID:       tok.OpenID,
+		UnionID:      unionID,
+		Nickname:     u.Nickname,
+		Avatar:       u.HeadImgURL,
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		ExpiresIn:    tok.ExpiresIn,
+	}, nil
+}
+
+// get 微信接口出错时 http 状态码仍为 200，需要检查 errcode
+func (w *wechat) get(ctx context.Context, path string, q url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, w.conf.Endpoint+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	b, err := doJSON(ctx, w.client, req)
+	if err != nil {
+		return err
+	}
+	var e wechatError
+	if err := json.Unmarshal(b, &e); err != nil {
+		return fmt.Errorf("[oauth] wechat unmarshal resp err: %v, body: %s", err, b)
+	}
+	if e.ErrCode != 0 {
+		return fmt.Errorf("[oauth] wechat %s err, code: %d, msg: %s", path, e.ErrCode, e.ErrMsg)
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,279 @@
+// Package openapi 按 swag 生成的 swagger 2.0 文档校验接口的实际响应，防止文档和实现不一致
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// refPrefix swag 生成的 $ref 都指向 definitions
+const refPrefix = "#/definitions/"
+
+// Schema 文档中的数据结构，只解析校验需要的字段
+type Schema struct {
+	Type       string             `json:"type"`
+	Ref        string             `json:"$ref"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	AllOf      []*Schema          `json:"allOf"`
+}
+
+// Response 接口声明的响应
+type Response struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Operation 接口，按状态码声明响应
+type Operation struct {
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Spec swagger 2.0 文档
+type Spec struct {
+	BasePath    string                           `json:"basePath"`
+	Paths       map[string]map[string]*Operation `json:"paths"`
+	Definitions map[string]*Schema               `json:"definitions"`
+}
+
+// Load 读取 swag init 生成的 swagger.json
+func Load(filename string) (*Spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse 解析 swagger 2.0 文档
+func Parse(data []byte) (*Spec, error) {
+	var s Spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse swagger doc: %v", err)
+	}
+	return &s, nil
+}
+
+// Operation 按请求的方法和路径查找文档中的接口，路径包含 basePath，返回匹配到的路径模板
+// 同时匹配多个模板时优先使用参数少的，如 /users/follow 优先于 /users/{id}
+func (s *Spec) Operation(method, path string) (*Operation, string, bool) {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if !strings.HasPrefix(path, s.BasePath) {
+		return nil, "", false
+	}
+	segments := split(strings.TrimPrefix(path, s.BasePath))
+
+	var (
+		found    *Operation
+		template string
+		params   = -1
+	)
+	for tpl, ops := range s.Paths {
+		op, ok := ops[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+		n, ok := match(split(tpl), segments)
+		if !ok {
+			continue
+		}
+		if params < 0 || n < params || (n == params && tpl < template) {
+			found, template, params = op, tpl, n
+		}
+	}
+	return found, template, found != nil
+}
+
+// split 按 / 拆分路径，忽略首尾的 /
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// match 路径是否符合模板，返回模板中参数的个数
+func match(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	params := 0
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			params++
+			continue
+		}
+		if t != segments[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// Validate 校验接口的实际响应，状态码需要在文档中声明，body 需要符合声明的 schema
+// 所有不一致的地方合并在一个错误中返回
+func (s *Spec) Validate(method, path string, status int, body []byte) error {
+	op, template, ok := s.Operation(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: status %d %s is not documented", method, template, status, http.StatusText(status))
+	}
+	if resp == nil || resp.Schema == nil {
+		return nil
+	}
+
+	// 文件下载等接口声明为 string，body 不是 json
+	if !json.Valid(body) {
+		if s.resolve(resp.Schema).Type == "string" {
+			return nil
+		}
+		return fmt.Errorf("%s %s: body is not json: %.64q", method, template, body)
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%s %s: decode body: %v", method, template, err)
+	}
+
+	var errs []string
+	s.validate(v, resp.Schema, "body", &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s %s: %s", method, template, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// resolve 找到 $ref 指向的定义，找不到时返回原 schema
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema.Ref != "" {
+		def, ok := s.Definitions[strings.TrimPrefix(schema.Ref, refPrefix)]
+		if !ok {
+			return schema
+		}
+		schema = def
+	}
+	return schema
+}
+
+// validate 按 schema 校验 json 解析后的值，错误中带上字段的路径
+// go 中 nil 的指针、slice、map 会编码为 null，文档中不区分，所以 null 符合任意类型
+func (s *Spec) validate(v interface{}, schema *Schema, at string, errs *[]string) {
+	if schema.Ref != "" {
+		if _, ok := s.Definitions[strings.TrimPrefix(schema.Ref, refPrefix)]; !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: undefined %s", at, schema.Ref))
+			return
+		}
+		schema = s.resolve(schema)
+	}
+	for _, sub := range schema.AllOf {
+		s.validate(v, sub, at, errs)
+	}
+	if v == nil {
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		// swag 把 interface{} 生成为没有字段的 object，可以是任意值，如 handler.Response 的 data
+		if len(schema.Properties) == 0 && len(schema.Required) == 0 {
+			return
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want object, got %s", at, typeOf(v)))
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required field %q", at, name))
+			}
+		}
+		if len(schema.Properties) == 0 {
+			return
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: undocumented field %q", at, name))
+				continue
+			}
+			s.validate(obj[name], prop, at+"."+name, errs)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want array, got %s", at, typeOf(v)))
+			return
+		}
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range arr {
+			s.validate(item, schema.Items, fmt.Sprintf("%s[%d]", at, i), errs)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want integer, got %s", at, typeOf(v)))
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				*errs = append(*errs, fmt.Sprintf("%s: want integer, got %s", at, n))
+			}
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want number, got %s", at, typeOf(v)))
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want string, got %s", at, typeOf(v)))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: want boolean, got %s", at, typeOf(v)))
+		}
+	}
+}
+
+// typeOf json 值的类型名称
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `{
+    "swagger": "2.0",
+    "basePath": "/v1",
+    "paths": {
+        "/users/{id}": {
+            "get": {"responses": {"200": {"schema": {"type": "object", "$ref": "#/definitions/handler.Response"}}}}
+        },
+        "/users/follow": {
+            "get": {"responses": {"200": {"schema": {"type": "array", "items": {"$ref": "#/definitions/User"}}}}}
+        },
+        "/users/{id}/avatar": {
+            "get": {"responses": {"302": {}}}
+        },
+        "/export": {
+            "get": {"responses": {"200": {"schema": {"type": "string"}}}}
+        }
+    },
+    "definitions": {
+        "handler.Response": {
+            "type": "object",
+            "properties": {"code": {"type": "integer"}, "message": {"type": "string"}, "data": {"type": "object"}}
+        },
+        "User": {
+            "type": "object",
+            "required": ["id"],
+            "properties": {"id": {"type": "integer"}, "name": {"type": "string"}, "vip": {"type": "boolean"}}
+        }
+    }
+}`
+
+func TestSpec_Operation(t *testing.T) {
+	s, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         string
+		ok           bool
+	}{
+		{"GET", "/v1/users/12", "/users/{id}", true},
+		{"GET", "/v1/users/follow?limit=10", "/users/follow", true},
+		{"GET", "/v1/users/12/avatar", "/users/{id}/avatar", true},
+		{"POST", "/v1/users/12", "", false},
+		{"GET", "/users/12", "", false},
+		{"GET", "/v1/users", "", false},
+	}
+	for _, tt := range tests {
+		_, got, ok := s.Operation(tt.method, tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Operation(%s %s) = %q, %v, want %q, %v", tt.method, tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSpec_Validate(t *testing.T) {
+	s, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		errs   []string
+	}{
+		{"ok", "GET", "/v1/users/1", 200, `{"code":0,"message":"OK","data":{"id":1}}`, nil},
+		{"array data", "GET", "/v1/users/1", 200, `{"code":0,"message":"OK","data":[1,2]}`, nil},
+		{"null data", "GET", "/v1/users/1", 200, `{"code":20102,"message":"not found","data":null}`, nil},
+		{"array", "GET", "/v1/users/follow", 200, `[{"id":1,"name":"snake","vip":true},{"id":2}]`, nil},
+		{"no schema", "GET", "/v1/users/1/avatar", 302, ``, nil},
+		{"raw string", "GET", "/v1/export", 200, "1,2\n1,3\n", nil},
+		{"undocumented path", "GET", "/v1/unknown", 200, `{}`, []string{"not documented"}},
+		{"undocumented status", "GET", "/v1/users/1", 503, `{}`, []string{"status 503 Service Unavailable is not documented"}},
+		{"wrong type", "GET", "/v1/users/1", 200, `{"code":"0","message":"OK"}`, []string{`body.code: want integer, got string`}},
+		{"not integer", "GET", "/v1/users/1", 200, `{"code":1.5}`, []string{`body.code: want integer, got 1.5`}},
+		{"undocumented field", "GET", "/v1/users/1", 200, `{"code":0,"msg":"OK"}`, []string{`body: undocumented field "msg"`}},
+		{"missing required", "GET", "/v1/users/follow", 200, `[{"name":"snake"}]`, []string{`body[0]: missing required field "id"`}},
+		{"nested", "GET", "/v1/users/follow", 200, `[{"id":1,"vip":"yes"}]`, []string{`body[0].vip: want boolean, got string`}},
+		{"object as string", "GET", "/v1/export", 200, `{"code":0}`, []string{`body: want string, got object`}},
+		{"not json", "GET", "/v1/users/1", 200, `ok`, []string{"body is not json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Validate(tt.method, tt.path, tt.status, []byte(tt.body))
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() err = nil, want %v", tt.errs)
+			}
+			for _, e := range tt.errs {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("Validate() err = %v, want contains %q", err, e)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package otp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/1024casts/snake/pkg/sms"
+)
+
+// ChannelSMS 短信渠道，接收方为手机号
+const ChannelSMS = "sms"
+
+// Channel 验证码的发送渠道
+type Channel interface {
+	Name() string
+	Deliver(ctx context.Context, purpose, target, code string) error
+}
+
+type smsChannel struct{}
+
+// SMS 短信渠道，所有用途共用验证码模板 login，发送频率见 sms.limits，超出时返回 *sms.LimitError
+func SMS() Channel {
+	return smsChannel{}
+}
+
+func (smsChannel) Name() string {
+	return ChannelSMS
+}
+
+func (smsChannel) Deliver(ctx context.Context, purpose, target, code string) error {
+	if target == "" || code == "" {
+		return errors.New("otp: phone or code is empty")
+	}
+	return sms.Send(ctx, &sms.Message{
+		Phone:    target,
+		Template: sms.TemplateLogin,
+		Params:   map[string]string{"code": code},
+	})
+}
@@ -0,0 +1,287 @@
+// Package otp 一次性验证码，短信验证码和图形验证码共用生成、发送、校验、作废的流程
+// 验证码按 用途+接收方 保存在 redis 中，同一用途重新发送会覆盖之前的验证码；
+// 校验成功后立即作废，不能重复使用，连续校验失败 MaxAttempts 次后也会作废，需要重新获取
+//
+// 手机号登录(未注册时自动注册)、换绑手机号使用短信验证码；
+// 重置密码、验证邮箱、修改邮箱通过邮件中的一次性链接完成，链接中是签名的 token 而不是需要用户输入的验证码，
+// 见 internal/service/user/user_link_token.go，不使用这个包
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+// 验证码的用途，不同用途的验证码互不通用
+const (
+	// PurposeLogin 手机号登录，未注册的手机号登录时自动注册
+	PurposeLogin = "login"
+	// PurposeRebind 换绑手机号，新旧手机号都需要验证
+	PurposeRebind = "rebind"
+	// PurposeCaptcha 图形验证码，由调用方展示，不通过渠道发送
+	PurposeCaptcha = "captcha"
+)
+
+// 默认配置
+const (
+	DefaultLength         = 6
+	DefaultTTL            = 10 * time.Minute
+	DefaultMaxAttempts    = 5
+	DefaultResendInterval = time.Minute
+)
+
+// PrefixOTPKey 验证码key前缀，完整的 key 为 snake:otp:<用途>:<接收方>
+const PrefixOTPKey = "snake:otp"
+
+var (
+	// ErrInvalid 验证码不存在、已过期或不正确
+	ErrInvalid = errors.New("otp: invalid code")
+	// ErrTooManyAttempts 校验失败次数过多，验证码已作废
+	ErrTooManyAttempts = errors.New("otp: too many attempts")
+	// ErrResendTooSoon 距离上次发送不足 ResendInterval
+	ErrResendTooSoon = errors.New("otp: resend too soon")
+	// ErrChannelNotFound 没有注册对应的发送渠道
+	ErrChannelNotFound = errors.New("otp: channel not found")
+)
+
+// 校验一组验证码，全部正确时一起删除，有不正确的时都不删除，只给不正确的增加失败次数，达到上限的删除
+// ARGV 依次为各个 key 的验证码，最后一个为失败次数上限
+// 返回 1:全部正确 0:有不正确的 -1:有不存在或已过期的 -2:有失败次数达到上限的
+var verifyScript = redis.NewScript(`
+local max = tonumber(ARGV[#ARGV])
+local codes = {}
+for i, key in ipairs(KEYS) do
+	codes[i] = redis.call("HGET", key, "code")
+	if not codes[i] then
+		return -1
+	end
+end
+local matched = true
+for i = 1, #KEYS do
+	if codes[i] ~= ARGV[i] then
+		matched = false
+	end
+end
+if matched then
+	redis.call("DEL", unpack(KEYS))
+	return 1
+end
+local ret = 0
+for i, key in ipairs(KEYS) do
+	if codes[i] ~= ARGV[i] and redis.call("HINCRBY", key, "attempts", 1) >= max then
+		redis.call("DEL", key)
+		ret = -2
+	end
+end
+return ret
+`)
+
+// Config 验证码配置
+type Config struct {
+	// Length 验证码位数
+	Length int `mapstructure:"length"`
+	// TTL 有效期
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxAttempts 最多校验失败的次数
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// ResendInterval 同一用途、同一接收方两次发送的最小间隔，小于0时不限制
+	ResendInterval time.Duration `mapstructure:"resend_interval"`
+	// TestTargets 测试用的接收方，不发送验证码，任意验证码都能通过，只能在测试环境配置
+	TestTargets []string `mapstructure:"test_targets"`
+}
+
+// Service 验证码服务
+type Service struct {
+	cfg      Config
+	client   *redis.Client
+	channels map[string]Channel
+	tests    map[string]struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	client = New(Config{}, nil, SMS())
+)
+
+// Init 按配置 otp 初始化，注册短信渠道
+func Init() *Service {
+	var cfg Config
+	_ = viper.UnmarshalKey("otp", &cfg)
+	s := New(cfg, nil, SMS())
+	mu.Lock()
+	client = s
+	mu.Unlock()
+	return s
+}
+
+// Default 返回全局的验证码服务，未初始化时使用默认配置
+func Default() *Service {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// New 实例化，配置为0时使用默认值
+// redisClient 为 nil 时每次使用默认的 redis client，包初始化时配置还没有加载
+func New(cfg Config, redisClient *redis.Client, channels ...Channel) *Service {
+	if cfg.Length <= 0 {
+		cfg.Length = DefaultLength
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.ResendInterval == 0 {
+		cfg.ResendInterval = DefaultResendInterval
+	}
+	s := &Service{
+		cfg:      cfg,
+		client:   redisClient,
+		channels: make(map[string]Channel, len(channels)),
+		tests:    make(map[string]struct{}, len(cfg.TestTargets)),
+	}
+	for _, c := range channels {
+		s.channels[c.Name()] = c
+	}
+	for _, t := range cfg.TestTargets {
+		s.tests[t] = struct{}{}
+	}
+	return s
+}
+
+func (s *Service) redis() *redis.Client {
+	if s.client != nil {
+		return s.client
+	}
+	return redis2.RedisClient
+}
+
+// Send 生成验证码并通过渠道发送，发送失败时作废验证码，可以立即重新发送
+func (s *Service) Send(ctx context.Context, channel, purpose, target string) error {
+	c, ok := s.channels[channel]
+	if !ok {
+		return ErrChannelNotFound
+	}
+	if _, ok := s.tests[target]; ok {
+		return nil
+	}
+
+	if s.cfg.ResendInterval > 0 {
+		ok, err := s.redis().SetNX(resendKey(purpose, target), 1, s.cfg.ResendInterval).Result()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrResendTooSoon
+		}
+	}
+
+	code, err := s.Generate(ctx, purpose, target)
+	if err != nil {
+		s.redis().Del(resendKey(purpose, target))
+		return err
+	}
+	if err := c.Deliver(ctx, purpose, target, code); err != nil {
+		s.redis().Del(key(purpose, target), resendKey(purpose, target))
+		return err
+	}
+	return nil
+}
+
+// Generate 生成验证码，不发送，用于图形验证码等由调用方展示的场景
+func (s *Service) Generate(ctx context.Context, purpose, target string) (string, error) {
+	code, err := newCode(s.cfg.Length)
+	if err != nil {
+		return "", err
+	}
+	k := key(purpose, target)
+	pipe := s.redis().TxPipeline()
+	pipe.Del(k)
+	pipe.HSet(k, "code", code)
+	pipe.Expire(k, s.cfg.TTL)
+	if _, err := pipe.Exec(); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Verify 校验验证码，正确时作废，不正确时返回 ErrInvalid，失败次数达到上限时作废并返回 ErrTooManyAttempts
+func (s *Service) Verify(ctx context.Context, purpose, target, code string) error {
+	return s.VerifyAll(ctx, purpose, map[string]string{target: code})
+}
+
+// VerifyAll 校验同一用途的一组验证码，key 为接收方，全部正确时才一起作废
+// 用于换绑手机号等需要同时验证多个接收方的场景，其中一个输错时另一个仍然可以使用
+func (s *Service) VerifyAll(ctx context.Context, purpose string, codes map[string]string) error {
+	keys := make([]string, 0, len(codes))
+	args := make([]interface{}, 0, len(codes)+1)
+	for target, code := range codes {
+		if _, ok := s.tests[target]; ok {
+			continue
+		}
+		if code == "" {
+			return ErrInvalid
+		}
+		keys = append(keys, key(purpose, target))
+		args = append(args, code)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	args = append(args, s.cfg.MaxAttempts)
+
+	ret, err := verifyScript.Run(s.redis(), keys, args...).Int()
+	if err != nil {
+		return err
+	}
+	switch ret {
+	case 1:
+		return nil
+	case -2:
+		return ErrTooManyAttempts
+	default:
+		return ErrInvalid
+	}
+}
+
+// Invalidate 作废验证码，如换绑完成后作废另一个手机号还未使用的验证码
+func (s *Service) Invalidate(ctx context.Context, purpose, target string) error {
+	return s.redis().Del(key(purpose, target)).Err()
+}
+
+func key(purpose, target string) string {
+	return fmt.Sprintf("%s:%s:%s", PrefixOTPKey, purpose, target)
+}
+
+func resendKey(purpose, target string) string {
+	return fmt.Sprintf("%s:resend:%s:%s", PrefixOTPKey, purpose, target)
+}
+
+// newCode 生成随机数字验证码，首位不为0，客户端按数字传递时不会丢失位数
+func newCode(length int) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		max, offset := int64(10), byte('0')
+		if i == 0 {
+			max, offset = 9, '1'
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(max))
+		if err != nil {
+			return "", err
+		}
+		b[i] = offset + byte(n.Int64())
+	}
+	return string(b), nil
+}
@@ -0,0 +1,170 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// fakeChannel 记录最后发送的验证码
+type fakeChannel struct {
+	code  string
+	err   error
+	calls int
+}
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Deliver(ctx context.Context, purpose, target, code string) error {
+	c.calls++
+	c.code = code
+	return c.err
+}
+
+func TestService_SendAndVerify(t *testing.T) {
+	redis.InitTestRedis()
+	ctx := context.Background()
+	ch := &fakeChannel{}
+	s := New(Config{MaxAttempts: 3, TestTargets: []string{"13010102020"}}, redis.RedisClient, ch)
+
+	if err := s.Send(ctx, "unknown", PurposeLogin, "13800000000"); err != ErrChannelNotFound {
+		t.Fatalf("Send() unknown channel = %v, want ErrChannelNotFound", err)
+	}
+	if err := s.Send(ctx, "fake", PurposeLogin, "13800000000"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.code) != DefaultLength || ch.code[0] == '0' {
+		t.Fatalf("code = %q, want %d digits not starting with 0", ch.code, DefaultLength)
+	}
+	// 重新发送需要间隔 ResendInterval
+	if err := s.Send(ctx, "fake", PurposeLogin, "13800000000"); err != ErrResendTooSoon {
+		t.Fatalf("Send() again = %v, want ErrResendTooSoon", err)
+	}
+
+	// 不同用途的验证码不通用，校验成功后作废
+	if err := s.Verify(ctx, PurposeRebind, "13800000000", ch.code); err != ErrInvalid {
+		t.Fatalf("Verify() other purpose = %v, want ErrInvalid", err)
+	}
+	if err := s.Verify(ctx, PurposeLogin, "13800000000", ch.code); err != nil {
+		t.Fatalf("Verify() = %v, want nil", err)
+	}
+	if err := s.Verify(ctx, PurposeLogin, "13800000000", ch.code); err != ErrInvalid {
+		t.Fatalf("Verify() used code = %v, want ErrInvalid", err)
+	}
+
+	// 测试号不发送，任意验证码都能通过
+	if err := s.Send(ctx, "fake", PurposeLogin, "13010102020"); err != nil || ch.calls != 1 {
+		t.Fatalf("Send() test target = %v, calls = %d", err, ch.calls)
+	}
+	if err := s.Verify(ctx, PurposeLogin, "13010102020", "1"); err != nil {
+		t.Fatalf("Verify() test target = %v, want nil", err)
+	}
+}
+
+func TestService_VerifyAttempts(t *testing.T) {
+	redis.InitTestRedis()
+	ctx := context.Background()
+	s := New(Config{MaxAttempts: 3}, redis.RedisClient)
+
+	code, err := s.Generate(ctx, PurposeCaptcha, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(ctx, PurposeCaptcha, "id", ""); err != ErrInvalid {
+		t.Fatalf("Verify() empty = %v, want ErrInvalid", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Verify(ctx, PurposeCaptcha, "id", "0"); err != ErrInvalid {
+			t.Fatalf("Verify() attempt %d = %v, want ErrInvalid", i+1, err)
+		}
+	}
+	// 达到上限后作废，正确的验证码也不能再通过
+	if err := s.Verify(ctx, PurposeCaptcha, "id", "0"); err != ErrTooManyAttempts {
+		t.Fatalf("Verify() attempt 3 = %v, want ErrTooManyAttempts", err)
+	}
+	if err := s.Verify(ctx, PurposeCaptcha, "id", code); err != ErrInvalid {
+		t.Fatalf("Verify() after too many attempts = %v, want ErrInvalid", err)
+	}
+
+	// 重新生成后失败次数清零
+	code, _ = s.Generate(ctx, PurposeCaptcha, "id")
+	_ = s.Verify(ctx, PurposeCaptcha, "id", "0")
+	if err := s.Verify(ctx, PurposeCaptcha, "id", code); err != nil {
+		t.Fatalf("Verify() regenerated = %v, want nil", err)
+	}
+}
+
+func TestService_VerifyAll(t *testing.T) {
+	redis.InitTestRedis()
+	ctx := context.Background()
+	s := New(Config{MaxAttempts: 2}, redis.RedisClient)
+
+	oldCode, _ := s.Generate(ctx, PurposeRebind, "13800000000")
+	newCode, _ := s.Generate(ctx, PurposeRebind, "13900000000")
+
+	// 新手机号输错时旧手机号的验证码不作废，也不增加失败次数
+	err := s.VerifyAll(ctx, PurposeRebind, map[string]string{"13800000000": oldCode, "13900000000": "0"})
+	if err != ErrInvalid {
+		t.Fatalf("VerifyAll() wrong new code = %v, want ErrInvalid", err)
+	}
+	err = s.VerifyAll(ctx, PurposeRebind, map[string]string{"13800000000": oldCode, "13900000000": "0"})
+	if err != ErrTooManyAttempts {
+		t.Fatalf("VerifyAll() wrong new code again = %v, want ErrTooManyAttempts", err)
+	}
+	// 新手机号的验证码已作废，旧手机号的还可以使用
+	if err := s.VerifyAll(ctx, PurposeRebind, map[string]string{"13800000000": oldCode, "13900000000": newCode}); err != ErrInvalid {
+		t.Fatalf("VerifyAll() after new code invalidated = %v, want ErrInvalid", err)
+	}
+	newCode, _ = s.Generate(ctx, PurposeRebind, "13900000000")
+	if err := s.VerifyAll(ctx, PurposeRebind, map[string]string{"13800000000": oldCode, "13900000000": newCode}); err != nil {
+		t.Fatalf("VerifyAll() = %v, want nil", err)
+	}
+	// 全部正确后一起作废
+	if err := s.Verify(ctx, PurposeRebind, "13800000000", oldCode); err != ErrInvalid {
+		t.Fatalf("Verify() used old code = %v, want ErrInvalid", err)
+	}
+}
+
+func TestService_SendDeliverFailed(t *testing.T) {
+	redis.InitTestRedis()
+	ctx := context.Background()
+	ch := &fakeChannel{err: errors.New("gateway error")}
+	s := New(Config{}, redis.RedisClient, ch)
+
+	if err := s.Send(ctx, "fake", PurposeLogin, "13800000000"); err != ch.err {
+		t.Fatalf("Send() = %v, want %v", err, ch.err)
+	}
+	// 发送失败时验证码作废，可以立即重新发送
+	if err := s.Verify(ctx, PurposeLogin, "13800000000", ch.code); err != ErrInvalid {
+		t.Fatalf("Verify() undelivered = %v, want ErrInvalid", err)
+	}
+	ch.err = nil
+	if err := s.Send(ctx, "fake", PurposeLogin, "13800000000"); err != nil {
+		t.Fatalf("Send() retry = %v, want nil", err)
+	}
+}
+
+func TestSMS_Deliver(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	tests := []struct {
+		name    string
+		phone   string
+		code    string
+		wantErr bool
+	}{
+		{"empty phone", "", "123456", true},
+		{"empty code", "13010102020", "", true},
+		// 未配置服务商时不发送
+		{"no provider", "13010102020", "123456", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SMS().Deliver(context.Background(), PurposeLogin, tt.phone, tt.code); (err != nil) != tt.wantErr {
+				t.Errorf("Deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 游标分页的请求参数
+const (
+	// QueryCursor 上一页返回的 next_cursor
+	QueryCursor = "cursor"
+	// QueryPageSize 每页条数，超出范围时按 Options 截断
+	QueryPageSize = "page_size"
+	// QueryLastID 旧版本客户端使用的上一页最后一条记录的id，没有 cursor 时使用
+	QueryLastID = "last_id"
+)
+
+// MaxID 按id倒序翻页时第一页的上界
+const MaxID = 0xffffffffffff
+
+// ErrInvalidCursor 游标格式错误，一般是客户端修改了游标
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// Cursor 游标，指向上一页的最后一条记录，下一页从这条记录之后开始，不包含这条记录
+// 对客户端不透明，编码为 base64 的 "id:毫秒时间戳"，客户端只需要原样传回
+type Cursor struct {
+	// ID 记录的id，按id倒序的列表从小于 ID 的记录开始
+	ID uint64
+	// Time 记录的创建时间，按时间排序的列表和 ID 一起作为排序值，零值编码为0
+	Time time.Time
+}
+
+// Encode 编码为 base64 字符串，零值返回空字符串
+func (c Cursor) Encode() string {
+	if c.ID == 0 {
+		return ""
+	}
+	var ts int64
+	if !c.Time.IsZero() {
+		ts = c.Time.UnixMilli()
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(c.ID, 10) + ":" + strconv.FormatInt(ts, 10)))
+}
+
+// DecodeCursor 解析游标，空字符串返回零值，表示第一页
+// id 必须大于0，时间戳不能为负数，格式不对时返回 ErrInvalidCursor
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || id == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	ts, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || ts < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	c := Cursor{ID: id}
+	if ts > 0 {
+		c.Time = time.UnixMilli(ts)
+	}
+	return c, nil
+}
+
+// BeforeID 按id倒序翻页时查询 id 小于返回值的记录，第一页 lastID 为0时返回 MaxID
+func BeforeID(lastID uint64) uint64 {
+	if lastID == 0 {
+		return MaxID
+	}
+	return lastID
+}
+
+// Options 每页条数的默认值和最大值
+type Options struct {
+	DefaultSize int
+	MaxSize     int
+}
+
+// Params 游标分页参数
+type Params struct {
+	Cursor Cursor
+	Limit  int
+}
+
+// LastID 上一页最后一条记录的id，第一页为0
+func (p Params) LastID() uint64 {
+	return p.Cursor.ID
+}
+
+// Parse 从请求参数中解析游标和每页条数，没有 cursor 时兼容 last_id
+func Parse(query url.Values, opts Options) (Params, error) {
+	c, err := DecodeCursor(query.Get(QueryCursor))
+	if err != nil {
+		return Params{}, err
+	}
+	if c.ID == 0 {
+		if id, err := strconv.ParseUint(query.Get(QueryLastID), 10, 64); err == nil {
+			c.ID = id
+		}
+	}
+	size, _ := strconv.Atoi(query.Get(QueryPageSize))
+	return Params{Cursor: c, Limit: Clamp(size, opts)}, nil
+}
+
+// Clamp 截断每页条数，小于等于0时使用默认值，超出最大值时使用最大值
+func Clamp(size int, opts Options) int {
+	if size <= 0 {
+		size = opts.DefaultSize
+	}
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		size = opts.MaxSize
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
+
+// Page 截取本页，items 需要比 limit 多查一条用来判断是否有下一页
+// 有下一页时返回本页最后一条记录的游标，否则返回空字符串
+func Page[T any](items []T, limit int, cursor func(T) Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, cursor(items[limit-1]).Encode()
+}
@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	c := Cursor{ID: 42, Time: time.UnixMilli(1700000000123)}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil || got.ID != c.ID || !got.Time.Equal(c.Time) {
+		t.Fatalf("DecodeCursor(Encode()) = %+v, %v, want %+v", got, err, c)
+	}
+	if got, err := DecodeCursor(""); err != nil || got.ID != 0 {
+		t.Fatalf("DecodeCursor(\"\") = %+v, %v, want zero", got, err)
+	}
+	if (Cursor{}).Encode() != "" {
+		t.Fatal("zero cursor should encode to empty string")
+	}
+	for _, s := range []string{"!!", "NDI", "MDow", "NDI6LTE", "NDI6MTo", "YWJjOjE"} {
+		if _, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) err = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	opts := Options{DefaultSize: 10, MaxSize: 50}
+	tests := []struct {
+		name    string
+		query   string
+		lastID  uint64
+		limit   int
+		wantErr bool
+	}{
+		{"first page", "", 0, 10, false},
+		{"cursor", "cursor=" + Cursor{ID: 7}.Encode() + "&page_size=20", 7, 20, false},
+		{"cursor before last_id", "cursor=" + Cursor{ID: 7}.Encode() + "&last_id=9", 7, 10, false},
+		{"legacy last_id", "last_id=9", 9, 10, false},
+		{"clamp max", "page_size=1000", 0, 50, false},
+		{"clamp negative", "page_size=-1", 0, 10, false},
+		{"invalid cursor", "cursor=abc", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			p, err := Parse(q, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if p.LastID() != tt.lastID || p.Limit != tt.limit {
+				t.Fatalf("Parse() = %d, %d, want %d, %d", p.LastID(), p.Limit, tt.lastID, tt.limit)
+			}
+		})
+	}
+}
+
+func TestBeforeID(t *testing.T) {
+	if got := BeforeID(0); got != MaxID {
+		t.Fatalf("BeforeID(0) = %d, want MaxID", got)
+	}
+	if got := BeforeID(9); got != 9 {
+		t.Fatalf("BeforeID(9) = %d, want 9", got)
+	}
+}
+
+func TestPage(t *testing.T) {
+	toCursor := func(id uint64) Cursor { return Cursor{ID: id} }
+	items, next := Page([]uint64{9, 8, 7}, 2, toCursor)
+	if len(items) != 2 || next != (Cursor{ID: 8}).Encode() {
+		t.Fatalf("Page() = %v, %q, want 2 items and cursor of 8", items, next)
+	}
+	items, next = Page([]uint64{9, 8}, 2, toCursor)
+	if len(items) != 2 || next != "" {
+		t.Fatalf("Page() last page = %v, %q, want no cursor", items, next)
+	}
+}
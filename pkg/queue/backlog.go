@@ -0,0 +1,39 @@
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBacklogUnsupported 队列驱动不支持统计积压消息数
+var ErrBacklogUnsupported = errors.New("queue: backlog unsupported")
+
+// Backlog 统计主题下等待消费的消息数，不包括延迟消息和死信消息
+type Backlog interface {
+	Backlog(ctx context.Context, topic string) (int64, error)
+}
+
+// Backlogs 获取默认队列的积压统计
+func Backlogs() (Backlog, error) {
+	b, ok := Default.(Backlog)
+	if !ok {
+		return nil, ErrBacklogUnsupported
+	}
+	return b, nil
+}
+
+// Backlog 主题对应 list 的长度
+func (q *redisQueue) Backlog(ctx context.Context, topic string) (int64, error) {
+	return q.client.LLen(q.key(topic)).Result()
+}
+
+// Backlog 主题下还没有投递给处理函数的消息数
+func (q *memoryQueue) Backlog(ctx context.Context, topic string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.topics[topic]
+	if !ok {
+		return 0, nil
+	}
+	return int64(len(t.msgs)), nil
+}
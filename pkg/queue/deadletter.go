@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMessageNotFound 死信消息不存在
+var ErrMessageNotFound = errors.New("queue: message not found")
+
+// ErrDeadLetterUnsupported 队列驱动不支持死信队列
+var ErrDeadLetterUnsupported = errors.New("queue: dead letter unsupported")
+
+// DeadTopic 有死信消息的主题
+type DeadTopic struct {
+	Topic string `json:"topic"`
+	Count int64  `json:"count"`
+}
+
+// DeadLetter 死信队列，超过最大重试次数的消息，按主题保存
+type DeadLetter interface {
+	// DeadTopics 所有有死信消息的主题
+	DeadTopics(ctx context.Context) ([]*DeadTopic, error)
+	// ListDead 按进入死信队列的时间倒序获取消息
+	ListDead(ctx context.Context, topic string, offset, limit int) ([]*Message, error)
+	// GetDead 获取死信消息，不存在时返回 ErrMessageNotFound
+	GetDead(ctx context.Context, topic, id string) (*Message, error)
+	// ReplayDead 重新投递消息，重试次数清零，ids 为空时重放主题下所有消息，返回重放的条数
+	ReplayDead(ctx context.Context, topic string, ids ...string) (int, error)
+	// DiscardDead 丢弃消息，返回丢弃的条数
+	DiscardDead(ctx context.Context, topic string, ids ...string) (int, error)
+}
+
+// DeadLetters 获取默认队列的死信队列
+func DeadLetters() (DeadLetter, error) {
+	dl, ok := Default.(DeadLetter)
+	if !ok {
+		return nil, ErrDeadLetterUnsupported
+	}
+	return dl, nil
+}
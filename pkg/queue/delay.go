@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDelayUnsupported 队列驱动不支持延迟消息
+var ErrDelayUnsupported = errors.New("queue: delay unsupported")
+
+// Delayer 延迟消息，到期后才能被消费
+type Delayer interface {
+	// PublishAt 发布消息，at 之后才投递给消费者，返回消息id
+	PublishAt(ctx context.Context, topic string, body interface{}, at time.Time) (string, error)
+}
+
+// PublishAt 发布延迟消息到默认的队列，返回消息id
+func PublishAt(ctx context.Context, topic string, body interface{}, at time.Time) (string, error) {
+	d, ok := Default.(Delayer)
+	if !ok {
+		return "", ErrDelayUnsupported
+	}
+	return d.PublishAt(ctx, topic, body, at)
+}
@@ -0,0 +1,241 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DeliverySync 发布时在发布者的 goroutine 中直接调用处理函数，Publish 返回时消息已处理完，用于单元测试
+	DeliverySync = "sync"
+	// DeliveryAsync 发布后由 Consume 所在的 goroutine 处理，和 redis 驱动的行为一致，用于本地开发
+	DeliveryAsync = "async"
+)
+
+// memoryTopic 主题下未处理的消息，同步模式下还保存处理函数
+type memoryTopic struct {
+	msgs    []*Message
+	notify  chan struct{}
+	handler Handler
+}
+
+// memoryQueue 进程内的队列，不需要外部依赖，进程退出后消息丢失
+type memoryQueue struct {
+	mu         sync.Mutex
+	mode       string
+	maxRetries int
+	topics     map[string]*memoryTopic
+	dead       map[string][]*Message
+}
+
+// NewMemoryQueue 实例化进程内的队列，mode 为空时使用 DeliveryAsync
+func NewMemoryQueue(maxRetries int, mode string) Queue {
+	if mode != DeliverySync {
+		mode = DeliveryAsync
+	}
+	return &memoryQueue{
+		mode:       mode,
+		maxRetries: maxRetries,
+		topics:     make(map[string]*memoryTopic),
+		dead:       make(map[string][]*Message),
+	}
+}
+
+// Publish 发布消息，同步模式下已有消费者时直接处理
+func (q *memoryQueue) Publish(ctx context.Context, topic string, body interface{}) error {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return err
+	}
+	return q.push(msg)
+}
+
+// PublishAt 发布延迟消息，到期后投递
+func (q *memoryQueue) PublishAt(ctx context.Context, topic string, body interface{}, at time.Time) (string, error) {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return "", err
+	}
+	time.AfterFunc(time.Until(at), func() {
+		_ = q.push(msg)
+	})
+	return msg.ID, nil
+}
+
+// Consume 阻塞消费消息，直到 ctx 被取消
+// 同步模式下每个主题只能有一个消费者，注册后先处理已发布的消息
+func (q *memoryQueue) Consume(ctx context.Context, topic string, handler Handler) error {
+	if q.mode == DeliverySync {
+		q.mu.Lock()
+		t := q.topic(topic)
+		t.handler = handler
+		msgs := t.msgs
+		t.msgs = nil
+		q.mu.Unlock()
+
+		for _, msg := range msgs {
+			_ = process(ctx, msg, handler, q.maxRetries, q.push, q.bury)
+		}
+		<-ctx.Done()
+
+		q.mu.Lock()
+		t.handler = nil
+		q.mu.Unlock()
+		return nil
+	}
+
+	for {
+		msg, notify := q.pop(topic)
+		if msg == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-notify:
+			}
+			continue
+		}
+		_ = process(ctx, msg, handler, q.maxRetries, q.push, q.bury)
+	}
+}
+
+// pop 取出最早的消息，没有消息时返回新消息的通知
+func (q *memoryQueue) pop(topic string) (*Message, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t := q.topic(topic)
+	if len(t.msgs) == 0 {
+		return nil, t.notify
+	}
+	msg := t.msgs[0]
+	t.msgs = t.msgs[1:]
+	return msg, nil
+}
+
+func (q *memoryQueue) push(msg *Message) error {
+	q.mu.Lock()
+	t := q.topic(msg.Topic)
+	handler := t.handler
+	if handler == nil {
+		t.msgs = append(t.msgs, msg)
+		select {
+		case t.notify <- struct{}{}:
+		default:
+		}
+	}
+	q.mu.Unlock()
+
+	// 同步模式下失败的消息通过 push 重试，直到成功或进入死信队列
+	if handler != nil {
+		return process(context.Background(), msg, handler, q.maxRetries, q.push, q.bury)
+	}
+	return nil
+}
+
+// topic 需要持有锁
+func (q *memoryQueue) topic(name string) *memoryTopic {
+	t, ok := q.topics[name]
+	if !ok {
+		t = &memoryTopic{notify: make(chan struct{}, 1)}
+		q.topics[name] = t
+	}
+	return t
+}
+
+func (q *memoryQueue) bury(msg *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg.DeadAt = time.Now().Unix()
+	q.dead[msg.Topic] = append(q.dead[msg.Topic], msg)
+	return nil
+}
+
+// DeadTopics 所有有死信消息的主题
+func (q *memoryQueue) DeadTopics(ctx context.Context) ([]*DeadTopic, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ret := make([]*DeadTopic, 0, len(q.dead))
+	for topic, msgs := range q.dead {
+		if len(msgs) > 0 {
+			ret = append(ret, &DeadTopic{Topic: topic, Count: int64(len(msgs))})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Topic < ret[j].Topic })
+	return ret, nil
+}
+
+// ListDead 按进入死信队列的时间倒序获取消息
+func (q *memoryQueue) ListDead(ctx context.Context, topic string, offset, limit int) ([]*Message, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.dead[topic]
+	ret := make([]*Message, 0, limit)
+	for i := len(msgs) - 1 - offset; i >= 0 && len(ret) < limit; i-- {
+		ret = append(ret, msgs[i])
+	}
+	return ret, nil
+}
+
+// GetDead 获取死信消息
+func (q *memoryQueue) GetDead(ctx context.Context, topic, id string) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, msg := range q.dead[topic] {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// ReplayDead 重新投递消息，重试次数清零
+func (q *memoryQueue) ReplayDead(ctx context.Context, topic string, ids ...string) (int, error) {
+	msgs := q.remove(topic, ids)
+	for _, msg := range msgs {
+		msg.Attempts = 0
+		msg.DeadAt = 0
+		if err := q.push(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(msgs), nil
+}
+
+// DiscardDead 丢弃消息
+func (q *memoryQueue) DiscardDead(ctx context.Context, topic string, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return len(q.remove(topic, ids)), nil
+}
+
+// remove 从死信队列中移除消息，ids 为空时移除主题下所有消息
+func (q *memoryQueue) remove(topic string, ids []string) []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(ids) == 0 {
+		msgs := q.dead[topic]
+		delete(q.dead, topic)
+		return msgs
+	}
+
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	removed := make([]*Message, 0, len(ids))
+	kept := make([]*Message, 0, len(q.dead[topic]))
+	for _, msg := range q.dead[topic] {
+		if want[msg.ID] {
+			removed = append(removed, msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+	q.dead[topic] = kept
+	return removed
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryQueue_Sync(t *testing.T) {
+	q := NewMemoryQueue(1, DeliverySync)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 消费者注册前发布的消息在注册后处理
+	if err := q.Publish(ctx, "sync", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]int, 0)
+	ready := make(chan struct{})
+	go func() {
+		_ = q.Consume(ctx, "sync", func(ctx context.Context, msg *Message) error {
+			var p struct{ N int }
+			if err := msg.Decode(&p); err != nil {
+				return err
+			}
+			got = append(got, p.N)
+			if p.N == 1 {
+				close(ready)
+			}
+			return nil
+		})
+	}()
+	<-ready
+
+	// Publish 返回时消息已经处理完
+	if err := q.Publish(ctx, "sync", map[string]int{"n": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+}
+
+func TestMemoryQueue_Async(t *testing.T) {
+	q := NewMemoryQueue(3, DeliveryAsync)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attempts := 0
+	done := make(chan int, 1)
+	go func() {
+		_ = q.Consume(ctx, "async", func(ctx context.Context, msg *Message) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("temporary err")
+			}
+			done <- msg.Attempts
+			return nil
+		})
+	}()
+	if err := q.Publish(ctx, "async", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-done:
+		if n != 1 {
+			t.Errorf("got attempts %d, want 1", n)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestMemoryQueue_DeadLetter(t *testing.T) {
+	q := NewMemoryQueue(1, DeliverySync).(*memoryQueue)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fail := true
+	handled := make(chan struct{}, 10)
+	go func() {
+		_ = q.Consume(ctx, "dead", func(ctx context.Context, msg *Message) error {
+			defer func() { handled <- struct{}{} }()
+			if fail {
+				return errors.New("permanent err")
+			}
+			return nil
+		})
+	}()
+	// 等待消费者注册
+	for {
+		q.mu.Lock()
+		registered := q.topic("dead").handler != nil
+		q.mu.Unlock()
+		if registered {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Publish(ctx, "dead", "b"); err != nil {
+		t.Fatal(err)
+	}
+	topics, _ := q.DeadTopics(ctx)
+	if len(topics) != 1 || topics[0].Count != 1 {
+		t.Fatalf("dead topics got %+v", topics)
+	}
+	msgs, _ := q.ListDead(ctx, "dead", 0, 10)
+	if len(msgs) != 1 || msgs[0].Attempts != 2 {
+		t.Fatalf("dead messages got %+v", msgs)
+	}
+
+	fail = false
+	n, err := q.ReplayDead(ctx, "dead")
+	if err != nil || n != 1 {
+		t.Fatalf("replay got %d, %v", n, err)
+	}
+	if _, err := q.GetDead(ctx, "dead", msgs[0].ID); err != ErrMessageNotFound {
+		t.Errorf("replayed message should be removed, got %v", err)
+	}
+}
+
+func TestMemoryQueue_PublishAt(t *testing.T) {
+	q := NewMemoryQueue(1, DeliveryAsync).(*memoryQueue)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := q.PublishAt(ctx, "delay", "c", time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := q.pop("delay"); msg != nil {
+		t.Fatal("delayed message should not be delivered before due")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = q.Consume(ctx, "delay", func(ctx context.Context, msg *Message) error {
+			close(done)
+			return nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for delayed message")
+	}
+}
+
+func TestMemoryQueue_Backlog(t *testing.T) {
+	q := NewMemoryQueue(1, DeliveryAsync)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := q.Publish(ctx, "backlog", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := q.(Backlog)
+	if n, _ := b.Backlog(ctx, "backlog"); n != 3 {
+		t.Fatalf("Backlog = %d, want 3", n)
+	}
+	if n, _ := b.Backlog(ctx, "unknown"); n != 0 {
+		t.Fatalf("Backlog(unknown) = %d, want 0", n)
+	}
+}
@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/1024casts/snake/pkg/nonce"
+)
+
+const (
+	idempotencyProcessing = "processing"
+	idempotencyDone       = "done"
+
+	// idempotencyLockTTL 处理中的标记的有效期，消费者崩溃后超过该时间消息可以被再次处理
+	idempotencyLockTTL = 5 * time.Minute
+)
+
+// ErrMessageProcessing 相同的消息正在被其他消费者处理，返回后消息会稍后重试
+var ErrMessageProcessing = errors.New("queue: message is processing")
+
+// Middleware 消费中间件，包装 Handler 增加通用的处理逻辑
+type Middleware func(Handler) Handler
+
+// Chain 按顺序应用中间件，第一个中间件在最外层
+func Chain(handler Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
+
+// Idempotent 消费幂等，记录处理成功的消息id，ttl 内重复投递的消息直接跳过
+// 处理前先标记为处理中，失败时清除标记，让重试可以再次处理
+func Idempotent(store nonce.Store, ttl time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			key := "queue:" + msg.Topic + ":" + msg.ID
+			ok, err := store.SetNX(key, idempotencyProcessing, idempotencyLockTTL)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				val, err := store.Get(key)
+				if err != nil {
+					return err
+				}
+				if val == idempotencyDone {
+					return nil
+				}
+				return ErrMessageProcessing
+			}
+
+			if err := next(ctx, msg); err != nil {
+				if delErr := store.Del(key); delErr != nil {
+					return delErr
+				}
+				return err
+			}
+			return store.Set(key, idempotencyDone, ttl)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/nonce"
+)
+
+func TestIdempotent(t *testing.T) {
+	calls := 0
+	fail := true
+	handler := Chain(func(ctx context.Context, msg *Message) error {
+		calls++
+		if fail {
+			return errors.New("temporary err")
+		}
+		return nil
+	}, Idempotent(nonce.NewMemoryStore("test"), time.Hour))
+
+	msg, _ := NewMessage("counter", map[string]int{"step": 1})
+	ctx := context.Background()
+
+	// 失败后重试可以再次处理
+	if err := handler(ctx, msg); err == nil {
+		t.Fatal("want err")
+	}
+	fail = false
+	if err := handler(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	// 重复投递直接跳过
+	if err := handler(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,171 @@
+// Package queue 异步消息队列，Producer 发布消息，Consumer 按主题消费
+// 处理失败的消息会重试，超过最大重试次数或错误不可重试时进入死信队列，可以查看后重放或丢弃
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// DriverRedis 基于 redis list
+	DriverRedis = "redis"
+	// DriverRedisStream 基于 redis stream，消费者崩溃时已读取的消息不会丢失
+	DriverRedisStream = "redis_stream"
+	// DriverMemory 进程内的队列，用于单元测试和本地开发
+	DriverMemory = "memory"
+
+	defaultMaxRetries = 3
+)
+
+// ErrUnknownDriver 未知的队列驱动
+var ErrUnknownDriver = errors.New("queue: unknown driver")
+
+// Default 默认的队列
+var Default Queue
+
+// Message 消息
+type Message struct {
+	ID        string          `json:"id"`
+	Topic     string          `json:"topic"`
+	Body      json.RawMessage `json:"body"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt int64           `json:"created_at"`
+	// Errors 每次处理失败的错误，重放后保留
+	Errors []*MessageError `json:"errors,omitempty"`
+	// DeadAt 进入死信队列的时间
+	DeadAt int64 `json:"dead_at,omitempty"`
+}
+
+// MessageError 处理失败的记录
+type MessageError struct {
+	Attempt int    `json:"attempt"`
+	Error   string `json:"error"`
+	Time    int64  `json:"time"`
+}
+
+// NewMessage 创建消息，body 会被序列化为json
+func NewMessage(topic string, body interface{}) (*Message, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		ID:        uuid.New().String(),
+		Topic:     topic,
+		Body:      b,
+		CreatedAt: time.Now().Unix(),
+	}, nil
+}
+
+// Decode 反序列化消息内容
+func (m *Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Body, v)
+}
+
+// Handler 消息处理函数，返回可重试的错误时消息会被重试，见 errno.IsRetryable
+type Handler func(ctx context.Context, msg *Message) error
+
+// process 处理单条消息，handler 发生 panic 时按失败处理
+// 失败后可以重试的消息通过 requeue 重新投递，超过最大重试次数或不可重试的消息通过 bury 进入死信队列
+// 返回重新投递或进入死信队列时的错误，消息没有被保存下来
+func process(ctx context.Context, msg *Message, handler Handler, maxRetries int, requeue, bury func(*Message) error) error {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return handler(ctx, msg)
+	}()
+	if err == nil {
+		return nil
+	}
+
+	msg.Attempts++
+	msg.Errors = append(msg.Errors, &MessageError{Attempt: msg.Attempts, Error: err.Error(), Time: time.Now().Unix()})
+	// 参数有误、资源不存在等错误重试也不会成功，直接进入死信队列
+	if !errno.IsRetryable(err) || msg.Attempts > maxRetries {
+		log.Errorf("[queue] message dead after %d attempts, kind: %s, err: %v, topic: %s, id: %s, body: %s",
+			msg.Attempts, errno.KindOf(err), err, msg.Topic, msg.ID, msg.Body)
+		if err := bury(msg); err != nil {
+			log.Errorf("[queue] save dead message err: %v, topic: %s, id: %s", err, msg.Topic, msg.ID)
+			return err
+		}
+		return nil
+	}
+	log.Warnf("[queue] handle message err: %v, topic: %s, id: %s, attempts: %d", err, msg.Topic, msg.ID, msg.Attempts)
+	if err := requeue(msg); err != nil {
+		log.Errorf("[queue] requeue message err: %v, topic: %s, id: %s", err, msg.Topic, msg.ID)
+		return err
+	}
+	return nil
+}
+
+// Producer 生产者
+type Producer interface {
+	Publish(ctx context.Context, topic string, body interface{}) error
+}
+
+// Consumer 消费者
+type Consumer interface {
+	// Consume 阻塞消费主题下的消息，直到 ctx 被取消
+	Consume(ctx context.Context, topic string, handler Handler) error
+}
+
+// Queue 同时支持生产和消费
+type Queue interface {
+	Producer
+	Consumer
+}
+
+// Init 按配置初始化默认的队列
+func Init() Queue {
+	q, err := New(viper.GetString("queue.driver"))
+	if err != nil {
+		panic(err)
+	}
+	Default = q
+	return Default
+}
+
+// New 按驱动名称实例化队列，默认使用 redis
+func New(driver string) (Queue, error) {
+	maxRetries := viper.GetInt("queue.max_retries")
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	switch driver {
+	case "", DriverRedis:
+		return NewRedisQueue(redis.RedisClient, maxRetries), nil
+	case DriverRedisStream:
+		return NewRedisStreamQueue(redis.RedisClient, maxRetries, StreamOptions{
+			Group:     viper.GetString("queue.stream.group"),
+			Consumer:  viper.GetString("queue.stream.consumer"),
+			MaxLen:    viper.GetInt64("queue.stream.max_len"),
+			ClaimIdle: viper.GetDuration("queue.stream.claim_idle"),
+		}), nil
+	case DriverMemory:
+		return NewMemoryQueue(maxRetries, viper.GetString("queue.memory.mode")), nil
+	}
+	return nil, ErrUnknownDriver
+}
+
+// Publish 发布消息到默认的队列
+func Publish(ctx context.Context, topic string, body interface{}) error {
+	if Default == nil {
+		return errors.New("queue: not initialized")
+	}
+	return Default.Publish(ctx, topic, body)
+}
@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	redis.InitTestRedis()
+	m.Run()
+}
+
+func TestRedisQueue(t *testing.T) {
+	q := NewRedisQueue(redis.RedisClient, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type payload struct {
+		Key string `json:"key"`
+	}
+	if err := q.Publish(ctx, "test", payload{Key: "a.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 前两次处理失败，第三次成功
+	attempts := 0
+	done := make(chan string, 1)
+	go func() {
+		_ = q.Consume(ctx, "test", func(ctx context.Context, msg *Message) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("temporary err")
+			}
+			var p payload
+			if err := msg.Decode(&p); err != nil {
+				return err
+			}
+			done <- p.Key
+			return nil
+		})
+	}()
+
+	select {
+	case key := <-done:
+		if key != "a.png" {
+			t.Errorf("got key %s, want a.png", key)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestRedisQueue_DeadLetter(t *testing.T) {
+	q := NewRedisQueue(redis.RedisClient, 0).(*redisQueue)
+	ctx := context.Background()
+
+	msg, err := NewMessage("dead", map[string]string{"key": "b.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.handle(ctx, msg, func(ctx context.Context, msg *Message) error {
+		return errors.New("permanent err")
+	})
+
+	topics, err := q.DeadTopics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 1 || topics[0].Topic != "dead" || topics[0].Count != 1 {
+		t.Fatalf("dead topics got %+v", topics)
+	}
+	dead, err := q.GetDead(ctx, "dead", msg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead.Errors) != 1 || dead.Errors[0].Error != "permanent err" {
+		t.Errorf("dead message errors got %+v", dead.Errors)
+	}
+
+	n, err := q.ReplayDead(ctx, "dead")
+	if err != nil || n != 1 {
+		t.Fatalf("replay got %d, %v", n, err)
+	}
+	if _, err := q.GetDead(ctx, "dead", msg.ID); err != ErrMessageNotFound {
+		t.Errorf("replayed message should be removed, got %v", err)
+	}
+	l, _ := redis.RedisClient.LLen(q.key("dead")).Result()
+	if l != 1 {
+		t.Errorf("replayed message should be requeued, queue len %d", l)
+	}
+}
+
+func TestRedisQueue_NotRetryable(t *testing.T) {
+	q := NewRedisQueue(redis.RedisClient, 3).(*redisQueue)
+	ctx := context.Background()
+
+	msg, err := NewMessage("invalid", map[string]string{"key": "c.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.handle(ctx, msg, func(ctx context.Context, msg *Message) error {
+		return errno.ErrTaskNotFound
+	})
+
+	// 资源不存在重试也不会成功，第一次失败就进入死信队列
+	dead, err := q.GetDead(ctx, "invalid", msg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead.Attempts != 1 {
+		t.Errorf("dead message attempts got %d, want 1", dead.Attempts)
+	}
+	l, _ := redis.RedisClient.LLen(q.key("invalid")).Result()
+	if l != 0 {
+		t.Errorf("not retryable message should not be requeued, queue len %d", l)
+	}
+}
+
+func TestRedisQueue_PublishAt(t *testing.T) {
+	q := NewRedisQueue(redis.RedisClient, 2).(*redisQueue)
+	ctx := context.Background()
+
+	if _, err := q.PublishAt(ctx, "test_delay", "later", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := q.PublishAt(ctx, "test_delay", "now", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只投递到期的消息
+	n, err := q.moveDue("test_delay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("got %d due messages, want 1", n)
+	}
+	ret, err := redis.RedisClient.RPop(q.key("test_delay")).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{}
+	if err := json.Unmarshal([]byte(ret), msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != id {
+		t.Errorf("got message %s, want %s", msg.ID, id)
+	}
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// PrefixQueueKey 队列key前缀
+const PrefixQueueKey = "snake:queue"
+
+// popTimeout 阻塞读取的超时时间，超时后检查 ctx 是否被取消
+const popTimeout = time.Second
+
+// redisQueue 基于 redis list 的队列，LPUSH 写入，BRPOP 读取
+type redisQueue struct {
+	*redisDeadLetter
+	client     *redis.Client
+	maxRetries int
+}
+
+// NewRedisQueue 实例化 redis 队列
+func NewRedisQueue(client *redis.Client, maxRetries int) Queue {
+	q := &redisQueue{client: client, maxRetries: maxRetries}
+	q.redisDeadLetter = &redisDeadLetter{client: client, push: q.push}
+	return q
+}
+
+// Publish 发布消息
+func (q *redisQueue) Publish(ctx context.Context, topic string, body interface{}) error {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return err
+	}
+	return q.push(msg)
+}
+
+// Consume 阻塞消费消息，处理失败时重新放回队列，超过最大重试次数后进入死信队列
+func (q *redisQueue) Consume(ctx context.Context, topic string, handler Handler) error {
+	key := q.key(topic)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		// 阻塞读取最多等待 popTimeout，延迟消息的投递误差也在这个范围内
+		if _, err := q.moveDue(topic); err != nil {
+			log.Warnf("[queue] move due delayed messages err: %v, topic: %s", err, topic)
+		}
+
+		ret, err := q.client.BRPop(popTimeout, key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Warnf("[queue] brpop err: %v, topic: %s", err, topic)
+			time.Sleep(popTimeout)
+			continue
+		}
+
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(ret[1]), msg); err != nil {
+			log.Errorf("[queue] unmarshal message err: %v, topic: %s, data: %s", err, topic, ret[1])
+			continue
+		}
+		q.handle(ctx, msg, handler)
+	}
+}
+
+// handle 处理单条消息，消息已经出队，重新投递或进入死信队列失败时只能记录日志
+func (q *redisQueue) handle(ctx context.Context, msg *Message, handler Handler) {
+	_ = process(ctx, msg, handler, q.maxRetries, q.push, q.bury)
+}
+
+func (q *redisQueue) push(msg *Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(q.key(msg.Topic), b).Err()
+}
+
+func (q *redisQueue) key(topic string) string {
+	return fmt.Sprintf("%s:%s", PrefixQueueKey, topic)
+}
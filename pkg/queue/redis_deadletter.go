@@ -0,0 +1,192 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 死信队列按主题保存，hash 存消息内容，有序集合按进入时间排序，另有一个集合记录所有主题
+const (
+	deadPrefix    = PrefixQueueKey + ":dead"
+	deadTopicsKey = deadPrefix + ":topics"
+
+	replayBatchSize = 100
+)
+
+// redisDeadLetter 基于 redis 的死信队列，list 和 stream 驱动共用，重放时通过 push 重新投递
+type redisDeadLetter struct {
+	client *redis.Client
+	push   func(msg *Message) error
+}
+
+// bury 保存到死信队列
+func (q *redisDeadLetter) bury(msg *Message) error {
+	msg.DeadAt = time.Now().Unix()
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	pipe := q.client.TxPipeline()
+	pipe.HSet(q.deadKey(msg.Topic), msg.ID, b)
+	pipe.ZAdd(q.deadIndexKey(msg.Topic), redis.Z{Score: float64(msg.DeadAt), Member: msg.ID})
+	pipe.SAdd(deadTopicsKey, msg.Topic)
+	_, err = pipe.Exec()
+	return err
+}
+
+// DeadTopics 所有有死信消息的主题
+func (q *redisDeadLetter) DeadTopics(ctx context.Context) ([]*DeadTopic, error) {
+	topics, err := q.client.SMembers(deadTopicsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*DeadTopic, 0, len(topics))
+	for _, topic := range topics {
+		count, err := q.client.ZCard(q.deadIndexKey(topic)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			continue
+		}
+		ret = append(ret, &DeadTopic{Topic: topic, Count: count})
+	}
+	return ret, nil
+}
+
+// ListDead 按进入死信队列的时间倒序获取消息
+func (q *redisDeadLetter) ListDead(ctx context.Context, topic string, offset, limit int) ([]*Message, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	ids, err := q.client.ZRevRange(q.deadIndexKey(topic), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return q.getDead(topic, ids)
+}
+
+// GetDead 获取死信消息
+func (q *redisDeadLetter) GetDead(ctx context.Context, topic, id string) (*Message, error) {
+	msgs, err := q.getDead(topic, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrMessageNotFound
+	}
+	return msgs[0], nil
+}
+
+// ReplayDead 先投递再从死信队列删除，投递失败的消息保留在死信队列
+func (q *redisDeadLetter) ReplayDead(ctx context.Context, topic string, ids ...string) (int, error) {
+	if len(ids) > 0 {
+		return q.replay(topic, ids)
+	}
+
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		ids, err := q.client.ZRange(q.deadIndexKey(topic), 0, replayBatchSize-1).Result()
+		if err != nil {
+			return count, err
+		}
+		if len(ids) == 0 {
+			return count, nil
+		}
+		n, err := q.replay(topic, ids)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// DiscardDead 丢弃消息
+func (q *redisDeadLetter) DiscardDead(ctx context.Context, topic string, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	n, err := q.client.HDel(q.deadKey(topic), ids...).Result()
+	if err != nil {
+		return 0, err
+	}
+	members := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id)
+	}
+	if err := q.client.ZRem(q.deadIndexKey(topic), members...).Err(); err != nil {
+		return int(n), err
+	}
+	return int(n), nil
+}
+
+func (q *redisDeadLetter) replay(topic string, ids []string) (int, error) {
+	msgs, err := q.getDead(topic, ids)
+	if err != nil {
+		return 0, err
+	}
+	// 只有索引没有内容的消息直接清理，避免重放全部时死循环
+	if len(msgs) < len(ids) {
+		members := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			members = append(members, id)
+		}
+		if err := q.client.ZRem(q.deadIndexKey(topic), members...).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, msg := range msgs {
+		msg.Attempts = 0
+		msg.DeadAt = 0
+		if err := q.push(msg); err != nil {
+			return count, err
+		}
+		if _, err := q.DiscardDead(context.Background(), topic, msg.ID); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// getDead 批量获取消息，不存在的会被忽略
+func (q *redisDeadLetter) getDead(topic string, ids []string) ([]*Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := q.client.HMGet(q.deadKey(topic), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*Message, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(s), msg); err != nil {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (q *redisDeadLetter) deadKey(topic string) string {
+	return deadPrefix + ":" + topic
+}
+
+func (q *redisDeadLetter) deadIndexKey(topic string) string {
+	return deadPrefix + ":" + topic + ":index"
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// delayBatch 每次最多投递的到期消息数
+const delayBatch = 100
+
+// 把到期的消息从 zset 移到队列，多个消费者同时执行时不会重复投递
+var moveDueScript = redis.NewScript(`
+local items = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call("ZREM", KEYS[1], item)
+	redis.call("LPUSH", KEYS[2], item)
+end
+return #items
+`)
+
+// PublishAt 发布延迟消息，保存在按投递时间排序的 zset 中，消费时把到期的消息移到队列
+func (q *redisQueue) PublishAt(ctx context.Context, topic string, body interface{}, at time.Time) (string, error) {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	err = q.client.ZAdd(q.delayKey(topic), redis.Z{Score: float64(at.UnixNano() / int64(time.Millisecond)), Member: b}).Err()
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// moveDue 投递到期的延迟消息
+func (q *redisQueue) moveDue(topic string) (int64, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	return moveDueScript.Run(q.client, []string{q.delayKey(topic), q.key(topic)}, now, delayBatch).Int64()
+}
+
+func (q *redisQueue) delayKey(topic string) string {
+	return fmt.Sprintf("%s:delayed:%s", PrefixQueueKey, topic)
+}
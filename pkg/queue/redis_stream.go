@@ -0,0 +1,288 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const (
+	// streamField 消息内容保存在 stream 条目的该字段中
+	streamField = "message"
+
+	// streamReadCount 每次最多读取的消息数
+	streamReadCount = 10
+	// streamClaimCount 每次最多认领的消息数
+	streamClaimCount = 100
+
+	defaultStreamGroup     = "snake"
+	defaultStreamMaxLen    = 100000
+	defaultStreamClaimIdle = 5 * time.Minute
+)
+
+// 把到期的延迟消息从 zset 移到 stream
+var moveDueStreamScript = redis.NewScript(`
+local items = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call("ZREM", KEYS[1], item)
+	redis.call("XADD", KEYS[2], "MAXLEN", "~", ARGV[3], "*", "message", item)
+end
+return #items
+`)
+
+// StreamOptions redis stream 队列的配置
+type StreamOptions struct {
+	// Group 消费组，同一个组内的消费者分摊消息
+	Group string
+	// Consumer 消费者名称，同一个组内需要唯一，默认为 hostname-pid
+	Consumer string
+	// MaxLen stream 保留的最大条数(近似)，超过后最早的消息被删除，需要远大于积压的消息数
+	MaxLen int64
+	// ClaimIdle 消费者崩溃后，已读取但超过该时间没有确认的消息被其他消费者认领
+	ClaimIdle time.Duration
+}
+
+// streamQueue 基于 redis stream 的队列，使用消费组读取，处理完成后确认
+// 处理失败的消息重新写入 stream 并确认原消息，消费者崩溃时未确认的消息由其他消费者认领
+type streamQueue struct {
+	*redisDeadLetter
+	client     *redis.Client
+	maxRetries int
+	opts       StreamOptions
+}
+
+// NewRedisStreamQueue 实例化 redis stream 队列
+func NewRedisStreamQueue(client *redis.Client, maxRetries int, opts StreamOptions) Queue {
+	if opts.Group == "" {
+		opts.Group = defaultStreamGroup
+	}
+	if opts.Consumer == "" {
+		hostname, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = defaultStreamMaxLen
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = defaultStreamClaimIdle
+	}
+
+	q := &streamQueue{client: client, maxRetries: maxRetries, opts: opts}
+	q.redisDeadLetter = &redisDeadLetter{client: client, push: q.push}
+	return q
+}
+
+// Publish 发布消息
+func (q *streamQueue) Publish(ctx context.Context, topic string, body interface{}) error {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return err
+	}
+	return q.push(msg)
+}
+
+// PublishAt 发布延迟消息，到期后由消费者移到 stream
+func (q *streamQueue) PublishAt(ctx context.Context, topic string, body interface{}, at time.Time) (string, error) {
+	msg, err := NewMessage(topic, body)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	err = q.client.ZAdd(q.delayKey(topic), redis.Z{Score: float64(at.UnixNano() / int64(time.Millisecond)), Member: b}).Err()
+	if err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// Consume 阻塞消费消息，消费组不存在时自动创建，定期认领其他消费者超时未确认的消息
+func (q *streamQueue) Consume(ctx context.Context, topic string, handler Handler) error {
+	stream := q.key(topic)
+	if err := q.createGroup(stream); err != nil {
+		return err
+	}
+
+	var lastClaim time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if _, err := q.moveDue(topic); err != nil {
+			log.Warnf("[queue] move due delayed messages err: %v, topic: %s", err, topic)
+		}
+		if time.Since(lastClaim) >= q.opts.ClaimIdle/2 {
+			if err := q.claim(ctx, stream, handler); err != nil {
+				log.Warnf("[queue] claim pending messages err: %v, topic: %s", err, topic)
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := q.client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    q.opts.Group,
+			Consumer: q.opts.Consumer,
+			Streams:  []string{stream, ">"},
+			Count:    streamReadCount,
+			Block:    popTimeout,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Warnf("[queue] xreadgroup err: %v, topic: %s", err, topic)
+			time.Sleep(popTimeout)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, x := range s.Messages {
+				q.handle(ctx, stream, x, handler)
+			}
+		}
+	}
+}
+
+// claim 认领超过 ClaimIdle 没有确认的消息并处理，投递次数超过最大重试次数的消息直接进入死信队列，
+// 避免导致消费者崩溃的消息被反复认领
+func (q *streamQueue) claim(ctx context.Context, stream string, handler Handler) error {
+	pending, err := q.client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  q.opts.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  streamClaimCount,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		if p.Idle >= q.opts.ClaimIdle {
+			ids = append(ids, p.Id)
+			deliveries[p.Id] = p.RetryCount
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	msgs, err := q.client.XClaim(&redis.XClaimArgs{
+		Stream:   stream,
+		Group:    q.opts.Group,
+		Consumer: q.opts.Consumer,
+		MinIdle:  q.opts.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, x := range msgs {
+		log.Warnf("[queue] claimed pending message, stream: %s, entry: %s, deliveries: %d", stream, x.ID, deliveries[x.ID])
+		if deliveries[x.ID] > int64(q.maxRetries) {
+			q.buryEntry(stream, x)
+			continue
+		}
+		q.handle(ctx, stream, x, handler)
+	}
+	return nil
+}
+
+// handle 处理单条消息，消息重新投递或进入死信队列后才确认，失败时由其他消费者认领后再处理
+func (q *streamQueue) handle(ctx context.Context, stream string, x redis.XMessage, handler Handler) {
+	msg, ok := q.decode(stream, x)
+	if !ok {
+		q.ack(stream, x.ID)
+		return
+	}
+	if err := process(ctx, msg, handler, q.maxRetries, q.push, q.bury); err != nil {
+		return
+	}
+	q.ack(stream, x.ID)
+}
+
+// buryEntry 把消息直接放入死信队列
+func (q *streamQueue) buryEntry(stream string, x redis.XMessage) {
+	msg, ok := q.decode(stream, x)
+	if !ok {
+		q.ack(stream, x.ID)
+		return
+	}
+	msg.Attempts++
+	msg.Errors = append(msg.Errors, &MessageError{Attempt: msg.Attempts, Error: "delivered too many times without ack", Time: time.Now().Unix()})
+	if err := q.bury(msg); err != nil {
+		log.Errorf("[queue] save dead message err: %v, topic: %s, id: %s", err, msg.Topic, msg.ID)
+		return
+	}
+	q.ack(stream, x.ID)
+}
+
+// decode 解析消息，格式有误的消息记录日志后丢弃
+func (q *streamQueue) decode(stream string, x redis.XMessage) (*Message, bool) {
+	val, _ := x.Values[streamField].(string)
+	msg := &Message{}
+	if err := json.Unmarshal([]byte(val), msg); err != nil {
+		log.Errorf("[queue] unmarshal message err: %v, stream: %s, entry: %s, data: %s", err, stream, x.ID, val)
+		return nil, false
+	}
+	return msg, true
+}
+
+// ack 确认并删除消息，stream 中只保留未处理的消息
+func (q *streamQueue) ack(stream, id string) {
+	pipe := q.client.TxPipeline()
+	pipe.XAck(stream, q.opts.Group, id)
+	pipe.XDel(stream, id)
+	if _, err := pipe.Exec(); err != nil {
+		log.Warnf("[queue] ack message err: %v, stream: %s, entry: %s", err, stream, id)
+	}
+}
+
+// createGroup 创建消费组，已存在时忽略，新建的组从头开始消费
+func (q *streamQueue) createGroup(stream string) error {
+	err := q.client.XGroupCreateMkStream(stream, q.opts.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func (q *streamQueue) push(msg *Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return q.client.XAdd(&redis.XAddArgs{
+		Stream:       q.key(msg.Topic),
+		MaxLenApprox: q.opts.MaxLen,
+		Values:       map[string]interface{}{streamField: b},
+	}).Err()
+}
+
+// moveDue 投递到期的延迟消息
+func (q *streamQueue) moveDue(topic string) (int64, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	return moveDueStreamScript.Run(q.client, []string{q.delayKey(topic), q.key(topic)}, now, delayBatch, q.opts.MaxLen).Int64()
+}
+
+func (q *streamQueue) key(topic string) string {
+	return fmt.Sprintf("%s:stream:%s", PrefixQueueKey, topic)
+}
+
+func (q *streamQueue) delayKey(topic string) string {
+	return fmt.Sprintf("%s:delayed:%s", PrefixQueueKey, topic)
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// miniredis 不支持 stream，设置 SNAKE_TEST_REDIS_ADDR 后使用真实的 redis 测试
+func newTestStreamClient(t *testing.T) *redis.Client {
+	addr := os.Getenv("SNAKE_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("SNAKE_TEST_REDIS_ADDR not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	keys, _ := client.Keys(PrefixQueueKey + ":*").Result()
+	if len(keys) > 0 {
+		client.Del(keys...)
+	}
+	return client
+}
+
+func TestStreamQueue(t *testing.T) {
+	client := newTestStreamClient(t)
+	q := NewRedisStreamQueue(client, 2, StreamOptions{Consumer: "c1"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := q.Publish(ctx, "stream", map[string]string{"key": "a.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次处理失败，重新写入 stream 后第二次成功
+	attempts := 0
+	done := make(chan int, 1)
+	go func() {
+		_ = q.Consume(ctx, "stream", func(ctx context.Context, msg *Message) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("temporary err")
+			}
+			done <- msg.Attempts
+			return nil
+		})
+	}()
+
+	select {
+	case n := <-done:
+		if n != 1 {
+			t.Errorf("got attempts %d, want 1", n)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestStreamQueue_Claim(t *testing.T) {
+	client := newTestStreamClient(t)
+	q := NewRedisStreamQueue(client, 2, StreamOptions{Consumer: "c2", ClaimIdle: 100 * time.Millisecond}).(*streamQueue)
+	stream := q.key("claim")
+	if err := q.createGroup(stream); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Publish(context.Background(), "claim", map[string]string{"key": "b.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟读取后崩溃的消费者
+	_, err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    q.opts.Group,
+		Consumer: "crashed",
+		Streams:  []string{stream, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	handled := 0
+	if err := q.claim(context.Background(), stream, func(ctx context.Context, msg *Message) error {
+		handled++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 1 {
+		t.Fatalf("got handled %d, want 1", handled)
+	}
+	pending, err := client.XPending(stream, q.opts.Group).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("claimed message should be acked, pending %d", pending.Count)
+	}
+}
@@ -0,0 +1,234 @@
+// 按套餐(plan)限制用户每天/每月的请求次数，计数存放在 redis 中
+// subject 为配额的主体，可以是用户，也可以是 api key
+
+package quota
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PlanFree 免费套餐
+	PlanFree = "free"
+	// PlanVIP vip套餐
+	PlanVIP = "vip"
+
+	// PrefixQuotaKey key前缀
+	PrefixQuotaKey = "snake:quota"
+
+	// keyExpireDelay 计数key在周期结束后多保留一段时间，避免边界上的请求读到空值
+	keyExpireDelay = time.Hour
+)
+
+// Client 全局的配额客户端，未开启配额时为nil
+var Client *Quota
+
+// Limit 套餐的配额，0 表示不限制
+type Limit struct {
+	Daily   int64 `json:"daily" mapstructure:"daily"`
+	Monthly int64 `json:"monthly" mapstructure:"monthly"`
+}
+
+// Usage 配额的使用情况
+type Usage struct {
+	Plan         string    `json:"plan"`
+	DailyLimit   int64     `json:"daily_limit"`
+	DailyUsed    int64     `json:"daily_used"`
+	DailyReset   time.Time `json:"daily_reset"`
+	MonthlyLimit int64     `json:"monthly_limit"`
+	MonthlyUsed  int64     `json:"monthly_used"`
+	MonthlyReset time.Time `json:"monthly_reset"`
+	// Allowed 本次请求是否允许
+	Allowed bool `json:"-"`
+}
+
+// Remaining 剩余可用次数，取每天和每月剩余次数中较小的，-1 表示不限制
+func (u *Usage) Remaining() int64 {
+	remaining := int64(-1)
+	if u.DailyLimit > 0 {
+		remaining = nonNegative(u.DailyLimit - u.DailyUsed)
+	}
+	if u.MonthlyLimit > 0 {
+		monthly := nonNegative(u.MonthlyLimit - u.MonthlyUsed)
+		if remaining < 0 || monthly < remaining {
+			remaining = monthly
+		}
+	}
+	return remaining
+}
+
+// Limit 生效的限制次数，-1 表示不限制
+func (u *Usage) Limit() int64 {
+	if u.DailyLimit > 0 {
+		return u.DailyLimit
+	}
+	if u.MonthlyLimit > 0 {
+		return u.MonthlyLimit
+	}
+	return -1
+}
+
+// Reset 剩余次数的重置时间
+func (u *Usage) Reset() time.Time {
+	if u.DailyLimit == 0 && u.MonthlyLimit > 0 {
+		return u.MonthlyReset
+	}
+	return u.DailyReset
+}
+
+// 先检查每天和每月是否都还有余量，再同时计数，避免被拒绝的请求也消耗配额
+var consumeScript = redis.NewScript(`
+local daily = tonumber(redis.call("GET", KEYS[1]) or "0")
+local monthly = tonumber(redis.call("GET", KEYS[2]) or "0")
+local dailyLimit = tonumber(ARGV[1])
+local monthlyLimit = tonumber(ARGV[2])
+if (dailyLimit > 0 and daily >= dailyLimit) or (monthlyLimit > 0 and monthly >= monthlyLimit) then
+	return {daily, monthly, 0}
+end
+daily = redis.call("INCR", KEYS[1])
+if daily == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+monthly = redis.call("INCR", KEYS[2])
+if monthly == 1 then
+	redis.call("PEXPIRE", KEYS[2], ARGV[4])
+end
+return {daily, monthly, 1}
+`)
+
+// Quota 配额
+type Quota struct {
+	client *redis.Client
+	limits map[string]Limit
+	now    func() time.Time
+}
+
+// Init 根据配置初始化配额，未开启时 Client 为nil
+func Init() *Quota {
+	if !viper.GetBool("quota.enable") {
+		return nil
+	}
+	limits := make(map[string]Limit)
+	if err := viper.UnmarshalKey("quota.plans", &limits); err != nil {
+		panic(fmt.Sprintf("[quota] unmarshal quota plans err: %v", err))
+	}
+	Client = New(redis2.RedisClient, limits)
+	return Client
+}
+
+// New 实例化配额, limits 为套餐名到配额的映射
+func New(client *redis.Client, limits map[string]Limit) *Quota {
+	return &Quota{
+		client: client,
+		limits: limits,
+		now:    time.Now,
+	}
+}
+
+// GetLimit 获取套餐的配额，未知套餐按免费套餐处理
+func (q *Quota) GetLimit(plan string) (string, Limit) {
+	if limit, ok := q.limits[plan]; ok {
+		return plan, limit
+	}
+	return PlanFree, q.limits[PlanFree]
+}
+
+// Consume 消耗一次配额，配额不足时 Usage.Allowed 为false且不计数
+func (q *Quota) Consume(subject, plan string) (*Usage, error) {
+	usage := q.newUsage(plan)
+	now := q.now()
+	dailyKey, monthlyKey := q.keys(subject, now)
+
+	res, err := consumeScript.Run(q.client, []string{dailyKey, monthlyKey},
+		usage.DailyLimit, usage.MonthlyLimit,
+		int64(usage.DailyReset.Sub(now)+keyExpireDelay)/int64(time.Millisecond),
+		int64(usage.MonthlyReset.Sub(now)+keyExpireDelay)/int64(time.Millisecond),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 3 {
+		return nil, fmt.Errorf("[quota] unexpected script result: %v", res)
+	}
+	usage.DailyUsed, _ = vals[0].(int64)
+	usage.MonthlyUsed, _ = vals[1].(int64)
+	allowed, _ := vals[2].(int64)
+	usage.Allowed = allowed == 1
+
+	return usage, nil
+}
+
+// Get 获取配额的使用情况，不计数
+func (q *Quota) Get(subject, plan string) (*Usage, error) {
+	usage := q.newUsage(plan)
+	dailyKey, monthlyKey := q.keys(subject, q.now())
+
+	vals, err := q.client.MGet(dailyKey, monthlyKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	usage.DailyUsed = parseCount(vals[0])
+	usage.MonthlyUsed = parseCount(vals[1])
+	usage.Allowed = usage.Remaining() != 0
+
+	return usage, nil
+}
+
+// Reset 清空本日、本月已使用的次数
+func (q *Quota) Reset(subject string) error {
+	dailyKey, monthlyKey := q.keys(subject, q.now())
+	return q.client.Del(dailyKey, monthlyKey).Err()
+}
+
+func (q *Quota) newUsage(plan string) *Usage {
+	plan, limit := q.GetLimit(plan)
+	now := q.now()
+	year, month, day := now.Date()
+	return &Usage{
+		Plan:         plan,
+		DailyLimit:   limit.Daily,
+		DailyReset:   time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()),
+		MonthlyLimit: limit.Monthly,
+		MonthlyReset: time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()),
+	}
+}
+
+// UserSubject 用户配额的主体标识
+func UserSubject(userID uint64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// APIKeySubject api key 配额的主体标识，每个 key 单独计数
+func APIKeySubject(keyID uint64) string {
+	return fmt.Sprintf("api_key:%d", keyID)
+}
+
+func (q *Quota) keys(subject string, now time.Time) (dailyKey, monthlyKey string) {
+	dailyKey = fmt.Sprintf("%s:%s:d:%s", PrefixQuotaKey, subject, now.Format("20060102"))
+	monthlyKey = fmt.Sprintf("%s:%s:m:%s", PrefixQuotaKey, subject, now.Format("200601"))
+	return
+}
+
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func parseCount(v interface{}) int64 {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
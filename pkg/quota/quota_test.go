@@ -0,0 +1,60 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestQuota_Consume(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	q := New(redis.RedisClient, map[string]Limit{
+		PlanFree: {Daily: 2, Monthly: 3},
+		PlanVIP:  {Daily: 0, Monthly: 0},
+	})
+	q.now = func() time.Time {
+		return time.Date(2020, 6, 30, 12, 0, 0, 0, time.Local)
+	}
+
+	for i := 1; i <= 2; i++ {
+		usage, err := q.Consume("user:1", PlanFree)
+		asserts.NoError(err)
+		asserts.True(usage.Allowed)
+		asserts.Equal(int64(i), usage.DailyUsed)
+		asserts.Equal(int64(2-i), usage.Remaining())
+	}
+
+	// 超出每天的配额，不再计数
+	usage, err := q.Consume("user:1", PlanFree)
+	asserts.NoError(err)
+	asserts.False(usage.Allowed)
+	asserts.Equal(int64(2), usage.MonthlyUsed)
+	asserts.Equal(time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), usage.DailyReset)
+
+	// 进入新的一天和新的月份，重新计数
+	q.now = func() time.Time {
+		return time.Date(2020, 7, 1, 12, 0, 0, 0, time.Local)
+	}
+	usage, err = q.Get("user:1", PlanFree)
+	asserts.NoError(err)
+	asserts.Equal(int64(0), usage.DailyUsed)
+	asserts.Equal(int64(0), usage.MonthlyUsed)
+
+	// 未知套餐按免费套餐处理
+	usage, err = q.Consume("user:2", "unknown")
+	asserts.NoError(err)
+	asserts.Equal(PlanFree, usage.Plan)
+
+	// vip 不限制
+	for i := 0; i < 5; i++ {
+		usage, err = q.Consume("user:3", PlanVIP)
+		asserts.NoError(err)
+		asserts.True(usage.Allowed)
+	}
+	asserts.Equal(int64(-1), usage.Remaining())
+}
@@ -0,0 +1,228 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// ruleKey 按路由的限流规则，hash 的 field 为 "method route"
+	ruleKey = PrefixRateLimitKey + ":rules"
+	// ruleRefreshInterval 本地缓存规则的时间，修改后其他实例最多延迟这么久生效
+	ruleRefreshInterval = 10 * time.Second
+	// scanCount 每次 scan 的数量
+	scanCount = 100
+)
+
+// ErrInvalidRule 限流规则有误
+var ErrInvalidRule = errors.New("ratelimit: invalid rule")
+
+// Rule 按路由的限流规则，保存在 redis 中，修改后无需重新部署
+type Rule struct {
+	// Method 为空时匹配所有方法
+	Method string `json:"method" example:"POST"`
+	// Route 路由，和注册的路由一致
+	Route string `json:"route" example:"/v1/users/follow"`
+	// Limit 每个窗口的请求数
+	Limit int64 `json:"limit" example:"10"`
+	// Window 窗口大小，单位秒
+	Window int64 `json:"window" example:"60"`
+}
+
+func (r *Rule) field() string {
+	return r.Method + " " + r.Route
+}
+
+// Counter 限流计数
+type Counter struct {
+	// Subject 限流的主体，路由单独计数时带上路由，如 ip:127.0.0.1@POST /v1/users/follow
+	Subject string `json:"subject"`
+	// Count 当前窗口的请求数
+	Count int64 `json:"count"`
+	// Reset 计数的过期时间
+	Reset time.Time `json:"reset"`
+	// Grace 软限制的宽限窗口
+	Grace bool `json:"grace,omitempty"`
+}
+
+// rules 本地缓存的限流规则
+type rules struct {
+	mu       sync.RWMutex
+	items    map[string]Rule
+	loadedAt time.Time
+}
+
+// Rules 获取所有限流规则
+func (l *Limiter) Rules() ([]Rule, error) {
+	values, err := l.client.HGetAll(ruleKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Rule, 0, len(values))
+	for _, v := range values {
+		r := Rule{}
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			continue
+		}
+		items = append(items, r)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].field() < items[j].field() })
+	return items, nil
+}
+
+// SetRule 新增或修改路由的限流规则
+func (l *Limiter) SetRule(rule Rule) error {
+	rule.Method = strings.ToUpper(rule.Method)
+	if !strings.HasPrefix(rule.Route, "/") || rule.Limit <= 0 || rule.Window <= 0 {
+		return ErrInvalidRule
+	}
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	if err := l.client.HSet(ruleKey, rule.field(), b).Err(); err != nil {
+		return err
+	}
+	l.expireRules()
+	return nil
+}
+
+// DeleteRule 删除路由的限流规则，恢复使用默认的限制
+func (l *Limiter) DeleteRule(method, route string) error {
+	r := Rule{Method: strings.ToUpper(method), Route: route}
+	if err := l.client.HDel(ruleKey, r.field()).Err(); err != nil {
+		return err
+	}
+	l.expireRules()
+	return nil
+}
+
+// Counters 获取主体当前的计数，包括按路由单独的计数
+func (l *Limiter) Counters(subject string) ([]*Counter, error) {
+	keys, err := l.scanKeys(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]*Counter, 0, len(keys))
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, PrefixRateLimitKey+":")
+		idx := strings.LastIndex(name, ":")
+		if idx < 0 {
+			continue
+		}
+		c := &Counter{Subject: name[:idx]}
+		val, err := l.client.Get(key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := l.client.PTTL(key).Result()
+		if err != nil {
+			return nil, err
+		}
+		c.Reset = l.now().Add(ttl)
+
+		// 宽限记录的值是窗口id，不是计数
+		if name[idx+1:] == "grace" {
+			c.Grace = true
+		} else {
+			c.Count, _ = strconv.ParseInt(val, 10, 64)
+		}
+		counters = append(counters, c)
+	}
+	sort.Slice(counters, func(i, j int) bool { return counters[i].Subject < counters[j].Subject })
+	return counters, nil
+}
+
+// Reset 清空主体的所有计数和软限制的宽限记录，返回删除的 key 数量
+func (l *Limiter) Reset(subject string) (int, error) {
+	keys, err := l.scanKeys(subject)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	n, err := l.client.Del(keys...).Result()
+	return int(n), err
+}
+
+// rule 获取路由的限流规则，规则在本地缓存一段时间，减少 redis 的访问
+func (l *Limiter) rule(method, route string) (Rule, bool, error) {
+	l.rules.mu.RLock()
+	fresh := l.rules.items != nil && l.now().Sub(l.rules.loadedAt) < ruleRefreshInterval
+	items := l.rules.items
+	l.rules.mu.RUnlock()
+
+	if !fresh {
+		values, err := l.client.HGetAll(ruleKey).Result()
+		if err != nil {
+			return Rule{}, false, err
+		}
+		items = make(map[string]Rule, len(values))
+		for field, v := range values {
+			r := Rule{}
+			if err := json.Unmarshal([]byte(v), &r); err == nil {
+				items[field] = r
+			}
+		}
+		l.rules.mu.Lock()
+		l.rules.items = items
+		l.rules.loadedAt = l.now()
+		l.rules.mu.Unlock()
+	}
+
+	if r, ok := items[method+" "+route]; ok {
+		return r, true, nil
+	}
+	r, ok := items[" "+route]
+	return r, ok, nil
+}
+
+// expireRules 修改规则后当前实例立即生效
+func (l *Limiter) expireRules() {
+	l.rules.mu.Lock()
+	l.rules.items = nil
+	l.rules.mu.Unlock()
+}
+
+// scanKeys 查找主体的所有 key
+func (l *Limiter) scanKeys(subject string) ([]string, error) {
+	keys := make([]string, 0)
+	for _, pattern := range []string{
+		fmt.Sprintf("%s:%s:*", PrefixRateLimitKey, escapePattern(subject)),
+		fmt.Sprintf("%s:%s@*", PrefixRateLimitKey, escapePattern(subject)),
+	} {
+		iter := l.client.Scan(0, pattern, scanCount).Iterator()
+		for iter.Next() {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// routeSubject 按路由单独计数的主体
+func routeSubject(subject string, rule Rule) string {
+	return subject + "@" + rule.field()
+}
+
+// escapePattern 转义 scan 的通配符
+func escapePattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return r.Replace(s)
+}
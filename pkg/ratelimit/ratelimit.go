@@ -0,0 +1,264 @@
+// 基于 redis 固定窗口计数的限流
+// 开启软限制(soft)后，第一个超限的窗口内请求仍然放行，只返回警告，之后的窗口再真正拒绝，
+// 给新接入的调用方一个发现和调整的缓冲期
+
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/log"
+	redis2 "github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// PrefixRateLimitKey key前缀
+	PrefixRateLimitKey = "snake:ratelimit"
+
+	// DefaultLimit 默认每个窗口的请求数
+	DefaultLimit = 100
+	// DefaultWindow 默认窗口大小
+	DefaultWindow = time.Minute
+	// DefaultGraceTTL 默认软限制宽限期的有效时间，过期后可以再次获得宽限
+	DefaultGraceTTL = 24 * time.Hour
+)
+
+// Client 全局的限流客户端，未开启限流时为nil
+var Client *Limiter
+
+// Result 限流结果
+type Result struct {
+	Limit     int64
+	Remaining int64
+	Reset     time.Time
+	// Allowed 是否放行
+	Allowed bool
+	// Warning 已超限，但处于软限制的宽限期内被放行
+	Warning bool
+}
+
+// Limiter 限流器
+type Limiter struct {
+	client *redis.Client
+	now    func() time.Time
+
+	// mu 保护 settings，配置热加载时整体替换
+	mu sync.RWMutex
+	settings
+
+	rules rules
+}
+
+// settings 默认的限制，可以在运行时修改
+type settings struct {
+	limit    int64
+	window   time.Duration
+	soft     bool
+	graceTTL time.Duration
+}
+
+// Option 设置可选参数
+type Option func(*Limiter)
+
+// WithLimit 设置每个窗口的请求数
+func WithLimit(limit int64) Option {
+	return func(l *Limiter) {
+		l.limit = limit
+	}
+}
+
+// WithWindow 设置窗口大小
+func WithWindow(window time.Duration) Option {
+	return func(l *Limiter) {
+		l.window = window
+	}
+}
+
+// WithSoft 开启软限制, graceTTL 为宽限期的有效时间
+func WithSoft(graceTTL time.Duration) Option {
+	return func(l *Limiter) {
+		l.soft = true
+		l.graceTTL = graceTTL
+	}
+}
+
+// Init 根据配置初始化限流，未开启时 Client 为nil
+// 配置文件变化时重新加载 limit、window、soft、grace_ttl，enable 的修改需要重启后生效
+func Init() *Limiter {
+	if !viper.GetBool("ratelimit.enable") {
+		return nil
+	}
+	Client = New(redis2.RedisClient, options()...)
+	conf.OnChange(Reload)
+	return Client
+}
+
+// Reload 按配置更新 Client 的默认限制
+func Reload() {
+	if Client == nil {
+		return
+	}
+	Client.Update(options()...)
+	log.Infof("[ratelimit] reloaded, limit: %d, window: %s, soft: %t",
+		viper.GetInt64("ratelimit.limit"), viper.GetDuration("ratelimit.window"), viper.GetBool("ratelimit.soft"))
+}
+
+// options 读取配置 ratelimit 下的默认限制
+func options() []Option {
+	opts := []Option{
+		WithLimit(viper.GetInt64("ratelimit.limit")),
+		WithWindow(viper.GetDuration("ratelimit.window")),
+	}
+	if viper.GetBool("ratelimit.soft") {
+		opts = append(opts, WithSoft(viper.GetDuration("ratelimit.grace_ttl")))
+	}
+	return opts
+}
+
+// New 实例化一个限流器
+func New(client *redis.Client, opts ...Option) *Limiter {
+	l := &Limiter{
+		client:   client,
+		now:      time.Now,
+		settings: newSettings(opts...),
+	}
+	return l
+}
+
+// Update 替换默认的限制，未设置的参数使用默认值，不影响已有的计数和按路由的规则
+func (l *Limiter) Update(opts ...Option) {
+	s := newSettings(opts...)
+	l.mu.Lock()
+	l.settings = s
+	l.mu.Unlock()
+}
+
+// current 当前的默认限制
+func (l *Limiter) current() settings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.settings
+}
+
+func newSettings(opts ...Option) settings {
+	l := &Limiter{settings: settings{
+		limit:    DefaultLimit,
+		window:   DefaultWindow,
+		graceTTL: DefaultGraceTTL,
+	}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.limit <= 0 {
+		l.limit = DefaultLimit
+	}
+	if l.window <= 0 {
+		l.window = DefaultWindow
+	}
+	if l.graceTTL <= 0 {
+		l.graceTTL = DefaultGraceTTL
+	}
+	return l.settings
+}
+
+// Allow 计数并判断是否放行, subject 为限流的主体，比如 ip、用户
+func (l *Limiter) Allow(subject string) (*Result, error) {
+	s := l.current()
+	return l.allow(subject, s.limit, s.window)
+}
+
+// AllowRoute 按路由的限流规则计数，路由没有规则时使用默认的限制
+// 有规则的路由单独计数，不占用默认的限制
+func (l *Limiter) AllowRoute(subject, method, route string) (*Result, error) {
+	rule, ok, err := l.rule(method, route)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return l.Allow(subject)
+	}
+	return l.allow(routeSubject(subject, rule), rule.Limit, time.Duration(rule.Window)*time.Second)
+}
+
+// AllowLimit 按指定的限制计数，用于配置文件中按路由声明的限流
+func (l *Limiter) AllowLimit(subject string, limit int64, window time.Duration) (*Result, error) {
+	s := l.current()
+	if limit <= 0 {
+		limit = s.limit
+	}
+	if window <= 0 {
+		window = s.window
+	}
+	return l.allow(subject, limit, window)
+}
+
+func (l *Limiter) allow(subject string, limit int64, window time.Duration) (*Result, error) {
+	now := l.now()
+	windowID := now.UnixNano() / int64(window)
+	key := fmt.Sprintf("%s:%s:%d", PrefixRateLimitKey, subject, windowID)
+
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(key)
+	pipe.Expire(key, window)
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	count := incr.Val()
+	res := &Result{
+		Limit:     limit,
+		Remaining: limit - count,
+		Reset:     time.Unix(0, (windowID+1)*int64(window)),
+		Allowed:   true,
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if count <= limit {
+		return res, nil
+	}
+
+	if !l.current().soft {
+		res.Allowed = false
+		return res, nil
+	}
+
+	// 软限制：记录第一次超限的窗口，这个窗口内只警告不拒绝
+	inGrace, err := l.inGraceWindow(subject, windowID)
+	if err != nil {
+		return nil, err
+	}
+	res.Allowed = inGrace
+	res.Warning = inGrace
+	return res, nil
+}
+
+// inGraceWindow 当前窗口是否是宽限窗口
+func (l *Limiter) inGraceWindow(subject string, windowID int64) (bool, error) {
+	graceKey := fmt.Sprintf("%s:%s:grace", PrefixRateLimitKey, subject)
+	windowStr := strconv.FormatInt(windowID, 10)
+
+	ok, err := l.client.SetNX(graceKey, windowStr, l.current().graceTTL).Result()
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, nil
+	}
+
+	val, err := l.client.Get(graceKey).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return val == windowStr, nil
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.Local)
+	l := New(redis.RedisClient, WithLimit(2), WithWindow(time.Minute))
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		res, err := l.Allow("ip:127.0.0.1")
+		asserts.NoError(err)
+		asserts.True(res.Allowed)
+	}
+	res, err := l.Allow("ip:127.0.0.1")
+	asserts.NoError(err)
+	asserts.False(res.Allowed)
+	asserts.Equal(int64(0), res.Remaining)
+	asserts.Equal(now.Add(time.Minute), res.Reset)
+}
+
+func TestLimiter_AllowSoft(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.Local)
+	l := New(redis.RedisClient, WithLimit(1), WithWindow(time.Minute), WithSoft(time.Hour))
+	l.now = func() time.Time { return now }
+
+	res, err := l.Allow("ip:127.0.0.1")
+	asserts.NoError(err)
+	asserts.True(res.Allowed)
+	asserts.False(res.Warning)
+
+	// 第一个超限的窗口只警告
+	for i := 0; i < 3; i++ {
+		res, err = l.Allow("ip:127.0.0.1")
+		asserts.NoError(err)
+		asserts.True(res.Allowed)
+		asserts.True(res.Warning)
+	}
+
+	// 下一个窗口超限后拒绝
+	now = now.Add(time.Minute)
+	res, err = l.Allow("ip:127.0.0.1")
+	asserts.NoError(err)
+	asserts.True(res.Allowed)
+	res, err = l.Allow("ip:127.0.0.1")
+	asserts.NoError(err)
+	asserts.False(res.Allowed)
+	asserts.False(res.Warning)
+}
+
+func TestLimiter_AllowRoute(t *testing.T) {
+	redis.InitTestRedis()
+	asserts := assert.New(t)
+
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.Local)
+	l := New(redis.RedisClient, WithLimit(100), WithWindow(time.Minute))
+	l.now = func() time.Time { return now }
+
+	asserts.Equal(ErrInvalidRule, l.SetRule(Rule{Route: "/v1/users/follow"}))
+	asserts.NoError(l.SetRule(Rule{Method: "post", Route: "/v1/users/follow", Limit: 1, Window: 60}))
+	rules, err := l.Rules()
+	asserts.NoError(err)
+	asserts.Equal([]Rule{{Method: "POST", Route: "/v1/users/follow", Limit: 1, Window: 60}}, rules)
+
+	res, err := l.AllowRoute("ip:10.0.0.1", "POST", "/v1/users/follow")
+	asserts.NoError(err)
+	asserts.True(res.Allowed)
+	res, err = l.AllowRoute("ip:10.0.0.1", "POST", "/v1/users/follow")
+	asserts.NoError(err)
+	asserts.False(res.Allowed)
+
+	// 没有规则的路由使用默认限制
+	res, err = l.AllowRoute("ip:10.0.0.1", "GET", "/v1/users/:id")
+	asserts.NoError(err)
+	asserts.True(res.Allowed)
+	asserts.Equal(int64(99), res.Remaining)
+
+	counters, err := l.Counters("ip:10.0.0.1")
+	asserts.NoError(err)
+	asserts.Len(counters, 2)
+	asserts.Equal("ip:10.0.0.1", counters[0].Subject)
+	asserts.Equal(int64(1), counters[0].Count)
+	asserts.Equal("ip:10.0.0.1@POST /v1/users/follow", counters[1].Subject)
+	asserts.Equal(int64(2), counters[1].Count)
+
+	// 清空计数后可以继续请求
+	n, err := l.Reset("ip:10.0.0.1")
+	asserts.NoError(err)
+	asserts.Equal(2, n)
+	res, err = l.AllowRoute("ip:10.0.0.1", "POST", "/v1/users/follow")
+	asserts.NoError(err)
+	asserts.True(res.Allowed)
+
+	// 删除规则后恢复默认限制
+	asserts.NoError(l.DeleteRule("POST", "/v1/users/follow"))
+	res, err = l.AllowRoute("ip:10.0.0.1", "POST", "/v1/users/follow")
+	asserts.NoError(err)
+	asserts.Equal(int64(99), res.Remaining)
+}
@@ -0,0 +1,64 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// fileSink 追加写入文件，每行一个 json
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink 打开文件，不存在时创建
+func NewFileSink(name string) (Sink, error) {
+	if name == "" {
+		return nil, errors.New("recorder: file is empty")
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: f}, nil
+}
+
+// Write 写入一行
+func (s *fileSink) Write(rec *Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(b, '\n'))
+	return err
+}
+
+// Close 关闭文件
+func (s *fileSink) Close() error {
+	return s.file.Close()
+}
+
+// ReadRecords 逐行读取录制文件, fn 返回错误时停止
+func ReadRecords(r io.Reader, fn func(rec *Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,278 @@
+// Package recorder 请求录制和回放
+// 按比例采样线上请求，脱敏后写入文件或队列，再用 cmd/replay 回放到测试环境，
+// 对比状态码和响应内容，用于验证大的重构(如升级 gorm)没有改变接口的行为
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/queue"
+)
+
+// 录制的存储方式
+const (
+	// DriverFile 写入本地文件，每行一个 json
+	DriverFile = "file"
+	// DriverQueue 发布到队列，由 worker 统一写入文件，适用于多实例部署
+	DriverQueue = "queue"
+)
+
+// TopicRequestRecorded 录制的请求
+const TopicRequestRecorded = "request.recorded"
+
+const (
+	// Masked 脱敏后的值
+	Masked = "***"
+
+	// DefaultMaxBody 默认最大录制的请求体、响应体大小
+	DefaultMaxBody = 64 * 1024
+
+	// bufferSize 待写入的缓冲，写满时丢弃新的录制，不影响请求
+	bufferSize = 1024
+)
+
+var (
+	// ErrUnknownDriver 未知的存储方式
+	ErrUnknownDriver = errors.New("recorder: unknown driver")
+
+	// DefaultMaskFields 默认需要脱敏的字段
+	DefaultMaskFields = []string{"password", "password_confirmation", "token", "verify_code", "phone", "email",
+		"access_key", "secret"}
+
+	// sensitiveHeaders 不录制的请求头，回放时通过命令行参数传入
+	sensitiveHeaders = map[string]bool{
+		"Authorization":   true,
+		"Cookie":          true,
+		"X-Api-Key":       true,
+		"X-Forwarded-For": true,
+		"X-Real-Ip":       true,
+	}
+)
+
+// Client 全局的录制器，未开启时为 nil
+var Client *Recorder
+
+// Record 一次请求和响应
+type Record struct {
+	Time      time.Time         `json:"time"`
+	RequestID string            `json:"request_id,omitempty"`
+	Method    string            `json:"method"`
+	Route     string            `json:"route"`
+	URL       string            `json:"url"`
+	Header    map[string]string `json:"header,omitempty"`
+	Body      string            `json:"body,omitempty"`
+	// Truncated 请求体超过大小限制时不录制，回放时跳过
+	Truncated bool   `json:"truncated,omitempty"`
+	Status    int    `json:"status"`
+	Response  string `json:"response,omitempty"`
+	Latency   int64  `json:"latency_ms"`
+}
+
+// Sink 录制的存储
+type Sink interface {
+	Write(rec *Record) error
+	Close() error
+}
+
+// Recorder 采样、脱敏并异步写入录制
+type Recorder struct {
+	sampleRate float64
+	maxBody    int64
+	masker     *Masker
+	sink       Sink
+
+	records chan *Record
+	wg      sync.WaitGroup
+	once    sync.Once
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Init 初始化全局的录制器
+func Init() *Recorder {
+	if !viper.GetBool("record.enable") {
+		return nil
+	}
+
+	sink, err := NewSink(viper.GetString("record.driver"), viper.GetString("record.file"))
+	if err != nil {
+		log.Warnf("[recorder] new sink err: %v", err)
+		return nil
+	}
+	fields := viper.GetStringSlice("record.mask_fields")
+	if len(fields) == 0 {
+		fields = DefaultMaskFields
+	}
+	Client = New(sink, viper.GetFloat64("record.sample_rate"), viper.GetInt64("record.max_body"), NewMasker(fields))
+	return Client
+}
+
+// NewSink 按存储方式实例化
+func NewSink(driver, file string) (Sink, error) {
+	switch driver {
+	case "", DriverFile:
+		return NewFileSink(file)
+	case DriverQueue:
+		return &queueSink{}, nil
+	}
+	return nil, ErrUnknownDriver
+}
+
+// New 实例化录制器, sampleRate 为采样比例 0-1
+func New(sink Sink, sampleRate float64, maxBody int64, masker *Masker) *Recorder {
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBody
+	}
+	r := &Recorder{
+		sampleRate: sampleRate,
+		maxBody:    maxBody,
+		masker:     masker,
+		sink:       sink,
+		records:    make(chan *Record, bufferSize),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+// MaxBody 最大录制的请求体、响应体大小
+func (r *Recorder) MaxBody() int64 {
+	return r.maxBody
+}
+
+// Sample 是否录制本次请求
+func (r *Recorder) Sample() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64() < r.sampleRate
+}
+
+// Add 脱敏后异步写入，缓冲已满时丢弃
+func (r *Recorder) Add(rec *Record) {
+	rec.Header = r.masker.Header(rec.Header)
+	rec.Body = r.masker.Body(rec.Body)
+	rec.Response = r.masker.Body(rec.Response)
+	if len(rec.Response) > int(r.maxBody) {
+		rec.Response = ""
+	}
+
+	select {
+	case r.records <- rec:
+	default:
+		log.Warnf("[recorder] buffer is full, drop record: %s %s", rec.Method, rec.URL)
+	}
+}
+
+// Close 写入缓冲中剩余的录制后关闭
+func (r *Recorder) Close() error {
+	r.once.Do(func() {
+		close(r.records)
+	})
+	r.wg.Wait()
+	return r.sink.Close()
+}
+
+func (r *Recorder) loop() {
+	defer r.wg.Done()
+	for rec := range r.records {
+		if err := r.sink.Write(rec); err != nil {
+			log.Warnf("[recorder] write record err: %v", err)
+		}
+	}
+}
+
+// queueSink 发布到队列
+type queueSink struct{}
+
+// Write 发布录制
+func (s *queueSink) Write(rec *Record) error {
+	return queue.Publish(context.Background(), TopicRequestRecorded, rec)
+}
+
+// Close 无需关闭
+func (s *queueSink) Close() error {
+	return nil
+}
+
+// Masker 脱敏
+type Masker struct {
+	fields map[string]bool
+}
+
+// NewMasker 实例化, fields 为需要脱敏的字段名，不区分大小写
+func NewMasker(fields []string) *Masker {
+	m := &Masker{fields: make(map[string]bool, len(fields))}
+	for _, f := range fields {
+		m.fields[strings.ToLower(f)] = true
+	}
+	return m
+}
+
+// Header 去掉敏感的请求头
+func (m *Masker) Header(header map[string]string) map[string]string {
+	masked := make(map[string]string, len(header))
+	for k, v := range header {
+		if sensitiveHeaders[k] {
+			continue
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// Body 脱敏 json 或表单，其他格式原样返回
+func (m *Masker) Body(body string) string {
+	if body == "" {
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err == nil {
+		b, err := json.Marshal(m.JSON(v))
+		if err != nil {
+			return body
+		}
+		return string(b)
+	}
+
+	if values, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") {
+		for k := range values {
+			if m.fields[strings.ToLower(k)] {
+				values.Set(k, Masked)
+			}
+		}
+		return values.Encode()
+	}
+	return body
+}
+
+// JSON 递归脱敏 json 对象
+func (m *Masker) JSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if m.fields[strings.ToLower(k)] {
+				val[k] = Masked
+				continue
+			}
+			val[k] = m.JSON(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = m.JSON(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package recorder
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memorySink struct {
+	records []*Record
+}
+
+func (s *memorySink) Write(rec *Record) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestRecorder_Add(t *testing.T) {
+	asserts := assert.New(t)
+
+	sink := &memorySink{}
+	r := New(sink, 1, 0, NewMasker(DefaultMaskFields))
+	asserts.True(r.Sample())
+
+	r.Add(&Record{
+		Method:   "POST",
+		URL:      "/v1/login",
+		Header:   map[string]string{"Authorization": "Bearer xxx", "Content-Type": "application/json"},
+		Body:     `{"email":"a@b.com","password":"123456"}`,
+		Status:   200,
+		Response: `{"code":0,"data":{"token":"xxx","user":{"id":1,"phone":13800000000}}}`,
+	})
+	r.Add(&Record{Method: "POST", URL: "/v1/login/phone", Body: "phone=13800000000&verify_code=1234&area_code=86"})
+	asserts.NoError(r.Close())
+
+	asserts.Len(sink.records, 2)
+	rec := sink.records[0]
+	asserts.Equal(map[string]string{"Content-Type": "application/json"}, rec.Header)
+	asserts.JSONEq(`{"email":"***","password":"***"}`, rec.Body)
+	asserts.JSONEq(`{"code":0,"data":{"token":"***","user":{"id":1,"phone":"***"}}}`, rec.Response)
+	asserts.Equal("area_code=86&phone=%2A%2A%2A&verify_code=%2A%2A%2A", sink.records[1].Body)
+}
+
+func TestReplayer_Replay(t *testing.T) {
+	asserts := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asserts.Equal("Bearer staging", r.Header.Get("Authorization"))
+		buf := &bytes.Buffer{}
+		_, _ = buf.ReadFrom(r.Body)
+		if r.URL.Path == "/v1/users/1" {
+			_, _ = w.Write([]byte(`{"code":0,"data":{"id":1,"username":"snake","email":"a@b.com","created_at":"now"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	r := NewReplayer(srv.URL, map[string]string{"Authorization": "Bearer staging"}, []string{"data.created_at"},
+		NewMasker(DefaultMaskFields))
+
+	res := r.Replay(&Record{Method: "GET", URL: "/v1/users/1", Status: 200,
+		Response: `{"code":0,"data":{"id":1,"username":"snake","email":"***","created_at":"before"}}`})
+	asserts.True(res.Match())
+
+	res = r.Replay(&Record{Method: "GET", URL: "/v1/users/1", Status: 200,
+		Response: `{"code":0,"data":{"id":2,"username":"snake","email":"***"}}`})
+	asserts.False(res.Match())
+	asserts.Equal([]string{"data.id"}, res.Diffs)
+
+	res = r.Replay(&Record{Method: "POST", URL: "/v1/users/follow", Body: `{"user_id":1}`, Status: 200})
+	asserts.Equal([]string{"status: 200 != 500"}, res.Diffs)
+}
@@ -0,0 +1,156 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result 一次回放的结果
+type Result struct {
+	Record *Record
+	Status int
+	// Diffs 响应不一致的字段，如 status、data.user.id
+	Diffs []string
+	Err   error
+}
+
+// Match 回放结果和录制是否一致
+func (r *Result) Match() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Replayer 把录制的请求回放到测试环境
+type Replayer struct {
+	target string
+	header map[string]string
+	ignore map[string]bool
+	masker *Masker
+	client *http.Client
+}
+
+// NewReplayer 实例化, target 为测试环境的地址, header 为额外的请求头(如测试账号的 Authorization),
+// ignore 为对比时忽略的字段，如 data.created_at
+func NewReplayer(target string, header map[string]string, ignore []string, masker *Masker) *Replayer {
+	r := &Replayer{
+		target: strings.TrimRight(target, "/"),
+		header: header,
+		ignore: make(map[string]bool, len(ignore)),
+		masker: masker,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, f := range ignore {
+		r.ignore[f] = true
+	}
+	return r
+}
+
+// Replay 回放一个请求，对比状态码和响应
+func (r *Replayer) Replay(rec *Record) *Result {
+	res := &Result{Record: rec}
+
+	req, err := http.NewRequest(rec.Method, r.target+rec.URL, strings.NewReader(rec.Body))
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for k, v := range rec.Header {
+		req.Header.Set(k, v)
+	}
+	for k, v := range r.header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	res.Status = resp.StatusCode
+	if res.Status != rec.Status {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("status: %d != %d", rec.Status, res.Status))
+	}
+	if rec.Response != "" {
+		res.Diffs = append(res.Diffs, r.diff(rec.Response, r.masker.Body(string(body)))...)
+	}
+	return res
+}
+
+// diff 对比两个响应，都是 json 时按字段对比
+func (r *Replayer) diff(expected, actual string) []string {
+	var ev, av interface{}
+	if json.Unmarshal([]byte(expected), &ev) != nil || json.Unmarshal([]byte(actual), &av) != nil {
+		if expected != actual {
+			return []string{"body"}
+		}
+		return nil
+	}
+
+	diffs := make([]string, 0)
+	r.diffValue("", ev, av, &diffs)
+	sort.Strings(diffs)
+	return diffs
+}
+
+func (r *Replayer) diffValue(path string, ev, av interface{}, diffs *[]string) {
+	if r.ignore[path] {
+		return
+	}
+
+	switch e := ev.(type) {
+	case map[string]interface{}:
+		a, ok := av.(map[string]interface{})
+		if !ok {
+			*diffs = append(*diffs, pathName(path))
+			return
+		}
+		keys := make(map[string]bool, len(e)+len(a))
+		for k := range e {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+		for k := range keys {
+			r.diffValue(joinPath(path, k), e[k], a[k], diffs)
+		}
+	case []interface{}:
+		a, ok := av.([]interface{})
+		if !ok || len(a) != len(e) {
+			*diffs = append(*diffs, pathName(path))
+			return
+		}
+		for i := range e {
+			r.diffValue(joinPath(path, fmt.Sprint(i)), e[i], a[i], diffs)
+		}
+	default:
+		if fmt.Sprint(ev) != fmt.Sprint(av) {
+			*diffs = append(*diffs, pathName(path))
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
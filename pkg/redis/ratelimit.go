@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// RateLimiter 限制每秒执行的命令数，按固定间隔放行，pipeline 中的每个命令单独计数
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter 每秒最多放行 rate 个命令，rate <= 0 时返回 nil 表示不限制
+func NewRateLimiter(rate int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(rate)}
+}
+
+// Wait 等待放行 n 个命令，ctx 结束时返回 ctx 的错误
+func (l *RateLimiter) Wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval * time.Duration(n))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type rateLimiterKey struct{}
+
+// WithRateLimiter 在 ctx 中绑定限流器，之后通过 WithContext 获取的 client 发出的命令都会被限流
+func WithRateLimiter(ctx context.Context, l *RateLimiter) context.Context {
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterKey{}, l)
+}
+
+func rateLimiterFrom(ctx context.Context) *RateLimiter {
+	l, _ := ctx.Value(rateLimiterKey{}).(*RateLimiter)
+	return l
+}
+
+// LimitRate 限制 client 每秒执行的命令数，会修改传入的 client，
+// 用于计划任务等后台进程限制整个进程对 redis 的压力
+func LimitRate(client *redis.Client, l *RateLimiter) {
+	if l == nil {
+		return
+	}
+	wrapRateLimit(client, client.Context(), l)
+}
+
+// wrapRateLimit 执行命令前等待限流器放行，ctx 结束时不再发出命令
+func wrapRateLimit(client *redis.Client, ctx context.Context, l *RateLimiter) {
+	client.WrapProcess(func(old func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			if err := l.Wait(ctx, 1); err != nil {
+				log.Warnf("[redis] skip cmd %s: %v", cmd.Name(), err)
+				return closedClient.Process(cmd)
+			}
+			return old(cmd)
+		}
+	})
+	client.WrapProcessPipeline(func(old func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			if err := l.Wait(ctx, len(cmds)); err != nil {
+				log.Warnf("[redis] skip pipeline of %d cmds: %v", len(cmds), err)
+				return skipPipeline(cmds)
+			}
+			return old(cmds)
+		}
+	})
+}
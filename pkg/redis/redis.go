@@ -1,12 +1,17 @@
 package redis
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/alicebob/miniredis"
+	miniredisv2 "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis"
 	"github.com/spf13/viper"
 
+	"github.com/1024casts/snake/pkg/deadline"
+	"github.com/1024casts/snake/pkg/demo"
 	"github.com/1024casts/snake/pkg/log"
 )
 
@@ -16,10 +21,27 @@ var RedisClient *redis.Client
 // Nil redis 返回为空
 const Nil = redis.Nil
 
-// Init 实例化一个redis client
+// Init 实例化一个redis client，redis 不可用时 panic
 func Init() *redis.Client {
+	Connect()
+
+	_, err := RedisClient.Ping().Result()
+	if err != nil {
+		log.Errorf("[redis] redis ping err: %+v", err)
+		panic(err)
+	}
+	return RedisClient
+}
+
+// Connect 实例化一个redis client，不检查 redis 是否可用，连接在第一次使用时建立
+// 演示模式下连接进程内启动的 redis
+func Connect() *redis.Client {
+	addr := viper.GetString("redis.addr")
+	if demo.Enabled() {
+		addr = demoAddr()
+	}
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:         viper.GetString("redis.addr"),
+		Addr:         addr,
 		Password:     viper.GetString("redis.password"),
 		DB:           viper.GetInt("redis.db"),
 		DialTimeout:  viper.GetDuration("redis.dial_timeout"),
@@ -29,14 +51,95 @@ func Init() *redis.Client {
 		PoolTimeout:  viper.GetDuration("redis.pool_timeout"),
 	})
 
-	fmt.Println("redis addr:", viper.GetString("redis.addr"))
+	fmt.Println("redis addr:", addr)
+	return RedisClient
+}
 
-	_, err := RedisClient.Ping().Result()
-	if err != nil {
-		log.Errorf("[redis] redis ping err: %+v", err)
-		panic(err)
+var (
+	demoOnce   sync.Once
+	demoServer *miniredisv2.Miniredis
+)
+
+// demoAddr 演示模式在进程内启动 redis，只启动一次，数据在重启后重置
+// 使用 miniredis v2，支持 stream 和 pub/sub
+func demoAddr() string {
+	demoOnce.Do(func() {
+		var err error
+		demoServer, err = miniredisv2.Run()
+		if err != nil {
+			log.Errorf("[redis] start demo redis err: %+v", err)
+			panic(err)
+		}
+	})
+	return demoServer.Addr()
+}
+
+// Ping 探测默认的 redis 和各地区的 redis 是否可用
+func Ping() error {
+	if _, err := RedisClient.Ping().Result(); err != nil {
+		return err
 	}
-	return RedisClient
+	for region, client := range regionClients {
+		if _, err := client.Ping().Result(); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// closedClient 已关闭的 client，ctx 超时后的命令交给它执行，命令返回 redis: client is closed
+var closedClient = func() *redis.Client {
+	client := redis.NewClient(&redis.Options{})
+	_ = client.Close()
+	return client
+}()
+
+// WithContext 返回绑定了请求 ctx 的 client，使用 deadline.Downstream 的预算，
+// 超时或客户端断开连接后不再发出新的命令；go-redis v6 不能中断已发出的命令，单个命令的耗时由 read_timeout 限制。
+// ctx 通过 WithRateLimiter 绑定了限流器时，命令在发出前等待限流器放行
+func WithContext(ctx context.Context) *redis.Client {
+	limiter := rateLimiterFrom(ctx)
+	ctx = deadline.Downstream(ctx)
+	if ctx.Done() == nil && limiter == nil {
+		return RedisClient
+	}
+
+	client := RedisClient.WithContext(ctx)
+	if limiter != nil {
+		wrapRateLimit(client, ctx, limiter)
+	}
+	if ctx.Done() == nil {
+		return client
+	}
+	client.WrapProcess(func(old func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			if err := ctx.Err(); err != nil {
+				log.Warnf("[redis] skip cmd %s: %v", cmd.Name(), err)
+				return closedClient.Process(cmd)
+			}
+			return old(cmd)
+		}
+	})
+	client.WrapProcessPipeline(func(old func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			if err := ctx.Err(); err != nil {
+				log.Warnf("[redis] skip pipeline of %d cmds: %v", len(cmds), err)
+				return skipPipeline(cmds)
+			}
+			return old(cmds)
+		}
+	})
+	return client
+}
+
+// skipPipeline 交给已关闭的 client 执行，每个命令都返回 redis: client is closed
+func skipPipeline(cmds []redis.Cmder) error {
+	pipe := closedClient.Pipeline()
+	for _, cmd := range cmds {
+		_ = pipe.Process(cmd)
+	}
+	_, err := pipe.Exec()
+	return err
 }
 
 // InitTestRedis 实例化一个可以用于单元测试的redis
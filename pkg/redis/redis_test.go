@@ -1,8 +1,11 @@
 package redis
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/1024casts/snake/pkg/log"
 )
 
 func TestInitTestRedis(t *testing.T) {
@@ -32,3 +35,55 @@ func TestRedisSetGet(t *testing.T) {
 
 	t.Log("redis set get test pass")
 }
+
+func TestWithContext(t *testing.T) {
+	InitTestRedis()
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := WithContext(ctx)
+	if err := client.Set("test-ctx", "1", time.Minute).Err(); err != nil {
+		t.Fatalf("set err: %v", err)
+	}
+
+	// ctx 取消后不再发出命令
+	cancel()
+	if err := client.Get("test-ctx").Err(); err == nil {
+		t.Error("get after cancel want err")
+	}
+	pipe := client.Pipeline()
+	pipe.Get("test-ctx")
+	if _, err := pipe.Exec(); err == nil {
+		t.Error("pipeline after cancel want err")
+	}
+	if val := RedisClient.Get("test-ctx").Val(); val != "1" {
+		t.Errorf("default client get = %q, want 1", val)
+	}
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	InitTestRedis()
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+
+	// 每秒 20 个命令，第一个立即放行，之后每 50ms 放行一个
+	ctx := WithRateLimiter(context.Background(), NewRateLimiter(20))
+	client := WithContext(ctx)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := client.Set("test-rate", i, time.Minute).Err(); err != nil {
+			t.Fatalf("set err: %v", err)
+		}
+	}
+	if cost := time.Since(start); cost < 200*time.Millisecond {
+		t.Errorf("5 cmds took %s, want >= 200ms", cost)
+	}
+
+	// 等待期间 ctx 结束时不再发出命令
+	ctx, cancel := context.WithTimeout(WithRateLimiter(context.Background(), NewRateLimiter(1)), 100*time.Millisecond)
+	defer cancel()
+	client = WithContext(ctx)
+	_ = client.Get("test-rate").Err()
+	if err := client.Get("test-rate").Err(); err == nil {
+		t.Error("want err after ctx timeout")
+	}
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/lifecycle"
+)
+
+// regionClients 按地区单独部署的 redis，key 和用户的 region 一致
+var regionClients = map[string]*redis.Client{}
+
+// InitRegions 初始化配置 residency.regions 中各地区的 redis，和地区的数据库成对使用
+// 不检查 redis 是否可用，由 Ping 探测
+func InitRegions() map[string]*redis.Client {
+	if !viper.GetBool("residency.enable") {
+		return regionClients
+	}
+
+	for region := range viper.GetStringMap("residency.regions") {
+		key := fmt.Sprintf("residency.regions.%s.redis", region)
+		if !viper.IsSet(key) {
+			continue
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:         viper.GetString(key + ".addr"),
+			Password:     viper.GetString(key + ".password"),
+			DB:           viper.GetInt(key + ".db"),
+			DialTimeout:  viper.GetDuration("redis.dial_timeout"),
+			ReadTimeout:  viper.GetDuration("redis.read_timeout"),
+			WriteTimeout: viper.GetDuration("redis.write_timeout"),
+			PoolSize:     viper.GetInt("redis.pool_size"),
+			PoolTimeout:  viper.GetDuration("redis.pool_timeout"),
+		})
+		regionClients[region] = client
+	}
+	return regionClients
+}
+
+// Close 关闭默认的 redis 和各地区 redis 的连接池，返回第一个错误
+func Close() error {
+	var err error
+	if RedisClient != nil {
+		err = RedisClient.Close()
+	}
+	for _, client := range regionClients {
+		if e := client.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Hook 连接池的退出回调，和数据库一起最先注册
+func Hook() lifecycle.Hook {
+	return lifecycle.Hook{Name: "redis", OnStop: func(ctx context.Context) error { return Close() }}
+}
+
+// GetRegionClient 返回地区的 redis，未单独部署的地区返回默认的 redis
+func GetRegionClient(region string) *redis.Client {
+	if client, ok := regionClients[region]; ok {
+		return client
+	}
+	return RedisClient
+}
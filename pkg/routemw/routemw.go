@@ -0,0 +1,177 @@
+// 按路由声明的中间件，在配置文件 middleware.routes 中声明哪些路由需要额外的认证、邮箱验证、限流、并发限制、幂等、缓存、请求合并，
+// 配置文件修改后热加载，新上线的公开接口可以直接在线上加限流，不需要重新部署
+
+package routemw
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 支持的中间件，按下面的顺序执行
+const (
+	Auth        = "auth"
+	Verified    = "verified"
+	RateLimit   = "ratelimit"
+	Concurrency = "concurrency"
+	Idempotency = "idempotency"
+	Cache       = "cache"
+	Coalesce    = "coalesce"
+)
+
+// Rule 路由规则，method 为空时匹配所有方法，route 为注册的路由的前缀
+type Rule struct {
+	Method string   `mapstructure:"method"`
+	Route  string   `mapstructure:"route"`
+	Use    []string `mapstructure:"use"`
+	// Limit、Window ratelimit 的限制，为0时使用 ratelimit 的默认值
+	Limit  int64         `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+	// CacheTTL cache 返回的 Cache-Control max-age
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// MaxConcurrency concurrency 同时处理的请求数，规则命中的所有路由共用
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+	// RetryAfter concurrency 已满时返回的 Retry-After，为0时使用 DefaultRetryAfter
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// DefaultRetryAfter 并发已满时默认建议客户端重试的间隔
+const DefaultRetryAfter = time.Second
+
+// Policy 路由命中的规则合并后的结果
+type Policy struct {
+	Auth bool
+	// Verified 只允许邮箱已验证的用户访问，声明 verified 时同时开启 auth
+	Verified    bool
+	Idempotency bool
+	// RateLimit 第一条声明了 ratelimit 的规则
+	RateLimit *Rule
+	// Concurrency 第一条声明了 concurrency 的规则
+	Concurrency *Rule
+	// CacheTTL 第一条声明了 cache 的规则的缓存时间
+	CacheTTL time.Duration
+	// Coalesce 合并相同的并发 GET 请求
+	Coalesce bool
+}
+
+// Table 路由规则
+type Table struct {
+	rules []Rule
+}
+
+// current 当前生效的规则，热加载时整体替换
+var current atomic.Value
+
+// Init 加载配置中的路由规则，并在配置文件变化时重新加载
+func Init() *Table {
+	t := Reload()
+	conf.OnChange(func() { Reload() })
+	return t
+}
+
+// Reload 从配置重新加载路由规则，配置有误时保留原来的规则
+func Reload() *Table {
+	rules := make([]Rule, 0)
+	if err := viper.UnmarshalKey("middleware.routes", &rules); err != nil {
+		log.Warnf("[routemw] unmarshal routes err: %v", err)
+		return Get()
+	}
+	t := New(rules)
+	Set(t)
+	log.Infof("[routemw] route middleware loaded, rules: %d", len(t.rules))
+	return t
+}
+
+// New 实例化路由规则，忽略不支持的中间件
+func New(rules []Rule) *Table {
+	t := &Table{rules: make([]Rule, 0, len(rules))}
+	for _, r := range rules {
+		if !strings.HasPrefix(r.Route, "/") {
+			log.Warnf("[routemw] invalid route: %q", r.Route)
+			continue
+		}
+		r.Method = strings.ToUpper(r.Method)
+		use := make([]string, 0, len(r.Use))
+		for _, name := range r.Use {
+			switch name {
+			case Concurrency:
+				if r.MaxConcurrency <= 0 {
+					log.Warnf("[routemw] concurrency requires max_concurrency > 0, route: %s", r.Route)
+					continue
+				}
+				use = append(use, name)
+			case Auth, Verified, RateLimit, Idempotency, Cache, Coalesce:
+				use = append(use, name)
+			default:
+				log.Warnf("[routemw] unknown middleware: %q, route: %s", name, r.Route)
+			}
+		}
+		r.Use = use
+		t.rules = append(t.rules, r)
+	}
+	return t
+}
+
+// Set 替换当前生效的规则
+func Set(t *Table) {
+	current.Store(t)
+}
+
+// Get 当前生效的规则，未加载时为空
+func Get() *Table {
+	if t, ok := current.Load().(*Table); ok {
+		return t
+	}
+	return &Table{}
+}
+
+// Match 合并路由命中的所有规则
+func (t *Table) Match(method, route string) Policy {
+	p := Policy{}
+	if route == "" {
+		return p
+	}
+	for i := range t.rules {
+		r := &t.rules[i]
+		if (r.Method != "" && r.Method != method) || !strings.HasPrefix(route, r.Route) {
+			continue
+		}
+		for _, name := range r.Use {
+			switch name {
+			case Auth:
+				p.Auth = true
+			case Verified:
+				p.Auth = true
+				p.Verified = true
+			case Idempotency:
+				p.Idempotency = true
+			case Coalesce:
+				p.Coalesce = true
+			case RateLimit:
+				if p.RateLimit == nil {
+					p.RateLimit = r
+				}
+			case Concurrency:
+				if p.Concurrency == nil {
+					p.Concurrency = r
+				}
+			case Cache:
+				if p.CacheTTL == 0 {
+					p.CacheTTL = r.CacheTTL
+				}
+			}
+		}
+	}
+	return p
+}
+
+// Match 按当前生效的规则匹配
+func Match(method, route string) Policy {
+	return Get().Match(method, route)
+}
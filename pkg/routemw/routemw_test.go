@@ -0,0 +1,75 @@
+package routemw
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	m.Run()
+}
+
+func TestTable_Match(t *testing.T) {
+	asserts := assert.New(t)
+
+	table := New([]Rule{
+		{Method: "get", Route: "/v1/policies", Use: []string{RateLimit, Cache}, Limit: 10, Window: time.Minute, CacheTTL: 5 * time.Minute},
+		{Route: "/v1/", Use: []string{Idempotency, "unknown"}},
+		{Method: "GET", Route: "/v1/users", Use: []string{Coalesce}},
+		{Method: "POST", Route: "/v1/users", Use: []string{Auth, RateLimit}, Limit: 5},
+		{Route: "v1/invalid", Use: []string{Auth}},
+		{Method: "POST", Route: "/v1/users/follow", Use: []string{Verified}},
+	})
+
+	p := table.Match("GET", "/v1/policies/:type")
+	asserts.False(p.Auth)
+	asserts.True(p.Idempotency)
+	asserts.Equal(int64(10), p.RateLimit.Limit)
+	asserts.Equal(5*time.Minute, p.CacheTTL)
+	asserts.False(p.Coalesce)
+	asserts.True(table.Match("GET", "/v1/users/:id").Coalesce)
+
+	p = table.Match("POST", "/v1/users/:id/follow")
+	asserts.True(p.Auth)
+	asserts.Equal(int64(5), p.RateLimit.Limit)
+	asserts.Equal(time.Duration(0), p.CacheTTL)
+	asserts.False(p.Verified)
+
+	// verified 依赖登录用户，同时开启 auth
+	p = table.Match("POST", "/v1/users/follow")
+	asserts.True(p.Auth)
+	asserts.True(p.Verified)
+
+	asserts.Equal(Policy{}, table.Match("GET", "/health"))
+	asserts.Equal(Policy{}, table.Match("GET", ""))
+}
+
+func TestTable_MatchConcurrency(t *testing.T) {
+	asserts := assert.New(t)
+
+	table := New([]Rule{
+		{Method: "GET", Route: "/v1/admin/follows/export", Use: []string{Concurrency}, MaxConcurrency: 2},
+		{Route: "/v1/admin", Use: []string{Concurrency}, MaxConcurrency: 10},
+		// 没有设置 max_concurrency 的规则忽略 concurrency
+		{Route: "/v1/search", Use: []string{Concurrency}},
+	})
+
+	asserts.Equal(2, table.Match("GET", "/v1/admin/follows/export").Concurrency.MaxConcurrency)
+	asserts.Equal(10, table.Match("GET", "/v1/admin/users").Concurrency.MaxConcurrency)
+	asserts.Nil(table.Match("GET", "/v1/search").Concurrency)
+}
+
+func TestSet(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal(Policy{}, Match("GET", "/v1/policies/tos"))
+	Set(New([]Rule{{Route: "/v1/policies", Use: []string{Auth}}}))
+	asserts.True(Match("GET", "/v1/policies/tos").Auth)
+	Set(New(nil))
+	asserts.False(Match("GET", "/v1/policies/tos").Auth)
+}
@@ -0,0 +1,53 @@
+package saga
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// mysqlStore mysql 存储
+type mysqlStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLStore 实例化 mysql 存储
+func NewMySQLStore(db *gorm.DB) Store {
+	return &mysqlStore{db: db}
+}
+
+func (s *mysqlStore) Create(r *Record) error {
+	now := time.Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	if err := s.db.Create(r).Error; err != nil {
+		return errors.Wrap(err, "[saga] create record err")
+	}
+	return nil
+}
+
+func (s *mysqlStore) Update(r *Record) error {
+	r.UpdatedAt = time.Now()
+	err := s.db.Model(&Record{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+		"status":     r.Status,
+		"step":       r.Step,
+		"data":       r.Data,
+		"error":      r.Error,
+		"updated_at": r.UpdatedAt,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "[saga] update record err")
+	}
+	return nil
+}
+
+func (s *mysqlStore) ListPending(before time.Time, limit int) ([]*Record, error) {
+	records := make([]*Record, 0)
+	err := s.db.Where("status in (?) and updated_at < ?", []string{StatusRunning, StatusCompensating, StatusFailed}, before).
+		Order("id asc").Limit(limit).Find(&records).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Wrap(err, "[saga] list pending records err")
+	}
+	return records, nil
+}
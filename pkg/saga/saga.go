@@ -0,0 +1,187 @@
+// Package saga 多步骤操作的补偿事务
+// 每个步骤成功后持久化进度，某一步失败时按相反顺序执行已完成步骤的补偿操作，
+// 进程崩溃导致中断的 saga 由定时任务调用 Recover 继续补偿
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// maxErrorLen 记录的错误信息的最大长度
+const maxErrorLen = 1024
+
+// Data saga 的数据，在步骤之间传递，会被持久化，补偿时可以读取执行时记录的值
+type Data map[string]json.RawMessage
+
+// Set 写入数据，值需要可以被序列化为json
+func (d Data) Set(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d[key] = b
+	return nil
+}
+
+// Get 读取数据，key 不存在时不修改 v
+func (d Data) Get(key string, v interface{}) error {
+	b, ok := d[key]
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Step 步骤，Action 需要是原子的，失败时不会执行自己的 Compensate
+type Step struct {
+	Name   string
+	Action func(ctx context.Context, data Data) error
+	// Compensate 撤销 Action 的修改，需要可以重复执行，不需要撤销时为nil
+	Compensate func(ctx context.Context, data Data) error
+}
+
+// Definition saga 的定义，按名称注册后才能在崩溃后恢复
+type Definition struct {
+	Name  string
+	Steps []*Step
+}
+
+var (
+	mu          sync.RWMutex
+	definitions = make(map[string]*Definition)
+)
+
+// Register 注册 saga，一般在包的 init 中调用
+func Register(def *Definition) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := definitions[def.Name]; ok {
+		panic("saga: duplicate definition " + def.Name)
+	}
+	definitions[def.Name] = def
+}
+
+func getDefinition(name string) (*Definition, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	def, ok := definitions[name]
+	return def, ok
+}
+
+// Run 使用默认的存储执行 saga
+// 执行失败并且补偿成功时返回步骤的错误，补偿失败时 saga 保持 failed 状态，由 Recover 重试
+func Run(ctx context.Context, name string, data Data) error {
+	def, ok := getDefinition(name)
+	if !ok {
+		return fmt.Errorf("saga: unknown definition %s", name)
+	}
+	if data == nil {
+		data = Data{}
+	}
+
+	r := &Record{Name: name, Status: StatusRunning}
+	if err := r.setData(data); err != nil {
+		return err
+	}
+	if err := Default.Create(r); err != nil {
+		return errors.Wrapf(err, "[saga] create record err, name: %s", name)
+	}
+
+	for i, step := range def.Steps {
+		if err := step.Action(ctx, data); err != nil {
+			log.Warnf("[saga] %s step %s err: %v, id: %d", name, step.Name, err, r.ID)
+			r.Error = truncate(step.Name+": "+err.Error(), maxErrorLen)
+			if cerr := compensate(ctx, def, r, data); cerr != nil {
+				return errors.Wrapf(cerr, "[saga] %s compensate err after step %s failed: %v", name, step.Name, err)
+			}
+			return err
+		}
+		r.Step = i + 1
+		if err := r.setData(data); err != nil {
+			return err
+		}
+		if err := Default.Update(r); err != nil {
+			return errors.Wrapf(err, "[saga] update record err, id: %d", r.ID)
+		}
+	}
+
+	r.Status = StatusDone
+	if err := Default.Update(r); err != nil {
+		return errors.Wrapf(err, "[saga] update record err, id: %d", r.ID)
+	}
+	return nil
+}
+
+// Recover 补偿 before 之前中断或补偿失败的 saga，返回补偿成功的个数
+func Recover(ctx context.Context, before time.Time, limit int) (int, error) {
+	records, err := Default.ListPending(before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "[saga] list pending records err")
+	}
+
+	count := 0
+	for _, r := range records {
+		def, ok := getDefinition(r.Name)
+		if !ok {
+			log.Warnf("[saga] unknown definition %s, id: %d", r.Name, r.ID)
+			continue
+		}
+		data := Data{}
+		if err := json.Unmarshal([]byte(r.Data), &data); err != nil {
+			log.Warnf("[saga] unmarshal data err: %v, id: %d", err, r.ID)
+			continue
+		}
+		if r.Error == "" {
+			r.Error = "interrupted"
+		}
+		if err := compensate(ctx, def, r, data); err != nil {
+			log.Warnf("[saga] recover %s err: %v, id: %d", r.Name, err, r.ID)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// compensate 从最后完成的步骤开始倒序补偿，每补偿一步减少 Step，中断后可以继续
+func compensate(ctx context.Context, def *Definition, r *Record, data Data) error {
+	r.Status = StatusCompensating
+	if err := Default.Update(r); err != nil {
+		return err
+	}
+
+	for r.Step > 0 {
+		step := def.Steps[r.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, data); err != nil {
+				r.Status = StatusFailed
+				if uerr := Default.Update(r); uerr != nil {
+					log.Warnf("[saga] update record err: %v, id: %d", uerr, r.ID)
+				}
+				return errors.Wrapf(err, "compensate step %s", step.Name)
+			}
+		}
+		r.Step--
+		if err := Default.Update(r); err != nil {
+			return err
+		}
+	}
+
+	r.Status = StatusCompensated
+	return Default.Update(r)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,109 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// memoryStore 测试用的内存存储
+type memoryStore struct {
+	records map[uint64]*Record
+}
+
+func (s *memoryStore) Create(r *Record) error {
+	r.ID = uint64(len(s.records) + 1)
+	r.UpdatedAt = time.Now()
+	s.records[r.ID] = r
+	return nil
+}
+
+func (s *memoryStore) Update(r *Record) error {
+	r.UpdatedAt = time.Now()
+	s.records[r.ID] = r
+	return nil
+}
+
+func (s *memoryStore) ListPending(before time.Time, limit int) ([]*Record, error) {
+	var records []*Record
+	for _, r := range s.records {
+		if r.Status != StatusDone && r.Status != StatusCompensated && r.UpdatedAt.Before(before) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	m.Run()
+}
+
+func TestRun_Compensate(t *testing.T) {
+	store := &memoryStore{records: make(map[uint64]*Record)}
+	Default = store
+
+	var calls []string
+	step := func(name string, fail bool) *Step {
+		return &Step{
+			Name: name,
+			Action: func(ctx context.Context, data Data) error {
+				calls = append(calls, name)
+				if fail {
+					return errors.New("boom")
+				}
+				return data.Set(name, true)
+			},
+			Compensate: func(ctx context.Context, data Data) error {
+				var done bool
+				if err := data.Get(name, &done); err != nil || !done {
+					t.Errorf("compensate %s without data", name)
+				}
+				calls = append(calls, "undo_"+name)
+				return nil
+			},
+		}
+	}
+	Register(&Definition{Name: "test_compensate", Steps: []*Step{step("a", false), step("b", false), step("c", true)}})
+
+	err := Run(context.Background(), "test_compensate", nil)
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("run err got %v", err)
+	}
+	want := []string{"a", "b", "c", "undo_b", "undo_a"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls got %v, want %v", calls, want)
+	}
+	if r := store.records[1]; r.Status != StatusCompensated || r.Step != 0 {
+		t.Errorf("record got %+v", r)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	store := &memoryStore{records: make(map[uint64]*Record)}
+	Default = store
+
+	undone := 0
+	Register(&Definition{Name: "test_recover", Steps: []*Step{
+		{Name: "a", Action: func(ctx context.Context, data Data) error { return nil },
+			Compensate: func(ctx context.Context, data Data) error { undone++; return nil }},
+		{Name: "b", Action: func(ctx context.Context, data Data) error { return nil }},
+	}})
+
+	// 模拟执行完第一步后进程崩溃
+	r := &Record{Name: "test_recover", Status: StatusRunning, Step: 1, Data: "{}"}
+	_ = store.Create(r)
+	r.UpdatedAt = time.Now().Add(-time.Hour)
+
+	n, err := Recover(context.Background(), time.Now().Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || undone != 1 || r.Status != StatusCompensated {
+		t.Errorf("recover got n=%d undone=%d status=%s", n, undone, r.Status)
+	}
+}
@@ -0,0 +1,66 @@
+package saga
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/1024casts/snake/internal/model"
+)
+
+// saga 状态
+const (
+	// StatusRunning 执行中
+	StatusRunning = "running"
+	// StatusDone 全部步骤执行成功
+	StatusDone = "done"
+	// StatusCompensating 补偿中
+	StatusCompensating = "compensating"
+	// StatusCompensated 补偿完成
+	StatusCompensated = "compensated"
+	// StatusFailed 补偿失败，等待重试
+	StatusFailed = "failed"
+)
+
+// Default 默认的存储
+var Default Store
+
+// Record saga 的执行记录
+type Record struct {
+	ID     uint64 `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Name   string `gorm:"column:name" json:"name"`
+	Status string `gorm:"column:status" json:"status"`
+	// Step 已完成并且没有被补偿的步骤数
+	Step      int       `gorm:"column:step" json:"step"`
+	Data      string    `gorm:"column:data" json:"data"`
+	Error     string    `gorm:"column:error" json:"error"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (r *Record) TableName() string {
+	return "saga"
+}
+
+func (r *Record) setData(data Data) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	r.Data = string(b)
+	return nil
+}
+
+// Store saga 记录的存储
+type Store interface {
+	Create(r *Record) error
+	Update(r *Record) error
+	// ListPending 获取 before 之前没有更新的执行中、补偿中或补偿失败的记录
+	ListPending(before time.Time, limit int) ([]*Record, error)
+}
+
+// Init 初始化默认的存储，使用 mysql
+func Init() Store {
+	Default = NewMySQLStore(model.GetDB())
+	return Default
+}
@@ -0,0 +1,87 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ElasticsearchConfig Elasticsearch 配置
+type ElasticsearchConfig struct {
+	Addr     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// elasticsearch 通过 REST 接口读写文档
+// see: https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-index_.html
+type elasticsearch struct {
+	addr     string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewElasticsearch 实例化 Elasticsearch 索引
+func NewElasticsearch(c ElasticsearchConfig) Indexer {
+	return &elasticsearch{
+		addr:     strings.TrimRight(c.Addr, "/"),
+		username: c.Username,
+		password: c.Password,
+		client:   &http.Client{Timeout: c.Timeout},
+	}
+}
+
+// Index 写入文档
+func (es *elasticsearch) Index(ctx context.Context, index, id string, doc interface{}) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = es.do(ctx, http.MethodPut, es.docURL(index, id), b)
+	return err
+}
+
+// Delete 删除文档
+func (es *elasticsearch) Delete(ctx context.Context, index, id string) error {
+	status, err := es.do(ctx, http.MethodDelete, es.docURL(index, id), nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (es *elasticsearch) docURL(index, id string) string {
+	return es.addr + "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id)
+}
+
+func (es *elasticsearch) do(ctx context.Context, method, u string, body []byte) (int, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if es.username != "" {
+		req.SetBasicAuth(es.username, es.password)
+	}
+
+	resp, err := es.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("search: elasticsearch %s %s status: %d, body: %s", method, u, resp.StatusCode, b)
+	}
+	return resp.StatusCode, nil
+}
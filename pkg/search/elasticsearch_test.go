@@ -0,0 +1,45 @@
+package search
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestElasticsearch(t *testing.T) {
+	docs := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			b, _ := ioutil.ReadAll(r.Body)
+			docs[r.URL.Path] = string(b)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			if _, ok := docs[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(docs, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	es := NewElasticsearch(ElasticsearchConfig{Addr: srv.URL + "/", Timeout: time.Second})
+	ctx := context.Background()
+
+	if err := es.Index(ctx, "snake_user", "1", map[string]interface{}{"username": "snake"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := docs["/snake_user/_doc/1"]; got != `{"username":"snake"}` {
+		t.Errorf("indexed doc got %q", got)
+	}
+	if err := es.Delete(ctx, "snake_user", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Delete(ctx, "snake_user", "1"); err != nil {
+		t.Errorf("delete missing doc should not err, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Package search 搜索索引，目前支持 Elasticsearch，通过 search.driver 选择，为空时不写入索引
+package search
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DriverElasticsearch Elasticsearch 的 REST 接口
+	DriverElasticsearch = "elasticsearch"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// ErrUnknownDriver 未知的搜索驱动
+var ErrUnknownDriver = errors.New("search: unknown driver")
+
+// Client 全局的搜索索引，未配置时为nil
+var Client Indexer
+
+// Indexer 写入、删除索引中的文档
+type Indexer interface {
+	// Index 写入文档，id 相同时覆盖
+	Index(ctx context.Context, index, id string, doc interface{}) error
+	// Delete 删除文档，文档不存在时不返回错误
+	Delete(ctx context.Context, index, id string) error
+}
+
+// Init 按配置初始化全局的搜索索引
+func Init() Indexer {
+	idx, err := New(viper.GetString("search.driver"))
+	if err != nil {
+		panic(err)
+	}
+	Client = idx
+	return Client
+}
+
+// New 按驱动名称实例化搜索索引，名称为空时返回nil
+func New(driver string) (Indexer, error) {
+	timeout := viper.GetDuration("search.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch driver {
+	case "":
+		return nil, nil
+	case DriverElasticsearch:
+		return NewElasticsearch(ElasticsearchConfig{
+			Addr:     viper.GetString("search.elasticsearch.addr"),
+			Username: viper.GetString("search.elasticsearch.username"),
+			Password: viper.GetString("search.elasticsearch.password"),
+			Timeout:  timeout,
+		}), nil
+	}
+	return nil, ErrUnknownDriver
+}
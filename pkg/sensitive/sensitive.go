@@ -0,0 +1,81 @@
+// 敏感词过滤，词库配置在 sensitive.words 中
+// 匹配时忽略大小写和空白字符，避免通过插入空格绕过
+
+package sensitive
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// Default 全局的敏感词过滤器
+var Default = New(nil)
+
+// Filter 敏感词过滤器
+type Filter struct {
+	mu    sync.RWMutex
+	words []string
+}
+
+// Init 从配置加载词库
+func Init() *Filter {
+	Default.Load(viper.GetStringSlice("sensitive.words"))
+	return Default
+}
+
+// New 实例化过滤器
+func New(words []string) *Filter {
+	f := &Filter{}
+	f.Load(words)
+	return f
+}
+
+// Load 替换词库
+func (f *Filter) Load(words []string) {
+	normalized := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = normalize(w); w != "" {
+			normalized = append(normalized, w)
+		}
+	}
+
+	f.mu.Lock()
+	f.words = normalized
+	f.mu.Unlock()
+}
+
+// Match 返回文本中包含的敏感词，没有时返回空
+func (f *Filter) Match(text string) []string {
+	text = normalize(text)
+	if text == "" {
+		return nil
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var matched []string
+	for _, w := range f.words {
+		if strings.Contains(text, w) {
+			matched = append(matched, w)
+		}
+	}
+	return matched
+}
+
+// Contains 文本中是否包含敏感词
+func (f *Filter) Contains(text string) bool {
+	return len(f.Match(text)) > 0
+}
+
+// normalize 转为小写并去掉空白字符
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
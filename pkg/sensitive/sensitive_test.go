@@ -0,0 +1,26 @@
+package sensitive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	f := New([]string{"Spam", "广告", " "})
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello world", nil},
+		{"buy SPAM now", []string{"spam"}},
+		{"s p a m", []string{"spam"}},
+		{"这是广告, spam", []string{"spam", "广告"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
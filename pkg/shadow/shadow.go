@@ -0,0 +1,145 @@
+// Package shadow 影子读，用于灰度上线新的读取路径(如用读穿缓存替换直接查库)
+// 按比例同时执行旧路径(control)和新路径(candidate)，返回其中一个的结果，另一个异步执行后对比，
+// 不一致时记录日志和指标，确认新路径没有问题后再切换 serve 并下线旧路径
+package shadow
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 返回哪条路径的结果
+const (
+	// ServeControl 返回旧路径的结果，默认值
+	ServeControl = "control"
+	// ServeCandidate 返回新路径的结果
+	ServeCandidate = "candidate"
+)
+
+// 对比结果
+const (
+	ResultMatch    = "match"
+	ResultMismatch = "mismatch"
+	ResultError    = "error"
+)
+
+var comparisons = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "snake_shadow_comparisons_total",
+	Help: "Total number of shadow read comparisons.",
+}, []string{"name", "result"})
+
+// Config 影子读配置
+type Config struct {
+	Enable bool `mapstructure:"enable"`
+	// Serve 返回哪条路径的结果 control、candidate，未开启时只执行 serve 对应的路径
+	Serve string `mapstructure:"serve"`
+	// SampleRate 同时执行两条路径并对比的比例 0-1
+	SampleRate float64 `mapstructure:"sample_rate"`
+}
+
+var (
+	mu      sync.RWMutex
+	configs = make(map[string]Config)
+
+	randMu sync.Mutex
+	rnd    = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// async 在后台执行影子路径，测试时可以替换为同步执行
+	async = func(f func()) { go f() }
+)
+
+// Init 加载配置 shadow 下的所有影子读
+func Init() {
+	cfgs := make(map[string]Config)
+	if err := viper.UnmarshalKey("shadow", &cfgs); err != nil {
+		log.Warnf("[shadow] unmarshal config err: %v", err)
+		return
+	}
+	mu.Lock()
+	configs = cfgs
+	mu.Unlock()
+}
+
+// Set 设置影子读配置
+func Set(name string, cfg Config) {
+	mu.Lock()
+	configs[name] = cfg
+	mu.Unlock()
+}
+
+// Get 获取影子读配置，未配置时返回关闭的配置
+func Get(name string) Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return configs[name]
+}
+
+// Read 按配置执行读取
+// diff 返回两个结果不一致的字段，为空表示一致，shadow 路径的结果在后台对比，不影响返回
+func Read[T any](name string, control, candidate func() (T, error), diff func(control, candidate T) []string) (T, error) {
+	cfg := Get(name)
+	serve, shadow := control, candidate
+	if cfg.Serve == ServeCandidate {
+		serve, shadow = candidate, control
+	}
+
+	res, err := serve()
+	if !cfg.Enable || !sample(cfg.SampleRate) {
+		return res, err
+	}
+
+	async(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Warnf("[shadow] %s panic: %v", name, r)
+			}
+		}()
+
+		shadowRes, shadowErr := shadow()
+		if err != nil || shadowErr != nil {
+			if (err == nil) != (shadowErr == nil) {
+				comparisons.WithLabelValues(name, ResultError).Inc()
+				log.Warnf("[shadow] %s error mismatch, serve(%s) err: %v, shadow err: %v", name, serveName(cfg), err, shadowErr)
+				return
+			}
+			comparisons.WithLabelValues(name, ResultMatch).Inc()
+			return
+		}
+
+		controlRes, candidateRes := res, shadowRes
+		if cfg.Serve == ServeCandidate {
+			controlRes, candidateRes = shadowRes, res
+		}
+		if fields := diff(controlRes, candidateRes); len(fields) > 0 {
+			comparisons.WithLabelValues(name, ResultMismatch).Inc()
+			log.Warnf("[shadow] %s mismatch, fields: %v", name, fields)
+			return
+		}
+		comparisons.WithLabelValues(name, ResultMatch).Inc()
+	})
+
+	return res, err
+}
+
+func serveName(cfg Config) string {
+	if cfg.Serve == ServeCandidate {
+		return ServeCandidate
+	}
+	return ServeControl
+}
+
+func sample(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	randMu.Lock()
+	defer randMu.Unlock()
+	return rnd.Float64() < rate
+}
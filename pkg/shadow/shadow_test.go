@@ -0,0 +1,54 @@
+package shadow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestRead(t *testing.T) {
+	asserts := assert.New(t)
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	async = func(f func()) { f() }
+
+	calls := make([]string, 0)
+	control := func() (int, error) {
+		calls = append(calls, ServeControl)
+		return 1, nil
+	}
+	candidate := func() (int, error) {
+		calls = append(calls, ServeCandidate)
+		return 2, nil
+	}
+	diffs := make([][]string, 0)
+	diff := func(a, b int) []string {
+		if a == b {
+			return nil
+		}
+		diffs = append(diffs, []string{"value"})
+		return []string{"value"}
+	}
+
+	// 未配置时只执行 control
+	v, err := Read("test", control, candidate, diff)
+	asserts.NoError(err)
+	asserts.Equal(1, v)
+	asserts.Equal([]string{ServeControl}, calls)
+
+	// 返回 candidate 的结果，同时对比 control
+	calls = calls[:0]
+	Set("test", Config{Enable: true, Serve: ServeCandidate, SampleRate: 1})
+	v, err = Read("test", control, candidate, diff)
+	asserts.NoError(err)
+	asserts.Equal(2, v)
+	asserts.Equal([]string{ServeCandidate, ServeControl}, calls)
+	asserts.Len(diffs, 1)
+
+	// 只有一条路径出错时也记为不一致，不影响返回
+	_, err = Read("test", control, func() (int, error) { return 0, errors.New("cache err") }, diff)
+	asserts.Error(err)
+	asserts.Len(diffs, 1)
+}
@@ -0,0 +1,56 @@
+// 合并相同 key 的并发调用，同一时刻只有一个调用真正执行，其他调用等待并共享结果
+
+package singleflight
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPanic 执行的函数 panic 时等待的调用返回的错误
+var ErrPanic = errors.New("singleflight: function panicked")
+
+// call 正在执行的调用
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+	// dups 等待结果的调用数
+	dups int
+}
+
+// Group 调用分组，零值可以直接使用
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do 执行 fn，相同 key 已有调用在执行时等待其结果，shared 表示结果是否被多个调用共享
+// fn panic 时执行 fn 的调用继续 panic，等待的调用返回 ErrPanic
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{err: ErrPanic}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		shared = c.dups > 0
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.val, c.err = fn()
+	return c.val, c.err, shared
+}
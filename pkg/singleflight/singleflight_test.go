@@ -0,0 +1,76 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "ok", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	shared := make([]bool, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, shared[i] = g.Do("key", fn)
+		}(i)
+	}
+	// 等待所有调用进入等待
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	asserts.Equal(int32(1), atomic.LoadInt32(&calls))
+	for i := range results {
+		asserts.Equal("ok", results[i])
+		asserts.True(shared[i])
+	}
+
+	// 执行完成后相同 key 重新执行
+	v, err, s := g.Do("key", func() (interface{}, error) { return nil, errors.New("failed") })
+	asserts.Nil(v)
+	asserts.EqualError(err, "failed")
+	asserts.False(s)
+}
+
+func TestGroup_DoPanic(t *testing.T) {
+	asserts := assert.New(t)
+
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		defer func() { _ = recover() }()
+		_, _, _ = g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	go func() {
+		_, err, _ := g.Do("key", func() (interface{}, error) { return "other", nil })
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	asserts.Equal(ErrPanic, <-done)
+}
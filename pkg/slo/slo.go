@@ -0,0 +1,391 @@
+// Package slo 进程内的 SLO 告警，没有部署完整的告警系统时也能发现接口的可用性、延迟问题
+// 按路由配置可用性、延迟目标，在进程内按分钟统计请求数，计算短窗口、长窗口的错误预算消耗速度(burn rate)，
+// 两个窗口都超过阈值时通过 Notifier 发出告警，长窗口保证告警的意义，短窗口保证问题恢复后告警能及时停止
+// see: https://sre.google/workbook/alerting-on-slos/
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 告警类型
+const (
+	// KindAvailability 可用性
+	KindAvailability = "availability"
+	// KindLatency 延迟
+	KindLatency = "latency"
+)
+
+// 默认配置，对应 1 小时内消耗 2% 的月度错误预算
+const (
+	DefaultShortWindow = 5 * time.Minute
+	DefaultLongWindow  = time.Hour
+	DefaultBurnRate    = 14.4
+	DefaultMinRequests = 100
+	DefaultCooldown    = 30 * time.Minute
+	DefaultInterval    = time.Minute
+
+	// resolution 统计的时间粒度
+	resolution = time.Minute
+)
+
+// Client 全局的 SLO 统计，未开启时为 nil
+var Client *Tracker
+
+// Objective 路由的 SLO 目标
+type Objective struct {
+	// Method 为空时匹配所有方法
+	Method string `mapstructure:"method" json:"method"`
+	// Route 路由，和 gin 注册的路由一致，如 /v1/users/:id
+	Route string `mapstructure:"route" json:"route"`
+	// Availability 可用性目标，如 0.999，为0时不统计可用性
+	Availability float64 `mapstructure:"availability" json:"availability"`
+	// Latency 延迟阈值，超过时记为慢请求，为0时不统计延迟
+	Latency time.Duration `mapstructure:"latency" json:"latency"`
+	// LatencyTarget 延迟目标，未超过延迟阈值的请求比例，如 0.99
+	LatencyTarget float64 `mapstructure:"latency_target" json:"latency_target"`
+}
+
+// Name 目标的名称，用于告警
+func (o *Objective) Name() string {
+	if o.Method == "" {
+		return o.Route
+	}
+	return o.Method + " " + o.Route
+}
+
+// Alert 告警
+type Alert struct {
+	Kind          string        `json:"kind"`
+	Objective     Objective     `json:"objective"`
+	Target        float64       `json:"target"`
+	ShortWindow   time.Duration `json:"short_window"`
+	LongWindow    time.Duration `json:"long_window"`
+	ShortBurnRate float64       `json:"short_burn_rate"`
+	LongBurnRate  float64       `json:"long_burn_rate"`
+	Threshold     float64       `json:"threshold"`
+	FiredAt       time.Time     `json:"fired_at"`
+}
+
+// String 告警内容
+func (a *Alert) String() string {
+	return fmt.Sprintf("%s %s objective %.4f is burning error budget: %.1fx over %s, %.1fx over %s (threshold %.1fx)",
+		a.Objective.Name(), a.Kind, a.Target, a.LongBurnRate, a.LongWindow, a.ShortBurnRate, a.ShortWindow, a.Threshold)
+}
+
+// Notifier 发送告警
+type Notifier interface {
+	Notify(alert *Alert) error
+}
+
+// NotifierFunc 函数形式的 Notifier
+type NotifierFunc func(alert *Alert) error
+
+// Notify 发送告警
+func (f NotifierFunc) Notify(alert *Alert) error {
+	return f(alert)
+}
+
+// logNotifier 只记录日志，没有注入 Notifier 时使用
+type logNotifier struct{}
+
+// Notify 记录告警日志
+func (logNotifier) Notify(alert *Alert) error {
+	log.Warnf("[slo] %s", alert)
+	return nil
+}
+
+// Option 配置项
+type Option func(t *Tracker)
+
+// WithWindows 设置短窗口、长窗口
+func WithWindows(short, long time.Duration) Option {
+	return func(t *Tracker) {
+		t.shortWindow = short
+		t.longWindow = long
+	}
+}
+
+// WithBurnRate 设置告警的 burn rate 阈值
+func WithBurnRate(burnRate float64) Option {
+	return func(t *Tracker) {
+		t.burnRate = burnRate
+	}
+}
+
+// WithMinRequests 设置长窗口内的最少请求数，请求太少时不告警
+func WithMinRequests(n int64) Option {
+	return func(t *Tracker) {
+		t.minRequests = n
+	}
+}
+
+// WithCooldown 设置同一告警的最小间隔
+func WithCooldown(cooldown time.Duration) Option {
+	return func(t *Tracker) {
+		t.cooldown = cooldown
+	}
+}
+
+// WithNotifier 设置告警的发送方式
+func WithNotifier(notifier Notifier) Option {
+	return func(t *Tracker) {
+		t.notifier = notifier
+	}
+}
+
+// bucket 一个时间粒度内的请求数
+type bucket struct {
+	start  int64
+	total  int64
+	failed int64
+	slow   int64
+}
+
+// series 一个目标的统计
+type series struct {
+	objective Objective
+	buckets   []bucket
+}
+
+// Tracker 按路由统计请求，检查 SLO
+type Tracker struct {
+	shortWindow time.Duration
+	longWindow  time.Duration
+	burnRate    float64
+	minRequests int64
+	cooldown    time.Duration
+	notifier    Notifier
+	now         func() time.Time
+
+	mu     sync.Mutex
+	series map[string]*series
+	fired  map[string]time.Time
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// Init 初始化全局的 SLO 统计，未开启或没有配置目标时返回 nil
+func Init() *Tracker {
+	if !viper.GetBool("slo.enable") {
+		return nil
+	}
+	objectives := make([]Objective, 0)
+	if err := viper.UnmarshalKey("slo.objectives", &objectives); err != nil {
+		log.Warnf("[slo] unmarshal objectives err: %v", err)
+		return nil
+	}
+	if len(objectives) == 0 {
+		return nil
+	}
+
+	Client = New(objectives,
+		WithWindows(viper.GetDuration("slo.short_window"), viper.GetDuration("slo.long_window")),
+		WithBurnRate(viper.GetFloat64("slo.burn_rate")),
+		WithMinRequests(viper.GetInt64("slo.min_requests")),
+		WithCooldown(viper.GetDuration("slo.cooldown")),
+	)
+	Client.Start(viper.GetDuration("slo.check_interval"))
+	return Client
+}
+
+// New 实例化一个 SLO 统计
+func New(objectives []Objective, opts ...Option) *Tracker {
+	t := &Tracker{
+		shortWindow: DefaultShortWindow,
+		longWindow:  DefaultLongWindow,
+		burnRate:    DefaultBurnRate,
+		minRequests: DefaultMinRequests,
+		cooldown:    DefaultCooldown,
+		notifier:    logNotifier{},
+		now:         time.Now,
+		series:      make(map[string]*series),
+		fired:       make(map[string]time.Time),
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.shortWindow <= 0 {
+		t.shortWindow = DefaultShortWindow
+	}
+	if t.longWindow < t.shortWindow {
+		t.longWindow = DefaultLongWindow
+	}
+	if t.burnRate <= 0 {
+		t.burnRate = DefaultBurnRate
+	}
+	if t.minRequests <= 0 {
+		t.minRequests = DefaultMinRequests
+	}
+
+	size := int(t.longWindow / resolution)
+	if size < 1 {
+		size = 1
+	}
+	for _, o := range objectives {
+		t.series[key(o.Method, o.Route)] = &series{objective: o, buckets: make([]bucket, size)}
+	}
+	return t
+}
+
+// SetNotifier 设置告警的发送方式，由业务层在启动时注入
+func (t *Tracker) SetNotifier(notifier Notifier) {
+	t.mu.Lock()
+	t.notifier = notifier
+	t.mu.Unlock()
+}
+
+// Record 记录一次请求，没有配置目标的路由会被忽略
+func (t *Tracker) Record(method, route string, failed bool, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[key(method, route)]
+	if !ok {
+		if s, ok = t.series[key("", route)]; !ok {
+			return
+		}
+	}
+
+	start := t.now().Truncate(resolution).Unix()
+	b := &s.buckets[int(start/int64(resolution/time.Second))%len(s.buckets)]
+	if b.start != start {
+		*b = bucket{start: start}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+	if s.objective.Latency > 0 && latency > s.objective.Latency {
+		b.slow++
+	}
+}
+
+// Check 检查所有目标，返回并发送新的告警
+func (t *Tracker) Check() []*Alert {
+	t.mu.Lock()
+	now := t.now()
+	alerts := make([]*Alert, 0)
+	for k, s := range t.series {
+		o := s.objective
+		if o.Availability > 0 && o.Availability < 1 {
+			if a := t.evaluate(s, KindAvailability, o.Availability, now, func(b *bucket) int64 { return b.failed }); a != nil {
+				alerts = t.fire(alerts, k, a, now)
+			}
+		}
+		if o.Latency > 0 && o.LatencyTarget > 0 && o.LatencyTarget < 1 {
+			if a := t.evaluate(s, KindLatency, o.LatencyTarget, now, func(b *bucket) int64 { return b.slow }); a != nil {
+				alerts = t.fire(alerts, k, a, now)
+			}
+		}
+	}
+	notifier := t.notifier
+	t.mu.Unlock()
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Objective.Name()+alerts[i].Kind < alerts[j].Objective.Name()+alerts[j].Kind
+	})
+	for _, a := range alerts {
+		if err := notifier.Notify(a); err != nil {
+			log.Warnf("[slo] notify err: %v, alert: %s", err, a)
+		}
+	}
+	return alerts
+}
+
+// Start 定时检查, interval 为0时使用默认间隔
+func (t *Tracker) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.Check()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时检查
+func (t *Tracker) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	t.wg.Wait()
+}
+
+// evaluate 计算短窗口、长窗口的 burn rate，都超过阈值时返回告警
+func (t *Tracker) evaluate(s *series, kind string, target float64, now time.Time, bad func(b *bucket) int64) *Alert {
+	longTotal, longBad := sum(s, now, t.longWindow, bad)
+	if longTotal < t.minRequests {
+		return nil
+	}
+	shortTotal, shortBad := sum(s, now, t.shortWindow, bad)
+	if shortTotal == 0 {
+		return nil
+	}
+
+	budget := 1 - target
+	longRate := float64(longBad) / float64(longTotal) / budget
+	shortRate := float64(shortBad) / float64(shortTotal) / budget
+	if longRate < t.burnRate || shortRate < t.burnRate {
+		return nil
+	}
+	return &Alert{
+		Kind:          kind,
+		Objective:     s.objective,
+		Target:        target,
+		ShortWindow:   t.shortWindow,
+		LongWindow:    t.longWindow,
+		ShortBurnRate: shortRate,
+		LongBurnRate:  longRate,
+		Threshold:     t.burnRate,
+		FiredAt:       now,
+	}
+}
+
+// fire 同一告警在 cooldown 内只发送一次
+func (t *Tracker) fire(alerts []*Alert, k string, a *Alert, now time.Time) []*Alert {
+	k += "|" + a.Kind
+	if last, ok := t.fired[k]; ok && now.Sub(last) < t.cooldown {
+		return alerts
+	}
+	t.fired[k] = now
+	return append(alerts, a)
+}
+
+// sum 统计窗口内的总请求数和异常请求数
+func sum(s *series, now time.Time, window time.Duration, bad func(b *bucket) int64) (total, n int64) {
+	from := now.Add(-window).Unix()
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.start == 0 || b.start <= from {
+			continue
+		}
+		total += b.total
+		n += bad(b)
+	}
+	return total, n
+}
+
+func key(method, route string) string {
+	return method + " " + route
+}
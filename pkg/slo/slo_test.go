@@ -0,0 +1,53 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker_Check(t *testing.T) {
+	asserts := assert.New(t)
+
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.Local)
+	notified := make([]*Alert, 0)
+	tr := New([]Objective{
+		{Method: "GET", Route: "/v1/users/:id", Availability: 0.99, Latency: 100 * time.Millisecond, LatencyTarget: 0.9},
+	}, WithBurnRate(5), WithMinRequests(10), WithNotifier(NotifierFunc(func(a *Alert) error {
+		notified = append(notified, a)
+		return nil
+	})))
+	tr.now = func() time.Time { return now }
+
+	// 没有配置目标的路由被忽略
+	tr.Record("GET", "/v1/users/:id/avatar", true, time.Second)
+	// 10% 的错误率，burn rate 为 10
+	for i := 0; i < 100; i++ {
+		tr.Record("GET", "/v1/users/:id", i%10 == 0, 10*time.Millisecond)
+	}
+	alerts := tr.Check()
+	asserts.Len(alerts, 1)
+	asserts.Equal(KindAvailability, alerts[0].Kind)
+	asserts.InDelta(10, alerts[0].LongBurnRate, 0.001)
+	asserts.Len(notified, 1)
+
+	// cooldown 内不重复告警
+	asserts.Len(tr.Check(), 0)
+
+	// 错误发生在短窗口之外时，短窗口的 burn rate 为0，不告警
+	now = now.Add(DefaultCooldown)
+	for i := 0; i < 10; i++ {
+		tr.Record("GET", "/v1/users/:id", false, time.Second)
+	}
+	alerts = tr.Check()
+	asserts.Len(alerts, 0)
+
+	// 慢请求超过延迟目标
+	for i := 0; i < 100; i++ {
+		tr.Record("GET", "/v1/users/:id", false, time.Second)
+	}
+	alerts = tr.Check()
+	asserts.Len(alerts, 1)
+	asserts.Equal(KindLatency, alerts[0].Kind)
+}
@@ -0,0 +1,164 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// see: https://help.aliyun.com/document_detail/419273.html
+
+const (
+	aliyunDefaultEndpoint = "https://dysmsapi.aliyuncs.com"
+	aliyunDefaultRegion   = "cn-hangzhou"
+	aliyunAPIVersion      = "2017-05-25"
+	aliyunCodeOK          = "OK"
+)
+
+// AliyunConfig 阿里云短信服务配置
+type AliyunConfig struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	Endpoint        string
+	RegionID        string
+	// SignName 短信签名
+	SignName string
+	// Templates 模板名到模板的映射
+	Templates map[string]Template
+	Timeout   time.Duration
+}
+
+// aliyun 阿里云短信服务
+type aliyun struct {
+	conf   AliyunConfig
+	client *http.Client
+}
+
+// NewAliyun 实例化阿里云短信服务
+func NewAliyun(conf AliyunConfig) Provider {
+	if conf.Endpoint == "" {
+		conf.Endpoint = aliyunDefaultEndpoint
+	}
+	if conf.RegionID == "" {
+		conf.RegionID = aliyunDefaultRegion
+	}
+	return &aliyun{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (a *aliyun) Name() string {
+	return ProviderAliyun
+}
+
+// Template 获取模板
+func (a *aliyun) Template(name string) (Template, bool) {
+	tpl, ok := a.conf.Templates[name]
+	return tpl, ok && tpl.ID != ""
+}
+
+type aliyunSendResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizID     string `json:"BizId"`
+	RequestID string `json:"RequestId"`
+}
+
+// Send 发送短信，模板参数以 json 对象传递
+func (a *aliyun) Send(ctx context.Context, msg *Message, tpl Template) error {
+	params := make(map[string]string, len(tpl.Params))
+	for _, name := range tpl.Params {
+		params[name] = msg.Params[name]
+	}
+	tplParam, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("AccessKeyId", a.conf.AccessKeyID)
+	query.Set("Action", "SendSms")
+	query.Set("Format", "JSON")
+	query.Set("PhoneNumbers", aliyunPhone(msg.Phone))
+	query.Set("RegionId", a.conf.RegionID)
+	query.Set("SignName", a.conf.SignName)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureNonce", uuid.New().String())
+	query.Set("SignatureVersion", "1.0")
+	query.Set("TemplateCode", tpl.ID)
+	query.Set("TemplateParam", string(tplParam))
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	query.Set("Version", aliyunAPIVersion)
+	query.Set("Signature", a.sign(http.MethodGet, query))
+
+	req, err := http.NewRequest(http.MethodGet, a.conf.Endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentTypeJSON)
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var sendResp aliyunSendResponse
+	if err := json.Unmarshal(b, &sendResp); err != nil {
+		return fmt.Errorf("[sms] aliyun unmarshal resp err: %v, body: %s", err, b)
+	}
+	if sendResp.Code != aliyunCodeOK {
+		return fmt.Errorf("[sms] aliyun send err, code: %s, msg: %s, request_id: %s",
+			sendResp.Code, sendResp.Message, sendResp.RequestID)
+	}
+	return nil
+}
+
+// sign 按阿里云 RPC 风格签名
+func (a *aliyun) sign(method string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunEscape(k)+"="+aliyunEscape(query.Get(k)))
+	}
+
+	stringToSign := method + "&" + aliyunEscape("/") + "&" + aliyunEscape(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(a.conf.AccessKeySecret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunEscape 按 RFC3986 编码，空格编码为 %20
+func aliyunEscape(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.Replace(s, "+", "%20", -1)
+	s = strings.Replace(s, "*", "%2A", -1)
+	return strings.Replace(s, "%7E", "~", -1)
+}
+
+// aliyunPhone 中国大陆号码不带国家码，其他地区为国家码加号码，不带 +
+func aliyunPhone(phone string) string {
+	phone = normalizePhone(phone)
+	if strings.HasPrefix(phone, "+86") {
+		return phone[3:]
+	}
+	return phone[1:]
+}
@@ -0,0 +1,99 @@
+package sms
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// PrefixLimitKey 手机号发送计数的key前缀
+const PrefixLimitKey = "snake:sms:limit"
+
+// Limit 一个时间窗口内同一手机号最多发送的次数
+type Limit struct {
+	Window time.Duration `mapstructure:"window"`
+	Max    int64         `mapstructure:"max"`
+}
+
+// LimitError 超出发送频率限制
+type LimitError struct {
+	Limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("[sms] send limit exceeded, %d per %s", e.Max, e.Window)
+}
+
+// 先检查所有窗口是否都还有余量，再同时计数，被拒绝的请求不计数
+// 返回超出限制的窗口序号，从1开始，0 表示允许发送
+var allowScript = redis.NewScript(`
+for i = 1, #KEYS do
+	if tonumber(redis.call("GET", KEYS[i]) or "0") >= tonumber(ARGV[i * 2 - 1]) then
+		return i
+	end
+end
+for i = 1, #KEYS do
+	if redis.call("INCR", KEYS[i]) == 1 then
+		redis.call("PEXPIRE", KEYS[i], ARGV[i * 2])
+	end
+end
+return 0
+`)
+
+// Limiter 按手机号限制发送频率，计数存放在 redis 中，窗口从第一次发送开始计算
+type Limiter struct {
+	client *redis.Client
+	limits []Limit
+}
+
+// NewLimiter 实例化，忽略窗口或次数不大于0的配置
+func NewLimiter(client *redis.Client, limits []Limit) *Limiter {
+	l := &Limiter{client: client}
+	for _, limit := range limits {
+		if limit.Window > 0 && limit.Max > 0 {
+			l.limits = append(l.limits, limit)
+		}
+	}
+	return l
+}
+
+// Allow 记录一次发送，超出任一窗口的限制时返回 *LimitError 且不计数
+// 发送前计数，服务商发送失败也会消耗次数，避免被用来反复触发发送
+func (l *Limiter) Allow(phone string) error {
+	if len(l.limits) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(l.limits))
+	args := make([]interface{}, 0, len(l.limits)*2)
+	for _, limit := range l.limits {
+		keys = append(keys, limitKey(phone, limit.Window))
+		args = append(args, limit.Max, int64(limit.Window/time.Millisecond))
+	}
+
+	i, err := allowScript.Run(l.client, keys, args...).Int64()
+	if err != nil {
+		return err
+	}
+	if i > 0 && int(i) <= len(l.limits) {
+		return &LimitError{Limit: l.limits[i-1]}
+	}
+	return nil
+}
+
+// Reset 清空手机号的发送计数
+func (l *Limiter) Reset(phone string) error {
+	if len(l.limits) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(l.limits))
+	for _, limit := range l.limits {
+		keys = append(keys, limitKey(phone, limit.Window))
+	}
+	return l.client.Del(keys...).Err()
+}
+
+func limitKey(phone string, window time.Duration) string {
+	return PrefixLimitKey + ":" + strconv.FormatInt(int64(window/time.Second), 10) + ":" + normalizePhone(phone)
+}
@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qiniu/api.v7/auth"
+	qiniusms "github.com/qiniu/api.v7/sms"
+)
+
+// QiniuConfig 七牛云短信配置
+type QiniuConfig struct {
+	AccessKey string
+	SecretKey string
+	// SignatureID 短信签名id
+	SignatureID string
+	// Templates 模板名到模板的映射
+	Templates map[string]Template
+}
+
+// qiniu 七牛云短信，只支持中国大陆号码
+type qiniu struct {
+	conf    QiniuConfig
+	manager *qiniusms.Manager
+}
+
+// NewQiniu 实例化七牛云短信
+func NewQiniu(conf QiniuConfig) Provider {
+	return &qiniu{conf: conf, manager: qiniusms.NewManager(auth.New(conf.AccessKey, conf.SecretKey))}
+}
+
+// Name 名称
+func (q *qiniu) Name() string {
+	return ProviderQiniu
+}
+
+// Template 获取模板
+func (q *qiniu) Template(name string) (Template, bool) {
+	tpl, ok := q.conf.Templates[name]
+	return tpl, ok && tpl.ID != ""
+}
+
+// Send 发送短信，七牛的 sdk 不支持 context
+func (q *qiniu) Send(ctx context.Context, msg *Message, tpl Template) error {
+	params := make(map[string]interface{}, len(tpl.Params))
+	for _, name := range tpl.Params {
+		params[name] = msg.Params[name]
+	}
+	ret, err := q.manager.SendMessage(qiniusms.MessagesRequest{
+		SignatureID: q.conf.SignatureID,
+		TemplateID:  tpl.ID,
+		Mobiles:     []string{strings.TrimPrefix(normalizePhone(msg.Phone), "+86")},
+		Parameters:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("[sms] qiniu send err: %v", err)
+	}
+	if len(ret.JobID) == 0 {
+		return errors.New("[sms] qiniu send err, empty job id")
+	}
+	return nil
+}
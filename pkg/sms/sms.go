@@ -0,0 +1,262 @@
+// 短信发送，支持阿里云、腾讯云和七牛云
+// 按 sms.providers 的顺序发送，失败时切换到下一个服务商，同一手机号的发送频率受 sms.limits 限制
+
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+const (
+	// ProviderAliyun 阿里云短信服务
+	ProviderAliyun = "aliyun"
+	// ProviderTencent 腾讯云短信
+	ProviderTencent = "tencent"
+	// ProviderQiniu 七牛云短信
+	ProviderQiniu = "qiniu"
+
+	// TemplateLogin 登录验证码，参数为 code
+	TemplateLogin = "login"
+
+	defaultTimeout  = 5 * time.Second
+	defaultCooldown = time.Minute
+	contentTypeJSON = "application/json"
+)
+
+var (
+	// ErrUnknownProvider 未知的短信服务商
+	ErrUnknownProvider = errors.New("unknown sms provider")
+	// ErrTemplateNotFound 服务商没有配置对应的模板
+	ErrTemplateNotFound = errors.New("sms template not found")
+)
+
+// Client 全局的短信客户端，未配置服务商时为nil
+var Client *Sender
+
+// Lock 读写锁
+var Lock sync.RWMutex
+
+// Message 短信内容
+type Message struct {
+	// Phone 手机号，不带国家码时按中国大陆号码发送
+	Phone string
+	// Template 模板名，如 login，各服务商在配置中映射到自己的模板id
+	Template string
+	// Params 模板参数
+	Params map[string]string
+}
+
+// Template 服务商的短信模板
+type Template struct {
+	// ID 服务商后台审核通过的模板id
+	ID string `mapstructure:"id"`
+	// Params 模板参数名，腾讯云按顺序传参
+	Params []string `mapstructure:"params"`
+}
+
+// Provider 短信服务商
+type Provider interface {
+	Name() string
+	// Template 获取模板名对应的模板，未配置时返回false
+	Template(name string) (Template, bool)
+	Send(ctx context.Context, msg *Message, tpl Template) error
+}
+
+// Init 按配置初始化全局的短信客户端
+func Init() *Sender {
+	names := viper.GetStringSlice("sms.providers")
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		p, err := New(name)
+		if err != nil {
+			panic(fmt.Sprintf("[sms] new provider %s err: %v", name, err))
+		}
+		providers = append(providers, p)
+	}
+
+	var limits []Limit
+	if err := viper.UnmarshalKey("sms.limits", &limits); err != nil {
+		panic(fmt.Sprintf("[sms] unmarshal sms limits err: %v", err))
+	}
+
+	Lock.Lock()
+	defer Lock.Unlock()
+	Client = nil
+	if len(providers) > 0 {
+		Client = NewSender(providers, NewLimiter(redis.RedisClient, limits), viper.GetDuration("sms.failover_cooldown"))
+	}
+	return Client
+}
+
+// New 按名称实例化短信服务商
+func New(name string) (Provider, error) {
+	timeout := viper.GetDuration("sms.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch name {
+	case ProviderAliyun:
+		return NewAliyun(AliyunConfig{
+			AccessKeyID:     viper.GetString("sms.aliyun.access_key_id"),
+			AccessKeySecret: viper.GetString("sms.aliyun.access_key_secret"),
+			Endpoint:        viper.GetString("sms.aliyun.endpoint"),
+			RegionID:        viper.GetString("sms.aliyun.region_id"),
+			SignName:        viper.GetString("sms.aliyun.sign_name"),
+			Templates:       templates("sms.aliyun.templates"),
+			Timeout:         timeout,
+		}), nil
+	case ProviderTencent:
+		return NewTencent(TencentConfig{
+			SecretID:  viper.GetString("sms.tencent.secret_id"),
+			SecretKey: viper.GetString("sms.tencent.secret_key"),
+			Endpoint:  viper.GetString("sms.tencent.endpoint"),
+			Region:    viper.GetString("sms.tencent.region"),
+			SdkAppID:  viper.GetString("sms.tencent.sdk_app_id"),
+			SignName:  viper.GetString("sms.tencent.sign_name"),
+			Templates: templates("sms.tencent.templates"),
+			Timeout:   timeout,
+		}), nil
+	case ProviderQiniu:
+		// 和对象存储共用七牛云的 access_key、secret_key
+		return NewQiniu(QiniuConfig{
+			AccessKey:   viper.GetString("qiniu.access_key"),
+			SecretKey:   viper.GetString("qiniu.secret_key"),
+			SignatureID: viper.GetString("sms.qiniu.signature_id"),
+			Templates:   templates("sms.qiniu.templates"),
+		}), nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+func templates(key string) map[string]Template {
+	tpls := make(map[string]Template)
+	if err := viper.UnmarshalKey(key, &tpls); err != nil {
+		panic(fmt.Sprintf("[sms] unmarshal %s err: %v", key, err))
+	}
+	return tpls
+}
+
+// Send 使用全局的短信客户端发送短信，未配置服务商时只记录日志
+func Send(ctx context.Context, msg *Message) error {
+	Lock.RLock()
+	defer Lock.RUnlock()
+
+	if Client == nil {
+		log.Warnf("[sms] no provider configured, drop message, phone: %s, template: %s", msg.Phone, msg.Template)
+		return nil
+	}
+	return Client.Send(ctx, msg)
+}
+
+// Sender 按顺序使用多个服务商发送短信
+type Sender struct {
+	providers []Provider
+	limiter   *Limiter
+	// cooldown 发送失败的服务商在这段时间内排到最后
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	failedAt map[string]time.Time
+	now      func() time.Time
+}
+
+// NewSender 实例化，limiter 为nil时不限制发送频率
+func NewSender(providers []Provider, limiter *Limiter, cooldown time.Duration) *Sender {
+	if cooldown <= 0 {
+		cooldown = defaultCooldown
+	}
+	return &Sender{
+		providers: providers,
+		limiter:   limiter,
+		cooldown:  cooldown,
+		failedAt:  make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// Send 发送短信，先检查手机号的发送频率，超出时返回 *LimitError
+// 某个服务商发送失败或没有对应的模板时切换到下一个，全部失败时返回最后一个错误
+func (s *Sender) Send(ctx context.Context, msg *Message) error {
+	if msg.Phone == "" || msg.Template == "" {
+		return errors.New("[sms] phone or template is empty")
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Allow(msg.Phone); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for _, p := range s.ordered() {
+		tpl, ok := p.Template(msg.Template)
+		if !ok {
+			lastErr = fmt.Errorf("[sms] %s: %w, template: %s", p.Name(), ErrTemplateNotFound, msg.Template)
+			continue
+		}
+		if err := p.Send(ctx, msg, tpl); err != nil {
+			log.Warnf("[sms] send via %s err, phone: %s, err: %v", p.Name(), msg.Phone, err)
+			s.markFailed(p.Name())
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		s.markOK(p.Name())
+		return nil
+	}
+	return lastErr
+}
+
+// ordered 冷却中的服务商排到最后，都在冷却中时仍按配置的顺序尝试
+func (s *Sender) ordered() []Provider {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	healthy := make([]Provider, 0, len(s.providers))
+	var cooling []Provider
+	for _, p := range s.providers {
+		if at, ok := s.failedAt[p.Name()]; ok && now.Sub(at) < s.cooldown {
+			cooling = append(cooling, p)
+			continue
+		}
+		healthy = append(healthy, p)
+	}
+	return append(healthy, cooling...)
+}
+
+func (s *Sender) markFailed(name string) {
+	s.mu.Lock()
+	s.failedAt[name] = s.now()
+	s.mu.Unlock()
+}
+
+func (s *Sender) markOK(name string) {
+	s.mu.Lock()
+	delete(s.failedAt, name)
+	s.mu.Unlock()
+}
+
+// normalizePhone 转换为 E.164 格式，不带国家码时按中国大陆号码处理
+func normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	if strings.HasPrefix(phone, "00") {
+		return "+" + phone[2:]
+	}
+	return "+86" + phone
+}
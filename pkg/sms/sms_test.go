@@ -0,0 +1,169 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+type fakeProvider struct {
+	name  string
+	tpls  map[string]Template
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Template(name string) (Template, bool) {
+	tpl, ok := p.tpls[name]
+	return tpl, ok
+}
+
+func (p *fakeProvider) Send(ctx context.Context, msg *Message, tpl Template) error {
+	p.calls++
+	return p.err
+}
+
+func TestSender_Failover(t *testing.T) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	login := map[string]Template{TemplateLogin: {ID: "1", Params: []string{"code"}}}
+	bad := &fakeProvider{name: "bad", tpls: login, err: errors.New("gateway error")}
+	// 没有配置模板的服务商直接跳过
+	noTpl := &fakeProvider{name: "no_tpl"}
+	good := &fakeProvider{name: "good", tpls: login}
+
+	s := NewSender([]Provider{bad, noTpl, good}, nil, time.Minute)
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	msg := &Message{Phone: "13800000000", Template: TemplateLogin, Params: map[string]string{"code": "123456"}}
+
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if bad.calls != 1 || noTpl.calls != 0 || good.calls != 1 {
+		t.Fatalf("calls = %d %d %d, want 1 0 1", bad.calls, noTpl.calls, good.calls)
+	}
+
+	// 冷却期内失败的服务商排到最后
+	if err := s.Send(context.Background(), msg); err != nil || bad.calls != 1 || good.calls != 2 {
+		t.Fatalf("send in cooldown = %v, calls = %d %d", err, bad.calls, good.calls)
+	}
+	now = now.Add(time.Minute)
+	_ = s.Send(context.Background(), msg)
+	if bad.calls != 2 {
+		t.Fatalf("bad calls after cooldown = %d, want 2", bad.calls)
+	}
+
+	// 全部失败时返回最后尝试的服务商的错误，冷却中的 bad 最后尝试
+	good.err = errors.New("quota exceeded")
+	if err := s.Send(context.Background(), msg); err != bad.err {
+		t.Fatalf("send all failed = %v, want %v", err, bad.err)
+	}
+	if err := s.Send(context.Background(), &Message{Phone: "13800000000", Template: "unknown"}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("send unknown template = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	redis.InitTestRedis()
+	l := NewLimiter(redis.RedisClient, []Limit{{Window: time.Minute, Max: 1}, {Window: time.Hour, Max: 2}, {Window: time.Hour}})
+
+	if err := l.Allow("13800000000"); err != nil {
+		t.Fatal(err)
+	}
+	// 带国家码的同一号码共用计数
+	err := l.Allow("+8613800000000")
+	limitErr, ok := err.(*LimitError)
+	if !ok || limitErr.Window != time.Minute {
+		t.Fatalf("Allow() = %v, want minute limit", err)
+	}
+	if err := l.Allow("13900000000"); err != nil {
+		t.Fatalf("Allow() other phone = %v", err)
+	}
+
+	// 被拒绝的请求不计数，分钟窗口过期后仍受小时窗口限制
+	redis.RedisClient.Del(limitKey("13800000000", time.Minute))
+	if err := l.Allow("13800000000"); err != nil {
+		t.Fatal(err)
+	}
+	redis.RedisClient.Del(limitKey("13800000000", time.Minute))
+	if err, ok := l.Allow("13800000000").(*LimitError); !ok || err.Window != time.Hour {
+		t.Fatalf("Allow() = %v, want hour limit", err)
+	}
+
+	if err := l.Reset("13800000000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Allow("13800000000"); err != nil {
+		t.Fatalf("Allow() after reset = %v", err)
+	}
+}
+
+func TestAliyun_Send(t *testing.T) {
+	conf := AliyunConfig{AccessKeyID: "ak", AccessKeySecret: "sk", SignName: "snake"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		sign := q.Get("Signature")
+		q.Del("Signature")
+		if sign != (&aliyun{conf: conf}).sign(http.MethodGet, q) {
+			_, _ = w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"bad sign"}`))
+			return
+		}
+		if q.Get("PhoneNumbers") != "13800000000" || q.Get("TemplateParam") != `{"code":"123456"}` {
+			_, _ = w.Write([]byte(`{"Code":"isv.INVALID_PARAMETERS"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"1"}`))
+	}))
+	defer srv.Close()
+
+	conf.Endpoint = srv.URL
+	p := NewAliyun(conf)
+	msg := &Message{Phone: "+8613800000000", Params: map[string]string{"code": "123456", "extra": "x"}}
+	if err := p.Send(context.Background(), msg, Template{ID: "SMS_1", Params: []string{"code"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	p = NewAliyun(AliyunConfig{AccessKeyID: "ak", AccessKeySecret: "wrong", Endpoint: srv.URL})
+	if err := p.Send(context.Background(), msg, Template{ID: "SMS_1", Params: []string{"code"}}); err == nil ||
+		!strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("send with wrong secret = %v", err)
+	}
+}
+
+func TestTencent_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req tencentSendRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "TC3-HMAC-SHA256 Credential=sid/") ||
+			r.Header.Get("X-TC-Action") != "SendSms" {
+			_, _ = w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure","Message":"bad sign"}}}`))
+			return
+		}
+		if len(req.PhoneNumberSet) != 1 || req.PhoneNumberSet[0] != "+8613800000000" ||
+			strings.Join(req.TemplateParamSet, ",") != "123456,5" {
+			_, _ = w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"InvalidParameter"}]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"Ok","Message":"send success"}]}}`))
+	}))
+	defer srv.Close()
+
+	p := NewTencent(TencentConfig{SecretID: "sid", SecretKey: "sk", Endpoint: srv.URL, SdkAppID: "1400000000"})
+	msg := &Message{Phone: "13800000000", Params: map[string]string{"code": "123456", "minutes": "5"}}
+	if err := p.Send(context.Background(), msg, Template{ID: "1", Params: []string{"code", "minutes"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Send(context.Background(), msg, Template{ID: "1", Params: []string{"minutes"}}); err == nil {
+		t.Fatal("send with wrong params should fail")
+	}
+}
@@ -0,0 +1,189 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// see: https://cloud.tencent.com/document/product/382/55981
+
+const (
+	tencentDefaultEndpoint = "https://sms.tencentcloudapi.com"
+	tencentDefaultRegion   = "ap-guangzhou"
+	tencentService         = "sms"
+	tencentAPIVersion      = "2021-01-11"
+	tencentAlgorithm       = "TC3-HMAC-SHA256"
+	tencentContentType     = "application/json; charset=utf-8"
+	tencentCodeOK          = "Ok"
+)
+
+// TencentConfig 腾讯云短信配置
+type TencentConfig struct {
+	SecretID  string
+	SecretKey string
+	Endpoint  string
+	Region    string
+	// SdkAppID 短信应用id
+	SdkAppID string
+	// SignName 短信签名
+	SignName string
+	// Templates 模板名到模板的映射，Params 的顺序对应模板中的 {1}、{2}
+	Templates map[string]Template
+	Timeout   time.Duration
+}
+
+// tencent 腾讯云短信
+type tencent struct {
+	conf   TencentConfig
+	client *http.Client
+}
+
+// NewTencent 实例化腾讯云短信
+func NewTencent(conf TencentConfig) Provider {
+	if conf.Endpoint == "" {
+		conf.Endpoint = tencentDefaultEndpoint
+	}
+	if conf.Region == "" {
+		conf.Region = tencentDefaultRegion
+	}
+	return &tencent{conf: conf, client: &http.Client{Timeout: conf.Timeout}}
+}
+
+// Name 名称
+func (t *tencent) Name() string {
+	return ProviderTencent
+}
+
+// Template 获取模板
+func (t *tencent) Template(name string) (Template, bool) {
+	tpl, ok := t.conf.Templates[name]
+	return tpl, ok && tpl.ID != ""
+}
+
+type tencentSendRequest struct {
+	PhoneNumberSet   []string `json:"PhoneNumberSet"`
+	SmsSdkAppID      string   `json:"SmsSdkAppId"`
+	SignName         string   `json:"SignName"`
+	TemplateID       string   `json:"TemplateId"`
+	TemplateParamSet []string `json:"TemplateParamSet"`
+}
+
+type tencentSendResponse struct {
+	Response struct {
+		SendStatusSet []struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"SendStatusSet"`
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		RequestID string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// Send 发送短信，模板参数按 Template.Params 的顺序传递
+func (t *tencent) Send(ctx context.Context, msg *Message, tpl Template) error {
+	params := make([]string, 0, len(tpl.Params))
+	for _, name := range tpl.Params {
+		params = append(params, msg.Params[name])
+	}
+	body, err := json.Marshal(tencentSendRequest{
+		PhoneNumberSet:   []string{normalizePhone(msg.Phone)},
+		SmsSdkAppID:      t.conf.SdkAppID,
+		SignName:         t.conf.SignName,
+		TemplateID:       tpl.ID,
+		TemplateParamSet: params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", tencentContentType)
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", tencentAPIVersion)
+	req.Header.Set("X-TC-Region", t.conf.Region)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("Authorization", t.sign(req.URL, body, now))
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var sendResp tencentSendResponse
+	if err := json.Unmarshal(b, &sendResp); err != nil {
+		return fmt.Errorf("[sms] tencent unmarshal resp err: %v, body: %s", err, b)
+	}
+	r := sendResp.Response
+	if r.Error != nil {
+		return fmt.Errorf("[sms] tencent send err, code: %s, msg: %s, request_id: %s",
+			r.Error.Code, r.Error.Message, r.RequestID)
+	}
+	if len(r.SendStatusSet) == 0 || r.SendStatusSet[0].Code != tencentCodeOK {
+		return fmt.Errorf("[sms] tencent send status err, resp: %s", b)
+	}
+	return nil
+}
+
+// sign 生成 TC3-HMAC-SHA256 签名
+// see: https://cloud.tencent.com/document/api/382/52072
+func (t *tencent) sign(u *url.URL, body []byte, now time.Time) string {
+	date := now.UTC().Format("2006-01-02")
+	credentialScope := date + "/" + tencentService + "/tc3_request"
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:" + tencentContentType + "\nhost:" + u.Host + "\n",
+		"content-type;host",
+		sha256Hex(body),
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		tencentAlgorithm,
+		strconv.FormatInt(now.Unix(), 10),
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+t.conf.SecretKey), date)
+	secretService := hmacSHA256(secretDate, tencentService)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return tencentAlgorithm + " Credential=" + t.conf.SecretID + "/" + credentialScope +
+		", SignedHeaders=content-type;host, Signature=" + signature
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
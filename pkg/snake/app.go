@@ -1,15 +1,37 @@
 package snake
 
 import (
-	"context"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"github.com/1024casts/snake/pkg/agegate"
+	"github.com/1024casts/snake/pkg/authz"
+	"github.com/1024casts/snake/pkg/chaos"
 	"github.com/1024casts/snake/pkg/conf"
+	"github.com/1024casts/snake/pkg/consistency"
+	"github.com/1024casts/snake/pkg/deadline"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/idgen"
+	"github.com/1024casts/snake/pkg/imageaudit"
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/metrics"
+	"github.com/1024casts/snake/pkg/nonce"
+	"github.com/1024casts/snake/pkg/oauth"
+	"github.com/1024casts/snake/pkg/otp"
+	"github.com/1024casts/snake/pkg/queue"
+	"github.com/1024casts/snake/pkg/quota"
+	"github.com/1024casts/snake/pkg/ratelimit"
+	"github.com/1024casts/snake/pkg/recorder"
 	redis2 "github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/routemw"
+	"github.com/1024casts/snake/pkg/saga"
+	"github.com/1024casts/snake/pkg/sensitive"
+	"github.com/1024casts/snake/pkg/shadow"
+	"github.com/1024casts/snake/pkg/slo"
+	"github.com/1024casts/snake/pkg/sms"
+	"github.com/1024casts/snake/pkg/startup"
+	"github.com/1024casts/snake/pkg/storage"
+	"github.com/1024casts/snake/pkg/tracing"
+	"github.com/1024casts/snake/pkg/ws"
 
 	//"github.com/1024casts/snake/pkg/schedule"
 
@@ -17,8 +39,8 @@ import (
 	"github.com/1024casts/snake/pkg/log"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
-	"github.com/jinzhu/gorm"
 	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
 const (
@@ -46,18 +68,107 @@ type Application struct {
 func New(cfg *conf.Config) *Application {
 	app := new(Application)
 
+	// init log, 后面的初始化会输出日志，需要最先初始化
+	conf.InitLog()
+
+	// init lifecycle, 先注册的模块先启动、后停止，连接池最先注册最后关闭
+	lifecycle.Init()
+
 	// init db
 	app.DB = model.Init()
+	model.InitRegions()
+
+	// init redis, 是否可用由启动编排探测
+	app.RedisClient = redis2.Connect()
+	redis2.InitRegions()
+	lifecycle.Client.Add(model.Hook())
+	lifecycle.Client.Add(redis2.Hook())
+
+	// init nonce store
+	nonce.Init()
+
+	// init quota
+	quota.Init()
+
+	// init rate limit
+	ratelimit.Init()
+
+	// init route middleware, 配置修改后热加载
+	routemw.Init()
+
+	// init slo
+	slo.Init()
+
+	// init http metrics
+	metrics.Init()
+
+	// init chaos, only for non-release mode
+	chaos.Init()
+
+	// init experiment
+	experiment.Init()
+
+	// init sensitive words
+	sensitive.Init()
+
+	// init image audit provider
+	imageaudit.Init()
+
+	// init sms providers, 发送频率计数依赖 redis
+	sms.Init()
+
+	// init otp, 短信、邮件和图形验证码保存在 redis
+	otp.Init()
+
+	// init oauth providers, 授权的 state 保存在 redis
+	oauth.Init()
 
-	// init redis
-	app.RedisClient = redis2.Init()
+	// init storage and queue
+	storage.Init()
+	queue.Init()
+
+	// init request recorder, queue driver depends on queue
+	recorder.Init()
+
+	// init shadow reads
+	shadow.Init()
+
+	// init timeout budget
+	deadline.Init()
+
+	// init read-your-writes consistency
+	consistency.Init()
+
+	// init saga store
+	saga.Init()
+
+	// init age gate
+	agegate.Init()
+
+	// init authz roles
+	authz.Init()
+
+	// init id generator
+	idgen.Init()
+
+	// init websocket hub, 退出时断开所有连接，客户端重连到其他实例
+	if ws.Init() != nil {
+		lifecycle.Client.Append("ws", ws.Client.Stop)
+	}
+
+	// init tracing, 退出时导出剩余的 span
+	tracing.Init()
+	lifecycle.Client.Add(tracing.Hook())
+
+	// init startup orchestrator, mysql 和 redis 就绪前只开放健康检查
+	startup.Init(
+		startup.Dependency{Name: "mysql", Probe: model.Ping},
+		startup.Dependency{Name: "redis", Probe: redis2.Ping},
+	)
 
 	// init router
 	app.Router = gin.Default()
 
-	// init log
-	conf.InitLog()
-
 	//// init schedule
 	//schedule.Init()
 
@@ -69,44 +180,18 @@ func New(cfg *conf.Config) *Application {
 }
 
 // Run start a app
+// http 服务最后注册，其他模块启动后才开始监听端口；收到 SIGINT、SIGTERM 后由 lifecycle 停止接收新请求，
+// 等待处理中的请求完成，再停止后台任务和关闭连接池
 func (a *Application) Run() {
 	log.Infof("Start to listening the incoming requests on http address: %s", viper.GetString("app.addr"))
 	srv := &http.Server{
 		Addr:    viper.GetString("app.addr"),
 		Handler: a.Router,
 	}
-	go func() {
-		// service connections
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s", err.Error())
-		}
-	}()
-
-	gracefulStop(srv)
-}
+	lifecycle.Client.Add(lifecycle.HTTP(srv))
 
-// gracefulStop 优雅退出
-// 等待中断信号以超时 5 秒正常关闭服务器
-// 官方说明：https://github.com/gin-gonic/gin#graceful-restart-or-stop
-func gracefulStop(srv *http.Server) {
-	quit := make(chan os.Signal)
-	// kill 命令发送信号 syscall.SIGTERM
-	// kill -2 命令发送信号 syscall.SIGINT
-	// kill -9 命令发送信号 syscall.SIGKILL
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Info("Shutdown Server ...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server Shutdown:", err)
-	}
-	// 5 秒后捕获 ctx.Done() 信号
-	select {
-	case <-ctx.Done():
-		log.Info("timeout of 5 seconds.")
-	default:
+	if err := lifecycle.Client.Run(); err != nil {
+		log.Fatalf("run server err: %v", err)
 	}
 	log.Info("Server exiting")
 }
@@ -0,0 +1,25 @@
+package snake
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/version"
+)
+
+// banner generate by http://patorjk.com/software/taag/#p=display&f=Small%20Slant&t=Snake
+const banner = "   ____          __\n" +
+	"  / __/__  ___ _/ /_____\n" +
+	" _\\ \\/ _ \\/ _ `/  '_/ -_)\n" +
+	"/___/_//_/\\_,_/_/\\_\\\\__/\n\n"
+
+// PrintBanner 启动时输出版本和主要配置，方便确认部署的是哪个版本、使用的哪个配置文件
+func PrintBanner(w io.Writer) {
+	ver := version.Get()
+	fmt.Fprint(w, banner)
+	fmt.Fprintf(w, "version: %s, commit: %s, build date: %s, %s\n", ver.GitTag, ver.GitCommit, ver.BuildDate, ver.GoVersion)
+	fmt.Fprintf(w, "mode: %s, addr: %s, config: %s\n", viper.GetString("app.run_mode"), viper.GetString("app.addr"), viper.ConfigFileUsed())
+	fmt.Fprintf(w, "run `snake doctor -c %s` to check config and dependencies\n\n", viper.ConfigFileUsed())
+}
@@ -0,0 +1,202 @@
+// 启动编排，按顺序等待 MySQL、Redis 等依赖可用，依赖不可用时按指数退避重试，超过次数后返回错误
+// 等待期间服务处于降级模式：健康检查可以访问，业务路由返回 503
+
+package startup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+const (
+	defaultMaxAttempts    = 30
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+)
+
+// Client 全局的启动编排，未初始化时认为依赖已就绪
+var Client *Orchestrator
+
+// Retry 依赖的重试配置
+type Retry struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// Config 启动配置，Dependencies 按依赖名称覆盖默认的重试配置
+type Config struct {
+	Retry        `mapstructure:",squash"`
+	Dependencies map[string]Retry `mapstructure:"dependencies"`
+}
+
+// Dependency 启动依赖，Probe 返回 nil 表示依赖可用
+type Dependency struct {
+	Name  string
+	Probe func() error
+}
+
+// Status 依赖的状态
+type Status struct {
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Orchestrator 启动编排
+type Orchestrator struct {
+	cfg   Config
+	deps  []Dependency
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu     sync.RWMutex
+	status []Status
+	ready  int32
+}
+
+// Init 按配置初始化全局的启动编排
+func Init(deps ...Dependency) *Orchestrator {
+	cfg := Config{}
+	if err := viper.UnmarshalKey("startup", &cfg); err != nil {
+		log.Warnf("[startup] unmarshal config err: %v", err)
+	}
+	Client = New(cfg, deps...)
+	return Client
+}
+
+// New 实例化启动编排，依赖按传入的顺序探测
+func New(cfg Config, deps ...Dependency) *Orchestrator {
+	status := make([]Status, len(deps))
+	for i, dep := range deps {
+		status[i].Name = dep.Name
+	}
+	return &Orchestrator{
+		cfg:    cfg,
+		deps:   deps,
+		sleep:  sleep,
+		status: status,
+	}
+}
+
+// Start 按顺序等待所有依赖可用，某个依赖超过重试次数或 ctx 取消时返回错误
+func (o *Orchestrator) Start(ctx context.Context) error {
+	for i, dep := range o.deps {
+		if err := o.wait(ctx, i, dep); err != nil {
+			return err
+		}
+	}
+	atomic.StoreInt32(&o.ready, 1)
+	log.Info("[startup] all dependencies are ready")
+	return nil
+}
+
+// Ready 所有依赖是否已就绪
+func (o *Orchestrator) Ready() bool {
+	return atomic.LoadInt32(&o.ready) == 1
+}
+
+// Status 各依赖的状态
+func (o *Orchestrator) Status() []Status {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	status := make([]Status, len(o.status))
+	copy(status, o.status)
+	return status
+}
+
+// wait 探测依赖，失败时按指数退避重试
+func (o *Orchestrator) wait(ctx context.Context, i int, dep Dependency) error {
+	retry := o.retry(dep.Name)
+	backoff := retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := dep.Probe()
+		o.setStatus(i, attempt, err)
+		if err == nil {
+			log.Infof("[startup] %s is ready, attempts: %d", dep.Name, attempt)
+			return nil
+		}
+		if attempt >= retry.MaxAttempts {
+			return fmt.Errorf("[startup] %s not ready after %d attempts: %w", dep.Name, attempt, err)
+		}
+
+		log.Warnf("[startup] %s not ready: %v, attempt: %d, retry in %s", dep.Name, err, attempt, backoff)
+		if err := o.sleep(ctx, backoff); err != nil {
+			return fmt.Errorf("[startup] wait for %s canceled: %w", dep.Name, err)
+		}
+		backoff *= 2
+		if backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+}
+
+// retry 依赖的重试配置，未配置的项使用默认值
+func (o *Orchestrator) retry(name string) Retry {
+	retry := o.cfg.Retry
+	if r, ok := o.cfg.Dependencies[name]; ok {
+		if r.MaxAttempts > 0 {
+			retry.MaxAttempts = r.MaxAttempts
+		}
+		if r.InitialBackoff > 0 {
+			retry.InitialBackoff = r.InitialBackoff
+		}
+		if r.MaxBackoff > 0 {
+			retry.MaxBackoff = r.MaxBackoff
+		}
+	}
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = defaultMaxAttempts
+	}
+	if retry.InitialBackoff <= 0 {
+		retry.InitialBackoff = defaultInitialBackoff
+	}
+	if retry.MaxBackoff < retry.InitialBackoff {
+		retry.MaxBackoff = defaultMaxBackoff
+		if retry.MaxBackoff < retry.InitialBackoff {
+			retry.MaxBackoff = retry.InitialBackoff
+		}
+	}
+	return retry
+}
+
+// setStatus 记录依赖的探测结果
+func (o *Orchestrator) setStatus(i, attempts int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.status[i].Attempts = attempts
+	o.status[i].Ready = err == nil
+	o.status[i].LastError = ""
+	if err != nil {
+		o.status[i].LastError = err.Error()
+	}
+}
+
+// sleep 等待一段时间，ctx 取消时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Ready 依赖是否已就绪，未初始化启动编排时返回 true
+func Ready() bool {
+	if Client == nil {
+		return true
+	}
+	return Client.Ready()
+}
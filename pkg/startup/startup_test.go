@@ -0,0 +1,65 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/1024casts/snake/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	m.Run()
+}
+
+func TestOrchestrator_Start(t *testing.T) {
+	asserts := assert.New(t)
+
+	calls := 0
+	var backoffs []time.Duration
+	o := New(Config{
+		Retry: Retry{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second},
+	}, Dependency{Name: "mysql", Probe: func() error {
+		calls++
+		if calls < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}, Dependency{Name: "redis", Probe: func() error { return nil }})
+	o.sleep = func(ctx context.Context, d time.Duration) error {
+		asserts.False(o.Ready())
+		backoffs = append(backoffs, d)
+		return nil
+	}
+
+	asserts.NoError(o.Start(context.Background()))
+	asserts.True(o.Ready())
+	asserts.Equal([]time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, backoffs)
+	asserts.Equal([]Status{
+		{Name: "mysql", Ready: true, Attempts: 4},
+		{Name: "redis", Ready: true, Attempts: 1},
+	}, o.Status())
+}
+
+func TestOrchestrator_StartFailed(t *testing.T) {
+	asserts := assert.New(t)
+
+	o := New(Config{
+		Retry:        Retry{MaxAttempts: 5},
+		Dependencies: map[string]Retry{"redis": {MaxAttempts: 2}},
+	}, Dependency{Name: "redis", Probe: func() error { return errors.New("connection refused") }})
+	o.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	asserts.Error(o.Start(context.Background()))
+	asserts.False(o.Ready())
+	asserts.Equal([]Status{{Name: "redis", Attempts: 2, LastError: "connection refused"}}, o.Status())
+}
+
+func TestReady(t *testing.T) {
+	Client = nil
+	assert.True(t, Ready())
+}
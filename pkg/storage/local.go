@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLocalRoot    = "./static/uploads"
+	defaultLocalBaseURL = "/static/uploads"
+)
+
+// LocalConfig 本地磁盘存储配置
+type LocalConfig struct {
+	Root string
+	// BaseURL 公开访问地址，一般为静态资源路由
+	BaseURL string
+	// SignEndpoint 签名地址由 API 服务处理，如 http://localhost:8080/v1/storage/objects
+	SignEndpoint string
+	SignSecret   string
+}
+
+// local 本地磁盘存储，文件通过静态资源路由访问
+type local struct {
+	conf LocalConfig
+}
+
+// NewLocal 实例化本地磁盘存储
+func NewLocal(conf LocalConfig) Storage {
+	if conf.Root == "" {
+		conf.Root = defaultLocalRoot
+	}
+	if conf.BaseURL == "" {
+		conf.BaseURL = defaultLocalBaseURL
+	}
+	conf.BaseURL = strings.TrimSuffix(conf.BaseURL, "/")
+	conf.SignEndpoint = strings.TrimSuffix(conf.SignEndpoint, "/")
+	return &local{conf: conf}
+}
+
+// Put 写入文件，先写临时文件再重命名，避免读到写了一半的文件
+func (l *local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Get 读取文件
+func (l *local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除文件，文件不存在时不报错
+func (l *local) Delete(ctx context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL 文件的访问地址
+func (l *local) URL(key string) string {
+	return l.conf.BaseURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+// SignURL 生成由 API 服务处理的签名地址
+func (l *local) SignURL(method, key string, expires time.Duration) (string, error) {
+	if method != http.MethodGet && method != http.MethodPut {
+		return "", ErrSignUnsupported
+	}
+	if l.conf.SignSecret == "" {
+		return "", ErrSignUnsupported
+	}
+
+	key = strings.TrimPrefix(key, "/")
+	expiresAt := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", l.sign(method, key, expiresAt))
+	return l.conf.SignEndpoint + "/" + key + "?" + query.Encode(), nil
+}
+
+// Verify 校验签名和有效期
+func (l *local) Verify(method, key string, expires int64, signature string) bool {
+	if l.conf.SignSecret == "" || time.Now().Unix() > expires {
+		return false
+	}
+	expected := l.sign(method, strings.TrimPrefix(key, "/"), expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func (l *local) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(l.conf.SignSecret))
+	mac.Write([]byte(method + "\n" + key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path 防止 key 中的 .. 访问到存储目录之外
+func (l *local) path(key string) string {
+	return filepath.Join(l.conf.Root, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+// InitMultipart 分片保存在存储目录下的 .multipart/<uploadID> 中
+func (l *local) InitMultipart(ctx context.Context, key, contentType string) (string, error) {
+	uploadID := uuid.New().String()
+	if err := os.MkdirAll(l.partDir(uploadID), 0755); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// UploadPart 上传分片，etag 为分片内容的md5
+func (l *local) UploadPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (string, error) {
+	dir := l.partDir(uploadID)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+
+	f, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	h := md5.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, fmt.Sprintf("%d", number))); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CompleteMultipart 按顺序合并分片后删除分片目录
+func (l *local) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	dir := l.partDir(uploadID)
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("%d", part.Number)))
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	if err := l.Put(ctx, key, io.MultiReader(readers...), 0, ""); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// AbortMultipart 删除已上传的分片
+func (l *local) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return os.RemoveAll(l.partDir(uploadID))
+}
+
+func (l *local) partDir(uploadID string) string {
+	return filepath.Join(l.conf.Root, ".multipart", filepath.Base(uploadID))
+}
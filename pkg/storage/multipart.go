@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// PartSize 分片大小，除最后一片外每片都必须是这个大小，与七牛的块大小一致
+const PartSize = 4 << 20
+
+// ErrMultipartUnsupported 存储不支持分片上传
+var ErrMultipartUnsupported = errors.New("storage: multipart upload unsupported")
+
+// Part 已上传的分片
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// MultipartStorage 支持分片上传的存储，分片可以重复上传，以最后一次为准
+type MultipartStorage interface {
+	InitMultipart(ctx context.Context, key, contentType string) (uploadID string, err error)
+	// UploadPart 上传分片，number 从1开始
+	UploadPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (etag string, err error)
+	// CompleteMultipart 按分片序号合并，parts 需要按序号排好
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+// Multipart 获取存储的分片上传能力
+func Multipart(s Storage) (MultipartStorage, error) {
+	if m, ok := s.(MultipartStorage); ok {
+		return m, nil
+	}
+	return nil, ErrMultipartUnsupported
+}
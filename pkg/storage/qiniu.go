@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qiniu/api.v7/auth"
+	qiniustorage "github.com/qiniu/api.v7/storage"
+)
+
+// qiniu 七牛云对象存储
+type qiniu struct {
+	cred    *auth.Credentials
+	bucket  string
+	domain  string
+	cfg     *qiniustorage.Config
+	manager *qiniustorage.BucketManager
+}
+
+// NewQiniu 实例化七牛云对象存储，domain 为 bucket 绑定的访问域名
+func NewQiniu(accessKey, secretKey, bucket, domain string) Storage {
+	cred := auth.New(accessKey, secretKey)
+	cfg := &qiniustorage.Config{UseHTTPS: true}
+	return &qiniu{
+		cred:    cred,
+		bucket:  bucket,
+		domain:  strings.TrimSuffix(domain, "/"),
+		cfg:     cfg,
+		manager: qiniustorage.NewBucketManager(cred, cfg),
+	}
+}
+
+// Put 表单上传，同名文件会被覆盖
+func (q *qiniu) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	var ret qiniustorage.PutRet
+	uploader := qiniustorage.NewFormUploader(q.cfg)
+	return uploader.Put(ctx, &ret, q.uploadToken(key), key, r, size, &qiniustorage.PutExtra{MimeType: contentType})
+}
+
+// Get 通过访问域名下载文件
+func (q *qiniu) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, q.URL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: qiniu get %s status: %d", key, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除文件，文件不存在时不报错
+func (q *qiniu) Delete(ctx context.Context, key string) error {
+	err := q.manager.Delete(q.bucket, key)
+	if err != nil && strings.Contains(err.Error(), "no such file") {
+		return nil
+	}
+	return err
+}
+
+// URL 文件的公开访问地址
+func (q *qiniu) URL(key string) string {
+	return qiniustorage.MakePublicURL(q.domain, strings.TrimPrefix(key, "/"))
+}
+
+// InitMultipart 七牛分片上传不需要初始化，uploadID 只用于业务层标识
+// see: https://developer.qiniu.com/kodo/api/1286/mkblk
+func (q *qiniu) InitMultipart(ctx context.Context, key, contentType string) (string, error) {
+	return uuid.New().String(), nil
+}
+
+// UploadPart 每个分片作为一个块上传，etag 为七牛返回的块 ctx
+func (q *qiniu) UploadPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (string, error) {
+	uploader := qiniustorage.NewResumeUploader(q.cfg)
+	upHost, err := uploader.UpHost(q.cred.AccessKey, q.bucket)
+	if err != nil {
+		return "", err
+	}
+
+	var ret qiniustorage.BlkputRet
+	err = uploader.Mkblk(ctx, q.uploadToken(key), upHost, &ret, int(size), r, int(size))
+	if err != nil {
+		return "", err
+	}
+	return ret.Ctx, nil
+}
+
+// CompleteMultipart 按块 ctx 的顺序生成文件，块 ctx 七天内有效
+func (q *qiniu) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	uploader := qiniustorage.NewResumeUploader(q.cfg)
+	upHost, err := uploader.UpHost(q.cred.AccessKey, q.bucket)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	progresses := make([]qiniustorage.BlkputRet, 0, len(parts))
+	for _, part := range parts {
+		size += part.Size
+		progresses = append(progresses, qiniustorage.BlkputRet{Ctx: part.ETag})
+	}
+
+	var ret qiniustorage.PutRet
+	extra := &qiniustorage.RputExtra{Progresses: progresses}
+	return uploader.Mkfile(ctx, q.uploadToken(key), upHost, &ret, key, true, size, extra)
+}
+
+// AbortMultipart 未合并的块会被七牛自动清理
+func (q *qiniu) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return nil
+}
+
+// SignURL 私有空间的下载地址，七牛上传需要使用上传凭证，不支持 PUT
+func (q *qiniu) SignURL(method, key string, expires time.Duration) (string, error) {
+	if method != http.MethodGet {
+		return "", ErrSignUnsupported
+	}
+	deadline := time.Now().Add(expires).Unix()
+	return qiniustorage.MakePrivateURL(q.cred, q.domain, strings.TrimPrefix(key, "/"), deadline), nil
+}
+
+// uploadToken 允许覆盖同名文件的上传凭证
+func (q *qiniu) uploadToken(key string) string {
+	policy := qiniustorage.PutPolicy{Scope: q.bucket + ":" + key}
+	return policy.UploadToken(q.cred)
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// see: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3MaxExpires 签名地址最长有效期为7天
+	s3MaxExpires = 7 * 24 * time.Hour
+	// s3RequestExpires 服务端自己请求时使用的签名有效期
+	s3RequestExpires = 15 * time.Minute
+)
+
+// S3Config 兼容 S3 协议的对象存储配置，阿里云 OSS、腾讯云 COS、MinIO 等都可以使用
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle 为 true 时使用 endpoint/bucket/key，否则使用 bucket.endpoint/key
+	PathStyle bool
+	// PublicURL 公开访问地址，一般为 CDN 域名，为空时使用存储地址
+	PublicURL string
+}
+
+// s3 兼容 S3 协议的对象存储，所有请求都使用签名地址，不依赖 SDK
+type s3 struct {
+	conf   S3Config
+	scheme string
+	host   string
+	client *http.Client
+}
+
+// NewS3 实例化兼容 S3 协议的对象存储
+func NewS3(conf S3Config) (Storage, error) {
+	u, err := url.Parse(conf.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint: %s", conf.Endpoint)
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+
+	host := u.Host
+	if !conf.PathStyle {
+		host = conf.Bucket + "." + host
+	}
+	if conf.PublicURL == "" {
+		conf.PublicURL = u.Scheme + "://" + host
+		if conf.PathStyle {
+			conf.PublicURL += "/" + conf.Bucket
+		}
+	}
+	conf.PublicURL = strings.TrimSuffix(conf.PublicURL, "/")
+
+	return &s3{conf: conf, scheme: u.Scheme, host: host, client: &http.Client{}}, nil
+}
+
+// Put 上传文件
+func (s *s3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get 下载文件
+func (s *s3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除文件，文件不存在时 S3 同样返回成功
+func (s *s3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL 文件的公开访问地址
+func (s *s3) URL(key string) string {
+	return s.conf.PublicURL + "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+}
+
+// SignURL 生成签名地址，PUT 时客户端需要使用同样的 Content-Type
+func (s *s3) SignURL(method, key string, expires time.Duration) (string, error) {
+	if method != http.MethodGet && method != http.MethodPut {
+		return "", ErrSignUnsupported
+	}
+	if expires > s3MaxExpires {
+		expires = s3MaxExpires
+	}
+	return s.presign(method, key, expires, time.Now()), nil
+}
+
+func (s *s3) do(ctx context.Context, method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.presign(method, key, s3RequestExpires, time.Now()), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: s3 %s %s status: %d, body: %s", method, key, resp.StatusCode, b)
+	}
+	return resp, nil
+}
+
+// presign 生成 SigV4 签名地址，只签名 host 头
+func (s *s3) presign(method, key string, expires time.Duration, now time.Time) string {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.conf.Region, "s3", "aws4_request"}, "/")
+
+	path := "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+	if s.conf.PathStyle {
+		path = "/" + s.conf.Bucket + path
+	}
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     s3Algorithm,
+		"X-Amz-Credential":    s.conf.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expires.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(query[k], true))
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+
+	canonicalRequest := strings.Join([]string{
+		method, path, canonicalQuery, "host:" + s.host + "\n", "host", s3UnsignedPayload,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.conf.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.conf.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return s.scheme + "://" + s.host + path + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode 按 RFC 3986 编码，路径中的 / 不编码
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
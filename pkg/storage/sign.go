@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// localSignPath 本地磁盘存储的签名地址由 API 服务的该路由处理
+const localSignPath = "/v1/storage/objects"
+
+// ErrSignUnsupported 存储不支持生成签名地址
+var ErrSignUnsupported = errors.New("storage: signed url unsupported")
+
+// Signer 生成带签名的临时地址，客户端可以直接上传、下载私有文件，不需要经过 API 服务中转
+type Signer interface {
+	// SignURL method 为 GET 或 PUT，地址在 expires 后失效
+	SignURL(method, key string, expires time.Duration) (string, error)
+}
+
+// Verifier 签名地址由 API 服务处理的存储需要校验签名，如本地磁盘
+type Verifier interface {
+	Verify(method, key string, expires int64, signature string) bool
+}
+
+// SignURL 生成默认存储的签名地址
+func SignURL(method, key string, expires time.Duration) (string, error) {
+	s, ok := Default.(Signer)
+	if !ok {
+		return "", ErrSignUnsupported
+	}
+	return s.SignURL(method, key, expires)
+}
@@ -0,0 +1,101 @@
+// 对象存储，支持本地磁盘、七牛云和兼容 S3 协议的存储，通过 storage.driver 选择
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DriverLocal 本地磁盘，适合开发环境
+	DriverLocal = "local"
+	// DriverQiniu 七牛云对象存储
+	DriverQiniu = "qiniu"
+	// DriverS3 兼容 S3 协议的对象存储，如 AWS S3、阿里云 OSS
+	DriverS3 = "s3"
+)
+
+var (
+	// ErrNotFound 文件不存在
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrUnknownDriver 未知的存储驱动
+	ErrUnknownDriver = errors.New("storage: unknown driver")
+)
+
+// Default 默认的存储
+var Default Storage
+
+// Storage 对象存储，key 为文件的存储路径，如 avatar/1/xxx.jpg
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL 文件的访问地址
+	URL(key string) string
+}
+
+// Init 按配置初始化默认的存储
+func Init() Storage {
+	s, err := New(viper.GetString("storage.driver"))
+	if err != nil {
+		panic(err)
+	}
+	Default = s
+	return Default
+}
+
+// New 按驱动名称实例化存储，默认使用本地磁盘
+func New(driver string) (Storage, error) {
+	switch driver {
+	case "", DriverLocal:
+		secret := viper.GetString("storage.local.sign_secret")
+		if secret == "" {
+			secret = viper.GetString("app.jwt_secret")
+		}
+		return NewLocal(LocalConfig{
+			Root:         viper.GetString("storage.local.root"),
+			BaseURL:      viper.GetString("storage.local.base_url"),
+			SignEndpoint: viper.GetString("app.url") + localSignPath,
+			SignSecret:   secret,
+		}), nil
+	case DriverQiniu:
+		domain := viper.GetString("storage.qiniu.domain")
+		if domain == "" {
+			domain = viper.GetString("qiniu.cdn_url")
+		}
+		return NewQiniu(
+			viper.GetString("qiniu.access_key"),
+			viper.GetString("qiniu.secret_key"),
+			viper.GetString("storage.qiniu.bucket"),
+			domain,
+		), nil
+	case DriverS3:
+		return NewS3(S3Config{
+			Endpoint:        viper.GetString("storage.s3.endpoint"),
+			Region:          viper.GetString("storage.s3.region"),
+			Bucket:          viper.GetString("storage.s3.bucket"),
+			AccessKeyID:     viper.GetString("storage.s3.access_key_id"),
+			SecretAccessKey: viper.GetString("storage.s3.secret_access_key"),
+			PathStyle:       viper.GetBool("storage.s3.path_style"),
+			PublicURL:       viper.GetString("storage.s3.public_url"),
+		})
+	}
+	return nil, ErrUnknownDriver
+}
+
+// KeyFromURL 从默认存储的访问地址中解析出存储路径，不是默认存储的地址时返回false
+func KeyFromURL(url string) (string, bool) {
+	if Default == nil || url == "" {
+		return "", false
+	}
+	prefix := Default.URL("")
+	if !strings.HasPrefix(url, prefix) || len(url) == len(prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
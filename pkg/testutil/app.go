@@ -6,7 +6,7 @@ import (
 	"github.com/1024casts/snake/internal/model"
 	"github.com/1024casts/snake/pkg/conf"
 
-	"github.com/jinzhu/gorm"
+	"gorm.io/gorm"
 )
 
 // App 结构体，主要是为了方便实例一个app
@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update 重新生成快照，如 go test ./internal/idl/ -update，生成后需要检查 diff 再提交
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// AssertGolden 把 v 编码为缩进的 json 后和 testdata/<name>.golden 比较
+// 对外结构的字段增删、改名都会导致快照变化，需要在 review 时确认对客户端的影响
+func AssertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("golden %s: marshal: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	filename := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("golden %s: %v", name, err)
+		}
+		if err := ioutil.WriteFile(filename, got, 0644); err != nil {
+			t.Fatalf("golden %s: %v", name, err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden %s: %s not found, run go test with -update to create it", name, filename)
+	}
+	if err != nil {
+		t.Fatalf("golden %s: %v", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("golden %s: output does not match %s, run go test with -update if the change is expected\n%s",
+			name, filename, diff(string(want), string(got)))
+	}
+}
+
+// diff 按行比较快照，- 为快照中的行，+ 为实际输出的行
+func diff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&buf, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&buf, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&buf, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return buf.String()
+}
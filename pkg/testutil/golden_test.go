@@ -0,0 +1,22 @@
+package testutil
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		want, got string
+		expect    string
+	}{
+		{"a\nb\n", "a\nb\n", "  a\n  b\n"},
+		{"a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"},
+		{"a\nb\nc\n", "a\nc\n", "  a\n- b\n  c\n"},
+		{"a\nb\n", "a\nx\n", "  a\n- b\n+ x\n"},
+	}
+	for _, tt := range tests {
+		if d := diff(tt.want, tt.got); d != tt.expect {
+			t.Errorf("diff(%q, %q) = %q, want %q", tt.want, tt.got, d, tt.expect)
+		}
+	}
+}
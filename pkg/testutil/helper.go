@@ -13,7 +13,10 @@ func Setup() *App {
 // Teardown 测试后的一些清理工作
 func Teardown(app *App) {
 	// Closing the connection discards the in-memory database.
-	err := app.DB.Close()
+	sqlDB, err := app.DB.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
 	if err != nil {
 		fmt.Printf("teardown, %+v", err)
 	}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+// PrefixRevokeKey 记录用户某种登录方式 token 的吊销时间
+const PrefixRevokeKey = "snake:token:revoke:%d:%s"
+
+// Revoke 吊销用户通过某种登录方式签发的所有 token
+// token 本身是无状态的，这里记录吊销时间，在此之前签发的 token 都视为无效
+func Revoke(userID uint64, loginType string) error {
+	key := fmt.Sprintf(PrefixRevokeKey, userID, loginType)
+	return redis.RedisClient.Set(key, time.Now().Unix(), 0).Err()
+}
+
+// IsRevoked token 是否已被吊销
+func IsRevoked(ctx *Context) (bool, error) {
+	return IsRevokedContext(context.Background(), ctx)
+}
+
+// IsRevokedContext 同 IsRevoked，redis 调用使用请求 ctx 的预算
+func IsRevokedContext(c context.Context, ctx *Context) (bool, error) {
+	if ctx.LoginType == "" {
+		return false, nil
+	}
+	key := fmt.Sprintf(PrefixRevokeKey, ctx.UserID, ctx.LoginType)
+	val, err := redis.WithContext(c).Get(key).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// iat 精确到秒，同一秒内签发的 token 也视为已吊销
+	return ctx.IssuedAt <= revokedAt, nil
+}
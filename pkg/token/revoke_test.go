@@ -0,0 +1,36 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1024casts/snake/pkg/redis"
+)
+
+func TestRevoke(t *testing.T) {
+	redis.InitTestRedis()
+
+	ctx := &Context{UserID: 1, LoginType: LoginTypePhone, IssuedAt: time.Now().Unix()}
+	if revoked, err := IsRevoked(ctx); err != nil || revoked {
+		t.Fatalf("IsRevoked() = %v, %v, want false", revoked, err)
+	}
+
+	if err := Revoke(1, LoginTypePhone); err != nil {
+		t.Fatalf("Revoke() err: %v", err)
+	}
+	if revoked, err := IsRevoked(ctx); err != nil || !revoked {
+		t.Errorf("IsRevoked() = %v, %v, want true", revoked, err)
+	}
+
+	// 其他登录方式不受影响
+	emailCtx := &Context{UserID: 1, LoginType: LoginTypeEmail, IssuedAt: ctx.IssuedAt}
+	if revoked, err := IsRevoked(emailCtx); err != nil || revoked {
+		t.Errorf("IsRevoked() = %v, %v, want false", revoked, err)
+	}
+
+	// 吊销之后签发的 token 有效
+	newCtx := &Context{UserID: 1, LoginType: LoginTypePhone, IssuedAt: time.Now().Unix() + 1}
+	if revoked, err := IsRevoked(newCtx); err != nil || revoked {
+		t.Errorf("IsRevoked() = %v, %v, want false", revoked, err)
+	}
+}
@@ -3,6 +3,7 @@
 package token
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -17,10 +18,35 @@ var (
 	ErrMissingHeader = errors.New("the length of the `Authorization` header is zero")
 )
 
+const (
+	// LoginTypeEmail 邮箱登录
+	LoginTypeEmail = "email"
+	// LoginTypePhone 手机登录
+	LoginTypePhone = "phone"
+	// LoginTypeOAuth 第三方帐号登录
+	LoginTypeOAuth = "oauth"
+)
+
 // Context is the context of the JSON web token.
 type Context struct {
-	UserID   uint64
-	Username string
+	// ID token 的唯一id(jti)，用于排查问题时定位某次签发
+	ID        string
+	UserID    uint64
+	Username  string
+	LoginType string
+	// IssuedAt 签发时间, 用于判断 token 是否已被吊销，签发时为0则使用当前时间
+	IssuedAt int64
+	// ExpiresAt 过期时间，为0时不过期
+	ExpiresAt int64
+	// ImpersonatorID 模拟登录的管理员id，不为0时表示是管理员模拟该用户签发的 token
+	ImpersonatorID uint64
+	// Region 用户所在地区，用于把数据读写路由到对应地区的存储
+	Region string
+}
+
+// IsImpersonated 是否是模拟登录的 token
+func (c *Context) IsImpersonated() bool {
+	return c.ImpersonatorID > 0
 }
 
 // secretFunc validates the secret format.
@@ -51,6 +77,19 @@ func Parse(tokenString string, secret string) (*Context, error) {
 	} else if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		ctx.UserID = uint64(claims["user_id"].(float64))
 		ctx.Username = claims["username"].(string)
+		// 兼容旧的 token
+		ctx.LoginType, _ = claims["login_type"].(string)
+		if iat, ok := claims["iat"].(float64); ok {
+			ctx.IssuedAt = int64(iat)
+		}
+		if exp, ok := claims["exp"].(float64); ok {
+			ctx.ExpiresAt = int64(exp)
+		}
+		if impersonatorID, ok := claims["impersonator_id"].(float64); ok {
+			ctx.ImpersonatorID = uint64(impersonatorID)
+		}
+		ctx.Region, _ = claims["region"].(string)
+		ctx.ID, _ = claims["jti"].(string)
 		return ctx, nil
 
 		// Other errors.
@@ -81,7 +120,7 @@ func ParseRequest(c *gin.Context) (*Context, error) {
 }
 
 // Sign signs the context with the specified secret.
-func Sign(ctx *gin.Context, c Context, secret string) (tokenString string, err error) {
+func Sign(ctx context.Context, c Context, secret string) (tokenString string, err error) {
 	// Load the jwt secret from the Gin config if the secret isn't specified.
 	if secret == "" {
 		secret = viper.GetString("jwt_secret")
@@ -95,12 +134,32 @@ func Sign(ctx *gin.Context, c Context, secret string) (tokenString string, err e
 	// sub: （Subject）该JWT的主题
 	// nbf: （Not Before）不要早于这个时间
 	// jti: （JWT ID）用于标识JWT的唯一ID
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  c.UserID,
-		"username": c.Username,
-		"nbf":      time.Now().Unix(),
-		"iat":      time.Now().Unix(),
-	})
+	issuedAt := c.IssuedAt
+	if issuedAt == 0 {
+		issuedAt = time.Now().Unix()
+	}
+	claims := jwt.MapClaims{
+		"user_id":    c.UserID,
+		"username":   c.Username,
+		"login_type": c.LoginType,
+		"nbf":        issuedAt,
+		"iat":        issuedAt,
+	}
+	if c.ID != "" {
+		claims["jti"] = c.ID
+	}
+	if c.ExpiresAt > 0 {
+		claims["exp"] = c.ExpiresAt
+	}
+	if c.Region != "" {
+		claims["region"] = c.Region
+	}
+	// 模拟登录的 token 在 claims 中明确标识
+	if c.ImpersonatorID > 0 {
+		claims["impersonator_id"] = c.ImpersonatorID
+		claims["impersonated"] = true
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	// Sign the token with the specified secret.
 	tokenString, err = token.SignedString([]byte(secret))
 
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSignImpersonation(t *testing.T) {
+	secret := "test-secret"
+	expiresAt := time.Now().Add(time.Minute).Unix()
+
+	tokenStr, err := Sign(context.Background(), Context{UserID: 2, Username: "snake", ExpiresAt: expiresAt, ImpersonatorID: 1}, secret)
+	if err != nil {
+		t.Fatalf("Sign() err: %v", err)
+	}
+	ctx, err := Parse(tokenStr, secret)
+	if err != nil {
+		t.Fatalf("Parse() err: %v", err)
+	}
+	if !ctx.IsImpersonated() || ctx.ImpersonatorID != 1 || ctx.UserID != 2 || ctx.ExpiresAt != expiresAt {
+		t.Errorf("Parse() = %+v, want impersonated context", ctx)
+	}
+
+	// 过期的 token 无效
+	tokenStr, _ = Sign(context.Background(), Context{UserID: 2, ExpiresAt: time.Now().Add(-time.Minute).Unix(), ImpersonatorID: 1}, secret)
+	if _, err := Parse(tokenStr, secret); err == nil {
+		t.Error("Parse() expired token, want err")
+	}
+}
+
+func TestSignRegion(t *testing.T) {
+	secret := "test-secret"
+
+	tokenStr, err := Sign(context.Background(), Context{UserID: 2, Username: "snake", Region: "eu"}, secret)
+	if err != nil {
+		t.Fatalf("Sign() err: %v", err)
+	}
+	ctx, err := Parse(tokenStr, secret)
+	if err != nil {
+		t.Fatalf("Parse() err: %v", err)
+	}
+	if ctx.Region != "eu" {
+		t.Errorf("Parse() region = %q, want eu", ctx.Region)
+	}
+}
+
+func TestSignDeterministic(t *testing.T) {
+	secret := "test-secret"
+	c := Context{ID: "42", UserID: 2, Username: "snake", IssuedAt: time.Now().Add(-time.Hour).Unix()}
+
+	// 签发时间和 id 由调用方传入时，签发结果是确定的
+	tokenStr, err := Sign(context.Background(), c, secret)
+	if err != nil {
+		t.Fatalf("Sign() err: %v", err)
+	}
+	again, _ := Sign(context.Background(), c, secret)
+	if again != tokenStr {
+		t.Errorf("Sign() = %s, want %s", again, tokenStr)
+	}
+
+	ctx, err := Parse(tokenStr, secret)
+	if err != nil {
+		t.Fatalf("Parse() err: %v", err)
+	}
+	if ctx.ID != "42" || ctx.IssuedAt != c.IssuedAt {
+		t.Errorf("Parse() = %+v, want id 42 and iat %d", ctx, c.IssuedAt)
+	}
+}
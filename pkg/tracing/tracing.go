@@ -0,0 +1,180 @@
+// Package tracing 基于 OpenTelemetry 的链路追踪，trace 上下文通过 context.Context 在 handler、service、repository 之间传递，
+// 跨服务时通过 http header、grpc metadata 中的 traceparent 传递，导出到 jaeger 或支持 otlp 的后端
+// 未开启时使用 OpenTelemetry 默认的空实现，创建 span 的开销可以忽略
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/1024casts/snake/pkg/lifecycle"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// 导出方式
+const (
+	// ExporterJaeger 通过 http 发送到 jaeger collector，如 http://localhost:14268/api/traces
+	ExporterJaeger = "jaeger"
+	// ExporterOTLP 通过 otlp/http 发送，如 localhost:4318，jaeger 1.35 之后也支持
+	ExporterOTLP = "otlp"
+)
+
+// instrumentationName 创建 span 时使用的 tracer 名称
+const instrumentationName = "github.com/1024casts/snake"
+
+// shutdownTimeout 退出时等待剩余 span 导出的时间
+const shutdownTimeout = 5 * time.Second
+
+var provider *sdktrace.TracerProvider
+
+// 未开启时也解析和透传上游的 traceparent，调用链不会在当前服务断开
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Config 链路追踪配置
+type Config struct {
+	// ServiceName 服务名，为空时使用 app.name
+	ServiceName string
+	// Exporter jaeger 或 otlp
+	Exporter string
+	// Endpoint 导出地址，为空时使用 exporter 的默认地址
+	Endpoint string
+	// Insecure otlp 不使用 https
+	Insecure bool
+	// SampleRatio 采样比例，上游已采样的请求总是采样，为0时不采样
+	SampleRatio float64
+}
+
+// Init 按 trace 配置初始化，未开启时不导出
+func Init() {
+	if !viper.GetBool("trace.enable") {
+		return
+	}
+	c := Config{
+		ServiceName: viper.GetString("trace.service_name"),
+		Exporter:    viper.GetString("trace.exporter"),
+		Endpoint:    viper.GetString("trace.endpoint"),
+		Insecure:    viper.GetBool("trace.insecure"),
+		SampleRatio: viper.GetFloat64("trace.sample_ratio"),
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = viper.GetString("app.name")
+	}
+	if err := Setup(c); err != nil {
+		log.Warnf("[tracing] init err: %v", err)
+		return
+	}
+	log.Infof("[tracing] export to %s %s, sample ratio: %v", c.Exporter, c.Endpoint, c.SampleRatio)
+}
+
+// Setup 按配置创建 TracerProvider 并设置为全局的 provider
+func Setup(c Config) error {
+	exporter, err := newExporter(c)
+	if err != nil {
+		return err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(c.ServiceName)))
+	if err != nil {
+		return errors.Wrap(err, "[tracing] new resource err")
+	}
+
+	SetProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	))
+	return nil
+}
+
+func newExporter(c Config) (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case ExporterJaeger:
+		var opts []jaeger.CollectorEndpointOption
+		if c.Endpoint != "" {
+			opts = append(opts, jaeger.WithEndpoint(c.Endpoint))
+		}
+		return jaeger.New(jaeger.WithCollectorEndpoint(opts...))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("[tracing] unknown exporter: %q", c.Exporter)
+	}
+}
+
+// SetProvider 设置全局的 TracerProvider，测试中可以使用 tracetest.SpanRecorder 记录 span
+func SetProvider(p *sdktrace.TracerProvider) {
+	provider = p
+	otel.SetTracerProvider(p)
+}
+
+// Shutdown 导出剩余的 span，退出前调用
+func Shutdown() {
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Warnf("[tracing] shutdown err: %v", err)
+	}
+}
+
+// Hook 退出时导出剩余的 span，在 http 服务、后台任务停止后执行
+func Hook() lifecycle.Hook {
+	return lifecycle.Hook{Name: "tracing", OnStop: func(ctx context.Context) error {
+		Shutdown()
+		return nil
+	}}
+}
+
+// Start 创建 span，ctx 中没有 span 时为根 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer 创建服务端 span，用于 http、grpc 请求的入口
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// End 结束 span，err 不为 nil 时记录错误
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID 返回 ctx 中的 trace id，没有 span 或未采样时为空
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
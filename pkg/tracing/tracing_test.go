@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartEnd(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer SetProvider(sdktrace.NewTracerProvider())
+
+	ctx, root := StartServer(context.Background(), "root")
+	if TraceID(ctx) == "" {
+		t.Fatal("TraceID() is empty, want sampled trace id")
+	}
+	_, child := Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(root, nil)
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Fatal("child span is not a child of root span")
+	}
+	if spans[0].Status().Code != codes.Error || len(spans[0].Events()) != 1 {
+		t.Fatalf("child span status = %v, events = %d, want error recorded", spans[0].Status(), len(spans[0].Events()))
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Fatalf("root span status = %v, want unset", spans[1].Status())
+	}
+}
+
+func TestTraceID_NoSpan(t *testing.T) {
+	if id := TraceID(context.Background()); id != "" {
+		t.Fatalf("TraceID() = %q, want empty", id)
+	}
+}
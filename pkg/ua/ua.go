@@ -0,0 +1,169 @@
+// 解析 User-Agent，获取设备、操作系统及浏览器信息
+
+package ua
+
+import (
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DeviceTypeDesktop 桌面设备
+	DeviceTypeDesktop = "desktop"
+	// DeviceTypeMobile 手机
+	DeviceTypeMobile = "mobile"
+	// DeviceTypeTablet 平板
+	DeviceTypeTablet = "tablet"
+	// DeviceTypeBot 爬虫
+	DeviceTypeBot = "bot"
+	// DeviceTypeUnknown 未知设备
+	DeviceTypeUnknown = "unknown"
+
+	// Unknown 无法识别时的默认值
+	Unknown = "Unknown"
+)
+
+// UserAgent 解析后的 User-Agent
+type UserAgent struct {
+	Raw            string `json:"-"`
+	DeviceType     string `json:"device_type"`
+	Device         string `json:"device"`
+	OS             string `json:"os"`
+	OSVersion      string `json:"os_version"`
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version"`
+}
+
+// matcher 名称及匹配规则，第一个分组为版本号
+type matcher struct {
+	name string
+	reg  *regexp.Regexp
+}
+
+// 匹配有先后顺序，越具体的规则越靠前
+var (
+	botMatcher = regexp.MustCompile(`(?i)(bot|spider|crawler|curl|wget|python-requests|go-http-client)`)
+
+	osMatchers = []matcher{
+		{"Windows Phone", regexp.MustCompile(`Windows Phone(?: OS)? ([\d.]+)`)},
+		{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+		{"iOS", regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+		{"macOS", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+		{"HarmonyOS", regexp.MustCompile(`HarmonyOS(?:[ /]([\d.]+))?`)},
+		{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+		{"Chrome OS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+		{"Linux", regexp.MustCompile(`Linux()`)},
+	}
+
+	browserMatchers = []matcher{
+		{"WeChat", regexp.MustCompile(`MicroMessenger/([\d.]+)`)},
+		{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+		{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+		{"Samsung Browser", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+		{"UC Browser", regexp.MustCompile(`UCBrowser/([\d.]+)`)},
+		{"QQ Browser", regexp.MustCompile(`MQQBrowser/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+		{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+		{"IE", regexp.MustCompile(`(?:MSIE |Trident/.*rv:)([\d.]+)`)},
+	}
+
+	// 常见的移动设备型号
+	deviceMatchers = []matcher{
+		{"iPhone", regexp.MustCompile(`(iPhone)`)},
+		{"iPad", regexp.MustCompile(`(iPad)`)},
+		{"iPod", regexp.MustCompile(`(iPod)`)},
+		{"Android", regexp.MustCompile(`Android [\d.]+; (?:[a-zA-Z]{2}[-_][a-zA-Z]{2}; )?([^;)]+?)(?: Build/|;|\))`)},
+	}
+)
+
+// Parse 解析 User-Agent 字符串
+func Parse(userAgent string) *UserAgent {
+	u := &UserAgent{
+		Raw:            userAgent,
+		DeviceType:     DeviceTypeUnknown,
+		Device:         Unknown,
+		OS:             Unknown,
+		Browser:        Unknown,
+		OSVersion:      "",
+		BrowserVersion: "",
+	}
+	if strings.TrimSpace(userAgent) == "" {
+		return u
+	}
+
+	u.OS, u.OSVersion = match(osMatchers, userAgent)
+	u.Browser, u.BrowserVersion = match(browserMatchers, userAgent)
+	u.OSVersion = strings.Replace(u.OSVersion, "_", ".", -1)
+
+	device, model := match(deviceMatchers, userAgent)
+	switch device {
+	case "iPhone", "iPod", "iPad":
+		u.Device = device
+	case "Android":
+		u.Device = strings.TrimSpace(model)
+	}
+
+	u.DeviceType = parseDeviceType(userAgent, u)
+
+	return u
+}
+
+// match 按顺序匹配，返回第一个匹配成功的名称和版本号
+func match(matchers []matcher, userAgent string) (name, version string) {
+	for _, m := range matchers {
+		sub := m.reg.FindStringSubmatch(userAgent)
+		if sub == nil {
+			continue
+		}
+		if len(sub) > 1 {
+			version = sub[1]
+		}
+		return m.name, version
+	}
+	return Unknown, ""
+}
+
+// parseDeviceType 判断设备类型
+func parseDeviceType(userAgent string, u *UserAgent) string {
+	switch {
+	case botMatcher.MatchString(userAgent):
+		return DeviceTypeBot
+	case u.Device == "iPad" || (u.OS == "Android" && !strings.Contains(userAgent, "Mobile")):
+		return DeviceTypeTablet
+	case u.OS == "iOS" || u.OS == "Android" || u.OS == "Windows Phone" || u.OS == "HarmonyOS" ||
+		strings.Contains(userAgent, "Mobile"):
+		return DeviceTypeMobile
+	case u.OS == "Windows" || u.OS == "macOS" || u.OS == "Linux" || u.OS == "Chrome OS":
+		return DeviceTypeDesktop
+	default:
+		return DeviceTypeUnknown
+	}
+}
+
+// IsBot 是否是爬虫
+func (u *UserAgent) IsBot() bool {
+	return u.DeviceType == DeviceTypeBot
+}
+
+// IsMobile 是否是移动设备
+func (u *UserAgent) IsMobile() bool {
+	return u.DeviceType == DeviceTypeMobile || u.DeviceType == DeviceTypeTablet
+}
+
+// Name 用于展示的设备名称, eg: Chrome on macOS
+func (u *UserAgent) Name() string {
+	if u.Device != Unknown {
+		return fmt.Sprintf("%s on %s (%s)", u.Browser, u.OS, u.Device)
+	}
+	return fmt.Sprintf("%s on %s", u.Browser, u.OS)
+}
+
+// Key 设备归一化后的唯一标识
+// 只取设备类型、设备、系统和浏览器名称，忽略版本号，避免浏览器升级后被识别为新设备
+func (u *UserAgent) Key() string {
+	s := strings.Join([]string{u.DeviceType, u.Device, u.OS, u.Browser}, "|")
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.ToLower(s))))
+}
@@ -0,0 +1,66 @@
+package ua
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      UserAgent
+	}{
+		{
+			"chrome on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36",
+			UserAgent{DeviceType: DeviceTypeDesktop, Device: Unknown, OS: "macOS", OSVersion: "10.15.5", Browser: "Chrome", BrowserVersion: "83.0.4103.116"},
+		},
+		{
+			"safari on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 13_5_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1.1 Mobile/15E148 Safari/604.1",
+			UserAgent{DeviceType: DeviceTypeMobile, Device: "iPhone", OS: "iOS", OSVersion: "13.5.1", Browser: "Safari", BrowserVersion: "13.1.1"},
+		},
+		{
+			"wechat on android",
+			"Mozilla/5.0 (Linux; Android 10; MI 9 Build/QKQ1.190825.002; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/78.0.3904.62 Mobile Safari/537.36 MicroMessenger/7.0.15.1680",
+			UserAgent{DeviceType: DeviceTypeMobile, Device: "MI 9", OS: "Android", OSVersion: "10", Browser: "WeChat", BrowserVersion: "7.0.15.1680"},
+		},
+		{
+			"edge on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36 Edg/83.0.478.58",
+			UserAgent{DeviceType: DeviceTypeDesktop, Device: Unknown, OS: "Windows", OSVersion: "10.0", Browser: "Edge", BrowserVersion: "83.0.478.58"},
+		},
+		{
+			"curl",
+			"curl/7.64.1",
+			UserAgent{DeviceType: DeviceTypeBot, Device: Unknown, OS: Unknown, Browser: Unknown},
+		},
+		{
+			"empty",
+			"",
+			UserAgent{DeviceType: DeviceTypeUnknown, Device: Unknown, OS: Unknown, Browser: Unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.userAgent)
+			got.Raw = ""
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserAgent_Key(t *testing.T) {
+	a := Parse("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36")
+	b := Parse("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.89 Safari/537.36")
+	if a.Key() != b.Key() {
+		t.Errorf("Key() should ignore versions, got %s and %s", a.Key(), b.Key())
+	}
+
+	c := Parse("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36")
+	if a.Key() == c.Key() {
+		t.Errorf("Key() should differ between os, got %s", a.Key())
+	}
+}
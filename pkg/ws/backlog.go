@@ -0,0 +1,94 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// PrefixBacklogKey 待确认消息的 key 前缀
+	PrefixBacklogKey = "snake:ws:backlog"
+
+	// DefaultBacklogSize 每个用户默认最多保留的待确认消息数，超出时丢弃最早的
+	DefaultBacklogSize = 100
+	// DefaultBacklogTTL 待确认消息默认的保留时间，一段时间没有新消息时整体过期
+	DefaultBacklogTTL = 7 * 24 * time.Hour
+)
+
+// Backlog 用户待确认的消息，保存在 redis sorted set 中，score 为消息的序号
+type Backlog struct {
+	client *redis.Client
+	size   int64
+	ttl    time.Duration
+}
+
+// NewBacklog 实例化待确认队列，size、ttl 为0时使用默认值
+func NewBacklog(client *redis.Client, size int64, ttl time.Duration) *Backlog {
+	if size <= 0 {
+		size = DefaultBacklogSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultBacklogTTL
+	}
+	return &Backlog{client: client, size: size, ttl: ttl}
+}
+
+func (b *Backlog) key(userID uint64) string {
+	return fmt.Sprintf("%s:%d", PrefixBacklogKey, userID)
+}
+
+func (b *Backlog) seqKey(userID uint64) string {
+	return fmt.Sprintf("%s:%d:seq", PrefixBacklogKey, userID)
+}
+
+// Add 保存需要确认的消息，设置消息的 id 为用户内递增的序号
+func (b *Backlog) Add(userID uint64, env *Envelope) error {
+	seq, err := b.client.Incr(b.seqKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	env.ID = strconv.FormatInt(seq, 10)
+	env.Ack = true
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	key := b.key(userID)
+	pipe := b.client.TxPipeline()
+	pipe.ZAdd(key, redis.Z{Score: float64(seq), Member: data})
+	pipe.ZRemRangeByRank(key, 0, -b.size-1)
+	pipe.Expire(key, b.ttl)
+	pipe.Expire(b.seqKey(userID), b.ttl)
+	_, err = pipe.Exec()
+	return err
+}
+
+// Ack 确认消息，从队列中删除，重复确认或 id 不存在时不做处理
+func (b *Backlog) Ack(userID uint64, id string) error {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("invalid message id: %q", id)
+	}
+	return b.client.ZRemRangeByScore(b.key(userID), id, id).Err()
+}
+
+// List 按发送顺序获取所有待确认的消息
+func (b *Backlog) List(userID uint64) ([]*Envelope, error) {
+	members, err := b.client.ZRange(b.key(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	envs := make([]*Envelope, 0, len(members))
+	for _, m := range members {
+		var env Envelope
+		if err := json.Unmarshal([]byte(m), &env); err != nil {
+			continue
+		}
+		envs = append(envs, &env)
+	}
+	return envs, nil
+}
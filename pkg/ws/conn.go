@@ -0,0 +1,156 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/util"
+)
+
+// maxMessageSize 客户端消息的最大长度，客户端只发送 ack 和心跳
+const maxMessageSize = 4096
+
+// Conn 一个 websocket 连接，写操作只在 writeLoop 中进行
+type Conn struct {
+	id      string
+	hub     *Hub
+	ws      *websocket.Conn
+	userID  uint64
+	version int
+
+	out  chan *Envelope
+	done chan struct{}
+	once sync.Once
+}
+
+func newConn(h *Hub, ws *websocket.Conn, userID uint64, version int) *Conn {
+	return &Conn{
+		id:      util.GenUUID(),
+		hub:     h,
+		ws:      ws,
+		userID:  userID,
+		version: version,
+		out:     make(chan *Envelope, h.cfg.SendBuffer),
+		done:    make(chan struct{}),
+	}
+}
+
+// send 写入发送缓冲，缓冲满时说明客户端处理不过来，直接断开，重连后补发待确认的消息
+func (c *Conn) send(env *Envelope) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	select {
+	case c.out <- env:
+	default:
+		log.Warnf("[ws] send buffer full, close conn, uid: %d", c.userID)
+		c.close(websocket.CloseTryAgainLater, "send buffer full")
+	}
+}
+
+// writeNow 在 writeLoop 启动前直接写入，用于握手失败时返回错误
+func (c *Conn) writeNow(env *Envelope, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	env.Payload = b
+	_ = c.ws.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+	_ = c.ws.WriteJSON(env)
+}
+
+// close 发送关闭帧后关闭连接，可以多次调用
+func (c *Conn) close(code int, reason string) {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+			time.Now().Add(c.hub.cfg.WriteTimeout))
+		_ = c.ws.Close()
+	})
+}
+
+// writeLoop 发送消息和心跳，消息的版本按连接协商的版本设置
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(c.hub.cfg.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case env := <-c.out:
+			msg := *env
+			msg.V = c.version
+			_ = c.ws.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if err := c.ws.WriteJSON(&msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.hub.cfg.WriteTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			c.touch()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// readLoop 处理客户端的消息，超过2个心跳间隔没有收到任何消息时断开
+func (c *Conn) readLoop() {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	timeout := 2 * c.hub.cfg.Heartbeat
+	c.ws.SetReadLimit(maxMessageSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(timeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(timeout))
+
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			c.sendError(ErrCodeBadMessage, "invalid message")
+			continue
+		}
+		c.handle(&env)
+	}
+}
+
+func (c *Conn) handle(env *Envelope) {
+	switch env.Type {
+	case TypeAck:
+		if err := c.hub.backlog.Ack(c.userID, env.ID); err != nil {
+			log.Warnf("[ws] ack message err: %v, uid: %d, id: %s", err, c.userID, env.ID)
+			c.sendError(ErrCodeBadMessage, "ack failed")
+		}
+	case TypePing:
+		c.send(&Envelope{Type: TypePong, ID: env.ID})
+	default:
+		c.sendError(ErrCodeBadMessage, "unknown message type: "+env.Type)
+	}
+}
+
+// touch 续期在线状态，失败时只记录日志，用户可能被误判为离线，改为通过推送通知
+func (c *Conn) touch() {
+	if err := c.hub.presence.Touch(c.userID, c.id); err != nil {
+		log.Warnf("[ws] touch presence err: %v, uid: %d", err, c.userID)
+	}
+}
+
+func (c *Conn) sendError(code int, message string) {
+	env, _ := NewEnvelope(TypeError, Error{Code: code, Message: message})
+	c.send(env)
+}
@@ -0,0 +1,284 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/log"
+	redis2 "github.com/1024casts/snake/pkg/redis"
+	"github.com/1024casts/snake/pkg/util"
+)
+
+const (
+	// PushChannel 跨实例推送消息的 redis 频道，用户可能连接在任意一个实例上
+	PushChannel = "snake:ws:push"
+
+	// DefaultHeartbeat 默认的心跳间隔
+	DefaultHeartbeat = 30 * time.Second
+	// DefaultWriteTimeout 默认的写超时
+	DefaultWriteTimeout = 10 * time.Second
+	// DefaultSendBuffer 每个连接默认的发送缓冲，缓冲满时断开连接，客户端重连后补发待确认的消息
+	DefaultSendBuffer = 32
+)
+
+// Client 全局的 websocket hub，未开启时为nil
+var Client *Hub
+
+// Config websocket 配置
+type Config struct {
+	Heartbeat    time.Duration
+	WriteTimeout time.Duration
+	SendBuffer   int
+	BacklogSize  int64
+	BacklogTTL   time.Duration
+	// AllowedOrigins 允许跨域连接的来源，为空时只允许同源
+	AllowedOrigins []string
+}
+
+// Hub 管理当前实例上的连接，并通过 redis pub/sub 接收其他实例发送的消息
+type Hub struct {
+	cfg      Config
+	client   *redis.Client
+	backlog  *Backlog
+	presence *Presence
+	upgrader websocket.Upgrader
+	// instance 实例id，收到自己发布的消息时跳过，本实例的连接已经直接投递
+	instance string
+
+	mu     sync.RWMutex
+	conns  map[uint64]map[*Conn]struct{}
+	pubsub *redis.PubSub
+}
+
+// pushMessage 跨实例推送的消息
+type pushMessage struct {
+	Instance string    `json:"instance"`
+	UserID   uint64    `json:"user_id"`
+	Envelope *Envelope `json:"envelope"`
+}
+
+// Init 根据配置初始化，未开启时 Client 为nil
+func Init() *Hub {
+	if InitPublisher() == nil {
+		return nil
+	}
+	Client.Start()
+	return Client
+}
+
+// InitPublisher 只发送消息的进程(worker、计划任务)使用，不订阅其他实例的消息，也不接受连接
+func InitPublisher() *Hub {
+	if !viper.GetBool("ws.enable") {
+		return nil
+	}
+	Client = New(redis2.RedisClient, Config{
+		Heartbeat:      viper.GetDuration("ws.heartbeat"),
+		WriteTimeout:   viper.GetDuration("ws.write_timeout"),
+		SendBuffer:     viper.GetInt("ws.send_buffer"),
+		BacklogSize:    viper.GetInt64("ws.backlog_size"),
+		BacklogTTL:     viper.GetDuration("ws.backlog_ttl"),
+		AllowedOrigins: viper.GetStringSlice("ws.allowed_origins"),
+	})
+	return Client
+}
+
+// New 实例化一个 hub，未设置的参数使用默认值
+func New(client *redis.Client, cfg Config) *Hub {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = DefaultHeartbeat
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.SendBuffer <= 0 {
+		cfg.SendBuffer = DefaultSendBuffer
+	}
+
+	h := &Hub{
+		cfg:      cfg,
+		client:   client,
+		backlog:  NewBacklog(client, cfg.BacklogSize, cfg.BacklogTTL),
+		presence: NewPresence(client, 3*cfg.Heartbeat), // 心跳时续期，留出一次心跳失败的余量
+		instance: util.GenUUID(),
+		conns:    make(map[uint64]map[*Conn]struct{}),
+	}
+	protocols := make([]string, 0, len(SupportedVersions))
+	for _, v := range SupportedVersions {
+		protocols = append(protocols, ProtocolName(v))
+	}
+	h.upgrader = websocket.Upgrader{Subprotocols: protocols}
+	if len(cfg.AllowedOrigins) > 0 {
+		h.upgrader.CheckOrigin = h.checkOrigin
+	}
+	return h
+}
+
+// Start 订阅其他实例发送的消息
+func (h *Hub) Start() {
+	h.pubsub = h.client.Subscribe(PushChannel)
+	go func() {
+		for msg := range h.pubsub.Channel() {
+			var m pushMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil || m.Envelope == nil {
+				log.Warnf("[ws] unmarshal push message err: %v", err)
+				continue
+			}
+			if m.Instance == h.instance {
+				continue
+			}
+			h.deliver(m.UserID, m.Envelope)
+		}
+	}()
+}
+
+// Stop 停止订阅，断开所有连接，客户端重连到其他实例
+func (h *Hub) Stop(ctx context.Context) error {
+	if h.pubsub != nil {
+		_ = h.pubsub.Close()
+	}
+	h.mu.Lock()
+	conns := h.conns
+	h.conns = make(map[uint64]map[*Conn]struct{})
+	h.mu.Unlock()
+
+	for _, set := range conns {
+		for c := range set {
+			c.close(websocket.CloseGoingAway, "server shutting down")
+		}
+	}
+	return nil
+}
+
+// Send 给用户的所有连接发送消息，ack 为 true 时先保存到待确认队列，用户不在线时上线后再投递
+func (h *Hub) Send(userID uint64, typ string, payload interface{}, ack bool) error {
+	env, err := NewEnvelope(typ, payload)
+	if err != nil {
+		return err
+	}
+	if ack {
+		if err := h.backlog.Add(userID, env); err != nil {
+			return err
+		}
+	}
+
+	h.deliver(userID, env)
+	data, err := json.Marshal(pushMessage{Instance: h.instance, UserID: userID, Envelope: env})
+	if err != nil {
+		return err
+	}
+	return h.client.Publish(PushChannel, data).Err()
+}
+
+// Serve 升级为 websocket 连接，握手后补发待确认的消息，阻塞到连接断开
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, userID uint64) error {
+	requested := websocket.Subprotocols(r)
+	version, ok := Negotiate(requested)
+	// 版本不支持时仍然完成握手并回显客户端请求的协议，否则浏览器直接断开，客户端收不到支持的版本
+	upgrader, header := h.upgrader, http.Header(nil)
+	if !ok {
+		upgrader.Subprotocols = nil
+		header = http.Header{"Sec-Websocket-Protocol": {requested[0]}}
+	}
+	ws, err := upgrader.Upgrade(w, r, header)
+	if err != nil {
+		return err
+	}
+	c := newConn(h, ws, userID, version)
+
+	// 请求的版本都不支持时返回支持的版本，由客户端降级或提示升级
+	if !ok {
+		c.writeNow(&Envelope{V: SupportedVersions[0], Type: TypeError}, Error{
+			Code:     ErrCodeUnsupportedVersion,
+			Message:  "unsupported protocol version",
+			Versions: SupportedVersions,
+		})
+		c.close(websocket.CloseProtocolError, "unsupported protocol version")
+		return nil
+	}
+
+	// 先注册再读取待确认的消息，避免期间发送的消息丢失，重复的由客户端按 id 去重
+	h.register(c)
+	defer h.unregister(c)
+	go c.writeLoop()
+
+	hello, _ := NewEnvelope(TypeHello, Hello{
+		Version:   version,
+		Versions:  SupportedVersions,
+		Heartbeat: int(h.cfg.Heartbeat / time.Second),
+	})
+	c.send(hello)
+	envs, err := h.backlog.List(userID)
+	if err != nil {
+		log.Warnf("[ws] get backlog err: %v, uid: %d", err, userID)
+	}
+	for _, env := range envs {
+		c.send(env)
+	}
+
+	c.readLoop()
+	return nil
+}
+
+// Online 用户在当前实例上的连接数
+func (h *Hub) Online(userID uint64) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns[userID])
+}
+
+// IsOnline 用户在任意实例上是否在线，只发送消息的进程也可以使用
+func (h *Hub) IsOnline(userID uint64) (bool, error) {
+	return h.presence.Online(userID)
+}
+
+func (h *Hub) deliver(userID uint64, env *Envelope) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.conns[userID] {
+		c.send(env)
+	}
+}
+
+func (h *Hub) register(c *Conn) {
+	h.mu.Lock()
+	if h.conns[c.userID] == nil {
+		h.conns[c.userID] = make(map[*Conn]struct{})
+	}
+	h.conns[c.userID][c] = struct{}{}
+	h.mu.Unlock()
+
+	c.touch()
+}
+
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns[c.userID], c)
+	if len(h.conns[c.userID]) == 0 {
+		delete(h.conns, c.userID)
+	}
+	h.mu.Unlock()
+
+	if err := h.presence.Remove(c.userID, c.id); err != nil {
+		log.Warnf("[ws] remove presence err: %v, uid: %d", err, c.userID)
+	}
+}
+
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range h.cfg.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// PrefixPresenceKey 用户在线连接的 key 前缀
+const PrefixPresenceKey = "snake:ws:presence"
+
+// Presence 用户的在线状态，保存在 redis sorted set 中，member 为连接id，score 为过期时间
+// 连接在每次心跳时续期，实例崩溃没有清理的连接过期后视为离线
+type Presence struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewPresence 实例化在线状态，ttl 需要大于心跳间隔
+func NewPresence(client *redis.Client, ttl time.Duration) *Presence {
+	return &Presence{client: client, ttl: ttl}
+}
+
+func (p *Presence) key(userID uint64) string {
+	return fmt.Sprintf("%s:%d", PrefixPresenceKey, userID)
+}
+
+// Touch 记录或续期用户的连接
+func (p *Presence) Touch(userID uint64, connID string) error {
+	key := p.key(userID)
+	now := time.Now()
+	pipe := p.client.TxPipeline()
+	pipe.ZAdd(key, redis.Z{Score: float64(now.Add(p.ttl).Unix()), Member: connID})
+	pipe.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.Expire(key, p.ttl)
+	_, err := pipe.Exec()
+	return err
+}
+
+// Remove 连接断开时删除
+func (p *Presence) Remove(userID uint64, connID string) error {
+	return p.client.ZRem(p.key(userID), connID).Err()
+}
+
+// Online 用户在任意实例上是否有未过期的连接
+func (p *Presence) Online(userID uint64) (bool, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	n, err := p.client.ZCount(p.key(userID), "("+now, "+inf").Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
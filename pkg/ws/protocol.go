@@ -0,0 +1,113 @@
+// Package ws websocket 消息协议和连接管理
+// 所有消息都使用统一的信封 Envelope，通过 type 区分消息类型；需要确认的消息在客户端 ack 之前
+// 保存在 redis 的待确认队列中，客户端重连后重新投递，客户端需要按 id 去重
+package ws
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolPrefix 通过 Sec-WebSocket-Protocol 协商协议版本，eg: snake.v1
+	ProtocolPrefix = "snake.v"
+	// Version1 第一版协议
+	Version1 = 1
+)
+
+// SupportedVersions 支持的协议版本，从新到旧排列，客户端同时请求多个版本时选择最新的
+var SupportedVersions = []int{Version1}
+
+const (
+	// TypeHello 连接建立后服务端发送的第一条消息，payload 为 Hello
+	TypeHello = "hello"
+	// TypeError 协议错误，payload 为 Error
+	TypeError = "error"
+	// TypePing 客户端的心跳，服务端回复 TypePong
+	TypePing = "ping"
+	// TypePong 心跳回复
+	TypePong = "pong"
+	// TypeAck 客户端确认收到消息，id 为被确认的消息的 id
+	TypeAck = "ack"
+	// TypeNotification 通知，payload 为 model.NotificationModel
+	TypeNotification = "notification"
+)
+
+// Envelope 消息信封
+type Envelope struct {
+	// V 协议版本
+	V    int    `json:"v"`
+	Type string `json:"type"`
+	// ID 需要确认的消息的 id，同一个用户内递增，其他消息为空
+	ID string `json:"id,omitempty"`
+	// Ack 为 true 时客户端需要回复 ack，未确认的消息在重连后重新投递
+	Ack     bool            `json:"ack,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Hello 握手消息
+type Hello struct {
+	// Version 本次连接使用的协议版本
+	Version int `json:"version"`
+	// Versions 服务端支持的协议版本
+	Versions []int `json:"versions"`
+	// Heartbeat 心跳间隔(秒)，超过2个间隔没有收到客户端的消息时断开连接
+	Heartbeat int `json:"heartbeat"`
+}
+
+// Error 错误消息
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Versions 协议版本不支持时返回服务端支持的版本
+	Versions []int `json:"versions,omitempty"`
+}
+
+const (
+	// ErrCodeUnsupportedVersion 客户端请求的协议版本都不支持
+	ErrCodeUnsupportedVersion = 1
+	// ErrCodeBadMessage 消息格式错误或类型未知
+	ErrCodeBadMessage = 2
+)
+
+// NewEnvelope 构造一条消息，版本在发送时按连接协商的版本设置
+func NewEnvelope(typ string, payload interface{}) (*Envelope, error) {
+	env := &Envelope{Type: typ}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = b
+	}
+	return env, nil
+}
+
+// ProtocolName 协议版本对应的 Sec-WebSocket-Protocol
+func ProtocolName(version int) string {
+	return ProtocolPrefix + strconv.Itoa(version)
+}
+
+// Negotiate 按客户端请求的 Sec-WebSocket-Protocol 选择协议版本
+// 客户端没有请求时使用最新的版本，请求的版本都不支持时返回 false
+func Negotiate(protocols []string) (int, bool) {
+	if len(protocols) == 0 {
+		return SupportedVersions[0], true
+	}
+	requested := make(map[int]bool, len(protocols))
+	for _, p := range protocols {
+		if !strings.HasPrefix(p, ProtocolPrefix) {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimPrefix(p, ProtocolPrefix)); err == nil {
+			requested[v] = true
+		}
+	}
+	for _, v := range SupportedVersions {
+		if requested[v] {
+			return v, true
+		}
+	}
+	return 0, false
+}
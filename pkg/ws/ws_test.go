@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+	"github.com/gorilla/websocket"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		protocols []string
+		version   int
+		ok        bool
+	}{
+		{nil, Version1, true},
+		{[]string{"snake.v1"}, Version1, true},
+		{[]string{"snake.v9", "snake.v1"}, Version1, true},
+		{[]string{"snake.v9"}, 0, false},
+		{[]string{"chat"}, 0, false},
+	}
+	for _, tt := range tests {
+		version, ok := Negotiate(tt.protocols)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("Negotiate(%v) = %d, %t, want %d, %t", tt.protocols, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func newTestHub(t *testing.T) (*Hub, string) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+
+	h := New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), Config{Heartbeat: time.Second})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = h.Serve(w, r, 1)
+	}))
+	t.Cleanup(srv.Close)
+	return h, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string, protocols ...string) *websocket.Conn {
+	d := websocket.Dialer{Subprotocols: protocols}
+	conn, _, err := d.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn, typ string) *Envelope {
+	var env Envelope
+	if err := conn.ReadJSON(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != typ || env.V != Version1 {
+		t.Fatalf("got %s v%d, want %s v%d", env.Type, env.V, typ, Version1)
+	}
+	return &env
+}
+
+func TestServe(t *testing.T) {
+	h, url := newTestHub(t)
+
+	conn := dial(t, url, "snake.v1")
+	if conn.Subprotocol() != "snake.v1" {
+		t.Fatalf("subprotocol = %q, want snake.v1", conn.Subprotocol())
+	}
+	read(t, conn, TypeHello)
+	if online, err := h.IsOnline(1); err != nil || !online {
+		t.Fatalf("IsOnline = %t, %v, want true", online, err)
+	}
+
+	// miniredis 不支持 PUBLISH，只验证本实例的投递
+	_ = h.Send(1, TypeNotification, map[string]string{"title": "hi"}, true)
+	msg := read(t, conn, TypeNotification)
+	if !msg.Ack || msg.ID == "" {
+		t.Fatalf("message need ack, got %+v", msg)
+	}
+	_ = conn.Close()
+
+	// 未确认的消息重连后重新投递
+	conn = dial(t, url)
+	read(t, conn, TypeHello)
+	if again := read(t, conn, TypeNotification); again.ID != msg.ID {
+		t.Fatalf("redelivered id = %s, want %s", again.ID, msg.ID)
+	}
+	if err := conn.WriteJSON(Envelope{V: Version1, Type: TypeAck, ID: msg.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(Envelope{V: Version1, Type: TypePing}); err != nil {
+		t.Fatal(err)
+	}
+	read(t, conn, TypePong)
+	_ = conn.Close()
+
+	envs, err := h.backlog.List(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) != 0 {
+		t.Fatalf("backlog has %d messages after ack", len(envs))
+	}
+
+	// 连接断开后服务端异步清理在线状态
+	for i := 0; ; i++ {
+		online, err := h.IsOnline(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !online {
+			break
+		}
+		if i == 100 {
+			t.Fatal("user still online after disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeUnsupportedVersion(t *testing.T) {
+	_, url := newTestHub(t)
+
+	conn := dial(t, url, "snake.v9")
+	defer conn.Close()
+	var env struct {
+		Type    string `json:"type"`
+		Payload Error  `json:"payload"`
+	}
+	if err := conn.ReadJSON(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != TypeError || env.Payload.Code != ErrCodeUnsupportedVersion || len(env.Payload.Versions) == 0 {
+		t.Fatalf("got %+v, want unsupported version error", env)
+	}
+}
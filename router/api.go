@@ -10,18 +10,39 @@ import (
 	// import swagger handler
 	_ "github.com/1024casts/snake/docs" // docs is generated by Swag CLI, you have to import it.
 	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/handler/v1/admin"
+	"github.com/1024casts/snake/handler/v1/batch"
+	"github.com/1024casts/snake/handler/v1/task"
+	"github.com/1024casts/snake/handler/v1/upload"
 	"github.com/1024casts/snake/handler/v1/user"
+	"github.com/1024casts/snake/pkg/authz"
 	"github.com/1024casts/snake/router/middleware"
 )
 
+// 灰度上线的功能开关，对应配置 feature.flags 下的名称
+const (
+	featureIdentity = "user_identity"
+)
+
 // Load loads the middlewares, routes, handlers.
 func Load(g *gin.Engine, mw ...gin.HandlerFunc) *gin.Engine {
 	// 使用中间件
 	g.Use(middleware.NoCache)
 	g.Use(middleware.Options)
 	g.Use(middleware.Secure)
+	g.Use(middleware.Demo())
 	g.Use(middleware.Logging())
 	g.Use(middleware.RequestID())
+	g.Use(middleware.Tracing())
+	g.Use(middleware.Metrics())
+	g.Use(middleware.QueryCount())
+	g.Use(middleware.Startup())
+	g.Use(middleware.Timeout())
+	g.Use(middleware.SLO())
+	g.Use(middleware.Record())
+	g.Use(middleware.Chaos())
+	g.Use(middleware.RateLimit())
+	g.Use(middleware.Route()...)
 	g.Use(mw...)
 
 	// 404 Handler.
@@ -42,22 +63,153 @@ func Load(g *gin.Engine, mw ...gin.HandlerFunc) *gin.Engine {
 	// see: https://github.com/gin-contrib/pprof
 	pprof.Register(g)
 
+	// 批量请求，子请求在同一个 engine 中执行
+	g.POST(batch.Path, batch.Handle(g))
+
 	// 认证相关路由
 	g.POST("/v1/register", user.Register)
 	g.POST("/v1/login", user.Login)
 	g.POST("/v1/login/phone", user.PhoneLogin)
+	g.GET("/v1/oauth/:provider", user.OAuthURL)
+	g.POST("/v1/login/oauth/:provider", user.OAuthLogin)
 	g.GET("/v1/vcode", user.VCode)
+	g.GET("/v1/email/confirm", user.ConfirmEmail)
+	g.POST("/v1/password/forgot", user.ForgotPassword)
+	g.POST("/v1/password/reset", user.ResetPassword)
+	g.POST("/v1/users/verify", user.VerifyEmail)
+
+	// 协议
+	g.GET("/v1/policies/:type", user.GetPolicy)
 
 	// 用户
+	g.GET("/v1/users/follow_counts", user.FollowCounts)
 	g.GET("/v1/users/:id", user.Get)
+	g.GET("/v1/users/:id/avatar", user.Avatar)
+	g.GET("/v1/u/:username", user.GetByUsername)
 
 	u := g.Group("/v1/users")
-	u.Use(middleware.AuthMiddleware())
+	u.Use(middleware.AuthMiddleware(), middleware.Ban(), middleware.Policy(), middleware.AgeGate(),
+		middleware.Quota(), middleware.Idempotency())
 	{
 		u.PUT("/:id", user.Update)
+		u.POST("/:id/avatar", user.UploadAvatar)
 		u.POST("/follow", user.Follow)
+		u.POST("/block", user.Block)
+		u.POST("/unblock", user.Unblock)
+		u.POST("/verify/resend", user.ResendVerification)
 		u.GET("/:id/following", user.FollowList)
 		u.GET("/:id/followers", user.FollowerList)
+		u.GET("/:id/blocked", user.BlockedList)
+		u.GET("/:id/devices", user.DeviceList)
+		u.GET("/:id/activity", user.ActivityList)
+		u.GET("/:id/api_keys", user.APIKeyList)
+		u.POST("/:id/api_keys", user.CreateAPIKey)
+		u.POST("/:id/api_keys/:key_id/rotate", user.RotateAPIKey)
+		u.DELETE("/:id/api_keys/:key_id", user.RevokeAPIKey)
+		u.GET("/:id/api_keys/:key_id/usage", user.APIKeyUsage)
+		u.GET("/:id/quota", user.Quota)
+		// 修改凭证只能通过登录 token
+		u.POST("/:id/email", middleware.TokenOnly(), user.ChangeEmail)
+		u.POST("/:id/phone", middleware.TokenOnly(), user.ChangePhone)
+		u.GET("/:id/identities", middleware.Feature(featureIdentity), user.IdentityList)
+		u.DELETE("/:id/identities/:identity_id", middleware.Feature(featureIdentity), middleware.TokenOnly(), user.UnlinkIdentity)
+		u.GET("/:id/notifications", user.NotificationList)
+		u.POST("/:id/notifications/:notification_id/read", user.ReadNotification)
+		u.GET("/:id/announcements", user.AnnouncementList)
+		u.GET("/:id/notification/preferences", user.NotificationPreferences)
+		u.PUT("/:id/notification/preferences", user.UpdateNotificationPreferences)
+	}
+
+	// 封禁状态和申诉、同意协议，封禁中或还未同意新协议的用户也可以访问
+	b := g.Group("/v1/users")
+	b.Use(middleware.AuthMiddleware())
+	{
+		b.GET("/:id/ban", user.BanStatus)
+		b.POST("/:id/appeals", user.SubmitAppeal)
+		b.POST("/:id/policies/accept", user.AcceptPolicy)
+	}
+
+	// 实时消息，浏览器通过 access_token 参数传递 token，握手时通过 Sec-WebSocket-Protocol 协商协议版本
+	g.GET("/v1/ws", middleware.QueryToken(), middleware.AuthMiddleware(), middleware.Ban(), user.WebSocket)
+
+	// 异步任务状态
+	t := g.Group("/v1/tasks")
+	t.Use(middleware.AuthMiddleware())
+	{
+		t.GET("/:id", task.Get)
+	}
+
+	// 上传
+	up := g.Group("/v1/uploads")
+	up.Use(middleware.AuthMiddleware(), middleware.Ban(), middleware.Policy(), middleware.AgeGate(), middleware.Quota())
+	{
+		up.POST("/multipart", upload.InitMultipart)
+		up.GET("/multipart/:upload_id", upload.GetMultipart)
+		up.PUT("/multipart/:upload_id/parts/:part_number", upload.UploadPart)
+		up.POST("/multipart/:upload_id/complete", upload.CompleteMultipart)
+		up.DELETE("/multipart/:upload_id", upload.AbortMultipart)
+		up.POST("/presign", upload.Presign)
+		up.POST("/presign/complete", upload.CompletePresign)
+		up.GET("/signed-url", upload.SignedURL)
+	}
+
+	// 本地磁盘存储的签名地址，通过签名鉴权
+	g.GET("/v1/storage/objects/*key", upload.GetObject)
+	g.PUT("/v1/storage/objects/*key", upload.PutObject)
+
+	// 管理后台
+	a := g.Group("/v1/admin")
+	a.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	// 每个接口需要的权限，角色和权限的对应关系在配置 authz.roles 中定义
+	perm := middleware.RequirePermission
+	{
+		a.POST("/users/:id/impersonate", perm(authz.PermUserImpersonate), admin.Impersonate)
+		a.POST("/users/:id/membership", perm(authz.PermUserMembership), admin.ActivateMembership)
+		a.POST("/users/:id/ban", perm(authz.PermUserBan), admin.BanUser)
+		a.POST("/users/:id/unban", perm(authz.PermUserBan), admin.UnbanUser)
+		a.POST("/users/:id/deactivate", perm(authz.PermUserDeactivate), admin.DeactivateUser)
+		a.POST("/users/:id/reactivate", perm(authz.PermUserDeactivate), admin.ReactivateUser)
+		a.GET("/follows/export", perm(authz.PermFollowExport), admin.ExportFollowGraph)
+		a.GET("/appeals", perm(authz.PermAppealRead), admin.AppealList)
+		a.POST("/appeals/:id/approve", perm(authz.PermAppealReview), admin.ApproveAppeal)
+		a.POST("/appeals/:id/reject", perm(authz.PermAppealReview), admin.RejectAppeal)
+		a.POST("/policies", perm(authz.PermPolicyWrite), admin.PublishPolicy)
+		a.GET("/policies", perm(authz.PermPolicyRead), admin.PolicyList)
+		a.GET("/tasks", perm(authz.PermTaskRead), admin.TaskList)
+		a.GET("/tasks/:id", perm(authz.PermTaskRead), admin.GetTask)
+		a.POST("/announcements", perm(authz.PermAnnouncementWrite), admin.CreateAnnouncement)
+		a.GET("/announcements", perm(authz.PermAnnouncementRead), admin.AnnouncementList)
+		a.GET("/announcements/:id", perm(authz.PermAnnouncementRead), admin.GetAnnouncement)
+		a.POST("/segments", perm(authz.PermSegmentWrite), admin.CreateSegment)
+		a.GET("/segments", perm(authz.PermSegmentRead), admin.SegmentList)
+		a.GET("/segments/:id", perm(authz.PermSegmentRead), admin.GetSegment)
+		a.PUT("/segments/:id", perm(authz.PermSegmentWrite), admin.UpdateSegment)
+		a.POST("/segments/:id/materialize", perm(authz.PermSegmentWrite), admin.MaterializeSegment)
+		a.GET("/experiments", perm(authz.PermExperimentRead), admin.ExperimentList)
+		a.POST("/experiments/:name/start", perm(authz.PermExperimentWrite), admin.StartExperiment)
+		a.POST("/experiments/:name/stop", perm(authz.PermExperimentWrite), admin.StopExperiment)
+		a.GET("/moderations", perm(authz.PermModerationRead), admin.ModerationList)
+		a.POST("/moderations/:id/approve", perm(authz.PermModerationReview), admin.ApproveModeration)
+		a.POST("/moderations/:id/reject", perm(authz.PermModerationReview), admin.RejectModeration)
+		a.GET("/queues/dead", perm(authz.PermQueueRead), admin.DeadTopicList)
+		a.GET("/queues/dead/:topic", perm(authz.PermQueueRead), admin.DeadMessageList)
+		a.GET("/queues/dead/:topic/:id", perm(authz.PermQueueRead), admin.GetDeadMessage)
+		a.POST("/queues/dead/:topic/replay", perm(authz.PermQueueWrite), admin.ReplayDeadMessages)
+		a.POST("/queues/dead/:topic/discard", perm(authz.PermQueueWrite), admin.DiscardDeadMessages)
+		a.GET("/ratelimit/counters", perm(authz.PermRateLimitRead), admin.RateLimitCounters)
+		a.POST("/ratelimit/reset", perm(authz.PermRateLimitWrite), admin.ResetRateLimit)
+		a.GET("/ratelimit/rules", perm(authz.PermRateLimitRead), admin.RateLimitRuleList)
+		a.PUT("/ratelimit/rules", perm(authz.PermRateLimitWrite), admin.SetRateLimitRule)
+		a.DELETE("/ratelimit/rules", perm(authz.PermRateLimitWrite), admin.DeleteRateLimitRule)
+		a.GET("/roles", perm(authz.PermRoleRead), admin.RoleList)
+		a.GET("/users/:id/roles", perm(authz.PermRoleRead), admin.UserRoles)
+		a.POST("/users/:id/roles", perm(authz.PermRoleWrite), admin.GrantRole)
+		a.GET("/users/:id/audit_logs", perm(authz.PermAuditRead), admin.AuditLogList)
+		a.GET("/users/:id/notification_deliveries", perm(authz.PermNotificationRead), admin.NotificationDeliveryList)
+		a.GET("/overview", perm(authz.PermOverviewRead), admin.Overview)
+		a.GET("/log/level", perm(authz.PermLogRead), admin.GetLogLevel)
+		a.PUT("/log/level", perm(authz.PermLogWrite), admin.SetLogLevel)
+		a.DELETE("/users/:id/roles/:role", perm(authz.PermRoleWrite), admin.RevokeRole)
 	}
 
 	return g
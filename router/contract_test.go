@@ -0,0 +1,85 @@
+package routers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/openapi"
+)
+
+// contractCase 契约测试的请求，响应需要符合 swagger 文档中对应接口的声明
+type contractCase struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+	Status  int               `json:"status"`
+}
+
+func TestMain(m *testing.M) {
+	_ = log.NewLogger(&log.Config{Writers: log.WriterStdOut, LoggerLevel: "error"}, log.InstanceZapLogger)
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// TestContract 用 testdata/contract.json 中的请求调用实际的路由，按 docs/swagger.json 校验响应
+// 修改了接口的响应或注释后需要执行 make swag-init 重新生成文档，make test-contract 单独执行
+func TestContract(t *testing.T) {
+	spec, err := openapi.Load("../docs/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("testdata/contract.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []contractCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+
+	g := Load(gin.New())
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, bytes.NewReader(tc.Body))
+			if len(tc.Body) > 0 {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for k, v := range tc.Headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+
+			if w.Code != tc.Status {
+				t.Errorf("status = %d, want %d, body: %s", w.Code, tc.Status, w.Body.String())
+			}
+			if err := spec.Validate(tc.Method, tc.Path, w.Code, w.Body.Bytes()); err != nil {
+				t.Errorf("response does not match swagger doc: %v", err)
+			}
+		})
+	}
+}
+
+// TestContract_Drift 文档和实现不一致时契约测试需要失败
+func TestContract_Drift(t *testing.T) {
+	spec, err := openapi.Load("../docs/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.Validate(http.MethodPost, "/v1/login", http.StatusOK, []byte(`{"code":0,"msg":"OK"}`)); err == nil {
+		t.Error("undocumented field passed validation")
+	}
+	if err := spec.Validate(http.MethodGet, "/v1/not-exists", http.StatusOK, []byte(`{}`)); err == nil {
+		t.Error("undocumented route passed validation")
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/permission"
+	"github.com/1024casts/snake/pkg/authz"
+	"github.com/1024casts/snake/pkg/errno"
+)
+
+// rolesKey 当前用户的角色，查询一次后保存在 gin.Context 中
+const rolesKey = "roles"
+
+// AdminMiddleware 管理员中间件
+// 只允许拥有角色的用户访问，具体接口需要的权限由 RequirePermission 检查，需要放在 AuthMiddleware 之后
+// 模拟登录的 token 和 api key 即使对应的用户是管理员也不能访问
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler.GetAPIKeyID(c) > 0 {
+			handler.SendResponse(c, errno.ErrAPIKeyNotAllowed, nil)
+			c.Abort()
+			return
+		}
+		if handler.GetImpersonatorID(c) > 0 {
+			handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+		roles, err := getRoles(c)
+		if err != nil {
+			handler.SendError(c, err)
+			c.Abort()
+			return
+		}
+		if len(roles) == 0 {
+			handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission 声明接口需要的权限，用户的角色都没有该权限时返回 ErrPermissionDenied
+// 需要放在 AuthMiddleware 之后，模拟登录的 token 和 api key 没有任何权限
+func RequirePermission(perm authz.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler.GetImpersonatorID(c) > 0 || handler.GetAPIKeyID(c) > 0 {
+			handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+		roles, err := getRoles(c)
+		if err != nil {
+			handler.SendError(c, err)
+			c.Abort()
+			return
+		}
+		if !authz.Default().Allowed(roles, perm) {
+			handler.SendResponse(c, errno.ErrPermissionDenied, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// getRoles 获取当前用户的角色，同一个请求只查询一次
+func getRoles(c *gin.Context) ([]string, error) {
+	if v, exists := c.Get(rolesKey); exists {
+		if roles, ok := v.([]string); ok {
+			return roles, nil
+		}
+	}
+	roles, err := permission.Svc.GetRoles(c.Request.Context(), handler.GetUserID(c))
+	if err != nil {
+		return nil, err
+	}
+	c.Set(rolesKey, roles)
+	return roles, nil
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/agegate"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// AgeGate 年龄限制中间件，配置 agegate.routes 中的路由只允许达到年龄的用户访问
+// 需要放在 AuthMiddleware 之后，没有填写生日的用户需要先填写
+func AgeGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := handler.GetUserID(c)
+		if agegate.Client == nil || userID == 0 {
+			c.Next()
+			return
+		}
+		rule, ok := agegate.Client.Match(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		u, err := user.Svc.GetUserByID(c.Request.Context(), userID)
+		if err != nil {
+			log.Warnf("[agegate] get user err: %v, uid: %d", err, userID)
+			handler.SendResponse(c, errno.InternalServerError, nil)
+			c.Abort()
+			return
+		}
+		if u.Birthday == nil {
+			handler.SendResponse(c, errno.ErrBirthdayRequired, nil)
+			c.Abort()
+			return
+		}
+		if !agegate.Client.Allow(rule, u.Birthday, u.Region, time.Now()) {
+			handler.SendResponse(c, errno.ErrAgeRestricted, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,9 +1,14 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/apikey"
+	"github.com/1024casts/snake/pkg/constvar"
 	"github.com/1024casts/snake/pkg/errno"
 	"github.com/1024casts/snake/pkg/log"
 	"github.com/1024casts/snake/pkg/token"
@@ -12,6 +17,23 @@ import (
 // AuthMiddleware 认证中间件
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 开发者通过 api key 访问时不需要 token
+		if secret := c.GetHeader(constvar.XAPIKey); secret != "" {
+			key, err := apikey.Svc.Authenticate(c.Request.Context(), secret)
+			if err != nil {
+				if err != errno.ErrAPIKeyInvalid {
+					log.Warnf("[auth] authenticate api key err: %v", err)
+				}
+				handler.SendResponse(c, errno.ErrAPIKeyInvalid, nil)
+				c.Abort()
+				return
+			}
+			c.Set("uid", key.UserID)
+			c.Set("api_key_id", key.ID)
+			c.Next()
+			return
+		}
+
 		// Parse the json web token.
 		ctx, err := token.ParseRequest(c)
 		log.Infof("context is: %+v", ctx)
@@ -22,9 +44,66 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 已被吊销的 token，比如修改手机号后，之前通过手机登录的 token
+		revoked, err := token.IsRevokedContext(c.Request.Context(), ctx)
+		if err != nil {
+			log.Warnf("[auth] check token revoked err: %v", err)
+		}
+		if revoked {
+			handler.SendResponse(c, errno.ErrTokenInvalid, nil)
+			c.Abort()
+			return
+		}
+
+		// 模拟登录的 token 只能访问只读接口，防止代替用户做出修改
+		if ctx.IsImpersonated() {
+			log.Infof("[auth] impersonated request, impersonator: %d, uid: %d, %s %s",
+				ctx.ImpersonatorID, ctx.UserID, c.Request.Method, c.Request.URL.Path)
+			if !isReadOnlyMethod(c.Request.Method) {
+				handler.SendResponse(c, errno.ErrImpersonationReadOnly, nil)
+				c.Abort()
+				return
+			}
+			c.Header(constvar.XImpersonatedBy, strconv.FormatUint(ctx.ImpersonatorID, 10))
+			c.Set("impersonator_id", ctx.ImpersonatorID)
+		}
+
 		// set uid to context
 		c.Set("uid", ctx.UserID)
+		c.Set("region", ctx.Region)
+
+		c.Next()
+	}
+}
 
+// TokenOnly 只允许通过登录 token 访问，需要放在 AuthMiddleware 之后
+// 用于管理后台和修改邮箱、手机号等凭证的接口，泄露的 api key 不能用来接管帐号
+func TokenOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler.GetAPIKeyID(c) > 0 {
+			handler.SendResponse(c, errno.ErrAPIKeyNotAllowed, nil)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
+
+// QueryToken 浏览器的 websocket 不能设置请求头，通过 access_token 参数传递 token，需要放在 AuthMiddleware 之前
+func QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t := c.Query("access_token"); t != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+t)
+		}
+		c.Next()
+	}
+}
+
+// isReadOnlyMethod 不会修改数据的请求方法
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
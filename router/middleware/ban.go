@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/ban"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Ban 封禁检查中间件，封禁中的用户已签发的 token 也不能继续使用
+// 需要放在 AuthMiddleware 之后
+func Ban() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := handler.GetUserID(c)
+		if userID == 0 {
+			c.Next()
+			return
+		}
+
+		err := ban.Svc.CheckBan(c.Request.Context(), userID)
+		if err == errno.ErrUserBanned {
+			handler.SendResponse(c, errno.ErrUserBanned, nil)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			// 查询失败时不影响正常请求
+			log.Warnf("[ban] check ban err: %v, uid: %d", err, userID)
+		}
+
+		c.Next()
+	}
+}
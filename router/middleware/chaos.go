@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/chaos"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Chaos 按路由注入延迟、错误或断开连接，只在测试环境通过配置开启
+func Chaos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if chaos.Client == nil {
+			c.Next()
+			return
+		}
+
+		rule := chaos.Client.Pick(c.Request.Method, c.FullPath())
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		log.Infof("[chaos] inject %s, method: %s, path: %s", rule.Fault, c.Request.Method, c.Request.URL.Path)
+		switch rule.Fault {
+		case chaos.FaultLatency:
+			c.Header(constvar.XChaosFault, rule.Fault)
+			select {
+			case <-time.After(rule.Latency):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+			c.Next()
+		case chaos.FaultError:
+			c.Header(constvar.XChaosFault, rule.Fault)
+			code, message := errno.DecodeErr(errno.InternalServerError)
+			c.AbortWithStatusJSON(rule.Status, handler.Response{
+				Code:    code,
+				Message: message,
+				Data:    nil,
+				Kind:    errno.InternalServerError.Kind.String(),
+			})
+		case chaos.FaultDrop:
+			c.Abort()
+			conn, _, err := c.Writer.Hijack()
+			if err != nil {
+				log.Warnf("[chaos] hijack err: %v", err)
+				return
+			}
+			_ = conn.Close()
+		}
+	}
+}
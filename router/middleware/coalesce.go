@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/singleflight"
+)
+
+// coalesceGroup 正在执行的 GET 请求
+var coalesceGroup singleflight.Group
+
+// coalescedResponse 合并执行的请求的响应
+type coalescedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Coalesce 合并相同的并发 GET 请求，路由、参数、调用方、语言都相同时只执行一次，其他请求等待并返回相同的响应
+// 用于客户端 bug 等导致同一个接口被集中请求时保护数据库，调用方见 coalesceKey
+func Coalesce(c *gin.Context) {
+	if c.Request.Method != http.MethodGet {
+		c.Next()
+		return
+	}
+
+	key := coalesceKey(c)
+	leader := false
+	v, err, _ := coalesceGroup.Do(key, func() (interface{}, error) {
+		leader = true
+		blw := &bodyLogWriter{
+			body:           bytes.NewBufferString(""),
+			ResponseWriter: c.Writer,
+		}
+		c.Writer = blw
+		c.Next()
+		return &coalescedResponse{
+			status: c.Writer.Status(),
+			header: c.Writer.Header().Clone(),
+			body:   blw.body.Bytes(),
+		}, nil
+	})
+	if leader {
+		return
+	}
+	if err != nil {
+		// 执行的请求 panic 时单独执行
+		log.Warnf("[coalesce] coalesced request err: %v, uri: %s", err, c.Request.URL.RequestURI())
+		c.Next()
+		return
+	}
+
+	resp := v.(*coalescedResponse)
+	for k, values := range resp.header {
+		// 请求id每个请求不同
+		if k == http.CanonicalHeaderKey(constvar.XRequestID) {
+			continue
+		}
+		c.Writer.Header()[k] = values
+	}
+	c.Writer.WriteHeader(resp.status)
+	_, _ = c.Writer.Write(resp.body)
+	c.Abort()
+}
+
+// coalesceKey 合并请求的key，已认证时按用户、api key、模拟登录的管理员区分，
+// 还没有经过认证中间件时按 Authorization 和 X-Api-Key 请求头区分；
+// time_format=local 等响应和 Accept-Language 有关，语言也需要相同
+func coalesceKey(c *gin.Context) string {
+	principal := fmt.Sprintf("uid:%d|key:%d|imp:%d",
+		handler.GetUserID(c), handler.GetAPIKeyID(c), handler.GetImpersonatorID(c))
+	if handler.GetUserID(c) == 0 {
+		principal = "auth:" + c.GetHeader("Authorization") + "|api_key:" + c.GetHeader(constvar.XAPIKey)
+	}
+	return c.Request.URL.RequestURI() + "|" + principal + "|" + c.GetHeader("Accept-Language")
+}
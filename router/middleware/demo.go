@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/demo"
+)
+
+// Demo 演示模式下在响应头中返回提示，避免把演示环境当成正式环境使用
+func Demo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if demo.Enabled() {
+			c.Header(constvar.XSnakeDemo, demo.Banner())
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/experiment"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Experiment A/B 实验中间件，为当前用户分配实验分组并记录曝光
+// handler 中通过 experiment.GetVariant(c, name) 获取分组，未登录用户和已停止的实验使用对照组
+// eg: u.GET("/:id/feed", middleware.Experiment("new_feed"), user.Feed)
+func Experiment(names ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if experiment.Client == nil {
+			c.Next()
+			return
+		}
+
+		userID := handler.GetUserID(c)
+		for _, name := range names {
+			e, err := experiment.Client.Get(name)
+			if err != nil {
+				if err != experiment.ErrNotFound {
+					log.Warnf("[experiment] get experiment err: %v, name: %s", err, name)
+				}
+				continue
+			}
+
+			if userID == 0 {
+				experiment.SetVariant(c, name, e.Control())
+				continue
+			}
+
+			variant := e.Assign(userID)
+			experiment.SetVariant(c, name, variant)
+			if e.Running {
+				experiment.LogExposure(&experiment.Exposure{
+					Experiment: name,
+					Variant:    variant,
+					UserID:     userID,
+					Path:       c.FullPath(),
+				})
+			}
+		}
+
+		c.Next()
+	}
+}
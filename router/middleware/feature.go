@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/feature"
+)
+
+// Feature 功能开关中间件，用于新接口的灰度上线
+// 只有白名单用户或放量范围内的用户可以访问，其他用户返回即将上线
+// 需要放在 AuthMiddleware 之后，否则只有全量时才能访问
+func Feature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !feature.Enabled(name, handler.GetUserID(c)) {
+			handler.SendResponse(c, errno.ErrComingSoon, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
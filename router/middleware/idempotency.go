@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/nonce"
+)
+
+const (
+	// idempotencyProcessing 请求处理中的占位值
+	idempotencyProcessing = "processing"
+	// idempotencyExpireTime 幂等结果保留时间
+	idempotencyExpireTime = 24 * time.Hour
+)
+
+// Idempotency 幂等中间件
+// 客户端通过 Idempotency-Key 请求头标识一次操作，相同 key 的重复请求直接返回第一次成功的结果,
+// 第一次请求还在处理中时返回 ErrDuplicateRequest，处理失败则删除 key 允许客户端重试
+// 需要放在 AuthMiddleware 之后，key 按用户隔离
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(constvar.XIdempotencyKey)
+		if idempotencyKey == "" || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := fmt.Sprintf("idempotency:%d:%s:%s", handler.GetUserID(c), c.Request.URL.Path, idempotencyKey)
+		ok, err := nonce.Client.SetNX(key, idempotencyProcessing, idempotencyExpireTime)
+		if err != nil {
+			// 存储不可用时不影响正常请求
+			log.Warnf("[idempotency] set idempotency key err: %v", err)
+			c.Next()
+			return
+		}
+
+		if !ok {
+			replayResponse(c, key)
+			return
+		}
+
+		blw := &bodyLogWriter{
+			body:           bytes.NewBufferString(""),
+			ResponseWriter: c.Writer,
+		}
+		c.Writer = blw
+
+		c.Next()
+
+		// 只缓存成功的结果
+		var response handler.Response
+		err = json.Unmarshal(blw.body.Bytes(), &response)
+		if err != nil || c.Writer.Status() != http.StatusOK || response.Code != errno.OK.Code {
+			if err := nonce.Client.Del(key); err != nil {
+				log.Warnf("[idempotency] del idempotency key err: %v", err)
+			}
+			return
+		}
+		if err := nonce.Client.Set(key, blw.body.String(), idempotencyExpireTime); err != nil {
+			log.Warnf("[idempotency] save idempotency response err: %v", err)
+		}
+	}
+}
+
+// replayResponse 返回第一次请求的结果
+func replayResponse(c *gin.Context, key string) {
+	val, err := nonce.Client.Get(key)
+	if err != nil {
+		log.Warnf("[idempotency] get idempotency response err: %v", err)
+		handler.SendResponse(c, errno.InternalServerError, nil)
+		c.Abort()
+		return
+	}
+
+	if val == idempotencyProcessing || val == "" {
+		handler.SendResponse(c, errno.ErrDuplicateRequest, nil)
+		c.Abort()
+		return
+	}
+
+	c.Header(constvar.XIdempotentReplayed, "true")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(val))
+	c.Abort()
+}
@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/willf/pad"
 
 	"github.com/1024casts/snake/handler"
 	"github.com/1024casts/snake/pkg/errno"
@@ -25,6 +24,9 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// accessLog 请求日志，请求量大时可以通过 log.module_levels 单独关闭
+var accessLog = log.Named("access")
+
 // Logging is a middleware function that logs the each request.
 func Logging() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -69,7 +71,7 @@ func Logging() gin.HandlerFunc {
 		// get code and message
 		var response handler.Response
 		if err := json.Unmarshal(blw.body.Bytes(), &response); err != nil {
-			log.Errorf("response body can not unmarshal to model.Response struct, body: `%s`, err: %+v",
+			accessLog.WithContext(c.Request.Context()).Errorf("response body can not unmarshal to model.Response struct, body: `%s`, err: %+v",
 				blw.body.Bytes(), err)
 			code = errno.InternalServerError.Code
 			message = err.Error()
@@ -78,7 +80,7 @@ func Logging() gin.HandlerFunc {
 			message = response.Message
 		}
 
-		log.Infof("%-13s | %-12s | %s %s | {code: %d, message: %s}", latency, ip,
-			pad.Right(method, 5, ""), path, code, message)
+		accessLog.WithContext(c.Request.Context()).Infow("request",
+			"latency", latency, "ip", ip, "method", method, "path", path, "code", code, "message", message)
 	}
 }
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/pkg/metrics"
+)
+
+// Metrics 按路由模板、租户、状态码分类统计请求数和延迟，在 /metrics 中暴露
+// 使用注册的路由如 /v1/users/:id，不使用原始路径，路由和租户的取值个数都有限制
+// 同时在进程内记录最近的错误率，用于运营面板
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		r := metrics.Default()
+		r.Observe(c.Request.Method, c.FullPath(), r.Tenant(c.Request), c.Writer.Status(), time.Since(start))
+		r.RecordResult(serverFailed(c))
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/policy"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// Policy 协议同意检查中间件
+// 协议发布新版本后，用户需要重新同意才能继续访问，data 中返回需要同意的协议
+// 需要放在 AuthMiddleware 之后，检查的协议类型由配置 policy.required 指定
+func Policy() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := handler.GetUserID(c)
+		if userID == 0 {
+			c.Next()
+			return
+		}
+
+		pending, err := policy.Svc.Pending(c.Request.Context(), userID)
+		if err != nil {
+			// 查询失败时不影响正常请求
+			log.Warnf("[policy] get pending policies err: %v, uid: %d", err, userID)
+			c.Next()
+			return
+		}
+		if len(pending) > 0 {
+			handler.SendResponse(c, errno.ErrPolicyNotAccepted, pending)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/internal/model"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/metrics"
+)
+
+// queryCountWriter 在写入响应头前带上已执行的语句条数
+type queryCountWriter struct {
+	gin.ResponseWriter
+	stats *model.QueryStats
+}
+
+func (w *queryCountWriter) setHeader() {
+	if !w.Written() {
+		w.Header().Set(constvar.XDBQueryCount, strconv.Itoa(w.stats.Count()))
+	}
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// QueryCount 统计每个请求执行的数据库语句条数，按路由在 /metrics 中暴露，
+// 超过 mysql.request_query_threshold 时输出警告日志和调用栈，debug 模式下通过 X-DB-Query-Count 响应头返回
+func QueryCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, stats := model.WithQueryStats(c.Request.Context(), viper.GetInt("mysql.request_query_threshold"))
+		c.Request = c.Request.WithContext(ctx)
+		if gin.IsDebugging() {
+			c.Writer = &queryCountWriter{ResponseWriter: c.Writer, stats: stats}
+		}
+
+		c.Next()
+
+		metrics.Default().ObserveQueries(c.Request.Method, c.FullPath(), stats.Count())
+	}
+}
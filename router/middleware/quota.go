@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/quota"
+)
+
+// Quota 套餐配额中间件
+// 按用户的套餐限制每天/每月的请求次数，并在响应头中返回配额信息
+// 需要放在 AuthMiddleware 之后，未开启配额或未登录时不做限制
+func Quota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := handler.GetUserID(c)
+		if quota.Client == nil || userID == 0 {
+			c.Next()
+			return
+		}
+
+		u, err := user.Svc.GetUserByID(c.Request.Context(), userID)
+		if err != nil {
+			log.Warnf("[quota] get user err: %v, uid: %d", err, userID)
+			c.Next()
+			return
+		}
+
+		// 通过 api key 访问时每个 key 单独计数，开发者可以在 api key 的用量中查看
+		subject := quota.UserSubject(userID)
+		if keyID := handler.GetAPIKeyID(c); keyID > 0 {
+			subject = quota.APIKeySubject(keyID)
+		}
+		usage, err := quota.Client.Consume(subject, u.Plan)
+		if err != nil {
+			// 存储不可用时不影响正常请求
+			log.Warnf("[quota] consume quota err: %v, uid: %d", err, userID)
+			c.Next()
+			return
+		}
+
+		if usage.Limit() > 0 {
+			c.Header(constvar.XQuotaLimit, strconv.FormatInt(usage.Limit(), 10))
+			c.Header(constvar.XQuotaRemaining, strconv.FormatInt(usage.Remaining(), 10))
+			c.Header(constvar.XQuotaReset, strconv.FormatInt(usage.Reset().Unix(), 10))
+		}
+
+		if !usage.Allowed {
+			handler.SendResponse(c, errno.ErrQuotaExceeded, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
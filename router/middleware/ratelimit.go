@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/ratelimit"
+)
+
+// rateLimitWarning 软限制宽限期内返回的警告
+const rateLimitWarning = `199 - "rate limit exceeded, further requests will be rejected"`
+
+// RateLimit 按ip限流，配置了限流规则的路由单独计数
+// 超限时返回 429，开启软限制后第一个超限的窗口返回 200 并带上 Warning 响应头
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ratelimit.Client == nil {
+			c.Next()
+			return
+		}
+
+		subject := "ip:" + c.ClientIP()
+		res, err := ratelimit.Client.AllowRoute(subject, c.Request.Method, c.FullPath())
+		if err != nil {
+			// 存储不可用时不影响正常请求
+			log.Warnf("[ratelimit] allow err: %v", err)
+			c.Next()
+			return
+		}
+
+		if !writeRateLimit(c, subject, res) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// writeRateLimit 返回限流相关的响应头，超限时返回 429 并中止请求
+func writeRateLimit(c *gin.Context, subject string, res *ratelimit.Result) bool {
+	c.Header(constvar.XRateLimitLimit, strconv.FormatInt(res.Limit, 10))
+	c.Header(constvar.XRateLimitRemaining, strconv.FormatInt(res.Remaining, 10))
+	c.Header(constvar.XRateLimitReset, strconv.FormatInt(res.Reset.Unix(), 10))
+
+	if res.Warning {
+		log.Warnf("[ratelimit] soft limit exceeded, subject: %s, path: %s", subject, c.Request.URL.Path)
+		c.Header(constvar.XWarning, rateLimitWarning)
+	}
+
+	if !res.Allowed {
+		log.Warnf("[ratelimit] rate limit exceeded, subject: %s, path: %s", subject, c.Request.URL.Path)
+		code, message := errno.DecodeErr(errno.ErrTooManyRequests)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, handler.Response{
+			Code:    code,
+			Message: message,
+			Data:    nil,
+			Kind:    errno.ErrTooManyRequests.Kind.String(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/recorder"
+)
+
+// Record 按比例录制 api 请求，用于在测试环境回放
+func Record() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if recorder.Client == nil || !strings.HasPrefix(c.FullPath(), "/v1/") || !recorder.Client.Sample() {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		rec := &recorder.Record{
+			Time:   start,
+			Method: c.Request.Method,
+			Route:  c.FullPath(),
+			URL:    c.Request.URL.RequestURI(),
+			Header: make(map[string]string),
+		}
+		for k := range c.Request.Header {
+			rec.Header[k] = c.Request.Header.Get(k)
+		}
+
+		// 请求体过大(如上传文件)时不录制，回放时跳过
+		if c.Request.Body != nil {
+			if c.Request.ContentLength < 0 || c.Request.ContentLength > recorder.Client.MaxBody() {
+				rec.Truncated = true
+			} else {
+				body, _ := ioutil.ReadAll(c.Request.Body)
+				c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+				rec.Body = string(body)
+			}
+		}
+
+		blw := &bodyLogWriter{
+			body:           bytes.NewBufferString(""),
+			ResponseWriter: c.Writer,
+		}
+		c.Writer = blw
+
+		c.Next()
+
+		rec.RequestID = c.Writer.Header().Get(constvar.XRequestID)
+		rec.Status = c.Writer.Status()
+		rec.Response = blw.body.String()
+		rec.Latency = time.Since(start).Milliseconds()
+		recorder.Client.Add(rec)
+	}
+}
@@ -4,25 +4,47 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/log"
 	"github.com/1024casts/snake/pkg/util"
 )
 
+// maxRequestIDLen 客户端传入的请求id的最大长度
+const maxRequestIDLen = 64
+
 // RequestID 透传Request-ID，如果没有则生成一个
+// 请求id同时保存到 request 的 context 中，service 中通过 log.WithContext(ctx) 打印的日志都会带上
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check for incoming header, use it if exists
 		requestID := c.Request.Header.Get(constvar.XRequestID)
 
 		// Create request id with UUID4
-		if requestID == "" {
+		// 不合法的请求id会写入日志，直接丢弃重新生成
+		if !validRequestID(requestID) {
 			requestID = util.GenUUID()
 		}
 
 		// Expose it for use in the application
 		c.Set(constvar.XRequestID, requestID)
+		c.Request = c.Request.WithContext(log.NewRequestIDContext(c.Request.Context(), requestID))
 
 		// Set X-Request-ID header
 		c.Writer.Header().Set(constvar.XRequestID, requestID)
 		c.Next()
 	}
 }
+
+// validRequestID 只允许字母、数字、-、_、.，避免换行等字符伪造日志
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
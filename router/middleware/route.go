@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/concurrency"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+	"github.com/1024casts/snake/pkg/ratelimit"
+	"github.com/1024casts/snake/pkg/routemw"
+)
+
+// ctxKeyRoutePolicy 当前请求命中的路由中间件规则
+const ctxKeyRoutePolicy = "route_policy"
+
+// Route 按配置 middleware.routes 给路由加上认证、邮箱验证、限流、并发限制、幂等、缓存、请求合并，配置修改后热加载，不需要重新部署
+// 返回的中间件按 auth、verified、ratelimit、concurrency、idempotency、cache、coalesce 的顺序执行，路由没有声明的中间件直接跳过
+func Route() []gin.HandlerFunc {
+	auth := AuthMiddleware()
+	verified := EmailVerified()
+	idempotency := Idempotency()
+	return []gin.HandlerFunc{
+		func(c *gin.Context) {
+			if routePolicy(c).Auth {
+				auth(c)
+			}
+		},
+		func(c *gin.Context) {
+			if routePolicy(c).Verified {
+				verified(c)
+			}
+		},
+		routeRateLimit,
+		routeConcurrency,
+		func(c *gin.Context) {
+			if routePolicy(c).Idempotency {
+				idempotency(c)
+			}
+		},
+		routeCache,
+		func(c *gin.Context) {
+			if routePolicy(c).Coalesce {
+				Coalesce(c)
+			}
+		},
+	}
+}
+
+// routePolicy 获取当前请求命中的规则，同一请求只匹配一次
+func routePolicy(c *gin.Context) routemw.Policy {
+	if v, ok := c.Get(ctxKeyRoutePolicy); ok {
+		if p, ok := v.(routemw.Policy); ok {
+			return p
+		}
+	}
+	p := routemw.Match(c.Request.Method, c.FullPath())
+	c.Set(ctxKeyRoutePolicy, p)
+	return p
+}
+
+// routeRateLimit 按规则中的限制对每个ip计数，同一规则命中的路由共用计数
+func routeRateLimit(c *gin.Context) {
+	rule := routePolicy(c).RateLimit
+	if rule == nil || ratelimit.Client == nil {
+		return
+	}
+
+	subject := "ip:" + c.ClientIP()
+	res, err := ratelimit.Client.AllowLimit(fmt.Sprintf("route:%s %s:%s", rule.Method, rule.Route, subject), rule.Limit, rule.Window)
+	if err != nil {
+		// 存储不可用时不影响正常请求
+		log.Warnf("[ratelimit] route allow err: %v", err)
+		return
+	}
+	writeRateLimit(c, subject, res)
+}
+
+// routeConcurrency 限制规则命中的路由同时处理的请求数，已满时返回 429 和 Retry-After，不排队等待
+func routeConcurrency(c *gin.Context) {
+	rule := routePolicy(c).Concurrency
+	if rule == nil {
+		return
+	}
+
+	name := rule.Method + " " + rule.Route
+	release, ok := concurrency.Acquire(name, rule.MaxConcurrency)
+	if !ok {
+		retryAfter := rule.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = routemw.DefaultRetryAfter
+		}
+		log.Warnf("[concurrency] %s is saturated, max: %d, path: %s", name, rule.MaxConcurrency, c.Request.URL.Path)
+		c.Header(constvar.XRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		code, message := errno.DecodeErr(errno.ErrTooManyRequests)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, handler.Response{
+			Code:    code,
+			Message: message,
+			Data:    nil,
+			Kind:    errno.ErrTooManyRequests.Kind.String(),
+		})
+		return
+	}
+	defer release()
+	c.Next()
+}
+
+// routeCache 允许客户端和 CDN 缓存 GET 请求的响应，覆盖 NoCache 设置的响应头
+func routeCache(c *gin.Context) {
+	ttl := routePolicy(c).CacheTTL
+	if ttl <= 0 || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+	c.Writer.Header().Del("Expires")
+}
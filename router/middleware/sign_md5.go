@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/1024casts/snake/pkg/nonce"
 	"github.com/1024casts/snake/pkg/sign"
 
 	"github.com/gin-gonic/gin"
@@ -12,10 +14,20 @@ import (
 	"github.com/1024casts/snake/pkg/errno"
 )
 
+// signTimeout 签名有效期，也是 nonce 的保留时间
+const signTimeout = 5 * time.Minute
+
+var errReplayRequest = errors.New("nonce_str has been used")
+
 // SignMd5Middleware md5 签名校验中间件
 func SignMd5Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sn, err := verifySign(c)
+		if err == errReplayRequest {
+			handler.SendResponse(c, errno.ErrReplayRequest, nil)
+			c.Abort()
+			return
+		}
 		if err != nil {
 			handler.SendResponse(c, errno.InternalServerError, nil)
 			c.Abort()
@@ -36,7 +48,7 @@ func SignMd5Middleware() gin.HandlerFunc {
 func verifySign(c *gin.Context) (map[string]string, error) {
 	requestUri := c.Request.RequestURI
 	// 创建Verify校验器
-	verifier := sign.NewVerifier()
+	verifier := sign.NewVerifier().SetTimeout(signTimeout)
 	sn := verifier.GetSign()
 
 	// 假定从RequestUri中读取校验参数
@@ -55,6 +67,16 @@ func verifySign(c *gin.Context) (map[string]string, error) {
 		return nil, errors.New("sign error")
 	}
 
+	// 防重放：同一个 app_id 的 nonce_str 在签名有效期内只能使用一次
+	key := fmt.Sprintf("sign:%s:%s", verifier.GetAppID(), verifier.GetNonceStr())
+	ok, err := nonce.Client.SetNX(key, "1", signTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errReplayRequest
+	}
+
 	return nil, nil
 }
 
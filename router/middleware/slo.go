@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/slo"
+)
+
+// SLO 按路由统计请求的可用性和延迟，用于 SLO 告警
+// http 状态码 5xx 或返回服务端错误码的请求记为失败
+func SLO() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slo.Client == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		slo.Client.Record(c.Request.Method, route, serverFailed(c), time.Since(start))
+	}
+}
+
+// serverFailed http 状态码 5xx 或返回服务端错误码时请求失败，客户端错误不算
+func serverFailed(c *gin.Context) bool {
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		return true
+	}
+	if code, ok := c.Get(handler.ContextKeyCode); ok {
+		switch code {
+		case errno.InternalServerError.Code, errno.ErrDatabase.Code:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/startup"
+)
+
+// startupRetryAfter 依赖未就绪时建议客户端重试的间隔，单位秒
+const startupRetryAfter = "5"
+
+// Startup 启动时依赖未就绪前处于降级模式，业务路由返回 503
+// 健康检查路由在加载业务路由前注册，不经过该中间件
+func Startup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if startup.Ready() {
+			c.Next()
+			return
+		}
+
+		code, message := errno.DecodeErr(errno.ErrServiceUnavailable)
+		c.Header(constvar.XRetryAfter, startupRetryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, handler.Response{
+			Code:    code,
+			Message: message,
+			Data:    nil,
+			Kind:    errno.ErrServiceUnavailable.Kind.String(),
+		})
+	}
+}
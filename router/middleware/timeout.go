@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"github.com/1024casts/snake/pkg/deadline"
+)
+
+// Timeout 给请求设置总的超时时间 timeout.request，DB、Redis 调用使用比剩余时间少 timeout.margin 的预算
+// 客户端断开连接时请求的 ctx 被取消，通过 model.WithContext、redis.WithContext 执行的调用随之停止
+func Timeout() gin.HandlerFunc {
+	budget := viper.GetDuration("timeout.request")
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if budget > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+
+		downstream, cancel := deadline.Hop(ctx)
+		defer cancel()
+		c.Request = c.Request.WithContext(deadline.WithDownstream(ctx, downstream))
+
+		c.Next()
+	}
+}
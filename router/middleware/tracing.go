@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+
+	"github.com/1024casts/snake/pkg/constvar"
+	"github.com/1024casts/snake/pkg/tracing"
+)
+
+// XTraceID 响应头中的 trace id，排查问题时用于在 jaeger 中查找
+const XTraceID = "X-Trace-ID"
+
+// Tracing 为每个请求创建 span，有上游 traceparent 时作为子 span
+// span 保存在 c.Request 的 ctx 中，service、repository 通过 c.Request.Context() 继续创建子 span
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unknown"
+		}
+		ctx, span := tracing.StartServer(ctx, c.Request.Method+" "+route,
+			semconv.HTTPMethodKey.String(c.Request.Method),
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPTargetKey.String(c.Request.URL.RequestURI()),
+			semconv.HTTPClientIPKey.String(c.ClientIP()),
+			attribute.String("http.request_id", c.GetString(constvar.XRequestID)),
+		)
+		defer span.End()
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			c.Header(XTraceID, traceID)
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/1024casts/snake/handler"
+	"github.com/1024casts/snake/internal/service/user"
+	"github.com/1024casts/snake/pkg/errno"
+	"github.com/1024casts/snake/pkg/log"
+)
+
+// EmailVerified 邮箱验证中间件，有未验证邮箱的用户不能访问，如关注其他用户
+// 需要放在 AuthMiddleware 之后，也可以在 middleware.routes 中通过 verified 按路由声明
+func EmailVerified() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := handler.GetUserID(c)
+		if userID == 0 {
+			c.Next()
+			return
+		}
+
+		err := user.Svc.CheckEmailVerified(c.Request.Context(), userID)
+		if err == errno.ErrEmailNotVerified {
+			handler.SendResponse(c, errno.ErrEmailNotVerified, nil)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Warnf("[verified] check email verified err: %v, uid: %d", err, userID)
+			handler.SendResponse(c, errno.InternalServerError, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}